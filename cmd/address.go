@@ -2,10 +2,8 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/Ghvstcode/sleeng/pkg/wallet"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"strings"
 )
 
 var (
@@ -13,11 +11,12 @@ var (
 )
 
 var AddressCmd = &cobra.Command{
-	Use:   "address",
-	Short: "Prints the public key of the Solana wallet",
+	Use:         "address",
+	Short:       "Prints the public key of the Solana wallet",
+	Annotations: usesFlags(keyAccess(keyAccessPublic), usesAlias, usesTransientKey),
 	Long: `By default, prints the public key of the current active Solana wallet.
 Provide an alias to get the public key of a specific wallet.
-Use the --all flag to list public keys of all wallets.`,
+Use the --all flag to list public keys of all wallets, and add --verbose to see how often each key has signed.`,
 	RunE: displayAddress,
 }
 
@@ -25,20 +24,50 @@ func init() {
 	AddressCmd.Flags().BoolVar(&listAll, "all", false, "List all wallet addresses")
 }
 
+// addressOutput is the JSON form of a wallet address.
+type addressOutput struct {
+	Alias     string `json:"alias"`
+	PublicKey string `json:"publicKey"`
+}
+
 func displayAddress(_ *cobra.Command, _ []string) error {
 	blue := color.New(color.FgBlue)
 	boldBlue := blue.Add(color.Bold)
 
-	wc := wallet.NewWalletConfig()
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
 
 	if listAll {
-		aliases, addressMap, err := wc.RetrieveWallets()
+		infos, err := wc.ListWallets()
 		if err != nil {
 			return fmt.Errorf("failed to retrieve wallets: %v", err)
 		}
-		for _, ali := range aliases {
-			actualAlias := strings.Split(ali, " ")[0]
-			boldBlue.Printf("Public Key of %s: %s\n", actualAlias, addressMap[actualAlias])
+
+		if jsonOutput() {
+			addresses := make([]addressOutput, 0, len(infos))
+			for _, info := range infos {
+				addresses = append(addresses, addressOutput{Alias: info.Alias, PublicKey: info.PublicKey})
+			}
+			return printJSON(addresses)
+		}
+
+		for _, info := range infos {
+			boldBlue.Printf("Public Key of %s: %s", info.Alias, info.PublicKey)
+			if info.Derivation.IsSeedDerived() {
+				boldBlue.Printf(" [%s]", info.Derivation)
+			}
+			if info.WatchOnly {
+				boldBlue.Printf(" (watch-only)")
+			}
+			if label := info.Note.Label(); label != "" {
+				boldBlue.Printf(" — %s", label)
+			}
+			if verboseFlag {
+				boldBlue.Printf(" (%s)", info.Usage)
+			}
+			boldBlue.Println()
 		}
 		return nil
 	}
@@ -48,6 +77,9 @@ func displayAddress(_ *cobra.Command, _ []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to retrieve public key for alias %s: %v", aliasFlag, err)
 		}
+		if jsonOutput() {
+			return printJSON(addressOutput{Alias: aliasFlag, PublicKey: publicKey})
+		}
 		boldBlue.Printf("Public Key of %s: %s\n", aliasFlag, publicKey)
 		return nil
 	}
@@ -57,6 +89,21 @@ func displayAddress(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to retrieve public key: %v", err)
 	}
 
+	if jsonOutput() {
+		// A key passed with --key has no alias.
+		output := addressOutput{PublicKey: publicKey}
+		if wc.Wallet == nil {
+			if info, err := wc.GetWalletInfo(""); err == nil {
+				output.Alias = info.Alias
+			}
+		}
+		return printJSON(output)
+	}
+
+	if transientWallet != nil {
+		boldBlue.Printf("Public Key of --key: %s\n", publicKey)
+		return nil
+	}
 	boldBlue.Printf("Public Key of The Active Wallet: %s\n", publicKey)
 	return nil
 }
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var airdropCmd = &cobra.Command{
+	Use:         "airdrop [amount]",
+	Short:       "Requests SOL from the faucet of the cluster for a specific or the current active wallet",
+	Annotations: usesFlags(keyAccess(keyAccessPublic), usesAlias, usesTransientKey),
+	Long: `Requests <amount> SOL, 1 SOL by default, from the faucet of the selected cluster, waits for the
+airdrop to be confirmed and prints the new balance. It works on devnet, testnet and a local test
+validator. The public faucets hand out at most 2 SOL per request and rate limit busy callers; a
+rate-limited request is retried a few times with backoff. Mainnet has no faucet, so the command
+refuses to run there.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: airdrop,
+}
+
+// airdropOutput is the JSON form of an airdrop.
+type airdropOutput struct {
+	Address   string          `json:"address"`
+	Signature string          `json:"signature"`
+	SOL       decimal.Decimal `json:"sol"`
+	Balance   decimal.Decimal `json:"balance"`
+	Network   wallet.Network  `json:"network"`
+}
+
+func airdrop(cmd *cobra.Command, args []string) error {
+	var lamports uint64
+	if len(args) > 0 {
+		var err error
+		if lamports, err = wallet.ParseSOL(args[0]); err != nil {
+			return err
+		}
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	if !jsonOutput() {
+		printBlue("Requesting an airdrop on %s and waiting for it to be confirmed...\n", wc.NetworkName())
+	}
+	receipt, err := wc.Airdrop(cmd.Context(), aliasFlag, lamports)
+	if err != nil {
+		return fmt.Errorf("failed to airdrop: %w", err)
+	}
+
+	if jsonOutput() {
+		return printJSON(airdropOutput{
+			Address:   receipt.Address,
+			Signature: receipt.Signature,
+			SOL:       wallet.LamportsToSOL(receipt.Lamports),
+			Balance:   wallet.LamportsToSOL(receipt.Balance),
+			Network:   wc.SelectedNetwork(),
+		})
+	}
+
+	fmt.Printf("Airdropped %s SOL to %s; the balance is now %s SOL. Transaction Signature: %s\n",
+		wallet.LamportsToSOL(receipt.Lamports), receipt.Address, wallet.LamportsToSOL(receipt.Balance), receipt.Signature)
+	return nil
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var allowlistCmd = &cobra.Command{
+	Use:   "allowlist",
+	Short: "Restricts sends to a list of approved recipient addresses",
+	Long: `While the allow-list is enforced, send, send-many, send --token, consolidate, multisig proposals,
+failsafe sweeps and the daemon refuse every destination that is not on it, whatever --yes says.
+Enforce it for every command with "wallet allowlist enforce on", or for a single run with
+--enforce-allowlist.
+
+A contact is added with the address it has at that moment, so changing the contact later does not
+change what is allowed. Changing the list or its enforcement asks for the keystore passphrase, so the
+key file must be encrypted with "wallet keystore encrypt" first.`,
+}
+
+var allowlistAddCmd = &cobra.Command{
+	Use:         "add [recipient...]",
+	Short:       "Adds contacts or addresses to the allow-list",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.MinimumNArgs(1),
+	RunE:        addAllowed,
+}
+
+var allowlistRemoveCmd = &cobra.Command{
+	Use:         "remove [recipient...]",
+	Short:       "Removes contacts or addresses from the allow-list",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.MinimumNArgs(1),
+	RunE:        removeAllowed,
+}
+
+var allowlistListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists the allow-list and whether it is enforced",
+	Annotations: keyAccess(keyAccessPublic),
+	Args:        cobra.NoArgs,
+	RunE:        listAllowed,
+}
+
+var allowlistEnforceCmd = &cobra.Command{
+	Use:         "enforce [on|off]",
+	Short:       "Switches enforcement of the allow-list on or off for every command",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.ExactArgs(1),
+	ValidArgs:   []string{"on", "off"},
+	RunE:        enforceAllowlist,
+}
+
+func init() {
+	allowlistCmd.AddCommand(allowlistAddCmd, allowlistRemoveCmd, allowlistListCmd, allowlistEnforceCmd)
+}
+
+// keystorePassphrase asks for the passphrase the keys of the key file are encrypted with.
+func keystorePassphrase() (string, error) {
+	prompt, _ := passphrasePrompt()
+	passphrase, err := prompt("the keystore", false)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return passphrase, nil
+}
+
+func addAllowed(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+	passphrase, err := keystorePassphrase()
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		recipient, err := wc.AllowRecipient(arg, passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to the allow-list: %w", arg, err)
+		}
+		printBlue("Allowed %s\n", recipient)
+	}
+	return nil
+}
+
+func removeAllowed(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+	passphrase, err := keystorePassphrase()
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		removed, err := wc.DisallowRecipient(arg, passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to remove %s from the allow-list: %w", arg, err)
+		}
+		printBlue("Removed %s\n", removed)
+	}
+	return nil
+}
+
+func listAllowed(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	list, err := wc.Allowlist()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput() {
+		return printJSON(list)
+	}
+	if list.Enforced {
+		printBlue("The allow-list is enforced: sends only go to the addresses below.\n")
+	} else {
+		printYellow("The allow-list is not enforced. Enforce it with `wallet allowlist enforce on` or --enforce-allowlist.\n")
+	}
+	if len(list.Recipients) == 0 {
+		fmt.Println("No allowed recipients. Add one with `wallet allowlist add [recipient]`.")
+		return nil
+	}
+	for _, recipient := range list.Recipients {
+		fmt.Println(recipient)
+	}
+	return nil
+}
+
+func enforceAllowlist(_ *cobra.Command, args []string) error {
+	var on bool
+	switch args[0] {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return fmt.Errorf("invalid argument %q: use on or off", args[0])
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+	passphrase, err := keystorePassphrase()
+	if err != nil {
+		return err
+	}
+
+	if err := wc.SetAllowlistEnforced(on, passphrase); err != nil {
+		return fmt.Errorf("failed to change enforcement of the allow-list: %w", err)
+	}
+	if on {
+		printBlue("The allow-list is now enforced for every send.\n")
+	} else {
+		printYellow("The allow-list is no longer enforced.\n")
+	}
+	return nil
+}
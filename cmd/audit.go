@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:         "audit",
+	Short:       "Prints the log of changes made to the key file and of sends, newest first",
+	Annotations: keyAccess(keyAccessNone),
+	RunE:        displayAudit,
+}
+
+var auditLimit int
+
+func init() {
+	auditCmd.Flags().IntVar(&auditLimit, "limit", 20, "Number of entries to show (0 for all)")
+}
+
+func displayAudit(_ *cobra.Command, _ []string) error {
+	rc, err := newReadOnlyContext()
+	if err != nil {
+		return err
+	}
+
+	entries, err := rc.AuditEntries()
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No audit entries.")
+		return nil
+	}
+
+	shown := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		if auditLimit > 0 && shown == auditLimit {
+			break
+		}
+		shown++
+
+		entry := entries[i]
+		printBlue("%s  %s", entry.Time.Local().Format(time.RFC3339), entry.Action)
+		if entry.Origin != nil {
+			printBlue("  [%s]", entry.Origin)
+		}
+		fmt.Println()
+		if entry.Detail != "" {
+			fmt.Printf("  %s\n", entry.Detail)
+		}
+		if entry.Endpoint != "" {
+			fmt.Printf("  via %s\n", entry.Endpoint)
+		}
+		if entry.Genesis != "" {
+			fmt.Printf("  custom cluster, genesis %s\n", entry.Genesis)
+		}
+		for _, change := range entry.Changes {
+			fmt.Printf("  %s\n", change)
+		}
+	}
+
+	return nil
+}
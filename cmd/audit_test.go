@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestCLIAuditOrigin(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(wallet.ConfigDirEnv, configDir)
+
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(cwd)
+	defer resetScopedFlags()
+	defer RootCmd.SetArgs(nil)
+
+	RootCmd.SetArgs([]string{"add-watch", "--alias", "cold", solana.NewWallet().PublicKey().String()})
+	assert.NoError(t, RootCmd.Execute())
+
+	want := wallet.Origin{Interface: wallet.InterfaceCLI, Command: "wallet add-watch"}
+	entries, err := wallet.NewWalletConfigInDir(configDir).AuditEntries()
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, &want, entries[0].Origin)
+	}
+	// Sends started by the command carry the origin in its context.
+	assert.Equal(t, want, wallet.OriginFrom(addWatchCmd.Context()))
+}
@@ -3,34 +3,115 @@ package cmd
 import (
 	"fmt"
 	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
 )
 
 var BalanceCmd = &cobra.Command{
-	Use:   "balance",
-	Short: "Prints the balance of a specific or the current active Solana wallet in EUR",
-	RunE:  displayBalance,
+	Use:         "balance",
+	Short:       "Prints the balance of a specific or the current active Solana wallet in the selected currency",
+	Annotations: usesFlags(keyAccess(keyAccessPublic), usesAlias, usesTransientKey),
+	RunE:        displayBalance,
+}
+
+var showTokens bool
+
+func init() {
+	BalanceCmd.Flags().BoolVar(&showTokens, "tokens", false, "Also list the SPL token accounts of the wallet")
+}
+
+// balanceOutput is the JSON form of a wallet balance.
+type balanceOutput struct {
+	Alias string          `json:"alias,omitempty"`
+	SOL   decimal.Decimal `json:"sol"`
+	// Fiat and Rate are left out when no exchange rate was available.
+	Fiat     *decimal.Decimal `json:"fiat,omitempty"`
+	Currency wallet.Currency  `json:"currency"`
+	// EUR repeats Fiat when the currency is EUR, for scripts written before currencies were configurable.
+	EUR    *decimal.Decimal `json:"eur,omitempty"`
+	Rate   *decimal.Decimal `json:"rate,omitempty"`
+	Tokens []tokenOutput    `json:"tokens,omitempty"`
+}
+
+// tokenOutput is the JSON form of an SPL token balance.
+type tokenOutput struct {
+	Symbol  string          `json:"symbol"`
+	Amount  decimal.Decimal `json:"amount"`
+	Mint    string          `json:"mint"`
+	Account string          `json:"account"`
 }
 
 func displayBalance(_ *cobra.Command, _ []string) error {
-	var balance string
-	var err error
-	wc := wallet.NewWalletConfig()
-	if aliasFlag == "" {
-		balance, err = wc.GetCurrentWalletBalanceInEUR("")
-	} else {
-		balance, err = wc.GetCurrentWalletBalanceInEUR(aliasFlag)
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
 	}
 
+	balance, err := wc.GetWalletBalance(aliasFlag)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve wallet balance: %v", err)
 	}
 
+	var tokens []wallet.TokenBalance
+	if showTokens {
+		tokens, err = wc.GetTokenBalances(aliasFlag)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve token balances: %v", err)
+		}
+	}
+
+	if balance.RateErr != nil {
+		printWarning("Warning: showing SOL only, %v\n", balance.RateErr)
+	}
+
+	defer wallet.TimePhase(wallet.PhaseRender)()
+	if jsonOutput() {
+		output := balanceOutput{Alias: aliasFlag, SOL: balance.SOL, Currency: balance.Currency}
+		if balance.RateErr == nil {
+			fiat := balance.Fiat.Round(2)
+			output.Fiat, output.Rate = &fiat, &balance.Rate
+			if balance.Currency == wallet.DefaultCurrency {
+				output.EUR = &fiat
+			}
+		}
+		for _, b := range tokens {
+			output.Tokens = append(output.Tokens, tokenOutput{Symbol: b.Symbol, Amount: b.Amount(), Mint: b.Mint, Account: b.Account})
+		}
+		return printJSON(output)
+	}
+
+	amount := balance.Currency.Format(balance.Fiat)
+	if balance.RateErr != nil {
+		amount = balance.SOL.String() + " SOL"
+	}
 	if aliasFlag != "" {
-		fmt.Printf("Balance of %s wallet: €%s\n", aliasFlag, balance)
+		fmt.Printf("Balance of %s wallet: %s\n", aliasFlag, amount)
+	} else if transientWallet != nil {
+		fmt.Printf("Balance of %s: %s\n", transientWallet.PublicKey(), amount)
 	} else {
-		fmt.Printf("Balance of the active wallet: €%s\n", balance)
+		fmt.Printf("Balance of the active wallet: %s\n", amount)
+	}
+
+	if showTokens {
+		return displayTokenBalances(tokens)
 	}
 
 	return nil
 }
+
+// displayTokenBalances prints one line per SPL token account of the wallet.
+func displayTokenBalances(balances []wallet.TokenBalance) error {
+	if len(balances) == 0 {
+		fmt.Println("No token accounts.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOKEN\tAMOUNT\tMINT")
+	for _, b := range balances {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", b.Symbol, b.Amount().String(), b.Mint)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Shows and changes the defaults of config.yaml",
+	Long: `config.yaml in the configuration directory (~/.config/sleeng on Linux) holds defaults for the
+persistent flags of every profile: the cluster, RPC URLs, fiat currency and locale, the key file and
+output preferences. Each key is named after its flag. A flag given on the command line, or the
+environment variable standing in for it, always takes precedence over the file.
+
+  network: mainnet-beta
+  rpc-url: https://rpc.helius.xyz/?api-key=KEY,https://api.mainnet-beta.solana.com
+  currency: USD
+  output: json
+
+The file can be edited by hand; set and unset keep its comments.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:         "get [key]",
+	Short:       "Prints a setting of config.yaml, or all of them",
+	Annotations: keyAccess(keyAccessNone),
+	Args:        cobra.MaximumNArgs(1),
+	RunE:        getConfig,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:         "set [key] [value]",
+	Short:       "Stores a setting in config.yaml",
+	Annotations: keyAccess(keyAccessNone),
+	Args:        cobra.ExactArgs(2),
+	RunE:        setConfig,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:         "unset [key]",
+	Short:       "Removes a setting from config.yaml",
+	Annotations: keyAccess(keyAccessNone),
+	Args:        cobra.ExactArgs(1),
+	RunE:        unsetConfig,
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd, configSetCmd, configUnsetCmd)
+}
+
+// setting is a key of config.yaml, named after the persistent flag it is the default of.
+type setting struct {
+	key string
+	// env is the environment variable that takes precedence over the file, if any.
+	env string
+	// parse validates a value and returns it as the flag takes it.
+	parse func(string) (string, error)
+}
+
+// settings are the keys config.yaml accepts, in the order they are applied and listed.
+var settings = []setting{
+	{key: "network", parse: func(v string) (string, error) {
+		network, err := wallet.ParseNetwork(v)
+		return string(network), err
+	}},
+	{key: "rpc-url", parse: func(v string) (string, error) {
+		return v, wallet.ValidateRPCURL(v)
+	}},
+	{key: "currency", env: wallet.CurrencyEnv, parse: func(v string) (string, error) {
+		currency, err := wallet.ParseCurrency(v)
+		return string(currency), err
+	}},
+	{key: "locale", env: wallet.LocaleEnv, parse: func(v string) (string, error) {
+		_, err := wallet.ParseLocale(v)
+		return v, err
+	}},
+	{key: "keyfile", env: wallet.KeyFileEnv, parse: expandHome},
+	{key: "rate-ttl", env: wallet.RateCacheTTLEnv, parse: func(v string) (string, error) {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl < 0 {
+			return "", fmt.Errorf("expected a duration such as 10m, got %q", v)
+		}
+		return v, nil
+	}},
+	{key: "output", parse: func(v string) (string, error) {
+		if v != outputText && v != outputJSON {
+			return "", fmt.Errorf("unknown output format %q, expected text or json", v)
+		}
+		return v, nil
+	}},
+	{key: "no-color", parse: func(v string) (string, error) {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", fmt.Errorf("expected true or false, got %q", v)
+		}
+		return strconv.FormatBool(b), nil
+	}},
+}
+
+// lookupSetting returns the setting of a key.
+func lookupSetting(key string) (setting, error) {
+	keys := make([]string, len(settings))
+	for i, s := range settings {
+		if s.key == key {
+			return s, nil
+		}
+		keys[i] = s.key
+	}
+	return setting{}, fmt.Errorf("unknown setting %q, expected one of: %s", key, strings.Join(keys, ", "))
+}
+
+// expandHome replaces a leading ~ in a path with the home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to expand %s: %w", path, err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// applySettings sets the persistent flags not given on the command line, or through their
+// environment variable, to the values of config.yaml. The network and RPC URLs go together: neither
+// is taken from the file when either flag is given. The config commands run without the file, so
+// they can repair one that does not parse.
+func applySettings(cmd *cobra.Command) error {
+	if cmd.HasParent() && cmd.Parent() == configCmd {
+		return nil
+	}
+
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+	values, err := profiles.Settings()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", profiles.SettingsPath(), err)
+	}
+	for key := range values {
+		if _, err := lookupSetting(key); err != nil {
+			return fmt.Errorf("%s: %w", profiles.SettingsPath(), err)
+		}
+	}
+
+	networkGiven := cmd.Flags().Changed("network") || cmd.Flags().Changed("rpc-url")
+	for _, s := range settings {
+		value, ok := values[s.key]
+		if !ok {
+			continue
+		}
+		value, err := s.parse(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid %s: %w", profiles.SettingsPath(), s.key, err)
+		}
+
+		flag := cmd.Flags().Lookup(s.key)
+		if flag == nil || flag.Changed || (s.env != "" && os.Getenv(s.env) != "") {
+			continue
+		}
+		if (s.key == "network" || s.key == "rpc-url") && networkGiven {
+			continue
+		}
+		// Setting the value directly leaves the flag unchanged, so it still counts as not given.
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("%s: invalid %s: %w", profiles.SettingsPath(), s.key, err)
+		}
+	}
+	return nil
+}
+
+func getConfig(_ *cobra.Command, args []string) error {
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+	values, err := profiles.Settings()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", profiles.SettingsPath(), err)
+	}
+
+	if len(args) == 1 {
+		if _, err := lookupSetting(args[0]); err != nil {
+			return err
+		}
+		value, ok := values[args[0]]
+		if jsonOutput() {
+			if !ok {
+				return printJSON(nil)
+			}
+			return printJSON(value)
+		}
+		if !ok {
+			return fmt.Errorf("%s is not set in %s", args[0], profiles.SettingsPath())
+		}
+		fmt.Println(value)
+		return nil
+	}
+
+	if jsonOutput() {
+		return printJSON(values)
+	}
+	if len(values) == 0 {
+		printBlue("Nothing is set in %s\n", profiles.SettingsPath())
+		return nil
+	}
+	printBlue("Settings of %s:\n", profiles.SettingsPath())
+	for _, s := range settings {
+		if value, ok := values[s.key]; ok {
+			fmt.Printf("%s: %s\n", s.key, value)
+		}
+	}
+	return nil
+}
+
+func setConfig(_ *cobra.Command, args []string) error {
+	s, err := lookupSetting(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := s.parse(args[1]); err != nil {
+		return fmt.Errorf("invalid %s: %w", s.key, err)
+	}
+
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+	if err := profiles.SetSetting(s.key, args[1]); err != nil {
+		return fmt.Errorf("failed to write %s: %w", profiles.SettingsPath(), err)
+	}
+
+	printBlue("%s set to %s in %s\n", s.key, args[1], profiles.SettingsPath())
+	if s.env != "" && os.Getenv(s.env) != "" {
+		printWarning("Warning: %s is set and takes precedence over %s.\n", s.env, wallet.SettingsFile)
+	}
+	return nil
+}
+
+func unsetConfig(_ *cobra.Command, args []string) error {
+	if _, err := lookupSetting(args[0]); err != nil {
+		return err
+	}
+
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+	if err := profiles.SetSetting(args[0], ""); err != nil {
+		return fmt.Errorf("failed to write %s: %w", profiles.SettingsPath(), err)
+	}
+
+	printBlue("%s removed from %s\n", args[0], profiles.SettingsPath())
+	return nil
+}
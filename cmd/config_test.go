@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestApplySettings(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(wallet.ConfigDirEnv, configDir)
+	t.Setenv(wallet.CurrencyEnv, "")
+	t.Setenv(wallet.KeyFileEnv, "")
+	home, err := os.UserHomeDir()
+	assert.NoError(t, err)
+
+	flags := RootCmd.PersistentFlags()
+	defer func() {
+		for _, s := range settings {
+			flag := flags.Lookup(s.key)
+			assert.NoError(t, flag.Value.Set(flag.DefValue))
+			flag.Changed = false
+		}
+	}()
+
+	file := "network: mainnet\nrpc-url: https://rpc.example.com\ncurrency: usd\nkeyfile: ~/keys.json\noutput: json\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(configDir, wallet.SettingsFile), []byte(file), 0600))
+
+	// --network wins, and takes the RPC URL of the file out too.
+	assert.NoError(t, BalanceCmd.ParseFlags([]string{"--network", "testnet", "--output", "text"}))
+	assert.NoError(t, applySettings(BalanceCmd))
+	assert.Equal(t, "testnet", networkFlag)
+	assert.Empty(t, rpcURLFlag)
+	assert.Equal(t, "USD", currencyFlag)
+	assert.Equal(t, filepath.Join(home, "keys.json"), keyFileFlag)
+	assert.Equal(t, outputText, outputFlag)
+	assert.False(t, flags.Changed("currency"), "a setting does not count as a flag given")
+
+	// The environment takes precedence over the file.
+	flags.Lookup("network").Changed = false
+	flags.Lookup("output").Changed = false
+	currencyFlag = "GBP"
+	t.Setenv(wallet.CurrencyEnv, "GBP")
+	assert.NoError(t, applySettings(BalanceCmd))
+	assert.Equal(t, "mainnet-beta", networkFlag)
+	assert.Equal(t, "https://rpc.example.com", rpcURLFlag)
+	assert.Equal(t, "GBP", currencyFlag)
+	assert.Equal(t, outputJSON, outputFlag)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(configDir, wallet.SettingsFile), []byte("cluster: devnet\n"), 0600))
+	err = applySettings(BalanceCmd)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown setting "cluster"`)
+	assert.NoError(t, applySettings(configSetCmd), "the config commands can repair a broken file")
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+var consolidateCmd = &cobra.Command{
+	Use:         "consolidate",
+	Short:       "Sweeps the balance of every stored wallet into one wallet or address",
+	Annotations: keyAccess(keyAccessPrivate),
+	Long: `Sweeps the spendable balance (balance minus the transaction fee) of every stored wallet into
+the destination. Wallets below --min are skipped, and a failing wallet does not stop the others.`,
+	RunE: consolidate,
+}
+
+var (
+	consolidateToFlag  string
+	consolidateMinFlag string
+)
+
+func init() {
+	consolidateCmd.Flags().StringVar(&consolidateToFlag, "to", "", "Alias or address to sweep into")
+	consolidateCmd.Flags().StringVar(&consolidateMinFlag, "min", "0", "Skip wallets with less than this much spendable SOL")
+	_ = consolidateCmd.MarkFlagRequired("to")
+}
+
+func consolidate(_ *cobra.Command, _ []string) error {
+	minSOL, err := decimal.NewFromString(consolidateMinFlag)
+	if err != nil || minSOL.IsNegative() {
+		return fmt.Errorf("invalid --min %q: expected a non-negative SOL amount", consolidateMinFlag)
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	report, err := wc.Consolidate(context.Background(), wallet.ConsolidateOptions{
+		Destination: consolidateToFlag,
+		MinLamports: uint64(minSOL.Shift(9).IntPart()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to consolidate wallets: %w", err)
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ALIAS\tADDRESS\tSOL\tRESULT")
+	for _, result := range report.Results {
+		outcome := result.Signature
+		switch {
+		case result.Err != nil:
+			outcome = "failed: " + result.Err.Error()
+		case result.Skipped != "":
+			outcome = "skipped: " + result.Skipped
+		}
+
+		amount := "-"
+		if result.Skipped == "" {
+			amount = wallet.LamportsToSOL(result.Lamports).String()
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", result.Alias, result.From, amount, outcome)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	printBlue("Consolidated %s SOL into %s.\n", wallet.LamportsToSOL(report.Total), report.Destination)
+	return nil
+}
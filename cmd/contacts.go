@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/spf13/cobra"
+)
+
+var contactsCmd = &cobra.Command{
+	Use:   "contacts",
+	Short: "Manages the address book of named recipients",
+	Long: `Contacts give recipient addresses a name, so that send takes "wallet send 20 mum" and
+transactions shows "mum" next to the address. Contact names cannot be wallet aliases.
+
+A name that is also a valid address itself resolves to the contact, with a warning.
+
+Groups such as "team" or "family" name several contacts, which send-many --group pays together.`,
+}
+
+var contactsAddCmd = &cobra.Command{
+	Use:         "add [name] [address]",
+	Short:       "Stores an address under a name",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.ExactArgs(2),
+	RunE:        addContact,
+}
+
+var contactsListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists the contacts",
+	Annotations: keyAccess(keyAccessPublic),
+	Args:        cobra.NoArgs,
+	RunE:        listContacts,
+}
+
+var contactsRemoveCmd = &cobra.Command{
+	Use:         "remove [name]",
+	Short:       "Removes a contact",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.ExactArgs(1),
+	RunE:        removeContact,
+}
+
+var contactsGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manages groups of contacts that are paid together",
+}
+
+var contactsGroupAddCmd = &cobra.Command{
+	Use:         "add [group] [contact...]",
+	Short:       "Adds contacts to a group, creating it when needed",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.MinimumNArgs(2),
+	RunE:        addGroupMembers,
+}
+
+var contactsGroupRemoveCmd = &cobra.Command{
+	Use:         "remove [group] [contact...]",
+	Short:       "Removes contacts from a group, or the whole group when no contact is named",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.MinimumNArgs(1),
+	RunE:        removeGroupMembers,
+}
+
+var contactsGroupListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists the groups and their members",
+	Annotations: keyAccess(keyAccessPublic),
+	Args:        cobra.NoArgs,
+	RunE:        listGroups,
+}
+
+func init() {
+	contactsGroupCmd.AddCommand(contactsGroupAddCmd, contactsGroupRemoveCmd, contactsGroupListCmd)
+	contactsCmd.AddCommand(contactsAddCmd, contactsListCmd, contactsRemoveCmd, contactsGroupCmd)
+}
+
+func addContact(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := wc.AddContact(args[0], args[1]); err != nil {
+		return fmt.Errorf("failed to add contact: %w", err)
+	}
+
+	printBlue("Contact %s added: %s\n", args[0], args[1])
+	return nil
+}
+
+func listContacts(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	contacts, err := wc.Contacts()
+	if err != nil {
+		return fmt.Errorf("failed to read contacts: %w", err)
+	}
+
+	if jsonOutput() {
+		return printJSON(contacts)
+	}
+	if len(contacts) == 0 {
+		fmt.Println("No contacts. Add one with `wallet contacts add [name] [address]`.")
+		return nil
+	}
+	for _, contact := range contacts {
+		printBlue("%s: %s\n", contact.Name, contact.Address)
+	}
+	return nil
+}
+
+func removeContact(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	address, err := wc.RemoveContact(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to remove contact: %w", err)
+	}
+
+	printBlue("Contact %s (%s) removed.\n", args[0], address)
+	return nil
+}
+
+func addGroupMembers(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := wc.AddGroupMembers(args[0], args[1:]...); err != nil {
+		return fmt.Errorf("failed to add to group: %w", err)
+	}
+
+	printBlue("Added %s to group %s.\n", strings.Join(args[1:], ", "), args[0])
+	return nil
+}
+
+func removeGroupMembers(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	left, err := wc.RemoveGroupMembers(args[0], args[1:]...)
+	if err != nil {
+		return fmt.Errorf("failed to remove from group: %w", err)
+	}
+
+	if len(left) == 0 {
+		printBlue("Group %s removed.\n", args[0])
+		return nil
+	}
+	printBlue("Removed %s from group %s, which now has %s.\n", strings.Join(args[1:], ", "), args[0], strings.Join(left, ", "))
+	return nil
+}
+
+func listGroups(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	groups, err := wc.Groups()
+	if err != nil {
+		return fmt.Errorf("failed to read groups: %w", err)
+	}
+
+	if jsonOutput() {
+		return printJSON(groups)
+	}
+	if len(groups) == 0 {
+		fmt.Println("No groups. Create one with `wallet contacts group add [group] [contact...]`.")
+		return nil
+	}
+	for _, group := range groups {
+		printBlue("%s: %s\n", group.Name, strings.Join(group.Members, ", "))
+	}
+	return nil
+}
+
+// resolveRecipient resolves a send argument to an address, warning when a contact name shadows a
+// valid address.
+func resolveRecipient(wc *wallet.WalletConfig, arg string) (wallet.Recipient, error) {
+	recipient, err := wc.ResolveRecipient(arg)
+	if err != nil {
+		return recipient, err
+	}
+	if recipient.Ambiguous {
+		printWarning("Warning: %s is both a contact and an address; sending to the contact %s\n", arg, recipient)
+	}
+	return recipient, nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/spf13/cobra"
+)
+
+var currencyCmd = &cobra.Command{
+	Use:         "currency [code]",
+	Short:       "Shows or stores the default fiat currency",
+	Annotations: keyAccess(keyAccessNone),
+	Long: `Without an argument this prints the default fiat currency used for balances, exchange rates,
+transaction history and amounts to send. With a code it stores that currency as the default for all
+profiles. EUR, USD and GBP are priced with Kraken, falling back to CoinGecko; AUD, BRL, CAD, CHF, INR
+and NGN with CoinGecko alone, and have no price history. --currency and SLEENG_CURRENCY still take
+precedence for a single command.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCurrency,
+}
+
+func runCurrency(_ *cobra.Command, args []string) error {
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		currency, err := profiles.Currency()
+		if err != nil {
+			return fmt.Errorf("failed to read default currency: %w", err)
+		}
+
+		printBlue("Default currency: %s (%s)\n", currency, currency.Source())
+		return nil
+	}
+
+	currency, err := wallet.ParseCurrency(args[0])
+	if err != nil {
+		return err
+	}
+	if err := profiles.SetCurrency(currency); err != nil {
+		return fmt.Errorf("failed to store default currency: %w", err)
+	}
+
+	printBlue("Default currency set to %s\n", currency)
+	return nil
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/daemon"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:         "daemon",
+	Short:       "Serves the wallet over gRPC so other programs can check balances, send and watch",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.NoArgs,
+	RunE:        runDaemon,
+}
+
+var (
+	daemonListen      string
+	daemonTokenFile   string
+	daemonTLSCert     string
+	daemonTLSKey      string
+	daemonTLSClientCA string
+	daemonRateRefresh time.Duration
+	daemonRateChange  float64
+	daemonMetrics     string
+	daemonMetricsPoll time.Duration
+)
+
+// daemonTokenEnv holds the bearer token callers must send when --token-file is not given.
+const daemonTokenEnv = "SLEENG_DAEMON_TOKEN"
+
+func init() {
+	daemonCmd.Flags().StringVar(&daemonListen, "listen", "127.0.0.1:7545", "Address to serve gRPC on")
+	daemonCmd.Flags().StringVar(&daemonTokenFile, "token-file", "", "File holding the bearer token callers must send (or set "+daemonTokenEnv+")")
+	daemonCmd.Flags().StringVar(&daemonTLSCert, "tls-cert", "", "Server certificate for TLS")
+	daemonCmd.Flags().StringVar(&daemonTLSKey, "tls-key", "", "Private key of the server certificate")
+	daemonCmd.Flags().StringVar(&daemonTLSClientCA, "tls-client-ca", "", "Require client certificates signed by this CA (mTLS)")
+	daemonCmd.Flags().DurationVar(&daemonRateRefresh, "rate-refresh", 30*time.Second, "How often to refresh the exchange rate in the background (0 disables it)")
+	daemonCmd.Flags().Float64Var(&daemonRateChange, "rate-threshold", 1, "Change of the exchange rate in percent that is sent to Watch clients")
+	daemonCmd.Flags().StringVar(&daemonMetrics, "metrics-listen", "", "Address to serve Prometheus metrics on at "+daemon.MetricsPath+" (off when empty)")
+	daemonCmd.Flags().DurationVar(&daemonMetricsPoll, "metrics-refresh", time.Minute, "How often to refresh the balances exported as metrics")
+}
+
+func runDaemon(_ *cobra.Command, _ []string) error {
+	opts := daemon.Options{Token: os.Getenv(daemonTokenEnv)}
+	if daemonTokenFile != "" {
+		token, err := os.ReadFile(daemonTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read token: %w", err)
+		}
+		opts.Token = strings.TrimSpace(string(token))
+	}
+
+	if daemonTLSCert != "" || daemonTLSKey != "" {
+		tlsConfig, err := daemon.LoadTLSConfig(daemonTLSCert, daemonTLSKey, daemonTLSClientCA)
+		if err != nil {
+			return err
+		}
+		opts.TLS = tlsConfig
+	} else if daemonTLSClientCA != "" {
+		return fmt.Errorf("--tls-client-ca needs --tls-cert and --tls-key")
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	if daemonRateRefresh < 0 || daemonRateChange < 0 {
+		return fmt.Errorf("--rate-refresh and --rate-threshold must not be negative")
+	}
+	if daemonMetrics != "" && daemonMetricsPoll <= 0 {
+		return fmt.Errorf("--metrics-refresh must be positive")
+	}
+
+	service := daemon.NewServer(wc)
+	var refresher *daemon.RateRefresher
+	if daemonRateRefresh > 0 {
+		refresher = daemon.NewRateRefresher(wc, daemonRateRefresh, decimal.NewFromFloat(daemonRateChange))
+		refresher.OnError = func(err error, retryIn time.Duration) {
+			printWarning("Warning: failed to refresh the exchange rate, retrying in %s: %v\n", retryIn, err)
+		}
+		service.PublishRateChanges(refresher)
+	}
+
+	server, err := daemon.NewGRPCServer(service, opts)
+	if err != nil {
+		return fmt.Errorf("%w: pass --token-file, set %s or use --tls-client-ca", err, daemonTokenEnv)
+	}
+
+	listener, err := net.Listen("tcp", daemonListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", daemonListen, err)
+	}
+
+	if opts.TLS == nil && !daemon.IsLoopback(listener.Addr()) {
+		printYellow("Warning: serving without TLS on %s; the token is sent in the clear.\n", listener.Addr())
+	}
+
+	var metrics *daemon.Metrics
+	var metricsServer *http.Server
+	var metricsListener net.Listener
+	if daemonMetrics != "" {
+		metrics = daemon.NewMetrics(wc, daemonMetricsPoll)
+		metrics.OnError = func(err error) {
+			printWarning("Warning: failed to refresh the metrics: %v\n", err)
+		}
+		service.RecordMetrics(metrics)
+
+		metricsListener, err = net.Listen("tcp", daemonMetrics)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to listen on %s: %w", daemonMetrics, err)
+		}
+		handler, err := metrics.Handler(opts, metricsListener.Addr())
+		if err != nil {
+			listener.Close()
+			metricsListener.Close()
+			return fmt.Errorf("%w: pass --token-file, set %s or bind --metrics-listen to 127.0.0.1", err, daemonTokenEnv)
+		}
+		metricsServer = &http.Server{Handler: handler, TLSConfig: opts.TLS, ReadHeaderTimeout: 10 * time.Second}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	refreshed := make(chan struct{})
+	if refresher != nil {
+		go func() {
+			defer close(refreshed)
+			refresher.Run(ctx)
+		}()
+	} else {
+		close(refreshed)
+	}
+	// The refresher stops with the server, before the command returns.
+	defer func() { <-refreshed }()
+	if metrics != nil {
+		polled := make(chan struct{})
+		go func() {
+			defer close(polled)
+			metrics.Run(ctx)
+		}()
+		defer func() { <-polled }()
+
+		go func() {
+			var err error
+			if opts.TLS != nil {
+				err = metricsServer.ServeTLS(metricsListener, "", "")
+			} else {
+				err = metricsServer.Serve(metricsListener)
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				printWarning("Warning: the metrics endpoint stopped: %v\n", err)
+			}
+		}()
+		defer metricsServer.Close()
+	}
+	// Requests read the key file as they come in, so a reload only needs reporting.
+	keyFileWatched := make(chan struct{})
+	go func() {
+		defer close(keyFileWatched)
+		watchKeyFile(ctx, wc, nil)
+	}()
+	defer func() { <-keyFileWatched }()
+	defer cancel()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		cancel()
+		server.GracefulStop()
+	}()
+
+	mode := "plaintext"
+	if opts.TLS != nil && opts.TLS.ClientAuth == tls.RequireAndVerifyClientCert {
+		mode = "mTLS"
+	} else if opts.TLS != nil {
+		mode = "TLS"
+	}
+	printBlue("Serving %s wallet on %s (%s). Press Ctrl+C to stop.\n", wc.NetworkName(), listener.Addr(), mode)
+	if metricsListener != nil {
+		scheme := "http"
+		if opts.TLS != nil {
+			scheme = "https"
+		}
+		printBlue("Serving metrics on %s://%s%s.\n", scheme, metricsListener.Addr(), daemon.MetricsPath)
+	}
+
+	return server.Serve(listener)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:         "doctor",
+	Short:       "Checks that the RPC endpoint serves the selected network",
+	Annotations: keyAccess(keyAccessPublic),
+	Long: `Fetches the genesis hash of the RPC endpoint and compares it with the one of the selected
+network, so an endpoint serving a local validator or another cluster is caught before funds are
+sent. Custom networks have no known genesis; their hash is shown and recorded with each send.`,
+	RunE: runDoctor,
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Network:  %s\n", wc.NetworkName())
+	check, err := wc.CheckGenesis(cmd.Context())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Endpoint: %s\n", check.Endpoint)
+	fmt.Printf("Genesis:  %s\n", check.Hash)
+
+	switch {
+	case check.Exempt():
+		printBlue("Custom cluster: its genesis is not checked, but recorded with each send.\n")
+	case check.Mismatch():
+		printYellow("Mismatch: %s has genesis %s.\n", check.Network, check.Expected)
+		return check.Err()
+	default:
+		printBlue("OK: the endpoint serves %s.\n", check.Network)
+	}
+	return nil
+}
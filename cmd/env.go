@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var envCmd = &cobra.Command{
+	Use:         "env [alias]",
+	Short:       "Prints shell lines that point other Solana tools at a wallet",
+	Annotations: usesFlags(keyAccess(keyAccessPrivate), usesAlias),
+	Long: `Prints export lines for WALLET_ADDRESS and SOLANA_RPC_URL of a wallet, by default the active one,
+so "eval $(wallet env savings)" points the Solana CLI, Anchor and scripts at the same wallet and
+cluster. Use --fish for "wallet env --fish | source" and --powershell for
+"wallet env --powershell | Invoke-Expression".
+
+--with-key also writes the key to a solana-keygen keypair file readable only by you and sets
+SOLANA_KEYPAIR to it. The file is removed by the first wallet command run after --ttl has passed.
+--no-key prints only the public settings, which is also the default.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: printWalletEnv,
+}
+
+var (
+	envWithKey    bool
+	envNoKey      bool
+	envKeyTTL     time.Duration
+	envFish       bool
+	envPowerShell bool
+)
+
+func init() {
+	envCmd.Flags().BoolVar(&envWithKey, "with-key", false, "Also export the key to a temporary keypair file and set SOLANA_KEYPAIR")
+	envCmd.Flags().BoolVar(&envNoKey, "no-key", false, "Only print the public settings, never the key")
+	envCmd.Flags().DurationVar(&envKeyTTL, "ttl", wallet.DefaultTempKeypairTTL, "How long the keypair file of --with-key is kept")
+	envCmd.Flags().BoolVar(&envFish, "fish", false, "Print fish commands")
+	envCmd.Flags().BoolVar(&envPowerShell, "powershell", false, "Print PowerShell commands")
+}
+
+func printWalletEnv(cmd *cobra.Command, args []string) error {
+	if envWithKey && envNoKey {
+		return errors.New("--with-key and --no-key cannot be used together")
+	}
+	if !envWithKey && cmd.Flags().Changed("ttl") {
+		return errors.New("--ttl is only used with --with-key")
+	}
+	if envKeyTTL <= 0 {
+		return errors.New("--ttl must be positive")
+	}
+	if envFish && envPowerShell {
+		return errors.New("--fish and --powershell cannot be used together")
+	}
+	shell := wallet.ShellPOSIX
+	if envFish {
+		shell = wallet.ShellFish
+	} else if envPowerShell {
+		shell = wallet.ShellPowerShell
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	alias := aliasFlag
+	if len(args) == 1 {
+		alias = args[0]
+	}
+	info, err := wc.GetWalletInfo(alias)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet info: %w", err)
+	}
+	endpoints, err := wc.Endpoints()
+	if err != nil {
+		return err
+	}
+
+	env := wallet.WalletEnv{Address: info.PublicKey, RPCURL: endpoints.RPC}
+	if envWithKey {
+		keypair, expires, err := wc.ExportTempKeypair(info.Alias, envKeyTTL)
+		if err != nil {
+			return fmt.Errorf("failed to export wallet: %w", err)
+		}
+		env.Keypair, env.Expires = keypair, &expires
+		// Everything but the lines goes to stderr, so it is seen when the output is evaluated.
+		color.New(color.FgRed, color.Bold).Fprintf(os.Stderr,
+			"WARNING: the key of %s (%s) is in %s until %s.\n"+
+				"Every program you run with this environment can spend from the wallet.\n",
+			info.Alias, info.PublicKey, env.Keypair, env.Expires.Format(time.Kitchen))
+	}
+
+	if jsonOutput() {
+		return printJSON(env)
+	}
+	fmt.Print(env.Format(shell))
+	return nil
+}
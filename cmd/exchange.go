@@ -3,26 +3,49 @@ package cmd
 import (
 	"fmt"
 	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
+	"time"
 )
 
 // exchangeCmd represents the exchange command
 var exchangeCmd = &cobra.Command{
-	Use:   "exchange",
-	Short: "Print the current exchange rate of SOL to EUR",
-	Long:  `This command fetches and prints the current exchange rate of SOL to EUR.`,
+	Use:         "exchange",
+	Short:       "Print the current exchange rate of SOL in the selected currency",
+	Annotations: keyAccess(keyAccessNone),
+	Long: `This command fetches and prints the current exchange rate of SOL in the currency selected with --currency
+(EUR unless another default was stored with "wallet currency").`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return PrintExchangeRate()
 	},
 }
 
+// rateOutput is the JSON form of the exchange rate.
+type rateOutput struct {
+	Rate      decimal.Decimal `json:"rate"`
+	Currency  wallet.Currency `json:"currency"`
+	Timestamp string          `json:"timestamp"`
+}
+
+// fetchExchangeRate fetches the rate for `exchange` (a package variable so tests can run offline).
+var fetchExchangeRate = (*wallet.ReadOnlyContext).FetchSOLRate
+
 func PrintExchangeRate() error {
-	wc := wallet.NewWalletConfig()
-	rate, err := wc.FetchSOLEURRate()
+	rc, err := newReadOnlyContext()
+	if err != nil {
+		return err
+	}
+
+	rate, err := fetchExchangeRate(rc)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Current exchange rate of SOL to EUR: %v\n", rate)
+
+	if jsonOutput() {
+		return printJSON(rateOutput{Rate: rate, Currency: rc.Currency, Timestamp: time.Now().UTC().Format(time.RFC3339)})
+	}
+
+	fmt.Printf("Current exchange rate of SOL to %s: %v\n", rc.Currency, rate)
 
 	return nil
 }
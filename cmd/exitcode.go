@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// Exit codes of failed commands, so scripts can branch on the cause instead of parsing the message.
+// They are stable: a code is never reused for another cause. ExitInputClosed and ExitBackupMismatch
+// are defined next to the errors they stand for.
+const (
+	// ExitError is the exit code of every failure without a code of its own.
+	ExitError = 1
+	// ExitInsufficientFunds means the wallet cannot cover the amount and the fee.
+	ExitInsufficientFunds = 5
+	// ExitInvalidRecipient means the recipient is neither a contact nor a valid address.
+	ExitInvalidRecipient = 6
+	// ExitInvalidAmount means the amount could not be read or is not allowed, such as a transfer below
+	// the rent-exempt minimum of a new account.
+	ExitInvalidAmount = 7
+	// ExitRPCUnavailable means no RPC endpoint could serve a request; trying again later may succeed.
+	ExitRPCUnavailable = 8
+	// ExitPassphrase means the key file passphrase was wrong or missing.
+	ExitPassphrase = 9
+	// ExitRefused means a safety setting refused the command: the spend limit, the allow-list or
+	// read-only mode.
+	ExitRefused = 10
+	// ExitKeyFileLocked means another sleeng process holds the key file.
+	ExitKeyFileLocked = 11
+	// ExitNotConfirmed means a transaction was sent but not confirmed in time. It may still land, so
+	// check its signature before sending again.
+	ExitNotConfirmed = 12
+)
+
+// exitCodes maps the errors that have an exit code of their own to it, checked in order.
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{ErrInputClosed, ExitInputClosed},
+	{ErrBackupMismatch, ExitBackupMismatch},
+	{wallet.ErrInsufficientFunds, ExitInsufficientFunds},
+	{wallet.ErrInvalidRecipient, ExitInvalidRecipient},
+	{wallet.ErrContactNotFound, ExitInvalidRecipient},
+	{wallet.ErrInvalidAmount, ExitInvalidAmount},
+	{wallet.ErrBelowRentExemption, ExitInvalidAmount},
+	{wallet.ErrRPCUnavailable, ExitRPCUnavailable},
+	{wallet.ErrWrongPassphrase, ExitPassphrase},
+	{wallet.ErrPassphraseRequired, ExitPassphrase},
+	{wallet.ErrSpendLimitExceeded, ExitRefused},
+	{wallet.ErrRecipientNotAllowed, ExitRefused},
+	{wallet.ErrReadOnlyMode, ExitRefused},
+	{wallet.ErrKeyFileLocked, ExitKeyFileLocked},
+	{wallet.ErrBlockhashExpired, ExitNotConfirmed},
+	{wallet.ErrConfirmTimeout, ExitNotConfirmed},
+}
+
+// ExitCode returns the exit code for the error a command failed with: the code of the first cause in
+// exitCodes it wraps, or ExitError.
+func ExitCode(err error) int {
+	for _, e := range exitCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return ExitError
+}
+
+// fatalf logs the message like log.Fatalf and exits with the exit code of err, for commands that stop
+// on the spot rather than returning their error.
+func fatalf(err error, format string, args ...interface{}) {
+	log.Printf(format, args...)
+	os.Exit(ExitCode(err))
+}
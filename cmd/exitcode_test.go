@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want int
+	}{
+		{errors.New("boom"), ExitError},
+		{fmt.Errorf("failed to send: %w", &wallet.InsufficientFundsError{Have: 1, Need: 2}), ExitInsufficientFunds},
+		{fmt.Errorf("%w: %q is neither a contact nor a valid address", wallet.ErrInvalidRecipient, "bob"), ExitInvalidRecipient},
+		{fmt.Errorf("failed to send: %w", wallet.ErrBelowRentExemption), ExitInvalidAmount},
+		{fmt.Errorf("get balance: %w", wallet.ErrRPCUnavailable), ExitRPCUnavailable},
+		{wallet.ErrWrongPassphrase, ExitPassphrase},
+		{fmt.Errorf("refused: %w", wallet.ErrReadOnlyMode), ExitRefused},
+		{wallet.ErrKeyFileLocked, ExitKeyFileLocked},
+		{fmt.Errorf("not confirmed: %w", wallet.ErrConfirmTimeout), ExitNotConfirmed},
+		{fmt.Errorf("aborted: %w", ErrInputClosed), ExitInputClosed},
+	} {
+		assert.Equal(t, tt.want, ExitCode(tt.err), tt.err.Error())
+	}
+}
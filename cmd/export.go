@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var exportCmd = &cobra.Command{
+	Use:         "export [alias]",
+	Short:       "Prints the private key of a wallet for backup",
+	Annotations: usesFlags(keyAccess(keyAccessPrivate), usesAlias),
+	Long: `Prints the private key of a wallet, by default the active one, so it can be backed up or
+imported elsewhere. Use --format bytes for the JSON byte array the Solana CLI reads, or base58 for
+browser wallets and --key. --keypair-file writes a solana-keygen keypair file instead, readable
+only by you. Anyone who sees the key can spend everything in the wallet.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: exportWallet,
+}
+
+var (
+	exportFormat      string
+	exportKeypairFile string
+	forceExport       bool
+)
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", string(wallet.ExportBase58), "Key format: base58 or bytes (Solana CLI)")
+	exportCmd.Flags().StringVar(&exportKeypairFile, "keypair-file", "", "Write a solana-keygen keypair file (like id.json) instead of printing the key")
+	exportCmd.Flags().BoolVar(&forceExport, "force", false, "Overwrite an existing --keypair-file")
+}
+
+func exportWallet(_ *cobra.Command, args []string) error {
+	format, err := wallet.ParseExportFormat(exportFormat)
+	if err != nil {
+		return err
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	alias := aliasFlag
+	if len(args) == 1 {
+		alias = args[0]
+	}
+	info, err := wc.GetWalletInfo(alias)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet info: %w", err)
+	}
+
+	if exportKeypairFile != "" {
+		if err := wc.ExportKeypairFile(info.Alias, exportKeypairFile, forceExport); err != nil {
+			if errors.Is(err, wallet.ErrKeypairFileExists) {
+				return fmt.Errorf("%w; pass --force to overwrite it", err)
+			}
+			return fmt.Errorf("failed to export wallet: %w", err)
+		}
+		printBlue("Wrote the keypair of %s (%s) to %s.\n", info.Alias, info.PublicKey, exportKeypairFile)
+		printWarning("Anyone who can read this file can spend from the wallet; keep it private.\n")
+		return nil
+	}
+
+	key, err := wc.ExportWallet(info.Alias, format)
+	if err != nil {
+		return fmt.Errorf("failed to export wallet: %w", err)
+	}
+
+	// The warning goes to stderr so it is seen even when the key is redirected to a file.
+	color.New(color.FgRed, color.Bold).Fprintf(os.Stderr,
+		"WARNING: the private key of %s (%s) follows. Anyone who sees it can take every token in the wallet.\n"+
+			"Do not paste it into websites, chats or screenshots, and clear your terminal history afterwards.\n",
+		info.Alias, info.PublicKey)
+	if info.Derivation.IsSeedDerived() {
+		printWarning("It was derived from a seed phrase along %s; the seed phrase restores it too.\n", info.Derivation)
+	}
+
+	fmt.Println(key)
+	return nil
+}
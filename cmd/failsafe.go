@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var failsafeCmd = &cobra.Command{
+	Use:   "failsafe",
+	Short: "Keeps a signed sweep of a wallet to a recovery address ready to broadcast",
+	Long: `A failsafe is a dead man's switch: a fully signed transfer of a wallet's whole balance to a
+recovery address, kept encrypted on disk instead of being sent. Whoever holds the failsafe
+passphrase can broadcast it with "wallet failsafe broadcast", without the wallet's private key.
+
+The sweep uses a durable nonce, whose authority must be the wallet, so it does not expire. Every
+send from the wallet advances that nonce, which makes the stored sweep invalid: it can never move a
+balance that was since spent. Run "wallet failsafe refresh" after sends and deposits, for example
+from cron, to sign the sweep again.`,
+}
+
+var failsafeArmCmd = &cobra.Command{
+	Use:         "arm [alias] --to [recovery] --nonce-account [address]",
+	Short:       "Signs a sweep of the wallet to a recovery address and stores it without sending it",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.ExactArgs(1),
+	RunE:        armFailsafe,
+}
+
+var failsafeBroadcastCmd = &cobra.Command{
+	Use:         "broadcast [alias]",
+	Short:       "Sends the stored sweep of a wallet to its recovery address",
+	Annotations: keyAccess(keyAccessPublic),
+	Args:        cobra.ExactArgs(1),
+	RunE:        broadcastFailsafe,
+}
+
+var failsafeRefreshCmd = &cobra.Command{
+	Use:         "refresh [alias...]",
+	Short:       "Signs the sweeps again whose balance changed or that a send invalidated",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.ArbitraryArgs,
+	RunE:        refreshFailsafes,
+}
+
+var failsafeDisarmCmd = &cobra.Command{
+	Use:         "disarm [alias]",
+	Short:       "Invalidates the stored sweep of a wallet and removes it",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.ExactArgs(1),
+	RunE:        disarmFailsafe,
+}
+
+var failsafeStatusCmd = &cobra.Command{
+	Use:         "status",
+	Short:       "Lists the stored sweeps",
+	Annotations: keyAccess(keyAccessPublic),
+	Args:        cobra.NoArgs,
+	RunE:        listFailsafes,
+}
+
+var (
+	failsafeTo           string
+	failsafeNonceAccount string
+	failsafeThreshold    string
+	failsafeYes          bool
+)
+
+func init() {
+	failsafeArmCmd.Flags().StringVar(&failsafeTo, "to", "", "Recovery address, contact or wallet alias the sweep sends to")
+	failsafeArmCmd.Flags().StringVar(&failsafeNonceAccount, "nonce-account", "", "Durable nonce account whose authority is the wallet")
+	_ = failsafeArmCmd.MarkFlagRequired("to")
+	_ = failsafeArmCmd.MarkFlagRequired("nonce-account")
+	failsafeRefreshCmd.Flags().StringVar(&failsafeThreshold, "threshold", wallet.DefaultFailsafeThreshold.String(), "Sign again when the balance moved by more than this many percent")
+	failsafeBroadcastCmd.Flags().BoolVarP(&failsafeYes, "yes", "y", false, "Broadcast without asking for confirmation")
+
+	failsafeCmd.AddCommand(failsafeArmCmd, failsafeBroadcastCmd, failsafeRefreshCmd, failsafeDisarmCmd, failsafeStatusCmd)
+}
+
+// failsafePassphrase asks for the passphrase of the sweep of alias, twice when it is new.
+func failsafePassphrase(alias string, isNew bool) (string, error) {
+	prompt, _ := passphrasePrompt()
+	return prompt("the failsafe of "+alias, isNew)
+}
+
+func armFailsafe(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := failsafePassphrase(args[0], true)
+	if err != nil {
+		return err
+	}
+
+	failsafe, err := wc.ArmFailsafe(context.Background(), args[0], failsafeTo, failsafeNonceAccount, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to arm the failsafe: %w", err)
+	}
+
+	printBlue("Failsafe armed: %s SOL from %s to %s.\n", failsafe.SOL(), args[0], failsafe.To)
+	printWarning("Keep the failsafe passphrase with whoever should be able to broadcast it. Sends from %s invalidate the sweep; run `wallet failsafe refresh` after them.\n", args[0])
+	return nil
+}
+
+func broadcastFailsafe(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	if !failsafeYes {
+		confirmed, err := promptForConfirmation(fmt.Sprintf("Sweep the whole balance of %s to its recovery address", args[0]))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Broadcast cancelled.")
+			return nil
+		}
+	}
+
+	passphrase, err := failsafePassphrase(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	failsafe, err := wc.BroadcastFailsafe(context.Background(), args[0], passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to broadcast the failsafe: %w", err)
+	}
+
+	printBlue("Swept %s SOL from %s to %s. Signature: %s\n", failsafe.SOL(), args[0], failsafe.To, failsafe.Signature)
+	return nil
+}
+
+func refreshFailsafes(_ *cobra.Command, args []string) error {
+	threshold, err := decimal.NewFromString(failsafeThreshold)
+	if err != nil || threshold.IsNegative() {
+		return fmt.Errorf("invalid --threshold %q: give a percentage such as 1 or 2.5", failsafeThreshold)
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	aliases := args
+	if len(aliases) == 0 {
+		failsafes, err := wc.Failsafes()
+		if err != nil {
+			return fmt.Errorf("failed to read the failsafes: %w", err)
+		}
+		for _, failsafe := range failsafes {
+			if failsafe.Status != wallet.FailsafeBroadcast {
+				aliases = append(aliases, failsafe.Alias)
+			}
+		}
+	}
+	if len(aliases) == 0 {
+		fmt.Println("No failsafe to refresh. Arm one with `wallet failsafe arm`.")
+		return nil
+	}
+
+	for _, alias := range aliases {
+		passphrase, err := failsafePassphrase(alias, false)
+		if err != nil {
+			return err
+		}
+
+		failsafe, changed, err := wc.RefreshFailsafe(context.Background(), alias, passphrase, threshold)
+		if err != nil {
+			return fmt.Errorf("failed to refresh the failsafe of %s: %w", alias, err)
+		}
+		if changed {
+			printBlue("%s: signed again, now sweeping %s SOL to %s.\n", alias, failsafe.SOL(), failsafe.To)
+		} else {
+			fmt.Printf("%s: up to date, sweeping %s SOL to %s.\n", alias, failsafe.SOL(), failsafe.To)
+		}
+	}
+	return nil
+}
+
+func disarmFailsafe(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := wc.DisarmFailsafe(context.Background(), args[0]); err != nil {
+		return fmt.Errorf("failed to disarm the failsafe: %w", err)
+	}
+
+	printBlue("Failsafe of %s disarmed; copies of the sweep can no longer be broadcast.\n", args[0])
+	return nil
+}
+
+func listFailsafes(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	failsafes, err := wc.Failsafes()
+	if err != nil {
+		return fmt.Errorf("failed to read the failsafes: %w", err)
+	}
+
+	if jsonOutput() {
+		return printJSON(failsafes)
+	}
+	if len(failsafes) == 0 {
+		fmt.Println("No failsafes. Arm one with `wallet failsafe arm`.")
+		return nil
+	}
+	for _, failsafe := range failsafes {
+		fmt.Printf(
+			"Alias: %s\nStatus: %s\nTo: %s\nAmount: %s SOL\nNonce Account: %s\nNetwork: %s\nSigned: %s\n---\n",
+			failsafe.Alias,
+			failsafe.Status,
+			failsafe.To,
+			failsafe.SOL(),
+			failsafe.NonceAccount,
+			failsafe.Network,
+			failsafe.SignedAt.Format(time.RFC3339),
+		)
+	}
+	return nil
+}
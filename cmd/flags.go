@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// flagsAnnotation lists how a command uses the persistent flags that only some commands honour.
+// Persistent flags are accepted by every command, so checkFlagContract turns one the command would
+// silently ignore into an error.
+const flagsAnnotation = "flags"
+
+const (
+	// usesAlias commands act on the stored wallet named by --alias, which must exist.
+	usesAlias = "alias"
+	// usesNewAlias commands store a new wallet under --alias, which must not be taken.
+	usesNewAlias = "new-alias"
+	// usesKey commands import the private key given with --key.
+	usesKey = "key"
+	// usesTransientKey commands act on the private key given with --key for this run only, and offer
+	// to store it afterwards or store it as --save-as.
+	usesTransientKey = "transient-key"
+)
+
+// scopedFlags maps the persistent flags only some commands honour to the uses that honour them.
+var scopedFlags = []struct {
+	name string
+	uses []string
+}{
+	{name: "alias", uses: []string{usesAlias, usesNewAlias}},
+	{name: "key", uses: []string{usesKey, usesTransientKey}},
+	{name: "save-as", uses: []string{usesTransientKey}},
+}
+
+// usesFlags adds the uses of scoped flags to a command's annotations.
+func usesFlags(annotations map[string]string, uses ...string) map[string]string {
+	annotations[flagsAnnotation] = strings.Join(uses, ",")
+	return annotations
+}
+
+// flagUses returns the uses of scoped flags a command is annotated with.
+func flagUses(cmd *cobra.Command) []string {
+	if cmd.Annotations[flagsAnnotation] == "" {
+		return nil
+	}
+	return strings.Split(cmd.Annotations[flagsAnnotation], ",")
+}
+
+// hasUse reports whether cmd is annotated with any of uses.
+func hasUse(cmd *cobra.Command, uses ...string) bool {
+	for _, use := range flagUses(cmd) {
+		for _, wanted := range uses {
+			if use == wanted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkFlagContract fails when a scoped flag is given to a command that would ignore it.
+func checkFlagContract(cmd *cobra.Command) error {
+	for _, flag := range scopedFlags {
+		if cmd.Flags().Changed(flag.name) && !hasUse(cmd, flag.uses...) {
+			return fmt.Errorf("--%s has no effect on '%s'", flag.name, strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "))
+		}
+	}
+	return nil
+}
+
+// validateAliasFlag resolves --alias from the key file before the command runs, so a mistyped
+// alias fails before any RPC or exchange rate request is made.
+func validateAliasFlag(cmd *cobra.Command) error {
+	if aliasFlag == "" || !hasUse(cmd, usesAlias, usesNewAlias) {
+		return nil
+	}
+
+	_, wc, err := profileWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	if hasUse(cmd, usesNewAlias) {
+		err = wc.CheckAliasFree(aliasFlag)
+	} else {
+		_, err = wc.GetWalletInfo(aliasFlag)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid --alias: %w", err)
+	}
+	return nil
+}
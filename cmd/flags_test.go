@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// resetScopedFlags clears the scoped flags after RootCmd ran, as cobra keeps a flag marked as
+// changed across executions.
+func resetScopedFlags() {
+	aliasFlag, privateKeyFlag, saveAsFlag = "", "", ""
+	transientWallet = nil
+	for _, flag := range scopedFlags {
+		RootCmd.PersistentFlags().Lookup(flag.name).Changed = false
+	}
+}
+
+func TestEveryFlagUseIsKnown(t *testing.T) {
+	known := make(map[string]bool)
+	for _, flag := range scopedFlags {
+		for _, use := range flag.uses {
+			known[use] = true
+		}
+	}
+
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, child := range c.Commands() {
+			for _, use := range flagUses(child) {
+				assert.True(t, known[use], "%s uses unknown flag use %q", child.CommandPath(), use)
+			}
+			walk(child)
+		}
+	}
+	walk(RootCmd)
+}
+
+func TestFlagContract(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(wallet.ConfigDirEnv, configDir)
+	keyFile := `{"activeAlias":"main","wallets":{"main":{"publicKey":"Addr1"}},"contacts":{"alice":"Addr2"}}`
+	assert.NoError(t, os.WriteFile(filepath.Join(configDir, wallet.KeyFileName), []byte(keyFile), 0600))
+
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(cwd)
+
+	defer resetScopedFlags()
+	defer RootCmd.SetArgs(nil)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "alias on exchange", args: []string{"exchange", "--alias", "main"}, wantErr: "--alias has no effect on 'exchange'"},
+		{name: "alias on send", args: []string{"send", "--alias", "main", "Addr2", "1"}, wantErr: "--alias has no effect on 'send'"},
+		{name: "alias on subcommand", args: []string{"contacts", "list", "-a", "main"}, wantErr: "--alias has no effect on 'contacts list'"},
+		{name: "key on exchange", args: []string{"exchange", "--key", "abc"}, wantErr: "--key has no effect on 'exchange'"},
+		{name: "save-as on init", args: []string{"init", "--save-as", "spare"}, wantErr: "--save-as has no effect on 'init'"},
+		{name: "save-as without key", args: []string{"balance", "--save-as", "spare"}, wantErr: "--save-as needs the private key given with --key"},
+		{name: "alias with key", args: []string{"balance", "--key", "abc", "--alias", "main"}, wantErr: "--alias cannot be combined with --key, which already names the wallet"},
+		{name: "unknown alias on balance", args: []string{"balance", "--alias", "mian"}, wantErr: "invalid --alias: no wallet found for alias: mian"},
+		{name: "unknown alias on request", args: []string{"request", "export", "--alias", "mian", "1"}, wantErr: "invalid --alias: no wallet found for alias: mian"},
+		{name: "taken alias on init", args: []string{"init", "--alias", "main"}, wantErr: "invalid --alias: alias already exists: main"},
+		{name: "contact name on add-watch", args: []string{"add-watch", "--alias", "alice", "Addr3"}, wantErr: "invalid --alias: alice is already the name of a contact"},
+		{name: "known alias on address", args: []string{"address", "--alias", "main"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer resetScopedFlags()
+			RootCmd.SetArgs(tt.args)
+			err := RootCmd.Execute()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Equal(t, tt.wantErr, strings.TrimSpace(err.Error()))
+			}
+		})
+	}
+}
+
+func TestClusterIsAnotherNameForNetwork(t *testing.T) {
+	defer func() {
+		networkFlag = ""
+		RootCmd.PersistentFlags().Lookup("network").Changed = false
+	}()
+
+	assert.NoError(t, RootCmd.ParseFlags([]string{"--cluster", "localnet"}))
+	assert.Equal(t, "localnet", networkFlag)
+}
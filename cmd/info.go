@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/spf13/cobra"
+)
+
+var infoCmd = &cobra.Command{
+	Use:         "info",
+	Short:       "Prints details about the active wallet, or the one given with --alias",
+	Annotations: usesFlags(keyAccess(keyAccessPublic), usesAlias),
+	RunE:        displayInfo,
+}
+
+var verifyDerivationCmd = &cobra.Command{
+	Use:         "verify-derivation [alias]",
+	Short:       "Re-derives a stored wallet from its seed phrase and checks it matches",
+	Annotations: keyAccess(keyAccessPublic),
+	Args:        cobra.ExactArgs(1),
+	RunE:        verifyDerivation,
+}
+
+func init() {
+	verifyDerivationCmd.Flags().BoolVar(&withPassphrase, "with-passphrase", false, "Prompt for the BIP-39 passphrase of the seed phrase")
+}
+
+func displayInfo(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	info, err := wc.GetWalletInfo(aliasFlag)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet info: %w", err)
+	}
+
+	derivation := "not derived from a seed phrase"
+	if info.Derivation.IsSeedDerived() {
+		derivation = info.Derivation.String()
+	}
+
+	printBlue("Alias: %s\nPublic Key: %s\nActive: %t\nEncrypted: %t\nWatch-only: %t\nOS keychain: %t\nDerivation: %s\n",
+		info.Alias,
+		info.PublicKey,
+		info.Active,
+		info.Encrypted,
+		info.WatchOnly,
+		info.Keychain,
+		derivation,
+	)
+	if label := info.Note.Label(); label != "" {
+		printBlue("Note: %s\n", label)
+	}
+	printBlue("Usage: %s\n", info.Usage)
+	return nil
+}
+
+func verifyDerivation(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	info, err := wc.GetWalletInfo(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet info: %w", err)
+	}
+	if !info.Derivation.IsSeedDerived() {
+		return fmt.Errorf("%s: %w", args[0], wallet.ErrNotSeedDerived)
+	}
+
+	seedPhrase, err := promptForSecret("Please enter the seed phrase of " + args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get seed phrase: %w", err)
+	}
+
+	var passphrase string
+	if info.Derivation.Passphrase || withPassphrase {
+		passphrase, err = promptForSecret("Please enter the BIP-39 passphrase of the seed phrase")
+		if err != nil {
+			return fmt.Errorf("failed to get seed passphrase: %w", err)
+		}
+	}
+
+	matched, err := wc.VerifyDerivation(args[0], seedPhrase, passphrase)
+	if errors.Is(err, wallet.ErrDerivationMismatch) {
+		return fmt.Errorf("seed phrase does NOT match %s (%s)", args[0], info.PublicKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify derivation: %w", err)
+	}
+
+	printBlue("Seed phrase matches %s (%s) along %s.\n", args[0], info.PublicKey, matched)
+	return nil
+}
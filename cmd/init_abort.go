@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// initFlow tracks what a run of init has done, so that a run stopped part way, by ^C at a prompt, an
+// interrupt between steps or an error, can say what it left behind.
+type initFlow struct {
+	// ctx is cancelled by an interrupt; the flow checks it between steps.
+	ctx context.Context
+	// unannounced holds the addresses of wallets written to the key file that were not printed yet.
+	unannounced []string
+	// unverifiedSeed is the address of a new paper wallet whose seed phrase was shown but not verified.
+	unverifiedSeed string
+	// settled is set once the wallet of the run is ready, after which stopping leaves nothing behind.
+	settled bool
+}
+
+// seedCheckWord picks which word of a new seed phrase the user re-enters to verify it.
+var seedCheckWord = func(words int) int {
+	return rand.Intn(words)
+}
+
+// checkpoint stops the flow once it has been interrupted.
+func (f *initFlow) checkpoint() error {
+	if err := f.ctx.Err(); err != nil {
+		return fmt.Errorf("init interrupted: %w", err)
+	}
+	return nil
+}
+
+// saved records a wallet written to the key file, until announced reports it to the user.
+func (f *initFlow) saved(address string) {
+	f.unannounced = append(f.unannounced, address)
+}
+
+func (f *initFlow) announced(address string) {
+	f.settled = true
+	for i, unannounced := range f.unannounced {
+		if unannounced == address {
+			f.unannounced = append(f.unannounced[:i], f.unannounced[i+1:]...)
+			return
+		}
+	}
+}
+
+// verifySeed asks for a random word of a seed phrase that was just shown, so the user has proof that
+// their copy is right before funding it.
+func (f *initFlow) verifySeed(address, seed string) error {
+	f.unverifiedSeed = address
+	if err := f.checkpoint(); err != nil {
+		return err
+	}
+
+	words := strings.Fields(seed)
+	i := seedCheckWord(len(words))
+	_, err := promptForInput(fmt.Sprintf("To confirm you wrote the seed phrase down, enter word #%d", i+1), func(input string) error {
+		if strings.TrimSpace(strings.ToLower(input)) != words[i] {
+			return errors.New("that is not the word in your seed phrase")
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify seed phrase: %w", err)
+	}
+
+	f.unverifiedSeed = ""
+	printBlue("Seed phrase verified.\n")
+	return nil
+}
+
+// report prints what a flow that ended with err left behind: wallets that were saved without being
+// announced, and how to check a seed phrase that was never verified.
+func (f *initFlow) report(out io.Writer, wc *wallet.WalletConfig, err error) {
+	if err == nil {
+		return
+	}
+
+	warn := color.New(color.FgYellow)
+	for _, address := range f.unannounced {
+		warn.Fprintf(out, "Init stopped, but wallet %s was saved to the key file. Its address is %s.\n", savedAlias(wc, address), address)
+	}
+	if f.unverifiedSeed != "" {
+		warn.Fprintf(out, "Init stopped before the seed phrase of %s was verified. Do not fund it until you have checked your copy: "+
+			"run `wallet init --paper`, choose Import and enter the phrase; it must derive %s. If you did not write it down, discard it.\n",
+			f.unverifiedSeed, f.unverifiedSeed)
+	}
+
+	aborted := errors.Is(err, ErrInputClosed) || errors.Is(err, context.Canceled)
+	if aborted && !f.settled && len(f.unannounced) == 0 && f.unverifiedSeed == "" {
+		warn.Fprintf(out, "Init stopped; no new wallet was saved.\n")
+	}
+}
+
+// savedAlias returns the alias a wallet was saved under, which is random when none was given, or the
+// address itself when it cannot be found.
+func savedAlias(wc *wallet.WalletConfig, address string) string {
+	infos, err := wc.ListWallets()
+	if err != nil {
+		return address
+	}
+	for _, info := range infos {
+		if info.PublicKey == address {
+			return info.Alias
+		}
+	}
+	return address
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// interruptingKeyStore interrupts the flow right after every write to the key file.
+type interruptingKeyStore struct {
+	wallet.KeyStore
+	interrupt context.CancelFunc
+}
+
+func (k *interruptingKeyStore) WriteKeyToFile(alias string, key ed25519.PrivateKey, walletAddress string) error {
+	defer k.interrupt()
+	return k.KeyStore.WriteKeyToFile(alias, key, walletAddress)
+}
+
+func (k *interruptingKeyStore) WriteDerivedKeyToFile(alias string, key ed25519.PrivateKey, walletAddress string, derivation wallet.KeyDerivation) error {
+	defer k.interrupt()
+	return k.KeyStore.WriteDerivedKeyToFile(alias, key, walletAddress, derivation)
+}
+
+func TestInterruptedInitReportsWhatWasSaved(t *testing.T) {
+	skipUnderRace(t)
+	defer func(alias string, index int, save bool) {
+		aliasFlag, accountIndex, saveSeedWallet = alias, index, save
+	}(aliasFlag, accountIndex, saveSeedWallet)
+
+	seedAddress := regexp.MustCompile(`New Wallet Created\. Your Address Is: (\w+)`)
+
+	tests := []struct {
+		name string
+		// interruptAfterWrite cancels the flow once the key file has been written.
+		interruptAfterWrite bool
+		interruptAtStart    bool
+		input               string
+		run                 func(flow *initFlow, wc *wallet.WalletConfig) error
+		wantErr             error
+		wantSaved           string
+		wantReport          string
+	}{
+		{
+			name:             "interrupted before the wallet is written",
+			interruptAtStart: true,
+			run: func(flow *initFlow, wc *wallet.WalletConfig) error {
+				return createNewFileBasedWallet(flow, wc, "main", "")
+			},
+			wantErr:    context.Canceled,
+			wantReport: "Init stopped; no new wallet was saved.",
+		},
+		{
+			name: "input closed at the alias prompt",
+			run: func(flow *initFlow, wc *wallet.WalletConfig) error {
+				return createNewFileBasedWallet(flow, wc, "", "")
+			},
+			wantErr:    ErrInputClosed,
+			wantReport: "Init stopped; no new wallet was saved.",
+		},
+		{
+			name:                "interrupted after the wallet is written",
+			interruptAfterWrite: true,
+			input:               "main\r",
+			run: func(flow *initFlow, wc *wallet.WalletConfig) error {
+				return createNewFileBasedWallet(flow, wc, "", "")
+			},
+			wantErr:    context.Canceled,
+			wantSaved:  "main",
+			wantReport: "Init stopped, but wallet main was saved to the key file.",
+		},
+		{
+			name: "input closed before the new seed is verified",
+			run: func(flow *initFlow, wc *wallet.WalletConfig) error {
+				return createNewPaperWallet(flow, wc)
+			},
+			wantErr:    ErrInputClosed,
+			wantReport: "Init stopped before the seed phrase of",
+		},
+		{
+			name:                "interrupted after an imported seed is saved",
+			interruptAfterWrite: true,
+			input:               "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about\r",
+			run: func(flow *initFlow, wc *wallet.WalletConfig) error {
+				aliasFlag, accountIndex, saveSeedWallet = "paper", 0, true
+				return importExistingPaperWallet(flow, wc)
+			},
+			wantErr:    context.Canceled,
+			wantSaved:  "paper",
+			wantReport: "Init stopped, but wallet paper was saved to the key file.",
+		},
+		{
+			name:  "input closed after the wallet is announced",
+			input: "main\r",
+			run: func(flow *initFlow, wc *wallet.WalletConfig) error {
+				if err := createNewFileBasedWallet(flow, wc, "", ""); err != nil {
+					return err
+				}
+				return postWalletInitializationActions(flow, wc)
+			},
+			wantErr:   ErrInputClosed,
+			wantSaved: "main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var printed bytes.Buffer
+			output := color.Output
+			color.Output = &printed
+			defer func() { color.Output = output }()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.interruptAtStart {
+				cancel()
+			}
+
+			wc := wallet.NewWalletConfigInDir(t.TempDir())
+			keyStore := wc.KeyOps
+			if tt.interruptAfterWrite {
+				wc.KeyOps = &interruptingKeyStore{KeyStore: keyStore, interrupt: cancel}
+			}
+			withStdin(t, tt.input)
+
+			flow := &initFlow{ctx: ctx}
+			err := tt.run(flow, wc)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			var report bytes.Buffer
+			flow.report(&report, wc, err)
+			if tt.wantReport == "" {
+				assert.Empty(t, report.String())
+			} else {
+				assert.Contains(t, report.String(), tt.wantReport)
+			}
+			if match := seedAddress.FindStringSubmatch(printed.String()); match != nil && flow.unverifiedSeed != "" {
+				assert.Contains(t, report.String(), "it must derive "+match[1])
+			}
+
+			wc.KeyOps = keyStore
+			if tt.wantSaved == "" {
+				hasWallets, err := wc.HasWallets()
+				assert.NoError(t, err)
+				assert.False(t, hasWallets)
+				return
+			}
+			aliases, err := keyStore.ListAliases()
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.wantSaved}, aliases)
+			info, err := keyStore.GetWalletInfo(tt.wantSaved)
+			assert.NoError(t, err)
+			if tt.wantReport != "" {
+				assert.Contains(t, report.String(), "Its address is "+info.PublicKey)
+			}
+		})
+	}
+}
+
+func TestNewSeedIsVerified(t *testing.T) {
+	skipUnderRace(t)
+	defer func(pick func(int) int) { seedCheckWord = pick }(seedCheckWord)
+	seedCheckWord = func(int) int { return 2 }
+
+	flow := &initFlow{ctx: context.Background()}
+	seed := "legal winner thank year wave sausage worth useful legal winner thank yellow"
+
+	// A wrong word is refused until the right one is typed.
+	withStdin(t, "winner\r"+strings.Repeat("\x7f", len("winner"))+"THANK\r")
+	assert.NoError(t, flow.verifySeed("address", seed))
+	assert.Empty(t, flow.unverifiedSeed)
+
+	withStdin(t, "winner\r")
+	assert.ErrorIs(t, flow.verifySeed("address", seed), ErrInputClosed)
+	assert.Equal(t, "address", flow.unverifiedSeed)
+}
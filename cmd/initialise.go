@@ -2,25 +2,40 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Ghvstcode/sleeng/pkg/wallet"
 	"github.com/atotto/clipboard"
 	"github.com/fatih/color"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
-	"log"
+	"os"
+	"os/signal"
 	"sort"
 	"strconv"
 	"strings"
 )
 
 var InitCmd = &cobra.Command{
-	Use:   "init",
-	Short: "Creates a new Solana wallet and saves the private key to disk",
-	RunE:  initializeWallet,
+	Use:         "init",
+	Short:       "Creates a new Solana wallet and saves the private key to disk",
+	Annotations: usesFlags(keyAccess(keyAccessPrivate), usesNewAlias, usesKey),
+	RunE:        initializeWallet,
 }
 
-var isPaperBased bool
+var (
+	isPaperBased     bool
+	legacyDerivation bool
+	withPassphrase   bool
+	accountIndex     int
+	derivationPath   string
+	saveSeedWallet   bool
+	fromKeypair      string
+	fromQR           string
+)
+
+// scannedAccounts is how many seed accounts are offered when importing without an explicit account.
+const scannedAccounts = 5
 
 var templates = &promptui.SelectTemplates{
 	Label:    "{{ . | cyan }} ",
@@ -29,6 +44,13 @@ var templates = &promptui.SelectTemplates{
 
 func init() {
 	InitCmd.Flags().BoolVarP(&isPaperBased, "paper", "p", false, "Create a paper-based wallet with seed phrase instead of saving private key to disk")
+	InitCmd.Flags().BoolVar(&legacyDerivation, "legacy-derivation", false, "Import a paper wallet created by older sleeng versions using the old, non-standard derivation")
+	InitCmd.Flags().BoolVar(&withPassphrase, "with-passphrase", false, "Prompt for the optional BIP-39 passphrase of the seed phrase")
+	InitCmd.Flags().IntVar(&accountIndex, "account-index", -1, "Account index to import, derived along m/44'/501'/<index>'/0' (default: choose from the first few)")
+	InitCmd.Flags().StringVar(&derivationPath, "derivation-path", "", "Explicit hardened derivation path to import, e.g. m/44'/501'/2'/0'")
+	InitCmd.Flags().BoolVar(&saveSeedWallet, "save", false, "Also store the seed-derived key and its derivation path in the key file, under --alias")
+	InitCmd.Flags().StringVar(&fromKeypair, "from-keypair", "", "Import a solana-keygen keypair file such as ~/.config/solana/id.json, under --alias")
+	InitCmd.Flags().StringVar(&fromQR, "from-qr", "", "Import the private key, seed phrase or address in the QR code of an image file, such as a photo of a phone screen")
 }
 
 func printBlue(msg string, args ...interface{}) {
@@ -36,57 +58,191 @@ func printBlue(msg string, args ...interface{}) {
 	blue.Printf(msg, args...)
 }
 
+// copyToClipboard copies text to the clipboard and returns a note saying so for the message showing
+// it. Read-only mode leaves the clipboard alone and the note is empty.
+func copyToClipboard(text string) string {
+	if wallet.ReadOnlyMode() {
+		return ""
+	}
+	if err := clipboard.WriteAll(text); err != nil {
+		return ""
+	}
+	return " (copied to clipboard)"
+}
+
+func printYellow(msg string, args ...interface{}) {
+	yellow := color.New(color.FgYellow)
+	yellow.Printf(msg, args...)
+}
+
 func initializeWallet(_ *cobra.Command, _ []string) error {
-	wc := wallet.NewWalletConfig()
-	if isPaperBased {
-		return handlePaperBasedWallet(wc)
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
 	}
-	return handleFileBasedWallet(wc)
+	if isPaperBased && fromKeypair != "" {
+		return errors.New("--from-keypair imports into the key file and cannot be combined with --paper")
+	}
+	if fromQR != "" && (isPaperBased || fromKeypair != "" || privateKeyFlag != "") {
+		return errors.New("--from-qr detects what to import and cannot be combined with --paper, --from-keypair or --private-key")
+	}
+
+	// An interrupt between prompts stops the flow at its next step instead of killing the process,
+	// so what was already saved can be reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	flow := &initFlow{ctx: ctx}
+	if fromQR != "" {
+		err = importFromQR(flow, wc, fromQR)
+	} else if isPaperBased {
+		err = handlePaperBasedWallet(flow, wc)
+	} else {
+		err = handleFileBasedWallet(flow, wc)
+	}
+	flow.report(os.Stderr, wc, err)
+	return err
 }
 
-func handlePaperBasedWallet(wc *wallet.WalletConfig) error {
+func handlePaperBasedWallet(flow *initFlow, wc *wallet.WalletConfig) error {
 	choice, err := promptForChoice("Do you want to create a new paper-based wallet or import an existing one?", []string{"New", "Import"})
 	if err != nil {
 		return fmt.Errorf("failed to get user choice: %w", err)
 	}
 	switch choice {
 	case "New":
-		return createNewPaperWallet(wc)
+		return createNewPaperWallet(flow, wc)
 	case "Import":
-		return importExistingPaperWallet(wc)
+		return importExistingPaperWallet(flow, wc)
 	default:
 		return fmt.Errorf("invalid choice: %s", choice)
 	}
 }
 
-func createNewPaperWallet(wc *wallet.WalletConfig) error {
+func createNewPaperWallet(flow *initFlow, wc *wallet.WalletConfig) error {
+	if err := flow.checkpoint(); err != nil {
+		return err
+	}
 	seed, walletAddr, err := wc.GenerateNewPaperWallet()
 	if err != nil {
 		return fmt.Errorf("failed to generate new paper wallet: %w", err)
 	}
-	clipboard.WriteAll(walletAddr)
-	printBlue("New Wallet Created. Your Address Is: %s (copied to clipboard)\n", walletAddr)
+	printBlue("New Wallet Created. Your Address Is: %s%s\n", walletAddr, copyToClipboard(walletAddr))
 	printBlue("Seed Phrase (keep this safe): %s\n", seed)
-	return postWalletInitializationActions(wc)
+	if err := flow.verifySeed(walletAddr, seed); err != nil {
+		return err
+	}
+	if err := saveSeedWalletIfRequested(flow, wc); err != nil {
+		return err
+	}
+	return postWalletInitializationActions(flow, wc)
 }
 
-func importExistingPaperWallet(wc *wallet.WalletConfig) error {
+func importExistingPaperWallet(flow *initFlow, wc *wallet.WalletConfig) error {
 	seedPhrase, err := promptForInput("Please enter your existing seed phrase:", wc.IsValidSeed)
 	if err != nil {
 		return fmt.Errorf("failed to get seed phrase: %w", err)
 	}
-	address, err := wc.ImportWalletFromSeed(seedPhrase)
+	return importSeedPhrase(flow, wc, seedPhrase)
+}
+
+// importSeedPhrase imports the wallet of a seed phrase for this session, and into the key file with --save.
+func importSeedPhrase(flow *initFlow, wc *wallet.WalletConfig, seedPhrase string) error {
+	var err error
+	opts := wallet.SeedOptions{
+		DerivationPath: derivationPath,
+		Legacy:         legacyDerivation,
+	}
+
+	if withPassphrase {
+		opts.Passphrase, err = promptForSecret("Please enter the BIP-39 passphrase of the seed phrase")
+		if err != nil {
+			return fmt.Errorf("failed to get seed passphrase: %w", err)
+		}
+	}
+
+	// Without an explicit account, show the first few accounts so the funded one can be picked.
+	switch {
+	case accountIndex >= 0:
+		opts.AccountIndex = uint32(accountIndex)
+	case !legacyDerivation && derivationPath == "":
+		opts.AccountIndex, err = chooseSeedAccount(flow, wc, seedPhrase, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := flow.checkpoint(); err != nil {
+		return err
+	}
+	address, err := wc.ImportWalletFromSeedWithOptions(seedPhrase, opts)
 	if err != nil {
 		return fmt.Errorf("failed to import wallet: %w", err)
 	}
-	clipboard.WriteAll(address)
-	printBlue("New Wallet Created. Your Address Is: %s (copied to clipboard)\n", address)
-	return postWalletInitializationActions(wc)
+	printBlue("New Wallet Created. Your Address Is: %s%s\n", address, copyToClipboard(address))
+	if err := saveSeedWalletIfRequested(flow, wc); err != nil {
+		return err
+	}
+	return postWalletInitializationActions(flow, wc)
+}
+
+// saveSeedWalletIfRequested stores the seed-derived wallet in the key file when --save is set.
+func saveSeedWalletIfRequested(flow *initFlow, wc *wallet.WalletConfig) error {
+	if !saveSeedWallet {
+		return nil
+	}
+	if err := flow.checkpoint(); err != nil {
+		return err
+	}
+
+	if err := wc.SaveSeedWallet(aliasFlag); err != nil {
+		return fmt.Errorf("failed to save wallet: %w", err)
+	}
+	address := wc.Wallet.PublicKey().String()
+	flow.saved(address)
+	if err := flow.checkpoint(); err != nil {
+		return err
+	}
+
+	printBlue("Wallet saved to the key file with its derivation path.\n")
+	flow.announced(address)
+	return nil
+}
+
+func chooseSeedAccount(flow *initFlow, wc *wallet.WalletConfig, seedPhrase string, opts wallet.SeedOptions) (uint32, error) {
+	accounts, err := wc.ScanSeedAccounts(flow.ctx, seedPhrase, opts, scannedAccounts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to derive accounts: %w", err)
+	}
+
+	items := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		balance := account.Balance.String() + " SOL"
+		if account.BalanceErr != nil {
+			balance = "balance unavailable"
+		}
+		items = append(items, fmt.Sprintf("#%d %s %s (%s)", account.Index, account.Path, account.PublicKey, balance))
+	}
+
+	choice, err := promptForChoice("Choose the account to import", items)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user choice: %w", err)
+	}
+
+	for i, item := range items {
+		if item == choice {
+			return accounts[i].Index, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid choice: %s", choice)
 }
 
-func handleFileBasedWallet(wc *wallet.WalletConfig) error {
+func handleFileBasedWallet(flow *initFlow, wc *wallet.WalletConfig) error {
+	if fromKeypair != "" {
+		return importKeypairFile(flow, wc, aliasFlag, fromKeypair)
+	}
 	if privateKeyFlag != "" {
-		return createNewFileBasedWallet(wc, aliasFlag, privateKeyFlag)
+		return createNewFileBasedWallet(flow, wc, aliasFlag, privateKeyFlag)
 	}
 
 	hasWallets, err := wc.HasWallets()
@@ -95,12 +251,12 @@ func handleFileBasedWallet(wc *wallet.WalletConfig) error {
 	}
 
 	if hasWallets {
-		return handleExistingWallets(wc)
+		return handleExistingWallets(flow, wc)
 	}
-	return createNewFileBasedWallet(wc, aliasFlag, "")
+	return createNewFileBasedWallet(flow, wc, aliasFlag, "")
 }
 
-func handleExistingWallets(wc *wallet.WalletConfig) error {
+func handleExistingWallets(flow *initFlow, wc *wallet.WalletConfig) error {
 	choice, err := promptForChoice("You already have an existing wallet with keys saved on this computer! Select an option below", []string{"Select Existing Wallet", "Create New Wallet"})
 	if err != nil {
 		return fmt.Errorf("failed to get user choice: %w", err)
@@ -110,7 +266,7 @@ func handleExistingWallets(wc *wallet.WalletConfig) error {
 	case "Select Existing Wallet":
 		return selectExistingWallet(wc)
 	case "Create New Wallet":
-		return createNewFileBasedWallet(wc, "", "")
+		return createNewFileBasedWallet(flow, wc, "", "")
 	default:
 		return fmt.Errorf("invalid choice: %s", choice)
 	}
@@ -122,7 +278,7 @@ func selectExistingWallet(wc *wallet.WalletConfig) error {
 		return fmt.Errorf("failed to retrieve existing wallets: %w", err)
 	}
 
-	selectedWallet, err := promptForChoice("Choose From Your List Of Existing Wallets", aliases)
+	selectedWallet, err := promptForSearchableChoice("Choose From Your List Of Existing Wallets", aliases)
 	if err != nil {
 		return fmt.Errorf("failed to get user choice: %w", err)
 	}
@@ -138,12 +294,76 @@ func selectExistingWallet(wc *wallet.WalletConfig) error {
 		return fmt.Errorf("failed to get the current wallet address: %w", err)
 	}
 
-	clipboard.WriteAll(newAddr)
-	printBlue("Switched To A New Wallet. Your Address Is: %s (copied to clipboard)\n", newAddr)
+	printBlue("Switched To A New Wallet. Your Address Is: %s%s\n", newAddr, copyToClipboard(newAddr))
 	return nil
 }
 
-func createNewFileBasedWallet(wc *wallet.WalletConfig, alias, privateKey string) error {
+func importKeypairFile(flow *initFlow, wc *wallet.WalletConfig, alias, path string) error {
+	if alias == "" {
+		var err error
+		alias, err = promptForInput("Create An Alias For Your Wallet:", nil)
+		if err != nil {
+			return fmt.Errorf("failed to get wallet alias: %w", err)
+		}
+	}
+
+	if err := flow.checkpoint(); err != nil {
+		return err
+	}
+	address, err := wc.ImportKeypairFile(alias, path)
+	if err != nil {
+		return fmt.Errorf("failed to import keypair file: %w", err)
+	}
+	flow.saved(address)
+	if err := flow.checkpoint(); err != nil {
+		return err
+	}
+
+	printBlue("New Wallet Imported. Your Address Is: %s%s\n", address, copyToClipboard(address))
+	flow.announced(address)
+
+	return nil
+}
+
+// importFromQR decodes the QR code in an image file and imports what it holds the way the matching
+// flag would: a private key into the key file, a seed phrase as with --paper, and an address as a
+// watch-only wallet. What was found is shown and confirmed first.
+func importFromQR(flow *initFlow, wc *wallet.WalletConfig, path string) error {
+	payload, err := wallet.ReadQRFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to import from QR code: %w", err)
+	}
+
+	confirmed, err := promptForConfirmation(fmt.Sprintf("The QR code holds %s. Import it", payload.Describe()))
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("Import cancelled.")
+		return nil
+	}
+
+	switch payload.Kind {
+	case wallet.QRPrivateKey:
+		return createNewFileBasedWallet(flow, wc, aliasFlag, payload.Value)
+	case wallet.QRSeedPhrase:
+		return importSeedPhrase(flow, wc, payload.Value)
+	default:
+		if err := flow.checkpoint(); err != nil {
+			return err
+		}
+		alias, err := wc.AddWatchOnlyWallet(aliasFlag, payload.Address)
+		if err != nil {
+			return fmt.Errorf("failed to add watch-only wallet: %w", err)
+		}
+		flow.saved(payload.Address)
+		printBlue("Watching %s as %s. It has no private key, so it cannot send.\n", payload.Address, alias)
+		flow.announced(payload.Address)
+		return nil
+	}
+}
+
+func createNewFileBasedWallet(flow *initFlow, wc *wallet.WalletConfig, alias, privateKey string) error {
 	// Prompt for alias if it's empty
 	if alias == "" {
 		var err error
@@ -153,6 +373,10 @@ func createNewFileBasedWallet(wc *wallet.WalletConfig, alias, privateKey string)
 		}
 	}
 
+	if err := flow.checkpoint(); err != nil {
+		return err
+	}
+
 	// Create or import the wallet based on whether a private key is provided
 	var newWallet string
 	var err error
@@ -164,14 +388,18 @@ func createNewFileBasedWallet(wc *wallet.WalletConfig, alias, privateKey string)
 	if err != nil {
 		return fmt.Errorf("failed to create new wallet: %w", err)
 	}
+	flow.saved(newWallet)
+	if err := flow.checkpoint(); err != nil {
+		return err
+	}
 
 	// Copy the new wallet address to the clipboard and print it
-	clipboard.WriteAll(newWallet)
 	action := "Created"
 	if privateKey != "" {
 		action = "Imported"
 	}
-	printBlue("New Wallet %s. Your Address Is: %s (copied to clipboard)\n", action, newWallet)
+	printBlue("New Wallet %s. Your Address Is: %s%s\n", action, newWallet, copyToClipboard(newWallet))
+	flow.announced(newWallet)
 
 	return nil
 }
@@ -180,26 +408,103 @@ func promptForChoice(label string, items []string) (string, error) {
 	prompt := promptui.Select{
 		Label:     label,
 		Items:     items,
-		Templates: templates,
+		Templates: selectTemplates(),
+		Stdin:     promptStdin,
+		Stdout:    promptStdout,
+	}
+	_, choice, err := prompt.Run()
+	if err != nil {
+		return "", promptError(err)
+	}
+	return choice, nil
+}
+
+// promptForSearchableChoice is promptForChoice with "/" search, matching the typed characters in order
+// anywhere in an item so wallets can be found by alias or note.
+func promptForSearchableChoice(label string, items []string) (string, error) {
+	prompt := promptui.Select{
+		Label:     label,
+		Items:     items,
+		Templates: selectTemplates(),
+		Stdin:     promptStdin,
+		Stdout:    promptStdout,
+		Searcher: func(input string, index int) bool {
+			return fuzzyMatch(input, items[index])
+		},
 	}
 	_, choice, err := prompt.Run()
 	if err != nil {
-		return "", err
+		return "", promptError(err)
 	}
 	return choice, nil
 }
 
+// fuzzyMatch reports whether the characters of input appear in item in the same order, ignoring case and spaces.
+func fuzzyMatch(input, item string) bool {
+	remaining := []rune(strings.ToLower(strings.ReplaceAll(input, " ", "")))
+	for _, r := range strings.ToLower(item) {
+		if len(remaining) == 0 {
+			break
+		}
+		if r == remaining[0] {
+			remaining = remaining[1:]
+		}
+	}
+	return len(remaining) == 0
+}
+
 func promptForInput(label string, validator func(input string) error) (string, error) {
 	prompt := promptui.Prompt{
-		Label:    label,
-		Validate: validator,
+		Label:     label,
+		Validate:  validUTF8(validator),
+		Templates: promptTemplates(),
+		Stdin:     promptStdin,
+		Stdout:    promptStdout,
+	}
+	input, err := prompt.Run()
+	return input, promptError(err)
+}
+
+// promptForConfirmation asks a yes/no question, treating anything but yes as no.
+func promptForConfirmation(label string) (bool, error) {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+		Templates: promptTemplates(),
+		Stdin:     promptStdin,
+		Stdout:    promptStdout,
+	}
+	_, err := prompt.Run()
+	if errors.Is(err, promptui.ErrAbort) {
+		return false, nil
+	}
+	if err != nil {
+		return false, promptError(err)
+	}
+	return true, nil
+}
+
+func promptForSecret(label string) (string, error) {
+	prompt := promptui.Prompt{
+		Label:     label,
+		Mask:      '*',
+		Validate:  validUTF8(nil),
+		Templates: promptTemplates(),
+		Stdin:     promptStdin,
+		Stdout:    promptStdout,
 	}
-	return prompt.Run()
+	secret, err := prompt.Run()
+	return secret, promptError(err)
 }
 
-func postWalletInitializationActions(wc *wallet.WalletConfig) error {
+func postWalletInitializationActions(flow *initFlow, wc *wallet.WalletConfig) error {
+	flow.settled = true
+	currency := wc.FiatCurrency()
 	for {
-		choice, err := promptForChoice("What would you like to do next?", []string{"Check Balance(EUR)", "Get Current SOL/EUR Rate", "Retrieve Wallet Address", "Retrieve Transactions", "Send EUR", "Exit"})
+		if err := flow.checkpoint(); err != nil {
+			return err
+		}
+		choice, err := promptForChoice("What would you like to do next?", []string{"Check Balance(" + currency.String() + ")", "Get Current SOL/" + currency.String() + " Rate", "Retrieve Wallet Address", "Retrieve Transactions", "Send " + currency.String(), "Exit"})
 		if err != nil {
 			return fmt.Errorf("failed to get user choice: %w", err)
 		}
@@ -208,7 +513,7 @@ func postWalletInitializationActions(wc *wallet.WalletConfig) error {
 		case "Exit":
 			return nil
 		default:
-			err := processPostInitializationChoice(choice, wc)
+			err := processPostInitializationChoice(flow.ctx, choice, wc)
 			if err != nil {
 				return fmt.Errorf("failed to process choice: %w", err)
 			}
@@ -216,27 +521,28 @@ func postWalletInitializationActions(wc *wallet.WalletConfig) error {
 	}
 }
 
-func processPostInitializationChoice(choice string, wc *wallet.WalletConfig) error {
+func processPostInitializationChoice(ctx context.Context, choice string, wc *wallet.WalletConfig) error {
+	currency := wc.FiatCurrency()
 	switch choice {
-	case "Check Balance(EUR)":
-		bal, err := wc.GetCurrentWalletBalanceInEUR("")
+	case "Check Balance(" + currency.String() + ")":
+		bal, err := wc.GetCurrentWalletBalanceInFiat("")
 		if err != nil {
 			return fmt.Errorf("failed to check balance: %w", err)
 		}
-		printBlue("Balance of the active wallet: €%s\n", bal)
+		printBlue("Balance of the active wallet: %s\n", bal)
 	case "Retrieve Wallet Address":
 		publicKey, err := wc.RetrieveCurrentWalletAddress()
 		if err != nil {
 			return fmt.Errorf("failed to retrieve wallet address: %w", err)
 		}
 		printBlue("Public Key of The Active Wallet: %s\n", publicKey)
-	case "Get Current SOL/EUR Rate":
-		rate, err := wc.FetchSOLEURRate()
+	case "Get Current SOL/" + currency.String() + " Rate":
+		rate, err := wc.FetchSOLRate()
 		if err != nil {
 			return fmt.Errorf("failed to retrieve rate: %w", err)
 		}
 
-		printBlue("Current SOL/EUR Rate: €%s\n", rate)
+		printBlue("Current SOL/%s Rate: %s%s\n", currency, currency.Symbol(), rate)
 	case "Retrieve Transactions":
 		transactions, err := wc.GetTransactionHistory()
 		if err != nil {
@@ -247,19 +553,14 @@ func processPostInitializationChoice(choice string, wc *wallet.WalletConfig) err
 			return transactions[i].Timestamp.After(transactions[j].Timestamp)
 		})
 
-		rate, err := wc.FetchSOLEURRate()
-		if err != nil {
-			return fmt.Errorf("error fetching SOL to EUR rate: %v", err)
-		}
-
-		printTransactions(transactions, rate)
-	case "Send EUR":
+		printTransactions(transactions, fetchRateOrWarn(wc), nil, currency, addressNamesOrWarn(wc), nil)
+	case "Send " + currency.String():
 		destination, err := promptForInput("Enter the recipient's address:", nil)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to get recipient: %w", err)
 		}
 
-		amount, err := promptForInput("Enter the amount of EUR to send:", func(input string) error {
+		amount, err := promptForInput("Enter the amount of "+currency.String()+" to send:", func(input string) error {
 			val, err := strconv.ParseFloat(input, 64)
 			if err != nil {
 				return fmt.Errorf("invalid amount: %w", err)
@@ -269,13 +570,16 @@ func processPostInitializationChoice(choice string, wc *wallet.WalletConfig) err
 			}
 			return nil
 		})
+		if err != nil {
+			return fmt.Errorf("failed to get amount: %w", err)
+		}
 
-		signature, err := wc.SendFunds(context.Background(), amount, destination)
+		signature, err := wc.SendFunds(ctx, amount, destination)
 		if err != nil {
-			log.Fatalf("Failed to send funds: %v", err.Error())
+			return fmt.Errorf("failed to send funds: %w", err)
 		}
 
-		fmt.Printf("Successfully sent %s EUR to %s. Transaction Signature: %s\n", amount, destination, signature)
+		fmt.Printf("Successfully sent %s %s to %s on %s. Transaction Signature: %s\n", amount, currency, destination, wc.NetworkName(), signature)
 	default:
 		fmt.Println("Invalid choice. Returning to main menu.")
 	}
@@ -0,0 +1,20 @@
+package cmd
+
+// keyAccessAnnotation classifies every command by what it needs from the key file. Commands that
+// need nothing use newReadOnlyContext, which never opens it; TestEveryCommandIsClassified fails
+// for a new command until it is classified.
+const keyAccessAnnotation = "keyAccess"
+
+const (
+	// keyAccessNone commands never open the key file and work on machines without one.
+	keyAccessNone = "none"
+	// keyAccessPublic commands read addresses and settings from the key file but never decrypt a key.
+	keyAccessPublic = "public"
+	// keyAccessPrivate commands may decrypt private keys or write the key file.
+	keyAccessPrivate = "private"
+)
+
+// keyAccess returns the annotations classifying a command's key requirements.
+func keyAccess(level string) map[string]string {
+	return map[string]string{keyAccessAnnotation: level}
+}
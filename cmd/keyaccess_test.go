@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// commandsByKeyAccess returns the path of every runnable command, grouped by its classification.
+// Cobra's own help and completion commands are left out.
+func commandsByKeyAccess() map[string][]string {
+	grouped := make(map[string][]string)
+
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, child := range c.Commands() {
+			if child.Name() == "help" || child.Name() == "completion" {
+				continue
+			}
+			if child.Runnable() {
+				path := strings.TrimPrefix(child.CommandPath(), RootCmd.Name()+" ")
+				level := child.Annotations[keyAccessAnnotation]
+				grouped[level] = append(grouped[level], path)
+			}
+			walk(child)
+		}
+	}
+	walk(RootCmd)
+
+	return grouped
+}
+
+func TestEveryCommandIsClassified(t *testing.T) {
+	for level, paths := range commandsByKeyAccess() {
+		switch level {
+		case keyAccessNone, keyAccessPublic, keyAccessPrivate:
+		default:
+			t.Errorf("commands %v have no valid %s annotation (got %q)", paths, keyAccessAnnotation, level)
+		}
+	}
+}
+
+func TestReadOnlyCommandsNeverOpenTheKeyFile(t *testing.T) {
+	// The key file of the default profile is a directory nobody may read, so any attempt to open
+	// it fails, even when the tests run as root.
+	workDir := t.TempDir()
+	configDir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(configDir, wallet.KeyFileName), 0))
+	t.Setenv(wallet.ConfigDirEnv, configDir)
+
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(workDir))
+	defer os.Chdir(cwd)
+
+	defer func(fetch func(*wallet.ReadOnlyContext) (decimal.Decimal, error)) { fetchExchangeRate = fetch }(fetchExchangeRate)
+	fetchExchangeRate = func(*wallet.ReadOnlyContext) (decimal.Decimal, error) {
+		return decimal.NewFromInt(150), nil
+	}
+	defer RootCmd.SetArgs(nil)
+	// Storing a locale selects it for the whole process.
+	defer wallet.SetLocale(wallet.DefaultLocale)
+
+	run := func(args ...string) error {
+		RootCmd.SetArgs(args)
+		return RootCmd.Execute()
+	}
+
+	// A command that reads the key file fails here, which shows the setup works.
+	assert.Error(t, run("address"))
+
+	invocations := map[string][]string{
+		"exchange":         {"exchange"},
+		"pending":          {"pending"},
+		"audit":            {"audit"},
+		"profile create":   {"profile", "create", "ci"},
+		"profile list":     {"profile", "list"},
+		"profile switch":   {"profile", "switch", "ci"},
+		"currency":         {"currency", "gbp"},
+		"stats":            {"stats", "--latency"},
+		"locale":           {"locale", "de-DE"},
+		"rpc add":          {"--network", "devnet", "rpc", "add", "helius", "https://devnet.helius-rpc.com/"},
+		"rpc list":         {"rpc", "list"},
+		"rpc remove":       {"rpc", "remove", "helius"},
+		"keystore backend": {"keystore", "backend", "file"},
+		"config get":       {"config", "get"},
+		"config set":       {"config", "set", "output", "text"},
+		"config unset":     {"config", "unset", "output"},
+	}
+	defer func() {
+		networkFlag = ""
+		RootCmd.PersistentFlags().Lookup("network").Changed = false
+	}()
+
+	for _, path := range commandsByKeyAccess()[keyAccessNone] {
+		args, ok := invocations[path]
+		if !assert.True(t, ok, "no invocation for read-only command %q", path) {
+			continue
+		}
+		assert.NoError(t, run(args...), path)
+	}
+}
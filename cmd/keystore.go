@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+// passphraseEnv lets scripts provide the keystore passphrase without a prompt.
+const passphraseEnv = "SLEENG_PASSPHRASE"
+
+var keystoreCmd = &cobra.Command{
+	Use:   "keystore",
+	Short: "Manages how private keys are stored on disk",
+}
+
+var keystoreEncryptCmd = &cobra.Command{
+	Use:         "encrypt",
+	Short:       "Encrypts every unencrypted private key in the key file with a passphrase",
+	Annotations: keyAccess(keyAccessPrivate),
+	Long: `Migrates the key file in place, encrypting every unencrypted private key with a passphrase.
+The change is previewed and confirmed before anything is written; --dry-run only previews it.`,
+	RunE: encryptKeystore,
+}
+
+var keystoreBackendCmd = &cobra.Command{
+	Use:         "backend [file|keychain]",
+	Short:       "Shows or stores where new private keys are kept",
+	Annotations: keyAccess(keyAccessNone),
+	Long: `Without an argument this prints where new private keys are kept. "file" keeps them in the key
+file, encrypted when a passphrase is given. "keychain" keeps them in the OS keychain (macOS Keychain,
+the Secret Service through secret-tool on Linux, or the Windows Credential Manager), and the key file
+only keeps their public keys. The setting applies to all profiles and only to new keys; move existing
+keys with "keystore move-to-keychain".`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runKeystoreBackend,
+}
+
+var keystoreMoveToKeychainCmd = &cobra.Command{
+	Use:         "move-to-keychain",
+	Short:       "Moves every private key in the key file into the OS keychain",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.NoArgs,
+	RunE:        moveKeysToKeychain,
+}
+
+func init() {
+	addPlanFlags(keystoreEncryptCmd)
+	keystoreCmd.AddCommand(keystoreEncryptCmd, keystoreBackendCmd, keystoreMoveToKeychainCmd)
+}
+
+// passphrasePrompt returns a wallet.PassphraseFunc that reads SLEENG_PASSPHRASE when set and
+// otherwise prompts, asking for confirmation when a new passphrase is chosen. The second function
+// forgets a passphrase that turned out to be wrong, so the next call prompts again; a wrong
+// SLEENG_PASSPHRASE is not retried.
+func passphrasePrompt() (wallet.PassphraseFunc, func(alias string) bool) {
+	entered := make(map[string]string)
+
+	retry := func(alias string) bool {
+		if _, ok := os.LookupEnv(passphraseEnv); ok {
+			return false
+		}
+		delete(entered, alias)
+		printWarning("Wrong passphrase for %s, try again.\n", alias)
+		return true
+	}
+
+	return func(alias string, isNew bool) (string, error) {
+		if passphrase, ok := os.LookupEnv(passphraseEnv); ok {
+			return passphrase, nil
+		}
+
+		if !isNew {
+			if passphrase, ok := entered[alias]; ok {
+				return passphrase, nil
+			}
+			passphrase, err := promptForSecret(fmt.Sprintf("Passphrase for %s", alias))
+			if err != nil {
+				return "", err
+			}
+			entered[alias] = passphrase
+			return passphrase, nil
+		}
+
+		passphrase, err := promptForSecret(fmt.Sprintf("Passphrase to encrypt %s (leave empty to store it unencrypted)", alias))
+		if err != nil || passphrase == "" {
+			return "", err
+		}
+
+		confirmation, err := promptForSecret("Repeat the passphrase")
+		if err != nil {
+			return "", err
+		}
+		if confirmation != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+
+		entered[alias] = passphrase
+		return passphrase, nil
+	}, retry
+}
+
+func encryptKeystore(cmd *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	prompt, _ := passphrasePrompt()
+	passphrase, err := prompt("all unencrypted wallets", true)
+	if err != nil {
+		return fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if passphrase == "" {
+		return errors.New("a passphrase is required to encrypt the key file")
+	}
+
+	plan, err := wc.PlanEncryptWallets(passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt key file: %w", err)
+	}
+
+	if plan.Empty() {
+		printBlue("All wallets are already encrypted.\n")
+		return nil
+	}
+
+	applied, err := applyPlan(cmd, wc, plan, nil)
+	if err != nil || !applied {
+		return err
+	}
+
+	encrypted := plan.Aliases()
+	printBlue("Encrypted %d wallet(s): %s\n", len(encrypted), strings.Join(encrypted, ", "))
+	return nil
+}
+
+func runKeystoreBackend(_ *cobra.Command, args []string) error {
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		backend, err := profiles.KeyBackend()
+		if err != nil {
+			return fmt.Errorf("failed to read key backend: %w", err)
+		}
+
+		printBlue("New keys are stored in: %s\n", backend)
+		return nil
+	}
+
+	backend, err := wallet.ParseKeyBackend(args[0])
+	if err != nil {
+		return err
+	}
+	if err := profiles.SetKeyBackend(backend); err != nil {
+		return fmt.Errorf("failed to store key backend: %w", err)
+	}
+
+	printBlue("New keys will be stored in: %s\n", backend)
+	return nil
+}
+
+func moveKeysToKeychain(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	moved, err := wc.MoveKeysToKeychain()
+	if err != nil {
+		return fmt.Errorf("failed to move keys to the OS keychain: %w", err)
+	}
+	if len(moved) == 0 {
+		printBlue("No private keys left in the key file.\n")
+		return nil
+	}
+
+	printBlue("Moved %d key(s) to the OS keychain: %s\n", len(moved), strings.Join(moved, ", "))
+	return nil
+}
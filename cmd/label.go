@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var labelCmd = &cobra.Command{
+	Use:         "label [signature] [text]",
+	Short:       "Labels a transaction for bookkeeping",
+	Annotations: keyAccess(keyAccessPublic),
+	Long: `Labels a transaction, such as "office rent" or "refund", optionally in a --category. Labels are
+shown in the transaction history, included in every --export format and can be filtered on with
+transactions --category. Labeling a transaction again replaces its text and keeps its category
+unless a new one is given.
+
+The signature is looked up on the selected network first, so a mistyped one is refused. Use
+--unchecked to label a transaction of another network or while offline.`,
+	Args: cobra.ExactArgs(2),
+	RunE: labelTransaction,
+}
+
+var labelRemoveCmd = &cobra.Command{
+	Use:         "remove [signature...]",
+	Short:       "Removes the labels of transactions",
+	Annotations: keyAccess(keyAccessPublic),
+	Args:        cobra.MinimumNArgs(1),
+	RunE:        removeLabels,
+}
+
+var labelListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists labeled transactions",
+	Annotations: keyAccess(keyAccessPublic),
+	Args:        cobra.NoArgs,
+	RunE:        listLabels,
+}
+
+var (
+	labelCategory  string
+	labelUnchecked bool
+)
+
+func init() {
+	labelCmd.Flags().StringVar(&labelCategory, "category", "", "Category of the label, such as rent or refund")
+	labelCmd.Flags().BoolVar(&labelUnchecked, "unchecked", false, "Label the signature without looking it up on the network")
+	labelListCmd.Flags().StringVar(&labelCategory, "category", "", "Only list labels in this category")
+	labelCmd.AddCommand(labelRemoveCmd, labelListCmd)
+}
+
+func labelTransaction(cmd *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	label, err := wc.LabelTransaction(cmd.Context(), args[0], wallet.LabelOptions{
+		Text:      args[1],
+		Category:  labelCategory,
+		Unchecked: labelUnchecked,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to label transaction: %w", err)
+	}
+	printBlue("Labeled %s: %s\n", label.Signature, label)
+	return nil
+}
+
+func removeLabels(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, signature := range args {
+		label, err := wc.UnlabelTransaction(signature)
+		if err != nil {
+			return fmt.Errorf("failed to remove label: %w", err)
+		}
+		printBlue("Removed the label %q of %s\n", label.String(), label.Signature)
+	}
+	return nil
+}
+
+func listLabels(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	labels, err := wc.ListLabels(labelCategory)
+	if err != nil {
+		return fmt.Errorf("failed to list labels: %w", err)
+	}
+
+	if jsonOutput() {
+		return printJSON(labels)
+	}
+	if len(labels) == 0 {
+		fmt.Println("No labeled transactions. Label one with `wallet label [signature] [text]`.")
+		return nil
+	}
+	for _, label := range labels {
+		fmt.Printf("%s  %s\n", label.Signature, label)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+var localeCmd = &cobra.Command{
+	Use:         "locale [tag]",
+	Short:       "Shows or stores the locale fiat amounts are shown in",
+	Annotations: keyAccess(keyAccessNone),
+	Long: `Without an argument this prints the locale fiat amounts are shown in, in balances, history, prompts
+and receipts. With a tag such as en-IE, de-DE or fr (POSIX names like de_DE.UTF-8 work too) it stores
+that locale for all profiles: de-DE shows 1.234,56 €, en-IE €1,234.56. Exports always keep plain
+numbers. --locale and SLEENG_LOCALE still take precedence for a single command.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLocale,
+}
+
+// exampleAmount shows what a locale looks like.
+var exampleAmount = decimal.New(123456, -2)
+
+func runLocale(_ *cobra.Command, args []string) error {
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		locale, err := profiles.Locale()
+		if err != nil {
+			return fmt.Errorf("failed to read locale: %w", err)
+		}
+
+		printBlue("Locale: %s (e.g. %s)\n", locale, locale.FormatMoney(exampleAmount, wallet.DefaultCurrency))
+		return nil
+	}
+
+	locale, err := wallet.ParseLocale(args[0])
+	if err != nil {
+		return err
+	}
+	if err := profiles.SetLocale(locale); err != nil {
+		return fmt.Errorf("failed to store locale: %w", err)
+	}
+
+	printBlue("Locale set to %s (e.g. %s)\n", locale, locale.FormatMoney(exampleAmount, wallet.DefaultCurrency))
+	return nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// offerKeyFileMigration offers to move the key file an older version left in the current directory
+// into the default profile. It only asks while the default profile has no key file of its own, so
+// the question stops once the keys have moved or a new wallet was created.
+func offerKeyFileMigration(wc *wallet.WalletConfig) error {
+	workDir, err := os.Getwd()
+	if err != nil || !wallet.HasLegacyKeyFile(workDir) {
+		return nil
+	}
+
+	present, err := wc.KeyOps.IsKeyFilePresent()
+	if err != nil || present {
+		return err
+	}
+
+	legacy := filepath.Join(workDir, wallet.LegacyKeyFileName)
+	profileDir := filepath.Dir(wc.KeyFile())
+	if wallet.ReadOnlyMode() {
+		printWarning("Warning: %s is a key file of an older version; run without --read-only to move it to %s, or pass --keyfile %s\n", legacy, profileDir, legacy)
+		return nil
+	}
+
+	move, err := promptForConfirmation(fmt.Sprintf("Found a key file of an older version in %s. Move it to %s", workDir, profileDir))
+	if errors.Is(err, ErrInputClosed) {
+		move = false
+	} else if err != nil {
+		return err
+	}
+	if !move {
+		printWarning("Leaving %s in place; pass --keyfile %s to keep using it.\n", legacy, legacy)
+		return nil
+	}
+
+	moved, err := wallet.MigrateLegacyFiles(workDir, profileDir)
+	for _, path := range moved {
+		printBlue("Moved to %s\n", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move the old key file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestLegacyKeyFileMigration(t *testing.T) {
+	skipUnderRace(t)
+	tests := []struct {
+		name      string
+		input     string
+		keyFile   bool
+		wantMoved bool
+	}{
+		{name: "accepted", input: "y\r", wantMoved: true},
+		{name: "declined", input: "n\r"},
+		{name: "input closed", input: ""},
+		{name: "explicit key file", input: "y\r", keyFile: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			configDir := t.TempDir()
+			t.Setenv(wallet.ConfigDirEnv, configDir)
+			legacy := filepath.Join(workDir, wallet.LegacyKeyFileName)
+			assert.NoError(t, os.WriteFile(legacy, []byte(`{"activeAlias":"main","wallets":{"main":{"publicKey":"Addr1"}}}`), 0600))
+
+			cwd, err := os.Getwd()
+			assert.NoError(t, err)
+			assert.NoError(t, os.Chdir(workDir))
+			defer os.Chdir(cwd)
+
+			defer func(path string) { keyFileFlag = path }(keyFileFlag)
+			if tt.keyFile {
+				keyFileFlag = legacy
+			}
+			withStdin(t, tt.input)
+
+			wc, err := newWalletConfig()
+			assert.NoError(t, err)
+
+			hasWallets, err := wc.HasWallets()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMoved || tt.keyFile, hasWallets)
+			if tt.wantMoved {
+				assert.NoFileExists(t, legacy)
+				assert.FileExists(t, filepath.Join(configDir, wallet.KeyFileName))
+			} else {
+				assert.FileExists(t, legacy)
+				assert.NoFileExists(t, filepath.Join(configDir, wallet.KeyFileName))
+			}
+		})
+	}
+}
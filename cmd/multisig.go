@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/spf13/cobra"
+)
+
+var multisigCmd = &cobra.Command{
+	Use:   "multisig",
+	Short: "Manages SPL Token multisig accounts shared with co-signers",
+	Long: `A multisig is an SPL Token multisig account: tokens it owns can only be moved with the signatures of
+--threshold of its signers. "wallet send --token usdc --multisig treasury --out transfer.json 10 mum"
+signs the transfer with the active wallet and writes it to a file, which each co-signer passes to
+"wallet multisig cosign". The last signature broadcasts it.`,
+}
+
+var multisigCreateCmd = &cobra.Command{
+	Use:         "create [name]",
+	Short:       "Creates a multisig account with the given signers, paid for by the active wallet",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.ExactArgs(1),
+	RunE:        createMultisig,
+}
+
+var multisigListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists the multisigs",
+	Annotations: keyAccess(keyAccessPublic),
+	Args:        cobra.NoArgs,
+	RunE:        listMultisigs,
+}
+
+var multisigCosignCmd = &cobra.Command{
+	Use:         "cosign [file]",
+	Short:       "Signs a partially-signed transfer and broadcasts it once every approver has signed",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.ExactArgs(1),
+	RunE:        cosignMultisig,
+}
+
+var (
+	multisigSigners   []string
+	multisigThreshold int
+)
+
+func init() {
+	multisigCreateCmd.Flags().StringSliceVar(&multisigSigners, "signers", nil, "Comma-separated addresses of the signers")
+	multisigCreateCmd.Flags().IntVar(&multisigThreshold, "threshold", 2, "Number of signers that must approve a transfer")
+	_ = multisigCreateCmd.MarkFlagRequired("signers")
+	multisigCosignCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Sign without asking for confirmation")
+	multisigCmd.AddCommand(multisigCreateCmd, multisigListCmd, multisigCosignCmd)
+}
+
+func createMultisig(cmd *cobra.Command, args []string) error {
+	// The signer set is checked before the key file is opened or any request is made.
+	if _, err := wallet.ValidateSignerSet(multisigSigners, multisigThreshold); err != nil {
+		return err
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	multisig, sig, err := wc.CreateMultisig(cmd.Context(), args[0], multisigSigners, multisigThreshold)
+	if err != nil {
+		return fmt.Errorf("failed to create multisig: %w", err)
+	}
+
+	printBlue("Multisig %s created on %s: %s\nTransaction Signature: %s\n", multisig.Alias, wc.NetworkName(), multisig, sig)
+	fmt.Printf("Send tokens to it with its address as the owner, e.g. `wallet send --token usdc 10 %s`.\n", multisig.Address)
+	return nil
+}
+
+func listMultisigs(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	multisigs, err := wc.Multisigs()
+	if err != nil {
+		return fmt.Errorf("failed to read multisigs: %w", err)
+	}
+
+	if jsonOutput() {
+		return printJSON(multisigs)
+	}
+	if len(multisigs) == 0 {
+		fmt.Println("No multisigs. Create one with `wallet multisig create [name] --signers [a,b]`.")
+		return nil
+	}
+	for _, m := range multisigs {
+		printBlue("%s: %s\n", m.Alias, m)
+		fmt.Printf("  signers: %s\n", strings.Join(m.Signers, ", "))
+	}
+	return nil
+}
+
+func cosignMultisig(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read partially-signed transaction: %w", err)
+	}
+	partial, err := wallet.ParsePartialTransaction(data)
+	if err != nil {
+		return err
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	if !assumeYes {
+		printBlue("Transfer: %s\nFrom multisig: %s\nNetwork: %s\n", partial.Description, partial.Multisig, partial.Network)
+		confirmed, err := promptForConfirmation("Sign this transfer")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Signing cancelled.")
+			return nil
+		}
+	}
+
+	sig, err := wc.CosignTransaction(cmd.Context(), partial)
+	if err != nil {
+		return fmt.Errorf("failed to co-sign: %w", err)
+	}
+	if sig != "" {
+		fmt.Printf("Every approver has signed; the transfer was sent on %s. Transaction Signature: %s\n", wc.NetworkName(), sig)
+		return nil
+	}
+
+	if err := writePartialTransaction(args[0], partial); err != nil {
+		return err
+	}
+	missing, err := partial.Missing()
+	if err != nil {
+		return err
+	}
+	printBlue("Signed. %d signature(s) still missing, next from %s; pass %s on with `wallet multisig cosign`.\n", len(missing), missing[0], args[0])
+	return nil
+}
+
+// writePartialTransaction writes a partially-signed transaction for the next co-signer.
+func writePartialTransaction(path string, partial *wallet.PartialTransaction) error {
+	data, err := partial.Encode()
+	if err != nil {
+		return err
+	}
+	if wallet.ReadOnlyMode() {
+		return fmt.Errorf("%w: %s", wallet.ErrReadOnlyMode, path)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write partially-signed transaction: %w", err)
+	}
+	return nil
+}
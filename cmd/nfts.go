@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var nftsCmd = &cobra.Command{
+	Use:         "nfts",
+	Short:       "Lists the Metaplex NFTs held by a specific or the current active Solana wallet",
+	Annotations: usesFlags(keyAccess(keyAccessPublic), usesAlias, usesTransientKey),
+	Long: `Lists the Metaplex NFTs held by the wallet with their name, collection and mint address. A
+token counts as an NFT when the wallet holds one token of a mint without decimals that has a Metaplex
+metadata account. A collection the NFT names but that has not verified it is marked "unverified".`,
+	Args: cobra.NoArgs,
+	RunE: displayNFTs,
+}
+
+// nftOutput is the JSON form of an NFT.
+type nftOutput struct {
+	Name               string `json:"name"`
+	Symbol             string `json:"symbol,omitempty"`
+	Mint               string `json:"mint"`
+	Account            string `json:"account"`
+	Collection         string `json:"collection,omitempty"`
+	CollectionVerified bool   `json:"collectionVerified,omitempty"`
+	URI                string `json:"uri,omitempty"`
+}
+
+func displayNFTs(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	nfts, err := wc.GetNFTs(aliasFlag)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve NFTs: %v", err)
+	}
+
+	defer wallet.TimePhase(wallet.PhaseRender)()
+	if jsonOutput() {
+		output := make([]nftOutput, 0, len(nfts))
+		for _, nft := range nfts {
+			output = append(output, nftOutput{
+				Name:               nft.Name,
+				Symbol:             nft.Symbol,
+				Mint:               nft.Mint,
+				Account:            nft.Account,
+				Collection:         nft.Collection,
+				CollectionVerified: nft.CollectionVerified,
+				URI:                nft.URI,
+			})
+		}
+		return printJSON(output)
+	}
+
+	if len(nfts) == 0 {
+		fmt.Println("No NFTs.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCOLLECTION\tMINT")
+	for _, nft := range nfts {
+		collection := nft.Collection
+		if collection == "" {
+			collection = "-"
+		} else if !nft.CollectionVerified {
+			collection += " (unverified)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", nft.Name, collection, nft.Mint)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/spf13/cobra"
+)
+
+var noteCmd = &cobra.Command{
+	Use:         "note [alias] [text]",
+	Short:       "Shows or sets the note and emoji attached to a wallet",
+	Annotations: keyAccess(keyAccessPrivate),
+	Long: `Attaches a short free-text note and an optional emoji to a wallet. Both are shown
+next to the alias in address --all, the wallet selector and info.
+Without text the current note is printed.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: editNote,
+}
+
+var (
+	noteIcon  string
+	clearNote bool
+)
+
+func init() {
+	noteCmd.Flags().StringVar(&noteIcon, "icon", "", "Emoji or symbol to show before the note")
+	noteCmd.Flags().BoolVar(&clearNote, "clear", false, "Remove the note and icon")
+}
+
+func editNote(cmd *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	alias := args[0]
+	info, err := wc.GetWalletInfo(alias)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet info: %w", err)
+	}
+
+	note := info.Note
+	switch {
+	case clearNote:
+		note = wallet.WalletNote{}
+	case len(args) == 1 && !cmd.Flags().Changed("icon"):
+		if note.Label() == "" {
+			fmt.Printf("%s has no note.\n", alias)
+		} else {
+			printBlue("%s: %s\n", alias, note.Label())
+		}
+		return nil
+	default:
+		if len(args) == 2 {
+			note.Text = args[1]
+		}
+		if cmd.Flags().Changed("icon") {
+			note.Icon = noteIcon
+		}
+	}
+
+	if err := wc.SetWalletNote(alias, note); err != nil {
+		return fmt.Errorf("failed to save note: %w", err)
+	}
+
+	fmt.Printf("Note of %s updated.\n", alias)
+	return nil
+}
@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/fatih/color"
+	"github.com/shopspring/decimal"
+	"golang.org/x/term"
+)
+
+// onelineWidth is the width --oneline keeps lines under when stdout is not a terminal.
+const onelineWidth = 120
+
+// Widths of the fixed columns of a --oneline line.
+const (
+	onelineTimeWidth      = len("2006-01-02 15:04")
+	onelineDirectionWidth = len("RECV")
+	onelineAmountWidth    = len("10000.0000 SOL")
+	onelineFiatWidth      = 13 // (10.000,00 €)
+	// onelinePartyWidth fits the arrow, a space and a shortened address or name.
+	onelinePartyWidth = 16
+	onelineFeeWidth   = len("fee 0.000005")
+)
+
+// terminalWidth returns the width of the terminal on stdout, or onelineWidth when stdout is not one.
+func terminalWidth() int {
+	fd := int(os.Stdout.Fd())
+	if term.IsTerminal(fd) {
+		if width, _, err := term.GetSize(fd); err == nil && width > 0 {
+			return width
+		}
+	}
+	return onelineWidth
+}
+
+// onelineFormat formats a transaction history with one line per transfer, such as
+//
+//	2024-05-02 14:03  SENT      0.2500 SOL  (€31.20)     → 9xK…3fA        fee 0.000005  sig 4fGq…
+//
+// A transaction with several transfers gets a header line carrying its fee and signature, followed
+// by a line per transfer with the time column left blank. The methods only depend on the fields,
+// so the output is the same wherever it runs.
+type onelineFormat struct {
+	// Rate converts SOL amounts to Currency; without one only SOL is shown.
+	Rate     *decimal.Decimal
+	Currency wallet.Currency
+	// Names are shown instead of the addresses of stored wallets and contacts.
+	Names map[string]string
+	// Labels are shown at the end of the line of the transaction they label, by signature.
+	Labels map[string]wallet.TransactionLabel
+	// Location is the time zone of the time column, UTC when nil.
+	Location *time.Location
+	// Width is the number of columns no line reaches.
+	Width int
+	// Color colours the direction column, which does not change the width of a line.
+	Color bool
+}
+
+// Lines returns the lines of groups, in their order.
+func (f onelineFormat) Lines(groups []*wallet.TransactionGroup) []string {
+	var lines []string
+	for _, group := range groups {
+		if len(group.Transfers) == 1 {
+			lines = append(lines, f.transferLine(group.Transfers[0], f.timestamp(group.Timestamp), group.Fee, group.Signature.String()))
+			continue
+		}
+
+		lines = append(lines, f.fit(onelineFields{
+			time:      f.timestamp(group.Timestamp),
+			direction: "TX",
+			amount:    fmt.Sprintf("%d transfers", len(group.Transfers)),
+			fee:       feeField(group.Fee),
+			signature: signatureField(group.Signature.String()),
+			label:     f.label(group.Signature.String()),
+		}))
+		for _, tx := range group.Transfers {
+			lines = append(lines, f.transferLine(tx, "", 0, ""))
+		}
+	}
+	return lines
+}
+
+// transferLine returns the line of one transfer. Transfers within a group leave the time, fee and
+// signature to the header line.
+func (f onelineFormat) transferLine(tx *wallet.Transaction, timestamp string, fee uint64, signature string) string {
+	fields := onelineFields{
+		time:      timestamp,
+		direction: "RECV",
+		party:     "← " + f.party(tx.From.String()),
+		fee:       feeField(fee),
+		signature: signatureField(signature),
+		label:     f.label(signature),
+	}
+	if tx.IsSender {
+		fields.direction = "SENT"
+		fields.party = "→ " + f.party(tx.To.String())
+	}
+	if tx.Reward {
+		fields.direction = "RWRD"
+		fields.party = fmt.Sprintf("epoch %d → %s", tx.Epoch, f.party(tx.To.String()))
+		fields.signature, fields.label = "", ""
+	}
+
+	if tx.IsToken() {
+		fields.amount = fmt.Sprintf("%s %s", tx.TokenAmount(), tx.Symbol)
+	} else {
+		sol := wallet.LamportsToSOL(tx.Amount)
+		fields.amount = onelineSOL(sol) + " SOL"
+		if f.Rate != nil {
+			fields.fiat = "(" + f.Currency.Format(sol.Mul(*f.Rate)) + ")"
+		}
+	}
+	return f.fit(fields)
+}
+
+// onelineFields are the columns of a line before they are padded. Empty fields are left blank.
+type onelineFields struct {
+	time, direction, amount, fiat, party, fee, signature, label string
+}
+
+// fit lays out fields in fixed-width columns under Width. When they do not fit, the label, the
+// signature, the fee and the fiat value are dropped in that order, and a line still too wide is cut
+// short with "…".
+func (f onelineFormat) fit(fields onelineFields) string {
+	line := f.layout(fields)
+	for _, drop := range []*string{&fields.label, &fields.signature, &fields.fee, &fields.fiat} {
+		if f.Width <= 0 || utf8.RuneCountInString(line) < f.Width {
+			break
+		}
+		*drop = ""
+		line = f.layout(fields)
+	}
+	if f.Width > 1 && utf8.RuneCountInString(line) >= f.Width {
+		line = string([]rune(line)[:f.Width-2]) + "…"
+	}
+
+	if f.Color && fields.direction != "" {
+		// The direction follows the time column, which is padded even when blank.
+		start := onelineTimeWidth + len("  ")
+		end := start + len(fields.direction)
+		if runes := []rune(line); len(runes) >= end {
+			line = string(runes[:start]) + directionColor(fields.direction).Sprint(fields.direction) + string(runes[end:])
+		}
+	}
+	return line
+}
+
+// layout pads the fields into their columns, separated by two spaces, without trailing blanks.
+func (f onelineFormat) layout(fields onelineFields) string {
+	columns := []string{
+		padRight(fields.time, onelineTimeWidth),
+		padRight(fields.direction, onelineDirectionWidth),
+		padLeft(fields.amount, onelineAmountWidth),
+		padLeft(fields.fiat, onelineFiatWidth),
+		padRight(fields.party, onelinePartyWidth),
+		padRight(fields.fee, onelineFeeWidth),
+		fields.signature,
+	}
+	if fields.label != "" {
+		columns[len(columns)-1] = padRight(fields.signature, len("sig ")+5)
+		columns = append(columns, fields.label)
+	}
+	return strings.TrimRight(strings.Join(columns, "  "), " ")
+}
+
+// timestamp formats the time column in Location, to the minute.
+func (f onelineFormat) timestamp(t time.Time) string {
+	location := f.Location
+	if location == nil {
+		location = time.UTC
+	}
+	return t.In(location).Format("2006-01-02 15:04")
+}
+
+// party shows the name of a stored wallet or contact, or else a shortened address.
+func (f onelineFormat) party(address string) string {
+	if name, ok := f.Names[address]; ok {
+		return shorten(name, onelinePartyWidth-2)
+	}
+	return shortAddress(address)
+}
+
+// label shows the label of the transaction with signature, or nothing when it has none. Transfers
+// within a group pass no signature and leave the label to the header line.
+func (f onelineFormat) label(signature string) string {
+	label, ok := f.Labels[signature]
+	if signature == "" || !ok {
+		return ""
+	}
+	return "# " + label.String()
+}
+
+// directionColor is the colour of a direction column: red for money out, green for money in.
+func directionColor(direction string) *color.Color {
+	var c *color.Color
+	switch direction {
+	case "SENT":
+		c = color.New(color.FgRed)
+	case "RECV", "RWRD":
+		c = color.New(color.FgGreen)
+	default:
+		c = color.New(color.Bold)
+	}
+	// Whether to colour is the formatter's decision, not the terminal's.
+	c.EnableColor()
+	return c
+}
+
+// onelineSOL shows SOL to four decimals, or in full when that would round a non-zero amount to zero.
+func onelineSOL(sol decimal.Decimal) string {
+	if !sol.IsZero() && sol.Abs().LessThan(decimal.New(1, -4)) {
+		return sol.String()
+	}
+	return sol.StringFixed(4)
+}
+
+// feeField shows the fee of a transaction, or nothing when the wallet paid none.
+func feeField(fee uint64) string {
+	if fee == 0 {
+		return ""
+	}
+	return "fee " + wallet.LamportsToSOL(fee).String()
+}
+
+// signatureField shows the start of a signature, enough to find it in an explorer's search.
+func signatureField(signature string) string {
+	if signature == "" {
+		return ""
+	}
+	return "sig " + shorten(signature, 5)
+}
+
+// shortAddress shows the first and last three characters of an address.
+func shortAddress(address string) string {
+	if utf8.RuneCountInString(address) <= 7 {
+		return address
+	}
+	runes := []rune(address)
+	return string(runes[:3]) + "…" + string(runes[len(runes)-3:])
+}
+
+// shorten cuts s to at most width runes, ending in "…" when it was cut.
+func shorten(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+func padLeft(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
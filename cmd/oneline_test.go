@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// Addresses of the oneline fixture.
+var (
+	onelineSelf  = solana.PublicKey{1}
+	onelineAlice = solana.PublicKey{2}
+	onelineBob   = solana.PublicKey{3}
+)
+
+// onelineHistory is a history with a received and a sent transfer, a token transfer and a
+// transaction with two transfers, newest first.
+func onelineHistory() []*wallet.TransactionGroup {
+	self, alice, bob := onelineSelf, onelineAlice, onelineBob
+	usdc := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	at := func(day, hour, minute int) time.Time { return time.Date(2024, 5, day, hour, minute, 0, 0, time.UTC) }
+
+	return wallet.GroupTransactions([]*wallet.Transaction{
+		{Signature: solana.Signature{1}, Amount: 250_000_000, Fee: 5000, From: self, To: alice, IsSender: true, Timestamp: at(2, 14, 3)},
+		{Signature: solana.Signature{2}, Amount: 1_500_000_000, From: bob, To: self, Timestamp: at(1, 9, 30)},
+		{Signature: solana.Signature{3}, Amount: 12_500_000, From: self, To: bob, IsSender: true, Fee: 5000, Mint: usdc, Symbol: "USDC", Decimals: 6, Timestamp: at(1, 8, 15)},
+		{Signature: solana.Signature{4}, Amount: 100_000_000, Fee: 10_000, From: self, To: alice, IsSender: true, Timestamp: at(1, 7, 0)},
+		{Signature: solana.Signature{4}, Amount: 3000, Fee: 10_000, From: self, To: bob, IsSender: true, Timestamp: at(1, 7, 0)},
+	})
+}
+
+func TestOnelineGolden(t *testing.T) {
+	rate := decimal.NewFromFloat(124.8)
+	names := map[string]string{onelineBob.String(): "bob's exchange deposit"}
+	labels := map[string]wallet.TransactionLabel{
+		solana.Signature{1}.String(): {Text: "office rent", Category: "rent"},
+		solana.Signature{4}.String(): {Text: "payroll"},
+	}
+
+	tests := []struct {
+		name   string
+		format onelineFormat
+		locale wallet.Locale
+	}{
+		{name: "wide", format: onelineFormat{Rate: &rate, Currency: wallet.DefaultCurrency, Names: names, Width: 120}},
+		{name: "without_rate", format: onelineFormat{Currency: wallet.DefaultCurrency, Names: names, Width: 120}},
+		{name: "narrow", format: onelineFormat{Rate: &rate, Currency: wallet.DefaultCurrency, Names: names, Width: 72}},
+		{name: "very_narrow", format: onelineFormat{Rate: &rate, Currency: wallet.DefaultCurrency, Names: names, Width: 40}},
+		{name: "color", format: onelineFormat{Rate: &rate, Currency: wallet.DefaultCurrency, Names: names, Width: 120, Color: true}},
+		{name: "german", format: onelineFormat{Rate: &rate, Currency: wallet.DefaultCurrency, Names: names, Width: 120}, locale: "de-DE"},
+		{name: "labeled", format: onelineFormat{Rate: &rate, Currency: wallet.DefaultCurrency, Names: names, Labels: labels, Width: 150}},
+		{name: "labeled_narrow", format: onelineFormat{Rate: &rate, Currency: wallet.DefaultCurrency, Names: names, Labels: labels, Width: 100}},
+	}
+	defer wallet.SetLocale(wallet.DefaultLocale)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallet.SetLocale(tt.locale)
+			lines := tt.format.Lines(onelineHistory())
+			got := strings.Join(lines, "\n") + "\n"
+
+			golden := filepath.Join("testdata", "oneline_"+tt.name+".golden")
+			if *updateGolden {
+				assert.NoError(t, os.WriteFile(golden, []byte(got), 0644))
+			}
+			want, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(want), got)
+
+			if !tt.format.Color {
+				for _, line := range lines {
+					assert.Less(t, utf8.RuneCountInString(line), tt.format.Width, line)
+				}
+			}
+		})
+	}
+}
+
+func TestOnelineColorKeepsWidth(t *testing.T) {
+	plain := onelineFormat{Width: 60}.Lines(onelineHistory())
+	colored := onelineFormat{Width: 60, Color: true}.Lines(onelineHistory())
+	assert.Len(t, colored, len(plain))
+	for i := range plain {
+		assert.NotEqual(t, plain[i], colored[i])
+		assert.Equal(t, plain[i], stripANSI(colored[i]))
+	}
+}
+
+// stripANSI removes the colour escape sequences of fatih/color.
+func stripANSI(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' {
+			for i < len(s) && s[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func TestOnelineReward(t *testing.T) {
+	stake := solana.PublicKey{5}
+	groups := wallet.GroupTransactions([]*wallet.Transaction{
+		{Amount: 250_000, From: stake, To: stake, Reward: true, Epoch: 598, Timestamp: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+	})
+	lines := onelineFormat{Width: 120, Location: time.UTC}.Lines(groups)
+	if assert.Len(t, lines, 1) {
+		assert.Contains(t, lines[0], "RWRD")
+		assert.Contains(t, lines[0], "epoch 598 → ")
+		assert.NotContains(t, lines[0], "sig ")
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+)
+
+// Output formats accepted by --output.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// validateOutput rejects unknown --output formats before a command runs.
+func validateOutput() error {
+	switch outputFlag {
+	case outputText, outputJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q, expected text or json", outputFlag)
+	}
+}
+
+// jsonOutput reports whether commands should print JSON instead of human-readable text.
+func jsonOutput() bool {
+	return outputFlag == outputJSON
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printWarning prints a warning to stderr, keeping stdout clean for JSON output.
+func printWarning(format string, args ...interface{}) {
+	color.New(color.FgYellow).Fprintf(os.Stderr, format, args...)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var pendingCmd = &cobra.Command{
+	Use:         "pending",
+	Short:       "Lists transactions sent from this machine and their status",
+	Annotations: keyAccess(keyAccessNone),
+	RunE:        listPending,
+}
+
+var cancelCmd = &cobra.Command{
+	Use:         "cancel [pending-id]",
+	Short:       "Cancels a stuck transaction that was sent with --nonce-account",
+	Annotations: keyAccess(keyAccessPrivate),
+	Long: `Cancels a stuck transaction by submitting a zero-value self-transfer that advances the
+same durable nonce, which makes the original transaction invalid.
+
+Only transactions sent with --nonce-account can be cancelled. Transactions that use a recent
+blockhash expire on their own and can only be awaited.`,
+	Args: cobra.ExactArgs(1),
+	RunE: cancelPending,
+}
+
+func listPending(_ *cobra.Command, _ []string) error {
+	rc, err := newReadOnlyContext()
+	if err != nil {
+		return err
+	}
+
+	pending, err := rc.ListPending()
+	if err != nil {
+		return fmt.Errorf("failed to list pending transactions: %w", err)
+	}
+
+	if len(pending) == 0 {
+		fmt.Println("No pending transactions to display.")
+		return nil
+	}
+
+	for _, tx := range pending {
+		nonce := "no"
+		if tx.NonceAccount != "" {
+			nonce = tx.NonceAccount
+		}
+		fmt.Printf(
+			"ID: %s\nStatus: %s\nTo: %s\nLamports: %d\nNetwork: %s\nNonce Account: %s\nCreated: %s\n---\n",
+			tx.ID,
+			tx.Status,
+			tx.To,
+			tx.Lamports,
+			tx.Network,
+			nonce,
+			tx.CreatedAt.Format(time.RFC3339),
+		)
+	}
+
+	return nil
+}
+
+func cancelPending(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	signature, err := wc.CancelPending(context.Background(), args[0])
+	if errors.Is(err, wallet.ErrAlreadyLanded) {
+		printBlue("Transaction %s already landed on chain and can no longer be cancelled.\n", args[0])
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to cancel transaction: %w", err)
+	}
+
+	printBlue("Transaction %s cancelled. Cancellation Signature: %s\n", args[0], signature)
+	return nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// Commands that can destroy keys work out a wallet.KeystorePlan first, preview it, and only write it
+// once confirmed. --dry-run stops after the preview.
+const (
+	dryRunFlag = "dry-run"
+	yesFlag    = "yes"
+)
+
+var errCancelled = errors.New("cancelled, the key file was not changed")
+
+// addPlanFlags adds --dry-run and --yes to a command that changes the key file through a plan.
+func addPlanFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool(dryRunFlag, false, "Print what would change in the key file without writing it")
+	cmd.Flags().BoolP(yesFlag, "y", false, "Apply the changes without asking for confirmation")
+}
+
+// isDryRun reports whether --dry-run was given.
+func isDryRun(cmd *cobra.Command) bool {
+	dryRun, _ := cmd.Flags().GetBool(dryRunFlag)
+	return dryRun
+}
+
+// printPlan previews what applying plan would change in the key file.
+func printPlan(plan *wallet.KeystorePlan) {
+	if plan.Empty() {
+		printBlue("The key file would not change.\n")
+		return
+	}
+
+	printBlue("The key file would change as follows:\n")
+	for _, change := range plan.Changes {
+		printBlue("  - %s\n", change)
+	}
+}
+
+// applyPlan previews plan and, unless this is a dry run, asks for confirmation and writes it. It
+// reports whether the key file was written. A nil confirm asks a yes/no question, which --yes skips.
+func applyPlan(cmd *cobra.Command, wc *wallet.WalletConfig, plan *wallet.KeystorePlan, confirm func() error) (bool, error) {
+	printPlan(plan)
+
+	if isDryRun(cmd) {
+		printBlue("Dry run: nothing was written.\n")
+		return false, nil
+	}
+	if plan.Empty() {
+		return false, nil
+	}
+
+	if confirm == nil {
+		confirm = confirmPlan
+		if yes, _ := cmd.Flags().GetBool(yesFlag); yes {
+			confirm = func() error { return nil }
+		}
+	}
+	if err := confirm(); err != nil {
+		return false, err
+	}
+
+	if err := wc.ApplyKeystorePlan(plan); err != nil {
+		return false, fmt.Errorf("failed to update key file: %w", err)
+	}
+	return true, nil
+}
+
+// confirmPlan asks whether to apply the previewed changes.
+func confirmPlan() error {
+	ok, err := promptForConfirmation("Apply these changes")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errCancelled
+	}
+	return nil
+}
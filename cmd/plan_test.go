@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestDryRunLeavesKeyFileUntouched(t *testing.T) {
+	workDir := t.TempDir()
+	configDir := t.TempDir()
+	t.Setenv(wallet.ConfigDirEnv, configDir)
+	t.Setenv(passphraseEnv, "correct horse")
+
+	keyOps := &wallet.KeyOps{FileReader: &wallet.IOUtilFileReader{}, FileWriter: &wallet.IOUtilFileWriter{}, Dir: configDir}
+	for _, alias := range []string{"main", "savings"} {
+		key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+		assert.NoError(t, keyOps.WriteKeyToFile(alias, key, alias+"Address"))
+	}
+	keyFile := filepath.Join(configDir, wallet.KeyFileName)
+	original, err := os.ReadFile(keyFile)
+	assert.NoError(t, err)
+
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(workDir))
+	defer os.Chdir(cwd)
+	defer RootCmd.SetArgs(nil)
+
+	for _, args := range [][]string{
+		{"remove", "savings", "--dry-run"},
+		{"remove", "main", "--dry-run"},
+		{"keystore", "encrypt", "--dry-run"},
+	} {
+		RootCmd.SetArgs(args)
+		assert.NoError(t, RootCmd.Execute(), args)
+
+		got, err := os.ReadFile(keyFile)
+		assert.NoError(t, err)
+		assert.Equal(t, original, got, args)
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manages profiles that keep separate sets of wallets",
+	Long: `Profiles keep wallets, pending transactions and settings apart, e.g. personal and work.
+Each profile has its own key file, so wallets never show up in another profile's selector.
+Moving a wallet between profiles means exporting it from one and importing it into the other.`,
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:         "create [name]",
+	Short:       "Creates a new, empty profile",
+	Annotations: keyAccess(keyAccessNone),
+	Args:        cobra.ExactArgs(1),
+	RunE:        createProfile,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists all profiles and marks the active one",
+	Annotations: keyAccess(keyAccessNone),
+	RunE:        listProfiles,
+}
+
+var profileSwitchCmd = &cobra.Command{
+	Use:         "switch [name]",
+	Short:       "Makes a profile the default for future commands",
+	Annotations: keyAccess(keyAccessNone),
+	Args:        cobra.ExactArgs(1),
+	RunE:        switchProfile,
+}
+
+func init() {
+	profileCmd.AddCommand(profileCreateCmd, profileListCmd, profileSwitchCmd)
+}
+
+func createProfile(_ *cobra.Command, args []string) error {
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+
+	if err := profiles.Create(args[0]); err != nil {
+		return fmt.Errorf("failed to create profile: %w", err)
+	}
+
+	printBlue("Profile %s created. Use it with --profile %s or `wallet profile switch %s`\n", args[0], args[0], args[0])
+	return nil
+}
+
+func listProfiles(_ *cobra.Command, _ []string) error {
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+
+	names, err := profiles.List()
+	if err != nil {
+		return fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	active := profileFlag
+	if active == "" {
+		active, err = profiles.Active()
+		if err != nil {
+			return fmt.Errorf("failed to read active profile: %w", err)
+		}
+	}
+
+	for _, name := range names {
+		if name == active {
+			printBlue("%s (Active)\n", name)
+			continue
+		}
+		fmt.Println(name)
+	}
+
+	return nil
+}
+
+func switchProfile(_ *cobra.Command, args []string) error {
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+
+	if err := profiles.Switch(args[0]); err != nil {
+		return fmt.Errorf("failed to switch profile: %w", err)
+	}
+
+	printBlue("Switched to profile %s\n", args[0])
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/manifoldco/promptui"
+)
+
+// ErrInputClosed is returned by every prompt when stdin is closed or exhausted, or the prompt is
+// interrupted, so interactive flows stop instead of asking again.
+var ErrInputClosed = errors.New("input closed — aborting")
+
+// ExitInputClosed is the exit code of a command aborted by ErrInputClosed.
+const ExitInputClosed = 3
+
+// promptStdin and promptStdout are what prompts read from and write to; nil means the terminal.
+// Tests replace them to drive interactive flows.
+var (
+	promptStdin  io.ReadCloser
+	promptStdout io.WriteCloser
+)
+
+// promptError translates the errors promptui returns when input ends (^D, a closed pipe) or is
+// interrupted (^C) into ErrInputClosed. Other errors are returned unchanged.
+func promptError(err error) error {
+	if errors.Is(err, promptui.ErrEOF) || errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, io.EOF) {
+		return ErrInputClosed
+	}
+	return err
+}
+
+// utf8Terminal reports whether the locale of the terminal can display UTF-8. The first of LC_ALL,
+// LC_CTYPE and LANG that is set decides, as for the C library; with none set UTF-8 is assumed.
+func utf8Terminal() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		locale := os.Getenv(name)
+		if locale == "" {
+			continue
+		}
+		locale = strings.ToLower(locale)
+		return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+	}
+	return true
+}
+
+// selectTemplates returns the templates of choice prompts, using ASCII icons on terminals that cannot
+// display the default ones.
+func selectTemplates() *promptui.SelectTemplates {
+	if utf8Terminal() {
+		return templates
+	}
+	return &promptui.SelectTemplates{
+		Label:    templates.Label,
+		Selected: templates.Selected,
+		Active:   "> {{ . | underline }}",
+		Inactive: "  {{ . }}",
+	}
+}
+
+// promptTemplates returns the templates of text prompts; nil keeps the promptui defaults.
+func promptTemplates() *promptui.PromptTemplates {
+	if utf8Terminal() {
+		return nil
+	}
+	return &promptui.PromptTemplates{
+		Prompt:  `{{ "?" | blue }} {{ . | bold }}: `,
+		Valid:   `{{ "v" | green }} {{ . | bold }}: `,
+		Invalid: `{{ "x" | red }} {{ . | bold }}: `,
+		Success: `{{ . | faint }}: `,
+		Confirm: `{{ "?" | bold }} {{ . | bold }}? {{ "[y/N]" | faint }} `,
+	}
+}
+
+// validUTF8 wraps validate so input that is not valid UTF-8, as typed on a terminal using another
+// encoding, is rejected before it reaches validate.
+func validUTF8(validate func(input string) error) func(input string) error {
+	return func(input string) error {
+		if !utf8.ValidString(input) {
+			return fmt.Errorf("input is not valid UTF-8; check the encoding of your terminal")
+		}
+		if validate == nil {
+			return nil
+		}
+		return validate(input)
+	}
+}
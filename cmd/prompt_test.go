@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/gagliardetto/solana-go"
+	"github.com/manifoldco/promptui"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// arrowDown moves the selection of a choice prompt one item down.
+const arrowDown = "\x1b[B"
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// skipUnderRace skips a test that drives promptui through whole prompts over piped input. promptui
+// reads its input on a goroutine that races with Run on the cursor and screen buffer, so the race
+// detector fails such tests although the prompts work. They run in normal builds.
+func skipUnderRace(t *testing.T) {
+	t.Helper()
+	if raceEnabled {
+		t.Skip("promptui races with itself over piped input")
+	}
+}
+
+// withStdin makes prompts read input, as if piped, until the test ends.
+func withStdin(t *testing.T, input string) {
+	promptStdin = io.NopCloser(strings.NewReader(input))
+	promptStdout = nopWriteCloser{io.Discard}
+	t.Cleanup(func() { promptStdin, promptStdout = nil, nil })
+}
+
+func TestPromptsAbortOnClosedInput(t *testing.T) {
+	prompts := map[string]func() error{
+		"choice": func() error {
+			_, err := promptForChoice("Pick one", []string{"a", "b"})
+			return err
+		},
+		"searchable choice": func() error {
+			_, err := promptForSearchableChoice("Pick one", []string{"a", "b"})
+			return err
+		},
+		"input": func() error {
+			_, err := promptForInput("Name", nil)
+			return err
+		},
+		"validated input": func() error {
+			_, err := promptForInput("Type main to confirm", func(input string) error {
+				if input != "main" {
+					return errors.New("type main exactly")
+				}
+				return nil
+			})
+			return err
+		},
+		"confirmation": func() error {
+			_, err := promptForConfirmation("Send this transfer")
+			return err
+		},
+		"secret": func() error {
+			_, err := promptForSecret("Passphrase")
+			return err
+		},
+	}
+
+	for name, prompt := range prompts {
+		for _, input := range []string{"", "mai"} {
+			t.Run(name+"/"+input, func(t *testing.T) {
+				withStdin(t, input)
+				assert.ErrorIs(t, prompt(), ErrInputClosed)
+			})
+		}
+	}
+}
+
+func TestPromptsReadPipedInput(t *testing.T) {
+	skipUnderRace(t)
+	withStdin(t, arrowDown+"\r")
+	choice, err := promptForChoice("Pick one", []string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", choice)
+
+	withStdin(t, "main\r")
+	input, err := promptForInput("Name", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "main", input)
+
+	withStdin(t, "n\r")
+	confirmed, err := promptForConfirmation("Send this transfer")
+	assert.NoError(t, err)
+	assert.False(t, confirmed)
+}
+
+func TestPostInitializationMenuStopsWhenInputCloses(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	key := ed25519.NewKeyFromSeed(seed)
+	address := solana.PublicKeyFromBytes(key.Public().(ed25519.PublicKey)).String()
+
+	wc := wallet.NewWalletConfigInDir(t.TempDir())
+	assert.NoError(t, wc.KeyOps.WriteKeyToFile("main", key, address))
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "closed before the first choice", input: ""},
+		{name: "closed after a choice", input: arrowDown + arrowDown + "\r", want: []string{address}},
+		{name: "closed while sending", input: strings.Repeat(arrowDown, 4) + "\r" + address + "\r0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var printed bytes.Buffer
+			output := color.Output
+			color.Output = &printed
+			defer func() { color.Output = output }()
+
+			withStdin(t, tt.input)
+			assert.ErrorIs(t, postWalletInitializationActions(&initFlow{ctx: context.Background()}, wc), ErrInputClosed)
+			for _, want := range tt.want {
+				assert.Contains(t, printed.String(), want)
+			}
+		})
+	}
+}
+
+func TestPromptError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{err: promptui.ErrEOF, want: ErrInputClosed},
+		{err: promptui.ErrInterrupt, want: ErrInputClosed},
+		{err: io.EOF, want: ErrInputClosed},
+		{err: promptui.ErrAbort, want: promptui.ErrAbort},
+		{err: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, promptError(tt.err))
+	}
+}
+
+func TestUTF8Terminal(t *testing.T) {
+	tests := []struct {
+		lcAll, lcCtype, lang string
+		want                 bool
+	}{
+		{want: true},
+		{lang: "en_US.UTF-8", want: true},
+		{lang: "de_DE.utf8", want: true},
+		{lang: "C", want: false},
+		{lcCtype: "en_US.ISO-8859-1", lang: "en_US.UTF-8", want: false},
+		{lcAll: "en_GB.UTF-8", lcCtype: "POSIX", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_CTYPE", tt.lcCtype)
+		t.Setenv("LANG", tt.lang)
+		assert.Equal(t, tt.want, utf8Terminal(), tt)
+		assert.Equal(t, tt.want, promptTemplates() == nil, tt)
+	}
+}
+
+func TestValidUTF8(t *testing.T) {
+	validate := validUTF8(nil)
+	assert.NoError(t, validate("größe"))
+
+	err := validate("gr\xf6\xdfe")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not valid UTF-8")
+}
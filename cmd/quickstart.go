@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var quickstartCmd = &cobra.Command{
+	Use:         "quickstart",
+	Short:       "Walks through funding a devnet wallet and sending a first transfer",
+	Annotations: usesFlags(keyAccess(keyAccessPrivate), usesAlias),
+	Long: `Runs the first steps on devnet with the same operations the other commands use: selects the
+active wallet (or --alias), creating one named quickstart when there is none, airdrops 1 SOL to it
+unless it already holds that much, creates a scratch wallet, sends 0.001 SOL to it and shows the
+transfer once it appears in the history. Ends with the commands that do each step by hand.
+
+Press Ctrl-C to stop after the step in progress. --cleanup sends the scratch wallet's balance back
+and removes it. Refuses to run on mainnet-beta.`,
+	Args: cobra.NoArgs,
+	RunE: runQuickstart,
+}
+
+var quickstartCleanup bool
+
+func init() {
+	quickstartCmd.Flags().BoolVar(&quickstartCleanup, "cleanup", false, "Send the scratch wallet's balance back and remove it at the end")
+}
+
+// quickstartStepTitles describes the steps as they start.
+var quickstartStepTitles = map[wallet.QuickstartStep]string{
+	wallet.QuickstartWallet:  "Selecting a wallet",
+	wallet.QuickstartAirdrop: "Requesting an airdrop and waiting for it",
+	wallet.QuickstartScratch: "Creating a scratch wallet to send to",
+	wallet.QuickstartSend:    "Sending a small amount to the scratch wallet",
+	wallet.QuickstartHistory: "Waiting for the transfer to show up in the history",
+}
+
+func runQuickstart(cmd *cobra.Command, _ []string) error {
+	if wallet.ReadOnlyMode() {
+		return fmt.Errorf("the quickstart creates wallets and sends funds, so it does not run in read-only mode")
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	number := 0
+	result, err := wc.Quickstart(ctx, wallet.QuickstartOptions{Alias: aliasFlag}, func(step wallet.QuickstartStep) {
+		number++
+		printBlue("[%d/%d] %s...\n", number, len(wallet.QuickstartSteps), quickstartStepTitles[step])
+	})
+	if result != nil {
+		printQuickstartResult(result)
+		printQuickstartCommands(wc, result)
+	}
+	if result != nil && errors.Is(err, context.Canceled) {
+		printYellow("Quickstart stopped after %d of %d steps.\n", len(result.Completed), len(wallet.QuickstartSteps))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("quickstart failed: %w", err)
+	}
+
+	if !quickstartCleanup {
+		fmt.Printf("Keep %s to experiment with, or run again with --cleanup to remove it.\n", result.ScratchAlias)
+		return nil
+	}
+	receipt, err := wc.RemoveQuickstartScratch(ctx, result)
+	if err != nil {
+		return fmt.Errorf("failed to clean up: %w", err)
+	}
+	fmt.Printf("Sent %s SOL back from %s and removed it. Transaction Signature: %s\n", receipt.SOL(), result.ScratchAlias, receipt.Signature)
+	return nil
+}
+
+// printQuickstartResult shows what each completed step did.
+func printQuickstartResult(result *wallet.QuickstartResult) {
+	for _, step := range result.Completed {
+		switch step {
+		case wallet.QuickstartWallet:
+			verb := "Using"
+			if result.Created {
+				verb = "Created"
+			}
+			fmt.Printf("%s wallet %s (%s).\n", verb, result.Alias, result.Address)
+		case wallet.QuickstartAirdrop:
+			if result.Airdropped == 0 {
+				fmt.Printf("No airdrop needed: %s already holds %s SOL.\n", result.Alias, wallet.LamportsToSOL(result.Balance))
+			} else {
+				fmt.Printf("Airdropped %s SOL; the balance is now %s SOL. Transaction Signature: %s\n",
+					wallet.LamportsToSOL(result.Airdropped), wallet.LamportsToSOL(result.Balance), result.AirdropSignature)
+			}
+		case wallet.QuickstartScratch:
+			verb := "Reusing"
+			if result.ScratchCreated {
+				verb = "Created"
+			}
+			fmt.Printf("%s scratch wallet %s (%s).\n", verb, result.ScratchAlias, result.ScratchAddress)
+		case wallet.QuickstartSend:
+			fmt.Printf("Sent %s to %s. Transaction Signature: %s\n",
+				formatSOLAndFiat(result.Receipt.SOL(), result.Receipt.Fiat, result.Receipt.Currency), result.ScratchAlias, result.Receipt.Signature)
+		case wallet.QuickstartHistory:
+			if result.Transaction == nil {
+				printYellow("The transfer is not finalized yet; it shows up in `wallet transactions` shortly.\n")
+				continue
+			}
+			fmt.Println("The transfer in your history:")
+			printTransaction(result.Transaction, nil, nil, result.Receipt.Currency, map[string]string{result.ScratchAddress: result.ScratchAlias}, wallet.TransactionLabel{})
+		}
+	}
+}
+
+// printQuickstartCommands lists the commands that do the completed steps by hand.
+func printQuickstartCommands(wc *wallet.WalletConfig, result *wallet.QuickstartResult) {
+	if len(result.Completed) == 0 {
+		return
+	}
+
+	var commands [][2]string
+	for _, step := range result.Completed {
+		switch step {
+		case wallet.QuickstartWallet:
+			commands = append(commands, [2]string{"wallet init", "create, import or select a wallet"})
+		case wallet.QuickstartAirdrop:
+			commands = append(commands, [2]string{"wallet balance", "check the balance; `send --auto-fund` airdrops a shortfall on devnet"})
+		case wallet.QuickstartScratch:
+			commands = append(commands, [2]string{"wallet address --all", "list the addresses of your wallets"})
+		case wallet.QuickstartSend:
+			commands = append(commands, [2]string{fmt.Sprintf("wallet send %s %s --unit sol", result.Receipt.SOL(), result.ScratchAddress), "send SOL"})
+		case wallet.QuickstartHistory:
+			commands = append(commands, [2]string{"wallet transactions --limit 1", "show the latest transaction"})
+		}
+	}
+	if result.ScratchAlias != "" {
+		commands = append(commands, [2]string{"wallet remove " + result.ScratchAlias + " --force", "remove the scratch wallet"})
+	}
+
+	fmt.Printf("\nThe commands behind each step, on %s:\n", wc.NetworkName())
+	for _, c := range commands {
+		fmt.Printf("  %-60s %s\n", c[0], color.New(color.Faint).Sprint("# "+c[1]))
+	}
+}
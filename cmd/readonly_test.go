@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestReadOnlyModeRefusesWrites(t *testing.T) {
+	workDir := t.TempDir()
+	configDir := t.TempDir()
+	t.Setenv(wallet.ConfigDirEnv, configDir)
+
+	keyOps := &wallet.KeyOps{FileReader: &wallet.IOUtilFileReader{}, FileWriter: &wallet.IOUtilFileWriter{}, Dir: configDir}
+	for i, alias := range []string{"main", "savings"} {
+		seed := make([]byte, ed25519.SeedSize)
+		seed[0] = byte(i)
+		key := ed25519.NewKeyFromSeed(seed)
+		assert.NoError(t, keyOps.WriteKeyToFile(alias, key, solana.PublicKeyFromBytes(key.Public().(ed25519.PublicKey)).String()))
+	}
+	keyFile := filepath.Join(configDir, wallet.KeyFileName)
+	original, err := os.ReadFile(keyFile)
+	assert.NoError(t, err)
+	// The key file lives in the configuration directory of the default profile; nothing may join it.
+	before, err := os.ReadDir(configDir)
+	assert.NoError(t, err)
+
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(workDir))
+	defer os.Chdir(cwd)
+	defer RootCmd.SetArgs(nil)
+	defer func() {
+		readOnlyFlag, aliasFlag, networkFlag = false, "", ""
+		wallet.SetReadOnlyMode(false)
+	}()
+
+	run := func(args ...string) error {
+		defer resetScopedFlags()
+		RootCmd.SetArgs(append([]string{"--read-only"}, args...))
+		return RootCmd.Execute()
+	}
+
+	for _, args := range [][]string{
+		{"address", "--all"},
+		{"info", "--alias", "savings"},
+		{"--network", "testnet", "address"},
+		{"profile", "list"},
+	} {
+		assert.NoError(t, run(args...), args)
+	}
+
+	for _, args := range [][]string{
+		{"rename", "savings", "spare"},
+		{"note", "main", "rent"},
+		{"profile", "create", "ci"},
+		{"profile", "switch", "default"},
+		{"currency", "usd"},
+		{"request", "export", "1", "--unit", "sol", "--out", filepath.Join(workDir, "request.json")},
+		{"transactions", "--export", "csv", "--file", filepath.Join(workDir, "history.csv")},
+	} {
+		err := run(args...)
+		assert.ErrorIs(t, err, wallet.ErrReadOnlyMode, args)
+	}
+
+	got, err := os.ReadFile(keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, original, got)
+
+	after, err := os.ReadDir(configDir)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+	assert.NoFileExists(t, filepath.Join(workDir, "request.json"))
+	assert.NoFileExists(t, filepath.Join(workDir, "history.csv"))
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var receiveCmd = &cobra.Command{
+	Use:         "receive [amount]",
+	Short:       "Prints the wallet address as a QR code to scan with a phone wallet",
+	Annotations: usesFlags(keyAccess(keyAccessPublic), usesAlias, usesTransientKey),
+	Long: `Prints the address of the active wallet, or of --alias, as a QR code another wallet can scan to pay
+it without copying the address. With an amount, or --label or --message, the code holds a Solana Pay
+URL asking for that amount, which phone wallets fill in for the payer. The amount is in the selected
+currency unless --unit says otherwise, and is converted to SOL at the current rate. The code is
+drawn for a dark terminal background; use --invert on a light one.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: receive,
+}
+
+var (
+	receiveUnit    string
+	receiveLabel   string
+	receiveMessage string
+	receiveInvert  bool
+)
+
+func init() {
+	receiveCmd.Flags().StringVar(&receiveUnit, "unit", "", "Unit of the amount: sol, lamports or a currency code such as eur (defaults to the selected --currency)")
+	receiveCmd.Flags().StringVar(&receiveLabel, "label", "", "Name of the recipient shown to the payer, such as a shop name")
+	receiveCmd.Flags().StringVar(&receiveMessage, "message", "", "Text shown to the payer, such as what the payment is for")
+	receiveCmd.Flags().BoolVar(&receiveInvert, "invert", false, "Draw the QR code for a terminal with a light background")
+}
+
+// receiveOutput is the JSON form of what receive shows.
+type receiveOutput struct {
+	Alias     string `json:"alias"`
+	PublicKey string `json:"publicKey"`
+	URL       string `json:"url"`
+	Lamports  uint64 `json:"lamports,omitempty"`
+}
+
+func receive(_ *cobra.Command, args []string) error {
+	unit, err := wallet.ParseUnit(receiveUnit)
+	if err != nil {
+		return err
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	info, err := wc.GetWalletInfo(aliasFlag)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet info: %w", err)
+	}
+	recipient, err := solana.PublicKeyFromBase58(info.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid address for %s: %w", info.Alias, err)
+	}
+
+	var lamports uint64
+	if len(args) == 1 {
+		if lamports, err = wc.ReceiveLamports(args[0], unit); err != nil {
+			return err
+		}
+	}
+
+	// A bare address is what every wallet can scan; the URL is only needed to carry more.
+	text := info.PublicKey
+	url := wallet.SolanaPayURL(recipient, lamports, receiveLabel, receiveMessage)
+	if lamports > 0 || receiveLabel != "" || receiveMessage != "" {
+		text = url
+	}
+
+	if jsonOutput() {
+		return printJSON(receiveOutput{Alias: info.Alias, PublicKey: info.PublicKey, URL: url, Lamports: lamports})
+	}
+
+	code, err := wallet.RenderQRCode(text, receiveInvert)
+	if err != nil {
+		return err
+	}
+	fmt.Print(code)
+	printBlue("Address of %s: %s\n", info.Alias, info.PublicKey)
+	if lamports > 0 {
+		printBlue("Amount: %s SOL\n", wallet.LamportsToSOL(lamports))
+	}
+	if text == url {
+		fmt.Println(url)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var removeCmd = &cobra.Command{
+	Use:         "remove [alias]",
+	Short:       "Deletes a wallet from the key file",
+	Annotations: keyAccess(keyAccessPrivate),
+	Long: `Deletes a wallet and its private key from the key file. Export the key first if you may
+need it again. The change is previewed and confirmed before anything is written; --dry-run
+only previews it. Removing the active wallet, or one that still holds SOL, is refused unless
+--force is given and the alias is typed to confirm. When the active wallet is removed,
+the first remaining alias becomes active.`,
+	Args: cobra.ExactArgs(1),
+	RunE: removeWallet,
+}
+
+var forceRemove bool
+
+func init() {
+	removeCmd.Flags().BoolVar(&forceRemove, "force", false, "Remove the wallet even if it is active or holds SOL, after typing its alias")
+	addPlanFlags(removeCmd)
+}
+
+func removeWallet(cmd *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	alias := args[0]
+	info, err := wc.GetWalletInfo(alias)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet info: %w", err)
+	}
+
+	plan, err := wc.PlanRemoveWallet(alias)
+	if err != nil {
+		return fmt.Errorf("failed to remove wallet: %w", err)
+	}
+
+	// A dry run only previews the change, so it does not look up the balance.
+	var confirm func() error
+	if !isDryRun(cmd) {
+		risks, err := wc.RemovalRisks(context.Background(), alias)
+		if err != nil {
+			// Without a balance the removal cannot be shown to be safe.
+			risks = append(risks, fmt.Sprintf("its balance could not be checked (%v)", err))
+		}
+
+		if len(risks) > 0 {
+			reason := strings.Join(risks, " and ")
+			if !forceRemove {
+				return fmt.Errorf("refusing to remove %s: %s; pass --force to remove it anyway", alias, reason)
+			}
+
+			printYellow("Warning: %s. Its private key will be deleted from the key file.\n", reason)
+			confirm = func() error {
+				_, err := promptForInput(fmt.Sprintf("Type %s to confirm", alias), func(input string) error {
+					if input != alias {
+						return fmt.Errorf("type %s exactly", alias)
+					}
+					return nil
+				})
+				if err != nil {
+					return fmt.Errorf("removal cancelled: %w", err)
+				}
+				return nil
+			}
+		}
+	}
+
+	applied, err := applyPlan(cmd, wc, plan, confirm)
+	if err != nil || !applied {
+		return err
+	}
+
+	printBlue("Removed %s.\n", alias)
+	switch {
+	case plan.ActiveAlias == "":
+		printBlue("No wallets are left; run init to create or import one.\n")
+	case info.Active:
+		printBlue("Active wallet is now %s.\n", plan.ActiveAlias)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var renameCmd = &cobra.Command{
+	Use:         "rename [old-alias] [new-alias]",
+	Short:       "Gives a stored wallet a new alias",
+	Annotations: keyAccess(keyAccessPrivate),
+	Args:        cobra.ExactArgs(2),
+	RunE:        renameWallet,
+}
+
+func renameWallet(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := wc.RenameWallet(args[0], args[1]); err != nil {
+		return fmt.Errorf("failed to rename wallet: %w", err)
+	}
+
+	printBlue("Renamed %s to %s.\n", args[0], args[1])
+	return nil
+}
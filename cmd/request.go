@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var requestCmd = &cobra.Command{
+	Use:   "request",
+	Short: "Creates payment requests others can pay with `wallet send --request`",
+}
+
+var requestExportCmd = &cobra.Command{
+	Use:         "export [amount]",
+	Short:       "Writes a payment request file for a wallet",
+	Annotations: usesFlags(keyAccess(keyAccessPublic), usesAlias),
+	Long: `Writes a JSON payment request asking for amount to be paid to a wallet, by default the active
+one. The payer runs "wallet send --request <file>", which shows the memo, reference and expiry before
+asking for confirmation and refuses the request once it has expired. Without --out the request is
+printed to stdout.`,
+	Args: cobra.ExactArgs(1),
+	RunE: exportPaymentRequest,
+}
+
+var (
+	requestUnit      string
+	requestMemo      string
+	requestReference string
+	requestExpiresIn time.Duration
+	requestOut       string
+)
+
+func init() {
+	requestExportCmd.Flags().StringVar(&requestUnit, "unit", "", "Unit of the amount: sol, lamports or a currency code such as eur (defaults to the selected --currency)")
+	requestExportCmd.Flags().StringVar(&requestMemo, "memo", "", "Text shown to the payer, such as an invoice number")
+	requestExportCmd.Flags().StringVar(&requestReference, "reference", "", "Address identifying this payment")
+	requestExportCmd.Flags().DurationVar(&requestExpiresIn, "expires-in", 0, "Refuse the request after this long, e.g. 24h (default never)")
+	requestExportCmd.Flags().StringVar(&requestOut, "out", "", "Write the request to this file instead of stdout")
+	requestCmd.AddCommand(requestExportCmd)
+}
+
+func exportPaymentRequest(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	info, err := wc.GetWalletInfo(aliasFlag)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet info: %w", err)
+	}
+
+	unit := wallet.Unit(requestUnit)
+	if unit == "" {
+		unit = wallet.Unit(wc.FiatCurrency())
+	}
+
+	var expiresAt time.Time
+	if requestExpiresIn > 0 {
+		expiresAt = time.Now().Add(requestExpiresIn)
+	}
+
+	request, err := wallet.NewPaymentRequest(info.PublicKey, args[0], unit, requestMemo, requestReference, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	data, err := request.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if requestOut == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if wallet.ReadOnlyMode() {
+		return fmt.Errorf("%w: %s", wallet.ErrReadOnlyMode, requestOut)
+	}
+	if err := os.WriteFile(requestOut, data, 0644); err != nil {
+		return fmt.Errorf("failed to write payment request: %w", err)
+	}
+	printBlue("Payment request for %s %s to %s written to %s\n", request.Amount, request.Unit, info.Alias, requestOut)
+	return nil
+}
@@ -1,23 +1,394 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"time"
 )
 
 var RootCmd = &cobra.Command{
 	Use:   "wallet",
 	Short: "Solana Wallet CLI",
 	Long:  `A command-line interface to interact with Solana wallet.`,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		// Arguments and flags are valid once this runs, so later errors are not usage errors.
+		cmd.SilenceUsage = true
+		if err := applySettings(cmd); err != nil {
+			return err
+		}
+		if timingsFlag {
+			commandTimer = wallet.StartTimings()
+		}
+		cliOrigin = wallet.Origin{Interface: wallet.InterfaceCLI, Command: cmd.CommandPath()}
+		cmd.SetContext(wallet.WithOrigin(cmd.Context(), cliOrigin))
+		if noColorFlag {
+			color.NoColor = true
+		}
+		wallet.SetReadOnlyMode(readOnlyFlag)
+		// Keypairs exported by `wallet env --with-key` are removed by the first command after they expire.
+		_, _ = wallet.SweepTempKeypairs(time.Now())
+		wallet.SetNodeOptions(nodeOptions())
+		if err := configureRateCache(); err != nil {
+			return err
+		}
+		if err := validateOutput(); err != nil {
+			return err
+		}
+		if err := selectLocale(); err != nil {
+			return err
+		}
+		if err := checkFlagContract(cmd); err != nil {
+			return err
+		}
+		if err := useTransientKey(cmd); err != nil {
+			return err
+		}
+		return validateAliasFlag(cmd)
+	},
+	PersistentPostRunE: func(_ *cobra.Command, _ []string) error {
+		if statsFlag {
+			defer printStats()
+		}
+		if commandTimer != nil {
+			defer printTimings(commandTimer.Report())
+		}
+		return offerToSaveKey()
+	},
 }
 
 var (
 	privateKeyFlag, aliasFlag string
+	networkFlag, rpcURLFlag   string
+	profileFlag               string
+	keyFileFlag               string
+	spendLimitFlag            string
+	rateTTLFlag               string
+	outputFlag                string
+	currencyFlag              string
+	localeFlag                string
+	statsFlag                 bool
+	timingsFlag               bool
+	freshFlag                 bool
+	readOnlyFlag              bool
+	pinNodeFlag               bool
+	verboseFlag               bool
+	noColorFlag               bool
+	strictFlag                bool
+	enforceAllowlistFlag      bool
 )
 
+// cliOrigin is the origin of the command being run, recorded in the audit log.
+var cliOrigin wallet.Origin
+
+// commandTimer measures the phases of the command when --timings is on.
+var commandTimer *wallet.PhaseTimer
+
+// timingsEnv turns on --timings for every command.
+const timingsEnv = "SLEENG_TIMINGS"
+
+// spendLimitEnv sets --spend-limit for every command, so the limit does not depend on remembering the flag.
+const spendLimitEnv = "SLEENG_SPEND_LIMIT"
+
 func init() {
 	RootCmd.PersistentFlags().StringVarP(&privateKeyFlag, "key", "k", "", "A base58 encoded private key to use instead of the one saved on disk")
+	RootCmd.PersistentFlags().StringVar(&saveAsFlag, "save-as", "", "Store the private key given with --key as a wallet with this alias once the command succeeds")
 	RootCmd.PersistentFlags().StringVarP(&aliasFlag, "alias", "a", "", "Optional alias for the wallet")
-	RootCmd.AddCommand(InitCmd, AddressCmd, BalanceCmd, exchangeCmd, transactionsCmd, sendCmd)
+	RootCmd.PersistentFlags().StringVar(&networkFlag, "network", "", "Solana cluster to use: devnet, testnet, mainnet-beta or localnet (remembered between runs; also --cluster)")
+	RootCmd.PersistentFlags().StringVar(&rpcURLFlag, "rpc-url", "", "Custom RPC endpoint, or a comma-separated list tried in order when one is unhealthy; the websocket endpoint is derived from the first")
+	RootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Profile to use (defaults to the one selected with \"wallet profile switch\")")
+	RootCmd.PersistentFlags().StringVar(&keyFileFlag, "keyfile", os.Getenv(wallet.KeyFileEnv), "Key file to use instead of the one of the profile (or set "+wallet.KeyFileEnv+")")
+	RootCmd.PersistentFlags().StringVar(&spendLimitFlag, "spend-limit", os.Getenv(spendLimitEnv), "Refuse SOL transfers larger than this many SOL, from the CLI and the daemon (or set "+spendLimitEnv+")")
+	RootCmd.PersistentFlags().StringVar(&currencyFlag, "currency", os.Getenv(wallet.CurrencyEnv), "Fiat currency for balances, rates, history and amounts, such as EUR, USD, GBP, CHF or NGN (or set "+wallet.CurrencyEnv+"; defaults to the one stored with \"wallet currency\")")
+	RootCmd.PersistentFlags().StringVar(&localeFlag, "locale", os.Getenv(wallet.LocaleEnv), "Locale fiat amounts are shown in, such as en-IE or de-DE (or set "+wallet.LocaleEnv+"; defaults to the one stored with \"wallet locale\")")
+	RootCmd.PersistentFlags().StringVar(&rateTTLFlag, "rate-ttl", os.Getenv(wallet.RateCacheTTLEnv), "How long an exchange rate fetched by one command is reused by the next ones, such as 10m; 0 turns this off (or set "+wallet.RateCacheTTLEnv+"; defaults to "+wallet.DefaultRateCacheTTL.String()+")")
+	RootCmd.PersistentFlags().BoolVar(&freshFlag, "fresh", false, "Fetch exchange rates instead of reusing the ones earlier commands fetched")
+	RootCmd.PersistentFlags().BoolVarP(&verboseFlag, "verbose", "v", false, "Print details such as every change made to the key file")
+	RootCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", outputText, "Output format of address, balance, exchange and transactions: text or json")
+	RootCmd.PersistentFlags().BoolVar(&readOnlyFlag, "read-only", false, "Safe mode: refuse every write to disk, including the key file, profiles, caches and the clipboard")
+	RootCmd.PersistentFlags().BoolVar(&pinNodeFlag, "pin-node", false, "Send every RPC request of the command to the same backend node where the provider allows it")
+	RootCmd.PersistentFlags().BoolVar(&noColorFlag, "no-color", false, "Print without colours (also set by NO_COLOR or when stdout is not a terminal)")
+	RootCmd.PersistentFlags().BoolVar(&enforceAllowlistFlag, "enforce-allowlist", false, "Refuse sends, from the CLI and the daemon, to any address not on \"wallet allowlist\", even when the key file does not enforce it")
+	RootCmd.PersistentFlags().BoolVar(&strictFlag, "strict", false, "Fail instead of warning when the RPC endpoint serves another cluster than the selected network")
+	RootCmd.PersistentFlags().BoolVar(&statsFlag, "stats", false, "Print cache statistics and the RPC endpoints used after the command finishes")
+	RootCmd.PersistentFlags().BoolVar(&timingsFlag, "timings", os.Getenv(timingsEnv) == "1", "Print how long each phase of the command took, with hints when a slow path dominated (or set "+timingsEnv+"=1)")
+	RootCmd.SetGlobalNormalizationFunc(flagAliases)
+	RootCmd.AddCommand(InitCmd, AddressCmd, infoCmd, verifyDerivationCmd, verifyBackupCmd, noteCmd, BalanceCmd, exchangeCmd, transactionsCmd, sendCmd, consolidateCmd, pendingCmd, cancelCmd, keystoreCmd, auditCmd, profileCmd, daemonCmd, renameCmd, removeCmd, exportCmd, currencyCmd, requestCmd, statsCmd, addWatchCmd, snapshotCmd, watchCmd, syncCmd, contactsCmd, multisigCmd, doctorCmd, envCmd, scanSeedCmd, localeCmd, quickstartCmd, receiveCmd, sendManyCmd, failsafeCmd, allowlistCmd, labelCmd, rpcCmd, nftsCmd, stakeCmd, airdropCmd, configCmd)
+}
+
+// flagAliases maps other names of flags to the ones they stand for, such as --cluster, the name the
+// Solana CLI uses, for --network.
+func flagAliases(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	if name == "cluster" {
+		name = "network"
+	}
+	return pflag.NormalizedName(name)
+}
+
+// newWalletConfig creates a WalletConfig for the active profile using the network selected via flags
+// or persisted in the key file, and prints a header naming the profile and network.
+func newWalletConfig() (*wallet.WalletConfig, error) {
+	profiles, wc, err := profileWalletConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := selectedCurrency(profiles)
+	if err != nil {
+		return nil, err
+	}
+	wc.UseCurrency(currency)
+
+	wc.Wallet = transientWallet
+	prompt, retry := passphrasePrompt()
+	wc.UsePassphrase(prompt)
+	wc.RetryPassphrase(retry)
+	if verboseFlag {
+		wc.OnKeystoreChange(printKeystoreChanges)
+	}
+	wc.EnforceAllowlist = enforceAllowlistFlag
+	if spendLimitFlag != "" {
+		wc.Policy.MaxLamports, err = wallet.ParseSOL(spendLimitFlag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --spend-limit: %w", err)
+		}
+	}
+	pool, err := profiles.RPCEndpoints()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RPC endpoints: %w", err)
+	}
+	wc.UseRPCPool(pool)
+	backend, err := profiles.KeyBackend()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key backend: %w", err)
+	}
+	if backend == wallet.BackendKeychain {
+		wc.StoreKeysInKeychain(wallet.OSKeychain())
+	}
+	if err := wc.UseNetwork(networkFlag, rpcURLFlag); err != nil {
+		return nil, fmt.Errorf("failed to select network: %w", err)
+	}
+	wc.UseGenesisCheck(wallet.GenesisOptions{
+		Cache:  wallet.DefaultGenesisCache(),
+		Strict: strictFlag,
+		OnMismatch: func(check wallet.GenesisCheck) {
+			if !strictFlag {
+				printWarning("Warning: %v. Use --strict to refuse such endpoints.\n", check.Err())
+			}
+		},
+	})
+
+	// The header goes to stderr so it never mixes with output meant for scripts.
+	color.New(color.Faint).Fprintf(os.Stderr, "[profile: %s | network: %s%s]\n", wc.Profile, wc.NetworkName(), readOnlyNote())
+	return wc, nil
+}
+
+// profileWalletConfig returns the WalletConfig of the active profile, or of --keyfile, without
+// selecting a network or printing anything. A legacy key file is offered for migration first.
+func profileWalletConfig() (*wallet.ProfileManager, *wallet.WalletConfig, error) {
+	profiles, profile, err := activeProfile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wc, err := profiles.WalletConfig(profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wc.UseOrigin(cliOrigin)
+	if keyFileFlag != "" {
+		wc.UseKeyFile(keyFileFlag)
+	} else if profile == wallet.DefaultProfile {
+		if err := offerKeyFileMigration(wc); err != nil {
+			return nil, nil, err
+		}
+	}
+	return profiles, wc, nil
+}
+
+// newReadOnlyContext creates a ReadOnlyContext for the active profile. Commands annotated with
+// keyAccessNone use it instead of newWalletConfig so they never open the key file. The network is
+// remembered in the key file, so the header only names the profile.
+func newReadOnlyContext() (*wallet.ReadOnlyContext, error) {
+	profiles, profile, err := activeProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := profiles.ReadOnlyContext(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Currency, err = selectedCurrency(profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	color.New(color.Faint).Fprintf(os.Stderr, "[profile: %s%s]\n", rc.Profile, readOnlyNote())
+	return rc, nil
+}
+
+// readOnlyNote marks the header of a command run with --read-only.
+func readOnlyNote() string {
+	if wallet.ReadOnlyMode() {
+		return " | read-only"
+	}
+	return ""
+}
+
+// activeProfile returns the profile selected with --profile, or else the one chosen with \"wallet profile switch\".
+func activeProfile() (*wallet.ProfileManager, string, error) {
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if profileFlag != "" {
+		return profiles, profileFlag, nil
+	}
+
+	profile, err := profiles.Active()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read active profile: %w", err)
+	}
+	return profiles, profile, nil
+}
+
+// selectedCurrency returns the currency chosen with --currency or SLEENG_CURRENCY, or else the stored default.
+func selectedCurrency(profiles *wallet.ProfileManager) (wallet.Currency, error) {
+	if currencyFlag != "" {
+		currency, err := wallet.ParseCurrency(currencyFlag)
+		if err != nil {
+			return "", fmt.Errorf("invalid --currency: %w", err)
+		}
+		return currency, nil
+	}
+
+	currency, err := profiles.Currency()
+	if err != nil {
+		return "", fmt.Errorf("failed to read default currency: %w", err)
+	}
+	return currency, nil
+}
+
+// selectLocale selects the locale of --locale, or the stored one, for every amount the command shows.
+func selectLocale() error {
+	if localeFlag != "" {
+		locale, err := wallet.ParseLocale(localeFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --locale: %w", err)
+		}
+		wallet.SetLocale(locale)
+		return nil
+	}
+
+	locale := wallet.DefaultLocale
+	if profiles, err := wallet.NewProfileManager(); err == nil {
+		// An unreadable config is reported by the commands that need it; amounts fall back to the default.
+		if stored, err := profiles.Locale(); err == nil {
+			locale = stored
+		}
+	}
+	wallet.SetLocale(locale)
+	return nil
+}
+
+// configureRateCache sets how long rates are reused across commands from --rate-ttl, and --fresh.
+func configureRateCache() error {
+	ttl := wallet.DefaultRateCacheTTL
+	if rateTTLFlag != "" {
+		var err error
+		if ttl, err = time.ParseDuration(rateTTLFlag); err != nil || ttl < 0 {
+			return fmt.Errorf("invalid --rate-ttl %q: use a duration such as 10m, or 0 to turn the cache off", rateTTLFlag)
+		}
+	}
+	wallet.SetRateCache(ttl, freshFlag)
+	return nil
+}
+
+// nodeOptions configures RPC node tracking: slot regressions are always reported, the nodes serving
+// requests only in verbose mode.
+func nodeOptions() wallet.NodeOptions {
+	opts := wallet.NodeOptions{
+		Pin: pinNodeFlag,
+		OnSlotRegression: func(regression wallet.SlotRegression) {
+			printWarning("Warning: %s; data may be inconsistent\n", regression)
+		},
+	}
+	if verboseFlag {
+		opts.OnNode = func(node wallet.NodeInfo) {
+			color.New(color.Faint).Fprintf(os.Stderr, "[rpc node: %s]\n", node)
+		}
+	}
+	return opts
+}
+
+// printKeystoreChanges prints what a write to the key file changed to stderr.
+func printKeystoreChanges(action string, changes []wallet.KeystoreChange) {
+	for _, change := range changes {
+		color.New(color.Faint).Fprintf(os.Stderr, "[keystore %s] %s\n", action, change)
+	}
+}
+
+// watchKeyFile reloads the key file whenever another process changes it, until ctx is cancelled,
+// printing what changed to stderr and passing each reload on to fn when it is not nil.
+func watchKeyFile(ctx context.Context, wc *wallet.WalletConfig, fn func(wallet.KeystoreReload)) {
+	watcher, err := wc.KeyFileWatcher()
+	if err != nil {
+		printWarning("Warning: changes other processes make to the key file are not picked up: %v\n", err)
+		return
+	}
+
+	err = watcher.Run(ctx, func(reload wallet.KeystoreReload) {
+		if reload.Err != nil {
+			printWarning("Warning: the key file changed but could not be reloaded, keeping the previous version: %v\n", reload.Err)
+		} else {
+			color.New(color.Faint).Fprintf(os.Stderr, "[keystore reloaded] %d changes by another process\n", len(reload.Changes))
+			printKeystoreChanges("reload", reload.Changes)
+		}
+		if fn != nil {
+			fn(reload)
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		printWarning("Warning: stopped watching the key file: %v\n", err)
+	}
+}
+
+// printStats prints the cache counters and the RPC endpoints that served the command to stderr so they never mix with command output.
+func printStats() {
+	stats := wallet.OHLCStats()
+	color.New(color.Faint).Fprintf(os.Stderr, "[ohlc cache: %d hits | %d misses | %d fetches | %d coalesced | %d corrupted]\n",
+		stats.Hits, stats.Misses, stats.Fetches, stats.Coalesced, stats.Corrupted)
+	for _, usage := range wallet.ServedEndpoints() {
+		line := fmt.Sprintf("[rpc endpoint: %s | %d requests", usage.Endpoint, usage.Requests)
+		if usage.Failed > 0 {
+			line += fmt.Sprintf(" | %d failed over", usage.Failed)
+		}
+		color.New(color.Faint).Fprintln(os.Stderr, line+"]")
+	}
+}
+
+// printTimings prints the --timings footer to stderr, so it never mixes with output meant for scripts.
+func printTimings(report wallet.TimingReport) {
+	faint := color.New(color.Faint)
+	faint.Fprintf(os.Stderr, "[timings: %s total]\n", report.Total.Round(time.Millisecond))
+	for _, phase := range report.Phases {
+		line := fmt.Sprintf("[  %-13s %9s  %3.0f%%", phase.Phase, phase.Duration.Round(time.Microsecond*100), phase.Share*100)
+		if phase.Calls > 1 {
+			line += fmt.Sprintf("  %d calls", phase.Calls)
+		}
+		faint.Fprintln(os.Stderr, line+"]")
+	}
+	for _, hint := range report.Hints {
+		printWarning("Hint: %s\n", hint)
+	}
 }
 
 func Execute() error {
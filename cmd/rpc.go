@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var rpcCmd = &cobra.Command{
+	Use:   "rpc",
+	Short: "Manages the RPC providers used as the failover pool of each network",
+	Long: `Registers RPC providers, such as Helius or QuickNode, for all profiles. The providers registered
+for the selected network are tried in the order they were added, with the public endpoint of the
+network as the last fallback: a read moves on to the next provider when one cannot be reached, answers
+with a server error or rate limits. --rpc-url, and an RPC URL remembered in the key file, take
+precedence over the pool.`,
+}
+
+var rpcAddCmd = &cobra.Command{
+	Use:         "add [name] [url]",
+	Short:       "Registers an RPC provider for the network given with --network",
+	Annotations: keyAccess(keyAccessNone),
+	Args:        cobra.ExactArgs(2),
+	RunE:        addRPCEndpoint,
+}
+
+var rpcListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists the registered RPC providers by network",
+	Annotations: keyAccess(keyAccessNone),
+	Args:        cobra.NoArgs,
+	RunE:        listRPCEndpoints,
+}
+
+var rpcRemoveCmd = &cobra.Command{
+	Use:         "remove [name]",
+	Short:       "Unregisters an RPC provider",
+	Annotations: keyAccess(keyAccessNone),
+	Args:        cobra.ExactArgs(1),
+	RunE:        removeRPCEndpoint,
+}
+
+func init() {
+	rpcCmd.AddCommand(rpcAddCmd, rpcListCmd, rpcRemoveCmd)
+}
+
+func addRPCEndpoint(_ *cobra.Command, args []string) error {
+	if networkFlag == "" {
+		return errors.New("--network is required: the provider serves one network")
+	}
+	network, err := wallet.ParseNetwork(networkFlag)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+	endpoint := wallet.RPCEndpoint{Name: args[0], URL: args[1], Network: network}
+	if err := profiles.AddRPCEndpoint(endpoint); err != nil {
+		return fmt.Errorf("failed to register RPC endpoint: %w", err)
+	}
+
+	printBlue("Registered %s for %s\n", endpoint.Name, endpoint.Network)
+	return nil
+}
+
+func listRPCEndpoints(_ *cobra.Command, _ []string) error {
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+	endpoints, err := profiles.RPCEndpoints()
+	if err != nil {
+		return fmt.Errorf("failed to read RPC endpoints: %w", err)
+	}
+
+	if jsonOutput() {
+		if endpoints == nil {
+			endpoints = []wallet.RPCEndpoint{}
+		}
+		return printJSON(endpoints)
+	}
+	if len(endpoints) == 0 {
+		fmt.Println("No RPC providers registered. Register one with `wallet --network mainnet-beta rpc add [name] [url]`.")
+		return nil
+	}
+	for _, endpoint := range endpoints {
+		fmt.Printf("%-14s %-10s %s\n", endpoint.Network, endpoint.Name, endpoint.URL)
+	}
+	return nil
+}
+
+func removeRPCEndpoint(_ *cobra.Command, args []string) error {
+	profiles, err := wallet.NewProfileManager()
+	if err != nil {
+		return err
+	}
+	endpoint, err := profiles.RemoveRPCEndpoint(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to remove RPC endpoint: %w", err)
+	}
+
+	printBlue("Removed %s, which served %s\n", endpoint.Name, endpoint.Network)
+	return nil
+}
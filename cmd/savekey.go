@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/fatih/color"
+	"github.com/gagliardetto/solana-go"
+	"github.com/spf13/cobra"
+)
+
+// transientWallet is the wallet of the private key given with --key to a command that uses it for
+// this run only. newWalletConfig acts on it instead of the active wallet.
+var transientWallet *solana.Wallet
+
+var saveAsFlag string
+
+// useTransientKey parses --key for the commands that act on it without storing it, so an invalid key
+// or a --save-as that cannot be honoured fails before the command does anything.
+func useTransientKey(cmd *cobra.Command) error {
+	transientWallet = nil
+	if !hasUse(cmd, usesTransientKey) {
+		return nil
+	}
+	if privateKeyFlag == "" {
+		if saveAsFlag != "" {
+			return errors.New("--save-as needs the private key given with --key")
+		}
+		return nil
+	}
+	if aliasFlag != "" {
+		return errors.New("--alias cannot be combined with --key, which already names the wallet")
+	}
+
+	key, err := solana.PrivateKeyFromBase58(privateKeyFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --key: %w", err)
+	}
+	if saveAsFlag != "" {
+		if err := checkCanSaveKey(key.PublicKey().String(), saveAsFlag); err != nil {
+			return fmt.Errorf("invalid --save-as: %w", err)
+		}
+	}
+
+	transientWallet = &solana.Wallet{PrivateKey: key}
+	return nil
+}
+
+// checkCanSaveKey fails when the key of address cannot be stored as alias: in read-only mode, when the
+// alias is taken, or when the address is already stored.
+func checkCanSaveKey(address, alias string) error {
+	if wallet.ReadOnlyMode() {
+		return wallet.ErrReadOnlyMode
+	}
+
+	_, wc, err := profileWalletConfig()
+	if err != nil {
+		return err
+	}
+	if err := wc.CheckAliasFree(alias); err != nil {
+		return err
+	}
+	stored, err := wc.StoredAlias(address)
+	if err != nil {
+		return err
+	}
+	if stored != "" {
+		return fmt.Errorf("address %s is already stored as %s", address, stored)
+	}
+	return nil
+}
+
+// offerToSaveKey stores the key given with --key once the command succeeded: as --save-as when given,
+// or else as the alias the user types when asked. An empty alias or a closed stdin declines. Nothing is
+// asked when the key is already stored, in read-only mode, or when the output is meant for a script.
+func offerToSaveKey() error {
+	if transientWallet == nil {
+		return nil
+	}
+	address := transientWallet.PublicKey().String()
+
+	if saveAsFlag != "" {
+		return saveTransientKey(saveAsFlag)
+	}
+	if wallet.ReadOnlyMode() || jsonOutput() || assumeYes {
+		return nil
+	}
+
+	_, wc, err := profileWalletConfig()
+	if err != nil {
+		return err
+	}
+	stored, err := wc.StoredAlias(address)
+	if err != nil || stored != "" {
+		return err
+	}
+
+	alias, err := promptForInput(fmt.Sprintf("Save the key of %s so you do not need --key next time? Alias (empty to skip)", address), func(input string) error {
+		if input == "" {
+			return nil
+		}
+		return wc.CheckAliasFree(input)
+	})
+	if errors.Is(err, ErrInputClosed) || alias == "" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return saveTransientKey(alias)
+}
+
+// saveTransientKey stores the key given with --key as alias through the same import as `wallet init
+// --key`, which makes it the active wallet.
+func saveTransientKey(alias string) error {
+	_, wc, err := profileWalletConfig()
+	if err != nil {
+		return err
+	}
+	address, err := wc.CreateNewWalletWithKey(alias, privateKeyFlag)
+	if err != nil {
+		return fmt.Errorf("failed to save the key given with --key: %w", err)
+	}
+
+	// Stderr, so the note never mixes with the output of the command.
+	color.New(color.FgBlue).Fprintf(os.Stderr, "Saved %s as wallet %s, now the active wallet\n", address, alias)
+	return nil
+}
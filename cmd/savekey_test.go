@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestOfferToSaveKey(t *testing.T) {
+	skipUnderRace(t)
+	key := solana.NewWallet().PrivateKey
+	address := key.PublicKey().String()
+
+	tests := []struct {
+		name string
+		args []string
+		// input is what the prompts read; empty is a closed stdin.
+		input string
+		// stored is true when the key is already stored as main before the command runs.
+		stored    bool
+		wantErr   string
+		wantAlias string
+	}{
+		{name: "save as", args: []string{"--save-as", "spare"}, wantAlias: "spare"},
+		{name: "accept the offer", input: "spare\r", wantAlias: "spare"},
+		{name: "decline the offer", input: "\r"},
+		{name: "closed input declines", input: ""},
+		{name: "no offer for JSON output", args: []string{"--output", "json"}, input: "spare\r"},
+		{name: "no offer for a stored key", input: "spare\r", stored: true, wantAlias: "main"},
+		{
+			name:      "save as a stored key",
+			args:      []string{"--save-as", "spare"},
+			stored:    true,
+			wantErr:   "invalid --save-as: address " + address + " is already stored as main",
+			wantAlias: "main",
+		},
+		{
+			name:    "save as a taken alias",
+			args:    []string{"--save-as", "main"},
+			stored:  true,
+			wantErr: "invalid --save-as: alias already exists: main",
+			// The check runs before the key is looked up, so main keeps its own key.
+			wantAlias: "main",
+		},
+	}
+
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	defer os.Chdir(cwd)
+	defer RootCmd.SetArgs(nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer resetScopedFlags()
+			defer func() { outputFlag = outputText }()
+			// Other tests may have run `address --all`.
+			listAll = false
+
+			configDir := t.TempDir()
+			t.Setenv(wallet.ConfigDirEnv, configDir)
+			assert.NoError(t, os.Chdir(t.TempDir()))
+			wc := wallet.NewWalletConfigInDir(configDir)
+			if tt.stored {
+				_, err := wc.CreateNewWalletWithKey("main", key.String())
+				assert.NoError(t, err)
+			}
+			withStdin(t, tt.input)
+
+			RootCmd.SetArgs(append([]string{"address", "--key", key.String()}, tt.args...))
+			err := RootCmd.Execute()
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tt.wantErr, err.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+
+			alias, err := wc.StoredAlias(address)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantAlias, alias)
+			if tt.wantAlias != "" {
+				info, err := wc.GetWalletInfo("")
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantAlias, info.Alias, "the saved wallet is the active one")
+			}
+		})
+	}
+}
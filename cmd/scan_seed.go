@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var scanSeedCmd = &cobra.Command{
+	Use:         "scan-seed",
+	Short:       "Finds the used accounts of a seed phrase and offers to import the funded ones",
+	Annotations: keyAccess(keyAccessPrivate),
+	Long: `Derives the accounts of a seed phrase along m/44'/501'/<index>'/0', starting at account 0, and
+checks each for a balance and transaction history until --gap-limit accounts in a row are unused.
+This finds every account of a wallet that rotates through receive addresses. The funded accounts
+can then be imported as <--alias-prefix>-<index>.`,
+	Args: cobra.NoArgs,
+	RunE: scanSeed,
+}
+
+var (
+	scanGapLimit    uint32
+	scanAliasPrefix string
+)
+
+func init() {
+	scanSeedCmd.Flags().Uint32Var(&scanGapLimit, "gap-limit", wallet.DefaultGapLimit, "Stop after this many unused accounts in a row")
+	scanSeedCmd.Flags().BoolVar(&withPassphrase, "with-passphrase", false, "Prompt for the BIP-39 passphrase of the seed phrase")
+	scanSeedCmd.Flags().StringVar(&scanAliasPrefix, "alias-prefix", "seed", "Import funded accounts as <prefix>-<index>")
+	scanSeedCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Import the funded accounts without asking")
+}
+
+func scanSeed(cmd *cobra.Command, _ []string) error {
+	if scanGapLimit == 0 {
+		return fmt.Errorf("--gap-limit must be at least 1")
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	seedPhrase, err := promptForSecret("Please enter the seed phrase to scan")
+	if err != nil {
+		return fmt.Errorf("failed to get seed phrase: %w", err)
+	}
+	var passphrase string
+	if withPassphrase {
+		passphrase, err = promptForSecret("Please enter the BIP-39 passphrase of the seed phrase")
+		if err != nil {
+			return fmt.Errorf("failed to get seed passphrase: %w", err)
+		}
+	}
+
+	color.New(color.Faint).Fprintf(os.Stderr, "Scanning accounts until %d in a row are unused...\n", scanGapLimit)
+	scan, err := wc.ScanSeed(cmd.Context(), seedPhrase, wallet.SeedScanOptions{Passphrase: passphrase, GapLimit: scanGapLimit})
+	if err != nil {
+		return fmt.Errorf("failed to scan seed phrase: %w", err)
+	}
+
+	if jsonOutput() {
+		return printJSON(scan)
+	}
+
+	if len(scan.Used) == 0 {
+		fmt.Printf("None of the first %d accounts on %s has a balance or transactions.\n", scan.Scanned, wc.NetworkName())
+		return nil
+	}
+	for _, account := range scan.Used {
+		state := "used, empty"
+		if account.Funded() {
+			state = wallet.LamportsToSOL(account.Lamports).String() + " SOL"
+		}
+		printBlue("#%d %s %s (%s)\n", account.Index, account.Path, account.PublicKey, state)
+	}
+	funded := scan.Funded()
+	fmt.Printf("Scanned %d accounts on %s: %d used, %d funded, %s SOL in total.\n",
+		scan.Scanned, wc.NetworkName(), len(scan.Used), len(funded), wallet.LamportsToSOL(scan.TotalLamports))
+
+	if len(funded) == 0 {
+		return nil
+	}
+	if wallet.ReadOnlyMode() {
+		fmt.Println("Read-only mode: the funded accounts were not imported.")
+		return nil
+	}
+	if !assumeYes {
+		confirmed, err := promptForConfirmation(fmt.Sprintf("Import the %d funded accounts as %s-<index>", len(funded), scanAliasPrefix))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return nil
+		}
+	}
+
+	for _, account := range funded {
+		alias := fmt.Sprintf("%s-%d", scanAliasPrefix, account.Index)
+		if _, err := wc.ImportSeedAccount(seedPhrase, passphrase, account.Index, alias); err != nil {
+			printWarning("Failed to import account %d as %s: %v\n", account.Index, alias, err)
+			continue
+		}
+		printBlue("Imported account %d as %s.\n", account.Index, alias)
+	}
+	return nil
+}
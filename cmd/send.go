@@ -2,29 +2,470 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
-	"log"
+	"os"
+	"strings"
+	"time"
 )
 
 var sendCmd = &cobra.Command{
-	Use:   "send [EUR amount] [destination]",
-	Short: "Sends <EUR amount>'s worth of SOL to the destination address",
-	Args:  cobra.ExactArgs(2), // You expect exactly two arguments
-	Run:   send,
+	Use:         "send [amount] [destination] | send --max [destination] | send --request [file]",
+	Short:       "Sends <amount> of SOL to the destination address, given in the selected --currency unless --unit says otherwise",
+	Annotations: usesFlags(keyAccess(keyAccessPrivate), usesTransientKey),
+	Long: `Sends <amount> of SOL, or of --token, to the destination. The destination is an address or the
+name of a contact added with ` + "`wallet contacts add`" + `. A contact name that is also a valid
+address itself resolves to the contact, with a warning.
+
+The amount may add and subtract numbers with optional unit suffixes, percentages of the spendable
+balance (what is left after the fee) and max: "50%", "max-0.1sol" or "12.5+3.2". The confirmation
+shows the amount it resolves to. --max sends the whole balance less the fee, leaving the wallet empty.`,
+	Args: sendArgs,
+	Run:  send,
+}
+
+var (
+	nonceAccountFlag string
+	unitFlag         string
+	assumeYes        bool
+	tokenFlag        string
+	autoFund         bool
+	requestFile      string
+	memoFlag         string
+	priorityFeeFlag  string
+	multisigFlag     string
+	multisigOut      string
+	allowBelowRent   bool
+	sendMax          bool
+	sendDryRun       bool
+)
+
+// autoFundEnv enables --auto-fund for every send, e.g. in integration test environments.
+const autoFundEnv = "SLEENG_AUTO_FUND"
+
+func init() {
+	sendCmd.Flags().StringVar(&nonceAccountFlag, "nonce-account", "", "Use this durable nonce account instead of a recent blockhash so the transfer can be cancelled")
+	sendCmd.Flags().StringVar(&unitFlag, "unit", string(wallet.UnitFiat), "Unit of the amount: fiat (the selected --currency), eur, usd, gbp, sol or lamports")
+	sendCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Send without asking for confirmation")
+	sendCmd.Flags().BoolVar(&sendDryRun, "dry-run", false, "Simulate the transfer on the cluster and print its balance changes, compute units and logs without sending it")
+	sendCmd.Flags().BoolVar(&sendMax, "max", false, "Send the whole balance less the fee to the destination, which is then the only argument")
+	sendCmd.Flags().BoolVar(&autoFund, "auto-fund", os.Getenv(autoFundEnv) == "1", "On devnet, airdrop the shortfall when the wallet cannot cover the amount and fee (or set "+autoFundEnv+"=1)")
+	sendCmd.Flags().StringVar(&requestFile, "request", "", "Pay the recipient and amount of this payment request file instead of the arguments")
+	sendCmd.Flags().StringVar(&tokenFlag, "token", "", "Send this SPL token (mint address or symbol such as USDC) instead of SOL; the amount is in whole tokens")
+	sendCmd.Flags().StringVar(&memoFlag, "memo", "", fmt.Sprintf("Record this memo with the transfer, e.g. for exchange deposits (at most %d bytes)", wallet.MaxMemoBytes))
+	sendCmd.Flags().StringVar(&multisigFlag, "multisig", "", "Propose a --token transfer from this multisig: sign it and write it for the co-signers instead of sending it")
+	sendCmd.Flags().StringVar(&multisigOut, "out", "", "With --multisig, write the partially-signed transfer to this file instead of stdout")
+	sendCmd.Flags().BoolVar(&allowBelowRent, "allow-below-rent", false, "Send to an address that holds no SOL yet even when the amount is below the rent-exempt minimum of its new account")
+	sendCmd.Flags().StringVar(&priorityFeeFlag, "priority-fee", "", "Pay this many micro-lamports per compute unit so the transfer lands sooner, or auto for the recent going rate")
+}
+
+// sendArgs takes the amount and destination from the arguments, or from the --request file alone.
+func sendArgs(cmd *cobra.Command, args []string) error {
+	if tokenFlag != "" {
+		for _, flag := range []string{"memo", "priority-fee"} {
+			if cmd.Flags().Changed(flag) {
+				return fmt.Errorf("--%s cannot be combined with --token", flag)
+			}
+		}
+	}
+
+	if sendDryRun && tokenFlag != "" {
+		return errors.New("--dry-run only simulates SOL transfers and cannot be combined with --token")
+	}
+
+	if multisigFlag != "" && tokenFlag == "" {
+		return errors.New("--multisig needs --token: multisig accounts hold SPL tokens")
+	}
+	if multisigFlag == "" && cmd.Flags().Changed("out") {
+		return errors.New("--out is only used with --multisig")
+	}
+
+	if sendMax {
+		for _, flag := range []string{"request", "token", "unit"} {
+			if cmd.Flags().Changed(flag) {
+				return fmt.Errorf("--max cannot be combined with --%s", flag)
+			}
+		}
+		if len(args) != 1 {
+			return errors.New("--max takes the destination as its only argument")
+		}
+		return nil
+	}
+
+	if requestFile == "" {
+		return cobra.ExactArgs(2)(cmd, args)
+	}
+
+	if len(args) > 0 {
+		return errors.New("--request takes the amount and destination from the file; do not pass them as arguments")
+	}
+	for _, flag := range []string{"unit", "token"} {
+		if cmd.Flags().Changed(flag) {
+			return fmt.Errorf("--%s cannot be combined with --request", flag)
+		}
+	}
+	return nil
 }
 
 func send(cmd *cobra.Command, args []string) {
+	var request *wallet.PaymentRequest
+	if requestFile != "" {
+		var err error
+		request, err = wallet.ReadPaymentRequestFile(requestFile, time.Now())
+		if err != nil {
+			fatalf(err, "Failed to send funds: %v", err)
+		}
+		args = []string{request.Amount, request.Recipient}
+		unitFlag = string(request.Unit)
+		if memoFlag == "" {
+			// The memo identifies the payment to the recipient, so it goes on-chain with it.
+			memoFlag = request.Memo
+		}
+	}
+
+	if sendMax {
+		args = []string{wallet.MaxKeyword, args[0]}
+	}
 	amount := args[0]
 	destination := args[1]
 
-	walletConfig := wallet.NewWalletConfig()
+	if tokenFlag != "" {
+		sendToken(amount, destination)
+		return
+	}
+
+	unit, err := wallet.ParseUnit(unitFlag)
+	if err != nil {
+		fatalf(err, "Failed to send funds: %v", err)
+	}
+
+	var priorityFee wallet.PriorityFee
+	if priorityFeeFlag != "" {
+		priorityFee, err = wallet.ParsePriorityFee(priorityFeeFlag)
+		if err != nil {
+			fatalf(err, "Failed to send funds: %v", err)
+		}
+	}
+	if err := wallet.ValidateMemo(memoFlag); err != nil {
+		fatalf(err, "Failed to send funds: %v", err)
+	}
+
+	walletConfig, err := newWalletConfig()
+	if err != nil {
+		fatalf(err, "Failed to send funds: %v", err)
+	}
 
-	signature, err := walletConfig.SendFunds(context.Background(), amount, destination)
+	recipient, err := resolveRecipient(walletConfig, destination)
 	if err != nil {
-		log.Fatalf("Failed to send funds: %v", err.Error())
+		fatalf(err, "Failed to send funds: %v", err)
 	}
 
-	fmt.Printf("Successfully sent %s EUR to %s. Transaction Signature: %s\n", amount, destination, signature)
+	opts := wallet.SendOptions{
+		NonceAccount:   nonceAccountFlag,
+		Unit:           unit,
+		Memo:           memoFlag,
+		PriorityFee:    priorityFee,
+		AllowBelowRent: allowBelowRent,
+		// A dry run must not change anything, and an airdrop would.
+		AutoFund: autoFund && !sendDryRun,
+	}
+
+	ctx := cmd.Context()
+	quote, err := walletConfig.PrepareSend(ctx, amount, recipient.Address, opts)
+	var insufficient *wallet.InsufficientFundsError
+	if errors.As(err, &insufficient) {
+		fatalf(err, "Failed to send funds: %s", describeInsufficientFunds(insufficient))
+	}
+	var belowRent *wallet.BelowRentExemptionError
+	if errors.As(err, &belowRent) {
+		fatalf(err, "Failed to send funds: %s", describeBelowRentExemption(belowRent))
+	}
+	if err != nil {
+		fatalf(err, "Failed to send funds: %v", err)
+	}
+
+	if quote.AutoFunded > 0 {
+		printYellow("Airdropped %s SOL from the devnet faucet to cover this transfer.\n", wallet.LamportsToSOL(quote.AutoFunded))
+	}
+
+	if quote.NewRecipient {
+		printNewRecipientWarning(quote)
+	}
+
+	if request != nil {
+		printPaymentRequest(request)
+	}
+
+	if sendDryRun {
+		printBlue("%s", sendPreview(quote, recipient.String(), walletConfig.NetworkName()))
+		simulation, err := walletConfig.SimulateSend(ctx, quote, opts)
+		if simulation != nil {
+			name := recipient.Contact
+			if name == "" {
+				name = "recipient"
+			}
+			fmt.Print(simulationReport(simulation, name))
+		}
+		if err != nil {
+			fatalf(err, "Dry run: %v", err)
+		}
+		fmt.Println("Dry run: nothing was sent.")
+		return
+	}
+
+	if !assumeYes {
+		printBlue("%s", sendPreview(quote, recipient.String(), walletConfig.NetworkName()))
+
+		confirmed, err := promptForConfirmation("Send this transfer")
+		if err != nil {
+			fatalf(err, "Failed to send funds: %v", err)
+		}
+		if !confirmed {
+			fmt.Println("Transfer cancelled.")
+			return
+		}
+	}
+
+	// The request may have expired while the confirmation prompt was open.
+	if request != nil && request.Expired(time.Now()) {
+		fatalf(wallet.ErrPaymentRequestExpired, "Failed to send funds: %v", wallet.ErrPaymentRequestExpired)
+	}
+
+	receipt, err := walletConfig.ExecuteSend(ctx, quote, opts)
+	if err != nil {
+		fatalf(err, "Failed to send funds: %v", err)
+	}
+
+	fmt.Printf("Successfully sent %s to %s on %s. Transaction Signature: %s\n", formatSOLAndFiat(receipt.SOL(), receipt.Fiat, receipt.Currency), recipient, walletConfig.NetworkName(), receipt.Signature)
+	if receipt.Memo != "" {
+		fmt.Printf("Memo: %s\n", receipt.Memo)
+	}
+	if receipt.PriorityFee > 0 {
+		fmt.Printf("Priority fee paid: %s SOL\n", wallet.LamportsToSOL(receipt.PriorityFee))
+	}
+	if receipt.Genesis != "" {
+		fmt.Printf("Cluster genesis: %s\n", receipt.Genesis)
+	}
+}
+
+// printPaymentRequest shows the metadata of a payment request so it can be checked against the invoice.
+func printPaymentRequest(request *wallet.PaymentRequest) {
+	printBlue("Payment request: %s %s\n", request.Amount, strings.ToUpper(string(request.Unit)))
+	if request.Memo != "" {
+		printBlue("Memo: %s\n", request.Memo)
+	}
+	if request.Reference != "" {
+		printBlue("Reference: %s\n", request.Reference)
+	}
+	if request.ExpiresAt != nil {
+		printBlue("Expires: %s\n", request.ExpiresAt.Local().Format(time.RFC1123))
+	}
+}
+
+// sendPreview describes a transfer before it is confirmed: the recipient, the amount, the fee and
+// what both take from the balance together, with the rate they are valued at.
+func sendPreview(quote *wallet.SendQuote, recipient, network string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Recipient: %s\nAmount: %s\n", recipient, formatSOLAndFiat(quote.SOL(), quote.Fiat(), quote.Currency))
+	if quote.Expression != "" {
+		fmt.Fprintf(&b, "Resolved from: %s (spendable balance %s SOL)\n", quote.Expression, wallet.LamportsToSOL(quote.Spendable()))
+	}
+	fmt.Fprintf(&b, "Estimated Fee: %s\n", feeAmount(quote.Fee, quote.Rate, quote.Currency))
+	if quote.ComputeUnitPrice > 0 {
+		fmt.Fprintf(&b, "Priority Fee: %s SOL of it (%d micro-lamports per compute unit)\n", wallet.LamportsToSOL(quote.PriorityFee), quote.ComputeUnitPrice)
+	}
+	fmt.Fprintf(&b, "Total: %s\n", formatSOLAndFiat(wallet.LamportsToSOL(quote.Total()), quote.TotalFiat(), quote.Currency))
+	if quote.Rate != nil {
+		fmt.Fprintf(&b, "Rate: 1 SOL = %s\n", quote.Currency.Format(*quote.Rate))
+	} else {
+		b.WriteString("Rate: unavailable, so amounts are shown in SOL only\n")
+	}
+	if quote.Memo != "" {
+		fmt.Fprintf(&b, "Memo: %s\n", quote.Memo)
+	}
+	fmt.Fprintf(&b, "Network: %s\n", network)
+	return b.String()
+}
+
+// simulationReport describes a simulated transfer: its outcome, the compute units it used, how it
+// changed the balances of the accounts it touched and what the programs logged.
+func simulationReport(simulation *wallet.SendSimulation, recipientName string) string {
+	var b strings.Builder
+	if simulation.Err != nil {
+		fmt.Fprintf(&b, "Simulation: failed, %v\n", simulation.Err)
+	} else {
+		b.WriteString("Simulation: succeeded\n")
+	}
+	if simulation.UnitsConsumed > 0 {
+		fmt.Fprintf(&b, "Compute Units: %d\n", simulation.UnitsConsumed)
+	}
+
+	b.WriteString("Balance Changes:\n")
+	names := []string{"you", recipientName, "nonce account"}
+	for i, change := range simulation.Changes {
+		name := change.Account.String()
+		if i < len(names) {
+			name += " (" + names[i] + ")"
+		}
+		fmt.Fprintf(&b, "  %s: %s SOL -> %s SOL (%s SOL)\n", name, wallet.LamportsToSOL(change.Before), wallet.LamportsToSOL(change.After), signed(decimal.NewFromInt(change.Change()).Shift(-9)))
+	}
+
+	if len(simulation.Logs) > 0 {
+		b.WriteString("Program Logs:\n")
+		for _, line := range simulation.Logs {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// formatSOLAndFiat formats a SOL amount together with its value in currency when one is known.
+func formatSOLAndFiat(sol decimal.Decimal, fiat *decimal.Decimal, currency wallet.Currency) string {
+	formatted := sol.String() + " SOL"
+	if fiat != nil {
+		formatted += " (" + currency.Format(*fiat) + ")"
+	}
+	return formatted
+}
+
+// describeInsufficientFunds explains a failed balance check in the quoted currency when the rate is known and
+// suggests the largest amount that can be sent when only the fee is missing.
+func describeInsufficientFunds(e *wallet.InsufficientFundsError) string {
+	have := wallet.LamportsToSOL(e.Have)
+	amount := wallet.LamportsToSOL(e.Need - e.Fee)
+	fee := wallet.LamportsToSOL(e.Fee)
+
+	message := fmt.Sprintf("you have %s SOL but tried to send %s SOL (+ ~%s SOL fee)", have, amount, fee)
+	if e.Rate != nil {
+		message = fmt.Sprintf("you have %s but tried to send %s (+ ~%s SOL fee)",
+			e.Currency.Format(have.Mul(*e.Rate)), e.Currency.Format(amount.Mul(*e.Rate)), fee)
+	}
+
+	if e.Need-e.Fee <= e.Have {
+		message += fmt.Sprintf("; the most you can send after fees is %s SOL (use --unit sol)", wallet.LamportsToSOL(e.MaxSendable()))
+	}
+
+	if e.AutoFundErr != nil {
+		message += fmt.Sprintf(" (auto-fund failed: %v)", e.AutoFundErr)
+	}
+
+	return message
+}
+
+// describeBelowRentExemption explains why a transfer to a new account was refused and what it takes to send it.
+func describeBelowRentExemption(e *wallet.BelowRentExemptionError) string {
+	return fmt.Sprintf("%s holds no SOL yet, so this transfer would create its account with %s SOL, below the rent-exempt minimum of %s SOL. "+
+		"Nodes may reject such a transfer and the account can be purged; send at least %s SOL (%d lamports), or pass --allow-below-rent to send it anyway",
+		e.Recipient, wallet.LamportsToSOL(e.Lamports), wallet.LamportsToSOL(e.Minimum), wallet.LamportsToSOL(e.Minimum), e.Minimum)
+}
+
+// printNewRecipientWarning warns that the recipient holds no SOL, which is worth a second look at the
+// address, and that the amount is below the rent-exempt minimum when --allow-below-rent let it through.
+func printNewRecipientWarning(quote *wallet.SendQuote) {
+	printYellow("The recipient holds no SOL yet; this transfer creates its account. Check the address before sending.\n")
+	if quote.RentExemptMinimum > 0 && quote.Lamports < quote.RentExemptMinimum {
+		printYellow("The amount is below the rent-exempt minimum of %s SOL for the new account; nodes may reject it.\n", wallet.LamportsToSOL(quote.RentExemptMinimum))
+	}
+}
+
+// sendToken transfers an SPL token, warning when the recipient's token account has to be created.
+func sendToken(amount, destination string) {
+	walletConfig, err := newWalletConfig()
+	if err != nil {
+		fatalf(err, "Failed to send tokens: %v", err)
+	}
+
+	recipient, err := resolveRecipient(walletConfig, destination)
+	if err != nil {
+		fatalf(err, "Failed to send tokens: %v", err)
+	}
+
+	ctx := context.Background()
+	if multisigFlag != "" {
+		proposeMultisigSend(ctx, walletConfig, recipient, amount)
+		return
+	}
+
+	quote, err := walletConfig.PrepareTokenSend(ctx, tokenFlag, amount, recipient.Address)
+	if err != nil {
+		fatalf(err, "Failed to send tokens: %v", err)
+	}
+
+	if quote.CreateAccount {
+		printYellow("The recipient has no %s account yet; creating it costs %s SOL in rent, paid by you.\n",
+			quote.Symbol, wallet.LamportsToSOL(quote.Rent))
+	}
+
+	if !assumeYes {
+		printBlue("Recipient: %s\nAmount: %s %s\nEstimated Fee: %s SOL\nNetwork: %s\n",
+			recipient, quote.Amount(), quote.Symbol, wallet.LamportsToSOL(quote.Fee), walletConfig.NetworkName())
+
+		confirmed, err := promptForConfirmation("Send this transfer")
+		if err != nil {
+			fatalf(err, "Failed to send tokens: %v", err)
+		}
+		if !confirmed {
+			fmt.Println("Transfer cancelled.")
+			return
+		}
+	}
+
+	sig, err := walletConfig.ExecuteTokenSend(ctx, quote)
+	if err != nil {
+		fatalf(err, "Failed to send tokens: %v", err)
+	}
+
+	fmt.Printf("Successfully sent %s %s to %s on %s. Transaction Signature: %s\n", quote.Amount(), quote.Symbol, recipient, walletConfig.NetworkName(), sig)
+}
+
+// proposeMultisigSend signs a token transfer from the --multisig account with the active wallet and
+// writes it for the co-signers.
+func proposeMultisigSend(ctx context.Context, walletConfig *wallet.WalletConfig, recipient wallet.Recipient, amount string) {
+	quote, err := walletConfig.PrepareMultisigTokenSend(ctx, multisigFlag, tokenFlag, amount, recipient.Address)
+	if err != nil {
+		fatalf(err, "Failed to propose transfer: %v", err)
+	}
+
+	if quote.CreateAccount {
+		printYellow("The recipient has no %s account yet; creating it costs %s SOL in rent, paid by you.\n",
+			quote.Symbol, wallet.LamportsToSOL(quote.Rent))
+	}
+
+	if !assumeYes {
+		printBlue("Recipient: %s\nAmount: %s %s\nFrom multisig: %s\nApprovers: %d\nEstimated Fee: %s SOL\nNetwork: %s\n",
+			recipient, quote.Amount(), quote.Symbol, multisigFlag, len(quote.Signers), wallet.LamportsToSOL(quote.Fee), walletConfig.NetworkName())
+
+		confirmed, err := promptForConfirmation("Sign this transfer")
+		if err != nil {
+			fatalf(err, "Failed to propose transfer: %v", err)
+		}
+		if !confirmed {
+			fmt.Println("Transfer cancelled.")
+			return
+		}
+	}
+
+	partial, err := walletConfig.ProposeMultisigSend(ctx, quote, nonceAccountFlag)
+	if err != nil {
+		fatalf(err, "Failed to propose transfer: %v", err)
+	}
+
+	if multisigOut == "" {
+		data, err := partial.Encode()
+		if err != nil {
+			fatalf(err, "Failed to propose transfer: %v", err)
+		}
+		_, _ = os.Stdout.Write(data)
+		return
+	}
+	if err := writePartialTransaction(multisigOut, partial); err != nil {
+		fatalf(err, "Failed to propose transfer: %v", err)
+	}
+	printBlue("Signed and written to %s. The co-signers add their signatures with `wallet multisig cosign %s`.\n", multisigOut, multisigOut)
+	if partial.LastValidBlockHeight > 0 {
+		printYellow("The transfer expires in about a minute; use --nonce-account to give the co-signers more time.\n")
+	}
 }
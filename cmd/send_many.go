@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var sendManyCmd = &cobra.Command{
+	Use:         "send-many [recipient...] --amount [amount]",
+	Short:       "Sends an amount of SOL to several recipients or a contact group, one transfer each",
+	Annotations: usesFlags(keyAccess(keyAccessPrivate), usesTransientKey),
+	Long: `Sends --amount to every member of the contact --group and to every recipient argument, which
+is a contact name or an address. --override name=amount pays one recipient another amount, and
+name=skip leaves it out. Amounts take the same expressions and --unit as send.
+
+Every transfer is checked first, and the balance must cover all of them together. Without --yes the
+transfers are listed and further overrides can be entered before confirming. The transfers are sent
+one after the other; if one fails, the ones before it have been sent and the rest are not.
+
+--priority-fee pays a compute unit price on every transfer, like send. With auto the price is
+looked up for each transfer from the fees recently paid for its sender and recipient.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runSendMany,
+}
+
+var (
+	sendManyGroup     string
+	sendManyAmount    string
+	sendManyOverrides []string
+	sendManyUnit      string
+	sendManyYes       bool
+	sendManyPriority  string
+)
+
+func init() {
+	sendManyCmd.Flags().StringVar(&sendManyGroup, "group", "", "Pay every member of this contact group")
+	sendManyCmd.Flags().StringVar(&sendManyAmount, "amount", "", "Amount to send to each recipient, in --unit")
+	sendManyCmd.Flags().StringArrayVar(&sendManyOverrides, "override", nil, "Pay one recipient another amount as name=amount, or leave it out with name=skip (repeatable)")
+	sendManyCmd.Flags().StringVar(&sendManyUnit, "unit", string(wallet.UnitFiat), "Unit of the amounts: fiat (the selected --currency), eur, usd, gbp, sol or lamports")
+	sendManyCmd.Flags().BoolVarP(&sendManyYes, "yes", "y", false, "Send without asking for confirmation")
+	sendManyCmd.Flags().StringVar(&sendManyPriority, "priority-fee", "", "Pay this many micro-lamports per compute unit on every transfer, or auto for the recent going rate")
+	_ = sendManyCmd.MarkFlagRequired("amount")
+}
+
+func runSendMany(cmd *cobra.Command, args []string) error {
+	if sendManyGroup == "" && len(args) == 0 {
+		return errors.New("name the recipients, or a contact group with --group")
+	}
+
+	unit, err := wallet.ParseUnit(sendManyUnit)
+	if err != nil {
+		return err
+	}
+	var priorityFee wallet.PriorityFee
+	if sendManyPriority != "" {
+		priorityFee, err = wallet.ParsePriorityFee(sendManyPriority)
+		if err != nil {
+			return err
+		}
+	}
+	overrides := make(map[string]string, len(sendManyOverrides))
+	for _, override := range sendManyOverrides {
+		name, amount, err := wallet.ParseOverride(override)
+		if err != nil {
+			return err
+		}
+		overrides[name] = amount
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	var recipients []wallet.Recipient
+	if sendManyGroup != "" {
+		recipients, err = wc.ExpandGroup(sendManyGroup)
+		if err != nil {
+			return err
+		}
+	}
+	for _, arg := range args {
+		recipient, err := resolveRecipient(wc, arg)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	ctx := cmd.Context()
+	opts := wallet.SendOptions{Unit: unit, PriorityFee: priorityFee}
+	quote, err := prepareSendMany(ctx, wc, recipients, overrides, opts)
+	if err != nil {
+		return err
+	}
+
+	if !sendManyYes {
+		quote, err = reviewSendMany(ctx, wc, recipients, overrides, opts, quote)
+		if err != nil {
+			return err
+		}
+
+		confirmed, err := promptForConfirmation(fmt.Sprintf("Send these %d transfers", len(quote.Quotes)))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Transfers cancelled.")
+			return nil
+		}
+	}
+
+	receipts, err := wc.SendFundsMulti(ctx, quote, opts)
+	for i, receipt := range receipts {
+		fmt.Printf("Sent %s to %s. Transaction Signature: %s\n", formatSOLAndFiat(receipt.SOL(), receipt.Fiat, receipt.Currency), quote.Items[i].Recipient, receipt.Signature)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send funds: %w", err)
+	}
+	fmt.Printf("Successfully sent %d transfers on %s.\n", len(receipts), wc.NetworkName())
+	return nil
+}
+
+// prepareSendMany merges the overrides into the recipients and checks the transfers.
+func prepareSendMany(ctx context.Context, wc *wallet.WalletConfig, recipients []wallet.Recipient, overrides map[string]string, opts wallet.SendOptions) (*wallet.MultiSendQuote, error) {
+	items, err := wallet.MergeAmounts(recipients, sendManyAmount, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := wc.PrepareSendMulti(ctx, items, opts)
+	var insufficient *wallet.InsufficientFundsError
+	if errors.As(err, &insufficient) {
+		return nil, fmt.Errorf("failed to send funds: %s: %w", describeInsufficientFunds(insufficient), wallet.ErrInsufficientFunds)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to send funds: %w", err)
+	}
+	return quote, nil
+}
+
+// reviewSendMany shows the transfers and takes further overrides until the user enters none. An
+// override that cannot be sent is reported and dropped again.
+func reviewSendMany(ctx context.Context, wc *wallet.WalletConfig, recipients []wallet.Recipient, overrides map[string]string, opts wallet.SendOptions, quote *wallet.MultiSendQuote) (*wallet.MultiSendQuote, error) {
+	for {
+		printSendMany(wc, quote)
+
+		input, err := promptForInput("Change an amount (name=amount or name=skip), or press enter to continue", func(input string) error {
+			if input == "" {
+				return nil
+			}
+			_, _, err := wallet.ParseOverride(input)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if input == "" {
+			return quote, nil
+		}
+
+		name, amount, _ := wallet.ParseOverride(input)
+		previous, hadPrevious := overrides[name]
+		overrides[name] = amount
+		updated, err := prepareSendMany(ctx, wc, recipients, overrides, opts)
+		if err != nil {
+			printWarning("%v\n", err)
+			if hadPrevious {
+				overrides[name] = previous
+			} else {
+				delete(overrides, name)
+			}
+			continue
+		}
+		quote = updated
+	}
+}
+
+// printSendMany lists the transfers of a multi-recipient send with their total.
+func printSendMany(wc *wallet.WalletConfig, quote *wallet.MultiSendQuote) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RECIPIENT\tAMOUNT")
+	for i, q := range quote.Quotes {
+		fmt.Fprintf(w, "%s\t%s\n", quote.Items[i].Recipient, formatSOLAndFiat(q.SOL(), q.Fiat(), q.Currency))
+	}
+	_ = w.Flush()
+
+	currency := quote.Quotes[0].Currency
+	printBlue("Total: %s\n", formatSOLAndFiat(wallet.LamportsToSOL(quote.Total()), quote.Fiat(), currency))
+	printBlue("Estimated Fees: %s SOL for %d transfers\n", wallet.LamportsToSOL(quote.Fees()), len(quote.Quotes))
+	if priority := quote.PriorityFees(); priority > 0 {
+		printBlue("Priority Fees: %s SOL of them\n", wallet.LamportsToSOL(priority))
+	}
+	printBlue("Network: %s\n", wc.NetworkName())
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestSendArgsMax(t *testing.T) {
+	defer func() {
+		sendMax, unitFlag = false, string(wallet.UnitFiat)
+		sendCmd.Flags().Lookup("unit").Changed = false
+	}()
+	sendMax = true
+
+	assert.NoError(t, sendArgs(sendCmd, []string{"alice"}))
+
+	err := sendArgs(sendCmd, []string{"1", "alice"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only argument")
+
+	assert.NoError(t, sendCmd.Flags().Set("unit", "sol"))
+	err = sendArgs(sendCmd, []string{"alice"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "--max cannot be combined with --unit")
+}
+
+func TestSendPreview(t *testing.T) {
+	eur := wallet.Currency("EUR")
+	rate := decimal.NewFromInt(100)
+	quote := &wallet.SendQuote{Lamports: 500_000_000, Fee: 5000, Rate: &rate, Currency: eur}
+
+	preview := sendPreview(quote, "alice", "devnet")
+	assert.Equal(t, "Recipient: alice\n"+
+		"Amount: 0.5 SOL ("+eur.Format(decimal.NewFromInt(50))+")\n"+
+		"Estimated Fee: 0.000005 SOL ("+eur.Format(decimal.RequireFromString("0.0005"))+")\n"+
+		"Total: 0.500005 SOL ("+eur.Format(decimal.NewFromInt(50))+")\n"+
+		"Rate: 1 SOL = "+eur.Format(rate)+"\n"+
+		"Network: devnet\n", preview)
+
+	quote.Rate = nil
+	preview = sendPreview(quote, "alice", "devnet")
+	assert.Contains(t, preview, "Amount: 0.5 SOL\n")
+	assert.Contains(t, preview, "Estimated Fee: 0.000005 SOL\n")
+	assert.Contains(t, preview, "Total: 0.500005 SOL\n")
+	assert.Contains(t, preview, "Rate: unavailable")
+}
+
+func TestSimulationReport(t *testing.T) {
+	from, to := solana.PublicKey{1}, solana.PublicKey{2}
+	simulation := &wallet.SendSimulation{
+		Changes: []wallet.BalanceChange{
+			{Account: from, Before: 2_000_000_000, After: 1_499_995_000},
+			{Account: to, Before: 0, After: 500_000_000},
+		},
+		UnitsConsumed: 150,
+		Logs:          []string{"Program 11111111111111111111111111111111 success"},
+	}
+
+	assert.Equal(t, "Simulation: succeeded\n"+
+		"Compute Units: 150\n"+
+		"Balance Changes:\n"+
+		"  "+from.String()+" (you): 2 SOL -> 1.499995 SOL (-0.500005 SOL)\n"+
+		"  "+to.String()+" (alice): 0 SOL -> 0.5 SOL (+0.5 SOL)\n"+
+		"Program Logs:\n"+
+		"  Program 11111111111111111111111111111111 success\n", simulationReport(simulation, "alice"))
+
+	simulation.Err = wallet.ErrSimulationFailed
+	assert.Contains(t, simulationReport(simulation, "alice"), "Simulation: failed, "+wallet.ErrSimulationFailed.Error()+"\n")
+}
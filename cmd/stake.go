@@ -0,0 +1,412 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var stakeCmd = &cobra.Command{
+	Use:   "stake",
+	Short: "Stakes SOL of the active wallet with a validator",
+}
+
+var stakeDelegateCmd = &cobra.Command{
+	Use:         "delegate [amount] [validator]",
+	Short:       "Creates a stake account funded from the active wallet and delegates it to a validator",
+	Annotations: keyAccess(keyAccessPrivate),
+	Long: `Creates a new stake account funded with <amount> from the active wallet and delegates it to the
+validator whose vote account is <validator>. The active wallet becomes the staker and withdrawer of the
+stake account. The amount is read like the amount of send, in the selected --currency unless --unit
+says otherwise, and may be an expression such as "50%" or "max". It includes the rent-exempt reserve
+the stake account keeps, which is not staked. The stake becomes active at the start of the next epoch.`,
+	Args: cobra.ExactArgs(2),
+	RunE: delegateStake,
+}
+
+var stakeListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists the stake accounts of the active wallet",
+	Annotations: keyAccess(keyAccessPublic),
+	Long: `Lists the stake accounts the active wallet is the staker or withdrawer of, with the validator
+they are delegated to, their activation state, the staked amount, the balance that can be withdrawn
+now and their lockup. Stake activates and deactivates at the start of the next epoch.`,
+	Args: cobra.NoArgs,
+	RunE: listStake,
+}
+
+var stakeDeactivateCmd = &cobra.Command{
+	Use:         "deactivate [stake-account]",
+	Short:       "Deactivates the stake of a stake account so it can be withdrawn",
+	Annotations: keyAccess(keyAccessPrivate),
+	Long: `Deactivates the delegated stake of <stake-account>, which the active wallet must be the staker of.
+The stake stops earning rewards and can be withdrawn with "stake withdraw" once it has cooled down at
+the start of the next epoch.`,
+	Args: cobra.ExactArgs(1),
+	RunE: deactivateStake,
+}
+
+var stakeWithdrawCmd = &cobra.Command{
+	Use:         "withdraw [stake-account] [amount]",
+	Short:       "Withdraws SOL from a stake account to the active wallet or --to",
+	Annotations: keyAccess(keyAccessPrivate),
+	Long: `Withdraws <amount> from <stake-account>, which the active wallet must be the withdrawer of, to the
+active wallet or the address or contact given with --to. The amount defaults to everything that can
+be withdrawn and may be an expression such as "50%" or "max", read against the stake account. All of
+an inactive stake account can be withdrawn, which closes it; while stake is delegated only the
+lamports beyond the stake and the rent-exempt reserve can. A lockup in force refuses the withdrawal
+unless the active wallet is its custodian.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: withdrawStake,
+}
+
+var stakeRewardsCmd = &cobra.Command{
+	Use:         "rewards",
+	Short:       "Lists the staking rewards earned by the stake accounts of the active wallet",
+	Annotations: keyAccess(keyAccessPublic),
+	Long: `Lists the inflation rewards the stake accounts of the active wallet earned in the latest --epochs
+completed epochs, newest first, with their value in the selected currency at the current rate and the
+commission the validator took. Rewards are paid into the stake account at the start of the next epoch
+and are staked along with it. Accounts that were closed since are no longer found.`,
+	Args: cobra.NoArgs,
+	RunE: listStakingRewards,
+}
+
+var (
+	stakeUnitFlag string
+	stakeYes      bool
+	stakeTo       string
+	stakeEpochs   int
+)
+
+// stakingRewardOutput is the JSON form of a staking reward.
+type stakingRewardOutput struct {
+	StakeAccount string           `json:"stakeAccount"`
+	Epoch        uint64           `json:"epoch"`
+	Lamports     uint64           `json:"lamports"`
+	SOL          string           `json:"sol"`
+	Fiat         *decimal.Decimal `json:"fiat,omitempty"`
+	Currency     wallet.Currency  `json:"currency,omitempty"`
+	Commission   *uint8           `json:"commission,omitempty"`
+	Slot         uint64           `json:"slot"`
+	Timestamp    string           `json:"timestamp"`
+}
+
+// stakeAccountOutput is the JSON form of a stake account.
+type stakeAccountOutput struct {
+	Address           string `json:"address"`
+	State             string `json:"state"`
+	Validator         string `json:"validator,omitempty"`
+	Balance           string `json:"balance"`
+	Staked            string `json:"staked"`
+	Withdrawable      string `json:"withdrawable"`
+	Staker            string `json:"staker"`
+	Withdrawer        string `json:"withdrawer"`
+	ActivationEpoch   uint64 `json:"activationEpoch,omitempty"`
+	DeactivationEpoch uint64 `json:"deactivationEpoch,omitempty"`
+	LockupUntil       string `json:"lockupUntil,omitempty"`
+	LockupEpoch       uint64 `json:"lockupEpoch,omitempty"`
+	LockupCustodian   string `json:"lockupCustodian,omitempty"`
+}
+
+func init() {
+	stakeDelegateCmd.Flags().StringVar(&stakeUnitFlag, "unit", string(wallet.UnitFiat), "Unit of the amount: fiat (the selected --currency), eur, usd, gbp, sol or lamports")
+	stakeDelegateCmd.Flags().BoolVarP(&stakeYes, "yes", "y", false, "Delegate without asking for confirmation")
+	stakeWithdrawCmd.Flags().StringVar(&stakeUnitFlag, "unit", string(wallet.UnitFiat), "Unit of the amount: fiat (the selected --currency), eur, usd, gbp, sol or lamports")
+	stakeWithdrawCmd.Flags().StringVar(&stakeTo, "to", "", "Address or contact to withdraw to instead of the active wallet")
+	stakeWithdrawCmd.Flags().BoolVarP(&stakeYes, "yes", "y", false, "Withdraw without asking for confirmation")
+	stakeDeactivateCmd.Flags().BoolVarP(&stakeYes, "yes", "y", false, "Deactivate without asking for confirmation")
+	stakeRewardsCmd.Flags().IntVar(&stakeEpochs, "epochs", wallet.DefaultRewardEpochs, "Number of latest completed epochs to list rewards of")
+	stakeCmd.AddCommand(stakeDelegateCmd, stakeListCmd, stakeDeactivateCmd, stakeWithdrawCmd, stakeRewardsCmd)
+}
+
+func delegateStake(cmd *cobra.Command, args []string) error {
+	unit, err := wallet.ParseUnit(stakeUnitFlag)
+	if err != nil {
+		return err
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	quote, err := wc.PrepareStake(ctx, args[0], args[1], unit)
+	var insufficient *wallet.InsufficientFundsError
+	if errors.As(err, &insufficient) {
+		return fmt.Errorf("failed to delegate stake: %s: %w", describeInsufficientFunds(insufficient), wallet.ErrInsufficientFunds)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delegate stake: %w", err)
+	}
+
+	if !stakeYes {
+		printBlue("Validator vote account: %s\nAmount: %s\n", quote.Vote, formatSOLAndFiat(quote.SOL(), quote.Fiat(), quote.Currency))
+		if quote.Expression != "" {
+			printBlue("Resolved from: %s (spendable balance %s SOL)\n", quote.Expression, wallet.LamportsToSOL(quote.Spendable()))
+		}
+		printBlue("Staked: %s SOL (%s SOL stays in the stake account as its rent-exempt reserve)\n", wallet.LamportsToSOL(quote.Staked()), wallet.LamportsToSOL(quote.Reserve))
+		printBlue("Estimated Fee: %s SOL\nNetwork: %s\n", wallet.LamportsToSOL(quote.Fee), wc.NetworkName())
+
+		confirmed, err := promptForConfirmation("Delegate this stake")
+		if err != nil {
+			return fmt.Errorf("failed to delegate stake: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Delegation cancelled.")
+			return nil
+		}
+	}
+
+	receipt, err := wc.ExecuteStake(ctx, quote)
+	if err != nil {
+		return fmt.Errorf("failed to delegate stake: %w", err)
+	}
+
+	fmt.Printf("Delegated %s SOL to %s on %s. Stake account: %s. Transaction Signature: %s\n",
+		wallet.LamportsToSOL(quote.Staked()), quote.Vote, wc.NetworkName(), receipt.StakeAccount, receipt.Signature)
+	return nil
+}
+
+func listStake(cmd *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	accounts, err := wc.StakeAccounts(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to list stake accounts: %w", err)
+	}
+
+	if jsonOutput() {
+		output := make([]stakeAccountOutput, 0, len(accounts))
+		for _, account := range accounts {
+			entry := stakeAccountOutput{
+				Address:      account.Address.String(),
+				State:        string(account.State),
+				Balance:      wallet.LamportsToSOL(account.Lamports).String(),
+				Staked:       wallet.LamportsToSOL(account.Delegated).String(),
+				Withdrawable: wallet.LamportsToSOL(account.Withdrawable()).String(),
+				Staker:       account.Staker.String(),
+				Withdrawer:   account.Withdrawer.String(),
+			}
+			if !account.Validator.IsZero() {
+				entry.Validator = account.Validator.String()
+				entry.ActivationEpoch = account.ActivationEpoch
+				if account.State == wallet.StakeDeactivating || account.State == wallet.StakeInactive {
+					entry.DeactivationEpoch = account.DeactivationEpoch
+				}
+			}
+			if !account.Lockup.IsZero() {
+				entry.LockupUntil = time.Unix(account.Lockup.UnixTimestamp, 0).UTC().Format(time.RFC3339)
+				entry.LockupEpoch = account.Lockup.Epoch
+				entry.LockupCustodian = account.Lockup.Custodian.String()
+			}
+			output = append(output, entry)
+		}
+		return printJSON(output)
+	}
+
+	if len(accounts) == 0 {
+		fmt.Println("No stake accounts.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACCOUNT\tSTATE\tVALIDATOR\tSTAKED\tWITHDRAWABLE\tLOCKUP")
+	for _, account := range accounts {
+		validator := "-"
+		if !account.Validator.IsZero() {
+			validator = account.Validator.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s SOL\t%s SOL\t%s\n", account.Address, account.State, validator,
+			wallet.LamportsToSOL(account.Delegated), wallet.LamportsToSOL(account.Withdrawable()), describeLockup(account.Lockup))
+	}
+	return w.Flush()
+}
+
+// describeLockup returns when a lockup ends and who its custodian is, or "-" without one.
+func describeLockup(lockup wallet.StakeLockup) string {
+	if lockup.IsZero() {
+		return "-"
+	}
+	return fmt.Sprintf("until %s and epoch %d (custodian %s)",
+		time.Unix(lockup.UnixTimestamp, 0).UTC().Format("2006-01-02"), lockup.Epoch, lockup.Custodian)
+}
+
+func deactivateStake(cmd *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	account, err := wc.StakeAccount(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to deactivate stake: %w", err)
+	}
+
+	if !stakeYes {
+		printBlue("Stake account: %s\nValidator vote account: %s\nStaked: %s SOL\nNetwork: %s\n",
+			account.Address, account.Validator, wallet.LamportsToSOL(account.Delegated), wc.NetworkName())
+		confirmed, err := promptForConfirmation("Deactivate this stake")
+		if err != nil {
+			return fmt.Errorf("failed to deactivate stake: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Deactivation cancelled.")
+			return nil
+		}
+	}
+
+	sig, err := wc.DeactivateStake(ctx, account)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate stake: %w", err)
+	}
+
+	fmt.Printf("Deactivated %s SOL in %s on %s; it can be withdrawn from the next epoch. Transaction Signature: %s\n",
+		wallet.LamportsToSOL(account.Delegated), account.Address, wc.NetworkName(), sig)
+	return nil
+}
+
+func withdrawStake(cmd *cobra.Command, args []string) error {
+	unit, err := wallet.ParseUnit(stakeUnitFlag)
+	if err != nil {
+		return err
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	account, err := wc.StakeAccount(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to withdraw stake: %w", err)
+	}
+
+	amount := "max"
+	if len(args) > 1 {
+		amount = args[1]
+	}
+	lamports, err := wc.StakeWithdrawalAmount(account, amount, unit)
+	if err != nil {
+		return fmt.Errorf("failed to withdraw stake: %w", err)
+	}
+
+	to, err := wc.RetrieveCurrentWalletAddress()
+	if err != nil {
+		return fmt.Errorf("failed to withdraw stake: %w", err)
+	}
+	if stakeTo != "" {
+		recipient, err := resolveRecipient(wc, stakeTo)
+		if err != nil {
+			return fmt.Errorf("failed to withdraw stake: %w", err)
+		}
+		to = recipient.Address
+	}
+
+	if !stakeYes {
+		printBlue("Stake account: %s (%s)\nAmount: %s SOL of %s SOL withdrawable\nRecipient: %s\nNetwork: %s\n",
+			account.Address, account.State, wallet.LamportsToSOL(lamports), wallet.LamportsToSOL(account.Withdrawable()), to, wc.NetworkName())
+		if lamports == account.Lamports {
+			printBlue("Withdrawing everything closes the stake account.\n")
+		}
+		confirmed, err := promptForConfirmation("Withdraw this stake")
+		if err != nil {
+			return fmt.Errorf("failed to withdraw stake: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Withdrawal cancelled.")
+			return nil
+		}
+	}
+
+	sig, err := wc.WithdrawStake(ctx, account, to, lamports, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to withdraw stake: %w", err)
+	}
+
+	fmt.Printf("Withdrew %s SOL from %s to %s on %s. Transaction Signature: %s\n",
+		wallet.LamportsToSOL(lamports), account.Address, to, wc.NetworkName(), sig)
+	return nil
+}
+
+func listStakingRewards(cmd *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	rewards, err := wc.StakingRewards(cmd.Context(), stakeEpochs)
+	if err != nil {
+		return fmt.Errorf("failed to list staking rewards: %w", err)
+	}
+
+	currency := wc.FiatCurrency()
+	rate := fetchRateOrWarn(wc)
+	fiat := func(lamports uint64) *decimal.Decimal {
+		if rate == nil {
+			return nil
+		}
+		value := wallet.LamportsToSOL(lamports).Mul(*rate).Round(2)
+		return &value
+	}
+
+	if jsonOutput() {
+		output := make([]stakingRewardOutput, 0, len(rewards))
+		for _, reward := range rewards {
+			entry := stakingRewardOutput{
+				StakeAccount: reward.StakeAccount.String(),
+				Epoch:        reward.Epoch,
+				Lamports:     reward.Lamports,
+				SOL:          wallet.LamportsToSOL(reward.Lamports).String(),
+				Fiat:         fiat(reward.Lamports),
+				Commission:   reward.Commission,
+				Slot:         reward.Slot,
+				Timestamp:    reward.Timestamp.UTC().Format(time.RFC3339),
+			}
+			if entry.Fiat != nil {
+				entry.Currency = currency
+			}
+			output = append(output, entry)
+		}
+		return printJSON(output)
+	}
+
+	if len(rewards) == 0 {
+		fmt.Println("No staking rewards.")
+		return nil
+	}
+
+	var total uint64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EPOCH\tSTAKE ACCOUNT\tREWARD\tVALUE\tCOMMISSION\tPAID")
+	for _, reward := range rewards {
+		total += reward.Lamports
+		value, commission := "-", "-"
+		if v := fiat(reward.Lamports); v != nil {
+			value = currency.Format(*v)
+		}
+		if reward.Commission != nil {
+			commission = fmt.Sprintf("%d%%", *reward.Commission)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s SOL\t%s\t%s\t%s\n", reward.Epoch, reward.StakeAccount,
+			wallet.LamportsToSOL(reward.Lamports), value, commission, reward.Timestamp.Format("2006-01-02 15:04"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("Total: %s\n", formatSOLAndFiat(wallet.LamportsToSOL(total), fiat(total), currency))
+	return nil
+}
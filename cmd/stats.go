@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var statsCmd = &cobra.Command{
+	Use:         "stats",
+	Short:       "Summarises statistics recorded by sends from this machine",
+	Annotations: keyAccess(keyAccessNone),
+	Long: `With --latency, prints the p50 and p90 time from submitting a transfer to it being processed,
+confirmed and finalized, per network and priority-fee bucket (compute-unit price in micro-lamports).
+Use it to see whether a higher priority fee actually confirms faster.`,
+	RunE: displayStats,
+}
+
+var (
+	latencyStats  bool
+	latencyRecent int
+)
+
+func init() {
+	statsCmd.Flags().BoolVar(&latencyStats, "latency", false, "Summarise confirmation latency of recent sends")
+	statsCmd.Flags().IntVar(&latencyRecent, "recent", 50, "Number of most recent measured sends to include (0 for all)")
+}
+
+func displayStats(_ *cobra.Command, _ []string) error {
+	if !latencyStats {
+		return errors.New("nothing to show, pass --latency")
+	}
+
+	rc, err := newReadOnlyContext()
+	if err != nil {
+		return err
+	}
+
+	stats, err := rc.LatencyStats(latencyRecent)
+	if err != nil {
+		return fmt.Errorf("failed to read send records: %w", err)
+	}
+
+	if jsonOutput() {
+		return printJSON(stats)
+	}
+
+	if len(stats) == 0 {
+		fmt.Println("No measured sends yet. Latency is recorded for every send from now on.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NETWORK\tPRIORITY FEE\tSENDS\tPROCESSED p50/p90\tCONFIRMED p50/p90\tFINALIZED p50/p90")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", s.Network, s.FeeBucket, s.Sends,
+			formatPercentiles(s.Processed), formatPercentiles(s.Confirmed), formatPercentiles(s.Finalized))
+	}
+	return w.Flush()
+}
+
+// formatPercentiles shows p50/p90 rounded to milliseconds, or a dash when the level was never observed.
+func formatPercentiles(p wallet.LatencyPercentiles) string {
+	if p.Samples == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%s / %s", p.P50.Round(time.Millisecond), p.P90.Round(time.Millisecond))
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var syncCmd = &cobra.Command{
+	Use:         "sync",
+	Short:       "Refreshes the cached balances of every stored wallet",
+	Annotations: keyAccess(keyAccessPublic),
+	Long: `Fetches the balance of every stored wallet from the cluster and caches it in the key file, where
+the wallet list of init shows it together with its age. Balances that could be fetched are saved even
+when others fail; the wallets that could not be refreshed are reported.`,
+	Args: cobra.NoArgs,
+	RunE: syncBalances,
+}
+
+func syncBalances(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	synced, err := wc.SyncBalances(context.Background())
+	var syncErr *wallet.SyncError
+	if err != nil && !errors.As(err, &syncErr) {
+		return fmt.Errorf("failed to sync balances: %w", err)
+	}
+
+	for _, balance := range synced {
+		printBlue("%s: %s SOL\n", balance.Alias, balance.SOL)
+	}
+	return err
+}
@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"github.com/Ghvstcode/sleeng/pkg/wallet"
+	"github.com/fatih/color"
 	"github.com/shopspring/decimal"
+	"io"
+	"os"
+	"os/signal"
 	"sort"
 	"time"
 
@@ -15,16 +20,180 @@ const (
 )
 
 var transactionsCmd = &cobra.Command{
-	Use:   "transactions",
-	Short: "Prints the transaction history in EUR, from newest to oldest.",
-	RunE:  executeTransactions,
+	Use:         "transactions",
+	Short:       "Prints the transaction history in the selected currency, from newest to oldest.",
+	Annotations: usesFlags(keyAccess(keyAccessPublic), usesTransientKey),
+	Long: `Prints the transaction history in the selected currency, from newest to oldest.
+
+With --export csv, json or jsonl the history is written for bookkeeping instead: one row per
+transfer, oldest first, with the timestamp, signature, direction, counterparty, amount in lamports
+and SOL, the rate and fiat value, the network and the fee paid. A transaction with several
+transfers gives several rows sharing its signature. Exports fetch the whole history unless --limit
+is given; use --since and --until to export a single year.
+
+With --export tax the history is written as CSV in the layout of a crypto tax tool, chosen with
+--format: koinly (Koinly's universal format) or cointracking. Transfers are valued at the SOL price
+of their time rather than today's, fees paid by the wallet are in SOL on the row of their
+transaction, and staking rewards are marked as income.
+
+CSV and JSON-lines exports to a --file are written a page of transactions at a time, with a
+checkpoint next to the file. When the export is interrupted, by ^C, a crash or an endpoint that
+keeps failing, run the same command with --resume to continue where it stopped.
+
+With --oneline each transfer takes one line with fixed-width columns: time, direction, amount,
+fiat value, counterparty, fee and signature. Lines stay under the terminal width, dropping the
+signature, the fee and the fiat value in that order when the terminal is narrow.
+
+Transactions are kept in the cache directory once fetched, so later runs only ask the node for
+what happened since. Once the whole history of the wallet has been fetched, even its signatures
+are only listed from the newest one known. --resync fetches everything again and rebuilds the
+cache. Local and custom clusters are not cached.
+
+Transactions labeled with "wallet label" show their label, and exports carry it in the label and
+category columns. --category only shows, or exports, transactions labeled in that category.
+
+With --rewards the staking rewards of the wallet's stake accounts in the latest --reward-epochs
+epochs are shown too, valued in the selected currency. Rewards are paid into the stake accounts, so
+they are totalled separately from what the wallet received.
+
+SOL transfers are valued at the current rate and, next to it, at the rate of the time they were
+made, taken from Kraken's price history. JSON
+output carries the latter in fiatAtTime and rateAtTime. Currencies Kraken does not quote have no
+price history; pass --historical-rates=false to skip fetching it.`,
+	RunE: executeTransactions,
+}
+
+var (
+	historyLimit      int
+	historySince      string
+	historyUntil      string
+	historyExport     string
+	historyExportFile string
+	historyOneline    bool
+	historyResume     bool
+	historyCategory   string
+	historySent       bool
+	historyReceived   bool
+	historyMinAmount  string
+	historyRewards    bool
+	historyAtTime     bool
+	historyResync     bool
+	historyTaxFormat  string
+	rewardEpochs      int
+	concurrency       int
+	maxAttempts       int
+)
+
+func init() {
+	transactionsCmd.Flags().IntVar(&historyLimit, "limit", wallet.DefaultTransactionLimit, "Maximum number of transactions to fetch (0 for no limit)")
+	transactionsCmd.Flags().IntVar(&concurrency, "concurrency", 0, "Maximum number of transactions fetched at once (default 50, lowered automatically when rate limited)")
+	transactionsCmd.Flags().IntVar(&maxAttempts, "max-attempts", 0, fmt.Sprintf("Attempts per request on rate-limit or network errors (default %d)", wallet.DefaultRetryPolicy.MaxAttempts))
+	transactionsCmd.Flags().StringVar(&historySince, "since", "", "Only show transactions from this date on (YYYY-MM-DD or RFC 3339)")
+	transactionsCmd.Flags().StringVar(&historyUntil, "until", "", "Only show transactions up to and including this date (YYYY-MM-DD or RFC 3339)")
+	transactionsCmd.Flags().StringVar(&historyExport, "export", "", "Write the history for bookkeeping as csv, json, jsonl (JSON lines) or tax (see --format)")
+	transactionsCmd.Flags().StringVar(&historyTaxFormat, "format", string(wallet.TaxFormatKoinly), "Layout of --export tax: koinly or cointracking")
+	transactionsCmd.Flags().StringVar(&historyExportFile, "file", "", "Write the export to this file instead of stdout")
+	transactionsCmd.Flags().BoolVar(&historyResume, "resume", false, "Continue an interrupted csv or jsonl export to --file from its checkpoint")
+	transactionsCmd.Flags().StringVar(&historyCategory, "category", "", "Only show transactions labeled in this category")
+	transactionsCmd.Flags().BoolVar(&historySent, "sent", false, "Only show transfers out of the wallet")
+	transactionsCmd.Flags().BoolVar(&historyReceived, "received", false, "Only show transfers into the wallet, staking rewards included")
+	transactionsCmd.Flags().StringVar(&historyMinAmount, "min-amount", "", "Only show transfers of at least this amount, in SOL or in whole tokens for token transfers")
+	transactionsCmd.Flags().BoolVar(&historyRewards, "rewards", false, "Include the staking rewards of the wallet's stake accounts")
+	transactionsCmd.Flags().IntVar(&rewardEpochs, "reward-epochs", wallet.DefaultRewardEpochs, "Number of latest completed epochs to include staking rewards of")
+	transactionsCmd.Flags().BoolVar(&historyAtTime, "historical-rates", true, "Also show the value of each SOL transfer at the time it was made, from Kraken's price history")
+	transactionsCmd.Flags().BoolVar(&historyResync, "resync", false, "Fetch the whole history again instead of only what happened since the last run")
+	transactionsCmd.Flags().BoolVar(&historyOneline, "oneline", false, "Print one line per transfer, kept under the terminal width (120 columns when not a terminal)")
+}
+
+// Formats accepted by --export.
+const (
+	exportCSV   = "csv"
+	exportJSON  = "json"
+	exportJSONL = "jsonl"
+	exportTax   = "tax"
+)
+
+// resumableExport reports whether the --export format can be written to --file page by page.
+func resumableExport() bool {
+	return historyExport == exportCSV || historyExport == exportJSONL
 }
 
 func executeTransactions(cmd *cobra.Command, args []string) error {
-	wc := wallet.NewWalletConfig()
+	switch historyExport {
+	case "", exportCSV, exportJSON, exportJSONL:
+		if cmd.Flags().Changed("format") {
+			return errors.New("--format only applies to --export tax")
+		}
+	case exportTax:
+		if _, err := wallet.ParseTaxFormat(historyTaxFormat); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid --export %q, expected %s, %s, %s or %s", historyExport, exportCSV, exportJSON, exportJSONL, exportTax)
+	}
+	if historyExportFile != "" && historyExport == "" {
+		return errors.New("--file needs --export")
+	}
+	if historyResume && (historyExportFile == "" || !resumableExport()) {
+		return errors.New("--resume needs --file with --export csv or jsonl")
+	}
+	if historyOneline && (historyExport != "" || jsonOutput()) {
+		return errors.New("--oneline only applies to the text output, not to --export or --output json")
+	}
+	if historyRewards && historyExportFile != "" && resumableExport() {
+		return errors.New("--rewards does not apply to csv or jsonl exports to --file; export to stdout instead")
+	}
+	// Refuse before the history is fetched, which can take a while.
+	if historyExportFile != "" && wallet.ReadOnlyMode() {
+		return fmt.Errorf("%w: %s", wallet.ErrReadOnlyMode, historyExportFile)
+	}
 
-	transactions, err := wc.GetTransactionHistory()
+	opts := wallet.GetTransactionHistoryOpts{Limit: historyLimit, Concurrency: concurrency, MaxAttempts: maxAttempts, Cache: wallet.DefaultHistoryCache(), Resync: historyResync}
+	if historyExport != "" && !cmd.Flags().Changed("limit") {
+		opts.Limit = 0
+	}
+	if historyRewards {
+		opts.RewardEpochs = rewardEpochs
+	}
+	var err error
+	if historySince != "" {
+		opts.Since, err = parseSince(historySince)
+		if err != nil {
+			return err
+		}
+	}
+	if historyUntil != "" {
+		opts.Until, err = parseUntil(historyUntil)
+		if err != nil {
+			return err
+		}
+	}
+	if !opts.Since.IsZero() && !opts.Until.IsZero() && opts.Until.Before(opts.Since) {
+		return fmt.Errorf("--until %s is before --since %s", historyUntil, historySince)
+	}
+	filter, err := historyFilter()
+	if err != nil {
+		return err
+	}
+
+	wc, err := newWalletConfig()
 	if err != nil {
+		return err
+	}
+
+	if historyExportFile != "" && resumableExport() {
+		return exportTransactionsToFile(cmd, wc, opts, filter)
+	}
+
+	transactions, err := wc.GetTransactionHistoryWithOpts(opts)
+	var partial *wallet.HistoryFetchError
+	if errors.As(err, &partial) {
+		if jsonOutput() {
+			defer printWarning("Warning: %v\n", partial)
+		} else {
+			defer printYellow("Warning: %v\n", partial)
+		}
+	} else if err != nil {
 		return fmt.Errorf("error fetching transactions: %v", err)
 	}
 
@@ -33,30 +202,265 @@ func executeTransactions(cmd *cobra.Command, args []string) error {
 		return transactions[i].Timestamp.After(transactions[j].Timestamp)
 	})
 
-	rate, err := wc.FetchSOLEURRate()
+	labels, err := wc.TransactionLabels()
 	if err != nil {
-		return fmt.Errorf("error fetching SOL to EUR rate: %v", err)
+		if historyCategory != "" {
+			return fmt.Errorf("failed to read transaction labels: %w", err)
+		}
+		printWarning("Warning: showing transactions without labels, %v\n", err)
+	}
+	if historyCategory != "" {
+		transactions = wallet.FilterByCategory(transactions, labels, historyCategory)
 	}
+	transactions = wallet.FilterTransactions(transactions, filter)
 
-	printTransactions(transactions, rate)
+	currency := wc.FiatCurrency()
+	rate := fetchRateOrWarn(wc)
+	defer wallet.TimePhase(wallet.PhaseRender)()
+	if historyExport != "" {
+		network := wc.Network
+		if network == "" {
+			network = wallet.DefaultNetwork
+		}
+		if historyExport == exportTax {
+			// Tax tools need what each transfer was worth when it was made, not today.
+			rows := wallet.HistoryRows(transactions, nil, currency, network)
+			rows = wallet.PriceHistoryRows(rows, historicalRatesOrWarn(wc, transactions), currency)
+			return exportTransactions(wallet.LabelHistoryRows(rows, labels))
+		}
+		return exportTransactions(wallet.LabelHistoryRows(wallet.HistoryRows(transactions, rate, currency, network), labels))
+	}
+	names := addressNamesOrWarn(wc)
+	var atTime wallet.HistoricalRates
+	if historyAtTime && rate != nil && !historyOneline {
+		atTime = historicalRatesOrWarn(wc, transactions)
+	}
+
+	if jsonOutput() {
+		return printJSON(transactionsOutput(transactions, rate, atTime, currency, names, labels))
+	}
+
+	printTransactions(transactions, rate, atTime, currency, names, labels)
 
 	return nil
 }
 
-func printTransactions(transactions []*wallet.Transaction, rate decimal.Decimal) {
+// exportTransactions writes rows in the --export format to --file, or to stdout without one.
+func exportTransactions(rows []wallet.HistoryRow) error {
+	write := wallet.WriteHistoryCSV
+	switch historyExport {
+	case exportJSON:
+		write = wallet.WriteHistoryJSON
+	case exportJSONL:
+		write = wallet.WriteHistoryJSONLines
+	case exportTax:
+		format, err := wallet.ParseTaxFormat(historyTaxFormat)
+		if err != nil {
+			return err
+		}
+		write = func(w io.Writer, rows []wallet.HistoryRow) error {
+			return wallet.WriteTaxReport(w, format, rows)
+		}
+	}
+
+	if historyExportFile == "" {
+		return write(os.Stdout, rows)
+	}
+
+	file, err := os.OpenFile(historyExportFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create export: %w", err)
+	}
+	if err := write(file, rows); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	printBlue("Exported %d transfers to %s\n", len(rows), historyExportFile)
+	return nil
+}
+
+// exportTransactionsToFile writes a csv or jsonl export to --file page by page, so an interrupted
+// export can be continued with --resume. ^C stops it at the end of the page being fetched.
+func exportTransactionsToFile(cmd *cobra.Command, wc *wallet.WalletConfig, opts wallet.GetTransactionHistoryOpts, filter wallet.HistoryFilter) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	exportOpts := wallet.HistoryExportOpts{
+		Path:     historyExportFile,
+		Format:   wallet.HistoryFormat(historyExport),
+		History:  opts,
+		Currency: wc.FiatCurrency(),
+		Resume:   historyResume,
+		Category: historyCategory,
+		Filter:   filter,
+		OnProgress: func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\rExported %d of %d transactions", done, total)
+		},
+	}
+	// A resumed export keeps the rate it started with.
+	if !historyResume {
+		exportOpts.Rate = fetchRateOrWarn(wc)
+	}
+
+	result, err := wc.ExportHistory(ctx, exportOpts)
+	if result != nil && result.Transactions > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil {
+		if _, statErr := os.Stat(historyExportFile + wallet.HistoryCheckpointSuffix); statErr == nil {
+			printWarning("Run the same command with --resume to continue the export.\n")
+		}
+		return err
+	}
+	printBlue("Exported %d transfers of %d transactions to %s\n", result.Rows, result.Transactions, historyExportFile)
+	return nil
+}
+
+// transactionOutput is the JSON form of a transaction. Token transfers carry the token amount
+// instead of lamports and their value in the selected currency.
+type transactionOutput struct {
+	Signature string `json:"signature"`
+	Direction string `json:"direction"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	// FromName and ToName name the address when it is a stored wallet or a contact.
+	FromName string           `json:"fromName,omitempty"`
+	ToName   string           `json:"toName,omitempty"`
+	Lamports uint64           `json:"lamports,omitempty"`
+	Fiat     *decimal.Decimal `json:"fiat,omitempty"`
+	Currency wallet.Currency  `json:"currency,omitempty"`
+	// EUR repeats Fiat when the currency is EUR, for scripts written before currencies were configurable.
+	EUR *decimal.Decimal `json:"eur,omitempty"`
+	// FiatAtTime is the value at RateAtTime, the SOL price when the transaction was made.
+	FiatAtTime *decimal.Decimal `json:"fiatAtTime,omitempty"`
+	RateAtTime *decimal.Decimal `json:"rateAtTime,omitempty"`
+	Token      string           `json:"token,omitempty"`
+	Mint       string           `json:"mint,omitempty"`
+	Amount     *decimal.Decimal `json:"amount,omitempty"`
+	Timestamp  string           `json:"timestamp"`
+	// Fee is the fee of the whole transaction in lamports, set on its first transfer when the wallet paid
+	// it, and FeeFiat its value.
+	Fee     uint64           `json:"fee,omitempty"`
+	FeeFiat *decimal.Decimal `json:"feeFiat,omitempty"`
+	// Label and Category are set when the transaction was labeled with "wallet label".
+	Label    string `json:"label,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Epoch is the epoch a staking reward was earned in.
+	Epoch uint64 `json:"epoch,omitempty"`
+	// Memo is the memo recorded with the transaction.
+	Memo string `json:"memo,omitempty"`
+}
+
+func transactionsOutput(transactions []*wallet.Transaction, rate *decimal.Decimal, atTime wallet.HistoricalRates, currency wallet.Currency, names map[string]string, labels map[string]wallet.TransactionLabel) []transactionOutput {
+	output := make([]transactionOutput, 0, len(transactions))
+	feeShown := make(map[string]bool)
+	for _, tx := range transactions {
+		entry := transactionOutput{
+			Signature: tx.Signature.String(),
+			Direction: "received",
+			From:      tx.From.String(),
+			To:        tx.To.String(),
+			FromName:  names[tx.From.String()],
+			ToName:    names[tx.To.String()],
+			Timestamp: tx.Timestamp.UTC().Format(time.RFC3339),
+			Label:     labels[tx.Signature.String()].Text,
+			Category:  labels[tx.Signature.String()].Category,
+			Memo:      tx.Memo,
+		}
+		if tx.IsSender {
+			entry.Direction = "sent"
+		}
+		// Every transfer of a transaction carries its fee, which is only counted once, on the first of them.
+		if !feeShown[tx.Signature.String()] {
+			feeShown[tx.Signature.String()] = true
+			entry.Fee = tx.Fee
+			if tx.Fee > 0 && rate != nil {
+				fee := wallet.LamportsToSOL(tx.Fee).Mul(*rate).Round(2)
+				entry.FeeFiat = &fee
+				entry.Currency = currency
+			}
+		}
+		if tx.Reward {
+			entry.Signature, entry.Direction, entry.Epoch = "", "reward", tx.Epoch
+			entry.Label, entry.Category = "", ""
+		}
+
+		if tx.IsToken() {
+			amount := tx.TokenAmount()
+			entry.Token = tx.Symbol
+			entry.Mint = tx.Mint.String()
+			entry.Amount = &amount
+		} else {
+			entry.Lamports = tx.Amount
+			if rate != nil {
+				fiat := wallet.LamportsToSOL(tx.Amount).Mul(*rate).Round(2)
+				entry.Fiat = &fiat
+				entry.Currency = currency
+				if currency == wallet.DefaultCurrency {
+					entry.EUR = &fiat
+				}
+			}
+			if rateAtTime := atTime.At(tx.Timestamp); rateAtTime != nil {
+				fiat := wallet.LamportsToSOL(tx.Amount).Mul(*rateAtTime).Round(2)
+				entry.FiatAtTime, entry.RateAtTime = &fiat, rateAtTime
+				entry.Currency = currency
+			}
+		}
+
+		output = append(output, entry)
+	}
+	return output
+}
+
+// printTransactions prints transactions grouped by signature, with the labels of the labeled ones.
+// SOL transfers found in atTime are also shown at the rate of their time.
+func printTransactions(transactions []*wallet.Transaction, rate *decimal.Decimal, atTime wallet.HistoricalRates, currency wallet.Currency, names map[string]string, labels map[string]wallet.TransactionLabel) {
 	if len(transactions) == 0 {
 		fmt.Println("No transactions to display.")
 		return
 	}
-	for _, tx := range transactions {
-		printTransaction(tx, rate)
+
+	groups := wallet.GroupTransactions(transactions)
+	if historyOneline {
+		format := onelineFormat{
+			Rate:     rate,
+			Currency: currency,
+			Names:    names,
+			Labels:   labels,
+			Location: time.Local,
+			Width:    terminalWidth(),
+			Color:    !color.NoColor,
+		}
+		for _, line := range format.Lines(groups) {
+			fmt.Println(line)
+		}
+		printHistorySummary(wallet.SummarizeHistory(groups), rate, currency)
+		return
+	}
+
+	for _, group := range groups {
+		label := labels[group.Signature.String()]
+		if len(group.Transfers) == 1 {
+			printTransaction(group.Transfers[0], rate, atTime, currency, names, label)
+			continue
+		}
+		printTransactionGroup(group, rate, atTime, currency, names, label)
 	}
+	printHistorySummary(wallet.SummarizeHistory(groups), rate, currency)
 }
 
-func printTransaction(tx *wallet.Transaction, rate decimal.Decimal) {
-	amountInLamports := decimal.NewFromInt(int64(tx.Amount))
-	amountInSol := amountInLamports.Div(decimal.NewFromInt(solToLamportConversion))
-	amountInEur := amountInSol.Mul(rate)
+// printTransaction prints one transaction. SOL transfers are shown in currency, or in SOL when rate is nil,
+// followed by their value at the time when atTime has it. Addresses found in names are shown with
+// their name, and a label that has text after the timestamp.
+func printTransaction(tx *wallet.Transaction, rate *decimal.Decimal, atTime wallet.HistoricalRates, currency wallet.Currency, names map[string]string, label wallet.TransactionLabel) {
+	if tx.Reward {
+		fmt.Printf("Action: Staking reward\nStake Account: %s\nEpoch: %d\nAmount: %s\nTimestamp: %s\n---\n",
+			namedAddress(tx.To.String(), names), tx.Epoch, transferAmount(tx, rate, atTime, currency), tx.Timestamp.Format(time.RFC3339))
+		return
+	}
 
 	action := "Received"
 	if tx.IsSender {
@@ -64,11 +468,174 @@ func printTransaction(tx *wallet.Transaction, rate decimal.Decimal) {
 	}
 
 	fmt.Printf(
-		"Action: %s\nFrom: %s\nTo: %s\nAmount: %s EUR\nTimestamp: %s\n---\n",
+		"Action: %s\nFrom: %s\nTo: %s\nAmount: %s\n",
 		action,
-		tx.From,
-		tx.To,
-		amountInEur.StringFixed(2),
-		tx.Timestamp.Format(time.RFC3339),
+		namedAddress(tx.From.String(), names),
+		namedAddress(tx.To.String(), names),
+		transferAmount(tx, rate, atTime, currency),
 	)
+	if tx.Fee > 0 {
+		fmt.Printf("Fee: %s\n", feeAmount(tx.Fee, rate, currency))
+	}
+	if tx.Memo != "" {
+		fmt.Printf("Memo: %s\n", tx.Memo)
+	}
+	fmt.Printf("Timestamp: %s\n", tx.Timestamp.Format(time.RFC3339))
+	printLabel(label)
+	fmt.Println("---")
+}
+
+// printLabel prints the label of a transaction, or nothing when it has none.
+func printLabel(label wallet.TransactionLabel) {
+	if label.Text != "" {
+		fmt.Printf("Label: %s\n", label)
+	}
+}
+
+// printTransactionGroup prints a transaction with several transfers as one block with a line per
+// transfer, in the order of its instructions. The fee is shown once, for the whole transaction.
+func printTransactionGroup(group *wallet.TransactionGroup, rate *decimal.Decimal, atTime wallet.HistoricalRates, currency wallet.Currency, names map[string]string, label wallet.TransactionLabel) {
+	fmt.Printf("Transaction: %s (%d transfers)\n", group.Signature, len(group.Transfers))
+	for _, tx := range group.Transfers {
+		if tx.IsSender {
+			fmt.Printf("  Sent %s to %s\n", transferAmount(tx, rate, atTime, currency), namedAddress(tx.To.String(), names))
+		} else {
+			fmt.Printf("  Received %s from %s\n", transferAmount(tx, rate, atTime, currency), namedAddress(tx.From.String(), names))
+		}
+	}
+	if group.Fee > 0 {
+		fmt.Printf("Fee: %s\n", feeAmount(group.Fee, rate, currency))
+	}
+	if group.Memo != "" {
+		fmt.Printf("Memo: %s\n", group.Memo)
+	}
+	fmt.Printf("Timestamp: %s\n", group.Timestamp.Format(time.RFC3339))
+	printLabel(label)
+	fmt.Println("---")
+}
+
+// printHistorySummary prints the SOL received, sent and paid in fees over the transactions shown.
+func printHistorySummary(summary wallet.HistorySummary, rate *decimal.Decimal, currency wallet.Currency) {
+	sol := func(lamports uint64) string {
+		return solAmount(decimal.NewFromInt(int64(lamports)), rate, currency)
+	}
+	fmt.Printf("%d transactions, %d transfers: received %s, sent %s, fees %s\n",
+		summary.Transactions, summary.Transfers, sol(summary.Received), sol(summary.Sent), feeAmount(summary.Fees, rate, currency))
+	if summary.Rewards > 0 {
+		fmt.Printf("Staking rewards: %s\n", sol(summary.Rewards))
+	}
+}
+
+// transferAmount shows the amount of a transfer: tokens in the token itself, SOL in currency, or in
+// SOL when rate is nil. A SOL transfer found in atTime is followed by its value at the time, as in
+// "€12.50 (€10.00 at the time)".
+func transferAmount(tx *wallet.Transaction, rate *decimal.Decimal, atTime wallet.HistoricalRates, currency wallet.Currency) string {
+	// Token transfers are shown in the token itself; the SOL rate says nothing about their value.
+	if tx.IsToken() {
+		return fmt.Sprintf("%s %s", tx.TokenAmount(), tx.Symbol)
+	}
+	amount := solAmount(decimal.NewFromInt(int64(tx.Amount)), rate, currency)
+	if rateAtTime := atTime.At(tx.Timestamp); rate != nil && rateAtTime != nil {
+		amount += fmt.Sprintf(" (%s at the time)", solAmount(decimal.NewFromInt(int64(tx.Amount)), rateAtTime, currency))
+	}
+	return amount
+}
+
+// solAmount shows an amount of lamports in currency, or in SOL when rate is nil.
+func solAmount(amountInLamports decimal.Decimal, rate *decimal.Decimal, currency wallet.Currency) string {
+	amountInSol := amountInLamports.Div(decimal.NewFromInt(solToLamportConversion))
+	if rate != nil {
+		return currency.Format(amountInSol.Mul(*rate))
+	}
+	return amountInSol.String() + " SOL"
+}
+
+// feeAmount shows a fee in SOL, where it is a readable number, followed by its value in currency
+// unless rate is nil.
+func feeAmount(lamports uint64, rate *decimal.Decimal, currency wallet.Currency) string {
+	fee := wallet.LamportsToSOL(lamports)
+	if rate == nil {
+		return fee.String() + " SOL"
+	}
+	return fmt.Sprintf("%s SOL (%s)", fee, currency.Format(fee.Mul(*rate)))
+}
+
+// namedAddress shows an address together with the name of the wallet or contact it belongs to.
+func namedAddress(address string, names map[string]string) string {
+	if name, ok := names[address]; ok {
+		return fmt.Sprintf("%s (%s)", address, name)
+	}
+	return address
+}
+
+// historyFilter returns the filter of --sent, --received and --min-amount.
+func historyFilter() (wallet.HistoryFilter, error) {
+	filter := wallet.HistoryFilter{Sent: historySent, Received: historyReceived}
+	if historyMinAmount != "" {
+		amount, err := decimal.NewFromString(historyMinAmount)
+		if err != nil || amount.IsNegative() {
+			return filter, fmt.Errorf("invalid --min-amount %q, expected a non-negative amount", historyMinAmount)
+		}
+		filter.MinAmount = amount
+	}
+	return filter, nil
+}
+
+// parseSince accepts a date, read in local time, or a full RFC 3339 timestamp.
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q, expected YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
+
+// parseUntil accepts a date, read in local time, or a full RFC 3339 timestamp. A date includes the
+// whole day, so --until 2023-12-31 ends the range at the end of the year.
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --until %q, expected YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
+
+// fetchRateOrWarn fetches the SOL rate in the selected currency, warning on stderr and returning nil
+// when it is unavailable so amounts can be shown in SOL instead.
+func fetchRateOrWarn(wc *wallet.WalletConfig) *decimal.Decimal {
+	rate, err := wc.FetchSOLRate()
+	if err != nil {
+		printWarning("Warning: showing amounts in SOL, %v\n", err)
+		return nil
+	}
+	return &rate
+}
+
+// historicalRatesOrWarn returns the SOL rate at the time of each transaction, warning on stderr when
+// some or all of them are unavailable so those transactions are only valued at the current rate.
+func historicalRatesOrWarn(wc *wallet.WalletConfig, transactions []*wallet.Transaction) wallet.HistoricalRates {
+	rates, err := wc.HistoricalRates(transactions)
+	if err != nil {
+		printWarning("Warning: showing some amounts without their value at the time, %v\n", err)
+	}
+	return rates
+}
+
+// addressNamesOrWarn returns the names of stored wallets and contacts by address, warning on stderr
+// and returning nil when they cannot be read so transactions are shown with bare addresses.
+func addressNamesOrWarn(wc *wallet.WalletConfig) map[string]string {
+	names, err := wc.AddressNames()
+	if err != nil {
+		printWarning("Warning: showing addresses without names, %v\n", err)
+		return nil
+	}
+	return names
 }
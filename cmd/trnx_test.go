@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestTransactionsOutputFees(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	sent := &wallet.Transaction{Signature: solana.Signature{1}, Amount: 2_000_000_000, Fee: 10_000_000, From: onelineSelf, To: onelineAlice, IsSender: true, Timestamp: at, Memo: "invoice 42"}
+	received := &wallet.Transaction{Signature: solana.Signature{2}, Amount: 1_000_000_000, From: onelineAlice, To: onelineSelf, Timestamp: at.Add(-time.Hour)}
+	rate := decimal.NewFromInt(150)
+	atTime := wallet.HistoricalRates{at.Unix(): decimal.NewFromInt(100)}
+
+	output := transactionsOutput([]*wallet.Transaction{sent, received}, &rate, atTime, "USD", nil, nil)
+	if assert.Len(t, output, 2) {
+		assert.Equal(t, uint64(10_000_000), output[0].Fee)
+		assert.Equal(t, "1.5", output[0].FeeFiat.String())
+		assert.Equal(t, "300", output[0].Fiat.String())
+		assert.Equal(t, "200", output[0].FiatAtTime.String())
+		assert.Equal(t, "100", output[0].RateAtTime.String())
+		assert.Equal(t, "invoice 42", output[0].Memo)
+
+		// The wallet paid no fee for what it received, and the rate at its time is unknown.
+		assert.Zero(t, output[1].Fee)
+		assert.Nil(t, output[1].FeeFiat)
+		assert.Nil(t, output[1].FiatAtTime)
+		assert.Empty(t, output[1].Memo)
+	}
+
+	// Without a rate, only the fee in lamports is known.
+	output = transactionsOutput([]*wallet.Transaction{sent}, nil, nil, "USD", nil, nil)
+	assert.Equal(t, uint64(10_000_000), output[0].Fee)
+	assert.Nil(t, output[0].FeeFiat)
+}
+
+func TestTransactionsOutputFeeOncePerTransaction(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	batch := solana.Signature{3}
+	transactions := []*wallet.Transaction{
+		{Signature: batch, Amount: 2_000_000_000, Fee: 10_000_000, From: onelineSelf, To: onelineAlice, IsSender: true, Timestamp: at},
+		{Signature: batch, Amount: 500_000_000, Fee: 10_000_000, From: onelineSelf, To: onelineBob, IsSender: true, Timestamp: at},
+		{Signature: solana.Signature{4}, Amount: 1_000_000_000, Fee: 5_000, From: onelineSelf, To: onelineBob, IsSender: true, Timestamp: at.Add(-time.Hour)},
+	}
+	rate := decimal.NewFromInt(100)
+
+	output := transactionsOutput(transactions, &rate, nil, "USD", nil, nil)
+	if assert.Len(t, output, 3) {
+		// The fee of the batch is on its first transfer only, so summing the fees counts it once.
+		assert.Equal(t, uint64(10_000_000), output[0].Fee)
+		assert.Equal(t, "1", output[0].FeeFiat.String())
+		assert.Zero(t, output[1].Fee)
+		assert.Nil(t, output[1].FeeFiat)
+		assert.Equal(t, uint64(5_000), output[2].Fee)
+
+		var total uint64
+		for _, entry := range output {
+			total += entry.Fee
+		}
+		assert.Equal(t, wallet.SummarizeHistory(wallet.GroupTransactions(transactions)).Fees, total)
+	}
+}
+
+func TestFeeAmount(t *testing.T) {
+	rate := decimal.NewFromInt(150)
+	assert.Equal(t, "0.000005 SOL", feeAmount(5000, nil, "EUR"))
+	assert.Equal(t, "0.01 SOL ("+wallet.Currency("EUR").Format(decimal.RequireFromString("1.5"))+")", feeAmount(10_000_000, &rate, "EUR"))
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// ErrBackupMismatch is returned by verify-backup when the seed phrase derives another address.
+var ErrBackupMismatch = errors.New("backup does not match")
+
+// ExitBackupMismatch is the exit code of verify-backup when the seed phrase derives another address.
+// Other failures, such as a mistyped word, exit with 1.
+const ExitBackupMismatch = 4
+
+var verifyBackupCmd = &cobra.Command{
+	Use:         "verify-backup",
+	Short:       "Checks a written-down seed phrase against the active wallet, or the one given with --alias",
+	Annotations: usesFlags(keyAccess(keyAccessPublic), usesAlias),
+	Long: `Asks for the seed phrase one masked word at a time, derives the address along the derivation path
+stored for the wallet and compares it with the stored public key. Nothing is written, and the words
+are wiped from memory once the address is derived.
+
+Exits with 0 when the backup matches, 4 when it derives another address and 1 on other errors.`,
+	Args: cobra.NoArgs,
+	RunE: verifyBackup,
+}
+
+var backupWordCount int
+
+func init() {
+	verifyBackupCmd.Flags().IntVar(&backupWordCount, "words", 12, "Number of words in the seed phrase: 12, 15, 18, 21 or 24")
+	verifyBackupCmd.Flags().BoolVar(&withPassphrase, "with-passphrase", false, "Prompt for the BIP-39 passphrase of the seed phrase")
+}
+
+// promptForSeedWords asks for count seed words one masked prompt at a time. Each word is checked
+// against the BIP-39 word list as it is typed, so a typo is caught at its position.
+func promptForSeedWords(count int) (wallet.SeedWords, error) {
+	return readSeedWords(count, func(i int) (string, error) {
+		prompt := promptui.Prompt{
+			Label:     fmt.Sprintf("Word %d of %d", i, count),
+			Mask:      '*',
+			Validate:  validUTF8(validSeedWord),
+			Templates: promptTemplates(),
+			Stdin:     promptStdin,
+			Stdout:    promptStdout,
+		}
+		return prompt.Run()
+	})
+}
+
+// readSeedWords reads count seed words with read, which is given the position of each word from 1.
+// When a read fails, the words read so far are wiped and input that ended fails with ErrInputClosed,
+// so a partial phrase is never checked.
+func readSeedWords(count int, read func(i int) (string, error)) (wallet.SeedWords, error) {
+	words := make(wallet.SeedWords, 0, count)
+	for i := 1; i <= count; i++ {
+		word, err := read(i)
+		if err != nil {
+			words.Wipe()
+			return nil, promptError(err)
+		}
+		words = append(words, []byte(seedWord(word)))
+	}
+	return words, nil
+}
+
+// seedWord normalises a typed seed word, ignoring case and surrounding spaces.
+func seedWord(input string) string {
+	return strings.ToLower(strings.TrimSpace(input))
+}
+
+// validSeedWord checks a typed seed word against the BIP-39 word list.
+func validSeedWord(input string) error {
+	if !wallet.IsSeedWord(seedWord(input)) {
+		return errors.New("not a BIP-39 word")
+	}
+	return nil
+}
+
+func verifyBackup(_ *cobra.Command, _ []string) error {
+	valid := false
+	for _, count := range wallet.SeedWordCounts {
+		valid = valid || backupWordCount == count
+	}
+	if !valid {
+		return fmt.Errorf("--words must be 12, 15, 18, 21 or 24")
+	}
+
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	info, err := wc.GetWalletInfo(aliasFlag)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet info: %w", err)
+	}
+	if !info.Derivation.IsSeedDerived() {
+		return fmt.Errorf("%s: %w", info.Alias, wallet.ErrNotSeedDerived)
+	}
+
+	printBlue("Enter the %d words of the seed phrase of %s (%s).\n", backupWordCount, info.Alias, info.PublicKey)
+	words, err := promptForSeedWords(backupWordCount)
+	if err != nil {
+		return fmt.Errorf("failed to get seed phrase: %w", err)
+	}
+	defer words.Wipe()
+
+	var passphrase string
+	if info.Derivation.Passphrase || withPassphrase {
+		passphrase, err = promptForSecret("Please enter the BIP-39 passphrase of the seed phrase")
+		if err != nil {
+			return fmt.Errorf("failed to get seed passphrase: %w", err)
+		}
+	}
+
+	check, err := wc.VerifyBackup(info.Alias, words, passphrase)
+	words.Wipe()
+	if err != nil && !errors.Is(err, wallet.ErrDerivationMismatch) {
+		return fmt.Errorf("failed to verify backup: %w", err)
+	}
+
+	if jsonOutput() {
+		if err := printJSON(check); err != nil {
+			return err
+		}
+	} else if check.Match {
+		printBlue("Backup matches %s (%s) along %s.\n", check.Alias, check.PublicKey, check.Derivation)
+	}
+	if !check.Match {
+		return fmt.Errorf("%w: the seed phrase does NOT derive %s (%s) along %s", ErrBackupMismatch, check.Alias, check.PublicKey, check.Derivation)
+	}
+	return nil
+}
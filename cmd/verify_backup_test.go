@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/manifoldco/promptui"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestValidSeedWord(t *testing.T) {
+	for _, word := range []string{"legal", " Legal", "ABANDON "} {
+		assert.NoError(t, validSeedWord(word), word)
+	}
+	for _, word := range []string{"", "legall", "legal winner"} {
+		assert.Error(t, validSeedWord(word), word)
+	}
+	assert.Error(t, validUTF8(validSeedWord)("leg\xffal"))
+}
+
+func TestReadSeedWords(t *testing.T) {
+	typed := []string{" Legal", "WINNER"}
+	read := func(i int) (string, error) {
+		if i > len(typed) {
+			return "", promptui.ErrEOF
+		}
+		return typed[i-1], nil
+	}
+
+	words, err := readSeedWords(2, read)
+	assert.NoError(t, err)
+	assert.Equal(t, wallet.SeedWords{[]byte("legal"), []byte("winner")}, words)
+
+	// Input ending before the last word aborts instead of checking a partial phrase.
+	_, err = readSeedWords(12, read)
+	assert.ErrorIs(t, err, ErrInputClosed)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+var watchCmd = &cobra.Command{
+	Use:         "watch",
+	Short:       "Prints incoming and outgoing transfers of a wallet as they happen",
+	Annotations: usesFlags(keyAccess(keyAccessPublic), usesAlias),
+	Long: `Subscribes to the wallet over the websocket API of the cluster and prints a line whenever its
+balance changes or a transfer to or from it is finalized, with the direction, counterparty, amount and
+its value in the selected currency. Watches the active wallet unless --alias is given, and runs until
+Ctrl-C. A dropped connection is reconnected automatically, and transfers finalized while it was down
+are printed once it is back. Changes other processes make to the key file are reported on stderr as
+they happen.`,
+	Args: cobra.NoArgs,
+	RunE: watchWallet,
+}
+
+func watchWallet(_ *cobra.Command, _ []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	info, err := wc.GetWalletInfo(aliasFlag)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet info: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	printBlue("Watching %s (%s) on %s. Press Ctrl-C to stop.\n", info.Alias, info.PublicKey, wc.NetworkName())
+	go watchKeyFile(ctx, wc, func(reload wallet.KeystoreReload) {
+		for _, change := range reload.Changes {
+			switch {
+			case change.Kind == wallet.ChangeRemoved && change.Alias == info.Alias:
+				printWarning("Warning: %s was removed from the key file; still watching %s\n", info.Alias, info.PublicKey)
+			case change.Kind == wallet.ChangeRenamed && change.From == info.Alias:
+				printWarning("Warning: %s was renamed to %s; still watching %s\n", info.Alias, change.To, info.PublicKey)
+			}
+		}
+	})
+	err = wc.WatchAddress(ctx, info.Alias, func(ev wallet.TransferEvent) {
+		switch {
+		case ev.Kind == wallet.TransferEventReconnecting:
+			printWarning("Warning: connection lost (%v); reconnecting in %s\n", ev.Err, ev.RetryIn.Round(time.Millisecond))
+		case ev.Err != nil:
+			printWarning("Warning: could not fetch transaction %s: %v\n", ev.Signature, ev.Err)
+		default:
+			fmt.Printf("%s %s\n", time.Now().Format("15:04:05"), formatTransferEvent(ev))
+		}
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// formatTransferEvent describes a balance change or transfer in one line.
+func formatTransferEvent(ev wallet.TransferEvent) string {
+	if ev.Kind == wallet.TransferEventBalance {
+		line := fmt.Sprintf("Balance %s SOL (%s SOL", wallet.LamportsToSOL(ev.Lamports), signed(decimal.NewFromInt(ev.Change).Shift(-9)))
+		if ev.Value != nil {
+			line += ", " + signedFiat(ev.Currency, *ev.Value)
+		}
+		return line + ")"
+	}
+
+	tx := ev.Transaction
+	direction, preposition, counterparty := "Received", "from", tx.From
+	if tx.IsSender {
+		direction, preposition, counterparty = "Sent", "to", tx.To
+	}
+
+	amount := fmt.Sprintf("%s %s", tx.TokenAmount(), tx.Symbol)
+	if !tx.IsToken() {
+		amount = wallet.LamportsToSOL(tx.Amount).String() + " SOL"
+		if ev.Value != nil {
+			amount += " (" + ev.Currency.Format(*ev.Value) + ")"
+		}
+	}
+
+	return fmt.Sprintf("%s %s %s %s, signature %s", direction, amount, preposition, counterparty, ev.Signature)
+}
+
+// signed shows a change with its sign, such as +0.5 or -1.
+func signed(change decimal.Decimal) string {
+	if change.IsPositive() {
+		return "+" + change.String()
+	}
+	return change.String()
+}
+
+// signedFiat shows a change in currency with its sign in front of the symbol, such as -€3.20.
+func signedFiat(currency wallet.Currency, change decimal.Decimal) string {
+	if change.IsNegative() {
+		return "-" + currency.Format(change.Neg())
+	}
+	return "+" + currency.Format(change)
+}
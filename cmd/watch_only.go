@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var addWatchCmd = &cobra.Command{
+	Use:         "add-watch [address]",
+	Short:       "Tracks an address without storing its private key",
+	Annotations: usesFlags(keyAccess(keyAccessPrivate), usesNewAlias),
+	Long: `Stores an address, such as a hardware wallet's, under --alias without its private key.
+balance, address and transactions work for it like for any other wallet; send refuses to
+sign from it. It only becomes the active wallet when there is no other.`,
+	Args: cobra.ExactArgs(1),
+	RunE: addWatchOnlyWallet,
+}
+
+func addWatchOnlyWallet(_ *cobra.Command, args []string) error {
+	wc, err := newWalletConfig()
+	if err != nil {
+		return err
+	}
+
+	alias, err := wc.AddWatchOnlyWallet(aliasFlag, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to add watch-only wallet: %w", err)
+	}
+
+	printBlue("Watching %s as %s.\n", args[0], alias)
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+func TestFormatTransferEvent(t *testing.T) {
+	owner := solana.MustPublicKeyFromBase58("9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM")
+	other := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	sig := solana.Signature{1}
+	value := func(s string) *decimal.Decimal {
+		d := decimal.RequireFromString(s)
+		return &d
+	}
+
+	tests := []struct {
+		name string
+		ev   wallet.TransferEvent
+		want string
+	}{
+		{
+			name: "balance increase",
+			ev:   wallet.TransferEvent{Kind: wallet.TransferEventBalance, Lamports: 2_000_000_000, Change: 500_000_000, Value: value("71.25"), Currency: "EUR"},
+			want: "Balance 2 SOL (+0.5 SOL, +€71.25)",
+		},
+		{
+			name: "balance decrease without rate",
+			ev:   wallet.TransferEvent{Kind: wallet.TransferEventBalance, Lamports: 1_000_000_000, Change: -1_000_000_000, Currency: "EUR"},
+			want: "Balance 1 SOL (-1 SOL)",
+		},
+		{
+			name: "received SOL",
+			ev: wallet.TransferEvent{
+				Kind:        wallet.TransferEventTransfer,
+				Signature:   sig,
+				Transaction: &wallet.Transaction{Amount: 250_000_000, From: other, To: owner},
+				Value:       value("35.6"),
+				Currency:    "USD",
+			},
+			want: "Received 0.25 SOL ($35.60) from " + other.String() + ", signature " + sig.String(),
+		},
+		{
+			name: "sent token",
+			ev: wallet.TransferEvent{
+				Kind:        wallet.TransferEventTransfer,
+				Signature:   sig,
+				Transaction: &wallet.Transaction{Amount: 12_500_000, From: owner, To: other, IsSender: true, Mint: other, Symbol: "USDC", Decimals: 6},
+			},
+			want: "Sent 12.5 USDC to " + other.String() + ", signature " + sig.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatTransferEvent(tt.ev))
+		})
+	}
+}
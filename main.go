@@ -1,12 +1,14 @@
 package main
 
 import (
+	"os"
+
 	"github.com/Ghvstcode/sleeng/cmd"
-	"log"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
-		log.Fatal(err)
+		// Cobra has already printed the error, so only the exit code is left to set.
+		os.Exit(cmd.ExitCode(err))
 	}
 }
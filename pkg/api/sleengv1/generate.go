@@ -0,0 +1,5 @@
+// Package sleengv1 holds the generated gRPC client and server code for the wallet daemon API
+// defined in proto/sleeng/v1/wallet.proto.
+package sleengv1
+
+//go:generate protoc -I ../../../proto --go_out=../../.. --go_opt=module=github.com/Ghvstcode/sleeng --go-grpc_out=../../.. --go-grpc_opt=module=github.com/Ghvstcode/sleeng sleeng/v1/wallet.proto
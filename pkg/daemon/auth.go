@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Ghvstcode/sleeng/pkg/api/sleengv1"
+)
+
+// authorizationHeader carries the bearer token on every call.
+const authorizationHeader = "authorization"
+
+var ErrNoAuth = errors.New("the daemon needs a token or client certificates to authenticate callers")
+
+// Options configures how the daemon authenticates its callers. At least one of a token or
+// required client certificates must be set, since the daemon can spend from the wallet.
+type Options struct {
+	// Token, when set, must be sent by every caller as "authorization: Bearer <token>".
+	Token string
+	// TLS, when set, serves over TLS. Set ClientAuth to tls.RequireAndVerifyClientCert for mTLS.
+	TLS *tls.Config
+}
+
+// requiresClientCerts reports whether callers are authenticated by their TLS certificate.
+func (o Options) requiresClientCerts() bool {
+	return o.TLS != nil && o.TLS.ClientAuth == tls.RequireAndVerifyClientCert
+}
+
+// NewGRPCServer returns a gRPC server serving srv that rejects unauthenticated callers.
+func NewGRPCServer(srv *Server, opts Options) (*grpc.Server, error) {
+	if opts.Token == "" && !opts.requiresClientCerts() {
+		return nil, ErrNoAuth
+	}
+
+	var serverOpts []grpc.ServerOption
+	if opts.TLS != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(opts.TLS)))
+	}
+	if opts.Token != "" {
+		serverOpts = append(serverOpts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				if err := checkToken(ctx, opts.Token); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := checkToken(ss.Context(), opts.Token); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+
+	server := grpc.NewServer(serverOpts...)
+	sleengv1.RegisterWalletServiceServer(server, srv)
+	return server, nil
+}
+
+// checkToken compares the caller's bearer token with the expected one in constant time.
+func checkToken(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(authorizationHeader) {
+		if validBearer(value, token) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid token")
+}
+
+// TokenCredentials sends a bearer token with every call made by a sleengv1.WalletServiceClient.
+// Pass it to grpc.Dial with grpc.WithPerRPCCredentials.
+type TokenCredentials struct {
+	Token string
+	// AllowInsecure permits sending the token without TLS, e.g. to a daemon on localhost.
+	AllowInsecure bool
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (c TokenCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	return map[string]string{authorizationHeader: "Bearer " + c.Token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.
+func (c TokenCredentials) RequireTransportSecurity() bool {
+	return !c.AllowInsecure
+}
+
+// LoadTLSConfig reads a server certificate and key. When clientCAFile is set, callers must present
+// a certificate signed by one of its CAs.
+func LoadTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
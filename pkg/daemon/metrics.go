@@ -0,0 +1,283 @@
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/status"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// MetricsPath is where the metrics endpoint is served.
+const MetricsPath = "/metrics"
+
+// metricsNamespace prefixes the name of every metric the daemon exports.
+const metricsNamespace = "sleeng"
+
+var ErrMetricsExposed = errors.New("the metrics endpoint needs the daemon token, client certificates or a loopback address")
+
+// The inventory is exported from the last refresh on every scrape, so its metrics are described
+// once here rather than kept in gauges that a refresh would have to reset.
+var (
+	solBalanceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "sol_balance"),
+		"SOL balance of a stored wallet.",
+		[]string{"alias", "network"}, nil,
+	)
+	tokenBalanceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "token_balance"),
+		"Balance of an SPL token held by a stored wallet, in whole tokens.",
+		[]string{"alias", "mint", "symbol"}, nil,
+	)
+	pendingOutgoingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "pending_outgoing_lamports"),
+		"Lamports sent from a stored wallet that have not landed yet.",
+		[]string{"alias"}, nil,
+	)
+	lastRefreshDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "inventory", "last_refresh_timestamp_seconds"),
+		"Unix time of the last successful refresh of the wallet inventory.",
+		nil, nil,
+	)
+)
+
+// walletInventory is what a refresh learned about one stored wallet.
+type walletInventory struct {
+	alias    string
+	lamports uint64
+	// tokens holds one balance per mint, summed over the wallet's token accounts.
+	tokens  []wallet.TokenBalance
+	pending uint64
+}
+
+// Metrics exports the public inventory of the stored wallets, their SOL and token balances and the
+// lamports of sends that have not landed, along with counters of the daemon's sends. Run reads the
+// inventory from the network every Interval and scrapes are answered from the last refresh, so a
+// busy scraper never adds RPC calls. A failed refresh keeps the previous inventory;
+// sleeng_inventory_last_refresh_timestamp_seconds tells how old it is.
+//
+// Labels carry aliases, mints, token symbols and the network name only. Addresses, keys and the
+// RPC URL, which may hold an API key, are never exported.
+type Metrics struct {
+	Interval time.Duration
+	// OnError is called with every failed refresh.
+	OnError func(err error)
+
+	wallet   Wallet
+	clock    Clock
+	registry *prometheus.Registry
+
+	sends         prometheus.Counter
+	sendErrors    *prometheus.CounterVec
+	refreshErrors prometheus.Counter
+
+	mu          sync.Mutex
+	network     string
+	inventory   []walletInventory
+	refreshedAt time.Time
+}
+
+// NewMetrics returns metrics for the wallets of w, refreshed every interval once Run is called.
+func NewMetrics(w Wallet, interval time.Duration) *Metrics {
+	m := &Metrics{
+		Interval: interval,
+		wallet:   w,
+		clock:    realClock{},
+		registry: prometheus.NewRegistry(),
+		sends: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "sends_total",
+			Help:      "Transfers sent through the daemon.",
+		}),
+		sendErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "send_errors_total",
+			Help:      "Send requests the daemon refused or failed, by gRPC status code.",
+		}, []string{"code"}),
+		refreshErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "inventory_refresh_errors_total",
+			Help:      "Refreshes of the wallet inventory that failed.",
+		}),
+	}
+	m.registry.MustRegister(m, m.sends, m.sendErrors, m.refreshErrors)
+	return m
+}
+
+// Registry returns the registry the metrics are gathered from.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Run refreshes the inventory until ctx is done. It refreshes once straight away.
+func (m *Metrics) Run(ctx context.Context) {
+	for {
+		if err := m.Refresh(ctx); err != nil && ctx.Err() == nil && m.OnError != nil {
+			m.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.clock.After(m.Interval):
+		}
+	}
+}
+
+// Refresh reads the balances of every stored wallet and the sends that are still pending.
+func (m *Metrics) Refresh(ctx context.Context) error {
+	inventory, err := m.readInventory(ctx)
+	if err != nil {
+		m.refreshErrors.Inc()
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.network = string(m.wallet.SelectedNetwork())
+	m.inventory = inventory
+	m.refreshedAt = m.clock.Now()
+	return nil
+}
+
+func (m *Metrics) readInventory(ctx context.Context) ([]walletInventory, error) {
+	infos, err := m.wallet.ListWallets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %w", err)
+	}
+
+	pending, err := m.wallet.ListPending()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending transactions: %w", err)
+	}
+	// Pending sends are recorded with the network they went to; only those of this one count.
+	network := m.wallet.NetworkName()
+	outgoing := make(map[string]uint64)
+	for _, p := range pending {
+		if p.Status == wallet.PendingSubmitted && p.Network == network {
+			outgoing[p.From] += p.Lamports
+		}
+	}
+
+	inventory := make([]walletInventory, 0, len(infos))
+	for _, info := range infos {
+		lamports, err := m.wallet.GetLamportBalance(ctx, info.Alias)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the balance of %s: %w", info.Alias, err)
+		}
+
+		balances, err := m.wallet.GetTokenBalances(info.Alias)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the token balances of %s: %w", info.Alias, err)
+		}
+
+		inventory = append(inventory, walletInventory{
+			alias:    info.Alias,
+			lamports: lamports,
+			tokens:   sumByMint(balances),
+			pending:  outgoing[info.PublicKey],
+		})
+	}
+	return inventory, nil
+}
+
+// sumByMint adds up the accounts of a wallet that hold the same mint, which would otherwise be
+// exported as duplicate series.
+func sumByMint(balances []wallet.TokenBalance) []wallet.TokenBalance {
+	var summed []wallet.TokenBalance
+	index := make(map[string]int, len(balances))
+	for _, b := range balances {
+		if i, ok := index[b.Mint]; ok {
+			summed[i].RawAmount += b.RawAmount
+			continue
+		}
+		if b.Symbol == "" {
+			b.Symbol = wallet.TokenSymbol(b.Mint)
+		}
+		b.Account = ""
+		index[b.Mint] = len(summed)
+		summed = append(summed, b)
+	}
+	return summed
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- solBalanceDesc
+	ch <- tokenBalanceDesc
+	ch <- pendingOutgoingDesc
+	ch <- lastRefreshDesc
+}
+
+// Collect implements prometheus.Collector with the inventory of the last refresh.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.refreshedAt.IsZero() {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(lastRefreshDesc, prometheus.GaugeValue, float64(m.refreshedAt.Unix()))
+	for _, w := range m.inventory {
+		ch <- prometheus.MustNewConstMetric(solBalanceDesc, prometheus.GaugeValue, wallet.LamportsToSOL(w.lamports).InexactFloat64(), w.alias, m.network)
+		ch <- prometheus.MustNewConstMetric(pendingOutgoingDesc, prometheus.GaugeValue, float64(w.pending), w.alias)
+		for _, t := range w.tokens {
+			ch <- prometheus.MustNewConstMetric(tokenBalanceDesc, prometheus.GaugeValue, t.Amount().InexactFloat64(), w.alias, t.Mint, t.Symbol)
+		}
+	}
+}
+
+// observeSend counts a send request by its outcome. Nil metrics count nothing.
+func (m *Metrics) observeSend(err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.sendErrors.WithLabelValues(status.Code(err).String()).Inc()
+		return
+	}
+	m.sends.Inc()
+}
+
+// Handler serves the metrics at MetricsPath. When opts has a token, callers must send it as
+// "Authorization: Bearer <token>", like callers of the gRPC API. Without one the endpoint is only
+// served when TLS client certificates authenticate the callers, or on a loopback addr.
+func (m *Metrics) Handler(opts Options, addr net.Addr) (http.Handler, error) {
+	if opts.Token == "" && !opts.requiresClientCerts() && !IsLoopback(addr) {
+		return nil, ErrMetricsExposed
+	}
+
+	metrics := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.Token != "" && !validBearer(r.Header.Get(authorizationHeader), opts.Token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+		metrics.ServeHTTP(w, r)
+	}))
+	return mux, nil
+}
+
+// validBearer reports whether an authorization value carries token, comparing in constant time.
+func validBearer(value, token string) bool {
+	given, ok := strings.CutPrefix(value, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// IsLoopback reports whether addr only accepts connections from this machine.
+func IsLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
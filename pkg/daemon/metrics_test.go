@@ -0,0 +1,223 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/client_golang/prometheus/testutil/promlint"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Ghvstcode/sleeng/pkg/api/sleengv1"
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+const (
+	usdcMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	// testRPCURL carries an API key the way hosted RPC providers hand them out.
+	testRPCURL = "https://rpc.example.com/?api-key=hunter2"
+)
+
+// inventoryWallet serves the stored wallets, balances and pending sends the metrics read.
+type inventoryWallet struct {
+	wallets  []wallet.WalletInfo
+	lamports map[string]uint64
+	tokens   map[string][]wallet.TokenBalance
+	pending  []wallet.PendingTransaction
+	err      error
+	Wallet
+}
+
+func (w *inventoryWallet) ListWallets() ([]wallet.WalletInfo, error) {
+	return w.wallets, nil
+}
+
+func (w *inventoryWallet) GetLamportBalance(_ context.Context, alias string) (uint64, error) {
+	return w.lamports[alias], w.err
+}
+
+func (w *inventoryWallet) GetTokenBalances(alias string) ([]wallet.TokenBalance, error) {
+	return w.tokens[alias], nil
+}
+
+func (w *inventoryWallet) ListPending() ([]wallet.PendingTransaction, error) {
+	return w.pending, nil
+}
+
+func (w *inventoryWallet) SelectedNetwork() wallet.Network {
+	return wallet.MainnetBeta
+}
+
+func (w *inventoryWallet) NetworkName() string {
+	return string(wallet.MainnetBeta) + " (" + testRPCURL + ")"
+}
+
+func newInventoryWallet() *inventoryWallet {
+	network := string(wallet.MainnetBeta) + " (" + testRPCURL + ")"
+	return &inventoryWallet{
+		wallets: []wallet.WalletInfo{
+			{Alias: "main", PublicKey: "Addr1", Active: true},
+			{Alias: "savings", PublicKey: "Addr2", WatchOnly: true},
+		},
+		lamports: map[string]uint64{"main": 1_500_000_000, "savings": 20 * wallet.LamportsInOneSol},
+		tokens: map[string][]wallet.TokenBalance{
+			"main": {
+				{Mint: usdcMint, Symbol: "USDC", Account: "TokenAcct1", RawAmount: 1_250_000, Decimals: 6},
+				{Mint: usdcMint, Symbol: "USDC", Account: "TokenAcct2", RawAmount: 750_000, Decimals: 6},
+			},
+		},
+		pending: []wallet.PendingTransaction{
+			{From: "Addr1", To: "Addr9", Lamports: 100_000_000, Network: network, Status: wallet.PendingSubmitted},
+			{From: "Addr1", To: "Addr9", Lamports: 5, Network: network, Status: wallet.PendingConfirmed},
+			{From: "Addr1", To: "Addr9", Lamports: 7, Network: string(wallet.Devnet), Status: wallet.PendingSubmitted},
+		},
+	}
+}
+
+func TestMetricsRegistry(t *testing.T) {
+	w := newInventoryWallet()
+	metrics := NewMetrics(w, 0)
+	metrics.clock = newFakeClock()
+
+	// Nothing is exported before the first refresh.
+	count, err := testutil.GatherAndCount(metrics.Registry(), "sleeng_sol_balance")
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+
+	assert.NoError(t, metrics.Refresh(context.Background()))
+	expected := `
+# HELP sleeng_inventory_last_refresh_timestamp_seconds Unix time of the last successful refresh of the wallet inventory.
+# TYPE sleeng_inventory_last_refresh_timestamp_seconds gauge
+sleeng_inventory_last_refresh_timestamp_seconds 1.7e+09
+# HELP sleeng_pending_outgoing_lamports Lamports sent from a stored wallet that have not landed yet.
+# TYPE sleeng_pending_outgoing_lamports gauge
+sleeng_pending_outgoing_lamports{alias="main"} 1e+08
+sleeng_pending_outgoing_lamports{alias="savings"} 0
+# HELP sleeng_sol_balance SOL balance of a stored wallet.
+# TYPE sleeng_sol_balance gauge
+sleeng_sol_balance{alias="main",network="mainnet-beta"} 1.5
+sleeng_sol_balance{alias="savings",network="mainnet-beta"} 20
+# HELP sleeng_token_balance Balance of an SPL token held by a stored wallet, in whole tokens.
+# TYPE sleeng_token_balance gauge
+sleeng_token_balance{alias="main",mint="EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",symbol="USDC"} 2
+`
+	inventory := []string{"sleeng_inventory_last_refresh_timestamp_seconds", "sleeng_pending_outgoing_lamports", "sleeng_sol_balance", "sleeng_token_balance"}
+	assert.NoError(t, testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), inventory...))
+
+	// A failed refresh is counted and keeps the last inventory.
+	w.err = errors.New("rpc down")
+	w.lamports["main"] = 0
+	assert.Error(t, metrics.Refresh(context.Background()))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.refreshErrors))
+	assert.NoError(t, testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), inventory...))
+}
+
+func TestMetricsCountSends(t *testing.T) {
+	metrics := NewMetrics(&fakeWallet{}, 0)
+	server := NewServer(&fakeWallet{policy: wallet.SpendPolicy{MaxLamports: wallet.LamportsInOneSol}})
+	server.RecordMetrics(metrics)
+	client := dialServer(t, server, &TokenCredentials{Token: testToken, AllowInsecure: true})
+
+	recipient := "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"
+	for _, amount := range []string{"0.5", "0.25", "2", "-1"} {
+		_, _ = client.Send(context.Background(), &sleengv1.SendRequest{Amount: amount, Unit: "sol", Recipient: recipient})
+	}
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.sends))
+	expected := `
+# HELP sleeng_send_errors_total Send requests the daemon refused or failed, by gRPC status code.
+# TYPE sleeng_send_errors_total counter
+sleeng_send_errors_total{code="InvalidArgument"} 1
+sleeng_send_errors_total{code="PermissionDenied"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "sleeng_send_errors_total"))
+}
+
+func TestMetricsNaming(t *testing.T) {
+	metrics := NewMetrics(newInventoryWallet(), 0)
+	metrics.sendErrors.WithLabelValues("Internal").Inc()
+	assert.NoError(t, metrics.Refresh(context.Background()))
+
+	families, err := metrics.Registry().Gather()
+	assert.NoError(t, err)
+	assert.Len(t, families, 7)
+
+	problems, err := promlint.NewWithMetricFamilies(families).Lint()
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+	for _, family := range families {
+		assert.True(t, strings.HasPrefix(family.GetName(), metricsNamespace+"_"), family.GetName())
+	}
+}
+
+func TestMetricsLabelHygiene(t *testing.T) {
+	w := newInventoryWallet()
+	metrics := NewMetrics(w, 0)
+	metrics.sendErrors.WithLabelValues("Internal").Inc()
+	assert.NoError(t, metrics.Refresh(context.Background()))
+
+	families, err := metrics.Registry().Gather()
+	assert.NoError(t, err)
+
+	allowed := map[string]bool{"alias": true, "network": true, "mint": true, "symbol": true, "code": true}
+	// Addresses identify the owner and the RPC URL may carry an API key; neither belongs in a label.
+	secrets := []string{"Addr1", "Addr2", "TokenAcct1", "TokenAcct2", testRPCURL, "hunter2"}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				assert.True(t, allowed[label.GetName()], "%s has label %s", family.GetName(), label.GetName())
+				for _, secret := range secrets {
+					assert.NotContains(t, label.GetValue(), secret, family.GetName())
+				}
+			}
+		}
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	metrics := NewMetrics(newInventoryWallet(), 0)
+	assert.NoError(t, metrics.Refresh(context.Background()))
+	loopback := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9100}
+	public := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 10), Port: 9100}
+
+	_, err := metrics.Handler(Options{}, public)
+	assert.ErrorIs(t, err, ErrMetricsExposed)
+
+	tests := []struct {
+		name       string
+		opts       Options
+		header     string
+		wantStatus int
+	}{
+		{name: "no token", opts: Options{Token: testToken}, wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", opts: Options{Token: testToken}, header: "Bearer guess", wantStatus: http.StatusUnauthorized},
+		{name: "valid token", opts: Options{Token: testToken}, header: "Bearer " + testToken, wantStatus: http.StatusOK},
+		{name: "loopback without a token", opts: Options{}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := metrics.Handler(tt.opts, loopback)
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, MetricsPath, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Contains(t, rec.Body.String(), `sleeng_sol_balance{alias="main",network="mainnet-beta"} 1.5`)
+			} else {
+				assert.NotContains(t, rec.Body.String(), "sleeng_sol_balance")
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// maxRateBackoff bounds how long the refresher waits after repeated provider failures.
+const maxRateBackoff = 15 * time.Minute
+
+// Clock tells the time and waits. The refresher takes one so tests can step through refreshes
+// without sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RateChange is a move of the SOL rate by more than the refresher's threshold.
+type RateChange struct {
+	Currency wallet.Currency
+	Rate     decimal.Decimal
+	// Previous is the rate of the last published change, or the first rate fetched.
+	Previous decimal.Decimal
+	Time     time.Time
+}
+
+// Percent is the signed change from Previous to Rate in percent.
+func (c RateChange) Percent() decimal.Decimal {
+	return c.Rate.Sub(c.Previous).Div(c.Previous).Mul(decimal.NewFromInt(100))
+}
+
+// RateRefresher keeps the wallet's exchange rate warm by refreshing it every Interval, and
+// publishes a RateChange to its subscribers whenever the rate has moved by at least Threshold
+// percent since the last one. After a failed refresh it waits twice as long as before, up to
+// maxRateBackoff, so an unavailable provider is not hammered.
+type RateRefresher struct {
+	Interval time.Duration
+	// Threshold is the change in percent that is published; zero publishes every change.
+	Threshold decimal.Decimal
+	// OnError is called with every failed refresh and the delay before the next attempt.
+	OnError func(err error, retryIn time.Duration)
+
+	wallet Wallet
+	clock  Clock
+
+	mu          sync.Mutex
+	baseline    decimal.Decimal
+	subscribers map[chan RateChange]struct{}
+}
+
+// NewRateRefresher refreshes the rate of w every interval.
+func NewRateRefresher(w Wallet, interval time.Duration, threshold decimal.Decimal) *RateRefresher {
+	return &RateRefresher{
+		Interval:    interval,
+		Threshold:   threshold,
+		wallet:      w,
+		clock:       realClock{},
+		subscribers: make(map[chan RateChange]struct{}),
+	}
+}
+
+// Run refreshes the rate until ctx is done. It refreshes once straight away.
+func (r *RateRefresher) Run(ctx context.Context) {
+	failures := 0
+	for {
+		delay := r.Interval
+		rate, err := r.wallet.RefreshSOLRate(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			failures++
+			delay = r.backoff(failures)
+			if r.OnError != nil {
+				r.OnError(err, delay)
+			}
+		default:
+			failures = 0
+			r.observe(rate)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.clock.After(delay):
+		}
+	}
+}
+
+// backoff returns the delay after the given number of consecutive failures.
+func (r *RateRefresher) backoff(failures int) time.Duration {
+	delay := r.Interval
+	for i := 0; i < failures && delay < maxRateBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRateBackoff && r.Interval < maxRateBackoff {
+		delay = maxRateBackoff
+	}
+	return delay
+}
+
+// observe publishes rate when it moved far enough from the baseline. The first rate only sets
+// the baseline.
+func (r *RateRefresher) observe(rate decimal.Decimal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.baseline.IsZero() {
+		r.baseline = rate
+		return
+	}
+
+	change := RateChange{Currency: r.wallet.FiatCurrency(), Rate: rate, Previous: r.baseline, Time: r.clock.Now()}
+	if rate.Equal(r.baseline) || change.Percent().Abs().LessThan(r.Threshold) {
+		return
+	}
+	r.baseline = rate
+
+	for ch := range r.subscribers {
+		// A subscriber that has not taken the previous change yet only gets the latest one, so a
+		// slow client never holds up the refresher.
+		select {
+		case ch <- change:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- change
+		}
+	}
+}
+
+// Subscribe returns a channel of rate changes and a function that stops them.
+func (r *RateRefresher) Subscribe() (<-chan RateChange, func()) {
+	ch := make(chan RateChange, 1)
+
+	r.mu.Lock()
+	r.subscribers[ch] = struct{}{}
+	r.mu.Unlock()
+
+	return ch, func() {
+		r.mu.Lock()
+		delete(r.subscribers, ch)
+		r.mu.Unlock()
+	}
+}
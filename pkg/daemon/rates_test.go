@@ -0,0 +1,238 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Ghvstcode/sleeng/pkg/api/sleengv1"
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// fakeClock hands every wait of the refresher to the test, which lets the time pass with fire.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits chan time.Duration
+	fired chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1_700_000_000, 0), waits: make(chan time.Duration, 1), fired: make(chan time.Time)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits <- d
+	return c.fired
+}
+
+// wait returns the delay the refresher waits for next, once it has finished the refresh before.
+func (c *fakeClock) wait(t *testing.T) time.Duration {
+	t.Helper()
+	select {
+	case d := <-c.waits:
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("the refresher did not wait")
+		return 0
+	}
+}
+
+// fire ends the current wait after d.
+func (c *fakeClock) fire(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	c.mu.Unlock()
+	c.fired <- now
+}
+
+func rate(value string) rateResult {
+	return rateResult{rate: decimal.RequireFromString(value)}
+}
+
+// runRefresher runs r until the test ends and reports when it has stopped.
+func runRefresher(t *testing.T, r *RateRefresher) (context.CancelFunc, <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		r.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+	return cancel, stopped
+}
+
+func TestRateRefresherBacksOff(t *testing.T) {
+	unavailable := rateResult{err: errors.New("kraken responded with 503 Service Unavailable")}
+	w := &fakeWallet{rates: []rateResult{rate("100"), unavailable, unavailable, unavailable, rate("100")}}
+
+	clock := newFakeClock()
+	refresher := NewRateRefresher(w, time.Minute, decimal.NewFromInt(1))
+	refresher.clock = clock
+	var failures []time.Duration
+	refresher.OnError = func(_ error, retryIn time.Duration) { failures = append(failures, retryIn) }
+	runRefresher(t, refresher)
+
+	var waits []time.Duration
+	for i := 0; i < 5; i++ {
+		d := clock.wait(t)
+		waits = append(waits, d)
+		if i < 4 {
+			clock.fire(d)
+		}
+	}
+
+	// A success resets the delay to the interval.
+	assert.Equal(t, []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, time.Minute}, waits)
+	assert.Equal(t, waits[1:4], failures)
+}
+
+func TestRateRefresherBackoffIsBounded(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		failures int
+		expected time.Duration
+	}{
+		{name: "Doubles", interval: time.Minute, failures: 3, expected: 8 * time.Minute},
+		{name: "Capped", interval: time.Minute, failures: 10, expected: maxRateBackoff},
+		{name: "Interval Above Cap", interval: time.Hour, failures: 2, expected: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refresher := NewRateRefresher(&fakeWallet{}, tt.interval, decimal.Zero)
+			assert.Equal(t, tt.expected, refresher.backoff(tt.failures))
+		})
+	}
+}
+
+func TestRateRefresherPublishesChangesAboveThreshold(t *testing.T) {
+	w := &fakeWallet{rates: []rateResult{rate("100"), rate("100.5"), rate("99.5"), rate("101"), rate("101.5"), rate("99.9")}}
+
+	clock := newFakeClock()
+	refresher := NewRateRefresher(w, time.Minute, decimal.NewFromInt(1))
+	refresher.clock = clock
+	changes, unsubscribe := refresher.Subscribe()
+	defer unsubscribe()
+	runRefresher(t, refresher)
+
+	var published []RateChange
+	for i := 0; i < 6; i++ {
+		d := clock.wait(t)
+		select {
+		case change := <-changes:
+			published = append(published, change)
+		default:
+		}
+		clock.fire(d)
+	}
+
+	// Changes are measured from the last published rate, so slow drifts are reported too.
+	if assert.Len(t, published, 2) {
+		assert.Equal(t, "100", published[0].Previous.String())
+		assert.Equal(t, "101", published[0].Rate.String())
+		assert.Equal(t, "1.00", published[0].Percent().StringFixed(2))
+		assert.Equal(t, wallet.DefaultCurrency, published[0].Currency)
+
+		assert.Equal(t, "101", published[1].Previous.String())
+		assert.Equal(t, "99.9", published[1].Rate.String())
+		assert.Equal(t, "-1.09", published[1].Percent().StringFixed(2))
+	}
+}
+
+func TestRateRefresherStopsOnShutdown(t *testing.T) {
+	clock := newFakeClock()
+	refresher := NewRateRefresher(&fakeWallet{rates: []rateResult{rate("100")}}, time.Minute, decimal.Zero)
+	refresher.clock = clock
+	stop, stopped := runRefresher(t, refresher)
+
+	clock.wait(t)
+	stop()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the refresher did not stop")
+	}
+}
+
+func TestGetRateHistory(t *testing.T) {
+	w := &fakeWallet{rateHistory: wallet.RateHistory{
+		Currency: "GBP",
+		Interval: 15 * time.Minute,
+		Candles: []wallet.Candle{
+			{Time: 900, Open: decimal.NewFromInt(100), High: decimal.NewFromInt(102), Low: decimal.NewFromInt(99), Close: decimal.NewFromInt(101)},
+		},
+	}}
+	client := dial(t, w, &TokenCredentials{Token: testToken, AllowInsecure: true})
+
+	resp, err := client.GetRateHistory(context.Background(), &sleengv1.GetRateHistoryRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "GBP", resp.GetCurrency())
+	assert.Equal(t, int64(900), resp.GetIntervalSeconds())
+	if assert.Len(t, resp.GetCandles(), 1) {
+		assert.Equal(t, int64(900), resp.GetCandles()[0].GetTimeUnix())
+		assert.Equal(t, "101", resp.GetCandles()[0].GetClose())
+	}
+
+	_, err = client.GetRateHistory(context.Background(), &sleengv1.GetRateHistoryRequest{Range: "forever"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWatchRateChanges(t *testing.T) {
+	address := solana.NewWallet().PublicKey()
+	w := &fakeWallet{
+		updates: []wallet.BalanceUpdate{{Address: address, Lamports: 10, Slot: 1}},
+		rates:   []rateResult{rate("100"), rate("100.5"), rate("102")},
+	}
+
+	clock := newFakeClock()
+	refresher := NewRateRefresher(w, time.Minute, decimal.NewFromInt(1))
+	refresher.clock = clock
+	server := NewServer(w)
+	server.PublishRateChanges(refresher)
+	client := dialServer(t, server, &TokenCredentials{Token: testToken, AllowInsecure: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.Watch(ctx, &sleengv1.WatchRequest{})
+	assert.NoError(t, err)
+
+	// The balance update arrives once the stream has subscribed to rate changes.
+	got, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), got.GetLamports())
+	assert.Nil(t, got.GetRate())
+
+	runRefresher(t, refresher)
+	clock.fire(clock.wait(t))
+	clock.fire(clock.wait(t))
+
+	got, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Empty(t, got.GetAddress())
+	assert.Equal(t, "EUR", got.GetRate().GetCurrency())
+	assert.Equal(t, "100", got.GetRate().GetPrevious())
+	assert.Equal(t, "102", got.GetRate().GetRate())
+	assert.Equal(t, "2.00", got.GetRate().GetChangePercent())
+	assert.Equal(t, clock.Now().Unix(), got.GetRate().GetTimeUnix())
+}
@@ -0,0 +1,304 @@
+// Package daemon serves a wallet over gRPC for `wallet daemon`, using the API in pkg/api/sleengv1.
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Ghvstcode/sleeng/pkg/api/sleengv1"
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+// Wallet is the part of *wallet.WalletConfig the daemon serves. Sends go through PrepareSend and
+// ExecuteSend, so the daemon applies the same validation, spend policy and audit log as the CLI.
+type Wallet interface {
+	GetWalletInfo(alias string) (wallet.WalletInfo, error)
+	ListWallets() ([]wallet.WalletInfo, error)
+	GetLamportBalance(ctx context.Context, alias string) (uint64, error)
+	FetchSOLRate() (decimal.Decimal, error)
+	RefreshSOLRate(ctx context.Context) (decimal.Decimal, error)
+	RateHistory(span time.Duration) (wallet.RateHistory, error)
+	FiatCurrency() wallet.Currency
+	PrepareSend(ctx context.Context, amount, recipient string, opts wallet.SendOptions) (*wallet.SendQuote, error)
+	ExecuteSend(ctx context.Context, quote *wallet.SendQuote, opts wallet.SendOptions) (*wallet.SendReceipt, error)
+	GetTransactionHistoryWithOpts(opts wallet.GetTransactionHistoryOpts) ([]*wallet.Transaction, error)
+	WatchBalance(ctx context.Context, alias string, fn func(wallet.BalanceUpdate) error) error
+	GetTokenBalances(alias string) ([]wallet.TokenBalance, error)
+	ListPending() ([]wallet.PendingTransaction, error)
+	SelectedNetwork() wallet.Network
+	NetworkName() string
+}
+
+// Server implements sleengv1.WalletServiceServer on top of a Wallet.
+type Server struct {
+	sleengv1.UnimplementedWalletServiceServer
+	wallet Wallet
+	// sendMu serialises sends so two requests never quote against the same balance.
+	sendMu sync.Mutex
+	// rates publishes rate changes to Watch streams; nil streams balances only.
+	rates *RateRefresher
+	// metrics counts sends; nil counts nothing.
+	metrics *Metrics
+}
+
+// NewServer returns a Server for w.
+func NewServer(w Wallet) *Server {
+	return &Server{wallet: w}
+}
+
+// PublishRateChanges makes Watch streams include the rate changes of r.
+func (s *Server) PublishRateChanges(r *RateRefresher) {
+	s.rates = r
+}
+
+// RecordMetrics makes Send count its sends and failures in m.
+func (s *Server) RecordMetrics(m *Metrics) {
+	s.metrics = m
+}
+
+// GetBalance returns the balance of a stored wallet, with its value in the selected currency when the rate is available.
+func (s *Server) GetBalance(ctx context.Context, req *sleengv1.GetBalanceRequest) (*sleengv1.GetBalanceResponse, error) {
+	info, err := s.wallet.GetWalletInfo(req.GetAlias())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	lamports, err := s.wallet.GetLamportBalance(ctx, info.Alias)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	currency := s.wallet.FiatCurrency()
+	resp := &sleengv1.GetBalanceResponse{Alias: info.Alias, Address: info.PublicKey, Lamports: lamports, Currency: currency.String()}
+	if rate, err := s.wallet.FetchSOLRate(); err == nil {
+		resp.Fiat = wallet.LamportsToSOL(lamports).Mul(rate).StringFixed(2)
+		if currency == wallet.DefaultCurrency {
+			// Older clients read eur, which was the only currency before it became configurable.
+			resp.Eur = resp.Fiat
+		}
+	}
+
+	return resp, nil
+}
+
+// Send transfers SOL from the active wallet.
+func (s *Server) Send(ctx context.Context, req *sleengv1.SendRequest) (resp *sleengv1.SendResponse, err error) {
+	defer func() { s.metrics.observeSend(err) }()
+
+	unit, err := wallet.ParseUnit(req.GetUnit())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	opts := wallet.SendOptions{NonceAccount: req.GetNonceAccount(), Unit: unit}
+	ctx = wallet.WithOrigin(ctx, wallet.Origin{Interface: wallet.InterfaceDaemon, Command: sleengv1.WalletService_Send_FullMethodName})
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	quote, err := s.wallet.PrepareSend(ctx, req.GetAmount(), req.GetRecipient(), opts)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	receipt, err := s.wallet.ExecuteSend(ctx, quote, opts)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &sleengv1.SendResponse{Signature: receipt.Signature, Lamports: receipt.Lamports, Fee: quote.Fee}, nil
+}
+
+// GetHistory returns the transaction history of the active wallet, newest first.
+// When only some transactions could be fetched the rest are returned with Partial set.
+func (s *Server) GetHistory(_ context.Context, req *sleengv1.GetHistoryRequest) (*sleengv1.GetHistoryResponse, error) {
+	opts := wallet.GetTransactionHistoryOpts{Limit: int(req.GetLimit())}
+	if opts.Limit == 0 {
+		opts.Limit = wallet.DefaultTransactionLimit
+	}
+	if req.GetSinceUnix() > 0 {
+		opts.Since = time.Unix(req.GetSinceUnix(), 0)
+	}
+
+	transactions, err := s.wallet.GetTransactionHistoryWithOpts(opts)
+	var partial *wallet.HistoryFetchError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, toStatus(err)
+	}
+
+	sort.Slice(transactions, func(i, j int) bool {
+		return transactions[i].Timestamp.After(transactions[j].Timestamp)
+	})
+
+	resp := &sleengv1.GetHistoryResponse{Partial: partial != nil}
+	for _, tx := range transactions {
+		pb := &sleengv1.Transaction{
+			From:          tx.From.String(),
+			To:            tx.To.String(),
+			Amount:        tx.Amount,
+			IsSender:      tx.IsSender,
+			TimestampUnix: tx.Timestamp.Unix(),
+		}
+		if tx.IsToken() {
+			pb.Mint = tx.Mint.String()
+			pb.Symbol = tx.Symbol
+			pb.Decimals = uint32(tx.Decimals)
+		}
+		resp.Transactions = append(resp.Transactions, pb)
+	}
+
+	return resp, nil
+}
+
+// ListKeys lists the stored wallets without their private keys.
+func (s *Server) ListKeys(_ context.Context, _ *sleengv1.ListKeysRequest) (*sleengv1.ListKeysResponse, error) {
+	infos, err := s.wallet.ListWallets()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &sleengv1.ListKeysResponse{}
+	for _, info := range infos {
+		resp.Keys = append(resp.Keys, &sleengv1.Key{
+			Alias:     info.Alias,
+			Address:   info.PublicKey,
+			Active:    info.Active,
+			Encrypted: info.Encrypted,
+			Note:      info.Note.Label(),
+		})
+	}
+
+	return resp, nil
+}
+
+// defaultRateRange is the history range used when a request leaves it empty.
+const defaultRateRange = "7d"
+
+// GetRateHistory returns the SOL price over the requested range from the candle cache.
+func (s *Server) GetRateHistory(_ context.Context, req *sleengv1.GetRateHistoryRequest) (*sleengv1.GetRateHistoryResponse, error) {
+	rateRange := req.GetRange()
+	if rateRange == "" {
+		rateRange = defaultRateRange
+	}
+	span, err := wallet.ParseRateRange(rateRange)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	history, err := s.wallet.RateHistory(span)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &sleengv1.GetRateHistoryResponse{
+		Currency:        history.Currency.String(),
+		IntervalSeconds: int64(history.Interval / time.Second),
+	}
+	for _, candle := range history.Candles {
+		resp.Candles = append(resp.Candles, &sleengv1.RateCandle{
+			TimeUnix: candle.Time,
+			Open:     candle.Open.String(),
+			High:     candle.High.String(),
+			Low:      candle.Low.String(),
+			Close:    candle.Close.String(),
+		})
+	}
+
+	return resp, nil
+}
+
+// Watch streams the balance of a wallet every time it changes until the client goes away. When
+// the server publishes rate changes they are interleaved with the balance updates.
+func (s *Server) Watch(req *sleengv1.WatchRequest, stream sleengv1.WalletService_WatchServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	// Balance updates and rate changes come from different goroutines; a stream takes one Send at a time.
+	var streamMu sync.Mutex
+	send := func(resp *sleengv1.WatchResponse) error {
+		streamMu.Lock()
+		defer streamMu.Unlock()
+		return stream.Send(resp)
+	}
+
+	if s.rates != nil {
+		changes, unsubscribe := s.rates.Subscribe()
+		defer unsubscribe()
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		// Runs before unsubscribe, so no change is sent once Watch has returned.
+		defer wg.Wait()
+		defer cancel()
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case change := <-changes:
+					if err := send(&sleengv1.WatchResponse{Rate: rateChangeToProto(change)}); err != nil {
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	err := s.wallet.WatchBalance(ctx, req.GetAlias(), func(update wallet.BalanceUpdate) error {
+		return send(&sleengv1.WatchResponse{
+			Address:  update.Address.String(),
+			Lamports: update.Lamports,
+			Slot:     update.Slot,
+		})
+	})
+	if stream.Context().Err() != nil {
+		return status.FromContextError(stream.Context().Err()).Err()
+	}
+	return toStatus(err)
+}
+
+// rateChangeToProto converts a RateChange for the wire.
+func rateChangeToProto(change RateChange) *sleengv1.RateChange {
+	return &sleengv1.RateChange{
+		Currency:      change.Currency.String(),
+		Rate:          change.Rate.String(),
+		Previous:      change.Previous.String(),
+		ChangePercent: change.Percent().StringFixed(2),
+		TimeUnix:      change.Time.Unix(),
+	}
+}
+
+// toStatus maps wallet errors to gRPC status codes so clients can tell a refusal from a failure.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var insufficient *wallet.InsufficientFundsError
+	switch {
+	case errors.Is(err, wallet.ErrSpendLimitExceeded), errors.Is(err, wallet.ErrRecipientNotAllowed):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.As(err, &insufficient):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, wallet.ErrInvalidAmount):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, wallet.ErrActiveWalletNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,304 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/Ghvstcode/sleeng/pkg/api/sleengv1"
+	"github.com/Ghvstcode/sleeng/pkg/wallet"
+)
+
+const testToken = "s3cret"
+
+// fakeWallet serves canned data. Its PrepareSend applies a real wallet.SpendPolicy the way
+// WalletConfig.PrepareSend does, so policy refusals travel the same path as in production.
+type fakeWallet struct {
+	policy   wallet.SpendPolicy
+	currency wallet.Currency
+	lamports uint64
+	history  []*wallet.Transaction
+	histErr  error
+	updates  []wallet.BalanceUpdate
+	executed []*wallet.SendQuote
+	// origins are the origins ExecuteSend was called with.
+	origins []wallet.Origin
+	// rates are the results of successive RefreshSOLRate calls.
+	rates       []rateResult
+	rateHistory wallet.RateHistory
+	Wallet
+}
+
+type rateResult struct {
+	rate decimal.Decimal
+	err  error
+}
+
+func (f *fakeWallet) GetWalletInfo(alias string) (wallet.WalletInfo, error) {
+	if alias == "" {
+		alias = "main"
+	}
+	if alias != "main" {
+		return wallet.WalletInfo{}, wallet.ErrActiveWalletNotFound
+	}
+	return wallet.WalletInfo{Alias: alias, PublicKey: "Addr1", Active: true}, nil
+}
+
+func (f *fakeWallet) ListWallets() ([]wallet.WalletInfo, error) {
+	return []wallet.WalletInfo{
+		{Alias: "main", PublicKey: "Addr1", Active: true},
+		{Alias: "savings", PublicKey: "Addr2", Encrypted: true, Note: wallet.WalletNote{Text: "cold", Icon: "🧊"}},
+	}, nil
+}
+
+func (f *fakeWallet) GetLamportBalance(context.Context, string) (uint64, error) {
+	return f.lamports, nil
+}
+
+func (f *fakeWallet) FetchSOLRate() (decimal.Decimal, error) {
+	return decimal.NewFromInt(100), nil
+}
+
+func (f *fakeWallet) RefreshSOLRate(context.Context) (decimal.Decimal, error) {
+	if len(f.rates) == 0 {
+		return decimal.Zero, errors.New("no more rates")
+	}
+	next := f.rates[0]
+	f.rates = f.rates[1:]
+	return next.rate, next.err
+}
+
+func (f *fakeWallet) RateHistory(time.Duration) (wallet.RateHistory, error) {
+	return f.rateHistory, nil
+}
+
+func (f *fakeWallet) FiatCurrency() wallet.Currency {
+	if f.currency == "" {
+		return wallet.DefaultCurrency
+	}
+	return f.currency
+}
+
+func (f *fakeWallet) PrepareSend(_ context.Context, amount, recipient string, opts wallet.SendOptions) (*wallet.SendQuote, error) {
+	to, err := solana.PublicKeyFromBase58(recipient)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Unit != wallet.UnitSOL {
+		return nil, errors.New("test only supports SOL")
+	}
+	lamports, err := wallet.ParseSOL(amount)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.policy.Check(lamports); err != nil {
+		return nil, err
+	}
+	return &wallet.SendQuote{To: to, Lamports: lamports, Fee: 5000}, nil
+}
+
+func (f *fakeWallet) ExecuteSend(ctx context.Context, quote *wallet.SendQuote, _ wallet.SendOptions) (*wallet.SendReceipt, error) {
+	f.executed = append(f.executed, quote)
+	f.origins = append(f.origins, wallet.OriginFrom(ctx))
+	return &wallet.SendReceipt{Signature: "sig", Lamports: quote.Lamports}, nil
+}
+
+func (f *fakeWallet) GetTransactionHistoryWithOpts(wallet.GetTransactionHistoryOpts) ([]*wallet.Transaction, error) {
+	return f.history, f.histErr
+}
+
+func (f *fakeWallet) WatchBalance(ctx context.Context, _ string, fn func(wallet.BalanceUpdate) error) error {
+	for _, update := range f.updates {
+		if err := fn(update); err != nil {
+			return err
+		}
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// dial serves w over an in-memory listener and returns a client sending creds.
+func dial(t *testing.T, w Wallet, creds *TokenCredentials) sleengv1.WalletServiceClient {
+	t.Helper()
+	return dialServer(t, NewServer(w), creds)
+}
+
+// dialServer serves s over an in-memory listener and returns a client sending creds.
+func dialServer(t *testing.T, s *Server, creds *TokenCredentials) sleengv1.WalletServiceClient {
+	t.Helper()
+
+	server, err := NewGRPCServer(s, Options{Token: testToken})
+	assert.NoError(t, err)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+	if creds != nil {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(*creds))
+	}
+
+	conn, err := grpc.Dial("bufnet", dialOpts...)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return sleengv1.NewWalletServiceClient(conn)
+}
+
+func TestNewGRPCServerRequiresAuth(t *testing.T) {
+	_, err := NewGRPCServer(NewServer(&fakeWallet{}), Options{})
+	assert.ErrorIs(t, err, ErrNoAuth)
+}
+
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name     string
+		creds    *TokenCredentials
+		wantCode codes.Code
+	}{
+		{name: "no token", creds: nil, wantCode: codes.Unauthenticated},
+		{name: "wrong token", creds: &TokenCredentials{Token: "guess", AllowInsecure: true}, wantCode: codes.Unauthenticated},
+		{name: "valid token", creds: &TokenCredentials{Token: testToken, AllowInsecure: true}, wantCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dial(t, &fakeWallet{}, tt.creds)
+
+			_, err := client.ListKeys(context.Background(), &sleengv1.ListKeysRequest{})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+
+			// Streams are guarded by the same token check.
+			stream, err := client.Watch(context.Background(), &sleengv1.WatchRequest{})
+			if err == nil && tt.wantCode != codes.OK {
+				_, err = stream.Recv()
+				assert.Equal(t, tt.wantCode, status.Code(err))
+			}
+		})
+	}
+}
+
+func TestSendSpendLimit(t *testing.T) {
+	recipient := solana.NewWallet().PublicKey().String()
+
+	tests := []struct {
+		name      string
+		amount    string
+		unit      string
+		wantCode  codes.Code
+		wantSends int
+	}{
+		{name: "within the limit", amount: "0.5", unit: "sol", wantCode: codes.OK, wantSends: 1},
+		{name: "at the limit", amount: "1", unit: "sol", wantCode: codes.OK, wantSends: 1},
+		{name: "over the limit", amount: "1.5", unit: "sol", wantCode: codes.PermissionDenied, wantSends: 0},
+		{name: "invalid amount", amount: "-1", unit: "sol", wantCode: codes.InvalidArgument, wantSends: 0},
+		{name: "unknown unit", amount: "1", unit: "btc", wantCode: codes.InvalidArgument, wantSends: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &fakeWallet{policy: wallet.SpendPolicy{MaxLamports: wallet.LamportsInOneSol}}
+			client := dial(t, w, &TokenCredentials{Token: testToken, AllowInsecure: true})
+
+			resp, err := client.Send(context.Background(), &sleengv1.SendRequest{Amount: tt.amount, Unit: tt.unit, Recipient: recipient})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Len(t, w.executed, tt.wantSends)
+			if tt.wantCode == codes.OK {
+				assert.Equal(t, "sig", resp.GetSignature())
+				assert.Equal(t, uint64(5000), resp.GetFee())
+				assert.Equal(t, []wallet.Origin{{Interface: wallet.InterfaceDaemon, Command: "/sleeng.v1.WalletService/Send"}}, w.origins)
+			}
+		})
+	}
+}
+
+func TestGetBalanceAndKeys(t *testing.T) {
+	client := dial(t, &fakeWallet{lamports: 2 * wallet.LamportsInOneSol}, &TokenCredentials{Token: testToken, AllowInsecure: true})
+
+	balance, err := client.GetBalance(context.Background(), &sleengv1.GetBalanceRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "main", balance.GetAlias())
+	assert.Equal(t, uint64(2*wallet.LamportsInOneSol), balance.GetLamports())
+	assert.Equal(t, "200.00", balance.GetFiat())
+	assert.Equal(t, "EUR", balance.GetCurrency())
+	assert.Equal(t, "200.00", balance.GetEur())
+
+	gbp := dial(t, &fakeWallet{lamports: 2 * wallet.LamportsInOneSol, currency: "GBP"}, &TokenCredentials{Token: testToken, AllowInsecure: true})
+	balance, err = gbp.GetBalance(context.Background(), &sleengv1.GetBalanceRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "200.00", balance.GetFiat())
+	assert.Equal(t, "GBP", balance.GetCurrency())
+	assert.Empty(t, balance.GetEur())
+
+	_, err = client.GetBalance(context.Background(), &sleengv1.GetBalanceRequest{Alias: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	keys, err := client.ListKeys(context.Background(), &sleengv1.ListKeysRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, keys.GetKeys(), 2)
+	assert.Equal(t, "🧊 cold", keys.GetKeys()[1].GetNote())
+	assert.True(t, keys.GetKeys()[1].GetEncrypted())
+}
+
+func TestGetHistoryPartial(t *testing.T) {
+	older := &wallet.Transaction{Amount: 1, Timestamp: time.Unix(100, 0)}
+	newer := &wallet.Transaction{Amount: 2, Timestamp: time.Unix(200, 0), IsSender: true}
+	w := &fakeWallet{
+		history: []*wallet.Transaction{older, newer},
+		histErr: &wallet.HistoryFetchError{Failed: 1, Total: 3, Err: errors.New("rate limited")},
+	}
+	client := dial(t, w, &TokenCredentials{Token: testToken, AllowInsecure: true})
+
+	resp, err := client.GetHistory(context.Background(), &sleengv1.GetHistoryRequest{})
+	assert.NoError(t, err)
+	assert.True(t, resp.GetPartial())
+	assert.Len(t, resp.GetTransactions(), 2)
+	assert.Equal(t, int64(200), resp.GetTransactions()[0].GetTimestampUnix())
+	assert.True(t, resp.GetTransactions()[0].GetIsSender())
+}
+
+func TestWatch(t *testing.T) {
+	address := solana.NewWallet().PublicKey()
+	w := &fakeWallet{updates: []wallet.BalanceUpdate{
+		{Address: address, Lamports: 10, Slot: 1},
+		{Address: address, Lamports: 20, Slot: 2},
+	}}
+	client := dial(t, w, &TokenCredentials{Token: testToken, AllowInsecure: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &sleengv1.WatchRequest{})
+	assert.NoError(t, err)
+
+	for _, want := range w.updates {
+		got, err := stream.Recv()
+		assert.NoError(t, err)
+		assert.Equal(t, address.String(), got.GetAddress())
+		assert.Equal(t, want.Lamports, got.GetLamports())
+		assert.Equal(t, want.Slot, got.GetSlot())
+	}
+
+	cancel()
+	_, err = stream.Recv()
+	assert.NotErrorIs(t, err, io.EOF)
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
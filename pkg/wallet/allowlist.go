@@ -0,0 +1,208 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	ErrRecipientNotAllowed  = errors.New("recipient is not on the allow-list")
+	ErrAllowlistUnprotected = errors.New("the allow-list can only be changed once the key file is encrypted; run `wallet keystore encrypt` first")
+	ErrNotAllowlisted       = errors.New("address is not on the allow-list")
+)
+
+// AllowedRecipient is an address sends may go to while the allow-list is enforced.
+type AllowedRecipient struct {
+	Address string `json:"address"`
+	// Contact is the contact name the address was added as, empty for a plain address. The address is
+	// kept as it was then, so editing the contact later does not change what is allowed.
+	Contact string `json:"contact,omitempty"`
+}
+
+func (r AllowedRecipient) String() string {
+	return Recipient{Address: r.Address, Contact: r.Contact}.String()
+}
+
+// Allowlist is the set of addresses sends are restricted to and whether the restriction is on.
+type Allowlist struct {
+	Enforced   bool               `json:"enforced"`
+	Recipients []AllowedRecipient `json:"recipients"`
+}
+
+// Allows reports whether address is on the list.
+func (a Allowlist) Allows(address string) bool {
+	for _, recipient := range a.Recipients {
+		if recipient.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyKeystorePassphrase checks passphrase against the encrypted keys in data, the active wallet's
+// first. Changes to the allow-list need it, so they take more than access to the command line.
+func (d WalletData) verifyKeystorePassphrase(passphrase string) error {
+	aliases := make([]string, 0, len(d.Wallets))
+	for alias, wallet := range d.Wallets {
+		if wallet.Encrypted {
+			aliases = append(aliases, alias)
+		}
+	}
+	if len(aliases) == 0 {
+		return ErrAllowlistUnprotected
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i] == d.ActiveAlias || (aliases[j] != d.ActiveAlias && aliases[i] < aliases[j])
+	})
+
+	wallet := d.Wallets[aliases[0]]
+	if _, err := decryptPrivateKey(wallet.PrivateKey, wallet.Salt, wallet.Nonce, passphrase); err != nil {
+		return fmt.Errorf("the keystore passphrase is needed to change the allow-list: %w", err)
+	}
+	return nil
+}
+
+// updateAllowlist applies change to the key file after checking the keystore passphrase.
+func (k *KeyOps) updateAllowlist(passphrase string, change func(data *WalletData) error) error {
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return err
+	}
+	if err := data.verifyKeystorePassphrase(passphrase); err != nil {
+		return err
+	}
+	if err := change(&data); err != nil {
+		return err
+	}
+
+	return k.writeWalletData("allowlist", data)
+}
+
+// AllowRecipient adds a recipient to the allow-list. passphrase must decrypt the keys in the key file.
+func (k *KeyOps) AllowRecipient(recipient Recipient, passphrase string) error {
+	if _, err := solana.PublicKeyFromBase58(recipient.Address); err != nil {
+		return fmt.Errorf("invalid address %q: %w", recipient.Address, err)
+	}
+
+	return k.updateAllowlist(passphrase, func(data *WalletData) error {
+		if data.Allowlist == nil {
+			data.Allowlist = make(map[string]string)
+		}
+		data.Allowlist[recipient.Address] = recipient.Contact
+		return nil
+	})
+}
+
+// DisallowRecipient removes an address, or the address added under a contact name, from the
+// allow-list and returns what was removed.
+func (k *KeyOps) DisallowRecipient(addressOrContact, passphrase string) (AllowedRecipient, error) {
+	var removed AllowedRecipient
+	err := k.updateAllowlist(passphrase, func(data *WalletData) error {
+		for address, contact := range data.Allowlist {
+			if address == addressOrContact || (contact != "" && contact == addressOrContact) {
+				removed = AllowedRecipient{Address: address, Contact: contact}
+				delete(data.Allowlist, address)
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrNotAllowlisted, addressOrContact)
+	})
+	return removed, err
+}
+
+// EnforceAllowlist switches enforcement of the allow-list on or off for every send.
+func (k *KeyOps) EnforceAllowlist(on bool, passphrase string) error {
+	return k.updateAllowlist(passphrase, func(data *WalletData) error {
+		data.EnforceAllowlist = on
+		return nil
+	})
+}
+
+// Allowlist returns the allow-list sorted by address. A missing key file has an empty one.
+func (k *KeyOps) Allowlist() (Allowlist, error) {
+	fileExists, err := k.IsKeyFilePresent()
+	if err != nil || !fileExists {
+		return Allowlist{}, err
+	}
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return Allowlist{}, err
+	}
+
+	list := Allowlist{Enforced: data.EnforceAllowlist, Recipients: make([]AllowedRecipient, 0, len(data.Allowlist))}
+	for address, contact := range data.Allowlist {
+		list.Recipients = append(list.Recipients, AllowedRecipient{Address: address, Contact: contact})
+	}
+	sort.Slice(list.Recipients, func(i, j int) bool { return list.Recipients[i].Address < list.Recipients[j].Address })
+	return list, nil
+}
+
+// AllowRecipient adds a recipient, given as a contact name or an address, to the allow-list. A
+// contact is added with the address it has now.
+func (w *WalletConfig) AllowRecipient(arg, passphrase string) (Recipient, error) {
+	keyOps, ok := w.KeyOps.(*KeyOps)
+	if !ok {
+		return Recipient{}, errors.New("the allow-list is kept in the key file")
+	}
+	recipient, err := w.ResolveRecipient(arg)
+	if err != nil {
+		return Recipient{}, err
+	}
+	return recipient, keyOps.AllowRecipient(recipient, passphrase)
+}
+
+// DisallowRecipient removes an address or contact name from the allow-list.
+func (w *WalletConfig) DisallowRecipient(arg, passphrase string) (AllowedRecipient, error) {
+	keyOps, ok := w.KeyOps.(*KeyOps)
+	if !ok {
+		return AllowedRecipient{}, errors.New("the allow-list is kept in the key file")
+	}
+	return keyOps.DisallowRecipient(arg, passphrase)
+}
+
+// SetAllowlistEnforced switches enforcement of the allow-list on or off in the key file.
+func (w *WalletConfig) SetAllowlistEnforced(on bool, passphrase string) error {
+	keyOps, ok := w.KeyOps.(*KeyOps)
+	if !ok {
+		return errors.New("the allow-list is kept in the key file")
+	}
+	return keyOps.EnforceAllowlist(on, passphrase)
+}
+
+// Allowlist returns the allow-list of the key file, enforced when either the key file or
+// w.EnforceAllowlist says so.
+func (w *WalletConfig) Allowlist() (Allowlist, error) {
+	var list Allowlist
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok {
+		var err error
+		if list, err = keyOps.Allowlist(); err != nil {
+			return Allowlist{}, fmt.Errorf("failed to read the allow-list: %w", err)
+		}
+	}
+	list.Enforced = list.Enforced || w.EnforceAllowlist
+	return list, nil
+}
+
+// checkRecipientAllowed returns an error wrapping ErrRecipientNotAllowed when the allow-list is
+// enforced and to is not on it. Every send path calls it before signing, so neither a confirmation
+// flag nor the daemon can get around it.
+func (w *WalletConfig) checkRecipientAllowed(to solana.PublicKey) error {
+	list, err := w.Allowlist()
+	if err != nil {
+		return err
+	}
+	if list.Enforced && !list.Allows(to.String()) {
+		return fmt.Errorf("%w: %s", ErrRecipientNotAllowed, to)
+	}
+	return nil
+}
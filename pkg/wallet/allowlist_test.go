@@ -0,0 +1,193 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const allowlistPassphrase = "pw"
+
+// newAllowlistWallet stores an encrypted wallet "main" holding balance lamports on a quickstartCluster.
+func newAllowlistWallet(t *testing.T, balance uint64) (*WalletConfig, *quickstartCluster, solana.PrivateKey) {
+	previous := newRPCClient
+	t.Cleanup(func() { newRPCClient = previous })
+
+	t.Helper()
+	files := newMemFiles()
+	keyOps := &KeyOps{FileReader: files, FileWriter: files, Passphrase: func(string, bool) (string, error) { return allowlistPassphrase, nil }}
+	main := solana.NewWallet()
+	assert.NoError(t, keyOps.WriteKeyToFile("main", []byte(main.PrivateKey), main.PublicKey().String()))
+
+	cluster := &quickstartCluster{
+		balances:  map[solana.PublicKey]uint64{main.PublicKey(): balance},
+		transfers: make(map[solana.Signature][2]solana.PublicKey),
+	}
+	newRPCClient = func(string) ClientInterface { return cluster.client(t) }
+
+	wc := &WalletConfig{
+		KeyOps:        keyOps,
+		Network:       Devnet,
+		Pending:       &PendingStore{FileReader: files, FileWriter: files},
+		FailsafeStore: &FailsafeStore{FileReader: files, FileWriter: files},
+	}
+	return wc, cluster, main.PrivateKey
+}
+
+func TestAllowlistChangesNeedKeystorePassphrase(t *testing.T) {
+	wc, _, _ := newAllowlistWallet(t, 0)
+	address := solana.NewWallet().PublicKey().String()
+
+	_, err := wc.AllowRecipient(address, "guess")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+	assert.ErrorIs(t, wc.SetAllowlistEnforced(true, "guess"), ErrWrongPassphrase)
+
+	_, err = wc.AllowRecipient(address, allowlistPassphrase)
+	assert.NoError(t, err)
+	_, err = wc.DisallowRecipient(address, "guess")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+
+	list, err := wc.Allowlist()
+	assert.NoError(t, err)
+	assert.Equal(t, Allowlist{Recipients: []AllowedRecipient{{Address: address}}}, list)
+
+	// Without encrypted keys there is no passphrase to protect the list with.
+	files := newMemFiles()
+	plain := &KeyOps{FileReader: files, FileWriter: files}
+	key := solana.NewWallet()
+	assert.NoError(t, plain.WriteKeyToFile("main", []byte(key.PrivateKey), key.PublicKey().String()))
+	assert.ErrorIs(t, plain.AllowRecipient(Recipient{Address: address}, ""), ErrAllowlistUnprotected)
+}
+
+func TestAllowlistPinsContactAddress(t *testing.T) {
+	wc, _, _ := newAllowlistWallet(t, 0)
+	original := solana.NewWallet().PublicKey()
+	assert.NoError(t, wc.AddContact("mum", original.String()))
+
+	recipient, err := wc.AllowRecipient("mum", allowlistPassphrase)
+	assert.NoError(t, err)
+	assert.Equal(t, Recipient{Address: original.String(), Contact: "mum"}, recipient)
+	assert.NoError(t, wc.SetAllowlistEnforced(true, allowlistPassphrase))
+
+	// Pointing the contact elsewhere does not carry the approval along.
+	_, err = wc.RemoveContact("mum")
+	assert.NoError(t, err)
+	replaced := solana.NewWallet().PublicKey()
+	assert.NoError(t, wc.AddContact("mum", replaced.String()))
+	assert.NoError(t, wc.checkRecipientAllowed(original))
+	assert.ErrorIs(t, wc.checkRecipientAllowed(replaced), ErrRecipientNotAllowed)
+
+	removed, err := wc.DisallowRecipient("mum", allowlistPassphrase)
+	assert.NoError(t, err)
+	assert.Equal(t, AllowedRecipient{Address: original.String(), Contact: "mum"}, removed)
+	assert.ErrorIs(t, wc.checkRecipientAllowed(original), ErrRecipientNotAllowed)
+
+	_, err = wc.DisallowRecipient("mum", allowlistPassphrase)
+	assert.ErrorIs(t, err, ErrNotAllowlisted)
+}
+
+func TestAllowlistEnforcement(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   bool
+		flag     bool
+		enforced bool
+	}{
+		{name: "Off"},
+		{name: "Stored", stored: true, enforced: true},
+		{name: "Flag Only", flag: true, enforced: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc, _, _ := newAllowlistWallet(t, 0)
+			allowed := solana.NewWallet().PublicKey()
+			_, err := wc.AllowRecipient(allowed.String(), allowlistPassphrase)
+			assert.NoError(t, err)
+			assert.NoError(t, wc.SetAllowlistEnforced(tt.stored, allowlistPassphrase))
+			wc.EnforceAllowlist = tt.flag
+
+			assert.NoError(t, wc.checkRecipientAllowed(allowed))
+			err = wc.checkRecipientAllowed(solana.NewWallet().PublicKey())
+			if tt.enforced {
+				assert.ErrorIs(t, err, ErrRecipientNotAllowed)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAllowlistEverySendPath(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	ctx := context.Background()
+	stranger := solana.NewWallet().PublicKey()
+	usdc := "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+
+	tests := []struct {
+		name string
+		send func(wc *WalletConfig) error
+	}{
+		{name: "Send", send: func(wc *WalletConfig) error {
+			_, err := wc.PrepareSend(ctx, "1", stranger.String(), SendOptions{Unit: UnitSOL})
+			return err
+		}},
+		{name: "Execute Send", send: func(wc *WalletConfig) error {
+			_, err := wc.ExecuteSend(ctx, &SendQuote{To: stranger, Lamports: 1000}, SendOptions{})
+			return err
+		}},
+		{name: "Send Many", send: func(wc *WalletConfig) error {
+			items := []MultiSendItem{{Recipient: Recipient{Address: stranger.String()}, Amount: "1"}}
+			_, err := wc.PrepareSendMulti(ctx, items, SendOptions{Unit: UnitSOL})
+			return err
+		}},
+		{name: "Send Token", send: func(wc *WalletConfig) error {
+			_, err := wc.PrepareTokenSend(ctx, usdc, "1", stranger.String())
+			return err
+		}},
+		{name: "Execute Token Send", send: func(wc *WalletConfig) error {
+			_, err := wc.ExecuteTokenSend(ctx, &TokenQuote{Recipient: stranger})
+			return err
+		}},
+		{name: "Multisig Proposal", send: func(wc *WalletConfig) error {
+			_, err := wc.ProposeMultisigSend(ctx, &TokenQuote{Recipient: stranger}, "")
+			return err
+		}},
+		{name: "Consolidate", send: func(wc *WalletConfig) error {
+			_, err := wc.Consolidate(ctx, ConsolidateOptions{Destination: stranger.String()})
+			return err
+		}},
+		{name: "Failsafe", send: func(wc *WalletConfig) error {
+			_, err := wc.ArmFailsafe(ctx, "main", stranger.String(), solana.NewWallet().PublicKey().String(), "secret")
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc, cluster, _ := newAllowlistWallet(t, 5*solana.LAMPORTS_PER_SOL)
+			assert.NoError(t, wc.SetAllowlistEnforced(true, allowlistPassphrase))
+			// The refusal comes before anything is asked of the cluster.
+			newRPCClient = func(string) ClientInterface { return &MockClientInterface{} }
+
+			assert.ErrorIs(t, tt.send(wc), ErrRecipientNotAllowed)
+			assert.Empty(t, cluster.history)
+		})
+	}
+}
+
+func TestAllowlistAllowsApprovedSend(t *testing.T) {
+	wc, cluster, _ := newAllowlistWallet(t, 5*solana.LAMPORTS_PER_SOL)
+	allowed := solana.NewWallet().PublicKey()
+	_, err := wc.AllowRecipient(allowed.String(), allowlistPassphrase)
+	assert.NoError(t, err)
+	assert.NoError(t, wc.SetAllowlistEnforced(true, allowlistPassphrase))
+
+	receipt, err := wc.SendFundsWithOptions(context.Background(), "1", allowed.String(), SendOptions{Unit: UnitSOL})
+	assert.NoError(t, err)
+	assert.Equal(t, solana.LAMPORTS_PER_SOL, receipt.Lamports)
+	assert.Equal(t, solana.LAMPORTS_PER_SOL, cluster.balances[allowed])
+}
@@ -0,0 +1,124 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Unit is the denomination an amount to send is given in.
+type Unit string
+
+const (
+	// UnitFiat is the wallet's selected currency.
+	UnitFiat     Unit = "fiat"
+	UnitEUR      Unit = "eur"
+	UnitSOL      Unit = "sol"
+	UnitLamports Unit = "lamports"
+)
+
+// solDecimals is the number of decimal places a lamport represents in SOL.
+const solDecimals = 9
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// ParseUnit validates a unit name, defaulting to the selected currency when empty. A supported
+// currency code such as "usd" gives the amount in that currency whatever currency is selected.
+func ParseUnit(name string) (Unit, error) {
+	switch unit := Unit(strings.ToLower(strings.TrimSpace(name))); unit {
+	case "", UnitFiat:
+		return UnitFiat, nil
+	case UnitSOL:
+		return UnitSOL, nil
+	case UnitLamports, "lamport":
+		return UnitLamports, nil
+	default:
+		if _, err := ParseCurrency(string(unit)); err == nil {
+			return unit, nil
+		}
+		codes := make([]string, 0, len(supportedCurrencies))
+		for _, currency := range SupportedCurrencies() {
+			codes = append(codes, strings.ToLower(string(currency)))
+		}
+		return "", fmt.Errorf("unknown unit %q, expected one of: fiat, %s, sol, lamports", name, strings.Join(codes, ", "))
+	}
+}
+
+// NeedsRate reports whether converting the unit to lamports requires an exchange rate.
+func (u Unit) NeedsRate() bool {
+	return u != UnitSOL && u != UnitLamports
+}
+
+// Currency returns the currency of a fiat unit; UnitFiat and the zero value mean selected.
+func (u Unit) Currency(selected Currency) Currency {
+	if u == UnitFiat || u == "" {
+		return selected.orDefault()
+	}
+	return Currency(strings.ToUpper(string(u)))
+}
+
+// amountToLamports converts an amount given in unit to lamports. The rate is only used for fiat units.
+// SOL and lamport amounts must be exactly representable in lamports; fiat amounts are truncated.
+func amountToLamports(amount string, unit Unit, rate decimal.Decimal) (uint64, error) {
+	value, err := decimal.NewFromString(strings.TrimSpace(amount))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a number", ErrInvalidAmount, amount)
+	}
+
+	if !value.IsPositive() {
+		return 0, fmt.Errorf("%w: amount must be greater than 0", ErrInvalidAmount)
+	}
+
+	lamports, err := toLamports(value, unit, rate)
+	if err != nil {
+		return 0, err
+	}
+	return checkLamports(lamports)
+}
+
+// toLamports converts value in unit to lamports, keeping its sign, with the rules of amountToLamports.
+func toLamports(value decimal.Decimal, unit Unit, rate decimal.Decimal) (decimal.Decimal, error) {
+	switch unit {
+	case UnitSOL:
+		if value.Exponent() < -solDecimals {
+			return decimal.Zero, fmt.Errorf("%w: SOL amounts have at most %d decimal places", ErrInvalidAmount, solDecimals)
+		}
+		return value.Shift(solDecimals), nil
+	case UnitLamports:
+		if !value.Equal(value.Truncate(0)) {
+			return decimal.Zero, fmt.Errorf("%w: lamports must be a whole number", ErrInvalidAmount)
+		}
+		return value, nil
+	default:
+		converted, err := convertFiatToLamports(value.String(), rate)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return decimal.NewFromInt(converted), nil
+	}
+}
+
+// checkLamports returns a converted amount as lamports when it is at least one and fits a uint64.
+func checkLamports(lamports decimal.Decimal) (uint64, error) {
+	if !lamports.IsPositive() {
+		return 0, fmt.Errorf("%w: amount is smaller than one lamport", ErrInvalidAmount)
+	}
+
+	if !lamports.BigInt().IsUint64() {
+		return 0, fmt.Errorf("%w: amount is too large", ErrInvalidAmount)
+	}
+
+	return lamports.BigInt().Uint64(), nil
+}
+
+// ParseSOL converts a SOL amount such as "1.5" to lamports.
+func ParseSOL(amount string) (uint64, error) {
+	return amountToLamports(amount, UnitSOL, decimal.Zero)
+}
+
+// LamportsToSOL converts lamports to SOL.
+func LamportsToSOL(lamports uint64) decimal.Decimal {
+	return decimal.NewFromInt(int64(lamports)).Shift(-solDecimals)
+}
@@ -0,0 +1,273 @@
+package wallet
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// plainNumber matches an amount that is a single number, optionally signed or with an exponent.
+var plainNumber = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// MaxKeyword stands for the whole spendable balance in an amount expression.
+const MaxKeyword = "max"
+
+// termKind is what a term of an amount expression stands for.
+type termKind int
+
+const (
+	// termFixed is a number in a unit, e.g. 12.5, 0.1sol or 5000lamports.
+	termFixed termKind = iota
+	// termPercent is a share of the spendable balance, e.g. 50%.
+	termPercent
+	// termMax is the whole spendable balance.
+	termMax
+)
+
+// amountTerm is one term of an amount expression with the sign it is added with.
+type amountTerm struct {
+	negative bool
+	kind     termKind
+	value    decimal.Decimal
+	unit     Unit
+}
+
+// AmountExpression is an amount to send written as terms added and subtracted from left to right:
+// numbers with an optional unit suffix, percentages of the spendable balance and the max keyword,
+// e.g. "12.5+3.2", "50%" or "max-0.1sol". There is no multiplication, division or grouping.
+type AmountExpression struct {
+	source string
+	terms  []amountTerm
+}
+
+// ParseAmountExpression parses an amount expression. Numbers without a suffix are in unit; a plain
+// number is an expression of one term, so every amount the send command took before still parses.
+func ParseAmountExpression(expression string, unit Unit) (*AmountExpression, error) {
+	source := strings.TrimSpace(expression)
+	if source == "" {
+		return nil, fmt.Errorf("%w: the amount is empty", ErrInvalidAmount)
+	}
+	// A lone number keeps the forms the send command always took, such as 1e3.
+	if plainNumber.MatchString(source) {
+		value, err := decimal.NewFromString(source)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a number", ErrInvalidAmount, source)
+		}
+		return &AmountExpression{source: source, terms: []amountTerm{{kind: termFixed, value: value, unit: unit}}}, nil
+	}
+
+	p := &amountParser{input: []rune(source)}
+	expr := &AmountExpression{source: source}
+	negative := false
+	for {
+		term, err := p.term(unit)
+		if err != nil {
+			return nil, err
+		}
+		term.negative = negative
+		expr.terms = append(expr.terms, term)
+
+		p.skipSpace()
+		if p.done() {
+			return expr, nil
+		}
+		switch op := p.next(); op {
+		case '+':
+			negative = false
+		case '-':
+			negative = true
+		case '*', '/', '×', '÷', '(', ')':
+			return nil, fmt.Errorf("%w: %q: amounts can only be added and subtracted", ErrInvalidAmount, op)
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q in %q", ErrInvalidAmount, op, source)
+		}
+	}
+}
+
+// String returns the expression as it was given.
+func (e *AmountExpression) String() string {
+	return e.source
+}
+
+// Relative reports whether the expression depends on the spendable balance.
+func (e *AmountExpression) Relative() bool {
+	for _, term := range e.terms {
+		if term.kind != termFixed {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsRate reports whether resolving the expression requires an exchange rate, because one of its
+// numbers is in a fiat currency.
+func (e *AmountExpression) NeedsRate() bool {
+	for _, unit := range e.Units() {
+		if unit.NeedsRate() {
+			return true
+		}
+	}
+	return false
+}
+
+// Simple reports whether the expression is a single number, which needs no explaining when shown
+// next to the amount it resolves to.
+func (e *AmountExpression) Simple() bool {
+	return len(e.terms) == 1 && e.terms[0].kind == termFixed
+}
+
+// Units returns the units of the numbers in the expression, each once.
+func (e *AmountExpression) Units() []Unit {
+	var units []Unit
+	seen := make(map[Unit]bool)
+	for _, term := range e.terms {
+		if term.kind == termFixed && !seen[term.unit] {
+			seen[term.unit] = true
+			units = append(units, term.unit)
+		}
+	}
+	return units
+}
+
+// Resolve evaluates the expression to lamports against the spendable balance, the balance left after
+// the fee. rate returns the SOL rate of a fiat unit. Numbers in the same unit are summed before they
+// are converted, so 12.5+3.2 truncates to lamports once, like 15.7 does.
+func (e *AmountExpression) Resolve(spendable uint64, rate func(Unit) (decimal.Decimal, error)) (uint64, error) {
+	if e.Simple() && !e.terms[0].value.IsPositive() {
+		return 0, fmt.Errorf("%w: amount must be greater than 0", ErrInvalidAmount)
+	}
+
+	sums := make(map[Unit]decimal.Decimal)
+	total := decimal.Zero
+	for _, term := range e.terms {
+		var value decimal.Decimal
+		switch term.kind {
+		case termFixed:
+			sums[term.unit] = sums[term.unit].Add(signed(term.value, term.negative))
+			continue
+		case termPercent:
+			value = lamportsDecimal(spendable).Mul(term.value).Div(decimal.NewFromInt(100)).Truncate(0)
+		case termMax:
+			value = lamportsDecimal(spendable)
+		}
+		total = total.Add(signed(value, term.negative))
+	}
+
+	for _, unit := range e.Units() {
+		var unitRate decimal.Decimal
+		if unit.NeedsRate() {
+			var err error
+			if unitRate, err = rate(unit); err != nil {
+				return 0, err
+			}
+		}
+		lamports, err := toLamports(sums[unit], unit, unitRate)
+		if err != nil {
+			return 0, err
+		}
+		total = total.Add(lamports)
+	}
+
+	switch {
+	case total.IsPositive() || e.Simple():
+		return checkLamports(total)
+	case e.Relative():
+		return 0, fmt.Errorf("%w: %s leaves nothing to send from a spendable balance of %s SOL", ErrInvalidAmount, e, LamportsToSOL(spendable))
+	default:
+		return 0, fmt.Errorf("%w: %s does not add up to more than 0", ErrInvalidAmount, e)
+	}
+}
+
+// lamportsDecimal returns lamports as a decimal without overflowing int64.
+func lamportsDecimal(lamports uint64) decimal.Decimal {
+	return decimal.NewFromBigInt(new(big.Int).SetUint64(lamports), 0)
+}
+
+func signed(value decimal.Decimal, negative bool) decimal.Decimal {
+	if negative {
+		return value.Neg()
+	}
+	return value
+}
+
+// amountParser reads the terms of an amount expression.
+type amountParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *amountParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *amountParser) next() rune {
+	r := p.input[p.pos]
+	p.pos++
+	return r
+}
+
+func (p *amountParser) skipSpace() {
+	for !p.done() && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// take returns the longest run of runes from the current position that match.
+func (p *amountParser) take(match func(rune) bool) string {
+	start := p.pos
+	for !p.done() && match(p.input[p.pos]) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+// term reads a number with an optional % or unit suffix, or the max keyword.
+func (p *amountParser) term(unit Unit) (amountTerm, error) {
+	p.skipSpace()
+	if p.done() {
+		return amountTerm{}, fmt.Errorf("%w: the amount ends with an operator", ErrInvalidAmount)
+	}
+
+	if word := p.take(unicode.IsLetter); word != "" {
+		if !strings.EqualFold(word, MaxKeyword) {
+			return amountTerm{}, fmt.Errorf("%w: %q is neither a number nor %q", ErrInvalidAmount, word, MaxKeyword)
+		}
+		return amountTerm{kind: termMax}, nil
+	}
+
+	number := p.take(func(r rune) bool { return unicode.IsDigit(r) || r == '.' })
+	if number == "" {
+		return amountTerm{}, fmt.Errorf("%w: expected a number, %q or a percentage at %q", ErrInvalidAmount, MaxKeyword, string(p.input[p.pos:]))
+	}
+	value, err := decimal.NewFromString(number)
+	if err != nil || strings.Trim(number, ".") == "" {
+		return amountTerm{}, fmt.Errorf("%w: %q is not a number", ErrInvalidAmount, number)
+	}
+
+	if !p.done() && p.input[p.pos] == '%' {
+		p.pos++
+		if !value.IsPositive() || value.GreaterThan(decimal.NewFromInt(100)) {
+			return amountTerm{}, fmt.Errorf("%w: %s%% is not a share between 0 and 100%%", ErrInvalidAmount, number)
+		}
+		return amountTerm{kind: termPercent, value: value}, nil
+	}
+
+	// A suffix may be set apart from its number, as in "0.5 sol", but never from the operator after it.
+	start := p.pos
+	p.skipSpace()
+	if suffix := p.take(unicode.IsLetter); suffix == "" {
+		p.pos = start
+	} else {
+		if strings.EqualFold(suffix, string(UnitFiat)) {
+			return amountTerm{}, fmt.Errorf("%w: %q is not a unit suffix; leave it out for the selected currency", ErrInvalidAmount, suffix)
+		}
+		if unit, err = ParseUnit(suffix); err != nil {
+			return amountTerm{}, fmt.Errorf("%w: %v", ErrInvalidAmount, err)
+		}
+	}
+	return amountTerm{kind: termFixed, value: value, unit: unit}, nil
+}
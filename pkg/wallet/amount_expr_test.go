@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAmountExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		unit       Unit
+		relative   bool
+		simple     bool
+		units      []Unit
+		expectErr  bool
+	}{
+		{name: "Plain Number", expression: "12.5", unit: UnitEUR, simple: true, units: []Unit{UnitEUR}},
+		{name: "Exponent", expression: "1e3", unit: UnitLamports, simple: true, units: []Unit{UnitLamports}},
+		{name: "Signed Number", expression: "+5", unit: UnitSOL, simple: true, units: []Unit{UnitSOL}},
+		{name: "Surrounding Space", expression: "  0.5 ", unit: UnitSOL, simple: true, units: []Unit{UnitSOL}},
+		{name: "Sum", expression: "12.5+3.2", unit: UnitEUR, units: []Unit{UnitEUR}},
+		{name: "Spaces Around Operators", expression: "12.5 + 3.2 - 1", unit: UnitEUR, units: []Unit{UnitEUR}},
+		{name: "Unit Suffix", expression: "0.5sol", unit: UnitEUR, simple: true, units: []Unit{UnitSOL}},
+		{name: "Spaced Unit Suffix", expression: "0.5 sol", unit: UnitEUR, simple: true, units: []Unit{UnitSOL}},
+		{name: "Spaced Suffixes In Sum", expression: "1 sol + 2 eur", unit: UnitFiat, units: []Unit{UnitSOL, UnitEUR}},
+		{name: "Mixed Units", expression: "10usd+0.1sol-5000lamports", unit: UnitEUR, units: []Unit{"usd", UnitSOL, UnitLamports}},
+		{name: "Upper Case Suffix", expression: "1SOL+2EUR", unit: UnitFiat, units: []Unit{UnitSOL, UnitEUR}},
+		{name: "Percentage", expression: "50%", unit: UnitEUR, relative: true},
+		{name: "Fractional Percentage", expression: "12.5%", unit: UnitEUR, relative: true},
+		{name: "Max", expression: "max", unit: UnitEUR, relative: true},
+		{name: "Max Upper Case", expression: "MAX", unit: UnitEUR, relative: true},
+		{name: "Max Minus SOL", expression: "max-0.1sol", unit: UnitEUR, relative: true, units: []Unit{UnitSOL}},
+		{name: "Percentage Plus Fiat", expression: "25% + 10", unit: UnitEUR, relative: true, units: []Unit{UnitEUR}},
+
+		{name: "Empty", expression: "", expectErr: true},
+		{name: "Only Spaces", expression: "   ", expectErr: true},
+		{name: "Division", expression: "10/2", expectErr: true},
+		{name: "Multiplication", expression: "10*2", expectErr: true},
+		{name: "Multiplication Sign", expression: "10×2", expectErr: true},
+		{name: "Division Sign", expression: "10÷2", expectErr: true},
+		{name: "Grouping", expression: "(1+2)", expectErr: true},
+		{name: "Closing Parenthesis", expression: "1+2)", expectErr: true},
+		{name: "Percent Of Max", expression: "50%max", expectErr: true},
+		{name: "Leading Operator", expression: "+max", expectErr: true},
+		{name: "Leading Minus In Expression", expression: "-5+1", expectErr: true},
+		{name: "Trailing Operator", expression: "5+", expectErr: true},
+		{name: "Double Operator", expression: "5+-1", expectErr: true},
+		{name: "Missing Operator", expression: "5 6", expectErr: true},
+		{name: "Max After Number", expression: "5 max", expectErr: true},
+		{name: "Unknown Word", expression: "all", expectErr: true},
+		{name: "Unknown Unit", expression: "5btc", expectErr: true},
+		{name: "Fiat Is Not A Suffix", expression: "5fiat+1", expectErr: true},
+		{name: "Two Decimal Points", expression: "1.2.3+1", expectErr: true},
+		{name: "Zero Percent", expression: "0%", expectErr: true},
+		{name: "More Than Everything", expression: "101%", expectErr: true},
+		{name: "Percent With Unit", expression: "50%sol", expectErr: true},
+		{name: "Max With Number", expression: "max5", expectErr: true},
+		{name: "Lone Dot", expression: ".+1", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseAmountExpression(tt.expression, tt.unit)
+			if tt.expectErr {
+				assert.ErrorIs(t, err, ErrInvalidAmount)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.relative, expr.Relative())
+			assert.Equal(t, tt.simple, expr.Simple())
+			assert.Equal(t, tt.units, expr.Units())
+		})
+	}
+}
+
+func TestAmountExpressionResolve(t *testing.T) {
+	rates := map[Unit]decimal.Decimal{UnitEUR: decimal.NewFromInt(20), "usd": decimal.NewFromInt(25)}
+	rate := func(u Unit) (decimal.Decimal, error) {
+		if r, ok := rates[u]; ok {
+			return r, nil
+		}
+		return decimal.Zero, errors.New("no rate")
+	}
+
+	tests := []struct {
+		name        string
+		expression  string
+		unit        Unit
+		spendable   uint64
+		expected    uint64
+		expectedErr bool
+	}{
+		{name: "Plain SOL", expression: "0.5", unit: UnitSOL, expected: 500_000_000},
+		{name: "Plain EUR", expression: "10", unit: UnitEUR, expected: 500_000_000},
+		{name: "EUR Sum", expression: "12.5+3.2", unit: UnitEUR, expected: 785_000_000},
+		{name: "Sum Truncates Once", expression: "1+1+1", unit: "usd", expected: 120_000_000},
+		{name: "Subtraction", expression: "10-2.5", unit: UnitEUR, expected: 375_000_000},
+		{name: "Mixed Units", expression: "10+1sol-5000lamports", unit: UnitEUR, expected: 1_499_995_000},
+		{name: "Other Currency Suffix", expression: "25usd", unit: UnitEUR, expected: 1_000_000_000},
+		{name: "Half", expression: "50%", unit: UnitEUR, spendable: 1_000_000_001, expected: 500_000_000},
+		{name: "Everything", expression: "100%", unit: UnitEUR, spendable: 1_000_000_000, expected: 1_000_000_000},
+		{name: "Max", expression: "max", unit: UnitEUR, spendable: 2_000_000_000, expected: 2_000_000_000},
+		{name: "Max Minus SOL", expression: "max-0.1", unit: UnitSOL, spendable: 2_000_000_000, expected: 1_900_000_000},
+		{name: "Max Minus EUR", expression: "max-1", unit: UnitEUR, spendable: 2_000_000_000, expected: 1_950_000_000},
+		{name: "Percentages Add Up", expression: "25%+25%", unit: UnitEUR, spendable: 1000, expected: 500},
+		{name: "Large Balance", expression: "max", unit: UnitEUR, spendable: 1 << 63, expected: 1 << 63},
+
+		{name: "Zero", expression: "0", unit: UnitSOL, expectedErr: true},
+		{name: "Negative", expression: "-1", unit: UnitLamports, expectedErr: true},
+		{name: "Below One Lamport", expression: "0.00000001", unit: UnitEUR, expectedErr: true},
+		{name: "SOL Too Precise", expression: "0.1+0.0000000001", unit: UnitSOL, expectedErr: true},
+		{name: "Fractional Lamports", expression: "1+0.5", unit: UnitLamports, expectedErr: true},
+		{name: "Subtracts To Nothing", expression: "1-1", unit: UnitSOL, expectedErr: true},
+		{name: "Max Of Nothing", expression: "max", unit: UnitEUR, spendable: 0, expectedErr: true},
+		{name: "Max Minus More Than Spendable", expression: "max-1", unit: UnitSOL, spendable: 500_000_000, expectedErr: true},
+		{name: "Percentage Below One Lamport", expression: "1%", unit: UnitEUR, spendable: 50, expectedErr: true},
+		{name: "Missing Rate", expression: "10gbp", unit: UnitEUR, expectedErr: true},
+		{name: "Too Large", expression: "max+max", unit: UnitEUR, spendable: 1 << 63, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseAmountExpression(tt.expression, tt.unit)
+			assert.NoError(t, err)
+
+			got, err := expr.Resolve(tt.spendable, rate)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestAmountExpressionNeedsRate(t *testing.T) {
+	for expression, expected := range map[string]bool{
+		"0.5sol":          false,
+		"500000 lamports": false,
+		"max-0.1sol":      false,
+		"50%":             false,
+		"12.5":            true,
+		"1sol+2usd":       true,
+		"25% + 10":        true,
+	} {
+		expr, err := ParseAmountExpression(expression, UnitFiat)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, expr.NeedsRate(), expression)
+	}
+}
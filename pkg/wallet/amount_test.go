@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertFiatToLamports(t *testing.T) {
+	tests := []struct {
+		name        string
+		eur         string
+		rate        decimal.Decimal
+		expected    int64
+		expectedErr bool
+	}{
+		{name: "Whole SOL", eur: "20", rate: decimal.NewFromInt(20), expected: 1_000_000_000},
+		{name: "Truncates Fractional Lamports", eur: "1", rate: decimal.NewFromInt(3), expected: 333_333_333},
+		{name: "Truncates Below One Lamport", eur: "0.00000001", rate: decimal.NewFromInt(20), expected: 0},
+		{name: "Invalid Amount", eur: "ten", rate: decimal.NewFromInt(20), expectedErr: true},
+		{name: "Zero Rate", eur: "10", rate: decimal.Zero, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertFiatToLamports(tt.eur, tt.rate)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestAmountToLamports(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      string
+		unit        Unit
+		expected    uint64
+		expectedErr bool
+	}{
+		{name: "SOL", amount: "0.5", unit: UnitSOL, expected: 500_000_000},
+		{name: "SOL Smallest Unit", amount: "0.000000001", unit: UnitSOL, expected: 1},
+		{name: "SOL Too Precise", amount: "0.0000000001", unit: UnitSOL, expectedErr: true},
+		{name: "Lamports", amount: "5000", unit: UnitLamports, expected: 5000},
+		{name: "Fractional Lamports", amount: "1.5", unit: UnitLamports, expectedErr: true},
+		{name: "EUR", amount: "10", unit: UnitEUR, expected: 500_000_000},
+		{name: "EUR Below One Lamport", amount: "0.00000001", unit: UnitEUR, expectedErr: true},
+		{name: "Zero", amount: "0", unit: UnitSOL, expectedErr: true},
+		{name: "Negative", amount: "-1", unit: UnitLamports, expectedErr: true},
+		{name: "Not A Number", amount: "lots", unit: UnitSOL, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := amountToLamports(tt.amount, tt.unit, decimal.NewFromInt(20))
+			if tt.expectedErr {
+				assert.ErrorIs(t, err, ErrInvalidAmount)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParseUnit(t *testing.T) {
+	for input, expected := range map[string]Unit{"": UnitFiat, "fiat": UnitFiat, "EUR": UnitEUR, "usd": Unit("usd"), "sol": UnitSOL, "lamports": UnitLamports} {
+		got, err := ParseUnit(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, got)
+	}
+
+	_, err := ParseUnit("btc")
+	assert.Error(t, err)
+}
+
+func TestUnitCurrency(t *testing.T) {
+	assert.Equal(t, Currency("GBP"), UnitFiat.Currency("GBP"))
+	assert.Equal(t, Currency("USD"), Unit("usd").Currency("GBP"))
+	assert.Equal(t, DefaultCurrency, UnitFiat.Currency(""))
+}
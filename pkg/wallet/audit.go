@@ -0,0 +1,122 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const AuditFilePath = "sleeng.audit.json"
+
+// maxAuditEntries bounds the audit log; the oldest entries are dropped first.
+const maxAuditEntries = 5000
+
+// AuditEntry records one change to the key file, or a transfer made with one of its keys.
+type AuditEntry struct {
+	Time    time.Time        `json:"time"`
+	Action  string           `json:"action"`
+	Changes []KeystoreChange `json:"changes"`
+	// Detail describes entries that do not change the key file, such as sends.
+	Detail string `json:"detail,omitempty"`
+	// Origin is where the change or send was started; entries written by older versions have none.
+	Origin *Origin `json:"origin,omitempty"`
+	// Genesis is the genesis hash of the custom cluster a send was made on.
+	Genesis string `json:"genesis,omitempty"`
+	// Endpoint is the RPC endpoint that served the last request before the entry was written.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// auditSchema is the schema of the audit log.
+var auditSchema = registerStoreSchema(storeSchema{
+	Kind:       StoreAudit,
+	Version:    1,
+	Migrations: map[int]storeMigration{0: bareStorePayload},
+})
+
+// auditEntries is the payload of the audit log.
+type auditEntries []AuditEntry
+
+func (a auditEntries) validate() error {
+	for i, entry := range a {
+		if entry.Action == "" || entry.Time.IsZero() {
+			return fmt.Errorf("entry %d has no action or time", i)
+		}
+	}
+	return nil
+}
+
+// AuditLog keeps a record of key file changes in a file next to the key file.
+type AuditLog struct {
+	FileReader FileReader
+	FileWriter FileWriter
+	// Dir is the profile directory holding the log. Empty means the current directory.
+	Dir string
+	// Origin is recorded in entries appended without an origin of their own, when set.
+	Origin Origin
+}
+
+// filePath returns the location of the audit log for this profile.
+func (a *AuditLog) filePath() string {
+	return filepath.Join(a.Dir, AuditFilePath)
+}
+
+// Entries returns the audit log, oldest first. A missing log is empty.
+func (a *AuditLog) Entries() ([]AuditEntry, error) {
+	var entries auditEntries
+	if err := readStore(a.FileReader, a.filePath(), auditSchema, &entries); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Append adds an entry to the audit log, with the log's Origin when the entry has none.
+func (a *AuditLog) Append(entry AuditEntry) error {
+	if entry.Origin == nil && !a.Origin.IsZero() {
+		origin := a.Origin
+		entry.Origin = &origin
+	}
+
+	entries, err := a.Entries()
+	if err != nil {
+		return err
+	}
+
+	entries = append(entries, entry)
+	if len(entries) > maxAuditEntries {
+		entries = entries[len(entries)-maxAuditEntries:]
+	}
+
+	return writeStore(a.FileReader, a.FileWriter, a.filePath(), auditSchema, auditEntries(entries))
+}
+
+// AuditEntries returns the audit log of the key file, oldest first.
+func (w *WalletConfig) AuditEntries() ([]AuditEntry, error) {
+	keyOps, ok := w.KeyOps.(*KeyOps)
+	if !ok || keyOps.Audit == nil {
+		return nil, nil
+	}
+	return keyOps.Audit.Entries()
+}
+
+// recordAudit appends an entry that does not change the key file, such as a send, to the audit log.
+// The entry records the origin of ctx, or else the one set with UseOrigin, the RPC endpoint that
+// served the operation and the genesis hash of a custom cluster.
+func (w *WalletConfig) recordAudit(ctx context.Context, action, detail string) error {
+	keyOps, ok := w.KeyOps.(*KeyOps)
+	if !ok || keyOps.Audit == nil {
+		return nil
+	}
+
+	entry := AuditEntry{Time: time.Now().UTC(), Action: action, Detail: detail, Genesis: w.customGenesis(), Endpoint: lastServedEndpoint()}
+	if origin := w.operationOrigin(ctx); !origin.IsZero() {
+		entry.Origin = &origin
+	}
+	return keyOps.Audit.Append(entry)
+}
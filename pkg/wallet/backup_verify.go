@@ -0,0 +1,172 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// SeedWordCounts are the lengths a BIP-39 seed phrase can have.
+var SeedWordCounts = []int{12, 15, 18, 21, 24}
+
+var ErrInvalidSeedWords = errors.New("not a valid seed phrase")
+
+// seedWordIndex maps every BIP-39 English word to its index. Looking words up with index[string(b)]
+// does not copy them into a string.
+var seedWordIndex = func() map[string]int {
+	index := make(map[string]int, 2048)
+	for i, word := range bip39.GetWordList() {
+		index[word] = i
+	}
+	return index
+}()
+
+// IsSeedWord reports whether word is in the BIP-39 English word list.
+func IsSeedWord(word string) bool {
+	_, ok := seedWordIndex[word]
+	return ok
+}
+
+// SeedWords is a seed phrase held word by word in buffers that Wipe overwrites, so a backup being
+// checked does not linger in memory the way a string would until the garbage collector reuses it.
+type SeedWords [][]byte
+
+// Wipe overwrites every word with zeros.
+func (s SeedWords) Wipe() {
+	for _, word := range s {
+		wipe(word)
+	}
+}
+
+// wipe overwrites b with zeros.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// phrase joins the words with single spaces into a new buffer the caller must wipe.
+func (s SeedWords) phrase() []byte {
+	size := len(s)
+	for _, word := range s {
+		size += len(word)
+	}
+	phrase := make([]byte, 0, size)
+	for i, word := range s {
+		if i > 0 {
+			phrase = append(phrase, ' ')
+		}
+		phrase = append(phrase, word...)
+	}
+	return phrase
+}
+
+// validate checks the number of words, that each is in the word list and the BIP-39 checksum,
+// without assembling the phrase. Errors name the position of a bad word, never the word.
+func (s SeedWords) validate() error {
+	valid := false
+	for _, count := range SeedWordCounts {
+		valid = valid || len(s) == count
+	}
+	if !valid {
+		return fmt.Errorf("%w: %d words, expected 12, 15, 18, 21 or 24", ErrInvalidSeedWords, len(s))
+	}
+
+	// The words concatenate to the entropy followed by a checksum of one bit per 32 bits of entropy.
+	total := len(s) * 11
+	checksumBits := total / 33
+	bits := make([]byte, (total+7)/8)
+	defer wipe(bits)
+	for i, word := range s {
+		index, ok := seedWordIndex[string(word)]
+		if !ok {
+			return fmt.Errorf("%w: word %d is not in the BIP-39 word list", ErrInvalidSeedWords, i+1)
+		}
+		for b := 0; b < 11; b++ {
+			if index&(1<<(10-b)) != 0 {
+				pos := i*11 + b
+				bits[pos/8] |= 0x80 >> (pos % 8)
+			}
+		}
+	}
+
+	entropy := bits[:(total-checksumBits)/8]
+	sum := sha256.Sum256(entropy)
+	defer wipe(sum[:])
+	if bits[len(entropy)]>>(8-checksumBits) != sum[0]>>(8-checksumBits) {
+		return fmt.Errorf("%w: the checksum does not match; a word is wrong or out of order", ErrInvalidSeedWords)
+	}
+	return nil
+}
+
+// derive derives the key for the words along derivation.
+func (s SeedWords) derive(derivation KeyDerivation, passphrase string) (ed25519.PrivateKey, error) {
+	phrase := s.phrase()
+	defer wipe(phrase)
+
+	opts := seedOptionsFor(derivation, passphrase)
+	if opts.Legacy {
+		// The legacy derivation only takes a string, so it gets a copy that cannot be wiped.
+		_, key, err := createLegacyKeyPairWithMnemonic(string(phrase))
+		return key, err
+	}
+
+	// The BIP-39 seed, as bip39.NewSeed computes it but from a buffer that can be wiped.
+	seed := pbkdf2.Key(phrase, []byte("mnemonic"+passphrase), 2048, 64, sha512.New)
+	defer wipe(seed)
+	return deriveSLIP10Key(seed, opts.path())
+}
+
+// BackupCheck is the outcome of checking a written-down seed phrase against a stored wallet.
+type BackupCheck struct {
+	Alias     string `json:"alias"`
+	PublicKey string `json:"publicKey"`
+	// Derivation is the derivation that matched, or the one stored for the wallet when none did.
+	Derivation KeyDerivation `json:"derivation"`
+	Match      bool          `json:"match"`
+}
+
+// VerifyBackup checks that a backup seed phrase derives the stored public key of the wallet alias, or
+// of the active wallet when alias is empty, along its stored derivation path. Nothing is written:
+// the key file is only read and the derived keys are wiped. A phrase that derives another address
+// returns the check with an error wrapping ErrDerivationMismatch.
+func (w *WalletConfig) VerifyBackup(alias string, words SeedWords, passphrase string) (BackupCheck, error) {
+	info, err := w.KeyOps.GetWalletInfo(alias)
+	if err != nil {
+		return BackupCheck{}, err
+	}
+
+	check := BackupCheck{Alias: info.Alias, PublicKey: info.PublicKey, Derivation: info.Derivation}
+	if !info.Derivation.IsSeedDerived() {
+		return check, fmt.Errorf("%w: %s", ErrNotSeedDerived, info.Alias)
+	}
+	if err := words.validate(); err != nil {
+		return check, err
+	}
+
+	candidates := derivationCandidates(info.Derivation, passphrase != "")
+	for _, candidate := range candidates {
+		key, err := words.derive(candidate, passphrase)
+		if err != nil {
+			// The legacy derivation only accepts 12 word seed phrases; other candidates may still match.
+			if len(candidates) > 1 {
+				continue
+			}
+			return check, err
+		}
+		match := solana.PrivateKey(key).PublicKey().String() == info.PublicKey
+		wipe(key)
+		if match {
+			check.Derivation, check.Match = candidate, true
+			return check, nil
+		}
+	}
+
+	return check, fmt.Errorf("%w: %s", ErrDerivationMismatch, info.Alias)
+}
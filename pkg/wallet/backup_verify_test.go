@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func seedWords(phrase string) SeedWords {
+	var words SeedWords
+	for _, word := range strings.Fields(phrase) {
+		words = append(words, []byte(word))
+	}
+	return words
+}
+
+func TestSeedWordsValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		phrase      string
+		expectedMsg string
+	}{
+		{name: "Valid", phrase: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+		{name: "Valid 24 Words", phrase: "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title"},
+		{name: "Wrong Count", phrase: "abandon abandon abandon", expectedMsg: "3 words"},
+		{name: "Unknown Word", phrase: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandonn about", expectedMsg: "word 11 is not in the BIP-39 word list"},
+		{name: "Bad Checksum", phrase: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about abandon", expectedMsg: "checksum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := seedWords(tt.phrase).validate()
+			if tt.expectedMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidSeedWords)
+			assert.Contains(t, err.Error(), tt.expectedMsg)
+			assert.NotContains(t, err.Error(), "abandonn")
+		})
+	}
+}
+
+func TestSeedWordsDeriveLikeMnemonic(t *testing.T) {
+	phrase := "legal winner thank year wave sausage worth useful legal winner thank yellow"
+	for _, derivation := range []KeyDerivation{
+		{Path: SolanaDerivationPath(0)},
+		{Path: "m/44'/501'/7'"},
+		{Path: DerivationLegacy},
+	} {
+		expected, err := deriveKeyFromMnemonic(phrase, seedOptionsFor(derivation, "TREZOR"))
+		assert.NoError(t, err)
+		got, err := seedWords(phrase).derive(derivation, "TREZOR")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, got, derivation.Path)
+	}
+}
+
+func TestSeedWordsWipe(t *testing.T) {
+	words := seedWords("legal winner")
+	words.Wipe()
+	assert.Equal(t, SeedWords{make([]byte, 5), make([]byte, 6)}, words)
+}
+
+func TestVerifyBackup(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	// The address wallets such as Phantom and Solflare derive for the first account of mnemonic.
+	const firstAccount = "HAgk14JpMQLgt6rVgv7cBQFJWFto5Dqxi472uT3DKpqk"
+
+	tests := []struct {
+		name          string
+		opts          SeedOptions
+		phrase        string
+		passphrase    string
+		expectedMatch bool
+		expectedErr   error
+	}{
+		{name: "Match", phrase: mnemonic, expectedMatch: true},
+		{name: "Match Other Account", opts: SeedOptions{AccountIndex: 3}, phrase: mnemonic, expectedMatch: true},
+		{name: "Match With Passphrase", opts: SeedOptions{Passphrase: "hunter2"}, phrase: mnemonic, passphrase: "hunter2", expectedMatch: true},
+		{name: "Wrong Passphrase", opts: SeedOptions{Passphrase: "hunter2"}, phrase: mnemonic, passphrase: "hunter3", expectedErr: ErrDerivationMismatch},
+		{name: "Other Seed", phrase: "legal winner thank year wave sausage worth useful legal winner thank yellow", expectedErr: ErrDerivationMismatch},
+		{name: "Mistyped Seed", phrase: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", expectedErr: ErrInvalidSeedWords},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}}
+			_, err := wc.ImportWalletFromSeedWithOptions(mnemonic, tt.opts)
+			assert.NoError(t, err)
+			assert.NoError(t, wc.SaveSeedWallet("main"))
+			before := string(files.files[KeyFileName])
+
+			check, err := wc.VerifyBackup("", seedWords(tt.phrase), tt.passphrase)
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expectedMatch, check.Match)
+			assert.Equal(t, "main", check.Alias)
+			assert.Equal(t, before, string(files.files[KeyFileName]), "verifying a backup must not write")
+			assert.Len(t, files.files, 1)
+		})
+	}
+
+	t.Run("Known Address", func(t *testing.T) {
+		files := newMemFiles()
+		wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}}
+		_, err := wc.ImportWalletFromSeedWithOptions(mnemonic, SeedOptions{})
+		assert.NoError(t, err)
+		assert.NoError(t, wc.SaveSeedWallet("main"))
+
+		check, err := wc.VerifyBackup("main", seedWords(mnemonic), "")
+		assert.NoError(t, err)
+		assert.Equal(t, BackupCheck{Alias: "main", PublicKey: firstAccount, Derivation: check.Derivation, Match: true}, check)
+		assert.Equal(t, SolanaDerivationPath(0), check.Derivation.Path)
+	})
+
+	t.Run("Not Seed Derived", func(t *testing.T) {
+		files := newMemFiles()
+		keyOps := &KeyOps{FileReader: files, FileWriter: files}
+		key := solana.NewWallet()
+		assert.NoError(t, keyOps.WriteKeyToFile("main", []byte(key.PrivateKey), key.PublicKey().String()))
+		wc := &WalletConfig{KeyOps: keyOps}
+
+		_, err := wc.VerifyBackup("main", seedWords(mnemonic), "")
+		assert.ErrorIs(t, err, ErrNotSeedDerived)
+	})
+}
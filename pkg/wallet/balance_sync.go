@@ -0,0 +1,190 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// syncConcurrency bounds how many balances SyncBalances fetches at once.
+const syncConcurrency = 8
+
+// SyncedBalance is the balance of a stored wallet as refreshed by SyncBalances.
+type SyncedBalance struct {
+	Alias string
+	SOL   decimal.Decimal
+}
+
+// SyncError reports the wallets whose balance could not be refreshed. The balances of the other
+// wallets were saved.
+type SyncError struct {
+	// Failed maps the alias of every wallet that was not refreshed to the reason.
+	Failed map[string]error
+}
+
+func (e *SyncError) Error() string {
+	aliases := make([]string, 0, len(e.Failed))
+	for alias := range e.Failed {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	reasons := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		reasons = append(reasons, fmt.Sprintf("%s (%v)", alias, e.Failed[alias]))
+	}
+	return fmt.Sprintf("could not refresh the balance of %s", strings.Join(reasons, ", "))
+}
+
+// UpdateBalances stores the SOL balances of wallets by alias, stamped with syncedAt. Aliases that are
+// no longer in the key file are ignored.
+func (k *KeyOps) UpdateBalances(balances map[string]decimal.Decimal, syncedAt time.Time) error {
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return err
+	}
+
+	syncedAt = syncedAt.UTC()
+	for alias, balance := range balances {
+		wallet, ok := data.Wallets[alias]
+		if !ok {
+			continue
+		}
+		wallet.Balance = balance
+		wallet.LastSynced = &syncedAt
+		data.Wallets[alias] = wallet
+	}
+
+	return k.writeWalletData("sync", data)
+}
+
+// SyncBalances fetches the balance of every stored wallet concurrently and stores them in the key
+// file, so listings can show them without asking the cluster. When some balances cannot be fetched,
+// the others are still stored and returned together with a *SyncError naming the failed wallets.
+func (w *WalletConfig) SyncBalances(ctx context.Context) ([]SyncedBalance, error) {
+	infos, err := w.ListWallets()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	var (
+		balances = make(map[string]decimal.Decimal, len(infos))
+		failed   = make(map[string]error)
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
+
+	limiter := newAdaptiveLimiter(syncConcurrency)
+	for _, info := range infos {
+		if err := limiter.Acquire(ctx); err != nil {
+			return nil, fmt.Errorf("failed to acquire request slot: %w", err)
+		}
+
+		info := info // pin
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer limiter.Release()
+
+			balance, err := fetchBalanceOf(ctx, client, info.PublicKey, limiter)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed[info.Alias] = err
+				return
+			}
+			balances[info.Alias] = balance
+		}()
+	}
+	wg.Wait()
+
+	if len(balances) > 0 {
+		if err := w.KeyOps.UpdateBalances(balances, time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to save balances: %w", err)
+		}
+	}
+
+	synced := make([]SyncedBalance, 0, len(balances))
+	for alias, balance := range balances {
+		synced = append(synced, SyncedBalance{Alias: alias, SOL: balance})
+	}
+	sort.Slice(synced, func(i, j int) bool { return synced[i].Alias < synced[j].Alias })
+
+	if len(failed) > 0 {
+		return synced, &SyncError{Failed: failed}
+	}
+	return synced, nil
+}
+
+// fetchBalanceOf fetches the finalized SOL balance of an address, slowing limiter down while the
+// node is rate limiting.
+func fetchBalanceOf(ctx context.Context, client ClientInterface, address string, limiter *adaptiveLimiter) (decimal.Decimal, error) {
+	publicKey, err := solana.PublicKeyFromBase58(address)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	var balance *rpc.GetBalanceResult
+	err = withRetry(ctx, DefaultRetryPolicy, func(err error) {
+		if isRateLimited(err) {
+			limiter.Throttle()
+		}
+	}, func(ctx context.Context) error {
+		balance, err = client.GetBalance(ctx, publicKey, rpc.CommitmentFinalized)
+		return err
+	})
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("failed to fetch balance: %w", err)
+	}
+
+	return LamportsToSOL(balance.Value), nil
+}
+
+// cacheBalance stores a balance that was just fetched for a stored wallet, so listings show it. A
+// failure to store it, for example in read-only mode, does not fail the query.
+func (w *WalletConfig) cacheBalance(alias string, balance decimal.Decimal) {
+	keyOps, ok := w.KeyOps.(*KeyOps)
+	if !ok || w.Wallet != nil {
+		return
+	}
+
+	info, err := keyOps.GetWalletInfo(alias)
+	if err != nil {
+		return
+	}
+	_ = keyOps.UpdateBalances(map[string]decimal.Decimal{info.Alias: balance}, time.Now())
+}
+
+// syncAge describes how long ago a balance was synced, such as "synced 2h ago".
+func syncAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "synced just now"
+	case age < time.Hour:
+		return fmt.Sprintf("synced %dm ago", int(age/time.Minute))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("synced %dh ago", int(age/time.Hour))
+	default:
+		return fmt.Sprintf("synced %dd ago", int(age/(24*time.Hour)))
+	}
+}
@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncBalances(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	defer func(policy RetryPolicy) { DefaultRetryPolicy = policy }(DefaultRetryPolicy)
+	DefaultRetryPolicy = fastRetries
+
+	main, savings, broken := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	files := newMemFiles()
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		ActiveAlias: "main",
+		Wallets: map[string]Wallet{
+			"main":    {PrivateKey: "main-key", PublicKey: main.String(), Balance: decimal.Zero},
+			"savings": {PublicKey: savings.String(), WatchOnly: true, Balance: decimal.Zero},
+			"broken":  {PrivateKey: "broken-key", PublicKey: broken.String(), Balance: decimal.NewFromInt(7)},
+		},
+	})))
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(_ context.Context, account solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				switch account {
+				case main:
+					return &rpc.GetBalanceResult{Value: 1_500_000_000}, nil
+				case savings:
+					return &rpc.GetBalanceResult{Value: 20_000_000_000}, nil
+				}
+				return nil, errors.New("node unreachable")
+			},
+		}
+	}
+
+	keyOps := &KeyOps{FileReader: files, FileWriter: files, Rates: StaticRate(decimal.NewFromInt(100))}
+	wc := &WalletConfig{KeyOps: keyOps, Network: Devnet, Rates: StaticRate(decimal.NewFromInt(100))}
+
+	before := time.Now()
+	synced, err := wc.SyncBalances(context.Background())
+
+	// The failed wallet is reported; the others are still saved.
+	var syncErr *SyncError
+	assert.True(t, errors.As(err, &syncErr))
+	assert.Contains(t, syncErr.Failed, "broken")
+	assert.Len(t, syncErr.Failed, 1)
+	assert.Contains(t, err.Error(), "could not refresh the balance of broken (failed to fetch balance: node unreachable)")
+	assert.Len(t, synced, 2)
+	assert.Equal(t, "main", synced[0].Alias)
+	assert.Equal(t, "1.5", synced[0].SOL.String())
+	assert.Equal(t, "savings", synced[1].Alias)
+	assert.Equal(t, "20", synced[1].SOL.String())
+
+	data, err := keyOps.readWalletData(KeyFileName)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.5", data.Wallets["main"].Balance.String())
+	assert.Equal(t, "20", data.Wallets["savings"].Balance.String())
+	assert.False(t, data.Wallets["main"].LastSynced.Before(before.UTC().Truncate(time.Second)))
+	assert.Equal(t, "7", data.Wallets["broken"].Balance.String())
+	assert.Nil(t, data.Wallets["broken"].LastSynced)
+
+	aliases, _, err := keyOps.PrintAllKeys()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"main (Active) // BAL - (€150.00, synced just now)",
+		"savings (watch-only) // BAL - (€2,000.00, synced just now)",
+		"broken // BAL - (not synced)",
+	}, aliases)
+
+	// A balance query refreshes the cache of the wallet it asked about.
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(context.Context, solana.PublicKey, rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				return &rpc.GetBalanceResult{Value: 2_000_000_000}, nil
+			},
+		}
+	}
+	_, err = wc.GetWalletBalance("")
+	assert.NoError(t, err)
+
+	data, err = keyOps.readWalletData(KeyFileName)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", data.Wallets["main"].Balance.String())
+	assert.Equal(t, "20", data.Wallets["savings"].Balance.String())
+}
+
+func TestSyncAge(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{age: 30 * time.Second, want: "synced just now"},
+		{age: 5 * time.Minute, want: "synced 5m ago"},
+		{age: 2*time.Hour + 59*time.Minute, want: "synced 2h ago"},
+		{age: 30 * time.Hour, want: "synced 30h ago"},
+		{age: 72 * time.Hour, want: "synced 3d ago"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, syncAge(tt.age))
+	}
+}
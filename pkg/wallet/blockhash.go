@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// maxBlockhashAttempts is how many times a transfer is signed with a fresh blockhash before a send
+// gives up because every blockhash expired first.
+const maxBlockhashAttempts = 3
+
+var ErrBlockhashExpired = errors.New("blockhash expired before the transaction landed")
+
+// isBlockhashExpired reports whether a send failed because its blockhash is too old or unknown to
+// the node, so signing again with a fresh one may succeed.
+func isBlockhashExpired(err error) bool {
+	if errors.Is(err, ErrBlockhashExpired) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "blockhash not found") || strings.Contains(message, "blockhashnotfound") ||
+		strings.Contains(message, "block height exceeded")
+}
+
+// fetchSignatureStatus returns the status of sig, or nil when the cluster does not know it (yet).
+func fetchSignatureStatus(ctx context.Context, client ClientInterface, sig solana.Signature, searchHistory bool) (*rpc.SignatureStatusesResult, error) {
+	statuses, err := client.GetSignatureStatuses(ctx, searchHistory, sig)
+	if err != nil {
+		return nil, fmt.Errorf("get signature status: %w", err)
+	}
+
+	if len(statuses.Value) == 0 {
+		return nil, nil
+	}
+	return statuses.Value[0], nil
+}
+
+// blockhashExpired reports whether the chain has passed lastValidBlockHeight while sig is still
+// unknown. The status is checked again after the height, because the transaction may have landed in
+// one of the last valid blocks between the two requests.
+func blockhashExpired(ctx context.Context, client ClientInterface, sig solana.Signature, lastValidBlockHeight uint64) (bool, error) {
+	height, err := client.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return false, fmt.Errorf("get block height: %w", err)
+	}
+	if height <= lastValidBlockHeight {
+		return false, nil
+	}
+
+	status, err := fetchSignatureStatus(ctx, client, sig, true)
+	if err != nil {
+		return false, err
+	}
+	return status == nil, nil
+}
+
+// landedAfterTimeout asks once more for the status of a transfer whose confirmation timed out, so a
+// transfer that went through is not reported as failed. It returns true when the transfer reached at
+// least the confirmed level.
+func landedAfterTimeout(ctx context.Context, client ClientInterface, sig solana.Signature, observe func(rpc.ConfirmationStatusType)) (bool, error) {
+	status, err := fetchSignatureStatus(ctx, client, sig, true)
+	if err != nil || status == nil {
+		return false, err
+	}
+	if status.Err != nil {
+		return false, fmt.Errorf("confirmed transaction with execution error: %v", status.Err)
+	}
+
+	observe(status.ConfirmationStatus)
+	return status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized, nil
+}
@@ -0,0 +1,235 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCluster is an RPC client for sends. Each blockhash it hands out is valid for validFor blocks;
+// every status poll advances the block height by one. land decides per attempt whether a submitted
+// transaction ever shows up, and reject whether the node refuses it outright.
+type fakeCluster struct {
+	mu       sync.Mutex
+	height   uint64
+	validFor uint64
+	land     func(attempt int) rpc.ConfirmationStatusType
+	reject   func(attempt int) error
+
+	blockhashes []solana.Hash
+	sent        map[solana.Signature]int
+}
+
+func (c *fakeCluster) client() *MockClientInterface {
+	c.sent = make(map[solana.Signature]int)
+
+	return &MockClientInterface{
+		GetLatestBlockhashFn: func(context.Context, rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			hash := solana.Hash{byte(len(c.blockhashes) + 1)}
+			c.blockhashes = append(c.blockhashes, hash)
+			return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: hash, LastValidBlockHeight: c.height + c.validFor}}, nil
+		},
+		SendTransactionWithOptsFn: func(_ context.Context, tx *solana.Transaction, _ rpc.TransactionOpts) (solana.Signature, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			attempt := len(c.blockhashes)
+			if c.reject != nil {
+				if err := c.reject(attempt); err != nil {
+					return solana.Signature{}, err
+				}
+			}
+			c.sent[tx.Signatures[0]] = attempt
+			return tx.Signatures[0], nil
+		},
+		GetBlockHeightFn: func(context.Context, rpc.CommitmentType) (uint64, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.height, nil
+		},
+		GetSignatureStatusesFn: func(_ context.Context, _ bool, sigs ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.height++
+			attempt, ok := c.sent[sigs[0]]
+			if !ok {
+				return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{nil}}, nil
+			}
+			status := c.land(attempt)
+			if status == "" {
+				return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{nil}}, nil
+			}
+			return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{{ConfirmationStatus: status}}}, nil
+		},
+	}
+}
+
+func TestSendLamportsRetriesExpiredBlockhash(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	defer func(interval time.Duration) { confirmPollInterval = interval }(confirmPollInterval)
+	confirmPollInterval = time.Millisecond
+
+	notFound := errors.New(`(*jsonrpc.RPCError)(0xc0001)({Code: -32002, Message: "Transaction simulation failed: Blockhash not found"})`)
+
+	tests := []struct {
+		name             string
+		land             func(attempt int) rpc.ConfirmationStatusType
+		reject           func(attempt int) error
+		expectedSends    int
+		expectedStatuses []PendingStatus
+		expectedErr      error
+	}{
+		{
+			name:             "Lands First Time",
+			land:             func(int) rpc.ConfirmationStatusType { return rpc.ConfirmationStatusFinalized },
+			expectedSends:    1,
+			expectedStatuses: []PendingStatus{PendingConfirmed},
+		},
+		{
+			name: "Expires While Confirming",
+			land: func(attempt int) rpc.ConfirmationStatusType {
+				if attempt == 1 {
+					return ""
+				}
+				return rpc.ConfirmationStatusFinalized
+			},
+			expectedSends:    2,
+			expectedStatuses: []PendingStatus{PendingExpired, PendingConfirmed},
+		},
+		{
+			name: "Rejected By The Node",
+			land: func(int) rpc.ConfirmationStatusType { return rpc.ConfirmationStatusFinalized },
+			reject: func(attempt int) error {
+				if attempt == 1 {
+					return notFound
+				}
+				return nil
+			},
+			expectedSends:    1,
+			expectedStatuses: []PendingStatus{PendingExpired, PendingConfirmed},
+		},
+		{
+			name:             "Every Blockhash Expires",
+			land:             func(int) rpc.ConfirmationStatusType { return "" },
+			expectedSends:    maxBlockhashAttempts,
+			expectedStatuses: []PendingStatus{PendingExpired, PendingExpired, PendingExpired},
+			expectedErr:      ErrBlockhashExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &fakeCluster{height: 100, validFor: 5, land: tt.land, reject: tt.reject}
+			client := cluster.client()
+			newRPCClient = func(string) ClientInterface { return client }
+
+			files := newMemFiles()
+			wc := &WalletConfig{Wallet: solana.NewWallet(), Network: Devnet, Pending: &PendingStore{FileReader: files, FileWriter: files}}
+
+			sig, err := wc.sendLamports(context.Background(), 1_000, solana.NewWallet().PublicKey(), SendOptions{})
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, len(tt.expectedStatuses), cluster.sent[solana.MustSignatureFromBase58(sig)])
+			}
+			assert.Len(t, cluster.sent, tt.expectedSends)
+			assert.Len(t, cluster.blockhashes, len(tt.expectedStatuses))
+
+			records, err := wc.ListPending()
+			assert.NoError(t, err)
+			statuses := make(map[PendingStatus]int)
+			for _, record := range records {
+				statuses[record.Status]++
+			}
+			expected := make(map[PendingStatus]int)
+			for _, status := range tt.expectedStatuses {
+				expected[status]++
+			}
+			assert.Equal(t, expected, statuses)
+		})
+	}
+}
+
+func TestSendLamportsChecksStatusAfterTimeout(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	defer func(interval, timeout time.Duration) {
+		confirmPollInterval, confirmTimeout = interval, timeout
+	}(confirmPollInterval, confirmTimeout)
+	confirmPollInterval = time.Millisecond
+	confirmTimeout = 20 * time.Millisecond
+
+	tests := []struct {
+		name        string
+		late        rpc.ConfirmationStatusType
+		expectedErr error
+	}{
+		{name: "Confirmed After Timeout", late: rpc.ConfirmationStatusConfirmed},
+		{name: "Still Processing", late: rpc.ConfirmationStatusProcessed, expectedErr: ErrConfirmTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The node never reports the transfer while waiting, only when asked with the history search.
+			client := &MockClientInterface{
+				GetLatestBlockhashFn: func(context.Context, rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+					return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}, LastValidBlockHeight: 1_000}}, nil
+				},
+				SendTransactionWithOptsFn: func(_ context.Context, tx *solana.Transaction, _ rpc.TransactionOpts) (solana.Signature, error) {
+					return tx.Signatures[0], nil
+				},
+				GetBlockHeightFn: func(context.Context, rpc.CommitmentType) (uint64, error) {
+					return 10, nil
+				},
+				GetSignatureStatusesFn: func(_ context.Context, searchHistory bool, _ ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+					if !searchHistory {
+						return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{nil}}, nil
+					}
+					return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{{ConfirmationStatus: tt.late}}}, nil
+				},
+			}
+			newRPCClient = func(string) ClientInterface { return client }
+
+			files := newMemFiles()
+			wc := &WalletConfig{Wallet: solana.NewWallet(), Network: Devnet, Pending: &PendingStore{FileReader: files, FileWriter: files}}
+
+			_, err := wc.sendLamports(context.Background(), 1_000, solana.NewWallet().PublicKey(), SendOptions{})
+			records, listErr := wc.ListPending()
+			assert.NoError(t, listErr)
+			assert.Len(t, records, 1)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Equal(t, PendingSubmitted, records[0].Status)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, PendingConfirmed, records[0].Status)
+		})
+	}
+}
+
+func TestIsBlockhashExpired(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{err: ErrBlockhashExpired, expected: true},
+		{err: errors.New("Transaction simulation failed: Blockhash not found"), expected: true},
+		{err: errors.New("transaction block height exceeded"), expected: true},
+		{err: errors.New("insufficient funds for rent"), expected: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, isBlockhashExpired(tt.err), tt.err.Error())
+	}
+}
@@ -0,0 +1,58 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// coinGeckoPriceURL is the CoinGecko simple price endpoint; the currency code is appended.
+const coinGeckoPriceURL = "https://api.coingecko.com/api/v3/simple/price?ids=solana&vs_currencies="
+
+// CoinGeckoRates is the CoinGecko simple price API as a RateProvider, used when Kraken is unavailable.
+type CoinGeckoRates struct {
+	// Client defaults to a client with a timeout.
+	Client *http.Client
+	// URL is the price endpoint the currency is appended to; it defaults to CoinGecko's.
+	URL string
+}
+
+func (c *CoinGeckoRates) Name() string {
+	return "CoinGecko"
+}
+
+// SOLRate fetches the current price of SOL in currency from CoinGecko.
+func (c *CoinGeckoRates) SOLRate(ctx context.Context, currency Currency) (decimal.Decimal, error) {
+	url := c.URL
+	if url == "" {
+		url = coinGeckoPriceURL
+	}
+
+	code := strings.ToLower(currency.String())
+	body, err := getRateAPI(ctx, c.Client, url+code)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return parseCoinGeckoPrice(body, code)
+}
+
+// parseCoinGeckoPrice returns the SOL price in the lower-case currency code from a simple price response,
+// such as {"solana":{"eur":140.22}}.
+func parseCoinGeckoPrice(body []byte, code string) (decimal.Decimal, error) {
+	var response map[string]map[string]decimal.Decimal
+	if err := json.Unmarshal(body, &response); err != nil {
+		return decimal.Zero, err
+	}
+
+	price, ok := response["solana"][code]
+	if !ok || !price.IsPositive() {
+		return decimal.Zero, fmt.Errorf("no SOL price in %s in the response", strings.ToUpper(code))
+	}
+
+	return price, nil
+}
@@ -0,0 +1,166 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// lamportsPerSignature is the base fee the cluster charges for each transaction signature.
+const lamportsPerSignature = 5000
+
+var ErrNothingToConsolidate = errors.New("no other wallets to consolidate")
+
+// ConsolidateOptions controls how wallets are swept into one destination.
+type ConsolidateOptions struct {
+	// Destination is the alias of a stored wallet or a base58 address.
+	Destination string
+	// MinLamports skips wallets whose spendable balance is below this amount.
+	MinLamports uint64
+}
+
+// SweepResult is the outcome of sweeping a single wallet.
+type SweepResult struct {
+	Alias     string
+	From      string
+	Lamports  uint64
+	Signature string
+	// Skipped explains why the wallet was not swept, empty when a sweep was attempted.
+	Skipped string
+	Err     error
+}
+
+// ConsolidateReport summarizes a consolidation run.
+type ConsolidateReport struct {
+	Destination string
+	Results     []SweepResult
+	// Total is the number of lamports that arrived at the destination.
+	Total uint64
+}
+
+// Consolidate sweeps the spendable balance of every stored wallet into a single destination.
+// Each sweep is signed by the wallet's own key, and a failing wallet does not stop the others.
+func (w *WalletConfig) Consolidate(ctx context.Context, opts ConsolidateOptions) (*ConsolidateReport, error) {
+	destination, err := w.resolveRecipient(opts.Destination)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkRecipientAllowed(destination); err != nil {
+		return nil, err
+	}
+
+	aliases, err := w.KeyOps.ListAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	report := &ConsolidateReport{Destination: destination.String()}
+	for _, alias := range aliases {
+		result := w.sweep(ctx, client, alias, destination, opts.MinLamports)
+		if result.Skipped == "" && result.Err == nil {
+			report.Total += result.Lamports
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if len(report.Results) == 0 {
+		return nil, ErrNothingToConsolidate
+	}
+
+	return report, nil
+}
+
+// sweep moves everything but the fee from one stored wallet to the destination.
+func (w *WalletConfig) sweep(ctx context.Context, client ClientInterface, alias string, destination solana.PublicKey, minLamports uint64) SweepResult {
+	result := SweepResult{Alias: alias}
+
+	publicKey, err := w.KeyOps.GetPublicKeyByAlias(alias)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.From = publicKey
+
+	if publicKey == destination.String() {
+		result.Skipped = "destination"
+		return result
+	}
+
+	storedKey, err := w.KeyOps.GetPrivateKeyByAlias(alias)
+	if err != nil && !errors.Is(err, ErrWatchOnlyWallet) {
+		result.Err = err
+		return result
+	}
+	if storedKey == "" {
+		result.Skipped = "watch-only"
+		return result
+	}
+
+	key, err := getPrivateKeyFromSolCLICompStr(storedKey)
+	if err != nil {
+		result.Err = fmt.Errorf("invalid stored key: %w", err)
+		return result
+	}
+	from := solana.PrivateKey(key)
+
+	balance, err := client.GetBalance(ctx, from.PublicKey(), rpc.CommitmentFinalized)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to fetch balance: %w", err)
+		return result
+	}
+
+	if balance.Value <= lamportsPerSignature || balance.Value-lamportsPerSignature < minLamports {
+		result.Skipped = "below threshold"
+		return result
+	}
+	result.Lamports = balance.Value - lamportsPerSignature
+
+	recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to fetch blockhash: %w", err)
+		return result
+	}
+
+	tx, err := buildTransfer(from, destination, result.Lamports, recent.Value.Blockhash, solana.PublicKey{}, transferExtras{})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	w.recordKeyUsage(from.PublicKey(), SignedSend, tx.Signatures[0])
+
+	sig, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to submit sweep: %w", err)
+		return result
+	}
+	result.Signature = sig.String()
+
+	if err := awaitSignature(ctx, client, sig); err != nil {
+		result.Err = err
+	}
+
+	return result
+}
+
+// resolveRecipient turns the alias of a stored wallet or a base58 address into a public key.
+func (w *WalletConfig) resolveRecipient(aliasOrAddress string) (solana.PublicKey, error) {
+	if publicKey, err := w.KeyOps.GetPublicKeyByAlias(aliasOrAddress); err == nil {
+		return solana.PublicKeyFromBase58(publicKey)
+	}
+
+	publicKey, err := solana.PublicKeyFromBase58(aliasOrAddress)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("%q is neither a wallet alias nor a valid address", aliasOrAddress)
+	}
+
+	return publicKey, nil
+}
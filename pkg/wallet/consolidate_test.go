@@ -0,0 +1,93 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsolidate(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	files := newMemFiles()
+	keyOps := &KeyOps{FileReader: files, FileWriter: files}
+
+	wallets := map[string]*solana.Wallet{
+		"main":   solana.NewWallet(),
+		"dust":   solana.NewWallet(),
+		"tester": solana.NewWallet(),
+		"broken": solana.NewWallet(),
+		"empty":  solana.NewWallet(),
+	}
+	for alias, w := range wallets {
+		assert.NoError(t, keyOps.WriteKeyToFile(alias, []byte(w.PrivateKey), w.PublicKey().String()))
+	}
+
+	balances := map[solana.PublicKey]uint64{
+		wallets["main"].PublicKey():   5_000_000_000,
+		wallets["dust"].PublicKey():   9_000,
+		wallets["tester"].PublicKey(): 2_000_000,
+		wallets["broken"].PublicKey(): 3_000_000,
+		wallets["empty"].PublicKey():  0,
+	}
+
+	var submitted []*solana.Transaction
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(ctx context.Context, publicKey solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				return &rpc.GetBalanceResult{Value: balances[publicKey]}, nil
+			},
+			GetLatestBlockhashFn: func(ctx context.Context, _ rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+				return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{7}}}, nil
+			},
+			SendTransactionFn: func(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+				if tx.Message.AccountKeys[0] == wallets["broken"].PublicKey() {
+					return solana.Signature{}, errors.New("node is unhealthy")
+				}
+				submitted = append(submitted, tx)
+				return tx.Signatures[0], nil
+			},
+			GetSignatureStatusesFn: func(ctx context.Context, _ bool, _ ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+				return signatureStatus(true), nil
+			},
+		}
+	}
+
+	wc := &WalletConfig{KeyOps: keyOps, Network: Devnet}
+	report, err := wc.Consolidate(context.Background(), ConsolidateOptions{Destination: "main", MinLamports: 10_000})
+	assert.NoError(t, err)
+
+	results := make(map[string]SweepResult)
+	for _, result := range report.Results {
+		results[result.Alias] = result
+	}
+
+	assert.Equal(t, wallets["main"].PublicKey().String(), report.Destination)
+	assert.Equal(t, "destination", results["main"].Skipped)
+	assert.Equal(t, "below threshold", results["dust"].Skipped)
+	assert.Equal(t, "below threshold", results["empty"].Skipped)
+	assert.Error(t, results["broken"].Err, "a failed sweep is reported")
+	assert.NoError(t, results["tester"].Err, "a failed sweep does not stop the others")
+	assert.Equal(t, uint64(2_000_000-lamportsPerSignature), results["tester"].Lamports)
+	assert.Equal(t, uint64(2_000_000-lamportsPerSignature), report.Total)
+
+	// Each sweep is signed by the wallet being swept.
+	assert.Len(t, submitted, 1)
+	assert.Equal(t, wallets["tester"].PublicKey(), submitted[0].Message.AccountKeys[0])
+	assert.NoError(t, submitted[0].VerifySignatures())
+}
+
+func TestConsolidateUnknownDestination(t *testing.T) {
+	files := newMemFiles()
+	keyOps := &KeyOps{FileReader: files, FileWriter: files}
+	w := solana.NewWallet()
+	assert.NoError(t, keyOps.WriteKeyToFile("main", []byte(w.PrivateKey), w.PublicKey().String()))
+
+	wc := &WalletConfig{KeyOps: keyOps, Network: Devnet}
+	_, err := wc.Consolidate(context.Background(), ConsolidateOptions{Destination: "nope"})
+	assert.Error(t, err)
+}
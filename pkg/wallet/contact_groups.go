@@ -0,0 +1,250 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrGroupNotFound = errors.New("contact group not found")
+
+// SkipKeyword as an override amount leaves a member out of a group send.
+const SkipKeyword = "skip"
+
+// ContactGroup is a named set of contacts that are paid together, such as "team" or "family".
+type ContactGroup struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// AddGroupMembers adds contacts to the group name, creating the group when it does not exist yet.
+// Every member must be a stored contact; members already in the group are kept once.
+func (k *KeyOps) AddGroupMembers(name string, members ...string) error {
+	if err := validContactName(name); err != nil {
+		return fmt.Errorf("invalid group name: %w", err)
+	}
+	if len(members) == 0 {
+		return errors.New("name at least one contact to add to the group")
+	}
+
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if _, ok := data.Contacts[member]; !ok {
+			return fmt.Errorf("%w: %s; add it with `wallet contacts add` first", ErrContactNotFound, member)
+		}
+	}
+	if data.Groups == nil {
+		data.Groups = make(map[string][]string)
+	}
+	data.Groups[name] = uniqueSorted(append(data.Groups[name], members...))
+
+	return k.writeWalletData("group", data)
+}
+
+// RemoveGroupMembers removes contacts from the group name and returns the members left. Without
+// members, or when none are left, the group is deleted.
+func (k *KeyOps) RemoveGroupMembers(name string, members ...string) ([]string, error) {
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	current, ok := data.Groups[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, name)
+	}
+
+	var left []string
+	if len(members) > 0 {
+		for _, member := range members {
+			if !contains(current, member) {
+				return nil, fmt.Errorf("%s is not a member of %s", member, name)
+			}
+		}
+		for _, member := range current {
+			if !contains(members, member) {
+				left = append(left, member)
+			}
+		}
+	}
+
+	if len(left) == 0 {
+		delete(data.Groups, name)
+	} else {
+		data.Groups[name] = left
+	}
+	return left, k.writeWalletData("group", data)
+}
+
+// Groups returns the stored contact groups sorted by name. A missing key file has none.
+func (k *KeyOps) Groups() ([]ContactGroup, error) {
+	fileExists, err := k.IsKeyFilePresent()
+	if err != nil || !fileExists {
+		return nil, err
+	}
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]ContactGroup, 0, len(data.Groups))
+	for name, members := range data.Groups {
+		groups = append(groups, ContactGroup{Name: name, Members: members})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+// removeFromGroups drops a contact from every group, deleting the groups it was the last member of.
+func (d *WalletData) removeFromGroups(contact string) {
+	for name, members := range d.Groups {
+		var left []string
+		for _, member := range members {
+			if member != contact {
+				left = append(left, member)
+			}
+		}
+		if len(left) == 0 {
+			delete(d.Groups, name)
+		} else {
+			d.Groups[name] = left
+		}
+	}
+}
+
+// uniqueSorted sorts names and drops repeats.
+func uniqueSorted(names []string) []string {
+	sort.Strings(names)
+	unique := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddGroupMembers adds contacts to a group, creating it when needed.
+func (w *WalletConfig) AddGroupMembers(name string, members ...string) error {
+	return w.KeyOps.AddGroupMembers(name, members...)
+}
+
+// RemoveGroupMembers removes contacts from a group, or the whole group without members.
+func (w *WalletConfig) RemoveGroupMembers(name string, members ...string) ([]string, error) {
+	return w.KeyOps.RemoveGroupMembers(name, members...)
+}
+
+// Groups returns the stored contact groups sorted by name.
+func (w *WalletConfig) Groups() ([]ContactGroup, error) {
+	return w.KeyOps.Groups()
+}
+
+// ExpandGroup resolves the members of the group name to their addresses, in the order of the group.
+func (w *WalletConfig) ExpandGroup(name string) ([]Recipient, error) {
+	groups, err := w.Groups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read groups: %w", err)
+	}
+	contacts, err := w.Contacts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contacts: %w", err)
+	}
+	addresses := make(map[string]string, len(contacts))
+	for _, contact := range contacts {
+		addresses[contact.Name] = contact.Address
+	}
+
+	for _, group := range groups {
+		if group.Name != name {
+			continue
+		}
+		recipients := make([]Recipient, 0, len(group.Members))
+		for _, member := range group.Members {
+			address, ok := addresses[member]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s, a member of %s", ErrContactNotFound, member, name)
+			}
+			recipients = append(recipients, Recipient{Address: address, Contact: member})
+		}
+		return recipients, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, name)
+}
+
+// MergeAmounts pays every recipient amount, except those overrides names by contact name or
+// address: they get their own amount instead, or are left out when it is SkipKeyword. A recipient
+// listed twice is paid once. An override that names no recipient fails, so a typo never goes
+// unnoticed.
+func MergeAmounts(recipients []Recipient, amount string, overrides map[string]string) ([]MultiSendItem, error) {
+	used := make(map[string]bool, len(overrides))
+	seen := make(map[string]bool, len(recipients))
+	items := make([]MultiSendItem, 0, len(recipients))
+	for _, recipient := range recipients {
+		if seen[recipient.Address] {
+			continue
+		}
+		seen[recipient.Address] = true
+
+		itemAmount := amount
+		for _, key := range []string{recipient.Contact, recipient.Address} {
+			if override, ok := overrides[key]; ok && key != "" {
+				itemAmount, used[key] = override, true
+				break
+			}
+		}
+		if strings.EqualFold(itemAmount, SkipKeyword) {
+			continue
+		}
+		if itemAmount == "" {
+			return nil, fmt.Errorf("no amount for %s", recipient)
+		}
+		items = append(items, MultiSendItem{Recipient: recipient, Amount: itemAmount})
+	}
+
+	for key := range overrides {
+		if !used[key] {
+			return nil, fmt.Errorf("%s is not one of the recipients", key)
+		}
+	}
+	if len(items) == 0 {
+		return nil, errors.New("every recipient was skipped")
+	}
+	return items, nil
+}
+
+// ParseOverride splits an override given as name=amount.
+func ParseOverride(s string) (string, string, error) {
+	name, amount, ok := strings.Cut(s, "=")
+	name, amount = strings.TrimSpace(name), strings.TrimSpace(amount)
+	if !ok || name == "" || amount == "" {
+		return "", "", fmt.Errorf("invalid override %q, expected name=amount or name=%s", s, SkipKeyword)
+	}
+	return name, amount, nil
+}
@@ -0,0 +1,230 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// groupKeyOps returns key operations on a key file with the contacts alice, bob and carol.
+func groupKeyOps(t *testing.T, contacts map[string]string) *KeyOps {
+	files := newMemFiles()
+	main := solana.NewWallet()
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		ActiveAlias: "main",
+		Wallets:     map[string]Wallet{"main": {PublicKey: main.PublicKey().String(), PrivateKey: main.PrivateKey.String()}},
+		Contacts:    contacts,
+	})))
+	return &KeyOps{FileReader: files, FileWriter: files}
+}
+
+func TestGroupMembership(t *testing.T) {
+	contacts := map[string]string{
+		"alice": solana.NewWallet().PublicKey().String(),
+		"bob":   solana.NewWallet().PublicKey().String(),
+		"carol": solana.NewWallet().PublicKey().String(),
+	}
+	keyOps := groupKeyOps(t, contacts)
+
+	assert.NoError(t, keyOps.AddGroupMembers("team", "bob", "alice"))
+	assert.NoError(t, keyOps.AddGroupMembers("team", "carol", "alice"))
+	assert.NoError(t, keyOps.AddGroupMembers("family", "carol"))
+	assert.ErrorIs(t, keyOps.AddGroupMembers("team", "dave"), ErrContactNotFound)
+	assert.Error(t, keyOps.AddGroupMembers("my team", "alice"))
+	assert.Error(t, keyOps.AddGroupMembers("team"))
+
+	groups, err := keyOps.Groups()
+	assert.NoError(t, err)
+	assert.Equal(t, []ContactGroup{
+		{Name: "family", Members: []string{"carol"}},
+		{Name: "team", Members: []string{"alice", "bob", "carol"}},
+	}, groups)
+
+	left, err := keyOps.RemoveGroupMembers("team", "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "carol"}, left)
+	_, err = keyOps.RemoveGroupMembers("team", "bob")
+	assert.Error(t, err)
+	_, err = keyOps.RemoveGroupMembers("friends")
+	assert.ErrorIs(t, err, ErrGroupNotFound)
+
+	// A removed contact leaves its groups, and a group left empty is deleted.
+	_, err = keyOps.RemoveContact("carol")
+	assert.NoError(t, err)
+	groups, err = keyOps.Groups()
+	assert.NoError(t, err)
+	assert.Equal(t, []ContactGroup{{Name: "team", Members: []string{"alice"}}}, groups)
+
+	// Without members the whole group is removed.
+	left, err = keyOps.RemoveGroupMembers("team")
+	assert.NoError(t, err)
+	assert.Empty(t, left)
+	groups, err = keyOps.Groups()
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestExpandGroup(t *testing.T) {
+	alice, bob := solana.NewWallet().PublicKey().String(), solana.NewWallet().PublicKey().String()
+	keyOps := groupKeyOps(t, map[string]string{"alice": alice, "bob": bob})
+	assert.NoError(t, keyOps.AddGroupMembers("team", "alice", "bob"))
+	wc := &WalletConfig{KeyOps: keyOps}
+
+	recipients, err := wc.ExpandGroup("team")
+	assert.NoError(t, err)
+	assert.Equal(t, []Recipient{{Address: alice, Contact: "alice"}, {Address: bob, Contact: "bob"}}, recipients)
+
+	_, err = wc.ExpandGroup("family")
+	assert.ErrorIs(t, err, ErrGroupNotFound)
+}
+
+func TestMergeAmounts(t *testing.T) {
+	alice := Recipient{Address: solana.NewWallet().PublicKey().String(), Contact: "alice"}
+	bob := Recipient{Address: solana.NewWallet().PublicKey().String(), Contact: "bob"}
+	plain := Recipient{Address: solana.NewWallet().PublicKey().String()}
+
+	tests := []struct {
+		name        string
+		recipients  []Recipient
+		overrides   map[string]string
+		expected    []MultiSendItem
+		expectedErr string
+	}{
+		{
+			name:       "Same Amount For Everyone",
+			recipients: []Recipient{alice, bob},
+			expected:   []MultiSendItem{{Recipient: alice, Amount: "25eur"}, {Recipient: bob, Amount: "25eur"}},
+		},
+		{
+			name:       "Override By Name",
+			recipients: []Recipient{alice, bob},
+			overrides:  map[string]string{"bob": "30eur"},
+			expected:   []MultiSendItem{{Recipient: alice, Amount: "25eur"}, {Recipient: bob, Amount: "30eur"}},
+		},
+		{
+			name:       "Override By Address",
+			recipients: []Recipient{alice, plain},
+			overrides:  map[string]string{plain.Address: "0.1sol"},
+			expected:   []MultiSendItem{{Recipient: alice, Amount: "25eur"}, {Recipient: plain, Amount: "0.1sol"}},
+		},
+		{
+			name:       "Skip A Member",
+			recipients: []Recipient{alice, bob},
+			overrides:  map[string]string{"alice": "SKIP"},
+			expected:   []MultiSendItem{{Recipient: bob, Amount: "25eur"}},
+		},
+		{
+			name:       "Repeated Recipient Is Paid Once",
+			recipients: []Recipient{alice, bob, alice},
+			expected:   []MultiSendItem{{Recipient: alice, Amount: "25eur"}, {Recipient: bob, Amount: "25eur"}},
+		},
+		{name: "Override Of A Stranger", recipients: []Recipient{alice}, overrides: map[string]string{"carol": "1"}, expectedErr: "carol is not one of the recipients"},
+		{name: "Everyone Skipped", recipients: []Recipient{alice}, overrides: map[string]string{"alice": "skip"}, expectedErr: "every recipient was skipped"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := MergeAmounts(tt.recipients, "25eur", tt.overrides)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, items)
+		})
+	}
+}
+
+func TestParseOverride(t *testing.T) {
+	name, amount, err := ParseOverride(" bob = 30eur ")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", name)
+	assert.Equal(t, "30eur", amount)
+
+	for _, invalid := range []string{"bob", "=30", "bob="} {
+		_, _, err := ParseOverride(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSendFundsMulti(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	sender := solana.NewWallet()
+	alice, bob := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	items := []MultiSendItem{
+		{Recipient: Recipient{Address: alice.String(), Contact: "alice"}, Amount: "0.25"},
+		{Recipient: Recipient{Address: bob.String(), Contact: "bob"}, Amount: "0.5"},
+	}
+
+	tests := []struct {
+		name        string
+		balance     uint64
+		expectedErr error
+	}{
+		{name: "Every Transfer Is Sent", balance: solana.LAMPORTS_PER_SOL},
+		// Each transfer is affordable on its own, but not both together.
+		{name: "Balance Covers Each But Not All", balance: 600_000_000, expectedErr: ErrInsufficientFunds},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			cluster := &quickstartCluster{balances: map[solana.PublicKey]uint64{sender.PublicKey(): tt.balance}, transfers: make(map[solana.Signature][2]solana.PublicKey)}
+			newRPCClient = func(string) ClientInterface { return cluster.client(t) }
+			wc := &WalletConfig{Wallet: sender, Network: Devnet, Pending: &PendingStore{FileReader: files, FileWriter: files}}
+
+			quote, err := wc.PrepareSendMulti(context.Background(), items, SendOptions{Unit: UnitSOL})
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(750_000_000), quote.Total())
+			assert.Equal(t, uint64(10_000), quote.Fees())
+
+			receipts, err := wc.SendFundsMulti(context.Background(), quote, SendOptions{Unit: UnitSOL})
+			assert.NoError(t, err)
+			assert.Len(t, receipts, 2)
+			assert.Equal(t, uint64(250_000_000), cluster.balances[alice])
+			assert.Equal(t, uint64(500_000_000), cluster.balances[bob])
+			assert.Equal(t, tt.balance-750_010_000, cluster.balances[sender.PublicKey()])
+		})
+	}
+}
+
+func TestPrepareSendMultiPriorityFee(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	sender := solana.NewWallet()
+	alice, bob := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	items := []MultiSendItem{
+		{Recipient: Recipient{Address: alice.String(), Contact: "alice"}, Amount: "0.25"},
+		{Recipient: Recipient{Address: bob.String(), Contact: "bob"}, Amount: "0.5"},
+	}
+
+	cluster := &quickstartCluster{balances: map[solana.PublicKey]uint64{sender.PublicKey(): solana.LAMPORTS_PER_SOL}, transfers: make(map[solana.Signature][2]solana.PublicKey)}
+	var lookups []solana.PublicKeySlice
+	newRPCClient = func(string) ClientInterface {
+		client := cluster.client(t)
+		client.GetRecentPrioritizationFeesFn = func(_ context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+			lookups = append(lookups, accounts)
+			return []rpc.PriorizationFeeResult{{PrioritizationFee: 1_000_000}}, nil
+		}
+		return client
+	}
+	wc := &WalletConfig{Wallet: sender, Network: Devnet}
+
+	quote, err := wc.PrepareSendMulti(context.Background(), items, SendOptions{Unit: UnitSOL, PriorityFee: PriorityFee{Auto: true}})
+	assert.NoError(t, err)
+
+	// Each transfer looks up the going rate for its own accounts, and pays for the units it requests.
+	assert.Equal(t, []solana.PublicKeySlice{{sender.PublicKey(), alice}, {sender.PublicKey(), bob}}, lookups)
+	for _, q := range quote.Quotes {
+		assert.Equal(t, uint64(1_000_000), q.ComputeUnitPrice)
+	}
+	assert.Equal(t, uint64(2*1_000), quote.PriorityFees())
+}
@@ -0,0 +1,260 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var ErrContactNotFound = errors.New("contact not found")
+
+// ErrInvalidRecipient is wrapped by errors for a recipient that is neither a contact nor a valid address.
+var ErrInvalidRecipient = errors.New("invalid recipient address")
+
+// Contact is a named recipient address stored in the key file.
+type Contact struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// Recipient is the address a send argument resolved to.
+type Recipient struct {
+	Address string
+	// Contact is the contact name the argument matched, empty for a plain address.
+	Contact string
+	// Ambiguous is set when the argument is both a contact name and a valid address itself. The
+	// contact wins.
+	Ambiguous bool
+}
+
+func (r Recipient) String() string {
+	if r.Contact == "" {
+		return r.Address
+	}
+	return fmt.Sprintf("%s (%s)", r.Contact, r.Address)
+}
+
+// checkNameFree fails when name is already used by a wallet, a contact or a multisig, so their
+// names never collide.
+func (d WalletData) checkNameFree(name string) error {
+	if _, exists := d.Wallets[name]; exists {
+		return fmt.Errorf("alias already exists: %s", name)
+	}
+	if _, exists := d.Contacts[name]; exists {
+		return fmt.Errorf("%s is already the name of a contact", name)
+	}
+	if _, exists := d.Multisigs[name]; exists {
+		return fmt.Errorf("%s is already the name of a multisig", name)
+	}
+	return nil
+}
+
+// checkAddressFree fails when publicKey is already stored as a wallet, so the same key is never
+// kept twice under different aliases.
+func (d WalletData) checkAddressFree(publicKey string) error {
+	for existing, wallet := range d.Wallets {
+		if wallet.PublicKey == publicKey {
+			return fmt.Errorf("address %s is already stored as %s", publicKey, existing)
+		}
+	}
+	return nil
+}
+
+// CheckAliasFree fails when alias already names a stored wallet or a contact, so a new wallet can be
+// refused before any work is done for it. Without a key file every name is free.
+func (w *WalletConfig) CheckAliasFree(alias string) error {
+	present, err := w.KeyOps.IsKeyFilePresent()
+	if err != nil || !present {
+		return err
+	}
+
+	aliases, err := w.KeyOps.ListAliases()
+	if err != nil {
+		return err
+	}
+	contacts, err := w.Contacts()
+	if err != nil {
+		return err
+	}
+	multisigs, err := w.Multisigs()
+	if err != nil {
+		return err
+	}
+
+	data := WalletData{Wallets: make(map[string]Wallet), Contacts: make(map[string]string), Multisigs: make(map[string]Multisig)}
+	for _, m := range multisigs {
+		data.Multisigs[m.Alias] = m
+	}
+	for _, existing := range aliases {
+		data.Wallets[existing] = Wallet{}
+	}
+	for _, contact := range contacts {
+		data.Contacts[contact.Name] = contact.Address
+	}
+	// The same check, and message, as when the wallet is written.
+	return data.checkNameFree(alias)
+}
+
+// validContactName rejects names that could not be typed as a single send argument.
+func validContactName(name string) error {
+	if name == "" {
+		return errors.New("contact name must not be empty")
+	}
+	if strings.IndexFunc(name, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return errors.New("contact name must not contain spaces")
+	}
+	return nil
+}
+
+// AddContact stores address under name. The name must not be taken by a wallet or another contact.
+func (k *KeyOps) AddContact(name, address string) error {
+	if err := validContactName(name); err != nil {
+		return err
+	}
+	if _, err := solana.PublicKeyFromBase58(address); err != nil {
+		return fmt.Errorf("invalid address %q: %w", address, err)
+	}
+
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var data WalletData
+	fileExists, err := k.IsKeyFilePresent()
+	if err != nil {
+		return fmt.Errorf("error checking if keys are already present: %w", err)
+	}
+
+	if fileExists {
+		data, err = k.readWalletData(k.keyFilePath())
+		if err != nil {
+			return err
+		}
+	} else {
+		data.Wallets = make(map[string]Wallet)
+	}
+
+	if err := data.checkNameFree(name); err != nil {
+		return err
+	}
+	if data.Contacts == nil {
+		data.Contacts = make(map[string]string)
+	}
+	data.Contacts[name] = address
+
+	return k.writeWalletData("contact", data)
+}
+
+// RemoveContact deletes a contact and returns the address it had. The contact leaves every group.
+func (k *KeyOps) RemoveContact(name string) (string, error) {
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return "", err
+	}
+
+	address, exists := data.Contacts[name]
+	if !exists {
+		return "", fmt.Errorf("%w: %s", ErrContactNotFound, name)
+	}
+	delete(data.Contacts, name)
+	data.removeFromGroups(name)
+
+	return address, k.writeWalletData("contact", data)
+}
+
+// Contacts returns the stored contacts sorted by name. A missing key file has none.
+func (k *KeyOps) Contacts() ([]Contact, error) {
+	fileExists, err := k.IsKeyFilePresent()
+	if err != nil || !fileExists {
+		return nil, err
+	}
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := make([]Contact, 0, len(data.Contacts))
+	for name, address := range data.Contacts {
+		contacts = append(contacts, Contact{Name: name, Address: address})
+	}
+	sort.Slice(contacts, func(i, j int) bool { return contacts[i].Name < contacts[j].Name })
+	return contacts, nil
+}
+
+// AddContact stores a named recipient address.
+func (w *WalletConfig) AddContact(name, address string) error {
+	return w.KeyOps.AddContact(name, address)
+}
+
+// RemoveContact deletes a contact and returns the address it had.
+func (w *WalletConfig) RemoveContact(name string) (string, error) {
+	return w.KeyOps.RemoveContact(name)
+}
+
+// Contacts returns the stored contacts sorted by name.
+func (w *WalletConfig) Contacts() ([]Contact, error) {
+	return w.KeyOps.Contacts()
+}
+
+// ResolveRecipient turns a send argument into an address. A contact name resolves to its address,
+// even when the name is a valid address itself; Recipient.Ambiguous reports that case. Anything
+// else must be a valid address.
+func (w *WalletConfig) ResolveRecipient(arg string) (Recipient, error) {
+	_, addressErr := solana.PublicKeyFromBase58(arg)
+
+	contacts, err := w.Contacts()
+	if err != nil {
+		return Recipient{}, fmt.Errorf("failed to read contacts: %w", err)
+	}
+	for _, contact := range contacts {
+		if contact.Name == arg {
+			return Recipient{Address: contact.Address, Contact: contact.Name, Ambiguous: addressErr == nil}, nil
+		}
+	}
+
+	if addressErr != nil {
+		return Recipient{}, fmt.Errorf("%w: %q is neither a contact nor a valid address: %w", ErrInvalidRecipient, arg, addressErr)
+	}
+	return Recipient{Address: arg}, nil
+}
+
+// AddressNames maps the addresses of stored wallets and contacts to their names, for showing who is
+// on the other side of a transfer. A wallet's own alias wins over a contact for the same address.
+// Without a key file there are no names.
+func (w *WalletConfig) AddressNames() (map[string]string, error) {
+	present, err := w.KeyOps.IsKeyFilePresent()
+	if err != nil || !present {
+		return map[string]string{}, err
+	}
+
+	contacts, err := w.Contacts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contacts: %w", err)
+	}
+	wallets, err := w.ListWallets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallets: %w", err)
+	}
+
+	names := make(map[string]string, len(contacts)+len(wallets))
+	for _, contact := range contacts {
+		names[contact.Address] = contact.Name
+	}
+	for _, wallet := range wallets {
+		names[wallet.PublicKey] = wallet.Alias
+	}
+	return names, nil
+}
@@ -0,0 +1,180 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddContact(t *testing.T) {
+	mum := solana.NewWallet().PublicKey().String()
+	main := solana.NewWallet().PublicKey().String()
+
+	tests := []struct {
+		name        string
+		contact     string
+		address     string
+		expectedErr string
+	}{
+		{name: "New Contact", contact: "mum", address: mum},
+		{name: "Invalid Address", contact: "dad", address: "not-an-address", expectedErr: `invalid address "not-an-address"`},
+		{name: "Empty Name", contact: "", address: mum, expectedErr: "contact name must not be empty"},
+		{name: "Name With Spaces", contact: "my mum", address: mum, expectedErr: "contact name must not contain spaces"},
+		{name: "Name Of A Wallet", contact: "main", address: mum, expectedErr: "alias already exists: main"},
+		{name: "Existing Contact", contact: "landlord", address: mum, expectedErr: "landlord is already the name of a contact"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+				ActiveAlias: "main",
+				Wallets:     map[string]Wallet{"main": {PublicKey: main}},
+				Contacts:    map[string]string{"landlord": solana.NewWallet().PublicKey().String()},
+			})))
+			keyOps := &KeyOps{FileReader: files, FileWriter: files}
+
+			err := keyOps.AddContact(tt.contact, tt.address)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+
+			contacts, err := keyOps.Contacts()
+			assert.NoError(t, err)
+			assert.Contains(t, contacts, Contact{Name: tt.contact, Address: tt.address})
+		})
+	}
+}
+
+func TestContactNamesAreNotWalletAliases(t *testing.T) {
+	files := newMemFiles()
+	keyOps := &KeyOps{FileReader: files, FileWriter: files}
+
+	account := solana.NewWallet()
+	assert.NoError(t, keyOps.AddContact("mum", solana.NewWallet().PublicKey().String()))
+	assert.NoError(t, keyOps.WriteKeyToFile("main", ed25519.PrivateKey(account.PrivateKey), account.PublicKey().String()))
+
+	err := keyOps.WriteKeyToFile("mum", ed25519.PrivateKey(account.PrivateKey), account.PublicKey().String())
+	assert.EqualError(t, err, "mum is already the name of a contact")
+	err = keyOps.RenameKey("main", "mum")
+	assert.EqualError(t, err, "mum is already the name of a contact")
+	err = keyOps.AddWatchOnlyWallet("mum", solana.NewWallet().PublicKey().String())
+	assert.EqualError(t, err, "mum is already the name of a contact")
+}
+
+func TestRemoveContact(t *testing.T) {
+	mum := solana.NewWallet().PublicKey().String()
+	files := newMemFiles()
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		Wallets:  map[string]Wallet{},
+		Contacts: map[string]string{"mum": mum},
+	})))
+
+	var changes []KeystoreChange
+	keyOps := &KeyOps{FileReader: files, FileWriter: files, OnChange: func(_ string, c []KeystoreChange) { changes = c }}
+
+	address, err := keyOps.RemoveContact("mum")
+	assert.NoError(t, err)
+	assert.Equal(t, mum, address)
+	assert.Equal(t, []KeystoreChange{{Kind: ChangeSetting, Field: "contact mum", From: mum}}, changes)
+
+	contacts, err := keyOps.Contacts()
+	assert.NoError(t, err)
+	assert.Empty(t, contacts)
+
+	_, err = keyOps.RemoveContact("mum")
+	assert.True(t, errors.Is(err, ErrContactNotFound))
+}
+
+func TestResolveRecipient(t *testing.T) {
+	mum := solana.NewWallet().PublicKey().String()
+	other := solana.NewWallet().PublicKey().String()
+	// A contact named like an address: the name shadows that address.
+	shadowed := solana.NewWallet().PublicKey().String()
+
+	files := newMemFiles()
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		Wallets:  map[string]Wallet{},
+		Contacts: map[string]string{"mum": mum, shadowed: other},
+	})))
+	wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}}
+
+	tests := []struct {
+		name        string
+		arg         string
+		expected    Recipient
+		expectedErr string
+	}{
+		{name: "Contact", arg: "mum", expected: Recipient{Address: mum, Contact: "mum"}},
+		{name: "Address", arg: other, expected: Recipient{Address: other}},
+		{name: "Contact Wins Over Address", arg: shadowed, expected: Recipient{Address: other, Contact: shadowed, Ambiguous: true}},
+		{name: "Unknown Name", arg: "dad", expectedErr: `"dad" is neither a contact nor a valid address`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipient, err := wc.ResolveRecipient(tt.arg)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, recipient)
+		})
+	}
+
+	assert.Equal(t, "mum ("+mum+")", Recipient{Address: mum, Contact: "mum"}.String())
+}
+
+func TestAddressNames(t *testing.T) {
+	main := solana.NewWallet().PublicKey().String()
+	mum := solana.NewWallet().PublicKey().String()
+
+	files := newMemFiles()
+	wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}}
+
+	// Without a key file nothing has a name.
+	names, err := wc.AddressNames()
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		ActiveAlias: "main",
+		Wallets:     map[string]Wallet{"main": {PublicKey: main}},
+		Contacts:    map[string]string{"mum": mum, "me": main},
+	})))
+
+	names, err = wc.AddressNames()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{main: "main", mum: "mum"}, names)
+}
+
+func TestCheckAliasFree(t *testing.T) {
+	files := newMemFiles()
+	keyOps := &KeyOps{FileReader: files, FileWriter: files}
+	wc := &WalletConfig{KeyOps: keyOps}
+	assert.NoError(t, wc.CheckAliasFree("main"), "every name is free without a key file")
+
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		ActiveAlias: "main",
+		Wallets:     map[string]Wallet{"main": {PublicKey: solana.NewWallet().PublicKey().String()}},
+		Contacts:    map[string]string{"landlord": solana.NewWallet().PublicKey().String()},
+	})))
+
+	assert.NoError(t, wc.CheckAliasFree("savings"))
+	if err := wc.CheckAliasFree("main"); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "alias already exists: main")
+	}
+	if err := wc.CheckAliasFree("landlord"); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "landlord is already the name of a contact")
+	}
+}
@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Currency is the fiat currency balances, history and amounts to send are shown in, as an ISO 4217 code.
+type Currency string
+
+// DefaultCurrency is used when no currency has been selected, as before currencies were configurable.
+const DefaultCurrency Currency = "EUR"
+
+// CurrencyEnv selects the currency for every command when --currency is not given.
+const CurrencyEnv = "SLEENG_CURRENCY"
+
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// currencyInfo is how a currency is quoted and shown to the user.
+type currencyInfo struct {
+	// pair is the Kraken pair quoting SOL in the currency, empty when Kraken has none and the rate
+	// comes from CoinGecko alone. Price history is only available for currencies with a pair.
+	pair   string
+	symbol string
+}
+
+// supportedCurrencies are the currencies Kraken or CoinGecko quote SOL in.
+var supportedCurrencies = map[Currency]currencyInfo{
+	"EUR": {pair: "SOLEUR", symbol: "€"},
+	"USD": {pair: "SOLUSD", symbol: "$"},
+	"GBP": {pair: "SOLGBP", symbol: "£"},
+	"AUD": {symbol: "A$"},
+	"BRL": {symbol: "R$"},
+	"CAD": {symbol: "CA$"},
+	"CHF": {symbol: "CHF"},
+	"INR": {symbol: "₹"},
+	"NGN": {symbol: "₦"},
+}
+
+// SupportedCurrencies returns the codes of all supported currencies in alphabetical order.
+func SupportedCurrencies() []Currency {
+	currencies := make([]Currency, 0, len(supportedCurrencies))
+	for currency := range supportedCurrencies {
+		currencies = append(currencies, currency)
+	}
+
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i] < currencies[j] })
+	return currencies
+}
+
+// ParseCurrency validates a currency code, ignoring case. An empty code selects DefaultCurrency.
+func ParseCurrency(code string) (Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+
+	currency := Currency(code)
+	if _, ok := supportedCurrencies[currency]; !ok {
+		pairs := make([]string, 0, len(supportedCurrencies))
+		for _, supported := range SupportedCurrencies() {
+			pairs = append(pairs, fmt.Sprintf("%s (%s)", supported, supported.Source()))
+		}
+		return "", fmt.Errorf("%w %q, supported: %s", ErrUnsupportedCurrency, code, strings.Join(pairs, ", "))
+	}
+
+	return currency, nil
+}
+
+// orDefault returns DefaultCurrency for the zero value.
+func (c Currency) orDefault() Currency {
+	if c == "" {
+		return DefaultCurrency
+	}
+	return c
+}
+
+// Pair returns the Kraken pair quoting SOL in the currency, such as SOLEUR, or an empty string when
+// Kraken does not quote it.
+func (c Currency) Pair() string {
+	return supportedCurrencies[c.orDefault()].pair
+}
+
+// Source names where the rate of the currency comes from: its Kraken pair, or CoinGecko.
+func (c Currency) Source() string {
+	if pair := c.Pair(); pair != "" {
+		return pair
+	}
+	return "CoinGecko"
+}
+
+// Symbol returns the currency sign, such as €.
+func (c Currency) Symbol() string {
+	return supportedCurrencies[c.orDefault()].symbol
+}
+
+// Format shows an amount in the currency rounded to cents in the locale selected with SetLocale, such
+// as €1,234.50 or 1.234,50 €.
+func (c Currency) Format(amount decimal.Decimal) string {
+	return CurrentLocale().FormatMoney(amount, c)
+}
+
+func (c Currency) String() string {
+	return string(c.orDefault())
+}
@@ -0,0 +1,139 @@
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        string
+		expected    Currency
+		expectedErr bool
+	}{
+		{name: "Empty Selects Default", code: "", expected: DefaultCurrency},
+		{name: "Lower Case", code: "usd", expected: "USD"},
+		{name: "Surrounding Space", code: " GBP ", expected: "GBP"},
+		{name: "CoinGecko Only", code: "ngn", expected: "NGN"},
+		{name: "Unsupported", code: "BTC", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCurrency(tt.code)
+			if tt.expectedErr {
+				assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+				assert.Contains(t, err.Error(), "CHF (CoinGecko), EUR (SOLEUR), GBP (SOLGBP)")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestCurrencyFormat(t *testing.T) {
+	amount := decimal.RequireFromString("12.5")
+
+	assert.Equal(t, "€12.50", Currency("EUR").Format(amount))
+	assert.Equal(t, "$12.50", Currency("USD").Format(amount))
+	assert.Equal(t, "£12.50", Currency("GBP").Format(amount))
+	assert.Equal(t, "€12.50", Currency("").Format(amount))
+	assert.Equal(t, "SOLGBP", Currency("GBP").Pair())
+	assert.Equal(t, "₦12.50", Currency("NGN").Format(amount))
+	assert.Equal(t, "CHF 12.50", Currency("CHF").Format(amount), "a code is set apart from the number")
+	assert.Empty(t, Currency("CHF").Pair())
+	assert.Equal(t, "CoinGecko", Currency("CHF").Source())
+}
+
+func TestParseKrakenTicker(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		pair        string
+		expected    string
+		expectedErr bool
+	}{
+		{name: "Requested Pair", body: `{"error":[],"result":{"SOLUSD":{"p":["101.1","102.2"]}}}`, pair: "SOLUSD", expected: "102.2"},
+		{name: "Kraken Pair Name", body: `{"error":[],"result":{"XSOLZGBP":{"p":["80.0","81.5"]}}}`, pair: "SOLGBP", expected: "81.5"},
+		{name: "Kraken Error", body: `{"error":["EQuery:Unknown asset pair"],"result":{}}`, pair: "SOLGBP", expectedErr: true},
+		{name: "Missing Price", body: `{"error":[],"result":{"SOLEUR":{"p":[]}}}`, pair: "SOLEUR", expectedErr: true},
+		{name: "Not JSON", body: `<html>`, pair: "SOLEUR", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKrakenTicker([]byte(tt.body), tt.pair)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got.String())
+		})
+	}
+}
+
+func TestFetchSOLRateRequestsCurrencyPair(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Query().Get("pair")
+		w.Write([]byte(`{"error":[],"result":{"SOLGBP":{"p":["80.0","81.5"]}}}`))
+	}))
+	defer server.Close()
+
+	wc := &WalletConfig{Currency: "GBP", Rates: &KrakenRates{URL: server.URL + "/?pair="}}
+	rate, err := wc.FetchSOLRate()
+	assert.NoError(t, err)
+	assert.Equal(t, "81.5", rate.String())
+	assert.Equal(t, "SOLGBP", requested)
+}
+
+func TestFetchSOLRateWithoutKrakenPair(t *testing.T) {
+	kraken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Kraken was asked for %s", r.URL)
+	}))
+	defer kraken.Close()
+	var requested string
+	coinGecko := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Query().Get("vs_currencies")
+		w.Write([]byte(`{"solana":{"chf":123.45}}`))
+	}))
+	defer coinGecko.Close()
+
+	wc := &WalletConfig{Currency: "CHF", Rates: FallbackRates{&KrakenRates{URL: kraken.URL + "/?pair="}, &CoinGeckoRates{URL: coinGecko.URL + "/?vs_currencies="}}}
+	rate, err := wc.FetchSOLRate()
+	assert.NoError(t, err)
+	assert.Equal(t, "123.45", rate.String())
+	assert.Equal(t, "chf", requested)
+
+	_, err = (&OHLCCache{}).History("CHF", 24*time.Hour)
+	assert.ErrorIs(t, err, ErrRateUnavailable)
+}
+
+func TestProfileCurrency(t *testing.T) {
+	profiles := newTestProfileManager(t)
+
+	currency, err := profiles.Currency()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultCurrency, currency)
+
+	assert.NoError(t, profiles.Create("work"))
+	assert.NoError(t, profiles.Switch("work"))
+	assert.NoError(t, profiles.SetCurrency("USD"))
+
+	currency, err = profiles.Currency()
+	assert.NoError(t, err)
+	assert.Equal(t, Currency("USD"), currency)
+
+	// Storing the currency keeps the selected profile.
+	active, err := profiles.Active()
+	assert.NoError(t, err)
+	assert.Equal(t, "work", active)
+}
@@ -0,0 +1,229 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/ed25519"
+)
+
+// hardenedOffset marks a BIP-32 index as hardened. SLIP-0010 ed25519 only supports hardened indices.
+const hardenedOffset uint32 = 0x80000000
+
+// slip10Curve is the HMAC key SLIP-0010 uses to derive the ed25519 master key.
+const slip10Curve = "ed25519 seed"
+
+// Values of KeyDerivation.Path that are not derivation paths.
+const (
+	// DerivationNone marks keys that were generated randomly or imported as a private key.
+	DerivationNone = "none"
+	// DerivationLegacy marks keys derived with the non-standard derivation of older sleeng versions.
+	DerivationLegacy = "legacy"
+	// DerivationUnknown marks entries stored before derivation paths were recorded.
+	DerivationUnknown = "unknown/legacy"
+)
+
+var (
+	ErrNotSeedDerived     = errors.New("wallet was not derived from a seed phrase")
+	ErrDerivationMismatch = errors.New("seed phrase does not derive the stored public key")
+)
+
+// KeyDerivation records how a stored key was derived from its seed phrase.
+type KeyDerivation struct {
+	Path         string  `json:"path"`
+	AccountIndex *uint32 `json:"accountIndex,omitempty"`
+	// Passphrase is true when a BIP-39 passphrase is needed; the passphrase itself is never stored.
+	Passphrase bool `json:"passphrase,omitempty"`
+}
+
+// IsSeedDerived reports whether the key may have been derived from a seed phrase.
+func (d KeyDerivation) IsSeedDerived() bool {
+	return d.Path != DerivationNone
+}
+
+// String describes the derivation for display.
+func (d KeyDerivation) String() string {
+	description := d.Path
+	if d.AccountIndex != nil {
+		description += fmt.Sprintf(" (account %d)", *d.AccountIndex)
+	}
+	if d.Passphrase {
+		description += " with passphrase"
+	}
+	return description
+}
+
+// SolanaDerivationPath returns the derivation path Phantom, Solflare and friends use for an account.
+func SolanaDerivationPath(account uint32) string {
+	return fmt.Sprintf("m/44'/501'/%d'/0'", account)
+}
+
+// SeedOptions controls how a key is derived from a seed phrase.
+type SeedOptions struct {
+	// Passphrase is the optional BIP-39 passphrase (sometimes called the 25th word).
+	Passphrase string
+	// AccountIndex selects m/44'/501'/<index>'/0' when DerivationPath is empty.
+	AccountIndex uint32
+	// DerivationPath overrides AccountIndex with an explicit hardened path.
+	DerivationPath string
+	// Legacy derives keys the way sleeng did before BIP-44 support, for paper wallets made back then.
+	Legacy bool
+}
+
+// path returns the derivation path the options resolve to.
+func (o SeedOptions) path() string {
+	if o.DerivationPath != "" {
+		return o.DerivationPath
+	}
+	return SolanaDerivationPath(o.AccountIndex)
+}
+
+// derivation returns the KeyDerivation to record for a key derived with these options.
+func (o SeedOptions) derivation() KeyDerivation {
+	derivation := KeyDerivation{Path: o.path(), Passphrase: o.Passphrase != ""}
+
+	switch {
+	case o.Legacy:
+		derivation.Path = DerivationLegacy
+	case o.DerivationPath == "":
+		index := o.AccountIndex
+		derivation.AccountIndex = &index
+	}
+
+	return derivation
+}
+
+// seedOptionsFor returns the options that reproduce a recorded derivation.
+func seedOptionsFor(derivation KeyDerivation, passphrase string) SeedOptions {
+	opts := SeedOptions{Passphrase: passphrase}
+
+	switch {
+	case derivation.Path == DerivationLegacy:
+		opts.Legacy = true
+	case derivation.AccountIndex != nil:
+		opts.AccountIndex = *derivation.AccountIndex
+	default:
+		opts.DerivationPath = derivation.Path
+	}
+
+	return opts
+}
+
+// VerifyDerivation re-derives a stored wallet from its seed phrase and checks that it yields the
+// stored public key. Wallets stored before paths were recorded are checked against the legacy and
+// default derivations, and the derivation that matched is returned.
+func (w *WalletConfig) VerifyDerivation(alias, mnemonic, passphrase string) (KeyDerivation, error) {
+	info, err := w.KeyOps.GetWalletInfo(alias)
+	if err != nil {
+		return KeyDerivation{}, err
+	}
+
+	if !info.Derivation.IsSeedDerived() {
+		return info.Derivation, fmt.Errorf("%w: %s", ErrNotSeedDerived, info.Alias)
+	}
+
+	candidates := derivationCandidates(info.Derivation, passphrase != "")
+	for _, candidate := range candidates {
+		key, err := deriveKeyFromMnemonic(mnemonic, seedOptionsFor(candidate, passphrase))
+		if err != nil {
+			// The legacy derivation only accepts 12 word seed phrases; other candidates may still match.
+			if len(candidates) > 1 {
+				continue
+			}
+			return info.Derivation, err
+		}
+
+		if solana.PrivateKey(key).PublicKey().String() == info.PublicKey {
+			return candidate, nil
+		}
+	}
+
+	return info.Derivation, fmt.Errorf("%w: %s", ErrDerivationMismatch, info.Alias)
+}
+
+// derivationCandidates returns the derivations a seed phrase is checked along for a stored wallet:
+// the recorded one, or for wallets stored before paths were recorded the legacy and default ones.
+func derivationCandidates(recorded KeyDerivation, passphrase bool) []KeyDerivation {
+	if recorded.Path != DerivationUnknown {
+		return []KeyDerivation{recorded}
+	}
+	first := uint32(0)
+	return []KeyDerivation{
+		{Path: SolanaDerivationPath(0), AccountIndex: &first, Passphrase: passphrase},
+		{Path: DerivationLegacy},
+	}
+}
+
+// deriveKeyFromMnemonic derives the ed25519 key for a mnemonic according to the options.
+func deriveKeyFromMnemonic(mnemonic string, opts SeedOptions) (ed25519.PrivateKey, error) {
+	if opts.Legacy {
+		_, key, err := createLegacyKeyPairWithMnemonic(mnemonic)
+		return key, err
+	}
+
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, opts.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("mnemonic not valid: %w", err)
+	}
+
+	return deriveSLIP10Key(seed, opts.path())
+}
+
+// deriveSLIP10Key derives an ed25519 key from a BIP-39 seed along a hardened SLIP-0010 path.
+func deriveSLIP10Key(seed []byte, path string) (ed25519.PrivateKey, error) {
+	indices, err := parseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha512.New, []byte(slip10Curve))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chainCode := sum[:32], sum[32:]
+
+	for _, index := range indices {
+		data := make([]byte, 0, 37)
+		data = append(data, 0x00)
+		data = append(data, key...)
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		mac = hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum = mac.Sum(nil)
+		key, chainCode = sum[:32], sum[32:]
+	}
+
+	return ed25519.NewKeyFromSeed(key), nil
+}
+
+// parseDerivationPath parses a path such as m/44'/501'/0'/0' into hardened indices.
+func parseDerivationPath(path string) ([]uint32, error) {
+	segments := strings.Split(strings.TrimSpace(path), "/")
+	if len(segments) == 0 || segments[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path %q: must start with m", path)
+	}
+
+	indices := make([]uint32, 0, len(segments)-1)
+	for _, segment := range segments[1:] {
+		trimmed := strings.TrimRight(segment, "'hH")
+		if trimmed == segment {
+			return nil, fmt.Errorf("invalid derivation path %q: ed25519 only supports hardened indices like %s'", path, segment)
+		}
+
+		index, err := strconv.ParseUint(trimmed, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path %q: %w", path, err)
+		}
+
+		indices = append(indices, uint32(index)+hardenedOffset)
+	}
+
+	return indices, nil
+}
@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeriveSLIP10Key(t *testing.T) {
+	// Test vector 1 from the SLIP-0010 specification for ed25519.
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	tests := []struct {
+		name        string
+		path        string
+		expectedKey string
+		expectedErr bool
+	}{
+		{name: "Master", path: "m", expectedKey: "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7"},
+		{name: "m/0H", path: "m/0'", expectedKey: "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3"},
+		{name: "m/0H/1H", path: "m/0H/1h", expectedKey: "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2"},
+		{name: "Non Hardened", path: "m/44'/501'/0", expectedErr: true},
+		{name: "Missing Root", path: "44'/501'", expectedErr: true},
+		{name: "Not A Number", path: "m/abc'", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := deriveSLIP10Key(seed, tt.path)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedKey, hex.EncodeToString(got.Seed()))
+		})
+	}
+}
+
+func TestDeriveKeyFromMnemonic(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	first, err := deriveKeyFromMnemonic(mnemonic, SeedOptions{})
+	assert.NoError(t, err)
+
+	explicit, err := deriveKeyFromMnemonic(mnemonic, SeedOptions{DerivationPath: "m/44'/501'/0'/0'"})
+	assert.NoError(t, err)
+	assert.Equal(t, first, explicit, "account 0 must match the standard path")
+
+	second, err := deriveKeyFromMnemonic(mnemonic, SeedOptions{AccountIndex: 1})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	withPassphrase, err := deriveKeyFromMnemonic(mnemonic, SeedOptions{Passphrase: "TREZOR"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, withPassphrase)
+
+	legacy, err := deriveKeyFromMnemonic(mnemonic, SeedOptions{Legacy: true})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, legacy)
+
+	_, legacyDirect, err := createLegacyKeyPairWithMnemonic(mnemonic)
+	assert.NoError(t, err)
+	assert.Equal(t, legacyDirect, legacy, "legacy derivation must keep producing the old keys")
+
+	_, err = deriveKeyFromMnemonic("not a real mnemonic", SeedOptions{})
+	assert.Error(t, err)
+}
+
+func TestVerifyDerivation(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	other := "legal winner thank year wave sausage worth useful legal winner thank yellow"
+
+	tests := []struct {
+		name         string
+		store        func(wc *WalletConfig) error
+		mnemonic     string
+		expectedPath string
+		expectedErr  error
+	}{
+		{
+			name: "Match",
+			store: func(wc *WalletConfig) error {
+				if _, err := wc.ImportWalletFromSeedWithOptions(mnemonic, SeedOptions{AccountIndex: 2}); err != nil {
+					return err
+				}
+				return wc.SaveSeedWallet("main")
+			},
+			mnemonic:     mnemonic,
+			expectedPath: SolanaDerivationPath(2),
+		},
+		{
+			name: "Mismatch",
+			store: func(wc *WalletConfig) error {
+				if _, err := wc.ImportWalletFromSeedWithOptions(mnemonic, SeedOptions{}); err != nil {
+					return err
+				}
+				return wc.SaveSeedWallet("main")
+			},
+			mnemonic:    other,
+			expectedErr: ErrDerivationMismatch,
+		},
+		{
+			name: "Legacy Entry Without Recorded Path",
+			store: func(wc *WalletConfig) error {
+				_, key, err := createLegacyKeyPairWithMnemonic(mnemonic)
+				if err != nil {
+					return err
+				}
+				// Entries written before derivation paths were recorded have no derivation at all.
+				return wc.KeyOps.(*KeyOps).FileWriter.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+					ActiveAlias: "main",
+					Wallets: map[string]Wallet{"main": {
+						PrivateKey: getSolCLIComptKey(key),
+						PublicKey:  solana.PrivateKey(key).PublicKey().String(),
+					}},
+				}))
+			},
+			mnemonic:     mnemonic,
+			expectedPath: DerivationLegacy,
+		},
+		{
+			name: "Not Seed Derived",
+			store: func(wc *WalletConfig) error {
+				_, err := wc.CreateNewWallet("main")
+				return err
+			},
+			mnemonic:    mnemonic,
+			expectedErr: ErrNotSeedDerived,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}}
+			assert.NoError(t, tt.store(wc))
+
+			got, err := wc.VerifyDerivation("main", tt.mnemonic, "")
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPath, got.Path)
+		})
+	}
+}
+
+func TestMigratedEntriesHaveUnknownDerivation(t *testing.T) {
+	files := newMemFiles()
+	ops := &KeyOps{FileReader: files, FileWriter: files}
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		ActiveAlias: "old",
+		Wallets:     map[string]Wallet{"old": {PublicKey: "address"}},
+	})))
+
+	info, err := ops.GetWalletInfo("")
+	assert.NoError(t, err)
+	assert.Equal(t, DerivationUnknown, info.Derivation.Path)
+}
@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shell is a shell dialect the environment of a wallet can be printed for.
+type Shell string
+
+const (
+	ShellPOSIX      Shell = "posix"
+	ShellFish       Shell = "fish"
+	ShellPowerShell Shell = "powershell"
+)
+
+// Variables set by WalletEnv.Format.
+const (
+	EnvKeypair = "SOLANA_KEYPAIR"
+	EnvRPCURL  = "SOLANA_RPC_URL"
+	EnvAddress = "WALLET_ADDRESS"
+)
+
+// DefaultTempKeypairTTL is how long a keypair exported for `wallet env` is kept.
+const DefaultTempKeypairTTL = time.Hour
+
+// WalletEnv is the environment that points other Solana tools at a wallet. Keypair is empty unless
+// the key was exported with ExportTempKeypair.
+type WalletEnv struct {
+	Address string     `json:"address"`
+	RPCURL  string     `json:"rpcUrl"`
+	Keypair string     `json:"keypair,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// Format returns the lines that set the environment in shell, each ending in a separator so they
+// still work when `eval $(wallet env)` joins them into one line.
+func (e WalletEnv) Format(shell Shell) string {
+	vars := [][2]string{{EnvAddress, e.Address}, {EnvRPCURL, e.RPCURL}}
+	if e.Keypair != "" {
+		vars = append(vars, [2]string{EnvKeypair, e.Keypair})
+	}
+
+	var b strings.Builder
+	for _, v := range vars {
+		switch shell {
+		case ShellFish:
+			fmt.Fprintf(&b, "set -gx %s %s;\n", v[0], quoteFish(v[1]))
+		case ShellPowerShell:
+			fmt.Fprintf(&b, "$env:%s = %s;\n", v[0], quotePowerShell(v[1]))
+		default:
+			fmt.Fprintf(&b, "export %s=%s;\n", v[0], quotePOSIX(v[1]))
+		}
+	}
+	return b.String()
+}
+
+// quotePOSIX single-quotes s; a single quote ends the quoting, is escaped and starts it again.
+func quotePOSIX(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quoteFish single-quotes s; fish only treats backslashes and single quotes specially inside them.
+func quoteFish(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// quotePowerShell single-quotes s; PowerShell escapes a single quote by doubling it.
+func quotePowerShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// tempKeypairDir is where ExportTempKeypair writes keypairs: a directory of the current user, so
+// other users cannot list the files either.
+var tempKeypairDir = func() string {
+	return filepath.Join(os.TempDir(), "sleeng-keypairs-"+strconv.Itoa(os.Getuid()))
+}
+
+// tempKeypairPrefix starts the name of every file ExportTempKeypair writes; the expiry follows it.
+const tempKeypairPrefix = "id-"
+
+// ExportTempKeypair writes the key of a stored wallet to a new solana-keygen keypair file in a
+// temporary directory, readable only by the current user, and returns its path. The expiry is part
+// of the file name; SweepTempKeypairs removes the file once it has passed.
+func (w *WalletConfig) ExportTempKeypair(alias string, ttl time.Duration) (string, time.Time, error) {
+	dir := tempKeypairDir()
+	if err := guardWrite(dir); err != nil {
+		return "", time.Time{}, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create keypair directory: %w", err)
+	}
+	// An existing directory keeps its mode, so tighten it like a new one.
+	if err := os.Chmod(dir, 0700); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to restrict keypair directory: %w", err)
+	}
+
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	file, err := os.CreateTemp(dir, fmt.Sprintf("%s%d-*.json", tempKeypairPrefix, expires.Unix()))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create keypair file: %w", err)
+	}
+	path := file.Name()
+	file.Close()
+
+	if err := w.ExportKeypairFile(alias, path, true); err != nil {
+		os.Remove(path)
+		return "", time.Time{}, err
+	}
+	return path, expires, nil
+}
+
+// SweepTempKeypairs removes the keypairs written by ExportTempKeypair whose expiry is before now and
+// returns how many it removed. Other files in the directory are left alone, and nothing is removed
+// in read-only mode.
+func SweepTempKeypairs(now time.Time) (int, error) {
+	if ReadOnlyMode() {
+		return 0, nil
+	}
+	dir := tempKeypairDir()
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read keypair directory: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		expires, ok := tempKeypairExpiry(entry.Name())
+		if !ok || entry.IsDir() || expires.After(now) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("failed to remove expired keypair: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// tempKeypairExpiry reads the expiry from the name of a file written by ExportTempKeypair.
+func tempKeypairExpiry(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, tempKeypairPrefix) || !strings.HasSuffix(name, ".json") {
+		return time.Time{}, false
+	}
+	stamp, _, ok := strings.Cut(strings.TrimPrefix(name, tempKeypairPrefix), "-")
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
@@ -0,0 +1,148 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestWalletEnvFormat(t *testing.T) {
+	env := WalletEnv{Address: "7Xq9fP", RPCURL: "https://rpc.example/?key=it's"}
+	withKey := env
+	withKey.Keypair = `/tmp/sleeng-keypairs-1000/id-1\2.json`
+
+	tests := []struct {
+		name     string
+		env      WalletEnv
+		shell    Shell
+		expected string
+	}{
+		{
+			name:  "POSIX",
+			env:   env,
+			shell: ShellPOSIX,
+			expected: "export WALLET_ADDRESS='7Xq9fP';\n" +
+				"export SOLANA_RPC_URL='https://rpc.example/?key=it'\\''s';\n",
+		},
+		{
+			name:  "POSIX With Key",
+			env:   withKey,
+			shell: ShellPOSIX,
+			expected: "export WALLET_ADDRESS='7Xq9fP';\n" +
+				"export SOLANA_RPC_URL='https://rpc.example/?key=it'\\''s';\n" +
+				"export SOLANA_KEYPAIR='/tmp/sleeng-keypairs-1000/id-1\\2.json';\n",
+		},
+		{
+			name:  "Fish With Key",
+			env:   withKey,
+			shell: ShellFish,
+			expected: "set -gx WALLET_ADDRESS '7Xq9fP';\n" +
+				"set -gx SOLANA_RPC_URL 'https://rpc.example/?key=it\\'s';\n" +
+				"set -gx SOLANA_KEYPAIR '/tmp/sleeng-keypairs-1000/id-1\\\\2.json';\n",
+		},
+		{
+			name:  "PowerShell With Key",
+			env:   withKey,
+			shell: ShellPowerShell,
+			expected: "$env:WALLET_ADDRESS = '7Xq9fP';\n" +
+				"$env:SOLANA_RPC_URL = 'https://rpc.example/?key=it''s';\n" +
+				"$env:SOLANA_KEYPAIR = '/tmp/sleeng-keypairs-1000/id-1\\2.json';\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.env.Format(tt.shell))
+		})
+	}
+}
+
+// useTempKeypairDir points ExportTempKeypair and SweepTempKeypairs at a directory of the test.
+func useTempKeypairDir(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "keypairs")
+	original := tempKeypairDir
+	tempKeypairDir = func() string { return dir }
+	t.Cleanup(func() { tempKeypairDir = original })
+	return dir
+}
+
+func TestExportTempKeypair(t *testing.T) {
+	dir := useTempKeypairDir(t)
+	account := solana.NewWallet()
+	files := newMemFiles()
+	wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}}
+	assert.NoError(t, wc.KeyOps.WriteKeyToFile("savings", ed25519.PrivateKey(account.PrivateKey), account.PublicKey().String()))
+
+	path, expires, err := wc.ExportTempKeypair("savings", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expires, 2*time.Second)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	key, err := ParseKeypair(data)
+	assert.NoError(t, err)
+	assert.Equal(t, ed25519.PrivateKey(account.PrivateKey), key)
+
+	stat, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+	stat, err = os.Stat(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), stat.Mode().Perm())
+
+	// A failed export leaves no file behind.
+	_, _, err = wc.ExportTempKeypair("missing", time.Hour)
+	assert.Error(t, err)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	SetReadOnlyMode(true)
+	defer SetReadOnlyMode(false)
+	_, _, err = wc.ExportTempKeypair("savings", time.Hour)
+	assert.ErrorIs(t, err, ErrReadOnlyMode)
+}
+
+func TestSweepTempKeypairs(t *testing.T) {
+	dir := useTempKeypairDir(t)
+
+	removed, err := SweepTempKeypairs(time.Now())
+	assert.NoError(t, err, "a missing directory has nothing to sweep")
+	assert.Zero(t, removed)
+
+	now := time.Unix(1_700_000_000, 0)
+	assert.NoError(t, os.MkdirAll(dir, 0700))
+	for _, name := range []string{
+		"id-1699999999-a.json", // expired
+		"id-1700000000-b.json", // expires now
+		"id-1700000001-c.json", // still valid
+		"id-soon-d.json",       // not written by ExportTempKeypair
+		"notes.txt",
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("[]"), 0600))
+	}
+
+	SetReadOnlyMode(true)
+	removed, err = SweepTempKeypairs(now)
+	SetReadOnlyMode(false)
+	assert.NoError(t, err)
+	assert.Zero(t, removed, "read-only mode removes nothing")
+
+	removed, err = SweepTempKeypairs(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	var left []string
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		left = append(left, entry.Name())
+	}
+	assert.Equal(t, []string{"id-1700000001-c.json", "id-soon-d.json", "notes.txt"}, left)
+}
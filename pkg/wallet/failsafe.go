@@ -0,0 +1,519 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// FailsafeFilePath is the file next to the key file that holds the armed failsafe sweeps.
+const FailsafeFilePath = "sleeng.failsafe.json"
+
+// DefaultFailsafeThreshold is the change of the balance, in percent, at which RefreshFailsafe signs
+// the sweep again.
+var DefaultFailsafeThreshold = decimal.NewFromInt(1)
+
+var (
+	ErrFailsafeNotArmed     = errors.New("no failsafe sweep is armed for this wallet")
+	ErrFailsafeInvalidated  = errors.New("the failsafe sweep was invalidated because its nonce moved on; sign a new one with `wallet failsafe refresh`")
+	ErrFailsafeBroadcast    = errors.New("the failsafe sweep was already broadcast")
+	ErrFailsafeNonceAccount = errors.New("the wallet has an armed failsafe, so its sends must advance the failsafe's nonce account")
+	ErrNothingToSweep       = errors.New("the wallet holds no more than the fee of a sweep")
+)
+
+// FailsafeStatus is where an armed sweep is in its lifecycle.
+type FailsafeStatus string
+
+const (
+	// FailsafeArmed sweeps can be broadcast.
+	FailsafeArmed FailsafeStatus = "armed"
+	// FailsafeInvalidated sweeps can never land: a send from the wallet advanced their nonce.
+	FailsafeInvalidated FailsafeStatus = "invalidated"
+	// FailsafeBroadcast sweeps were sent.
+	FailsafeBroadcast FailsafeStatus = "broadcast"
+)
+
+// Failsafe is a fully signed transfer of a wallet's whole balance to a recovery address, kept on
+// disk instead of being sent. It uses a durable nonce, so it stays valid until the nonce advances,
+// and every send from the wallet advances that nonce, so a sweep of a balance that has since been
+// spent can never land. The signed transaction is encrypted under a passphrase of its own.
+type Failsafe struct {
+	Alias        string         `json:"alias"`
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	NonceAccount string         `json:"nonceAccount"`
+	Nonce        string         `json:"nonce"`
+	Lamports     uint64         `json:"lamports"`
+	Network      string         `json:"network"`
+	Status       FailsafeStatus `json:"status"`
+	SignedAt     time.Time      `json:"signedAt"`
+	// Signature is the signature of the sweep once it was broadcast.
+	Signature string `json:"signature,omitempty"`
+	// Transaction is the signed sweep, AES-GCM encrypted under the failsafe passphrase.
+	Transaction string `json:"transaction"`
+	Salt        string `json:"salt"`
+	CipherNonce string `json:"cipherNonce"`
+}
+
+// SOL returns the amount the sweep transfers in SOL.
+func (f Failsafe) SOL() decimal.Decimal {
+	return LamportsToSOL(f.Lamports)
+}
+
+// failsafeSchema is the schema of the failsafe file.
+var failsafeSchema = registerStoreSchema(storeSchema{
+	Kind:       StoreFailsafe,
+	Version:    1,
+	Migrations: map[int]storeMigration{0: bareStorePayload},
+})
+
+// failsafeRecords is the payload of the failsafe file: the sweeps by wallet address.
+type failsafeRecords map[string]Failsafe
+
+func (f failsafeRecords) validate() error {
+	for from, failsafe := range f {
+		if failsafe.From != from {
+			return fmt.Errorf("the sweep of %q is stored under %q", failsafe.From, from)
+		}
+		switch failsafe.Status {
+		case FailsafeArmed, FailsafeInvalidated, FailsafeBroadcast:
+		default:
+			return fmt.Errorf("the sweep of %s has unknown status %q", from, failsafe.Status)
+		}
+		if failsafe.Transaction == "" || failsafe.NonceAccount == "" {
+			return fmt.Errorf("the sweep of %s has no signed transaction", from)
+		}
+	}
+	return nil
+}
+
+// FailsafeStore keeps the armed sweeps of a profile, one per wallet address.
+type FailsafeStore struct {
+	FileReader FileReader
+	FileWriter FileWriter
+	// Dir is the profile directory holding the store. Empty means the current directory.
+	Dir string
+}
+
+func (s *FailsafeStore) filePath() string {
+	return filepath.Join(s.Dir, FailsafeFilePath)
+}
+
+// read returns the sweeps by wallet address, or none when the file does not exist.
+func (s *FailsafeStore) read() (failsafeRecords, error) {
+	failsafes := make(failsafeRecords)
+	if err := readStore(s.FileReader, s.filePath(), failsafeSchema, &failsafes); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return failsafes, nil
+		}
+		return nil, err
+	}
+	return failsafes, nil
+}
+
+func (s *FailsafeStore) write(failsafes failsafeRecords) error {
+	return writeStore(s.FileReader, s.FileWriter, s.filePath(), failsafeSchema, failsafes)
+}
+
+// Get returns the sweep of the wallet with address from.
+func (s *FailsafeStore) Get(from string) (Failsafe, error) {
+	failsafes, err := s.read()
+	if err != nil {
+		return Failsafe{}, err
+	}
+
+	f, ok := failsafes[from]
+	if !ok {
+		return Failsafe{}, ErrFailsafeNotArmed
+	}
+	return f, nil
+}
+
+// Put adds or replaces the sweep of a wallet.
+func (s *FailsafeStore) Put(f Failsafe) error {
+	failsafes, err := s.read()
+	if err != nil {
+		return err
+	}
+	failsafes[f.From] = f
+	return s.write(failsafes)
+}
+
+// Delete removes the sweep of the wallet with address from.
+func (s *FailsafeStore) Delete(from string) error {
+	failsafes, err := s.read()
+	if err != nil {
+		return err
+	}
+	delete(failsafes, from)
+	return s.write(failsafes)
+}
+
+// List returns every stored sweep sorted by alias.
+func (s *FailsafeStore) List() ([]Failsafe, error) {
+	failsafes, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Failsafe, 0, len(failsafes))
+	for _, f := range failsafes {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Alias < list[j].Alias })
+	return list, nil
+}
+
+// ArmFailsafe signs a sweep of the whole balance of the wallet alias to recovery, which is an
+// alias or an address, advancing the durable nonce in nonceAccount, and stores it encrypted
+// under passphrase without sending it. The wallet must be the nonce authority. Arming again
+// replaces the sweep stored before.
+func (w *WalletConfig) ArmFailsafe(ctx context.Context, alias, recovery, nonceAccount, passphrase string) (*Failsafe, error) {
+	if passphrase == "" {
+		return nil, errors.New("the failsafe sweep needs a passphrase to be encrypted with")
+	}
+
+	key, err := w.failsafeKey(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := w.resolveRecipient(recovery)
+	if err != nil {
+		return nil, err
+	}
+	if to.Equals(key.PublicKey()) {
+		return nil, errors.New("the recovery address must not be the wallet itself")
+	}
+	if err := w.checkRecipientAllowed(to); err != nil {
+		return nil, err
+	}
+
+	account, err := solana.PublicKeyFromBase58(nonceAccount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce account: %w", err)
+	}
+
+	failsafe := &Failsafe{Alias: alias, From: key.PublicKey().String(), To: to.String(), NonceAccount: account.String()}
+	if err := w.signFailsafe(ctx, key, failsafe, passphrase); err != nil {
+		return nil, err
+	}
+
+	_ = w.recordAudit(ctx, "failsafe arm", fmt.Sprintf("%s SOL from %s to %s", failsafe.SOL(), alias, failsafe.To))
+	return failsafe, nil
+}
+
+// RefreshFailsafe signs the sweep of alias again when the wallet's balance moved by more than
+// threshold percent from the amount it sweeps, or when a send invalidated it, and returns whether
+// it did.
+func (w *WalletConfig) RefreshFailsafe(ctx context.Context, alias, passphrase string, threshold decimal.Decimal) (*Failsafe, bool, error) {
+	failsafe, err := w.storedFailsafe(alias)
+	if err != nil {
+		return nil, false, err
+	}
+	if failsafe.Status == FailsafeBroadcast {
+		return nil, false, fmt.Errorf("%w as %s", ErrFailsafeBroadcast, failsafe.Signature)
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, false, err
+	}
+	client := endpoints.client()
+
+	if failsafe.Status == FailsafeArmed {
+		stale, err := failsafeStale(ctx, client, failsafe, threshold)
+		if err != nil {
+			return nil, false, err
+		}
+		if !stale {
+			return &failsafe, false, nil
+		}
+	}
+
+	key, err := w.failsafeKey(alias)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := w.signFailsafe(ctx, key, &failsafe, passphrase); err != nil {
+		return nil, false, err
+	}
+
+	_ = w.recordAudit(ctx, "failsafe refresh", fmt.Sprintf("%s SOL from %s to %s", failsafe.SOL(), alias, failsafe.To))
+	return &failsafe, true, nil
+}
+
+// failsafeStale reports whether the nonce of an armed sweep moved on or the balance moved by more
+// than threshold percent from the amount it sweeps.
+func failsafeStale(ctx context.Context, client ClientInterface, failsafe Failsafe, threshold decimal.Decimal) (bool, error) {
+	nonce, err := fetchNonce(ctx, client, solana.MustPublicKeyFromBase58(failsafe.NonceAccount))
+	if err != nil {
+		return false, err
+	}
+	if nonce.Nonce.String() != failsafe.Nonce {
+		return true, nil
+	}
+
+	balance, err := client.GetBalance(ctx, solana.MustPublicKeyFromBase58(failsafe.From), rpc.CommitmentFinalized)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch the balance: %w", err)
+	}
+	sweepable := decimal.NewFromInt(int64(sweepAmount(balance.Value)))
+	swept := decimal.NewFromInt(int64(failsafe.Lamports))
+	return sweepable.Sub(swept).Abs().GreaterThan(swept.Mul(threshold).Div(decimal.NewFromInt(100))), nil
+}
+
+// BroadcastFailsafe sends the stored sweep of alias, decrypting it with passphrase. It needs no
+// private key. A sweep whose nonce moved on is marked invalidated and ErrFailsafeInvalidated is
+// returned.
+func (w *WalletConfig) BroadcastFailsafe(ctx context.Context, alias, passphrase string) (*Failsafe, error) {
+	failsafe, err := w.storedFailsafe(alias)
+	if err != nil {
+		return nil, err
+	}
+	switch failsafe.Status {
+	case FailsafeInvalidated:
+		return nil, ErrFailsafeInvalidated
+	case FailsafeBroadcast:
+		return nil, fmt.Errorf("%w as %s", ErrFailsafeBroadcast, failsafe.Signature)
+	}
+
+	plaintext, err := openWithPassphrase(failsafe.Transaction, failsafe.Salt, failsafe.CipherNonce, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt the failsafe sweep: %w", err)
+	}
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the failsafe sweep: %w", err)
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	nonce, err := fetchNonce(ctx, client, solana.MustPublicKeyFromBase58(failsafe.NonceAccount))
+	if err != nil {
+		return nil, err
+	}
+	if nonce.Nonce.String() != failsafe.Nonce {
+		failsafe.Status = FailsafeInvalidated
+		if err := w.FailsafeStore.Put(failsafe); err != nil {
+			return nil, err
+		}
+		return nil, ErrFailsafeInvalidated
+	}
+
+	sig, err := client.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{PreflightCommitment: rpc.CommitmentFinalized})
+	if err != nil {
+		return nil, fmt.Errorf("failed to broadcast the failsafe sweep: %w", err)
+	}
+	failsafe.Status = FailsafeBroadcast
+	failsafe.Signature = sig.String()
+	if err := w.FailsafeStore.Put(failsafe); err != nil {
+		return nil, err
+	}
+	_ = w.recordAudit(ctx, "failsafe broadcast", fmt.Sprintf("%s SOL from %s to %s (%s)", failsafe.SOL(), alias, failsafe.To, sig))
+
+	if err := awaitSignature(ctx, client, sig); err != nil {
+		return &failsafe, fmt.Errorf("failsafe sweep %s was sent but not confirmed: %w", sig, err)
+	}
+	return &failsafe, nil
+}
+
+// DisarmFailsafe removes the sweep of alias. An armed sweep is first made unusable by advancing its
+// nonce with a transfer of nothing to the wallet itself, so a copy of the file can never be sent.
+func (w *WalletConfig) DisarmFailsafe(ctx context.Context, alias string) error {
+	failsafe, err := w.storedFailsafe(alias)
+	if err != nil {
+		return err
+	}
+
+	if failsafe.Status == FailsafeArmed {
+		if err := w.advanceFailsafeNonce(ctx, alias, failsafe); err != nil {
+			return err
+		}
+	}
+
+	if err := w.FailsafeStore.Delete(failsafe.From); err != nil {
+		return err
+	}
+	_ = w.recordAudit(ctx, "failsafe disarm", alias)
+	return nil
+}
+
+// advanceFailsafeNonce submits a transfer of nothing from the wallet to itself that advances the
+// sweep's nonce, unless something else advanced it already.
+func (w *WalletConfig) advanceFailsafeNonce(ctx context.Context, alias string, failsafe Failsafe) error {
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return err
+	}
+	client := endpoints.client()
+
+	nonceAccount := solana.MustPublicKeyFromBase58(failsafe.NonceAccount)
+	nonce, err := fetchNonce(ctx, client, nonceAccount)
+	if err != nil {
+		return err
+	}
+	if nonce.Nonce.String() != failsafe.Nonce {
+		return nil
+	}
+
+	key, err := w.failsafeKey(alias)
+	if err != nil {
+		return err
+	}
+	tx, err := buildTransfer(key, key.PublicKey(), 0, solana.Hash(nonce.Nonce), nonceAccount, transferExtras{})
+	if err != nil {
+		return err
+	}
+	w.recordKeyUsage(key.PublicKey(), SignedSend, tx.Signatures[0])
+
+	sig, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("failed to advance the failsafe nonce: %w", err)
+	}
+	return awaitSignature(ctx, client, sig)
+}
+
+// Failsafes returns the stored sweeps sorted by alias.
+func (w *WalletConfig) Failsafes() ([]Failsafe, error) {
+	if w.FailsafeStore == nil {
+		return nil, nil
+	}
+	return w.FailsafeStore.List()
+}
+
+// storedFailsafe returns the sweep of the wallet alias.
+func (w *WalletConfig) storedFailsafe(alias string) (Failsafe, error) {
+	if w.FailsafeStore == nil {
+		return Failsafe{}, ErrFailsafeNotArmed
+	}
+	address, err := w.KeyOps.GetPublicKeyByAlias(alias)
+	if err != nil {
+		return Failsafe{}, err
+	}
+	failsafe, err := w.FailsafeStore.Get(address)
+	if err != nil {
+		return Failsafe{}, fmt.Errorf("%w: %s", err, alias)
+	}
+	return failsafe, nil
+}
+
+// failsafeKey returns the private key of the wallet alias.
+func (w *WalletConfig) failsafeKey(alias string) (solana.PrivateKey, error) {
+	encoded, err := w.KeyOps.GetPrivateKeyByAlias(alias)
+	if err != nil {
+		return nil, err
+	}
+	key, err := getPrivateKeyFromSolCLICompStr(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key for %s: %w", alias, err)
+	}
+	return solana.PrivateKey(key), nil
+}
+
+// sweepAmount is what a sweep of balance transfers after its fee.
+func sweepAmount(balance uint64) uint64 {
+	if balance <= lamportsPerSignature {
+		return 0
+	}
+	return balance - lamportsPerSignature
+}
+
+// signFailsafe signs a sweep of the current balance of key to failsafe.To with the current nonce of
+// failsafe.NonceAccount, encrypts it and stores it as armed.
+func (w *WalletConfig) signFailsafe(ctx context.Context, key solana.PrivateKey, failsafe *Failsafe, passphrase string) error {
+	if passphrase == "" {
+		return errors.New("the failsafe sweep needs a passphrase to be encrypted with")
+	}
+	if w.FailsafeStore == nil {
+		return errors.New("no failsafe store configured")
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return err
+	}
+	client := endpoints.client()
+
+	nonceAccount := solana.MustPublicKeyFromBase58(failsafe.NonceAccount)
+	nonce, err := fetchNonce(ctx, client, nonceAccount)
+	if err != nil {
+		return err
+	}
+	if !nonce.AuthorizedPubkey.Equals(key.PublicKey()) {
+		return ErrNonceAuthority
+	}
+
+	balance, err := client.GetBalance(ctx, key.PublicKey(), rpc.CommitmentFinalized)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the balance: %w", err)
+	}
+	lamports := sweepAmount(balance.Value)
+	if lamports == 0 {
+		return ErrNothingToSweep
+	}
+
+	tx, err := buildTransfer(key, solana.MustPublicKeyFromBase58(failsafe.To), lamports, solana.Hash(nonce.Nonce), nonceAccount, transferExtras{})
+	if err != nil {
+		return err
+	}
+	w.recordKeyUsage(key.PublicKey(), SignedSend, tx.Signatures[0])
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode the sweep: %w", err)
+	}
+	ciphertext, salt, cipherNonce, err := sealWithPassphrase(raw, passphrase)
+	if err != nil {
+		return err
+	}
+
+	failsafe.Nonce = nonce.Nonce.String()
+	failsafe.Lamports = lamports
+	failsafe.Network = w.NetworkName()
+	failsafe.Status = FailsafeArmed
+	failsafe.SignedAt = time.Now().UTC()
+	failsafe.Signature = ""
+	failsafe.Transaction, failsafe.Salt, failsafe.CipherNonce = ciphertext, salt, cipherNonce
+	return w.FailsafeStore.Put(*failsafe)
+}
+
+// armedFailsafe returns the armed sweep of the wallet with address from, or nil when it has none.
+func (w *WalletConfig) armedFailsafe(from solana.PublicKey) (*Failsafe, error) {
+	if w.FailsafeStore == nil {
+		return nil, nil
+	}
+	failsafe, err := w.FailsafeStore.Get(from.String())
+	if errors.Is(err, ErrFailsafeNotArmed) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the failsafe sweep: %w", err)
+	}
+	if failsafe.Status != FailsafeArmed {
+		return nil, nil
+	}
+	return &failsafe, nil
+}
+
+// invalidateFailsafe records that a send advanced the nonce of an armed sweep.
+func (w *WalletConfig) invalidateFailsafe(failsafe *Failsafe) error {
+	failsafe.Status = FailsafeInvalidated
+	if err := w.FailsafeStore.Put(*failsafe); err != nil {
+		return fmt.Errorf("failed to record that the failsafe sweep was invalidated: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+const failsafePassphrase = "correct horse battery staple"
+
+// failsafeCluster is a quickstartCluster with a durable nonce account: a transaction that advances
+// the nonce only lands while it names the current nonce, and moves the nonce on when it does.
+type failsafeCluster struct {
+	*quickstartCluster
+	authority solana.PublicKey
+	nonce     solana.Hash
+	advances  int
+}
+
+func (c *failsafeCluster) client(t *testing.T) *MockClientInterface {
+	client := c.quickstartCluster.client(t)
+	send := client.SendTransactionWithOptsFn
+
+	client.GetAccountInfoFn = func(context.Context, solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return nonceAccountInfo(t, c.authority, c.nonce), nil
+	}
+	client.SendTransactionWithOptsFn = func(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+		c.mu.Lock()
+		first := tx.Message.Instructions[0]
+		// The advance nonce instruction is the system instruction index 4 without arguments.
+		if tx.Message.AccountKeys[first.ProgramIDIndex].Equals(solana.SystemProgramID) && len(first.Data) == 4 && first.Data[0] == 4 {
+			if tx.Message.RecentBlockhash != c.nonce {
+				c.mu.Unlock()
+				return solana.Signature{}, errors.New("Transaction simulation failed: Blockhash not found")
+			}
+			c.advances++
+			c.nonce = solana.Hash{byte(c.advances), 42}
+		}
+		c.mu.Unlock()
+		return send(ctx, tx, opts)
+	}
+	client.SendTransactionFn = func(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+		return client.SendTransactionWithOptsFn(ctx, tx, rpc.TransactionOpts{})
+	}
+	return client
+}
+
+// noPrivateKeys is a key store whose private keys cannot be read, like one on a locked machine.
+type noPrivateKeys struct {
+	KeyStore
+}
+
+func (noPrivateKeys) GetPrivateKeyByAlias(alias string) (string, error) {
+	return "", fmt.Errorf("%s: %w", alias, ErrWatchOnlyWallet)
+}
+
+// newFailsafeWallet stores a wallet "main" holding balance lamports and returns it with the cluster
+// it lives on and the address of its nonce account.
+func newFailsafeWallet(t *testing.T, balance uint64) (*WalletConfig, *failsafeCluster, solana.PrivateKey, string) {
+	previous := newRPCClient
+	t.Cleanup(func() { newRPCClient = previous })
+
+	t.Helper()
+	interval := confirmPollInterval
+	t.Cleanup(func() { confirmPollInterval = interval })
+	confirmPollInterval = time.Millisecond
+
+	files := newMemFiles()
+	keyOps := &KeyOps{FileReader: files, FileWriter: files}
+	main := solana.NewWallet()
+	assert.NoError(t, keyOps.WriteKeyToFile("main", []byte(main.PrivateKey), main.PublicKey().String()))
+
+	cluster := &failsafeCluster{
+		quickstartCluster: &quickstartCluster{
+			balances:  map[solana.PublicKey]uint64{main.PublicKey(): balance},
+			transfers: make(map[solana.Signature][2]solana.PublicKey),
+		},
+		authority: main.PublicKey(),
+		nonce:     solana.Hash{9, 9},
+	}
+	newRPCClient = func(string) ClientInterface { return cluster.client(t) }
+
+	wc := &WalletConfig{
+		KeyOps:        keyOps,
+		Network:       Devnet,
+		Pending:       &PendingStore{FileReader: files, FileWriter: files},
+		FailsafeStore: &FailsafeStore{FileReader: files, FileWriter: files},
+	}
+	return wc, cluster, main.PrivateKey, solana.NewWallet().PublicKey().String()
+}
+
+func TestFailsafeLifecycle(t *testing.T) {
+	wc, cluster, key, nonceAccount := newFailsafeWallet(t, 2*solana.LAMPORTS_PER_SOL)
+	recovery := solana.NewWallet().PublicKey()
+	ctx := context.Background()
+
+	failsafe, err := wc.ArmFailsafe(ctx, "main", recovery.String(), nonceAccount, failsafePassphrase)
+	assert.NoError(t, err)
+	assert.Equal(t, FailsafeArmed, failsafe.Status)
+	assert.Equal(t, 2*solana.LAMPORTS_PER_SOL-lamportsPerSignature, failsafe.Lamports)
+	assert.Equal(t, solana.PublicKey(cluster.nonce).String(), failsafe.Nonce)
+	// Arming signs the sweep but never sends it.
+	assert.Zero(t, cluster.advances)
+	assert.Zero(t, cluster.balances[recovery])
+
+	stored, err := wc.Failsafes()
+	assert.NoError(t, err)
+	assert.Equal(t, []Failsafe{*failsafe}, stored)
+	_, err = wc.BroadcastFailsafe(ctx, "main", "wrong passphrase")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+
+	// Broadcasting needs no private key, only the failsafe passphrase.
+	wc.KeyOps = noPrivateKeys{wc.KeyOps}
+	sent, err := wc.BroadcastFailsafe(ctx, "main", failsafePassphrase)
+	assert.NoError(t, err)
+	assert.Equal(t, FailsafeBroadcast, sent.Status)
+	assert.NotEmpty(t, sent.Signature)
+	assert.Equal(t, failsafe.Lamports, cluster.balances[recovery])
+	assert.Zero(t, cluster.balances[key.PublicKey()])
+
+	_, err = wc.BroadcastFailsafe(ctx, "main", failsafePassphrase)
+	assert.ErrorIs(t, err, ErrFailsafeBroadcast)
+}
+
+func TestFailsafeInvalidatedBySend(t *testing.T) {
+	wc, cluster, key, nonceAccount := newFailsafeWallet(t, 2*solana.LAMPORTS_PER_SOL)
+	recovery := solana.NewWallet().PublicKey()
+	ctx := context.Background()
+
+	armed, err := wc.ArmFailsafe(ctx, "main", recovery.String(), nonceAccount, failsafePassphrase)
+	assert.NoError(t, err)
+
+	// A send naming another nonce account would leave the sweep valid, so it is refused.
+	_, err = wc.SendFundsWithOptions(ctx, "0.5", solana.NewWallet().PublicKey().String(), SendOptions{Unit: UnitSOL, NonceAccount: solana.NewWallet().PublicKey().String()})
+	assert.ErrorIs(t, err, ErrFailsafeNonceAccount)
+	assert.Zero(t, cluster.advances)
+
+	_, err = wc.SendFundsWithOptions(ctx, "0.5", solana.NewWallet().PublicKey().String(), SendOptions{Unit: UnitSOL})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cluster.advances)
+
+	stored, err := wc.FailsafeStore.Get(key.PublicKey().String())
+	assert.NoError(t, err)
+	assert.Equal(t, FailsafeInvalidated, stored.Status)
+	_, err = wc.BroadcastFailsafe(ctx, "main", failsafePassphrase)
+	assert.ErrorIs(t, err, ErrFailsafeInvalidated)
+
+	// Even a store that missed the send cannot broadcast the stale sweep: the nonce is checked on chain.
+	stored.Status = FailsafeArmed
+	assert.NoError(t, wc.FailsafeStore.Put(stored))
+	_, err = wc.BroadcastFailsafe(ctx, "main", failsafePassphrase)
+	assert.ErrorIs(t, err, ErrFailsafeInvalidated)
+	assert.Zero(t, cluster.balances[recovery])
+
+	refreshed, changed, err := wc.RefreshFailsafe(ctx, "main", failsafePassphrase, DefaultFailsafeThreshold)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, FailsafeArmed, refreshed.Status)
+	assert.Equal(t, cluster.balances[key.PublicKey()]-lamportsPerSignature, refreshed.Lamports)
+	assert.Less(t, refreshed.Lamports, armed.Lamports)
+
+	_, err = wc.BroadcastFailsafe(ctx, "main", failsafePassphrase)
+	assert.NoError(t, err)
+	assert.Equal(t, refreshed.Lamports, cluster.balances[recovery])
+}
+
+func TestRefreshFailsafeThreshold(t *testing.T) {
+	tests := []struct {
+		name            string
+		received        uint64
+		expectedChanged bool
+	}{
+		{name: "Unchanged", received: 0},
+		{name: "Within Threshold", received: solana.LAMPORTS_PER_SOL / 200},
+		{name: "Above Threshold", received: solana.LAMPORTS_PER_SOL / 20, expectedChanged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc, cluster, key, nonceAccount := newFailsafeWallet(t, solana.LAMPORTS_PER_SOL)
+			armed, err := wc.ArmFailsafe(context.Background(), "main", solana.NewWallet().PublicKey().String(), nonceAccount, failsafePassphrase)
+			assert.NoError(t, err)
+
+			cluster.balances[key.PublicKey()] += tt.received
+			refreshed, changed, err := wc.RefreshFailsafe(context.Background(), "main", failsafePassphrase, decimal.NewFromInt(1))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedChanged, changed)
+			if tt.expectedChanged {
+				assert.Equal(t, armed.Lamports+tt.received, refreshed.Lamports)
+			} else {
+				assert.Equal(t, *armed, *refreshed)
+			}
+			// Refreshing signs again with the same nonce, so the old sweep and the new one cannot both land.
+			assert.Equal(t, armed.Nonce, refreshed.Nonce)
+			assert.Zero(t, cluster.advances)
+		})
+	}
+}
+
+func TestArmFailsafeRefusals(t *testing.T) {
+	tests := []struct {
+		name         string
+		balance      uint64
+		authority    solana.PublicKey
+		recovery     string
+		noPassphrase bool
+		expectedErr  error
+	}{
+		{name: "Another Nonce Authority", balance: solana.LAMPORTS_PER_SOL, authority: solana.NewWallet().PublicKey(), expectedErr: ErrNonceAuthority},
+		{name: "Nothing To Sweep", balance: lamportsPerSignature, expectedErr: ErrNothingToSweep},
+		{name: "Recovery Is The Wallet", balance: solana.LAMPORTS_PER_SOL, recovery: "main"},
+		{name: "No Passphrase", balance: solana.LAMPORTS_PER_SOL, noPassphrase: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc, cluster, _, nonceAccount := newFailsafeWallet(t, tt.balance)
+			if !tt.authority.IsZero() {
+				cluster.authority = tt.authority
+			}
+			recovery, passphrase := tt.recovery, failsafePassphrase
+			if recovery == "" {
+				recovery = solana.NewWallet().PublicKey().String()
+			}
+			if tt.noPassphrase {
+				passphrase = ""
+			}
+
+			_, err := wc.ArmFailsafe(context.Background(), "main", recovery, nonceAccount, passphrase)
+			assert.Error(t, err)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			}
+			stored, err := wc.Failsafes()
+			assert.NoError(t, err)
+			assert.Empty(t, stored)
+		})
+	}
+}
+
+func TestDisarmFailsafe(t *testing.T) {
+	wc, cluster, _, nonceAccount := newFailsafeWallet(t, solana.LAMPORTS_PER_SOL)
+	recovery := solana.NewWallet().PublicKey()
+	ctx := context.Background()
+
+	armed, err := wc.ArmFailsafe(ctx, "main", recovery.String(), nonceAccount, failsafePassphrase)
+	assert.NoError(t, err)
+
+	assert.NoError(t, wc.DisarmFailsafe(ctx, "main"))
+	assert.Equal(t, 1, cluster.advances)
+	stored, err := wc.Failsafes()
+	assert.NoError(t, err)
+	assert.Empty(t, stored)
+	_, err = wc.BroadcastFailsafe(ctx, "main", failsafePassphrase)
+	assert.ErrorIs(t, err, ErrFailsafeNotArmed)
+
+	// A copy of the sweep kept from before can no longer land.
+	assert.NoError(t, wc.FailsafeStore.Put(*armed))
+	_, err = wc.BroadcastFailsafe(ctx, "main", failsafePassphrase)
+	assert.ErrorIs(t, err, ErrFailsafeInvalidated)
+	assert.Zero(t, cluster.balances[recovery])
+
+	// Disarming an invalidated sweep only forgets it.
+	assert.NoError(t, wc.DisarmFailsafe(ctx, "main"))
+	assert.Equal(t, 1, cluster.advances)
+}
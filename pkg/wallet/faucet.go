@@ -0,0 +1,148 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// maxAirdropLamports is the most the public devnet faucet hands out per request.
+	maxAirdropLamports = 2 * solana.LAMPORTS_PER_SOL
+	// airdropRetries is how often a rate-limited airdrop request is retried.
+	airdropRetries = 3
+	// airdropTimeout bounds how long to wait for an airdrop to be confirmed.
+	airdropTimeout = time.Minute
+)
+
+// DefaultAirdrop is how much Airdrop requests when no amount is given.
+const DefaultAirdrop = solana.LAMPORTS_PER_SOL
+
+var (
+	ErrAutoFundUnavailable = errors.New("auto-fund is only available on devnet")
+	ErrAirdropUnavailable  = errors.New("mainnet-beta has no faucet")
+)
+
+// AirdropReceipt is the outcome of an airdrop.
+type AirdropReceipt struct {
+	Address   string
+	Signature string
+	Lamports  uint64
+	// Balance is the balance of the wallet once the airdrop was confirmed, in lamports.
+	Balance uint64
+}
+
+// airdropRetryDelay is the delay before the first airdrop retry; it doubles with every attempt.
+var airdropRetryDelay = 2 * time.Second
+
+// autoFund requests a devnet airdrop covering the shortfall, waits for it to be confirmed and returns
+// the new balance. It refuses to run on any other network.
+func (w *WalletConfig) autoFund(ctx context.Context, client ClientInterface, account solana.PublicKey, shortfall uint64) (uint64, error) {
+	network := w.Network
+	if network == "" {
+		network = DefaultNetwork
+	}
+	if network != Devnet {
+		return 0, fmt.Errorf("%w, not %s", ErrAutoFundUnavailable, network)
+	}
+
+	if shortfall > maxAirdropLamports {
+		return 0, fmt.Errorf("shortfall of %s SOL exceeds the faucet limit of %s SOL",
+			LamportsToSOL(shortfall), LamportsToSOL(maxAirdropLamports))
+	}
+
+	sig, err := requestAirdrop(ctx, client, account, shortfall)
+	if err != nil {
+		return 0, err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, airdropTimeout)
+	defer cancel()
+
+	if err := awaitSignature(waitCtx, client, sig); err != nil {
+		return 0, fmt.Errorf("airdrop was not confirmed: %w", err)
+	}
+
+	balance, err := client.GetBalance(ctx, account, rpc.CommitmentConfirmed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch balance: %w", err)
+	}
+
+	return balance.Value, nil
+}
+
+// Airdrop requests lamports from the faucet of the selected cluster, such as devnet, testnet or a
+// local test validator, for the wallet under alias, or the active wallet when alias is empty. It waits
+// for the airdrop to be confirmed and returns the new balance. Zero lamports requests DefaultAirdrop.
+// Mainnet has no faucet, so it is refused.
+func (w *WalletConfig) Airdrop(ctx context.Context, alias string, lamports uint64) (*AirdropReceipt, error) {
+	if w.SelectedNetwork() == MainnetBeta {
+		return nil, ErrAirdropUnavailable
+	}
+	if lamports == 0 {
+		lamports = DefaultAirdrop
+	}
+	if lamports > maxAirdropLamports {
+		return nil, fmt.Errorf("an airdrop of %s SOL exceeds the faucet limit of %s SOL", LamportsToSOL(lamports), LamportsToSOL(maxAirdropLamports))
+	}
+
+	account, err := w.ownerPublicKey(alias)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	sig, err := requestAirdrop(ctx, client, account, lamports)
+	if err != nil {
+		return nil, err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, airdropTimeout)
+	defer cancel()
+	if err := awaitSignature(waitCtx, client, sig); err != nil {
+		return nil, fmt.Errorf("airdrop %s was not confirmed: %w", sig, err)
+	}
+
+	balance, err := client.GetBalance(ctx, account, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch balance: %w", err)
+	}
+
+	return &AirdropReceipt{Address: account.String(), Signature: sig.String(), Lamports: lamports, Balance: balance.Value}, nil
+}
+
+// requestAirdrop asks the faucet for lamports, retrying with backoff while it is rate limited.
+func requestAirdrop(ctx context.Context, client ClientInterface, account solana.PublicKey, lamports uint64) (solana.Signature, error) {
+	var lastErr error
+	delay := airdropRetryDelay
+	for attempt := 0; attempt < airdropRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return solana.Signature{}, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		sig, err := client.RequestAirdrop(ctx, account, lamports, rpc.CommitmentConfirmed)
+		if err == nil {
+			return sig, nil
+		}
+
+		lastErr = err
+		if !isRateLimited(err) {
+			break
+		}
+	}
+
+	return solana.Signature{}, fmt.Errorf("airdrop failed: %w", lastErr)
+}
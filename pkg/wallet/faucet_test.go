@@ -0,0 +1,137 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareSendAutoFund(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	sender := solana.NewWallet()
+	recipient := solana.NewWallet().PublicKey().String()
+	fee := uint64(5000)
+	defer func(delay time.Duration) { airdropRetryDelay = delay }(airdropRetryDelay)
+	airdropRetryDelay = 0
+
+	tests := []struct {
+		name            string
+		network         Network
+		balance         uint64
+		amount          string
+		airdropErrs     []error
+		expectedAirdrop uint64
+		expectedCalls   int
+		expectFundErr   bool
+	}{
+		{name: "Shortfall Is Airdropped", network: Devnet, balance: 100_000, amount: "500000", expectedAirdrop: 405_000, expectedCalls: 1},
+		{name: "Rate Limited Then Funded", network: Devnet, balance: 100_000, amount: "500000", airdropErrs: []error{errors.New("429 Too Many Requests")}, expectedAirdrop: 405_000, expectedCalls: 2},
+		{name: "Faucet Keeps Rate Limiting", network: Devnet, balance: 100_000, amount: "500000", airdropErrs: []error{errors.New("rate limit"), errors.New("rate limit"), errors.New("rate limit")}, expectedCalls: airdropRetries, expectFundErr: true},
+		{name: "Faucet Failure Is Not Retried", network: Devnet, balance: 100_000, amount: "500000", airdropErrs: []error{errors.New("faucet has run dry")}, expectedCalls: 1, expectFundErr: true},
+		{name: "Shortfall Above Faucet Limit", network: Devnet, balance: 0, amount: "5000000000", expectFundErr: true},
+		{name: "Disabled On Mainnet", network: MainnetBeta, balance: 100_000, amount: "500000", expectFundErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance := tt.balance
+			calls := 0
+
+			newRPCClient = func(string) ClientInterface {
+				return &MockClientInterface{
+					GetBalanceFn: func(ctx context.Context, publicKey solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+						return &rpc.GetBalanceResult{Value: balance}, nil
+					},
+					GetLatestBlockhashFn: func(ctx context.Context, _ rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+						return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}}}, nil
+					},
+					GetFeeForMessageFn: func(ctx context.Context, message string, _ rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+						return &rpc.GetFeeForMessageResult{Value: &fee}, nil
+					},
+					RequestAirdropFn: func(ctx context.Context, account solana.PublicKey, lamports uint64, _ rpc.CommitmentType) (solana.Signature, error) {
+						calls++
+						if calls <= len(tt.airdropErrs) {
+							return solana.Signature{}, tt.airdropErrs[calls-1]
+						}
+						assert.Equal(t, tt.expectedAirdrop, lamports)
+						balance += lamports
+						return solana.Signature{1}, nil
+					},
+					GetSignatureStatusesFn: func(ctx context.Context, _ bool, _ ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+						return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}}}, nil
+					},
+					GetMinimumBalanceForRentExemptionFn: rentExemptMinimum,
+				}
+			}
+
+			wc := &WalletConfig{Wallet: sender, Network: tt.network}
+			quote, err := wc.PrepareSend(context.Background(), tt.amount, recipient, SendOptions{Unit: UnitLamports, AutoFund: true})
+			assert.Equal(t, tt.expectedCalls, calls)
+
+			if tt.expectFundErr {
+				// A failed top-up falls back to the normal insufficient-funds error.
+				var insufficient *InsufficientFundsError
+				assert.True(t, errors.As(err, &insufficient))
+				assert.ErrorIs(t, err, ErrInsufficientFunds)
+				assert.Error(t, insufficient.AutoFundErr)
+				if tt.network == MainnetBeta {
+					assert.ErrorIs(t, insufficient.AutoFundErr, ErrAutoFundUnavailable)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedAirdrop, quote.AutoFunded)
+			assert.Equal(t, tt.balance+tt.expectedAirdrop, quote.Balance)
+		})
+	}
+}
+
+func TestAirdrop(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	owner := solana.NewWallet()
+	defer func(delay time.Duration) { airdropRetryDelay = delay }(airdropRetryDelay)
+	airdropRetryDelay = 0
+
+	var balance, requested uint64
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			RequestAirdropFn: func(_ context.Context, account solana.PublicKey, lamports uint64, _ rpc.CommitmentType) (solana.Signature, error) {
+				assert.Equal(t, owner.PublicKey(), account)
+				requested = lamports
+				balance += lamports
+				return solana.Signature{7}, nil
+			},
+			GetSignatureStatusesFn: func(context.Context, bool, ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+				return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}}}, nil
+			},
+			GetBalanceFn: func(context.Context, solana.PublicKey, rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				return &rpc.GetBalanceResult{Value: balance}, nil
+			},
+		}
+	}
+	ctx := context.Background()
+
+	receipt, err := (&WalletConfig{Wallet: owner, Network: Testnet}).Airdrop(ctx, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(DefaultAirdrop), requested)
+	assert.Equal(t, &AirdropReceipt{Address: owner.PublicKey().String(), Signature: solana.Signature{7}.String(), Lamports: DefaultAirdrop, Balance: DefaultAirdrop}, receipt)
+
+	receipt, err = (&WalletConfig{Wallet: owner}).Airdrop(ctx, "", 500_000_000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1_500_000_000), receipt.Balance)
+
+	_, err = (&WalletConfig{Wallet: owner}).Airdrop(ctx, "", 3*solana.LAMPORTS_PER_SOL)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "faucet limit")
+	}
+	_, err = (&WalletConfig{Wallet: owner, Network: MainnetBeta}).Airdrop(ctx, "", 0)
+	assert.ErrorIs(t, err, ErrAirdropUnavailable)
+}
@@ -0,0 +1,61 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrKeyFileLocked is returned when another process keeps the key file locked for longer than
+// keyFileLockTimeout.
+var ErrKeyFileLocked = errors.New("key file is locked by another sleeng process")
+
+// keyFileLockTimeout bounds how long an update waits for another process to finish its own.
+var keyFileLockTimeout = 10 * time.Second
+
+// keyFileLockPoll is how often a held lock is tried again.
+const keyFileLockPoll = 20 * time.Millisecond
+
+// FileLocker is implemented by FileWriters that can hold an advisory lock on a file across processes.
+// KeyOps holds it around every read-modify-write of the key file, so concurrent sleeng invocations
+// apply their updates one after the other instead of overwriting each other's.
+type FileLocker interface {
+	// LockFile locks filename exclusively and returns the function that releases the lock.
+	LockFile(filename string) (unlock func(), err error)
+}
+
+// LockFile takes an exclusive advisory lock on filename, using a ".lock" file next to it so the lock
+// survives the file being replaced by an atomic write. The lock file is left in place: removing it
+// would let two processes lock different files.
+func (w *IOUtilFileWriter) LockFile(filename string) (func(), error) {
+	path := filename + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file %s: %w", path, err)
+	}
+
+	deadline := time.Now().Add(keyFileLockTimeout)
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("error locking %s: %w", path, err)
+		}
+		if locked {
+			return func() {
+				_ = unlockFile(f)
+				f.Close()
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w: %s", ErrKeyFileLocked, filename)
+		}
+		time.Sleep(keyFileLockPoll)
+	}
+}
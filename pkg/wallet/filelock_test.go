@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDiskKeyOps(dir string) *KeyOps {
+	return &KeyOps{FileReader: &IOUtilFileReader{}, FileWriter: &IOUtilFileWriter{}, Dir: dir}
+}
+
+func TestInterruptedWriteKeepsTheOriginal(t *testing.T) {
+	defer func(sync func(*os.File) error) { syncFile = sync }(syncFile)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, KeyFileName)
+	writer := &IOUtilFileWriter{}
+	assert.NoError(t, writer.WriteFile(path, []byte(`{"wallets":{}}`)))
+
+	syncFile = func(*os.File) error { return errors.New("power cut") }
+	err := writer.WriteFile(path, []byte(`{"wallets":{"main":`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "power cut")
+	}
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"wallets":{}}`, string(data))
+
+	// The temporary file is cleaned up.
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestConcurrentWritersDoNotLoseUpdates(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, newDiskKeyOps(dir).SetNetwork("devnet", ""))
+
+	// Separate KeyOps stand in for separate processes: only the file lock serialises them.
+	const writers, contactsEach = 4, 10
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		keyOps := newDiskKeyOps(dir)
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < contactsEach; i++ {
+				assert.NoError(t, keyOps.AddContact(fmt.Sprintf("contact-%d-%d", w, i), solana.NewWallet().PublicKey().String()))
+			}
+		}()
+	}
+	wg.Wait()
+
+	contacts, err := newDiskKeyOps(dir).Contacts()
+	assert.NoError(t, err)
+	assert.Len(t, contacts, writers*contactsEach)
+}
+
+func TestKeyFileLockTimesOut(t *testing.T) {
+	defer func(timeout time.Duration) { keyFileLockTimeout = timeout }(keyFileLockTimeout)
+	keyFileLockTimeout = 50 * time.Millisecond
+
+	dir := t.TempDir()
+	unlock, err := (&IOUtilFileWriter{}).LockFile(filepath.Join(dir, KeyFileName))
+	assert.NoError(t, err)
+
+	err = newDiskKeyOps(dir).SetNetwork("devnet", "")
+	assert.True(t, errors.Is(err, ErrKeyFileLocked))
+
+	unlock()
+	assert.NoError(t, newDiskKeyOps(dir).SetNetwork("devnet", ""))
+}
+
+func TestCorruptedKeyFilePointsAtBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, KeyFileName)
+	keyOps := newDiskKeyOps(dir)
+
+	// Without a backup the error only names the key file.
+	assert.NoError(t, os.WriteFile(path, []byte(`{"wallets":{"main":`), 0600))
+	_, err := keyOps.ListAliases()
+	assert.True(t, errors.Is(err, ErrKeyFileCorrupted))
+	assert.NotContains(t, err.Error(), backupSuffix)
+
+	assert.NoError(t, os.Remove(path))
+	mum := solana.NewWallet().PublicKey().String()
+	assert.NoError(t, keyOps.AddContact("mum", mum))
+	assert.NoError(t, keyOps.AddContact("dad", solana.NewWallet().PublicKey().String()))
+
+	// A truncated key file, as left by a crash before writes were atomic.
+	assert.NoError(t, os.WriteFile(path, []byte(`{"wallets":{},"contacts":{"mum":`), 0600))
+	_, err = keyOps.Contacts()
+	assert.True(t, errors.Is(err, ErrKeyFileCorrupted))
+	assert.Contains(t, err.Error(), "the last good version is kept in "+path+backupSuffix)
+
+	// The backup is the version before the last write, and a corrupted file never replaces it.
+	assert.Error(t, keyOps.AddContact("gran", solana.NewWallet().PublicKey().String()))
+	assert.NoError(t, os.Rename(path+backupSuffix, path))
+	contacts, err := keyOps.Contacts()
+	assert.NoError(t, err)
+	assert.Equal(t, []Contact{{Name: "mum", Address: mum}}, contacts)
+}
+
+func TestBackupKeepsNoRetiredKeys(t *testing.T) {
+	main := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	spare := ed25519.NewKeyFromSeed(append(make([]byte, ed25519.SeedSize-1), 1))
+
+	tests := []struct {
+		name    string
+		retire  func(k *KeyOps) error
+		retired []string
+	}{
+		{
+			name:    "Remove",
+			retire:  func(k *KeyOps) error { _, err := k.DeleteKey("spare"); return err },
+			retired: []string{"spare"},
+		},
+		{
+			name:    "Encrypt",
+			retire:  func(k *KeyOps) error { _, err := k.EncryptKeys("correct horse"); return err },
+			retired: []string{"main", "spare"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			ops := &KeyOps{FileReader: files, FileWriter: files}
+			assert.NoError(t, ops.WriteKeyToFile("main", main, "mainAddress"))
+			assert.NoError(t, ops.WriteKeyToFile("spare", spare, "spareAddress"))
+			// Another write, so the backup holds both plaintext keys.
+			assert.NoError(t, ops.AddContact("mum", solana.NewWallet().PublicKey().String()))
+
+			before, err := parseWalletData(files.files[KeyFileName])
+			assert.NoError(t, err)
+			assert.NoError(t, tt.retire(ops))
+
+			backup, exists := files.files[KeyFileName+backupSuffix]
+			assert.True(t, exists)
+			_, err = parseWalletData(backup)
+			assert.NoError(t, err)
+			for _, alias := range tt.retired {
+				assert.NotContains(t, string(backup), before.Wallets[alias].PrivateKey, alias)
+			}
+			assert.Equal(t, files.files[KeyFileName], backup)
+		})
+	}
+}
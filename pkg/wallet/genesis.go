@@ -0,0 +1,238 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// knownGenesisHashes are the genesis hashes of the public clusters. An endpoint selected for one of
+// these networks that reports another hash serves a different cluster, such as a local test
+// validator or a fork, whose history and accounts mean nothing for the network.
+var knownGenesisHashes = map[Network]string{
+	MainnetBeta: "5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d",
+	Devnet:      "EtWTRABZaYq6iMfeYKouRu166VU2xqa1wcaWoxPkrZBG",
+	Testnet:     "4uhcVJyU9pJkvQyS88uRDiswHXSCkY3zQawwpjk2NsNY",
+}
+
+var ErrGenesisMismatch = errors.New("RPC endpoint serves another cluster than the selected network")
+
+// GenesisCacheFileName is the file, in the cache directory, holding the genesis hash of each endpoint.
+const GenesisCacheFileName = "genesis.json"
+
+// genesisCacheTTL is how long a cached genesis hash is trusted. The genesis of a cluster never
+// changes, but the cluster behind an endpoint can, so the hash is fetched again once a day.
+const genesisCacheTTL = 24 * time.Hour
+
+// GenesisCheck compares the genesis hash an endpoint reported with the one of the selected network.
+type GenesisCheck struct {
+	Network  Network
+	Endpoint string
+	// Hash is the genesis hash of the endpoint.
+	Hash string
+	// Expected is the genesis hash of Network, empty for custom clusters, which are not checked.
+	Expected string
+	// Cached is set when Hash was taken from the cache instead of the endpoint.
+	Cached bool
+}
+
+// Exempt reports whether the network has no known genesis, as for custom clusters.
+func (c GenesisCheck) Exempt() bool {
+	return c.Expected == ""
+}
+
+// Mismatch reports whether the endpoint serves another cluster than the network.
+func (c GenesisCheck) Mismatch() bool {
+	return !c.Exempt() && c.Hash != c.Expected
+}
+
+// Err returns an error wrapping ErrGenesisMismatch on a mismatch, and nil otherwise.
+func (c GenesisCheck) Err() error {
+	if !c.Mismatch() {
+		return nil
+	}
+	return fmt.Errorf("%w: %s has genesis %s, but %s has %s", ErrGenesisMismatch, c.Endpoint, c.Hash, c.Network, c.Expected)
+}
+
+// GenesisCache keeps the genesis hash of each endpoint, so the check costs one request per endpoint
+// a day. Hashes are not stored in read-only mode, and failing to store one only means it is fetched again.
+type GenesisCache struct {
+	FileReader FileReader
+	FileWriter FileWriter
+	Dir        string
+	// Now returns the current time; it is a field so tests can expire entries.
+	Now func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewGenesisCache creates a cache in dir.
+func NewGenesisCache(dir string) *GenesisCache {
+	return &GenesisCache{FileReader: &IOUtilFileReader{}, FileWriter: newFileWriter(), Dir: dir, Now: time.Now}
+}
+
+// DefaultGenesisCache returns a cache kept under the configuration directory, next to the OHLC cache.
+func DefaultGenesisCache() *GenesisCache {
+	dir, err := ConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return NewGenesisCache(filepath.Join(dir, "cache"))
+}
+
+// genesisCacheEntry is the genesis hash of an endpoint and when it was fetched.
+type genesisCacheEntry struct {
+	Hash    string    `json:"hash"`
+	Fetched time.Time `json:"fetched"`
+}
+
+func (c *GenesisCache) filePath() string {
+	return filepath.Join(c.Dir, GenesisCacheFileName)
+}
+
+// entries reads the cache. A missing or unreadable cache is empty.
+func (c *GenesisCache) entries() map[string]genesisCacheEntry {
+	entries := make(map[string]genesisCacheEntry)
+	data, err := c.FileReader.ReadFile(c.filePath())
+	if err == nil {
+		_ = json.Unmarshal(data, &entries)
+	}
+	return entries
+}
+
+// lookup returns the cached genesis hash of endpoint while it is fresh.
+func (c *GenesisCache) lookup(endpoint string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries()[endpoint]
+	if !ok || c.Now().Sub(entry.Fetched) > genesisCacheTTL {
+		return "", false
+	}
+	return entry.Hash, true
+}
+
+// store records the genesis hash of endpoint.
+func (c *GenesisCache) store(endpoint, hash string) {
+	if ReadOnlyMode() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := c.entries()
+	entries[endpoint] = genesisCacheEntry{Hash: hash, Fetched: c.Now().UTC()}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return
+	}
+	_ = c.FileWriter.WriteFile(c.filePath(), data)
+}
+
+// checkGenesis compares the genesis hash of endpoint with the one of network. The hash is taken from
+// cache while fresh, unless refresh asks for it to be fetched again.
+func checkGenesis(ctx context.Context, client ClientInterface, cache *GenesisCache, network Network, endpoint string, refresh bool) (GenesisCheck, error) {
+	check := GenesisCheck{Network: network, Endpoint: endpoint, Expected: knownGenesisHashes[network]}
+
+	if cache != nil && !refresh {
+		if hash, ok := cache.lookup(endpoint); ok {
+			check.Hash, check.Cached = hash, true
+			return check, nil
+		}
+	}
+
+	hash, err := client.GetGenesisHash(ctx)
+	if err != nil {
+		return check, fmt.Errorf("failed to fetch the genesis hash of %s: %w", endpoint, err)
+	}
+	check.Hash = hash.String()
+	if cache != nil {
+		cache.store(endpoint, check.Hash)
+	}
+	return check, nil
+}
+
+// GenesisOptions enables checking that the RPC endpoint serves the cluster of the selected network.
+type GenesisOptions struct {
+	// Cache keeps the genesis hash of each endpoint; nil fetches it for every WalletConfig.
+	Cache *GenesisCache
+	// Strict fails every operation that talks to the cluster on a mismatch, instead of only reporting it.
+	Strict bool
+	// OnMismatch is called once when the endpoint serves another cluster than the network.
+	OnMismatch func(GenesisCheck)
+}
+
+// genesisState is the outcome of the genesis check of a WalletConfig, made on first use of the cluster.
+type genesisState struct {
+	opts  GenesisOptions
+	once  sync.Once
+	check *GenesisCheck
+}
+
+// UseGenesisCheck checks the genesis hash of the RPC endpoint the first time an operation needs the
+// cluster. An endpoint that cannot be reached is not reported: the operation itself will fail.
+func (w *WalletConfig) UseGenesisCheck(opts GenesisOptions) {
+	w.genesis = &genesisState{opts: opts}
+}
+
+// verifyGenesis makes the genesis check of endpoint once and, under Strict, fails on a mismatch.
+func (w *WalletConfig) verifyGenesis(network Network, endpoint string) error {
+	state := w.genesis
+	state.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+		defer cancel()
+
+		check, err := checkGenesis(ctx, newRPCClient(endpoint), state.opts.Cache, network, endpoint, false)
+		if err != nil {
+			return
+		}
+		state.check = &check
+		if check.Mismatch() && state.opts.OnMismatch != nil {
+			state.opts.OnMismatch(check)
+		}
+	})
+
+	if state.opts.Strict && state.check != nil {
+		return state.check.Err()
+	}
+	return nil
+}
+
+// customGenesis returns the genesis hash of a custom cluster once it has been checked, so sends on it
+// can be told apart in receipts and the audit log. It is empty for the public networks.
+func (w *WalletConfig) customGenesis() string {
+	if w.genesis == nil || w.genesis.check == nil || !w.genesis.check.Exempt() {
+		return ""
+	}
+	return w.genesis.check.Hash
+}
+
+// CheckGenesis fetches the genesis hash of the selected endpoint and compares it with the one of the
+// selected network, updating the cache of UseGenesisCheck when set.
+func (w *WalletConfig) CheckGenesis(ctx context.Context) (GenesisCheck, error) {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	n := w.Network
+	if n == "" {
+		n = DefaultNetwork
+	}
+	endpoints, err := resolveEndpoints(n, w.RPCURL)
+	if err != nil {
+		return GenesisCheck{}, err
+	}
+
+	var cache *GenesisCache
+	if w.genesis != nil {
+		cache = w.genesis.opts.Cache
+	}
+	return checkGenesis(ctx, newRPCClient(endpoints.RPC), cache, n, endpoints.RPC, true)
+}
@@ -0,0 +1,148 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKnownGenesisHashes(t *testing.T) {
+	for _, network := range []Network{MainnetBeta, Devnet, Testnet} {
+		hash, ok := knownGenesisHashes[network]
+		if assert.True(t, ok, network) {
+			_, err := solana.HashFromBase58(hash)
+			assert.NoError(t, err, network)
+		}
+	}
+	_, ok := knownGenesisHashes[Custom]
+	assert.False(t, ok)
+}
+
+func TestGenesisCache(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	files := newMemFiles()
+	cache := &GenesisCache{FileReader: files, FileWriter: files, Dir: t.TempDir(), Now: func() time.Time { return now }}
+
+	fetches := 0
+	client := &MockClientInterface{GetGenesisHashFn: func(context.Context) (solana.Hash, error) {
+		fetches++
+		return solana.MustHashFromBase58(knownGenesisHashes[Devnet]), nil
+	}}
+	check := func(refresh bool) GenesisCheck {
+		c, err := checkGenesis(context.Background(), client, cache, Devnet, "https://rpc.example", refresh)
+		assert.NoError(t, err)
+		return c
+	}
+
+	assert.False(t, check(false).Cached)
+	assert.True(t, check(false).Cached)
+	assert.Equal(t, 1, fetches)
+
+	// A refresh, as done by doctor, always asks the endpoint.
+	assert.False(t, check(true).Cached)
+	assert.Equal(t, 2, fetches)
+
+	now = now.Add(genesisCacheTTL + time.Minute)
+	assert.False(t, check(false).Cached)
+	assert.Equal(t, 3, fetches)
+
+	// Other endpoints are cached separately.
+	_, ok := cache.lookup("https://other.example")
+	assert.False(t, ok)
+
+	SetReadOnlyMode(true)
+	defer SetReadOnlyMode(false)
+	now = now.Add(genesisCacheTTL + time.Minute)
+	check(false)
+	assert.False(t, check(false).Cached, "read-only mode stores nothing")
+}
+
+func TestCheckGenesis(t *testing.T) {
+	localValidator := solana.Hash{7}
+
+	tests := []struct {
+		name       string
+		network    Network
+		hash       solana.Hash
+		fetchErr   error
+		wantErr    string
+		exempt     bool
+		mismatch   bool
+		strictFail bool
+	}{
+		{name: "Devnet", network: Devnet, hash: solana.MustHashFromBase58(knownGenesisHashes[Devnet])},
+		{name: "Mainnet", network: MainnetBeta, hash: solana.MustHashFromBase58(knownGenesisHashes[MainnetBeta])},
+		{name: "Mainnet On Devnet Endpoint", network: MainnetBeta, hash: solana.MustHashFromBase58(knownGenesisHashes[Devnet]), mismatch: true, strictFail: true},
+		{name: "Local Validator As Devnet", network: Devnet, hash: localValidator, mismatch: true, strictFail: true},
+		{name: "Custom Cluster", network: Custom, hash: localValidator, exempt: true},
+		{name: "Unreachable Endpoint", network: Devnet, fetchErr: errors.New("connection refused"), wantErr: "failed to fetch the genesis hash of https://rpc.example: connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &MockClientInterface{GetGenesisHashFn: func(context.Context) (solana.Hash, error) {
+				return tt.hash, tt.fetchErr
+			}}
+			check, err := checkGenesis(context.Background(), client, nil, tt.network, "https://rpc.example", false)
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, tt.wantErr, err.Error())
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.hash.String(), check.Hash)
+			assert.Equal(t, tt.exempt, check.Exempt())
+			assert.Equal(t, tt.mismatch, check.Mismatch())
+			assert.Equal(t, tt.strictFail, errors.Is(check.Err(), ErrGenesisMismatch))
+		})
+	}
+}
+
+func TestVerifyGenesis(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	localValidator := solana.Hash{7}
+	fetches := 0
+	client := &MockClientInterface{GetGenesisHashFn: func(context.Context) (solana.Hash, error) {
+		fetches++
+		return localValidator, nil
+	}}
+	newRPCClient = func(string) ClientInterface { return client }
+
+	tests := []struct {
+		name       string
+		network    string
+		rpcURL     string
+		strict     bool
+		wantErr    bool
+		mismatches int
+		genesis    string
+	}{
+		{name: "Warns", network: "devnet", rpcURL: "http://127.0.0.1:8899", mismatches: 1},
+		{name: "Strict Fails", network: "devnet", rpcURL: "http://127.0.0.1:8899", strict: true, wantErr: true, mismatches: 1},
+		{name: "Custom Is Recorded", rpcURL: "http://127.0.0.1:8899", genesis: localValidator.String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetches = 0
+			mismatches := 0
+			wc := &WalletConfig{}
+			assert.NoError(t, wc.setNetwork(tt.network, tt.rpcURL))
+			wc.UseGenesisCheck(GenesisOptions{Strict: tt.strict, OnMismatch: func(GenesisCheck) { mismatches++ }})
+
+			for i := 0; i < 2; i++ {
+				_, err := wc.Endpoints()
+				assert.Equal(t, tt.wantErr, errors.Is(err, ErrGenesisMismatch))
+			}
+			assert.Equal(t, 1, fetches, "the check is made once")
+			assert.Equal(t, tt.mismatches, mismatches)
+			assert.Equal(t, tt.genesis, wc.customGenesis())
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package wallet
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// HistoryCacheDirName is the directory, in the cache directory, holding the synced history of each
+// wallet, in a file per cluster and wallet address.
+const HistoryCacheDirName = "history"
+
+// historyCacheVersion is bumped whenever the cached transfers change shape; older files count as empty.
+const historyCacheVersion = 2
+
+// HistoryCache keeps the transaction history of each wallet on disk, so the next fetch only asks the
+// node for what happened since. Transactions are decoded once and kept by signature, and the
+// signatures of an address are kept once they reach back to its first transaction, after which only
+// newer ones are listed. Only the public clusters are cached: a local validator is reset at will and
+// a custom cluster could be anything. Like GenesisCache it is only a cache: an unreadable file counts
+// as empty, failing to store the history only means it is fetched again, and nothing is stored in
+// read-only mode.
+type HistoryCache struct {
+	FileReader FileReader
+	FileWriter FileWriter
+	Dir        string
+
+	mu sync.Mutex
+}
+
+// NewHistoryCache creates a cache in dir.
+func NewHistoryCache(dir string) *HistoryCache {
+	return &HistoryCache{FileReader: &IOUtilFileReader{}, FileWriter: newFileWriter(), Dir: dir}
+}
+
+// DefaultHistoryCache returns a cache kept under the configuration directory, next to the OHLC cache.
+func DefaultHistoryCache() *HistoryCache {
+	dir, err := ConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return NewHistoryCache(filepath.Join(dir, "cache", HistoryCacheDirName))
+}
+
+// walletHistory is the cached history of a wallet on a cluster. A nil history caches nothing.
+type walletHistory struct {
+	Version int `json:"version"`
+	// Addresses are the signatures of the wallet and of each of its token accounts, newest first,
+	// stored once they reach back to the first transaction of the address.
+	Addresses map[string][]*rpc.TransactionSignature `json:"addresses"`
+	// Transfers are the transfers of each fetched transaction; one that moved nothing of the wallet has none.
+	Transfers map[solana.Signature][]*Transaction `json:"transfers"`
+}
+
+func newWalletHistory() *walletHistory {
+	return &walletHistory{
+		Version:   historyCacheVersion,
+		Addresses: make(map[string][]*rpc.TransactionSignature),
+		Transfers: make(map[solana.Signature][]*Transaction),
+	}
+}
+
+// addressSignatures returns the signatures of address and whether they reach back to its first transaction.
+func (h *walletHistory) addressSignatures(address solana.PublicKey) ([]*rpc.TransactionSignature, bool) {
+	if h == nil {
+		return nil, false
+	}
+	signatures, ok := h.Addresses[address.String()]
+	return signatures, ok
+}
+
+// setAddressSignatures records every signature of address, newest first.
+func (h *walletHistory) setAddressSignatures(address solana.PublicKey, signatures []*rpc.TransactionSignature) {
+	if h != nil {
+		h.Addresses[address.String()] = signatures
+	}
+}
+
+// transfers returns the transfers of a fetched transaction.
+func (h *walletHistory) transfers(signature solana.Signature) ([]*Transaction, bool) {
+	if h == nil {
+		return nil, false
+	}
+	transfers, ok := h.Transfers[signature]
+	return transfers, ok
+}
+
+// setTransfers records the transfers of a fetched transaction.
+func (h *walletHistory) setTransfers(signature solana.Signature, transfers []*Transaction) {
+	if h != nil {
+		h.Transfers[signature] = transfers
+	}
+}
+
+// cacheable reports whether the history of network is cached.
+func (c *HistoryCache) cacheable(network Network) bool {
+	return c != nil && knownGenesisHashes[network] != ""
+}
+
+func (c *HistoryCache) filePath(network Network, address string) string {
+	return filepath.Join(c.Dir, string(network), address+".json")
+}
+
+// load reads the history of the wallet at address on network. A missing, unreadable or outdated
+// history is empty.
+func (c *HistoryCache) load(network Network, address string) *walletHistory {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := c.FileReader.ReadFile(c.filePath(network, address))
+	if err != nil {
+		return newWalletHistory()
+	}
+	history := newWalletHistory()
+	if err := json.Unmarshal(data, history); err != nil || history.Version != historyCacheVersion {
+		return newWalletHistory()
+	}
+	if history.Addresses == nil {
+		history.Addresses = make(map[string][]*rpc.TransactionSignature)
+	}
+	if history.Transfers == nil {
+		history.Transfers = make(map[solana.Signature][]*Transaction)
+	}
+	return history
+}
+
+// store records the history of the wallet at address on network.
+func (c *HistoryCache) store(network Network, address string, history *walletHistory) {
+	if ReadOnlyMode() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return
+	}
+	path := c.filePath(network, address)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	_ = c.FileWriter.WriteFile(path, data)
+}
@@ -0,0 +1,105 @@
+package wallet
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchTransactionsSyncsIncrementally(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	owner := solana.NewWallet().PublicKey()
+	signature := func(i int) *rpc.TransactionSignature {
+		return &rpc.TransactionSignature{Signature: solana.Signature{byte(i)}, Slot: uint64(i)}
+	}
+	history := []*rpc.TransactionSignature{signature(3), signature(2), signature(1)}
+	var lastUntil solana.Signature
+	var fetched int32
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetTokenAccountsByOwnerFn: func(ctx context.Context, _ solana.PublicKey, _ *rpc.GetTokenAccountsConfig, _ *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+				return &rpc.GetTokenAccountsResult{}, nil
+			},
+			// Pages the history between the cursors like the RPC does.
+			GetSignaturesForAddressWithOptsFn: func(ctx context.Context, _ solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+				lastUntil = opts.Until
+				var page []*rpc.TransactionSignature
+				started := opts.Before.IsZero()
+				for _, sig := range history {
+					if sig.Signature == opts.Until || len(page) == *opts.Limit {
+						break
+					}
+					if started {
+						page = append(page, sig)
+					}
+					started = started || sig.Signature == opts.Before
+				}
+				return page, nil
+			},
+			GetTransactionFn: func(ctx context.Context, sig solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+				atomic.AddInt32(&fetched, 1)
+				return transferResult(t, owner), nil
+			},
+		}
+	}
+
+	endpoints := Endpoints{RPC: "http://rpc"}
+	cache := NewHistoryCache(t.TempDir())
+	fetch := func(opts GetTransactionHistoryOpts) []*Transaction {
+		synced := cache.load(MainnetBeta, owner.String())
+		transactions, err := fetchTransactions(endpoints, owner.String(), opts, synced)
+		assert.NoError(t, err)
+		cache.store(MainnetBeta, owner.String(), synced)
+		return transactions
+	}
+
+	assert.Len(t, fetch(GetTransactionHistoryOpts{Limit: 50}), 3)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fetched))
+	assert.True(t, lastUntil.IsZero())
+
+	// Only the signatures after the newest synced one are listed, and only their transactions fetched.
+	history = append([]*rpc.TransactionSignature{signature(5), signature(4)}, history...)
+	transactions := fetch(GetTransactionHistoryOpts{Limit: 50})
+	assert.Len(t, transactions, 5)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&fetched))
+	assert.Equal(t, signature(3).Signature, lastUntil)
+	assert.Equal(t, uint64(1_000), transactions[0].Amount)
+	assert.True(t, transactions[0].IsSender)
+
+	// Limits are applied to the synced history without fetching anything.
+	assert.Len(t, fetch(GetTransactionHistoryOpts{Limit: 2}), 2)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&fetched))
+
+	// A limit that stops short of the first transaction leaves the signatures of a new wallet unsynced.
+	other := NewHistoryCache(t.TempDir())
+	synced := other.load(MainnetBeta, owner.String())
+	_, err := fetchTransactions(endpoints, owner.String(), GetTransactionHistoryOpts{Limit: 2}, synced)
+	assert.NoError(t, err)
+	_, complete := synced.addressSignatures(owner)
+	assert.False(t, complete)
+	assert.Len(t, synced.Transfers, 2)
+}
+
+func TestHistoryCacheLoad(t *testing.T) {
+	cache := NewHistoryCache(t.TempDir())
+	owner := solana.NewWallet().PublicKey().String()
+
+	assert.True(t, cache.cacheable(MainnetBeta))
+	assert.False(t, cache.cacheable(Localnet))
+	assert.False(t, cache.cacheable(Custom))
+	assert.False(t, (*HistoryCache)(nil).cacheable(MainnetBeta))
+
+	// Outdated and unreadable files count as empty.
+	for _, content := range []string{`{"version":0,"transfers":{"x":[]}}`, `not json`} {
+		assert.NoError(t, cache.FileWriter.WriteFile(cache.filePath(Devnet, owner), []byte(content)))
+		history := cache.load(Devnet, owner)
+		assert.Empty(t, history.Addresses)
+		assert.Empty(t, history.Transfers)
+	}
+}
@@ -0,0 +1,177 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// HistoryRow is one transfer of an exported transaction history. A transaction with several
+// transfers gives several rows sharing the signature; its fee is only on the first of them.
+type HistoryRow struct {
+	Timestamp    time.Time       `json:"timestamp"`
+	Signature    string          `json:"signature"`
+	Direction    string          `json:"direction"`
+	Counterparty string          `json:"counterparty"`
+	Lamports     uint64          `json:"lamports"`
+	SOL          decimal.Decimal `json:"sol"`
+	// Rate is the SOL rate the fiat value was computed with; it and Fiat are nil without a rate and
+	// for token transfers.
+	Rate        *decimal.Decimal `json:"rate,omitempty"`
+	Fiat        *decimal.Decimal `json:"fiat,omitempty"`
+	Currency    Currency         `json:"currency,omitempty"`
+	Network     Network          `json:"network"`
+	FeeLamports uint64           `json:"feeLamports"`
+	Token       string           `json:"token,omitempty"`
+	Mint        string           `json:"mint,omitempty"`
+	TokenAmount *decimal.Decimal `json:"tokenAmount,omitempty"`
+	// Label and Category are the bookkeeping label of the transaction, empty when it has none.
+	Label    string `json:"label,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Memo is the memo recorded with the transaction, on every row of it.
+	Memo string `json:"memo,omitempty"`
+}
+
+// historyColumns is the header of a CSV export, in the order HistoryRow.record writes the fields.
+var historyColumns = []string{
+	"timestamp", "signature", "direction", "counterparty", "lamports", "sol", "rate", "fiat",
+	"currency", "network", "fee_lamports", "token", "mint", "token_amount", "label", "category", "memo",
+}
+
+// HistoryRows turns transactions into export rows, oldest first. Rows of one signature keep the
+// order their transfers were decoded in. rate may be nil, in which case fiat values are left out.
+func HistoryRows(transactions []*Transaction, rate *decimal.Decimal, currency Currency, network Network) []HistoryRow {
+	groups := GroupTransactions(transactions)
+	rows := make([]HistoryRow, 0, len(transactions))
+	for i := len(groups) - 1; i >= 0; i-- {
+		for j, tx := range groups[i].Transfers {
+			row := historyRow(tx, rate, currency, network)
+			if j == 0 {
+				row.FeeLamports = groups[i].Fee
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// historyRow returns the export row of one transfer, without its fee.
+func historyRow(tx *Transaction, rate *decimal.Decimal, currency Currency, network Network) HistoryRow {
+	row := HistoryRow{
+		Timestamp:    tx.Timestamp.UTC(),
+		Signature:    tx.Signature.String(),
+		Direction:    "received",
+		Counterparty: tx.From.String(),
+		Network:      network,
+		Memo:         tx.Memo,
+	}
+	if tx.IsSender {
+		row.Direction = "sent"
+		row.Counterparty = tx.To.String()
+	}
+	if tx.Reward {
+		row.Signature = ""
+		row.Direction = "reward"
+	}
+
+	if tx.IsToken() {
+		amount := tx.TokenAmount()
+		row.Token = tx.Symbol
+		row.Mint = tx.Mint.String()
+		row.TokenAmount = &amount
+	} else {
+		row.Lamports = tx.Amount
+		row.SOL = LamportsToSOL(tx.Amount)
+		if rate != nil {
+			fiat := row.SOL.Mul(*rate).Round(2)
+			row.Rate = rate
+			row.Fiat = &fiat
+			row.Currency = currency
+		}
+	}
+	return row
+}
+
+// record returns the CSV fields of the row in the order of historyColumns.
+func (r HistoryRow) record() []string {
+	optional := func(d *decimal.Decimal) string {
+		if d == nil {
+			return ""
+		}
+		return d.String()
+	}
+
+	return []string{
+		r.Timestamp.Format(time.RFC3339),
+		r.Signature,
+		r.Direction,
+		r.Counterparty,
+		strconv.FormatUint(r.Lamports, 10),
+		r.SOL.String(),
+		optional(r.Rate),
+		optional(r.Fiat),
+		string(r.Currency),
+		string(r.Network),
+		strconv.FormatUint(r.FeeLamports, 10),
+		r.Token,
+		r.Mint,
+		optional(r.TokenAmount),
+		r.Label,
+		r.Category,
+		r.Memo,
+	}
+}
+
+// WriteHistoryCSV writes rows as CSV with a header row, which is written even when there are no rows.
+func WriteHistoryCSV(w io.Writer, rows []HistoryRow) error {
+	return writeHistoryRecords(w, rows, true)
+}
+
+// writeHistoryRecords writes rows as CSV records, preceded by the header row when header is set.
+func writeHistoryRecords(w io.Writer, rows []HistoryRow, header bool) error {
+	writer := csv.NewWriter(w)
+	if header {
+		if err := writer.Write(historyColumns); err != nil {
+			return fmt.Errorf("error writing CSV: %w", err)
+		}
+	}
+	for _, row := range rows {
+		if err := writer.Write(row.record()); err != nil {
+			return fmt.Errorf("error writing CSV: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing CSV: %w", err)
+	}
+	return nil
+}
+
+// WriteHistoryJSON writes rows as an indented JSON array, which is empty rather than null without rows.
+func WriteHistoryJSON(w io.Writer, rows []HistoryRow) error {
+	if rows == nil {
+		rows = []HistoryRow{}
+	}
+	data, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteHistoryJSONLines writes rows as JSON lines, one object per row, so further rows can be appended.
+func WriteHistoryJSONLines(w io.Writer, rows []HistoryRow) error {
+	encoder := json.NewEncoder(w)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
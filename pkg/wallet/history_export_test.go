@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteHistoryCSV(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	other := solana.NewWallet().PublicKey()
+	third := solana.NewWallet().PublicKey()
+	batch := solana.Signature{2}
+	rate := decimal.NewFromInt(100)
+	day := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		transactions []*Transaction
+		rate         *decimal.Decimal
+		labels       map[string]TransactionLabel
+		expected     [][]string
+	}{
+		{
+			name:     "No Transactions",
+			expected: [][]string{historyColumns},
+		},
+		{
+			name: "Oldest First With One Row Per Transfer",
+			transactions: []*Transaction{
+				{Signature: batch, Amount: 2_000_000_000, From: owner, To: other, Timestamp: day.Add(time.Hour), IsSender: true, Fee: 5_000},
+				{Signature: batch, Amount: 500_000_000, From: owner, To: third, Timestamp: day.Add(time.Hour), IsSender: true, Fee: 5_000},
+				{Signature: solana.Signature{1}, Amount: 1_500_000_000, From: other, To: owner, Timestamp: day},
+			},
+			rate: &rate,
+			expected: [][]string{
+				historyColumns,
+				{"2023-06-01T12:00:00Z", solana.Signature{1}.String(), "received", other.String(), "1500000000", "1.5", "100", "150", "EUR", "devnet", "0", "", "", "", "", "", ""},
+				{"2023-06-01T13:00:00Z", batch.String(), "sent", other.String(), "2000000000", "2", "100", "200", "EUR", "devnet", "5000", "", "", "", "", "", ""},
+				{"2023-06-01T13:00:00Z", batch.String(), "sent", third.String(), "500000000", "0.5", "100", "50", "EUR", "devnet", "0", "", "", "", "", "", ""},
+			},
+		},
+		{
+			name: "Token Transfer Without Rate",
+			transactions: []*Transaction{
+				{Signature: solana.Signature{3}, Amount: 25_000_000, From: other, To: owner, Timestamp: day, Mint: usdcMint, Symbol: "USDC", Decimals: 6},
+				{Signature: solana.Signature{4}, Amount: 1_000, From: owner, To: other, Timestamp: day.Add(time.Minute), IsSender: true},
+			},
+			expected: [][]string{
+				historyColumns,
+				{"2023-06-01T12:00:00Z", solana.Signature{3}.String(), "received", other.String(), "0", "0", "", "", "", "devnet", "0", "USDC", usdcMint.String(), "25", "", "", ""},
+				{"2023-06-01T12:01:00Z", solana.Signature{4}.String(), "sent", other.String(), "1000", "0.000001", "", "", "", "devnet", "0", "", "", "", "", "", ""},
+			},
+		},
+		{
+			name: "Labels On Every Row Of Their Transaction",
+			transactions: []*Transaction{
+				{Signature: batch, Amount: 2_000_000_000, From: owner, To: other, Timestamp: day, IsSender: true, Fee: 5_000},
+				{Signature: batch, Amount: 500_000_000, From: owner, To: third, Timestamp: day, IsSender: true, Fee: 5_000},
+				{Signature: solana.Signature{5}, Amount: 1_000, From: other, To: owner, Timestamp: day.Add(time.Minute)},
+			},
+			labels: map[string]TransactionLabel{
+				batch.String(): {Signature: batch.String(), Text: "office rent, June", Category: "rent"},
+			},
+			expected: [][]string{
+				historyColumns,
+				{"2023-06-01T12:00:00Z", batch.String(), "sent", other.String(), "2000000000", "2", "", "", "", "devnet", "5000", "", "", "", "office rent, June", "rent", ""},
+				{"2023-06-01T12:00:00Z", batch.String(), "sent", third.String(), "500000000", "0.5", "", "", "", "devnet", "0", "", "", "", "office rent, June", "rent", ""},
+				{"2023-06-01T12:01:00Z", solana.Signature{5}.String(), "received", other.String(), "1000", "0.000001", "", "", "", "devnet", "0", "", "", "", "", "", ""},
+			},
+		},
+		{
+			name: "Memo On Every Row Of Its Transaction",
+			transactions: []*Transaction{
+				{Signature: batch, Amount: 2_000_000_000, From: owner, To: other, Timestamp: day, IsSender: true, Fee: 5_000, Memo: "invoice 42"},
+				{Signature: batch, Amount: 500_000_000, From: owner, To: third, Timestamp: day, IsSender: true, Fee: 5_000, Memo: "invoice 42"},
+			},
+			expected: [][]string{
+				historyColumns,
+				{"2023-06-01T12:00:00Z", batch.String(), "sent", other.String(), "2000000000", "2", "", "", "", "devnet", "5000", "", "", "", "", "", "invoice 42"},
+				{"2023-06-01T12:00:00Z", batch.String(), "sent", third.String(), "500000000", "0.5", "", "", "", "devnet", "0", "", "", "", "", "", "invoice 42"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			rows := LabelHistoryRows(HistoryRows(tt.transactions, tt.rate, DefaultCurrency, Devnet), tt.labels)
+			assert.NoError(t, WriteHistoryCSV(&buf, rows))
+
+			records, err := csv.NewReader(&buf).ReadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, records)
+		})
+	}
+}
+
+func TestWriteHistoryJSONWithoutRows(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteHistoryJSON(&buf, HistoryRows(nil, nil, DefaultCurrency, Devnet)))
+	assert.Equal(t, "[]\n", buf.String())
+}
@@ -0,0 +1,54 @@
+package wallet
+
+import "github.com/shopspring/decimal"
+
+// HistoryFilter narrows a transaction history down by direction and amount. The date range is part
+// of GetTransactionHistoryOpts instead, so the node is only asked for the transactions in it.
+type HistoryFilter struct {
+	// Sent and Received keep only the transfers out of or into the wallet; with neither set, or both,
+	// transfers in either direction are kept. Staking rewards count as received.
+	Sent     bool
+	Received bool
+	// MinAmount keeps only the transfers of at least this amount: in SOL for SOL transfers and
+	// rewards, and in whole tokens for token transfers. Zero keeps every amount.
+	MinAmount decimal.Decimal
+}
+
+// IsZero reports whether the filter keeps every transaction.
+func (f HistoryFilter) IsZero() bool {
+	return f.Sent == f.Received && !f.MinAmount.IsPositive()
+}
+
+// Match reports whether tx passes the filter.
+func (f HistoryFilter) Match(tx *Transaction) bool {
+	if f.Sent != f.Received {
+		sent := tx.IsSender && !tx.Reward
+		if sent != f.Sent {
+			return false
+		}
+	}
+	if f.MinAmount.IsPositive() {
+		amount := LamportsToSOL(tx.Amount)
+		if tx.IsToken() {
+			amount = tx.TokenAmount()
+		}
+		if amount.LessThan(f.MinAmount) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterTransactions returns the transactions that pass filter, keeping their order.
+func FilterTransactions(transactions []*Transaction, filter HistoryFilter) []*Transaction {
+	if filter.IsZero() {
+		return transactions
+	}
+	filtered := make([]*Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if filter.Match(tx) {
+			filtered = append(filtered, tx)
+		}
+	}
+	return filtered
+}
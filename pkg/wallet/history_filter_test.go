@@ -0,0 +1,37 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterTransactions(t *testing.T) {
+	sent := &Transaction{Signature: solana.Signature{1}, Amount: 2 * solana.LAMPORTS_PER_SOL, IsSender: true}
+	received := &Transaction{Signature: solana.Signature{2}, Amount: solana.LAMPORTS_PER_SOL / 2}
+	reward := &Transaction{Amount: solana.LAMPORTS_PER_SOL / 100, Reward: true, IsSender: true}
+	token := &Transaction{Signature: solana.Signature{3}, Amount: 5_000_000, Mint: usdcMint, Decimals: 6}
+	transactions := []*Transaction{sent, received, reward, token}
+
+	tests := []struct {
+		name     string
+		filter   HistoryFilter
+		expected []*Transaction
+	}{
+		{name: "No Filter", expected: transactions},
+		{name: "Both Directions", filter: HistoryFilter{Sent: true, Received: true}, expected: transactions},
+		{name: "Sent", filter: HistoryFilter{Sent: true}, expected: []*Transaction{sent}},
+		{name: "Received", filter: HistoryFilter{Received: true}, expected: []*Transaction{received, reward, token}},
+		{name: "Min Amount In SOL", filter: HistoryFilter{MinAmount: decimal.NewFromFloat(0.5)}, expected: []*Transaction{sent, received, token}},
+		{name: "Min Amount In Tokens", filter: HistoryFilter{MinAmount: decimal.NewFromInt(3)}, expected: []*Transaction{token}},
+		{name: "Received Over Amount", filter: HistoryFilter{Received: true, MinAmount: decimal.NewFromInt(1)}, expected: []*Transaction{token}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FilterTransactions(transactions, tt.filter))
+		})
+	}
+}
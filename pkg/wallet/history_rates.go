@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// HistoricalRates are the SOL prices at past times, by unix second.
+type HistoricalRates map[int64]decimal.Decimal
+
+// At returns the SOL price at t, or nil when it is not known.
+func (r HistoricalRates) At(t time.Time) *decimal.Decimal {
+	rate, ok := r[t.Unix()]
+	if !ok {
+		return nil
+	}
+	return &rate
+}
+
+// RatesAt returns the SOL price in currency at each of times: the close of the Kraken candle
+// containing it. One candle length is used for all of them, the shortest for which the candle of
+// the oldest time is still among the 720 Kraken serves. The prices found are returned along with the
+// first lookup that failed.
+func (c *OHLCCache) RatesAt(currency Currency, times []time.Time) (HistoricalRates, error) {
+	rates := make(HistoricalRates)
+	if currency.Pair() == "" {
+		return rates, fmt.Errorf("%w: no price history in %s, which Kraken does not quote", ErrRateUnavailable, currency)
+	}
+
+	sorted := make([]time.Time, 0, len(times))
+	for _, t := range times {
+		if !t.IsZero() {
+			sorted = append(sorted, t)
+		}
+	}
+	if len(sorted) == 0 {
+		return rates, nil
+	}
+	// Oldest first: the first lookup fetches every candle up to now, so the others are served from disk.
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	// The slack keeps the oldest candle from dropping out of Kraken's window while it is fetched.
+	interval := historyInterval(c.Now().Sub(sorted[0]) + time.Hour)
+	step := int64(interval) * 60
+	// The still-open candle is never stored, so it is only fetched once here.
+	candles := make(map[int64]Candle)
+
+	var firstErr error
+	for _, t := range sorted {
+		if _, ok := rates[t.Unix()]; ok {
+			continue
+		}
+		openTime := t.Unix() - t.Unix()%step
+		candle, ok := candles[openTime]
+		if !ok {
+			var err error
+			if candle, err = c.CandleAt(currency.Pair(), interval, t); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			candles[openTime] = candle
+		}
+		rates[t.Unix()] = candle.Close
+	}
+	return rates, firstErr
+}
+
+// HistoricalRates returns the SOL price in the selected currency at the time of each SOL transfer
+// and staking reward, from the candle cache shared by every command.
+func (w *WalletConfig) HistoricalRates(transactions []*Transaction) (HistoricalRates, error) {
+	defer TimePhase(PhaseRate)()
+
+	times := make([]time.Time, 0, len(transactions))
+	for _, tx := range transactions {
+		if !tx.IsToken() {
+			times = append(times, tx.Timestamp)
+		}
+	}
+	return defaultOHLCCache().RatesAt(w.FiatCurrency(), times)
+}
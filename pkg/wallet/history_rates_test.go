@@ -0,0 +1,62 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOHLCCacheRatesAt(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 40, 0, 0, time.UTC)
+	var fetches, interval int
+
+	cache := &OHLCCache{
+		FileWriter: &IOUtilFileWriter{},
+		Dir:        t.TempDir(),
+		// Every candle up to now, closing at its open time.
+		Fetch: func(pair string, i int, since int64) ([]Candle, error) {
+			fetches, interval = fetches+1, i
+			step := int64(i) * 60
+			var candles []Candle
+			for open := since - since%step; open <= now.Unix(); open += step {
+				candles = append(candles, Candle{Time: open, Close: decimal.NewFromInt(open)})
+			}
+			return candles, nil
+		},
+		Now: func() time.Time { return now },
+	}
+
+	old := time.Date(2024, 4, 20, 8, 15, 0, 0, time.UTC)
+	recent := time.Date(2024, 5, 1, 12, 31, 0, 0, time.UTC)
+	rates, err := cache.RatesAt("EUR", []time.Time{recent, {}, old, recent.Add(time.Minute)})
+	assert.NoError(t, err)
+	assert.Equal(t, 30, interval, "11 days in at most 720 candles")
+	assert.Len(t, rates, 3)
+	assert.True(t, decimal.NewFromInt(time.Date(2024, 4, 20, 8, 0, 0, 0, time.UTC).Unix()).Equal(*rates.At(old)))
+	assert.True(t, decimal.NewFromInt(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC).Unix()).Equal(*rates.At(recent)))
+	assert.Nil(t, rates.At(now.Add(-time.Hour)))
+	// The oldest lookup stores every closed candle; the open one is fetched once for both times in it.
+	assert.Equal(t, 2, fetches)
+}
+
+func TestOHLCCacheRatesAtErrors(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	cache := &OHLCCache{
+		FileWriter: &IOUtilFileWriter{},
+		Dir:        t.TempDir(),
+		Fetch: func(pair string, i int, since int64) ([]Candle, error) {
+			return nil, errors.New("kraken is down")
+		},
+		Now: func() time.Time { return now },
+	}
+
+	rates, err := cache.RatesAt("EUR", []time.Time{now.Add(-time.Hour)})
+	assert.EqualError(t, err, "kraken is down")
+	assert.Empty(t, rates)
+
+	_, err = cache.RatesAt("CHF", []time.Time{now.Add(-time.Hour)})
+	assert.True(t, errors.Is(err, ErrRateUnavailable))
+}
@@ -0,0 +1,343 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// HistoryFormat is the format of a history export written to a file.
+type HistoryFormat string
+
+// Formats that can be appended to, so an export can be written page by page and resumed.
+const (
+	HistoryCSV       HistoryFormat = "csv"
+	HistoryJSONLines HistoryFormat = "jsonl"
+)
+
+// HistoryCheckpointSuffix is appended to the path of an export to name its checkpoint file.
+const HistoryCheckpointSuffix = ".checkpoint"
+
+// exportPageSize is the number of transactions fetched and written between two checkpoints.
+const exportPageSize = 100
+
+// historyExportScope identifies what an export covers, so it is only resumed with the same options.
+type historyExportScope struct {
+	Address string        `json:"address"`
+	Network Network       `json:"network"`
+	Format  HistoryFormat `json:"format"`
+	Limit   int           `json:"limit,omitempty"`
+	// Since and Until are Unix times, zero when unbounded.
+	Since int64 `json:"since,omitempty"`
+	Until int64 `json:"until,omitempty"`
+	// Category is the label category the export is restricted to, empty for all transactions.
+	Category string `json:"category,omitempty"`
+	// Sent, Received and MinAmount are those of the filter the export is restricted to.
+	Sent      bool   `json:"sent,omitempty"`
+	Received  bool   `json:"received,omitempty"`
+	MinAmount string `json:"minAmount,omitempty"`
+}
+
+// historyCheckpoint records how far an export got. The export file holds exactly Offset bytes of rows
+// up to and including the transaction Signature; anything after that was written by an interrupted
+// page and is written again on resume.
+type historyCheckpoint struct {
+	Scope historyExportScope `json:"scope"`
+	// Signature is the last transaction whose rows were written, empty before the first page.
+	Signature    string `json:"signature,omitempty"`
+	Offset       int64  `json:"offset"`
+	Transactions int    `json:"transactions"`
+	Rows         int    `json:"rows"`
+	// Rate and Currency are the exchange rate the export started with, kept so resumed rows use it too.
+	Rate     *decimal.Decimal `json:"rate,omitempty"`
+	Currency Currency         `json:"currency,omitempty"`
+}
+
+// HistoryExportOpts configures ExportHistory.
+type HistoryExportOpts struct {
+	// Path is the export file; its checkpoint is kept next to it until the export completes.
+	Path   string
+	Format HistoryFormat
+	// History bounds the transactions exported and how fast they are fetched.
+	History GetTransactionHistoryOpts
+	// Rate and Currency value SOL amounts; without a rate fiat columns are left empty. A resumed
+	// export keeps the rate it started with.
+	Rate     *decimal.Decimal
+	Currency Currency
+	// Category restricts the export to transactions labeled in this category.
+	Category string
+	// Filter restricts the export to the transfers it matches.
+	Filter HistoryFilter
+	// Resume continues the export from its checkpoint instead of starting over.
+	Resume bool
+	// PageSize is the number of transactions written between checkpoints. Zero means exportPageSize.
+	PageSize int
+	// OnProgress, when set, is called after every page with the transactions done out of the total.
+	OnProgress func(done, total int)
+}
+
+// HistoryExportResult summarises an export.
+type HistoryExportResult struct {
+	// Transactions and Rows count everything in the file, including what was written before a resume.
+	Transactions int
+	Rows         int
+	Resumed      bool
+}
+
+// ExportHistory writes the history of the current wallet to opts.Path, oldest first, one page of
+// transactions at a time. After each page the file is synced and a checkpoint naming its last
+// transaction is written next to it, so an export interrupted by a crash, ^C or an RPC failure can
+// be continued with opts.Resume. Requests go through the same adaptive limiter as
+// GetTransactionHistoryWithOpts, which slows down while the node is rate limiting instead of failing.
+func (w *WalletConfig) ExportHistory(ctx context.Context, opts HistoryExportOpts) (*HistoryExportResult, error) {
+	if opts.Format != HistoryCSV && opts.Format != HistoryJSONLines {
+		return nil, fmt.Errorf("invalid export format %q, expected %s or %s", opts.Format, HistoryCSV, HistoryJSONLines)
+	}
+	if opts.History.Limit < 0 {
+		return nil, fmt.Errorf("invalid limit %d", opts.History.Limit)
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = exportPageSize
+	}
+
+	pub, err := w.currentPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	network := w.Network
+	if network == "" {
+		network = DefaultNetwork
+	}
+
+	labels, err := w.TransactionLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	scope := historyExportScope{
+		Address: pub.String(), Network: network, Format: opts.Format, Limit: opts.History.Limit, Category: opts.Category,
+		Sent: opts.Filter.Sent, Received: opts.Filter.Received,
+	}
+	if opts.Filter.MinAmount.IsPositive() {
+		scope.MinAmount = opts.Filter.MinAmount.String()
+	}
+	if !opts.History.Since.IsZero() {
+		scope.Since = opts.History.Since.Unix()
+	}
+	if !opts.History.Until.IsZero() {
+		scope.Until = opts.History.Until.Unix()
+	}
+
+	export, err := openHistoryExport(opts, scope)
+	if err != nil {
+		return nil, err
+	}
+	defer export.file.Close()
+
+	client := endpoints.client()
+	signatures, err := signaturesForWallet(ctx, client, pub, opts.History, nil)
+	if err != nil {
+		return nil, err
+	}
+	signatures = oldestFirst(signatures)
+
+	start := 0
+	if export.checkpoint.Signature != "" {
+		start = -1
+		for i, sig := range signatures {
+			if sig.Signature.String() == export.checkpoint.Signature {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, fmt.Errorf("the last exported transaction %s is no longer in the history; start the export again", export.checkpoint.Signature)
+		}
+	}
+
+	limiter := opts.History.limiter()
+	policy := opts.History.retryPolicy()
+	for i := start; i < len(signatures); i += pageSize {
+		end := i + pageSize
+		if end > len(signatures) {
+			end = len(signatures)
+		}
+		page := signatures[i:end]
+
+		transactions, err := fetchSignedTransactions(ctx, client, pub.String(), page, limiter, policy, nil)
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			return export.result(), fmt.Errorf("export interrupted after %d of %d transactions: %w", i, len(signatures), err)
+		}
+
+		if opts.Category != "" {
+			transactions = FilterByCategory(transactions, labels, opts.Category)
+		}
+		transactions = FilterTransactions(transactions, opts.Filter)
+		rows := LabelHistoryRows(HistoryRows(transactions, export.checkpoint.Rate, export.checkpoint.Currency, network), labels)
+		if err := export.appendPage(rows, len(page), page[len(page)-1].Signature.String()); err != nil {
+			return export.result(), err
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(i+len(page), len(signatures))
+		}
+	}
+
+	// A complete export needs no checkpoint; a stale one would let --resume skip everything.
+	if err := os.Remove(export.checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return export.result(), fmt.Errorf("failed to remove export checkpoint: %w", err)
+	}
+	return export.result(), nil
+}
+
+// oldestFirst orders signatures by slot, oldest first, in the reverse order GroupTransactions gives
+// transactions of one slot, so every run pages through the history in the same order.
+func oldestFirst(signatures []*rpc.TransactionSignature) []*rpc.TransactionSignature {
+	sort.SliceStable(signatures, func(i, j int) bool {
+		if signatures[i].Slot != signatures[j].Slot {
+			return signatures[i].Slot < signatures[j].Slot
+		}
+		return signatures[i].Signature.String() > signatures[j].Signature.String()
+	})
+	return signatures
+}
+
+// historyExport is an export file open for appending, with its checkpoint.
+type historyExport struct {
+	file           *os.File
+	checkpointPath string
+	checkpoint     historyCheckpoint
+	writer         FileWriter
+	resumed        bool
+}
+
+// openHistoryExport creates the export file with its header and first checkpoint or, when resuming,
+// reopens it and cuts off whatever was written after its checkpoint.
+func openHistoryExport(opts HistoryExportOpts, scope historyExportScope) (*historyExport, error) {
+	export := &historyExport{checkpointPath: opts.Path + HistoryCheckpointSuffix, writer: newFileWriter()}
+
+	if !opts.Resume {
+		file, err := os.OpenFile(opts.Path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create export: %w", err)
+		}
+		export.file = file
+		export.checkpoint = historyCheckpoint{Scope: scope, Rate: opts.Rate, Currency: opts.Currency}
+
+		var header []byte
+		if opts.Format == HistoryCSV {
+			if header, err = encodeHistoryRows(HistoryCSV, nil, true); err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+		if err := export.appendPage(nil, 0, "", header...); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return export, nil
+	}
+
+	data, err := os.ReadFile(export.checkpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no interrupted export to resume: %s does not exist", export.checkpointPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &export.checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse export checkpoint %s: %w", export.checkpointPath, err)
+	}
+	if export.checkpoint.Scope != scope {
+		return nil, fmt.Errorf("%s was started for another wallet, network, format or date range, or for another category; resume it with the same options", opts.Path)
+	}
+
+	file, err := os.OpenFile(opts.Path, os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	info, err := file.Stat()
+	if err == nil && info.Size() < export.checkpoint.Offset {
+		err = fmt.Errorf("%s is shorter than at its last checkpoint; start the export again", opts.Path)
+	}
+	if err == nil {
+		// Rows of the page that was being written when the export stopped are fetched again.
+		err = file.Truncate(export.checkpoint.Offset)
+	}
+	if err == nil {
+		_, err = file.Seek(export.checkpoint.Offset, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to resume export: %w", err)
+	}
+	export.file = file
+	export.resumed = true
+	return export, nil
+}
+
+// appendPage writes the rows of a page of transactions ending with signature, or the header given
+// as data, then syncs the file and moves the checkpoint past them.
+func (e *historyExport) appendPage(rows []HistoryRow, transactions int, signature string, data ...byte) error {
+	encoded, err := encodeHistoryRows(e.checkpoint.Scope.Format, rows, false)
+	if err != nil {
+		return err
+	}
+	data = append(data, encoded...)
+
+	if _, err := e.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if err := e.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	checkpoint := e.checkpoint
+	checkpoint.Offset += int64(len(data))
+	checkpoint.Transactions += transactions
+	checkpoint.Rows += len(rows)
+	if signature != "" {
+		checkpoint.Signature = signature
+	}
+	encodedCheckpoint, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	if err := e.writer.WriteFile(e.checkpointPath, encodedCheckpoint); err != nil {
+		return fmt.Errorf("failed to write export checkpoint: %w", err)
+	}
+	e.checkpoint = checkpoint
+	return nil
+}
+
+func (e *historyExport) result() *HistoryExportResult {
+	return &HistoryExportResult{Transactions: e.checkpoint.Transactions, Rows: e.checkpoint.Rows, Resumed: e.resumed}
+}
+
+// encodeHistoryRows returns rows as CSV records, preceded by the header when asked for, or as JSON lines.
+func encodeHistoryRows(format HistoryFormat, rows []HistoryRow, header bool) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case HistoryCSV:
+		err = writeHistoryRecords(&buf, rows, header)
+	case HistoryJSONLines:
+		err = WriteHistoryJSONLines(&buf, rows)
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,159 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// exportHistoryClient serves a history of n transfers sent by owner, newest first. GetTransaction
+// fails for the signatures in broken.
+func exportHistoryClient(t *testing.T, owner solana.PublicKey, n int, broken map[solana.Signature]bool) *MockClientInterface {
+	history := make([]*rpc.TransactionSignature, n)
+	for i := range history {
+		history[i] = &rpc.TransactionSignature{Signature: solana.Signature{byte(i + 1), 9}, Slot: uint64(1_000 - i)}
+	}
+	slots := make(map[solana.Signature]uint64)
+	for _, sig := range history {
+		slots[sig.Signature] = sig.Slot
+	}
+
+	return &MockClientInterface{
+		GetTokenAccountsByOwnerFn: func(context.Context, solana.PublicKey, *rpc.GetTokenAccountsConfig, *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+			return &rpc.GetTokenAccountsResult{}, nil
+		},
+		GetSignaturesForAddressWithOptsFn: func(context.Context, solana.PublicKey, *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+			return history, nil
+		},
+		GetTransactionFn: func(_ context.Context, sig solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+			if broken[sig] {
+				return nil, errors.New("transaction not found")
+			}
+			result := transferResultTo(t, owner, solana.PublicKey{sig[0]}, owner)
+			result.Slot = slots[sig]
+			return result, nil
+		},
+	}
+}
+
+func TestExportHistoryResume(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	defer func(policy RetryPolicy) { DefaultRetryPolicy = policy }(DefaultRetryPolicy)
+	DefaultRetryPolicy = fastRetries
+
+	owner := solana.NewWallet()
+	rate := decimal.NewFromInt(150)
+	const total = 25
+
+	for _, format := range []HistoryFormat{HistoryCSV, HistoryJSONLines} {
+		t.Run(string(format), func(t *testing.T) {
+			dir := t.TempDir()
+			wc := &WalletConfig{Wallet: owner, Network: Devnet}
+			opts := HistoryExportOpts{Format: format, Rate: &rate, Currency: DefaultCurrency, PageSize: 10, History: GetTransactionHistoryOpts{Concurrency: 4}}
+
+			// The reference export runs without interruption.
+			newRPCClient = func(string) ClientInterface { return exportHistoryClient(t, owner.PublicKey(), total, nil) }
+			opts.Path = filepath.Join(dir, "reference")
+			result, err := wc.ExportHistory(context.Background(), opts)
+			assert.NoError(t, err)
+			assert.Equal(t, total, result.Rows)
+			want, err := os.ReadFile(opts.Path)
+			assert.NoError(t, err)
+			assert.NoFileExists(t, opts.Path+HistoryCheckpointSuffix)
+
+			// The first run stops in the middle of the second page, oldest first.
+			broken := map[solana.Signature]bool{{byte(total - 15), 9}: true}
+			newRPCClient = func(string) ClientInterface { return exportHistoryClient(t, owner.PublicKey(), total, broken) }
+			opts.Path = filepath.Join(dir, "export")
+			result, err = wc.ExportHistory(context.Background(), opts)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "export interrupted after 10 of 25 transactions")
+			}
+			assert.Equal(t, 10, result.Transactions)
+			assert.FileExists(t, opts.Path+HistoryCheckpointSuffix)
+
+			// A crash while writing the next page leaves rows after the checkpoint, the last one cut short.
+			got, err := os.ReadFile(opts.Path)
+			assert.NoError(t, err)
+			partial := want[len(got) : len(got)+len(want)/5]
+			assert.NoError(t, os.WriteFile(opts.Path, append(got, partial...), 0600))
+
+			newRPCClient = func(string) ClientInterface { return exportHistoryClient(t, owner.PublicKey(), total, nil) }
+			opts.Resume = true
+			opts.Rate = nil // the rate of the interrupted run is kept
+			result, err = wc.ExportHistory(context.Background(), opts)
+			assert.NoError(t, err)
+			assert.True(t, result.Resumed)
+			assert.Equal(t, total, result.Transactions)
+			assert.Equal(t, total, result.Rows)
+			assert.NoFileExists(t, opts.Path+HistoryCheckpointSuffix)
+
+			got, err = os.ReadFile(opts.Path)
+			assert.NoError(t, err)
+			assert.Equal(t, string(want), string(got))
+			assertNoGapsOrDuplicates(t, got, total)
+		})
+	}
+}
+
+// assertNoGapsOrDuplicates checks that an export holds one line for each of the n transfers.
+func assertNoGapsOrDuplicates(t *testing.T, export []byte, n int) {
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(export))
+	for scanner.Scan() {
+		for i := 1; i <= n; i++ {
+			if strings.Contains(scanner.Text(), solana.Signature{byte(i), 9}.String()) {
+				seen[solana.Signature{byte(i), 9}.String()]++
+			}
+		}
+	}
+	assert.Len(t, seen, n)
+	for sig, count := range seen {
+		assert.Equal(t, 1, count, sig)
+	}
+}
+
+func TestExportHistoryResumeErrors(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	owner := solana.NewWallet()
+	newRPCClient = func(string) ClientInterface { return exportHistoryClient(t, owner.PublicKey(), 3, nil) }
+	path := filepath.Join(t.TempDir(), "export.csv")
+
+	wc := &WalletConfig{Wallet: owner, Network: Devnet}
+	_, err := wc.ExportHistory(context.Background(), HistoryExportOpts{Path: path, Format: HistoryCSV, Resume: true})
+	if assert.Error(t, err) {
+		assert.Equal(t, "no interrupted export to resume: "+path+HistoryCheckpointSuffix+" does not exist", err.Error())
+	}
+
+	// A checkpoint of another network is not resumed.
+	assert.NoError(t, os.WriteFile(path, []byte("timestamp\n"), 0600))
+	assert.NoError(t, os.WriteFile(path+HistoryCheckpointSuffix, []byte(`{"scope":{"address":"`+owner.PublicKey().String()+`","network":"mainnet-beta","format":"csv"},"offset":10}`), 0600))
+	_, err = wc.ExportHistory(context.Background(), HistoryExportOpts{Path: path, Format: HistoryCSV, Resume: true})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "was started for another wallet, network, format or date range")
+	}
+
+	// An export file shorter than its checkpoint has been changed since.
+	assert.NoError(t, os.WriteFile(path+HistoryCheckpointSuffix, []byte(`{"scope":{"address":"`+owner.PublicKey().String()+`","network":"devnet","format":"csv"},"offset":100}`), 0600))
+	_, err = wc.ExportHistory(context.Background(), HistoryExportOpts{Path: path, Format: HistoryCSV, Resume: true})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "is shorter than at its last checkpoint")
+	}
+
+	_, err = wc.ExportHistory(context.Background(), HistoryExportOpts{Path: path, Format: "json"})
+	if assert.Error(t, err) {
+		assert.Equal(t, `invalid export format "json", expected csv or jsonl`, err.Error())
+	}
+}
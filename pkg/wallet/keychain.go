@@ -0,0 +1,235 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/mr-tron/base58"
+)
+
+// KeychainService names the entries sleeng keeps in the OS credential store. Each entry is stored
+// under the public key of its wallet, so renaming a wallet leaves it in place.
+const KeychainService = "sleeng"
+
+var (
+	ErrKeychainUnavailable = errors.New("OS keychain is not available")
+	ErrKeychainKeyMissing  = errors.New("private key not found in the OS keychain")
+)
+
+// Keychain stores secrets in an OS credential store, by account.
+type Keychain interface {
+	// Get returns the secret of account, or an error wrapping ErrKeychainKeyMissing.
+	Get(account string) (string, error)
+	// Set adds or replaces the secret of account.
+	Set(account, secret string) error
+	// Delete removes the secret of account. Removing a missing secret is not an error.
+	Delete(account string) error
+}
+
+// KeyBackend is where new private keys are stored.
+type KeyBackend string
+
+const (
+	// BackendFile stores keys in the key file, encrypted when a passphrase is given.
+	BackendFile KeyBackend = "file"
+	// BackendKeychain stores keys in the OS keychain; the key file only keeps their public keys.
+	BackendKeychain KeyBackend = "keychain"
+)
+
+// ParseKeyBackend validates a key backend name.
+func ParseKeyBackend(name string) (KeyBackend, error) {
+	switch backend := KeyBackend(strings.ToLower(strings.TrimSpace(name))); backend {
+	case BackendFile, BackendKeychain:
+		return backend, nil
+	}
+	return "", fmt.Errorf("unknown key backend %q, expected file or keychain", name)
+}
+
+func (b KeyBackend) orDefault() KeyBackend {
+	if b == "" {
+		return BackendFile
+	}
+	return b
+}
+
+// keychain returns the keychain keys are kept in.
+func (k *KeyOps) keychain() Keychain {
+	if k.Keychain == nil {
+		return OSKeychain()
+	}
+	return k.Keychain
+}
+
+// storeInKeychain keeps key in the keychain under its address. Callers hold lockKeyFile.
+func (k *KeyOps) storeInKeychain(address string, key ed25519.PrivateKey) error {
+	if err := guardWrite("the OS keychain"); err != nil {
+		return err
+	}
+	if err := k.keychain().Set(address, base58.Encode(key)); err != nil {
+		return fmt.Errorf("error storing the key of %s in the OS keychain: %w", address, err)
+	}
+	return nil
+}
+
+// readFromKeychain returns the private key of the wallet at address from the keychain.
+func (k *KeyOps) readFromKeychain(alias, address string) (ed25519.PrivateKey, error) {
+	secret, err := k.keychain().Get(address)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", alias, err)
+	}
+	key, err := base58.Decode(secret)
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%s: the OS keychain holds no valid key for %s", alias, address)
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// removeFromKeychain deletes the keys of the keychain wallets in before that are no longer in after,
+// once after has been written. Failing to delete one leaves a stale entry, which is reported but
+// does not undo the write.
+func (k *KeyOps) removeFromKeychain(before, after WalletData) error {
+	kept := make(map[string]bool, len(after.Wallets))
+	for _, wallet := range after.Wallets {
+		if wallet.Keychain {
+			kept[wallet.PublicKey] = true
+		}
+	}
+
+	var errs []error
+	for _, wallet := range before.Wallets {
+		if !wallet.Keychain || kept[wallet.PublicKey] {
+			continue
+		}
+		if err := k.keychain().Delete(wallet.PublicKey); err != nil {
+			errs = append(errs, fmt.Errorf("the key of %s is still in the OS keychain: %w", wallet.PublicKey, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// commandKeychain drives a credential store through its command-line tool. The secret is passed on
+// stdin, never as an argument other processes could read.
+type commandKeychain struct {
+	// tool is the command, such as secret-tool; it must be on PATH.
+	tool string
+	// get, set and remove build the arguments and stdin of each operation.
+	get    func(account string) []string
+	set    func(account, secret string) ([]string, string)
+	remove func(account string) []string
+	// missing reports whether a failed get or remove means the account has no secret.
+	missing func(exitCode int, stderr string) bool
+}
+
+func (c *commandKeychain) run(args []string, stdin string) (string, int, error) {
+	path, err := exec.LookPath(c.tool)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %s is not installed", ErrKeychainUnavailable, c.tool)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return stderr.String(), exitErr.ExitCode(), fmt.Errorf("%s failed: %s", c.tool, strings.TrimSpace(stderr.String()))
+		}
+		return "", 0, err
+	}
+	return stdout.String(), 0, nil
+}
+
+func (c *commandKeychain) Get(account string) (string, error) {
+	out, code, err := c.run(c.get(account), "")
+	if err != nil {
+		if code != 0 && c.missing(code, out) {
+			return "", fmt.Errorf("%w: %s", ErrKeychainKeyMissing, account)
+		}
+		return "", err
+	}
+	secret := strings.TrimSpace(out)
+	if secret == "" {
+		return "", fmt.Errorf("%w: %s", ErrKeychainKeyMissing, account)
+	}
+	return secret, nil
+}
+
+func (c *commandKeychain) Set(account, secret string) error {
+	args, stdin := c.set(account, secret)
+	_, _, err := c.run(args, stdin)
+	return err
+}
+
+func (c *commandKeychain) Delete(account string) error {
+	out, code, err := c.run(c.remove(account), "")
+	if err != nil && code != 0 && c.missing(code, out) {
+		return nil
+	}
+	return err
+}
+
+// MoveKeysToKeychain moves every private key kept in the key file into the keychain, decrypting
+// encrypted keys first. It returns the aliases that were moved. Keys are decoded before the key file
+// is locked, since decoding may prompt for a passphrase; a wallet changed in between is left alone.
+// The key file backup is replaced by the new version too, so no moved key stays on disk.
+func (k *KeyOps) MoveKeysToKeychain() ([]string, error) {
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]ed25519.PrivateKey)
+	for alias, wallet := range data.Wallets {
+		if wallet.WatchOnly || wallet.Keychain {
+			continue
+		}
+		key, err := k.decodePrivateKey(alias, wallet)
+		if err != nil {
+			return nil, err
+		}
+		keys[alias] = key
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	current, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	var moved []string
+	for alias, key := range keys {
+		wallet, ok := current.Wallets[alias]
+		if !ok || wallet.PrivateKey != data.Wallets[alias].PrivateKey || wallet.Keychain {
+			continue
+		}
+		if err := k.storeInKeychain(wallet.PublicKey, key); err != nil {
+			return nil, err
+		}
+
+		wallet.Keychain = true
+		wallet.Encrypted = false
+		wallet.PrivateKey, wallet.Salt, wallet.Nonce = "", "", ""
+		current.Wallets[alias] = wallet
+		moved = append(moved, alias)
+	}
+	if len(moved) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(moved)
+	return moved, k.writeWalletData("keychain", current)
+}
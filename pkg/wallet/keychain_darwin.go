@@ -0,0 +1,24 @@
+//go:build darwin
+
+package wallet
+
+import "fmt"
+
+// OSKeychain returns the login keychain, driven through the security tool. Secrets are added in
+// its interactive mode, so they are read from stdin instead of the command line.
+func OSKeychain() Keychain {
+	return &commandKeychain{
+		tool: "security",
+		get: func(account string) []string {
+			return []string{"find-generic-password", "-s", KeychainService, "-a", account, "-w"}
+		},
+		set: func(account, secret string) ([]string, string) {
+			return []string{"-i"}, fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", KeychainService, account, secret)
+		},
+		remove: func(account string) []string {
+			return []string{"delete-generic-password", "-s", KeychainService, "-a", account}
+		},
+		// security exits with errSecItemNotFound (44) for a missing item.
+		missing: func(exitCode int, _ string) bool { return exitCode == 44 },
+	}
+}
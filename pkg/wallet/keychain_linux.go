@@ -0,0 +1,25 @@
+//go:build linux
+
+package wallet
+
+// OSKeychain returns the Secret Service (GNOME Keyring, KWallet), driven through secret-tool from
+// libsecret, which reads the secret to store from stdin.
+func OSKeychain() Keychain {
+	attributes := func(account string) []string {
+		return []string{"service", KeychainService, "account", account}
+	}
+	return &commandKeychain{
+		tool: "secret-tool",
+		get: func(account string) []string {
+			return append([]string{"lookup"}, attributes(account)...)
+		},
+		set: func(account, secret string) ([]string, string) {
+			return append([]string{"store", "--label=sleeng " + account}, attributes(account)...), secret
+		},
+		remove: func(account string) []string {
+			return append([]string{"clear"}, attributes(account)...)
+		},
+		// secret-tool lookup exits with 1 and prints nothing for a missing secret.
+		missing: func(exitCode int, stderr string) bool { return exitCode == 1 && stderr == "" },
+	}
+}
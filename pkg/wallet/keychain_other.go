@@ -0,0 +1,15 @@
+//go:build !darwin && !linux && !windows
+
+package wallet
+
+// OSKeychain returns a keychain that fails every operation: this platform has no supported
+// credential store.
+func OSKeychain() Keychain {
+	return unavailableKeychain{}
+}
+
+type unavailableKeychain struct{}
+
+func (unavailableKeychain) Get(string) (string, error) { return "", ErrKeychainUnavailable }
+func (unavailableKeychain) Set(string, string) error   { return ErrKeychainUnavailable }
+func (unavailableKeychain) Delete(string) error        { return ErrKeychainUnavailable }
@@ -0,0 +1,116 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/mr-tron/base58"
+	"github.com/stretchr/testify/assert"
+)
+
+// memKeychain is an in-memory Keychain.
+type memKeychain map[string]string
+
+func (m memKeychain) Get(account string) (string, error) {
+	secret, ok := m[account]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrKeychainKeyMissing, account)
+	}
+	return secret, nil
+}
+
+func (m memKeychain) Set(account, secret string) error {
+	m[account] = secret
+	return nil
+}
+
+func (m memKeychain) Delete(account string) error {
+	delete(m, account)
+	return nil
+}
+
+func TestKeysInTheKeychain(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	files := newMemFiles()
+	keychain := memKeychain{}
+	ops := &KeyOps{FileReader: files, FileWriter: files, Keychain: keychain, NewKeysInKeychain: true}
+
+	assert.NoError(t, ops.WriteKeyToFile("main", key, "mainAddress"))
+	assert.Equal(t, base58.Encode(key), keychain["mainAddress"])
+
+	var data WalletData
+	assert.NoError(t, json.Unmarshal(files.files[KeyFileName], &data))
+	assert.True(t, data.Wallets["main"].Keychain)
+	assert.Empty(t, data.Wallets["main"].PrivateKey)
+
+	exported, err := ops.ExportKey("main", ExportBase58)
+	assert.NoError(t, err)
+	assert.Equal(t, base58.Encode(key), exported)
+
+	// The key is kept under the public key, so renaming leaves it in place.
+	assert.NoError(t, ops.RenameKey("main", "daily"))
+	got, err := ops.GetPrivateKeyByAlias("daily")
+	assert.NoError(t, err)
+	assert.Equal(t, getSolCLIComptKey(key), got)
+
+	// Encrypting the key file leaves keychain keys alone.
+	plan, err := ops.PlanEncryptKeys("pw")
+	assert.NoError(t, err)
+	assert.True(t, plan.Empty())
+
+	delete(keychain, "mainAddress")
+	_, err = ops.GetPrivateKeyByAlias("daily")
+	assert.ErrorIs(t, err, ErrKeychainKeyMissing)
+	keychain["mainAddress"] = base58.Encode(key)
+
+	_, err = ops.DeleteKey("daily")
+	assert.NoError(t, err)
+	assert.Empty(t, keychain)
+}
+
+func TestMoveKeysToKeychain(t *testing.T) {
+	plain := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	encrypted := ed25519.NewKeyFromSeed(append(make([]byte, ed25519.SeedSize-1), 1))
+	files := newMemFiles()
+	ops := &KeyOps{FileReader: files, FileWriter: files}
+	assert.NoError(t, ops.WriteKeyToFile("plain", plain, "plainAddress"))
+	ops.Passphrase = func(string, bool) (string, error) { return "pw", nil }
+	assert.NoError(t, ops.WriteKeyToFile("encrypted", encrypted, "encryptedAddress"))
+	assert.NoError(t, ops.AddWatchOnlyWallet("watched", "11111111111111111111111111111111"))
+
+	keychain := memKeychain{}
+	ops.Keychain = keychain
+
+	SetReadOnlyMode(true)
+	_, err := ops.MoveKeysToKeychain()
+	assert.Error(t, err)
+	assert.Empty(t, keychain)
+	SetReadOnlyMode(false)
+
+	moved, err := ops.MoveKeysToKeychain()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"encrypted", "plain"}, moved)
+	assert.Equal(t, memKeychain{"plainAddress": base58.Encode(plain), "encryptedAddress": base58.Encode(encrypted)}, keychain)
+
+	// Neither the key file nor its backup holds the moved keys any more.
+	for _, file := range []string{KeyFileName, KeyFileName + backupSuffix} {
+		var data WalletData
+		assert.NoError(t, json.Unmarshal(files.files[file], &data), file)
+		for _, alias := range moved {
+			wallet := data.Wallets[alias]
+			assert.True(t, wallet.Keychain, file)
+			assert.False(t, wallet.Encrypted, file)
+			assert.Empty(t, wallet.PrivateKey+wallet.Salt+wallet.Nonce, file)
+		}
+	}
+
+	got, err := ops.GetPrivateKeyByAlias("encrypted")
+	assert.NoError(t, err)
+	assert.Equal(t, getSolCLIComptKey(encrypted), got)
+
+	moved, err = ops.MoveKeysToKeychain()
+	assert.NoError(t, err)
+	assert.Empty(t, moved)
+}
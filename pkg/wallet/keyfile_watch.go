@@ -0,0 +1,187 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// keyFileSettle is how long KeyFileWatcher waits after the last change to the key file before it
+// reads it, so the several events of one write are handled once.
+var keyFileSettle = 50 * time.Millisecond
+
+// KeystoreReload reports that another process changed the key file and it was read again.
+type KeystoreReload struct {
+	Time time.Time
+	// Changes is what changed since the key file was last loaded.
+	Changes []KeystoreChange
+	// Err is why the changed key file could not be loaded. The version loaded before is kept.
+	Err error
+}
+
+// KeyFileWatcher keeps the key file loaded for commands that run for a long time, such as watch and
+// the daemon, and reloads it when another process, or a sync, changes it. Changes are made with Plan
+// and Apply against the loaded version: a plan made before the file changed on disk fails with
+// ErrPlanStale instead of writing over the other change, until it is made again after a reload.
+type KeyFileWatcher struct {
+	keyOps *KeyOps
+
+	mu sync.Mutex
+	// loaded is the key file as last read or written by the watcher.
+	loaded []byte
+}
+
+// NewKeyFileWatcher loads the key file of k.
+func (k *KeyOps) NewKeyFileWatcher() (*KeyFileWatcher, error) {
+	w := &KeyFileWatcher{keyOps: k}
+	if _, err := w.Refresh(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// KeyFileWatcher loads the key file for watching, failing for key stores that are not a key file.
+func (w *WalletConfig) KeyFileWatcher() (*KeyFileWatcher, error) {
+	keyOps, ok := w.KeyOps.(*KeyOps)
+	if !ok {
+		return nil, errors.New("the key store is not a key file and cannot be watched")
+	}
+	return keyOps.NewKeyFileWatcher()
+}
+
+// Data returns a copy of the loaded key file.
+func (w *KeyFileWatcher) Data() WalletData {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// The loaded version parsed when it was read, so it parses again.
+	data, _ := parseWalletData(w.loaded)
+	return data
+}
+
+// Refresh reads the key file again and returns what changed since it was last loaded. A key file
+// that cannot be parsed fails with ErrKeyFileCorrupted and the loaded version is kept.
+func (w *KeyFileWatcher) Refresh() ([]KeystoreChange, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.reload()
+}
+
+// reload is Refresh with mu held.
+func (w *KeyFileWatcher) reload() ([]KeystoreChange, error) {
+	path := w.keyOps.keyFilePath()
+	fileData, err := w.keyOps.FileReader.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	if bytes.Equal(fileData, w.loaded) {
+		return nil, nil
+	}
+
+	after, err := parseWalletData(fileData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s (%v)", ErrKeyFileCorrupted, path, err)
+	}
+
+	var before WalletData
+	if w.loaded != nil {
+		before, _ = parseWalletData(w.loaded)
+	}
+	w.loaded = fileData
+	return DiffWalletData(before, after), nil
+}
+
+// Plan works out a change to the loaded key file without writing it, like the Plan methods of KeyOps.
+func (w *KeyFileWatcher) Plan(action string, change func(data *WalletData) error) (*KeystorePlan, error) {
+	w.mu.Lock()
+	loaded := w.loaded
+	w.mu.Unlock()
+
+	return planFrom(action, loaded, change)
+}
+
+// Apply writes a plan made with Plan. It fails with ErrPlanStale when the key file changed on disk
+// since the plan was made; call Refresh and make the plan again.
+func (w *KeyFileWatcher) Apply(plan *KeystorePlan) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.keyOps.ApplyPlan(plan); err != nil {
+		return err
+	}
+	if plan.Empty() {
+		return nil
+	}
+
+	// writeWalletData wrote exactly this, so the write is not reported as a reload.
+	written, err := json.Marshal(plan.after)
+	if err != nil {
+		return fmt.Errorf("error marshaling JSON: %w", err)
+	}
+	w.loaded = written
+	return nil
+}
+
+// Run reloads the key file every time another process changes it and calls fn with what changed,
+// until ctx is cancelled. A change that cannot be loaded is reported with Err and the loaded version
+// is kept. The directory of the key file is watched rather than the file, since writes replace it.
+func (w *KeyFileWatcher) Run(ctx context.Context, fn func(KeystoreReload)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch key file: %w", err)
+	}
+	defer watcher.Close()
+
+	path := filepath.Clean(w.keyOps.keyFilePath())
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to watch key file: %w", err)
+	}
+	// A change made between loading the file and watching it would otherwise go unnoticed.
+	w.check(fn)
+
+	settle := time.NewTimer(keyFileSettle)
+	settle.Stop()
+	defer settle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("key file watch ended")
+			}
+			if filepath.Clean(event.Name) == path {
+				settle.Reset(keyFileSettle)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("key file watch ended")
+			}
+			return fmt.Errorf("failed to watch key file: %w", err)
+		case <-settle.C:
+			w.check(fn)
+		}
+	}
+}
+
+// check reloads the key file and reports the outcome to fn when the file changed.
+func (w *KeyFileWatcher) check(fn func(KeystoreReload)) {
+	w.mu.Lock()
+	previous := w.loaded
+	changes, err := w.reload()
+	changed := !bytes.Equal(previous, w.loaded)
+	w.mu.Unlock()
+
+	if err != nil || changed {
+		fn(KeystoreReload{Time: time.Now(), Changes: changes, Err: err})
+	}
+}
@@ -0,0 +1,144 @@
+package wallet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startKeyFileWatcher runs w until the test ends and returns the reloads it reports.
+func startKeyFileWatcher(t *testing.T, w *KeyFileWatcher) <-chan KeystoreReload {
+	ctx, cancel := context.WithCancel(context.Background())
+	reloads := make(chan KeystoreReload, 10)
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx, func(reload KeystoreReload) { reloads <- reload }) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	// Give the watch time to be set up before the test changes anything.
+	time.Sleep(100 * time.Millisecond)
+	return reloads
+}
+
+func nextReload(t *testing.T, reloads <-chan KeystoreReload) KeystoreReload {
+	t.Helper()
+	select {
+	case reload := <-reloads:
+		return reload
+	case <-time.After(5 * time.Second):
+		t.Fatal("the key file was not reloaded")
+		return KeystoreReload{}
+	}
+}
+
+func assertNoReload(t *testing.T, reloads <-chan KeystoreReload) {
+	t.Helper()
+	select {
+	case reload := <-reloads:
+		t.Fatalf("unexpected reload: %+v", reload)
+	case <-time.After(4 * keyFileSettle):
+	}
+}
+
+func TestKeyFileWatcherReloadsExternalChanges(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, newDiskKeyOps(dir).SetNetwork("devnet", ""))
+
+	watcher, err := newDiskKeyOps(dir).NewKeyFileWatcher()
+	assert.NoError(t, err)
+	reloads := startKeyFileWatcher(t, watcher)
+
+	// Another process adds a wallet.
+	assert.NoError(t, newDiskKeyOps(dir).AddWatchOnlyWallet("cold", "9BtrcPNVzqnyywbcoXf9s3J6xYfh3mppxhnS2UCq4j83"))
+
+	reload := nextReload(t, reloads)
+	assert.NoError(t, reload.Err)
+	if assert.NotEmpty(t, reload.Changes) {
+		assert.Equal(t, KeystoreChange{Kind: ChangeAdded, Alias: "cold", To: "9BtrcPNVzqnyywbcoXf9s3J6xYfh3mppxhnS2UCq4j83"}, reload.Changes[0])
+	}
+	assert.Contains(t, watcher.Data().Wallets, "cold")
+}
+
+func TestKeyFileWatcherKeepsTheLoadedVersionOfACorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, newDiskKeyOps(dir).SetNetwork("devnet", ""))
+
+	watcher, err := newDiskKeyOps(dir).NewKeyFileWatcher()
+	assert.NoError(t, err)
+	reloads := startKeyFileWatcher(t, watcher)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, KeyFileName), []byte(`{"wallets":`), 0600))
+
+	reload := nextReload(t, reloads)
+	assert.ErrorIs(t, reload.Err, ErrKeyFileCorrupted)
+	assert.Equal(t, "devnet", watcher.Data().Network)
+
+	_, err = watcher.Refresh()
+	assert.ErrorIs(t, err, ErrKeyFileCorrupted)
+}
+
+func TestKeyFileWatcherRefusesToWriteOverExternalChanges(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, newDiskKeyOps(dir).SetNetwork("devnet", ""))
+
+	watcher, err := newDiskKeyOps(dir).NewKeyFileWatcher()
+	assert.NoError(t, err)
+	setNetwork := func(network string) func(*WalletData) error {
+		return func(data *WalletData) error {
+			data.Network = network
+			return nil
+		}
+	}
+
+	// The plan is made from the loaded key file, then another process changes it on disk.
+	plan, err := watcher.Plan("network", setNetwork("testnet"))
+	assert.NoError(t, err)
+	assert.NoError(t, newDiskKeyOps(dir).AddWatchOnlyWallet("cold", "9BtrcPNVzqnyywbcoXf9s3J6xYfh3mppxhnS2UCq4j83"))
+
+	assert.ErrorIs(t, watcher.Apply(plan), ErrPlanStale)
+	network, _, err := newDiskKeyOps(dir).GetNetwork()
+	assert.NoError(t, err)
+	assert.Equal(t, "devnet", network)
+
+	// After a refresh the change is made on top of the other one.
+	changes, err := watcher.Refresh()
+	assert.NoError(t, err)
+	assert.Contains(t, changes, KeystoreChange{Kind: ChangeAdded, Alias: "cold", To: "9BtrcPNVzqnyywbcoXf9s3J6xYfh3mppxhnS2UCq4j83"})
+	plan, err = watcher.Plan("network", setNetwork("testnet"))
+	assert.NoError(t, err)
+	assert.NoError(t, watcher.Apply(plan))
+
+	data, err := newDiskKeyOps(dir).readWalletData(filepath.Join(dir, KeyFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, "testnet", data.Network)
+	assert.Contains(t, data.Wallets, "cold")
+}
+
+func TestKeyFileWatcherDoesNotReportItsOwnWrites(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, newDiskKeyOps(dir).SetNetwork("devnet", ""))
+
+	watcher, err := newDiskKeyOps(dir).NewKeyFileWatcher()
+	assert.NoError(t, err)
+	reloads := startKeyFileWatcher(t, watcher)
+
+	plan, err := watcher.Plan("network", func(data *WalletData) error {
+		data.Network = "testnet"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, watcher.Apply(plan))
+	assertNoReload(t, reloads)
+
+	// A change by another process after it is still reported.
+	assert.NoError(t, newDiskKeyOps(dir).SetNetwork("mainnet-beta", ""))
+	reload := nextReload(t, reloads)
+	assert.NoError(t, reload.Err)
+	assert.Equal(t, []KeystoreChange{{Kind: ChangeSetting, Field: "network", From: "testnet", To: "mainnet-beta"}}, reload.Changes)
+}
@@ -0,0 +1,107 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/crypto/ed25519"
+)
+
+var (
+	ErrInvalidKeypair    = errors.New("keypair file must hold a JSON array of exactly 64 bytes")
+	ErrKeypairMismatch   = errors.New("the public key in the keypair file does not belong to its private key")
+	ErrKeypairFileExists = errors.New("keypair file already exists")
+)
+
+// ParseKeypair reads a keypair in the solana-keygen format: a JSON array of the 32-byte seed followed
+// by the 32-byte public key. The public key must be the one the seed derives.
+func ParseKeypair(data []byte) (ed25519.PrivateKey, error) {
+	var values []int
+	if err := json.Unmarshal(bytes.TrimSpace(data), &values); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeypair, err)
+	}
+	if len(values) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w, got %d", ErrInvalidKeypair, len(values))
+	}
+
+	key := make(ed25519.PrivateKey, ed25519.PrivateKeySize)
+	for i, v := range values {
+		if v < 0 || v > 255 {
+			return nil, fmt.Errorf("%w: value %d at position %d is not a byte", ErrInvalidKeypair, v, i)
+		}
+		key[i] = byte(v)
+	}
+
+	derived := ed25519.NewKeyFromSeed(key.Seed())
+	if !bytes.Equal(derived, key) {
+		return nil, ErrKeypairMismatch
+	}
+
+	return key, nil
+}
+
+// ImportKeypairFile stores the key of a solana-keygen keypair file, such as ~/.config/solana/id.json,
+// under alias and returns its address.
+func (w *WalletConfig) ImportKeypairFile(alias, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read keypair file: %w", err)
+	}
+
+	key, err := ParseKeypair(data)
+	if err != nil {
+		return "", err
+	}
+
+	if alias == "" {
+		alias = getRandomAlias() + "-" + "wallet"
+	}
+
+	address := solana.PrivateKey(key).PublicKey().String()
+	if err := w.KeyOps.WriteKeyToFile(alias, key, address); err != nil {
+		return "", err
+	}
+
+	return address, nil
+}
+
+// ExportKeypairFile writes the key of a stored wallet to path in the solana-keygen format, readable
+// only by the current user. An existing file is only replaced when overwrite is set.
+func (w *WalletConfig) ExportKeypairFile(alias, path string, overwrite bool) error {
+	keypair, err := w.KeyOps.ExportKey(alias, ExportByteArray)
+	if err != nil {
+		return err
+	}
+
+	if err := guardWrite(path); err != nil {
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrKeypairFileExists, path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create keypair file: %w", err)
+	}
+
+	// A replaced file keeps its mode, so tighten it like a new one.
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to restrict keypair file: %w", err)
+	}
+	if _, err := file.WriteString(keypair); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write keypair file: %w", err)
+	}
+	return file.Close()
+}
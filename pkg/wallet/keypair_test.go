@@ -0,0 +1,114 @@
+package wallet
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestParseKeypair(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	other := ed25519.NewKeyFromSeed([]byte("another seed of thirty two bytes"))
+
+	mismatched := append(append(ed25519.PrivateKey{}, key.Seed()...), other.Public().(ed25519.PublicKey)...)
+	outOfRange := make([]int, ed25519.PrivateKeySize)
+	outOfRange[3] = 256
+
+	tests := []struct {
+		name        string
+		data        []byte
+		expectedErr error
+	}{
+		{name: "solana-keygen Format", data: []byte(getSolCLIComptKey(key) + "\n")},
+		{name: "Too Short", data: jsonMarshalValue(t, bytesToInts(key)[:63]), expectedErr: ErrInvalidKeypair},
+		{name: "Too Long", data: jsonMarshalValue(t, append(bytesToInts(key), 1)), expectedErr: ErrInvalidKeypair},
+		{name: "Not A Byte", data: jsonMarshalValue(t, outOfRange), expectedErr: ErrInvalidKeypair},
+		{name: "Not An Array", data: []byte(`{"key": 1}`), expectedErr: ErrInvalidKeypair},
+		{name: "Public Key Does Not Match", data: []byte(getSolCLIComptKey(mismatched)), expectedErr: ErrKeypairMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeypair(tt.data)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, key, got)
+		})
+	}
+}
+
+func TestKeypairFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	account := solana.NewWallet()
+	idJSON := filepath.Join(dir, "id.json")
+	assert.NoError(t, os.WriteFile(idJSON, []byte(getSolCLIComptKey(ed25519.PrivateKey(account.PrivateKey))), 0600))
+
+	files := newMemFiles()
+	wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}}
+
+	address, err := wc.ImportKeypairFile("cli", idJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, account.PublicKey().String(), address)
+
+	info, err := wc.GetWalletInfo("cli")
+	assert.NoError(t, err)
+	assert.Equal(t, address, info.PublicKey)
+	assert.True(t, info.Active)
+
+	out := filepath.Join(dir, "out.json")
+	assert.NoError(t, wc.ExportKeypairFile("cli", out, false))
+
+	exported, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	original, err := os.ReadFile(idJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, original, exported)
+
+	stat, err := os.Stat(out)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	// An existing file is only replaced with overwrite.
+	assert.ErrorIs(t, wc.ExportKeypairFile("cli", out, false), ErrKeypairFileExists)
+	assert.NoError(t, os.Chmod(out, 0644))
+	assert.NoError(t, wc.ExportKeypairFile("cli", out, true))
+	stat, err = os.Stat(out)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+}
+
+func TestImportKeypairFileRejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "id.json")
+	assert.NoError(t, os.WriteFile(path, []byte("[1,2,3]"), 0600))
+
+	files := newMemFiles()
+	wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}}
+
+	_, err := wc.ImportKeypairFile("cli", path)
+	assert.ErrorIs(t, err, ErrInvalidKeypair)
+	assert.Empty(t, files.files)
+}
+
+func jsonMarshalValue(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return data
+}
+
+func bytesToInts(b []byte) []int {
+	ints := make([]int, len(b))
+	for i, v := range b {
+		ints[i] = int(v)
+	}
+	return ints
+}
@@ -0,0 +1,351 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// The crash test runs a bounded number of sequences by default. Soak it with, for example,
+//
+//	go test ./pkg/wallet -run TestKeystoreSurvivesCrashes -keystore.runs=20000 -keystore.seed=0
+//
+// and replay a failing sequence with the seed it reports and -keystore.runs=1.
+var (
+	keystoreRuns = flag.Int("keystore.runs", 30, "number of random operation sequences the keystore crash test runs")
+	keystoreSeed = flag.Int64("keystore.seed", 1, "seed of the first keystore crash sequence; 0 picks one from the clock")
+)
+
+var errCrash = errors.New("simulated crash")
+
+// crashFiles is an in-memory file system that crashes on a random write. A crash either loses the
+// write, lands it and then dies, or, when the file already exists, tears it to a prefix as a
+// filesystem without atomic renames could. Every write after a crash fails until reboot, as the
+// process is gone.
+type crashFiles struct {
+	*memFiles
+	rng       *rand.Rand
+	crashRate float64
+	down      bool
+}
+
+func (c *crashFiles) WriteFile(filename string, data []byte) error {
+	if c.down {
+		return errCrash
+	}
+	data = append([]byte(nil), data...)
+	if c.rng.Float64() >= c.crashRate {
+		return c.memFiles.WriteFile(filename, data)
+	}
+
+	c.down = true
+	switch c.rng.Intn(3) {
+	case 0:
+		// Lost: the process died before the write.
+	case 1:
+		c.files[filename] = data
+	case 2:
+		if _, exists := c.files[filename]; exists {
+			c.files[filename] = data[:c.rng.Intn(len(data))]
+		}
+	}
+	return errCrash
+}
+
+// reboot brings the file system back after a crash and restores the key file from its backup when
+// it no longer parses, as the corruption error tells the user to.
+func (c *crashFiles) reboot(t *testing.T) {
+	c.down = false
+	data, exists := c.files[KeyFileName]
+	if !exists {
+		return
+	}
+	if _, err := parseWalletData(data); err == nil {
+		return
+	}
+	backup, exists := c.files[KeyFileName+backupSuffix]
+	if !exists {
+		t.Fatalf("key file is corrupted and there is no backup")
+	}
+	if _, err := parseWalletData(backup); err != nil {
+		t.Fatalf("neither the key file nor its backup parses: %v", err)
+	}
+	c.files[KeyFileName] = backup
+}
+
+// keystoreModel is what the key file should hold: the public key of every alias and the active alias.
+type keystoreModel struct {
+	Wallets map[string]string
+	Active  string
+}
+
+func (m keystoreModel) clone() keystoreModel {
+	wallets := make(map[string]string, len(m.Wallets))
+	for alias, pub := range m.Wallets {
+		wallets[alias] = pub
+	}
+	return keystoreModel{Wallets: wallets, Active: m.Active}
+}
+
+func (m keystoreModel) String() string {
+	aliases := make([]string, 0, len(m.Wallets))
+	for alias := range m.Wallets {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return fmt.Sprintf("%v active=%q", aliases, m.Active)
+}
+
+// keystoreOp is a random operation: run performs it and states lists the models the key file may
+// hold afterwards, the first being the model when no crash interrupts it. A nil states means the
+// operation must fail and change nothing.
+type keystoreOp struct {
+	name   string
+	run    func(k *KeyOps) error
+	states []keystoreModel
+}
+
+// keystoreCrashRun is one random sequence of operations.
+type keystoreCrashRun struct {
+	t       *testing.T
+	rng     *rand.Rand
+	files   *crashFiles
+	model   keystoreModel
+	keys    map[string]ed25519.PrivateKey // every key ever generated, by public key
+	checked map[string]bool               // ciphertexts already decrypted to the right key
+	encrypt bool
+	imports int
+}
+
+var keystoreAliases = []string{"alpha", "bravo", "charlie", "delta", "echo"}
+
+func (r *keystoreCrashRun) alias() string {
+	return keystoreAliases[r.rng.Intn(len(keystoreAliases))]
+}
+
+func (r *keystoreCrashRun) newKey() (ed25519.PrivateKey, string) {
+	wallet := solana.NewWallet()
+	key := ed25519.PrivateKey(wallet.PrivateKey)
+	pub := wallet.PublicKey().String()
+	r.keys[pub] = key
+	return key, pub
+}
+
+func (r *keystoreCrashRun) nextOp() keystoreOp {
+	before := r.model
+	switch n := r.rng.Intn(20); {
+	case n < 6:
+		alias := r.alias()
+		key, pub := r.newKey()
+		op := keystoreOp{name: "create " + alias, run: func(k *KeyOps) error { return k.WriteKeyToFile(alias, key, pub) }}
+		if _, taken := before.Wallets[alias]; !taken {
+			after := before.clone()
+			after.Wallets[alias], after.Active = pub, alias
+			op.states = []keystoreModel{after}
+		}
+		return op
+	case n < 10:
+		alias := r.alias()
+		op := keystoreOp{name: "switch " + alias, run: func(k *KeyOps) error { return k.SetActiveKey(alias) }}
+		if _, exists := before.Wallets[alias]; exists {
+			after := before.clone()
+			after.Active = alias
+			op.states = []keystoreModel{after}
+		}
+		return op
+	case n < 14:
+		from, to := r.alias(), r.alias()
+		op := keystoreOp{name: "rename " + from + " " + to, run: func(k *KeyOps) error { return k.RenameKey(from, to) }}
+		_, exists := before.Wallets[from]
+		_, taken := before.Wallets[to]
+		if exists && !taken {
+			after := before.clone()
+			after.Wallets[to] = after.Wallets[from]
+			delete(after.Wallets, from)
+			if after.Active == from {
+				after.Active = to
+			}
+			op.states = []keystoreModel{after}
+		}
+		return op
+	case n < 17:
+		alias := r.alias()
+		op := keystoreOp{name: "delete " + alias, run: func(k *KeyOps) error { _, err := k.DeleteKey(alias); return err }}
+		if _, exists := before.Wallets[alias]; exists {
+			after := before.clone()
+			delete(after.Wallets, alias)
+			if after.Active == alias {
+				after.Active = ""
+				remaining := make([]string, 0, len(after.Wallets))
+				for other := range after.Wallets {
+					remaining = append(remaining, other)
+				}
+				sort.Strings(remaining)
+				if len(remaining) > 0 {
+					after.Active = remaining[0]
+				}
+			}
+			op.states = []keystoreModel{after}
+		}
+		return op
+	case n == 17 && r.encrypt:
+		// Encryption is slow by design, so only some sequences encrypt, and rarely.
+		op := keystoreOp{name: "encrypt", run: func(k *KeyOps) error { _, err := k.EncryptKeys(allowlistPassphrase); return err }}
+		if _, exists := r.files.files[KeyFileName]; exists {
+			op.states = []keystoreModel{before}
+		}
+		return op
+	default:
+		// A bulk import adds its keys one at a time, so a crash may leave any prefix of them.
+		r.imports++
+		type imported struct {
+			alias string
+			key   ed25519.PrivateKey
+			pub   string
+		}
+		batch := make([]imported, 1+r.rng.Intn(3))
+		prefixes := []keystoreModel{before}
+		for i := range batch {
+			key, pub := r.newKey()
+			batch[i] = imported{alias: fmt.Sprintf("import-%d-%d", r.imports, i), key: key, pub: pub}
+			next := prefixes[len(prefixes)-1].clone()
+			next.Wallets[batch[i].alias], next.Active = pub, batch[i].alias
+			prefixes = append(prefixes, next)
+		}
+		final := prefixes[len(prefixes)-1]
+		return keystoreOp{
+			name: fmt.Sprintf("import %d keys", len(batch)),
+			run: func(k *KeyOps) error {
+				for _, entry := range batch {
+					if err := k.WriteKeyToFile(entry.alias, entry.key, entry.pub); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			states: append([]keystoreModel{final}, prefixes[:len(prefixes)-1]...),
+		}
+	}
+}
+
+// stored reads the key file into a model, checking the invariants on the way: it parses, the active
+// alias exists or is empty and every private key decodes to exactly the key generated for its address.
+func (r *keystoreCrashRun) stored() keystoreModel {
+	r.t.Helper()
+
+	data, exists := r.files.files[KeyFileName]
+	if !exists {
+		return keystoreModel{Wallets: map[string]string{}}
+	}
+	walletData, err := parseWalletData(data)
+	if err != nil {
+		r.t.Fatalf("key file does not parse after recovery: %v", err)
+	}
+
+	model := keystoreModel{Wallets: make(map[string]string, len(walletData.Wallets)), Active: walletData.ActiveAlias}
+	if _, exists := walletData.Wallets[model.Active]; model.Active != "" && !exists {
+		r.t.Fatalf("active alias %q is not a stored wallet", model.Active)
+	}
+	for alias, wallet := range walletData.Wallets {
+		known, ok := r.keys[wallet.PublicKey]
+		if !ok {
+			r.t.Fatalf("%s holds unknown address %s", alias, wallet.PublicKey)
+		}
+		if !wallet.Encrypted {
+			key, err := getPrivateKeyFromSolCLICompStr(wallet.PrivateKey)
+			if err != nil || !key.Equal(known) {
+				r.t.Fatalf("the private key of %s was altered (%v)", alias, err)
+			}
+		} else if !r.checked[wallet.PrivateKey] {
+			key, err := decryptPrivateKey(wallet.PrivateKey, wallet.Salt, wallet.Nonce, allowlistPassphrase)
+			if err != nil || !key.Equal(known) {
+				r.t.Fatalf("the encrypted private key of %s was altered (%v)", alias, err)
+			}
+			r.checked[wallet.PrivateKey] = true
+		}
+		model.Wallets[alias] = wallet.PublicKey
+	}
+	return model
+}
+
+func (r *keystoreCrashRun) run(ops int) {
+	for i := 0; i < ops; i++ {
+		op := r.nextOp()
+		keyOps := &KeyOps{FileReader: r.files, FileWriter: r.files}
+		err := op.run(keyOps)
+
+		crashed := r.files.down
+		if crashed {
+			r.files.reboot(r.t)
+		}
+		got := r.stored()
+
+		switch {
+		case crashed:
+			accepted := append([]keystoreModel{r.model}, op.states...)
+			if !containsModel(accepted, got) {
+				r.t.Fatalf("op %d (%s) crashed and left %s, want one of %v", i, op.name, got, accepted)
+			}
+		case op.states == nil:
+			if err == nil {
+				r.t.Fatalf("op %d (%s) succeeded on %s, want an error", i, op.name, r.model)
+			}
+			if !reflect.DeepEqual(got, r.model) {
+				r.t.Fatalf("op %d (%s) failed but changed %s to %s", i, op.name, r.model, got)
+			}
+		default:
+			if err != nil {
+				r.t.Fatalf("op %d (%s) failed on %s: %v", i, op.name, r.model, err)
+			}
+			if !reflect.DeepEqual(got, op.states[0]) {
+				r.t.Fatalf("op %d (%s) left %s, want %s", i, op.name, got, op.states[0])
+			}
+		}
+		r.model = got
+	}
+}
+
+func containsModel(models []keystoreModel, model keystoreModel) bool {
+	for _, candidate := range models {
+		if reflect.DeepEqual(candidate, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestKeystoreSurvivesCrashes runs random sequences of keystore operations, crashing between and
+// during their writes, and checks after every recovery that no stored key was lost or altered,
+// that the active alias exists or is empty and that an interrupted operation either happened or
+// did not.
+func TestKeystoreSurvivesCrashes(t *testing.T) {
+	seed := *keystoreSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	for i := 0; i < *keystoreRuns; i++ {
+		runSeed := seed + int64(i)
+		t.Run(fmt.Sprintf("seed=%d", runSeed), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(runSeed))
+			run := &keystoreCrashRun{
+				t:       t,
+				rng:     rng,
+				files:   &crashFiles{memFiles: newMemFiles(), rng: rng, crashRate: 0.1},
+				model:   keystoreModel{Wallets: map[string]string{}},
+				keys:    make(map[string]ed25519.PrivateKey),
+				checked: make(map[string]bool),
+				encrypt: runSeed%10 == 0,
+			}
+			run.run(40)
+		})
+	}
+}
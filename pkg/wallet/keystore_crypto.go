@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt parameters used to derive the AES key from a passphrase.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLength   = 16
+)
+
+var (
+	ErrWrongPassphrase    = errors.New("wrong passphrase")
+	ErrPassphraseRequired = errors.New("wallet is encrypted and no passphrase was provided")
+)
+
+// maxPassphraseAttempts is how often a passphrase is asked for before decrypting a key fails.
+const maxPassphraseAttempts = 3
+
+// PassphraseFunc supplies the passphrase for a wallet alias.
+// isNew is true when the passphrase will be used to encrypt a key rather than decrypt one.
+type PassphraseFunc func(alias string, isNew bool) (string, error)
+
+// encryptPrivateKey encrypts a private key with AES-GCM under a scrypt-derived key.
+// It returns the base64 encoded ciphertext, salt and nonce.
+func encryptPrivateKey(key ed25519.PrivateKey, passphrase string) (string, string, string, error) {
+	return sealWithPassphrase(key, passphrase)
+}
+
+// decryptPrivateKey reverses encryptPrivateKey, returning ErrWrongPassphrase when authentication fails.
+func decryptPrivateKey(ciphertext, salt, nonce, passphrase string) (ed25519.PrivateKey, error) {
+	plaintext, err := openWithPassphrase(ciphertext, salt, nonce, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plaintext) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("decrypted key has invalid length %d", len(plaintext))
+	}
+
+	return ed25519.PrivateKey(plaintext), nil
+}
+
+// sealWithPassphrase encrypts plaintext with AES-GCM under a scrypt-derived key.
+// It returns the base64 encoded ciphertext, salt and nonce.
+func sealWithPassphrase(plaintext []byte, passphrase string) (string, string, string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", "", "", fmt.Errorf("error generating salt: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", "", fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+	return base64.StdEncoding.EncodeToString(ciphertext),
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(nonce),
+		nil
+}
+
+// openWithPassphrase reverses sealWithPassphrase, returning ErrWrongPassphrase when authentication fails.
+func openWithPassphrase(ciphertext, salt, nonce, passphrase string) ([]byte, error) {
+	rawCiphertext, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding ciphertext: %w", err)
+	}
+
+	rawSalt, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding salt: %w", err)
+	}
+
+	rawNonce, err := base64.StdEncoding.DecodeString(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding nonce: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, rawSalt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rawNonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(rawNonce))
+	}
+
+	plaintext, err := gcm.Open(nil, rawNonce, rawCiphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	return plaintext, nil
+}
+
+// newGCM derives an AES-256-GCM cipher from a passphrase and salt.
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving key: %w", err)
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
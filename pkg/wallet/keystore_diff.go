@@ -0,0 +1,266 @@
+package wallet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeKind classifies a single difference between two versions of the key file.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeRenamed ChangeKind = "renamed"
+	ChangeField   ChangeKind = "changed"
+	ChangeActive  ChangeKind = "active"
+	ChangeSetting ChangeKind = "setting"
+)
+
+// KeystoreChange is one difference between two versions of the key file. Secrets never appear in
+// From or To; a changed private key is only reported as changed.
+type KeystoreChange struct {
+	Kind  ChangeKind `json:"kind"`
+	Alias string     `json:"alias,omitempty"`
+	Field string     `json:"field,omitempty"`
+	From  string     `json:"from,omitempty"`
+	To    string     `json:"to,omitempty"`
+}
+
+func (c KeystoreChange) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("added wallet %s (%s)", c.Alias, c.To)
+	case ChangeRemoved:
+		return fmt.Sprintf("removed wallet %s (%s)", c.Alias, c.From)
+	case ChangeRenamed:
+		return fmt.Sprintf("renamed wallet %s to %s", c.From, c.To)
+	case ChangeActive:
+		return fmt.Sprintf("active wallet changed from %s to %s", orNone(c.From), orNone(c.To))
+	case ChangeSetting:
+		return fmt.Sprintf("%s changed from %s to %s", c.Field, orNone(c.From), orNone(c.To))
+	}
+
+	if c.Field == "key" {
+		return fmt.Sprintf("%s: private key changed", c.Alias)
+	}
+	return fmt.Sprintf("%s: %s changed from %s to %s", c.Alias, c.Field, orNone(c.From), orNone(c.To))
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// DiffWalletData lists what changed between two versions of the key file, in a stable order that does
+// not depend on map iteration. A wallet that disappears under one alias and reappears with the same
+// public key under another is reported as renamed, followed by any other field changes.
+func DiffWalletData(before, after WalletData) []KeystoreChange {
+	var changes []KeystoreChange
+
+	var removed, added []string
+	for alias := range before.Wallets {
+		if _, ok := after.Wallets[alias]; !ok {
+			removed = append(removed, alias)
+		}
+	}
+	for alias := range after.Wallets {
+		if _, ok := before.Wallets[alias]; !ok {
+			added = append(added, alias)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	renamedTo := make(map[string]string)
+	for _, oldAlias := range removed {
+		for _, newAlias := range added {
+			if _, taken := renamedTo[newAlias]; taken {
+				continue
+			}
+			if before.Wallets[oldAlias].PublicKey == after.Wallets[newAlias].PublicKey {
+				renamedTo[newAlias] = oldAlias
+				break
+			}
+		}
+	}
+	renamedFrom := make(map[string]bool, len(renamedTo))
+	for _, oldAlias := range renamedTo {
+		renamedFrom[oldAlias] = true
+	}
+
+	for _, alias := range removed {
+		if !renamedFrom[alias] {
+			changes = append(changes, KeystoreChange{Kind: ChangeRemoved, Alias: alias, From: before.Wallets[alias].PublicKey})
+		}
+	}
+
+	for _, alias := range added {
+		oldAlias, renamed := renamedTo[alias]
+		if !renamed {
+			changes = append(changes, KeystoreChange{Kind: ChangeAdded, Alias: alias, To: after.Wallets[alias].PublicKey})
+			continue
+		}
+		changes = append(changes, KeystoreChange{Kind: ChangeRenamed, Alias: alias, From: oldAlias, To: alias})
+		changes = append(changes, diffWallet(alias, before.Wallets[oldAlias], after.Wallets[alias])...)
+	}
+
+	var kept []string
+	for alias := range after.Wallets {
+		if _, ok := before.Wallets[alias]; ok {
+			kept = append(kept, alias)
+		}
+	}
+	sort.Strings(kept)
+	for _, alias := range kept {
+		changes = append(changes, diffWallet(alias, before.Wallets[alias], after.Wallets[alias])...)
+	}
+
+	if before.ActiveAlias != after.ActiveAlias {
+		changes = append(changes, KeystoreChange{Kind: ChangeActive, From: before.ActiveAlias, To: after.ActiveAlias})
+	}
+	if before.Network != after.Network {
+		changes = append(changes, KeystoreChange{Kind: ChangeSetting, Field: "network", From: before.Network, To: after.Network})
+	}
+	if before.RPCURL != after.RPCURL {
+		changes = append(changes, KeystoreChange{Kind: ChangeSetting, Field: "rpcUrl", From: before.RPCURL, To: after.RPCURL})
+	}
+
+	var contacts []string
+	for name := range before.Contacts {
+		contacts = append(contacts, name)
+	}
+	for name := range after.Contacts {
+		if _, ok := before.Contacts[name]; !ok {
+			contacts = append(contacts, name)
+		}
+	}
+	sort.Strings(contacts)
+	for _, name := range contacts {
+		if before.Contacts[name] != after.Contacts[name] {
+			changes = append(changes, KeystoreChange{Kind: ChangeSetting, Field: "contact " + name, From: before.Contacts[name], To: after.Contacts[name]})
+		}
+	}
+
+	var allowed []string
+	for address := range before.Allowlist {
+		allowed = append(allowed, address)
+	}
+	for address := range after.Allowlist {
+		if _, ok := before.Allowlist[address]; !ok {
+			allowed = append(allowed, address)
+		}
+	}
+	sort.Strings(allowed)
+	for _, address := range allowed {
+		from, to := describeAllowed(before.Allowlist, address), describeAllowed(after.Allowlist, address)
+		if from != to {
+			changes = append(changes, KeystoreChange{Kind: ChangeSetting, Field: "allowlist " + address, From: from, To: to})
+		}
+	}
+	if before.EnforceAllowlist != after.EnforceAllowlist {
+		changes = append(changes, KeystoreChange{Kind: ChangeSetting, Field: "enforceAllowlist", From: fmt.Sprint(before.EnforceAllowlist), To: fmt.Sprint(after.EnforceAllowlist)})
+	}
+
+	var multisigs []string
+	for name := range before.Multisigs {
+		multisigs = append(multisigs, name)
+	}
+	for name := range after.Multisigs {
+		if _, ok := before.Multisigs[name]; !ok {
+			multisigs = append(multisigs, name)
+		}
+	}
+	sort.Strings(multisigs)
+	for _, name := range multisigs {
+		from, to := describeMultisig(before.Multisigs, name), describeMultisig(after.Multisigs, name)
+		if from != to {
+			changes = append(changes, KeystoreChange{Kind: ChangeSetting, Field: "multisig " + name, From: from, To: to})
+		}
+	}
+
+	var groups []string
+	for name := range before.Groups {
+		groups = append(groups, name)
+	}
+	for name := range after.Groups {
+		if _, ok := before.Groups[name]; !ok {
+			groups = append(groups, name)
+		}
+	}
+	sort.Strings(groups)
+	for _, name := range groups {
+		from, to := strings.Join(before.Groups[name], ", "), strings.Join(after.Groups[name], ", ")
+		if from != to {
+			changes = append(changes, KeystoreChange{Kind: ChangeSetting, Field: "group " + name, From: from, To: to})
+		}
+	}
+
+	return changes
+}
+
+// describeAllowed describes the allow-list entry for address, or returns "" when there is none.
+func describeAllowed(allowlist map[string]string, address string) string {
+	contact, ok := allowlist[address]
+	switch {
+	case !ok:
+		return ""
+	case contact == "":
+		return "allowed"
+	default:
+		return "allowed as " + contact
+	}
+}
+
+// describeMultisig describes the multisig named name, or returns "" when there is none.
+func describeMultisig(multisigs map[string]Multisig, name string) string {
+	m, ok := multisigs[name]
+	if !ok {
+		return ""
+	}
+	return m.String() + " (" + strings.Join(m.Signers, ", ") + ")"
+}
+
+// diffWallet compares the fields of one wallet. The key, salt and nonce are compared but never reported.
+func diffWallet(alias string, before, after Wallet) []KeystoreChange {
+	var changes []KeystoreChange
+	field := func(name, from, to string) {
+		if from != to {
+			changes = append(changes, KeystoreChange{Kind: ChangeField, Alias: alias, Field: name, From: from, To: to})
+		}
+	}
+
+	field("publicKey", before.PublicKey, after.PublicKey)
+	if before.PrivateKey != after.PrivateKey || before.Salt != after.Salt || before.Nonce != after.Nonce {
+		changes = append(changes, KeystoreChange{Kind: ChangeField, Alias: alias, Field: "key"})
+	}
+	field("encrypted", fmt.Sprint(before.Encrypted), fmt.Sprint(after.Encrypted))
+	field("watchOnly", fmt.Sprint(before.WatchOnly), fmt.Sprint(after.WatchOnly))
+	field("keychain", fmt.Sprint(before.Keychain), fmt.Sprint(after.Keychain))
+	if !before.Balance.Equal(after.Balance) {
+		field("balance", before.Balance.String(), after.Balance.String())
+	}
+	field("derivation", derivationString(before.Derivation), derivationString(after.Derivation))
+	field("note", before.Note, after.Note)
+	field("icon", before.Icon, after.Icon)
+	field("usage", usageString(before.Usage), usageString(after.Usage))
+
+	return changes
+}
+
+func derivationString(d *KeyDerivation) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
+func usageString(u *KeyUsage) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
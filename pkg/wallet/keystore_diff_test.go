@@ -0,0 +1,188 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffWalletData(t *testing.T) {
+	index := uint32(1)
+	main := Wallet{PublicKey: "MainAddr", PrivateKey: "secret-1"}
+	savings := Wallet{PublicKey: "SavingsAddr", PrivateKey: "secret-2"}
+
+	tests := []struct {
+		name     string
+		before   WalletData
+		after    WalletData
+		expected []KeystoreChange
+	}{
+		{
+			name:   "No Change",
+			before: WalletData{ActiveAlias: "main", Wallets: map[string]Wallet{"main": main}},
+			after:  WalletData{ActiveAlias: "main", Wallets: map[string]Wallet{"main": main}},
+		},
+		{
+			name:   "Wallet Added To Empty File",
+			before: WalletData{},
+			after:  WalletData{ActiveAlias: "main", Wallets: map[string]Wallet{"main": main}},
+			expected: []KeystoreChange{
+				{Kind: ChangeAdded, Alias: "main", To: "MainAddr"},
+				{Kind: ChangeActive, To: "main"},
+			},
+		},
+		{
+			name:   "Wallet Removed",
+			before: WalletData{ActiveAlias: "main", Wallets: map[string]Wallet{"main": main, "savings": savings}},
+			after:  WalletData{ActiveAlias: "main", Wallets: map[string]Wallet{"main": main}},
+			expected: []KeystoreChange{
+				{Kind: ChangeRemoved, Alias: "savings", From: "SavingsAddr"},
+			},
+		},
+		{
+			name:   "Wallet Renamed",
+			before: WalletData{ActiveAlias: "main", Wallets: map[string]Wallet{"main": main}},
+			after:  WalletData{ActiveAlias: "primary", Wallets: map[string]Wallet{"primary": main}},
+			expected: []KeystoreChange{
+				{Kind: ChangeRenamed, Alias: "primary", From: "main", To: "primary"},
+				{Kind: ChangeActive, From: "main", To: "primary"},
+			},
+		},
+		{
+			name:   "Renamed With Field Change",
+			before: WalletData{Wallets: map[string]Wallet{"main": main}},
+			after:  WalletData{Wallets: map[string]Wallet{"primary": {PublicKey: "MainAddr", PrivateKey: "secret-1", Note: "daily"}}},
+			expected: []KeystoreChange{
+				{Kind: ChangeRenamed, Alias: "primary", From: "main", To: "primary"},
+				{Kind: ChangeField, Alias: "primary", Field: "note", To: "daily"},
+			},
+		},
+		{
+			name:   "Active Wallet Switched",
+			before: WalletData{ActiveAlias: "main", Wallets: map[string]Wallet{"main": main, "savings": savings}},
+			after:  WalletData{ActiveAlias: "savings", Wallets: map[string]Wallet{"main": main, "savings": savings}},
+			expected: []KeystoreChange{
+				{Kind: ChangeActive, From: "main", To: "savings"},
+			},
+		},
+		{
+			name:   "Key Encrypted Without Revealing Secrets",
+			before: WalletData{Wallets: map[string]Wallet{"main": main}},
+			after:  WalletData{Wallets: map[string]Wallet{"main": {PublicKey: "MainAddr", PrivateKey: "ciphertext", Salt: "salt", Nonce: "nonce", Encrypted: true}}},
+			expected: []KeystoreChange{
+				{Kind: ChangeField, Alias: "main", Field: "key"},
+				{Kind: ChangeField, Alias: "main", Field: "encrypted", From: "false", To: "true"},
+			},
+		},
+		{
+			name:   "Only Salt Changed Counts As Key Change",
+			before: WalletData{Wallets: map[string]Wallet{"main": {PublicKey: "MainAddr", Salt: "a"}}},
+			after:  WalletData{Wallets: map[string]Wallet{"main": {PublicKey: "MainAddr", Salt: "b"}}},
+			expected: []KeystoreChange{
+				{Kind: ChangeField, Alias: "main", Field: "key"},
+			},
+		},
+		{
+			name:   "Balance Derivation Note And Icon",
+			before: WalletData{Wallets: map[string]Wallet{"main": {PublicKey: "MainAddr", Balance: decimal.NewFromFloat(1.5)}}},
+			after: WalletData{Wallets: map[string]Wallet{"main": {
+				PublicKey:  "MainAddr",
+				Balance:    decimal.NewFromInt(2),
+				Derivation: &KeyDerivation{Path: "m/44'/501'/1'/0'", AccountIndex: &index},
+				Note:       "escrow",
+				Icon:       "🏦",
+			}}},
+			expected: []KeystoreChange{
+				{Kind: ChangeField, Alias: "main", Field: "balance", From: "1.5", To: "2"},
+				{Kind: ChangeField, Alias: "main", Field: "derivation", To: KeyDerivation{Path: "m/44'/501'/1'/0'", AccountIndex: &index}.String()},
+				{Kind: ChangeField, Alias: "main", Field: "note", To: "escrow"},
+				{Kind: ChangeField, Alias: "main", Field: "icon", To: "🏦"},
+			},
+		},
+		{
+			name:   "Equal Balances With Different Scale",
+			before: WalletData{Wallets: map[string]Wallet{"main": {PublicKey: "MainAddr", Balance: decimal.RequireFromString("1.50")}}},
+			after:  WalletData{Wallets: map[string]Wallet{"main": {PublicKey: "MainAddr", Balance: decimal.RequireFromString("1.5")}}},
+		},
+		{
+			name:   "Network Settings",
+			before: WalletData{Network: "devnet"},
+			after:  WalletData{Network: "custom", RPCURL: "http://localhost:8899"},
+			expected: []KeystoreChange{
+				{Kind: ChangeSetting, Field: "network", From: "devnet", To: "custom"},
+				{Kind: ChangeSetting, Field: "rpcUrl", To: "http://localhost:8899"},
+			},
+		},
+		{
+			name:   "Same Public Key Under Two New Aliases Is Renamed Once",
+			before: WalletData{Wallets: map[string]Wallet{"main": main}},
+			after:  WalletData{Wallets: map[string]Wallet{"a": main, "b": main}},
+			expected: []KeystoreChange{
+				{Kind: ChangeRenamed, Alias: "a", From: "main", To: "a"},
+				{Kind: ChangeAdded, Alias: "b", To: "MainAddr"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DiffWalletData(tt.before, tt.after))
+		})
+	}
+}
+
+func TestDiffWalletDataIsOrderInsensitive(t *testing.T) {
+	before := WalletData{Wallets: map[string]Wallet{}}
+	after := WalletData{Wallets: map[string]Wallet{}}
+	for _, alias := range []string{"delta", "alpha", "echo", "charlie", "bravo"} {
+		before.Wallets[alias+"-old"] = Wallet{PublicKey: "removed-" + alias}
+		after.Wallets[alias] = Wallet{PublicKey: "added-" + alias}
+	}
+
+	first := DiffWalletData(before, after)
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, first, DiffWalletData(before, after))
+	}
+	assert.Equal(t, "alpha-old", first[0].Alias)
+	assert.Equal(t, "alpha", first[5].Alias)
+}
+
+func TestKeystoreChangeNeverPrintsSecrets(t *testing.T) {
+	changes := DiffWalletData(
+		WalletData{Wallets: map[string]Wallet{"main": {PublicKey: "MainAddr", PrivateKey: "old-secret"}}},
+		WalletData{Wallets: map[string]Wallet{"main": {PublicKey: "MainAddr", PrivateKey: "new-secret"}}},
+	)
+
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "main: private key changed", changes[0].String())
+	assert.NotContains(t, changes[0].String(), "secret")
+}
+
+func TestKeyOpsMutationsAreAudited(t *testing.T) {
+	files := newMemFiles()
+	var reported []KeystoreChange
+	ops := &KeyOps{
+		FileReader: files,
+		FileWriter: files,
+		Audit:      &AuditLog{FileReader: files, FileWriter: files},
+		OnChange: func(action string, changes []KeystoreChange) {
+			reported = append(reported, changes...)
+		},
+	}
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		ActiveAlias: "main",
+		Wallets:     map[string]Wallet{"main": {PublicKey: "MainAddr"}, "savings": {PublicKey: "SavingsAddr"}},
+	})))
+
+	assert.NoError(t, ops.SetActiveKey("savings"))
+	// Switching to the already active wallet changes nothing and is not logged.
+	assert.NoError(t, ops.SetActiveKey("savings"))
+
+	entries, err := ops.Audit.Entries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "switch", entries[0].Action)
+	assert.Equal(t, []KeystoreChange{{Kind: ChangeActive, From: "main", To: "savings"}}, entries[0].Changes)
+	assert.Equal(t, entries[0].Changes, reported)
+}
@@ -1,55 +1,118 @@
 package wallet
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"github.com/shopspring/decimal"
-	"io/ioutil"
+	"fmt"
+	"io"
 	"net/http"
+
+	"github.com/shopspring/decimal"
 )
 
+// krakenTickerURL is the Kraken ticker endpoint; the pair is appended.
+const krakenTickerURL = "https://api.kraken.com/0/public/Ticker?pair="
+
 // KrakenResponse is the response from Kraken API
 type KrakenResponse struct {
-	Error  []string `json:"error"`
-	Result struct {
-		SOLEUR struct {
-			A []string `json:"a"`
-			B []string `json:"b"`
-			C []string `json:"c"`
-			V []string `json:"v"`
-			P []string `json:"p"`
-			T []int    `json:"t"`
-			L []string `json:"l"`
-			H []string `json:"h"`
-			O string   `json:"o"`
-		} `json:"SOLEUR"`
-	} `json:"result"`
+	Error  []string                `json:"error"`
+	Result map[string]KrakenTicker `json:"result"`
+}
+
+// KrakenTicker is the ticker of one pair, keyed by pair name in KrakenResponse.
+type KrakenTicker struct {
+	A []string `json:"a"`
+	B []string `json:"b"`
+	C []string `json:"c"`
+	V []string `json:"v"`
+	P []string `json:"p"`
+	T []int    `json:"t"`
+	L []string `json:"l"`
+	H []string `json:"h"`
+	O string   `json:"o"`
+}
+
+// KrakenRates is the Kraken ticker as a RateProvider, quoting the 24h volume weighted average price.
+type KrakenRates struct {
+	// Client defaults to a client with a timeout.
+	Client *http.Client
+	// URL is the ticker endpoint the pair is appended to; it defaults to Kraken's.
+	URL string
 }
 
-// fetchSOLEURRate fetches the current SOLEUR rate from Kraken API
-func fetchSOLEURRate() (decimal.Decimal, error) {
-	resp, err := http.Get("https://api.kraken.com/0/public/Ticker?pair=SOLEUR")
+func (k *KrakenRates) Name() string {
+	return "Kraken"
+}
+
+// SOLRate fetches the current rate of SOL in the given currency from Kraken API
+func (k *KrakenRates) SOLRate(ctx context.Context, currency Currency) (decimal.Decimal, error) {
+	url := k.URL
+	if url == "" {
+		url = krakenTickerURL
+	}
+
+	if currency.Pair() == "" {
+		return decimal.Zero, fmt.Errorf("no SOL pair in %s", currency)
+	}
+
+	body, err := getRateAPI(ctx, k.Client, url+currency.Pair())
 	if err != nil {
 		return decimal.NewFromFloat(0), err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	return parseKrakenTicker(body, currency.Pair())
+}
+
+// getRateAPI performs a GET request against a price API and returns the body of a successful response.
+func getRateAPI(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	if client == nil {
+		client = rateHTTPClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return decimal.NewFromFloat(0), err
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("responded with %s", resp.Status)
 	}
 
+	return io.ReadAll(resp.Body)
+}
+
+// parseKrakenTicker returns the 24h volume weighted average price of pair from a ticker response.
+func parseKrakenTicker(body []byte, pair string) (decimal.Decimal, error) {
 	var krakenResponse KrakenResponse
-	err = json.Unmarshal(body, &krakenResponse)
+	err := json.Unmarshal(body, &krakenResponse)
 	if err != nil {
 		return decimal.NewFromFloat(0), err
 	}
 
-	if len(krakenResponse.Result.SOLEUR.P) < 2 {
+	if len(krakenResponse.Error) > 0 {
+		return decimal.NewFromFloat(0), fmt.Errorf("kraken error for %s: %v", pair, krakenResponse.Error)
+	}
+
+	ticker, ok := krakenResponse.Result[pair]
+	if !ok && len(krakenResponse.Result) == 1 {
+		// Kraken sometimes answers with its own pair name, e.g. XSOLZEUR.
+		for _, only := range krakenResponse.Result {
+			ticker = only
+		}
+	}
+
+	if len(ticker.P) < 2 {
 		return decimal.NewFromFloat(0), errors.New("unexpected data structure from API")
 	}
 
-	rateStr := krakenResponse.Result.SOLEUR.P[1]
+	rateStr := ticker.P[1]
 	rate, err := decimal.NewFromString(rateStr)
 	if err != nil {
 		return decimal.NewFromFloat(0), err
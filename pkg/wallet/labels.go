@@ -0,0 +1,236 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// LabelsFilePath is the file transaction labels are kept in, in the profile directory.
+const LabelsFilePath = "sleeng.labels.json"
+
+var (
+	ErrUnknownTransaction = errors.New("transaction not found on the cluster")
+	ErrNoLabel            = errors.New("transaction has no label")
+)
+
+// TransactionLabel is a bookkeeping note on a transaction, shown in the history and its exports.
+type TransactionLabel struct {
+	Signature string `json:"signature"`
+	Text      string `json:"text"`
+	// Category groups labels for filtering, such as "rent" or "refund". Empty for none.
+	Category  string    `json:"category,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// String shows the label with its category in brackets.
+func (l TransactionLabel) String() string {
+	if l.Category == "" {
+		return l.Text
+	}
+	return fmt.Sprintf("%s [%s]", l.Text, l.Category)
+}
+
+// labelsSchema is the schema of the labels file.
+var labelsSchema = registerStoreSchema(storeSchema{
+	Kind:       StoreLabels,
+	Version:    1,
+	Migrations: map[int]storeMigration{0: bareStorePayload},
+})
+
+// labelRecords is the payload of the labels file: the labels by signature.
+type labelRecords map[string]TransactionLabel
+
+func (l labelRecords) validate() error {
+	for signature, label := range l {
+		if label.Signature != signature {
+			return fmt.Errorf("the label of %q is stored under %q", label.Signature, signature)
+		}
+		if label.Text == "" {
+			return fmt.Errorf("the label of %s has no text", signature)
+		}
+	}
+	return nil
+}
+
+// LabelStore keeps the transaction labels of a profile, keyed by signature.
+type LabelStore struct {
+	FileReader FileReader
+	FileWriter FileWriter
+	// Dir is the profile directory holding the store. Empty means the current directory.
+	Dir string
+}
+
+func (s *LabelStore) filePath() string {
+	return filepath.Join(s.Dir, LabelsFilePath)
+}
+
+// read returns the labels by signature, or none when the file does not exist.
+func (s *LabelStore) read() (labelRecords, error) {
+	labels := make(labelRecords)
+	if err := readStore(s.FileReader, s.filePath(), labelsSchema, &labels); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return labels, nil
+		}
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (s *LabelStore) write(labels labelRecords) error {
+	return writeStore(s.FileReader, s.FileWriter, s.filePath(), labelsSchema, labels)
+}
+
+// Put adds or replaces the label of a transaction.
+func (s *LabelStore) Put(label TransactionLabel) error {
+	labels, err := s.read()
+	if err != nil {
+		return err
+	}
+	labels[label.Signature] = label
+	return s.write(labels)
+}
+
+// Delete removes the label of the transaction with signature.
+func (s *LabelStore) Delete(signature string) (TransactionLabel, error) {
+	labels, err := s.read()
+	if err != nil {
+		return TransactionLabel{}, err
+	}
+	label, ok := labels[signature]
+	if !ok {
+		return TransactionLabel{}, fmt.Errorf("%w: %s", ErrNoLabel, signature)
+	}
+	delete(labels, signature)
+	return label, s.write(labels)
+}
+
+// All returns the labels by signature. A nil store has none.
+func (s *LabelStore) All() (map[string]TransactionLabel, error) {
+	if s == nil {
+		return map[string]TransactionLabel{}, nil
+	}
+	return s.read()
+}
+
+// LabelOptions describes a label to put on a transaction.
+type LabelOptions struct {
+	Text string
+	// Category replaces the category of the label; empty keeps the one it had.
+	Category string
+	// Unchecked labels a signature without looking it up on the cluster, for transactions of another
+	// network or when offline.
+	Unchecked bool
+}
+
+// LabelTransaction labels the transaction with signature, replacing any label it had. Unless
+// opts.Unchecked is set the signature must be known to the cluster, so a mistyped signature is not
+// labeled silently.
+func (w *WalletConfig) LabelTransaction(ctx context.Context, signature string, opts LabelOptions) (TransactionLabel, error) {
+	if w.Labels == nil {
+		return TransactionLabel{}, errors.New("transaction labels are not available")
+	}
+	sig, err := solana.SignatureFromBase58(signature)
+	if err != nil {
+		return TransactionLabel{}, fmt.Errorf("invalid signature %q: %w", signature, err)
+	}
+	text := strings.TrimSpace(opts.Text)
+	if text == "" {
+		return TransactionLabel{}, errors.New("the label needs a text")
+	}
+
+	if !opts.Unchecked {
+		endpoints, err := w.Endpoints()
+		if err != nil {
+			return TransactionLabel{}, err
+		}
+		status, err := fetchSignatureStatus(ctx, endpoints.client(), sig, true)
+		if err != nil {
+			return TransactionLabel{}, err
+		}
+		if status == nil {
+			return TransactionLabel{}, fmt.Errorf("%w: %s", ErrUnknownTransaction, signature)
+		}
+	}
+
+	labels, err := w.Labels.read()
+	if err != nil {
+		return TransactionLabel{}, err
+	}
+	label := TransactionLabel{
+		Signature: sig.String(),
+		Text:      text,
+		Category:  strings.TrimSpace(opts.Category),
+		UpdatedAt: time.Now().UTC(),
+	}
+	if label.Category == "" {
+		label.Category = labels[label.Signature].Category
+	}
+	labels[label.Signature] = label
+	return label, w.Labels.write(labels)
+}
+
+// UnlabelTransaction removes the label of the transaction with signature.
+func (w *WalletConfig) UnlabelTransaction(signature string) (TransactionLabel, error) {
+	if w.Labels == nil {
+		return TransactionLabel{}, fmt.Errorf("%w: %s", ErrNoLabel, signature)
+	}
+	return w.Labels.Delete(signature)
+}
+
+// TransactionLabels returns the labels by signature.
+func (w *WalletConfig) TransactionLabels() (map[string]TransactionLabel, error) {
+	return w.Labels.All()
+}
+
+// ListLabels returns the labels in category, or all labels when category is empty, sorted by
+// category and then by signature.
+func (w *WalletConfig) ListLabels(category string) ([]TransactionLabel, error) {
+	labels, err := w.Labels.All()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]TransactionLabel, 0, len(labels))
+	for _, label := range labels {
+		if category == "" || strings.EqualFold(label.Category, category) {
+			list = append(list, label)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Category != list[j].Category {
+			return list[i].Category < list[j].Category
+		}
+		return list[i].Signature < list[j].Signature
+	})
+	return list, nil
+}
+
+// FilterByCategory returns the transactions whose label is in category, compared case-insensitively.
+func FilterByCategory(transactions []*Transaction, labels map[string]TransactionLabel, category string) []*Transaction {
+	filtered := make([]*Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if label, ok := labels[tx.Signature.String()]; ok && strings.EqualFold(label.Category, category) {
+			filtered = append(filtered, tx)
+		}
+	}
+	return filtered
+}
+
+// LabelHistoryRows fills in the label and category of rows whose transaction is labeled.
+func LabelHistoryRows(rows []HistoryRow, labels map[string]TransactionLabel) []HistoryRow {
+	for i := range rows {
+		if label, ok := labels[rows[i].Signature]; ok {
+			rows[i].Label = label.Text
+			rows[i].Category = label.Category
+		}
+	}
+	return rows
+}
@@ -0,0 +1,139 @@
+package wallet
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// labelCluster knows the signatures in landed and no others.
+func labelCluster(landed ...solana.Signature) *MockClientInterface {
+	return &MockClientInterface{
+		GetSignatureStatusesFn: func(_ context.Context, _ bool, sigs ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+			for _, sig := range landed {
+				if sig == sigs[0] {
+					return signatureStatus(true), nil
+				}
+			}
+			return signatureStatus(false), nil
+		},
+	}
+}
+
+func TestLabelTransaction(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	known := solana.Signature{1, 2, 3}
+	unknown := solana.Signature{4, 5, 6}
+	newRPCClient = func(string) ClientInterface { return labelCluster(known) }
+
+	files := newMemFiles()
+	wc := &WalletConfig{Network: Devnet, Labels: &LabelStore{FileReader: files, FileWriter: files}}
+	ctx := context.Background()
+
+	_, err := wc.LabelTransaction(ctx, unknown.String(), LabelOptions{Text: "refund"})
+	assert.ErrorIs(t, err, ErrUnknownTransaction)
+	_, err = wc.LabelTransaction(ctx, "not-a-signature", LabelOptions{Text: "refund", Unchecked: true})
+	assert.Error(t, err)
+	_, err = wc.LabelTransaction(ctx, known.String(), LabelOptions{Text: "  "})
+	assert.Error(t, err)
+	assert.Empty(t, files.files, "refused labels must not be stored")
+
+	// A signature of another network can be labeled without looking it up.
+	label, err := wc.LabelTransaction(ctx, unknown.String(), LabelOptions{Text: "refund", Unchecked: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "refund", label.String())
+
+	label, err = wc.LabelTransaction(ctx, known.String(), LabelOptions{Text: "office rent", Category: "rent"})
+	assert.NoError(t, err)
+	assert.Equal(t, "office rent [rent]", label.String())
+
+	// Relabeling replaces the text and keeps the category unless a new one is given.
+	label, err = wc.LabelTransaction(ctx, known.String(), LabelOptions{Text: "office rent, May"})
+	assert.NoError(t, err)
+	assert.Equal(t, "rent", label.Category)
+	label, err = wc.LabelTransaction(ctx, known.String(), LabelOptions{Text: "office rent, May", Category: "Housing"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Housing", label.Category)
+
+	labels, err := wc.ListLabels("housing")
+	assert.NoError(t, err)
+	assert.Equal(t, []TransactionLabel{label}, labels)
+	labels, err = wc.ListLabels("")
+	assert.NoError(t, err)
+	assert.Len(t, labels, 2)
+
+	removed, err := wc.UnlabelTransaction(unknown.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "refund", removed.Text)
+	_, err = wc.UnlabelTransaction(unknown.String())
+	assert.ErrorIs(t, err, ErrNoLabel)
+
+	// Labels are kept in a versioned store file.
+	var envelope storeEnvelope
+	assert.NoError(t, json.Unmarshal(files.files[LabelsFilePath], &envelope))
+	assert.Equal(t, StoreLabels, envelope.Kind)
+	assert.Equal(t, 1, envelope.Version)
+}
+
+func TestFilterByCategory(t *testing.T) {
+	rent, refund, none := solana.Signature{1}, solana.Signature{2}, solana.Signature{3}
+	transactions := []*Transaction{{Signature: rent}, {Signature: refund}, {Signature: none}, {Signature: rent}}
+	labels := map[string]TransactionLabel{
+		rent.String():   {Signature: rent.String(), Text: "office", Category: "Rent"},
+		refund.String(): {Signature: refund.String(), Text: "returned chair", Category: "refund"},
+	}
+
+	filtered := FilterByCategory(transactions, labels, "rent")
+	assert.Equal(t, []*Transaction{transactions[0], transactions[3]}, filtered)
+	assert.Empty(t, FilterByCategory(transactions, labels, "salary"))
+}
+
+func TestExportHistoryIncludesLabels(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	defer func(policy RetryPolicy) { DefaultRetryPolicy = policy }(DefaultRetryPolicy)
+	DefaultRetryPolicy = fastRetries
+
+	owner := solana.NewWallet()
+	newRPCClient = func(string) ClientInterface { return exportHistoryClient(t, owner.PublicKey(), 3, nil) }
+
+	files := newMemFiles()
+	wc := &WalletConfig{Wallet: owner, Network: Devnet, Labels: &LabelStore{FileReader: files, FileWriter: files}}
+	rent := solana.Signature{2, 9}
+	assert.NoError(t, wc.Labels.Put(TransactionLabel{Signature: rent.String(), Text: "office rent", Category: "rent"}))
+
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		category string
+		rows     int
+	}{{rows: 3}, {category: "rent", rows: 1}} {
+		path := filepath.Join(dir, "export-"+tt.category+".csv")
+		result, err := wc.ExportHistory(context.Background(), HistoryExportOpts{Path: path, Format: HistoryCSV, Category: tt.category})
+		assert.NoError(t, err)
+		assert.Equal(t, tt.rows, result.Rows)
+
+		file, err := os.Open(path)
+		assert.NoError(t, err)
+		records, err := csv.NewReader(file).ReadAll()
+		file.Close()
+		assert.NoError(t, err)
+		assert.Len(t, records, tt.rows+1)
+
+		for _, record := range records[1:] {
+			label, category := record[len(record)-3], record[len(record)-2]
+			if record[1] == rent.String() {
+				assert.Equal(t, []string{"office rent", "rent"}, []string{label, category})
+			} else {
+				assert.Equal(t, []string{"", ""}, []string{label, category})
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// confirmPollInterval is how often the status of a sent transfer is polled while it confirms. It
+// bounds how precisely the latency of each commitment level is measured.
+var confirmPollInterval = 500 * time.Millisecond
+
+// confirmTimeout is how long a sent transfer is awaited before it is left pending.
+var confirmTimeout = 2 * time.Minute
+
+var ErrConfirmTimeout = errors.New("timed out waiting for the transaction to finalize")
+
+// ConfirmationLatency is how long a transfer took after it was submitted to reach each commitment
+// level. A zero duration means the level was never observed, for example because waiting timed out.
+type ConfirmationLatency struct {
+	Processed time.Duration `json:"processed,omitempty"`
+	Confirmed time.Duration `json:"confirmed,omitempty"`
+	Finalized time.Duration `json:"finalized,omitempty"`
+}
+
+// latencyRecorder measures ConfirmationLatency from the moment it is created.
+type latencyRecorder struct {
+	now       func() time.Time
+	submitted time.Time
+	latency   ConfirmationLatency
+}
+
+func newLatencyRecorder(now func() time.Time) *latencyRecorder {
+	return &latencyRecorder{now: now, submitted: now()}
+}
+
+// observe records the first time a transfer is seen at a commitment level. Reaching a level implies
+// the ones below it, so levels skipped between two polls get the same time.
+func (r *latencyRecorder) observe(status rpc.ConfirmationStatusType) {
+	elapsed := r.now().Sub(r.submitted)
+
+	levels := []*time.Duration{&r.latency.Processed, &r.latency.Confirmed, &r.latency.Finalized}
+	reached := 0
+	switch status {
+	case rpc.ConfirmationStatusProcessed:
+		reached = 1
+	case rpc.ConfirmationStatusConfirmed:
+		reached = 2
+	case rpc.ConfirmationStatusFinalized:
+		reached = 3
+	}
+
+	for _, level := range levels[:reached] {
+		if *level == 0 {
+			*level = elapsed
+		}
+	}
+}
+
+// awaitCommitments polls the status of sig until it is finalized, calling observe with every status
+// seen on the way. It fails when the transaction fails on chain or ctx is done first. With a
+// lastValidBlockHeight it also fails with ErrBlockhashExpired once the chain has passed that height
+// without the transaction landing; 0 disables the check, as for durable nonce transactions.
+func awaitCommitments(ctx context.Context, client ClientInterface, sig solana.Signature, lastValidBlockHeight uint64, observe func(rpc.ConfirmationStatusType)) error {
+	for {
+		status, err := fetchSignatureStatus(ctx, client, sig, false)
+		if err != nil {
+			return err
+		}
+
+		if status != nil {
+			if status.Err != nil {
+				return fmt.Errorf("confirmed transaction with execution error: %v", status.Err)
+			}
+			observe(status.ConfirmationStatus)
+			if status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+				return nil
+			}
+		} else if lastValidBlockHeight > 0 {
+			expired, err := blockhashExpired(ctx, client, sig, lastValidBlockHeight)
+			if err != nil {
+				return err
+			}
+			if expired {
+				return ErrBlockhashExpired
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrConfirmTimeout
+			}
+			return ctx.Err()
+		case <-time.After(confirmPollInterval):
+		}
+	}
+}
+
+// LatencyPercentiles summarises the latency of one commitment level over a number of sends.
+type LatencyPercentiles struct {
+	Samples int           `json:"samples"`
+	P50     time.Duration `json:"p50"`
+	P90     time.Duration `json:"p90"`
+}
+
+// LatencyStats summarises the confirmation latency of the sends on one network in one priority-fee bucket.
+type LatencyStats struct {
+	Network   string             `json:"network"`
+	FeeBucket string             `json:"feeBucket"`
+	Sends     int                `json:"sends"`
+	Processed LatencyPercentiles `json:"processed"`
+	Confirmed LatencyPercentiles `json:"confirmed"`
+	Finalized LatencyPercentiles `json:"finalized"`
+}
+
+// priorityFeeBuckets group compute-unit prices in micro-lamports; a price falls in the first bucket
+// whose bound is above it.
+var priorityFeeBuckets = []struct {
+	below uint64
+	name  string
+}{
+	{below: 1, name: "none"},
+	{below: 1_000, name: "<1k"},
+	{below: 10_000, name: "1k-10k"},
+	{below: 100_000, name: "10k-100k"},
+}
+
+// PriorityFeeBucket names the range a compute-unit price in micro-lamports falls in.
+func PriorityFeeBucket(microLamports uint64) string {
+	for _, bucket := range priorityFeeBuckets {
+		if microLamports < bucket.below {
+			return bucket.name
+		}
+	}
+	return "100k+"
+}
+
+// SummarizeLatency computes p50 and p90 confirmation latencies over the recent most recent sends
+// that were measured, grouped by network and priority-fee bucket. A recent of 0 uses all of them.
+// The result is sorted by network, then by bucket.
+func SummarizeLatency(records []PendingTransaction, recent int) []LatencyStats {
+	measured := make([]PendingTransaction, 0, len(records))
+	for _, record := range records {
+		if record.Latency != nil {
+			measured = append(measured, record)
+		}
+	}
+
+	sort.SliceStable(measured, func(i, j int) bool {
+		return measured[i].CreatedAt.After(measured[j].CreatedAt)
+	})
+	if recent > 0 && len(measured) > recent {
+		measured = measured[:recent]
+	}
+
+	type key struct{ network, bucket string }
+	type samples struct {
+		sends                           int
+		processed, confirmed, finalized []time.Duration
+	}
+	groups := make(map[key]*samples)
+	var keys []key
+	for _, record := range measured {
+		k := key{network: record.Network, bucket: PriorityFeeBucket(record.PriorityFee)}
+		group, ok := groups[k]
+		if !ok {
+			group = &samples{}
+			groups[k] = group
+			keys = append(keys, k)
+		}
+		group.sends++
+		group.processed = appendObserved(group.processed, record.Latency.Processed)
+		group.confirmed = appendObserved(group.confirmed, record.Latency.Confirmed)
+		group.finalized = appendObserved(group.finalized, record.Latency.Finalized)
+	}
+
+	bucketOrder := func(name string) int {
+		for i, bucket := range priorityFeeBuckets {
+			if bucket.name == name {
+				return i
+			}
+		}
+		return len(priorityFeeBuckets)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].network != keys[j].network {
+			return keys[i].network < keys[j].network
+		}
+		return bucketOrder(keys[i].bucket) < bucketOrder(keys[j].bucket)
+	})
+
+	stats := make([]LatencyStats, 0, len(keys))
+	for _, k := range keys {
+		group := groups[k]
+		stats = append(stats, LatencyStats{
+			Network:   k.network,
+			FeeBucket: k.bucket,
+			Sends:     group.sends,
+			Processed: percentiles(group.processed),
+			Confirmed: percentiles(group.confirmed),
+			Finalized: percentiles(group.finalized),
+		})
+	}
+	return stats
+}
+
+// appendObserved adds a latency to samples unless the level was never observed.
+func appendObserved(samples []time.Duration, latency time.Duration) []time.Duration {
+	if latency <= 0 {
+		return samples
+	}
+	return append(samples, latency)
+}
+
+// percentiles returns the nearest-rank p50 and p90 of samples.
+func percentiles(samples []time.Duration) LatencyPercentiles {
+	if len(samples) == 0 {
+		return LatencyPercentiles{}
+	}
+
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := func(p int) time.Duration {
+		// Nearest rank: the smallest sample with at least p percent of the samples at or below it.
+		index := (p*len(sorted)+99)/100 - 1
+		return sorted[index]
+	}
+
+	return LatencyPercentiles{Samples: len(sorted), P50: rank(50), P90: rank(90)}
+}
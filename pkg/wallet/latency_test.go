@@ -0,0 +1,193 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// statusSequence answers successive status lookups with the given statuses; an empty status means
+// the cluster has not seen the transaction yet. The last status repeats.
+func statusSequence(statuses ...rpc.ConfirmationStatusType) func(context.Context, bool, ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+	calls := 0
+	return func(context.Context, bool, ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+		status := statuses[len(statuses)-1]
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+
+		if status == "" {
+			return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{nil}}, nil
+		}
+		return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{{ConfirmationStatus: status}}}, nil
+	}
+}
+
+func TestAwaitCommitmentsMeasuresLatency(t *testing.T) {
+	defer func(interval time.Duration) { confirmPollInterval = interval }(confirmPollInterval)
+	confirmPollInterval = time.Millisecond
+
+	tests := []struct {
+		name      string
+		statuses  []rpc.ConfirmationStatusType
+		cancelled bool
+		expected  ConfirmationLatency
+	}{
+		{
+			name:     "Every Level Observed",
+			statuses: []rpc.ConfirmationStatusType{"", rpc.ConfirmationStatusProcessed, rpc.ConfirmationStatusConfirmed, rpc.ConfirmationStatusConfirmed, rpc.ConfirmationStatusFinalized},
+			expected: ConfirmationLatency{Processed: 200 * time.Millisecond, Confirmed: 300 * time.Millisecond, Finalized: 500 * time.Millisecond},
+		},
+		{
+			name:     "Levels Skipped Between Polls",
+			statuses: []rpc.ConfirmationStatusType{"", "", rpc.ConfirmationStatusFinalized},
+			expected: ConfirmationLatency{Processed: 300 * time.Millisecond, Confirmed: 300 * time.Millisecond, Finalized: 300 * time.Millisecond},
+		},
+		{
+			name:      "Partial Measurement Kept When Waiting Stops",
+			statuses:  []rpc.ConfirmationStatusType{rpc.ConfirmationStatusProcessed, rpc.ConfirmationStatusConfirmed},
+			cancelled: true,
+			expected:  ConfirmationLatency{Processed: 100 * time.Millisecond, Confirmed: 200 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// Every poll takes 100ms on the fake clock.
+			now := time.Unix(0, 0)
+			recorder := newLatencyRecorder(func() time.Time { return now })
+
+			lookup := statusSequence(tt.statuses...)
+			calls := 0
+			client := &MockClientInterface{GetSignatureStatusesFn: func(ctx context.Context, search bool, sigs ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+				calls++
+				now = now.Add(100 * time.Millisecond)
+				if tt.cancelled && calls == len(tt.statuses) {
+					cancel()
+				}
+				return lookup(ctx, search, sigs...)
+			}}
+
+			err := awaitCommitments(ctx, client, solana.Signature{1}, 0, recorder.observe)
+			if tt.cancelled {
+				assert.ErrorIs(t, err, context.Canceled)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, recorder.latency)
+		})
+	}
+}
+
+func TestAwaitSendPersistsLatency(t *testing.T) {
+	defer func(interval time.Duration) { confirmPollInterval = interval }(confirmPollInterval)
+	confirmPollInterval = time.Millisecond
+
+	files := newMemFiles()
+	wc := &WalletConfig{Pending: &PendingStore{FileReader: files, FileWriter: files}}
+	pending := PendingTransaction{ID: "sig12345", Network: "devnet", Status: PendingSubmitted}
+	assert.NoError(t, wc.Pending.Put(pending))
+
+	client := &MockClientInterface{GetSignatureStatusesFn: statusSequence(rpc.ConfirmationStatusConfirmed, rpc.ConfirmationStatusFinalized)}
+	assert.NoError(t, wc.awaitSend(context.Background(), client, solana.Signature{1}, pending, 0))
+
+	got, err := wc.Pending.Get("sig12345")
+	assert.NoError(t, err)
+	assert.Equal(t, PendingConfirmed, got.Status)
+	if assert.NotNil(t, got.Latency) {
+		assert.Positive(t, got.Latency.Processed)
+		assert.LessOrEqual(t, got.Latency.Confirmed, got.Latency.Finalized)
+	}
+
+	failing := &MockClientInterface{GetSignatureStatusesFn: func(context.Context, bool, ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+		return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{{Err: "InsufficientFundsForRent"}}}, nil
+	}}
+	assert.Error(t, wc.awaitSend(context.Background(), failing, solana.Signature{2}, PendingTransaction{ID: "sig67890", Status: PendingSubmitted}, 0))
+
+	got, err = wc.Pending.Get("sig67890")
+	assert.NoError(t, err)
+	assert.Equal(t, PendingSubmitted, got.Status)
+}
+
+func TestSummarizeLatency(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	record := func(minute int, network string, fee uint64, confirmedMs, finalizedMs int) PendingTransaction {
+		return PendingTransaction{
+			Network:     network,
+			PriorityFee: fee,
+			CreatedAt:   start.Add(time.Duration(minute) * time.Minute),
+			Latency: &ConfirmationLatency{
+				Processed: time.Duration(confirmedMs/2) * time.Millisecond,
+				Confirmed: time.Duration(confirmedMs) * time.Millisecond,
+				Finalized: time.Duration(finalizedMs) * time.Millisecond,
+			},
+		}
+	}
+
+	var records []PendingTransaction
+	for i := 1; i <= 10; i++ {
+		records = append(records, record(i, "devnet", 0, i*100, i*1000))
+	}
+	records = append(records,
+		record(11, "mainnet-beta", 5_000, 800, 12_000),
+		record(12, "mainnet-beta", 5_000, 400, 13_000),
+		record(13, "devnet", 200_000, 300, 0),                   // timed out before finalizing
+		PendingTransaction{Network: "devnet", CreatedAt: start}, // sent before latency was measured
+	)
+
+	stats := SummarizeLatency(records, 0)
+	assert.Equal(t, []LatencyStats{
+		{
+			Network: "devnet", FeeBucket: "none", Sends: 10,
+			Processed: LatencyPercentiles{Samples: 10, P50: 250 * time.Millisecond, P90: 450 * time.Millisecond},
+			Confirmed: LatencyPercentiles{Samples: 10, P50: 500 * time.Millisecond, P90: 900 * time.Millisecond},
+			Finalized: LatencyPercentiles{Samples: 10, P50: 5 * time.Second, P90: 9 * time.Second},
+		},
+		{
+			Network: "devnet", FeeBucket: "100k+", Sends: 1,
+			Processed: LatencyPercentiles{Samples: 1, P50: 150 * time.Millisecond, P90: 150 * time.Millisecond},
+			Confirmed: LatencyPercentiles{Samples: 1, P50: 300 * time.Millisecond, P90: 300 * time.Millisecond},
+		},
+		{
+			Network: "mainnet-beta", FeeBucket: "1k-10k", Sends: 2,
+			Processed: LatencyPercentiles{Samples: 2, P50: 200 * time.Millisecond, P90: 400 * time.Millisecond},
+			Confirmed: LatencyPercentiles{Samples: 2, P50: 400 * time.Millisecond, P90: 800 * time.Millisecond},
+			Finalized: LatencyPercentiles{Samples: 2, P50: 12 * time.Second, P90: 13 * time.Second},
+		},
+	}, stats)
+
+	// Only the three most recent sends: the two mainnet ones and the timed out devnet one.
+	recent := SummarizeLatency(records, 3)
+	assert.Len(t, recent, 2)
+	assert.Equal(t, "100k+", recent[0].FeeBucket)
+	assert.Equal(t, 2, recent[1].Sends)
+
+	assert.Empty(t, SummarizeLatency(nil, 0))
+}
+
+func TestPriorityFeeBucket(t *testing.T) {
+	for fee, expected := range map[uint64]string{0: "none", 1: "<1k", 999: "<1k", 1_000: "1k-10k", 50_000: "10k-100k", 100_000: "100k+"} {
+		assert.Equal(t, expected, PriorityFeeBucket(fee), fee)
+	}
+}
+
+func TestAwaitCommitmentsTimeout(t *testing.T) {
+	defer func(interval time.Duration) { confirmPollInterval = interval }(confirmPollInterval)
+	confirmPollInterval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	client := &MockClientInterface{GetSignatureStatusesFn: statusSequence(rpc.ConfirmationStatusProcessed)}
+	err := awaitCommitments(ctx, client, solana.Signature{1}, 0, func(rpc.ConfirmationStatusType) {})
+	assert.True(t, errors.Is(err, ErrConfirmTimeout))
+}
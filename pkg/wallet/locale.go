@@ -0,0 +1,178 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// Locale decides how amounts of money are shown: how digits are grouped, the decimal mark and on
+// which side of the number the currency symbol goes. It is a BCP 47 tag such as de-DE.
+type Locale string
+
+// DefaultLocale is used when no locale is configured.
+const DefaultLocale Locale = "en"
+
+// LocaleEnv selects the locale for every command when --locale is not given.
+const LocaleEnv = "SLEENG_LOCALE"
+
+var ErrUnsupportedLocale = errors.New("unsupported locale")
+
+// localeInfo is how a locale writes an amount of money.
+type localeInfo struct {
+	group   string
+	decimal string
+	// symbolAfter puts the symbol after the number, as in 1.234,56 €.
+	symbolAfter bool
+	// symbolSpace separates the symbol from the number with a space.
+	symbolSpace bool
+}
+
+var (
+	englishMoney = localeInfo{group: ",", decimal: "."}
+	germanMoney  = localeInfo{group: ".", decimal: ",", symbolAfter: true, symbolSpace: true}
+	// French groups with a narrow no-break space, so an amount never wraps inside a table cell.
+	frenchMoney = localeInfo{group: "\u202f", decimal: ",", symbolAfter: true, symbolSpace: true}
+	dutchMoney  = localeInfo{group: ".", decimal: ",", symbolSpace: true}
+)
+
+// supportedLocales are the locales amounts can be shown in. A tag with a region that is not listed
+// falls back to its language.
+var supportedLocales = map[Locale]localeInfo{
+	"en": englishMoney, "en-US": englishMoney, "en-GB": englishMoney, "en-IE": englishMoney,
+	"de": germanMoney, "de-DE": germanMoney, "de-AT": germanMoney,
+	"es": germanMoney, "es-ES": germanMoney,
+	"it": germanMoney, "it-IT": germanMoney,
+	"fr": frenchMoney, "fr-FR": frenchMoney,
+	"nl": dutchMoney, "nl-NL": dutchMoney,
+}
+
+// SupportedLocales returns the tags of all supported locales in alphabetical order.
+func SupportedLocales() []Locale {
+	locales := make([]Locale, 0, len(supportedLocales))
+	for locale := range supportedLocales {
+		locales = append(locales, locale)
+	}
+
+	sort.Slice(locales, func(i, j int) bool { return locales[i] < locales[j] })
+	return locales
+}
+
+// ParseLocale validates a locale tag. POSIX names such as de_DE.UTF-8 are accepted too, and a region
+// that is not supported falls back to its language. An empty tag selects DefaultLocale.
+func ParseLocale(tag string) (Locale, error) {
+	name := strings.TrimSpace(tag)
+	if name == "" {
+		return DefaultLocale, nil
+	}
+	// Drop the encoding and modifier of POSIX names.
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+
+	language, region, _ := strings.Cut(strings.ReplaceAll(name, "_", "-"), "-")
+	locale := Locale(strings.ToLower(language))
+	if region != "" {
+		if regional := locale + Locale("-"+strings.ToUpper(region)); isSupportedLocale(regional) {
+			return regional, nil
+		}
+	}
+	if !isSupportedLocale(locale) {
+		return "", fmt.Errorf("%w %q, supported: %s", ErrUnsupportedLocale, tag, localeList())
+	}
+	return locale, nil
+}
+
+func isSupportedLocale(l Locale) bool {
+	_, ok := supportedLocales[l]
+	return ok
+}
+
+// localeList lists the supported languages for error messages.
+func localeList() string {
+	var languages []string
+	for _, locale := range SupportedLocales() {
+		if !strings.Contains(string(locale), "-") {
+			languages = append(languages, string(locale))
+		}
+	}
+	return strings.Join(languages, ", ")
+}
+
+// orDefault returns DefaultLocale for the zero value.
+func (l Locale) orDefault() Locale {
+	if l == "" {
+		return DefaultLocale
+	}
+	return l
+}
+
+func (l Locale) String() string {
+	return string(l.orDefault())
+}
+
+// FormatMoney shows an amount in currency rounded to cents, such as €1,234.56 in en or 1.234,56 € in
+// de. Exports do not use it; they keep plain numbers.
+func (l Locale) FormatMoney(amount decimal.Decimal, currency Currency) string {
+	info, ok := supportedLocales[l.orDefault()]
+	if !ok {
+		info = englishMoney
+	}
+
+	rounded := amount.Round(2)
+	sign := ""
+	if rounded.IsNegative() {
+		sign = "-"
+	}
+	whole, cents, _ := strings.Cut(rounded.Abs().StringFixed(2), ".")
+	number := groupDigits(whole, info.group) + info.decimal + cents
+
+	space := ""
+	if info.symbolSpace || currency.letterSymbol() {
+		space = " "
+	}
+	if info.symbolAfter {
+		return sign + number + space + currency.Symbol()
+	}
+	return sign + currency.Symbol() + space + number
+}
+
+// letterSymbol reports whether the sign of the currency is a code such as CHF, which is always
+// separated from the number by a space.
+func (c Currency) letterSymbol() bool {
+	symbol := []rune(c.Symbol())
+	return len(symbol) > 0 && unicode.IsLetter(symbol[len(symbol)-1])
+}
+
+// groupDigits separates the digits into groups of three from the right.
+func groupDigits(digits, separator string) string {
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(separator)
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
+
+// selectedLocale is set by SetLocale.
+var selectedLocale atomic.Value
+
+// SetLocale selects the locale Currency.Format shows amounts in for the whole process.
+func SetLocale(l Locale) {
+	selectedLocale.Store(l.orDefault())
+}
+
+// CurrentLocale returns the locale selected with SetLocale, DefaultLocale when none was.
+func CurrentLocale() Locale {
+	if l, ok := selectedLocale.Load().(Locale); ok {
+		return l
+	}
+	return DefaultLocale
+}
@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		locale   Locale
+		amount   string
+		currency Currency
+		expected string
+	}{
+		{locale: "en", amount: "1234.56", currency: "EUR", expected: "€1,234.56"},
+		{locale: "en-IE", amount: "1234.56", currency: "EUR", expected: "€1,234.56"},
+		{locale: "", amount: "0", currency: "USD", expected: "$0.00"},
+		{locale: "en", amount: "999", currency: "GBP", expected: "£999.00"},
+		{locale: "en", amount: "1234567.891", currency: "USD", expected: "$1,234,567.89"},
+		{locale: "en", amount: "-1234.5", currency: "EUR", expected: "-€1,234.50"},
+		{locale: "de-DE", amount: "1234.56", currency: "EUR", expected: "1.234,56 €"},
+		{locale: "de", amount: "1000000", currency: "EUR", expected: "1.000.000,00 €"},
+		{locale: "de", amount: "-0.004", currency: "EUR", expected: "0,00 €"},
+		{locale: "es", amount: "-12.5", currency: "USD", expected: "-12,50 $"},
+		{locale: "fr", amount: "1234.56", currency: "EUR", expected: "1\u202f234,56 €"},
+		{locale: "nl", amount: "1234.56", currency: "EUR", expected: "€ 1.234,56"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.locale)+" "+tt.amount, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.locale.FormatMoney(decimal.RequireFromString(tt.amount), tt.currency))
+		})
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		tag         string
+		expected    Locale
+		expectedErr bool
+	}{
+		{tag: "", expected: DefaultLocale},
+		{tag: "de-DE", expected: "de-DE"},
+		{tag: "de_de", expected: "de-DE"},
+		{tag: "de_DE.UTF-8", expected: "de-DE"},
+		{tag: "fr_FR@euro", expected: "fr-FR"},
+		{tag: "de-CH", expected: "de"},
+		{tag: "EN", expected: "en"},
+		{tag: "ja-JP", expectedErr: true},
+		{tag: "C", expectedErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := ParseLocale(tt.tag)
+			if tt.expectedErr {
+				assert.ErrorIs(t, err, ErrUnsupportedLocale)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestCurrencyFormatFollowsLocale(t *testing.T) {
+	defer SetLocale(DefaultLocale)
+
+	SetLocale("de-DE")
+	assert.Equal(t, "2.000,00 €", Currency("EUR").Format(decimal.NewFromInt(2000)))
+
+	SetLocale("")
+	assert.Equal(t, DefaultLocale, CurrentLocale())
+	assert.Equal(t, "€2,000.00", Currency("EUR").Format(decimal.NewFromInt(2000)))
+}
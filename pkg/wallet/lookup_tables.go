@@ -0,0 +1,73 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// resolveAddressLookups appends the accounts a versioned transaction loads from address lookup
+// tables to its account keys, in the order instructions index them: the writable ones, then the
+// read-only ones. Without them an instruction naming a loaded account points past the account keys.
+// The node lists the loaded accounts in meta; for nodes that leave them out, the tables are fetched
+// and the lookups resolved against them, which fails once a table has been closed.
+func resolveAddressLookups(ctx context.Context, client ClientInterface, tx *solana.Transaction, meta *rpc.TransactionMeta) error {
+	lookups := tx.Message.GetAddressTableLookups()
+	loaded := 0
+	for _, lookup := range lookups {
+		loaded += len(lookup.WritableIndexes) + len(lookup.ReadonlyIndexes)
+	}
+	if !tx.Message.IsVersioned() || loaded == 0 {
+		return nil
+	}
+
+	if meta != nil && len(meta.LoadedAddresses.Writable)+len(meta.LoadedAddresses.ReadOnly) == loaded {
+		keys := make(solana.PublicKeySlice, 0, len(tx.Message.AccountKeys)+loaded)
+		keys = append(keys, tx.Message.AccountKeys...)
+		keys = append(keys, meta.LoadedAddresses.Writable...)
+		tx.Message.AccountKeys = append(keys, meta.LoadedAddresses.ReadOnly...)
+		return nil
+	}
+
+	tables := make(map[solana.PublicKey]solana.PublicKeySlice)
+	for _, lookup := range lookups {
+		if _, ok := tables[lookup.AccountKey]; ok {
+			continue
+		}
+		addresses, err := fetchLookupTable(ctx, client, lookup.AccountKey)
+		if err != nil {
+			return err
+		}
+		tables[lookup.AccountKey] = addresses
+	}
+	if err := tx.Message.SetAddressTables(tables); err != nil {
+		return fmt.Errorf("resolve address lookup tables: %w", err)
+	}
+	if err := tx.Message.ResolveLookups(); err != nil {
+		return fmt.Errorf("resolve address lookup tables: %w", err)
+	}
+	return nil
+}
+
+// fetchLookupTable returns the addresses held by an address lookup table.
+func fetchLookupTable(ctx context.Context, client ClientInterface, table solana.PublicKey) (solana.PublicKeySlice, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	account, err := client.GetAccountInfo(reqCtx, table)
+	if err != nil {
+		return nil, fmt.Errorf("get address lookup table %s: %w", table, err)
+	}
+	data := account.GetBinary()
+	if data == nil {
+		return nil, fmt.Errorf("address lookup table %s no longer exists", table)
+	}
+	state, err := addresslookuptable.DecodeAddressLookupTableState(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode address lookup table %s: %w", table, err)
+	}
+	return state.Addresses, nil
+}
@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// lookupTableData returns the account data of an address lookup table holding addresses.
+func lookupTableData(addresses ...solana.PublicKey) []byte {
+	// Type, deactivation slot, last extended slot and its start index, an absent authority and padding.
+	data := make([]byte, 56)
+	data[0] = 1
+	for i := 4; i < 20; i++ {
+		data[i] = 0xff
+	}
+	for _, address := range addresses {
+		data = append(data, address[:]...)
+	}
+	return data
+}
+
+func TestResolveAddressLookupsFetchesTables(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	sender := solana.NewWallet().PublicKey()
+	other := solana.NewWallet().PublicKey()
+	table := solana.NewWallet().PublicKey()
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(2_000, sender, owner).Build(),
+	}, solana.Hash{1}, solana.TransactionPayer(sender), solana.TransactionAddressTables(map[solana.PublicKey]solana.PublicKeySlice{
+		table: {other, owner},
+	}))
+	assert.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	assert.NoError(t, err)
+	envelope, err := json.Marshal([]string{base64.StdEncoding.EncodeToString(raw), "base64"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		account       *rpc.GetAccountInfoResult
+		expectedError string
+	}{
+		{
+			name:    "Table Fetched",
+			account: &rpc.GetAccountInfoResult{Value: &rpc.Account{Data: rpc.DataBytesOrJSONFromBytes(lookupTableData(other, owner))}},
+		},
+		{name: "Table Closed", account: &rpc.GetAccountInfoResult{}, expectedError: "no longer exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetchedTable solana.PublicKey
+			client := &MockClientInterface{
+				// The node leaves out the loaded addresses.
+				GetTransactionFn: func(ctx context.Context, _ solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+					blockTime := solana.UnixTimeSeconds(1_700_000_000)
+					result := &rpc.GetTransactionResult{Transaction: &rpc.TransactionResultEnvelope{}, BlockTime: &blockTime, Meta: &rpc.TransactionMeta{}}
+					assert.NoError(t, json.Unmarshal(envelope, result.Transaction))
+					return result, nil
+				},
+				GetAccountInfoFn: func(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+					fetchedTable = account
+					return tt.account, nil
+				},
+			}
+
+			transactions, err := fetchSingleTransaction(context.Background(), client, solana.Signature{1}, owner.String())
+			assert.Equal(t, table, fetchedTable)
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			if assert.Len(t, transactions, 1) {
+				assert.Equal(t, sender, transactions[0].From)
+				assert.Equal(t, owner, transactions[0].To)
+			}
+		})
+	}
+}
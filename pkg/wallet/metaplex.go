@@ -0,0 +1,202 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// MetaplexTokenMetadataProgramID is the Metaplex Token Metadata program, which owns the metadata
+// account of every Metaplex NFT.
+var MetaplexTokenMetadataProgramID = solana.MustPublicKeyFromBase58("metaqbxxUerdq28cj1RbAWkYQm3ybzjb6a8bt518x1s")
+
+// metadataV1Key is the first byte of a metadata account, telling it apart from the other accounts of
+// the program.
+const metadataV1Key = 4
+
+// TokenStandard is the kind of token a metadata account describes.
+type TokenStandard uint8
+
+const (
+	TokenStandardNonFungible TokenStandard = iota
+	TokenStandardFungibleAsset
+	TokenStandardFungible
+	TokenStandardNonFungibleEdition
+	TokenStandardProgrammableNonFungible
+	TokenStandardProgrammableNonFungibleEdition
+)
+
+var errNotMetadata = errors.New("not a Metaplex metadata account")
+
+// MetaplexCreator is a creator listed in a metadata account.
+type MetaplexCreator struct {
+	Address  solana.PublicKey
+	Verified bool
+	Share    uint8
+}
+
+// MetaplexCollection is the collection an NFT belongs to. Verified is only set once the collection's
+// update authority has confirmed the NFT is part of it.
+type MetaplexCollection struct {
+	Verified bool
+	Key      solana.PublicKey
+}
+
+// MetaplexMetadata is the part of a Metaplex metadata account sleeng reads. Fields added to the
+// account after the collection are not decoded.
+type MetaplexMetadata struct {
+	UpdateAuthority      solana.PublicKey
+	Mint                 solana.PublicKey
+	Name                 string
+	Symbol               string
+	URI                  string
+	SellerFeeBasisPoints uint16
+	Creators             []MetaplexCreator
+	PrimarySaleHappened  bool
+	IsMutable            bool
+	// TokenStandard and Collection are nil for accounts written before they existed.
+	TokenStandard *TokenStandard
+	Collection    *MetaplexCollection
+}
+
+// MetadataAddress returns the address of the metadata account of mint.
+func MetadataAddress(mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress(
+		[][]byte{[]byte("metadata"), MetaplexTokenMetadataProgramID[:], mint[:]},
+		MetaplexTokenMetadataProgramID,
+	)
+	return address, err
+}
+
+// DecodeMetaplexMetadata decodes the data of a metadata account. The name, symbol and URI are stored
+// padded with zero bytes, which are trimmed.
+func DecodeMetaplexMetadata(data []byte) (MetaplexMetadata, error) {
+	dec := bin.NewBorshDecoder(data)
+	var m MetaplexMetadata
+
+	key, err := dec.ReadUint8()
+	if err != nil {
+		return m, fmt.Errorf("read key: %w", err)
+	}
+	if key != metadataV1Key {
+		return m, errNotMetadata
+	}
+
+	if err := readPublicKey(dec, &m.UpdateAuthority); err != nil {
+		return m, fmt.Errorf("read update authority: %w", err)
+	}
+	if err := readPublicKey(dec, &m.Mint); err != nil {
+		return m, fmt.Errorf("read mint: %w", err)
+	}
+	for _, field := range []*string{&m.Name, &m.Symbol, &m.URI} {
+		value, err := dec.ReadString()
+		if err != nil {
+			return m, fmt.Errorf("read name, symbol or URI: %w", err)
+		}
+		*field = strings.TrimRight(value, "\x00")
+	}
+	if m.SellerFeeBasisPoints, err = dec.ReadUint16(bin.LE); err != nil {
+		return m, fmt.Errorf("read seller fee: %w", err)
+	}
+	if m.Creators, err = readCreators(dec); err != nil {
+		return m, fmt.Errorf("read creators: %w", err)
+	}
+	if m.PrimarySaleHappened, err = dec.ReadBool(); err != nil {
+		return m, fmt.Errorf("read primary sale: %w", err)
+	}
+	if m.IsMutable, err = dec.ReadBool(); err != nil {
+		return m, fmt.Errorf("read mutability: %w", err)
+	}
+
+	// The optional fields below were appended over time; older accounts simply end here.
+	if !dec.HasRemaining() {
+		return m, nil
+	}
+	// The edition nonce is not needed, but has to be read past.
+	if present, err := dec.ReadOption(); err != nil {
+		return m, fmt.Errorf("read edition nonce: %w", err)
+	} else if present {
+		if _, err := dec.ReadUint8(); err != nil {
+			return m, fmt.Errorf("read edition nonce: %w", err)
+		}
+	}
+
+	if !dec.HasRemaining() {
+		return m, nil
+	}
+	if present, err := dec.ReadOption(); err != nil {
+		return m, fmt.Errorf("read token standard: %w", err)
+	} else if present {
+		standard, err := dec.ReadUint8()
+		if err != nil {
+			return m, fmt.Errorf("read token standard: %w", err)
+		}
+		m.TokenStandard = (*TokenStandard)(&standard)
+	}
+
+	if !dec.HasRemaining() {
+		return m, nil
+	}
+	if present, err := dec.ReadOption(); err != nil {
+		return m, fmt.Errorf("read collection: %w", err)
+	} else if present {
+		var collection MetaplexCollection
+		if collection.Verified, err = dec.ReadBool(); err != nil {
+			return m, fmt.Errorf("read collection: %w", err)
+		}
+		if err := readPublicKey(dec, &collection.Key); err != nil {
+			return m, fmt.Errorf("read collection: %w", err)
+		}
+		m.Collection = &collection
+	}
+
+	return m, nil
+}
+
+// IsFungible reports whether the metadata describes a fungible token rather than an NFT.
+func (m MetaplexMetadata) IsFungible() bool {
+	return m.TokenStandard != nil &&
+		(*m.TokenStandard == TokenStandardFungible || *m.TokenStandard == TokenStandardFungibleAsset)
+}
+
+func readPublicKey(dec *bin.Decoder, out *solana.PublicKey) error {
+	key, err := dec.ReadNBytes(solana.PublicKeyLength)
+	if err != nil {
+		return err
+	}
+	copy(out[:], key)
+	return nil
+}
+
+func readCreators(dec *bin.Decoder) ([]MetaplexCreator, error) {
+	present, err := dec.ReadOption()
+	if err != nil || !present {
+		return nil, err
+	}
+
+	count, err := dec.ReadUint32(bin.LE)
+	if err != nil {
+		return nil, err
+	}
+	// Metaplex allows at most five creators; anything larger is not a metadata account.
+	if count > 5 {
+		return nil, fmt.Errorf("%d creators", count)
+	}
+
+	creators := make([]MetaplexCreator, count)
+	for i := range creators {
+		if err := readPublicKey(dec, &creators[i].Address); err != nil {
+			return nil, err
+		}
+		if creators[i].Verified, err = dec.ReadBool(); err != nil {
+			return nil, err
+		}
+		if creators[i].Share, err = dec.ReadUint8(); err != nil {
+			return nil, err
+		}
+	}
+	return creators, nil
+}
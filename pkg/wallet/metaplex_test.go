@@ -0,0 +1,98 @@
+package wallet
+
+import (
+	"bytes"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeMetadata returns the data of a metadata account, padding the strings like the program does.
+// Leaving out the token standard writes an account from before it existed, which ends after the
+// edition nonce.
+func encodeMetadata(t *testing.T, m MetaplexMetadata) []byte {
+	var buf bytes.Buffer
+	enc := bin.NewBorshEncoder(&buf)
+	pad := func(s string, size int) string { return s + string(make([]byte, size-len(s))) }
+
+	assert.NoError(t, enc.WriteUint8(metadataV1Key))
+	assert.NoError(t, enc.WriteBytes(m.UpdateAuthority[:], false))
+	assert.NoError(t, enc.WriteBytes(m.Mint[:], false))
+	assert.NoError(t, enc.WriteString(pad(m.Name, 32)))
+	assert.NoError(t, enc.WriteString(pad(m.Symbol, 10)))
+	assert.NoError(t, enc.WriteString(pad(m.URI, 200)))
+	assert.NoError(t, enc.WriteUint16(m.SellerFeeBasisPoints, bin.LE))
+	assert.NoError(t, enc.WriteOption(m.Creators != nil))
+	if m.Creators != nil {
+		assert.NoError(t, enc.WriteUint32(uint32(len(m.Creators)), bin.LE))
+		for _, creator := range m.Creators {
+			assert.NoError(t, enc.WriteBytes(creator.Address[:], false))
+			assert.NoError(t, enc.WriteBool(creator.Verified))
+			assert.NoError(t, enc.WriteUint8(creator.Share))
+		}
+	}
+	assert.NoError(t, enc.WriteBool(m.PrimarySaleHappened))
+	assert.NoError(t, enc.WriteBool(m.IsMutable))
+	assert.NoError(t, enc.WriteOption(true))
+	assert.NoError(t, enc.WriteUint8(255))
+	if m.TokenStandard == nil {
+		return buf.Bytes()
+	}
+
+	assert.NoError(t, enc.WriteOption(true))
+	assert.NoError(t, enc.WriteUint8(uint8(*m.TokenStandard)))
+	assert.NoError(t, enc.WriteOption(m.Collection != nil))
+	if m.Collection != nil {
+		assert.NoError(t, enc.WriteBool(m.Collection.Verified))
+		assert.NoError(t, enc.WriteBytes(m.Collection.Key[:], false))
+	}
+	// Later fields, such as uses, follow and are ignored.
+	assert.NoError(t, enc.WriteOption(false))
+	return buf.Bytes()
+}
+
+func TestDecodeMetaplexMetadata(t *testing.T) {
+	standard := TokenStandardNonFungible
+	full := MetaplexMetadata{
+		UpdateAuthority:      solana.NewWallet().PublicKey(),
+		Mint:                 solana.NewWallet().PublicKey(),
+		Name:                 "Mad Lad #8420",
+		Symbol:               "MAD",
+		URI:                  "https://madlads.s3.us-west-2.amazonaws.com/json/8420.json",
+		SellerFeeBasisPoints: 420,
+		Creators:             []MetaplexCreator{{Address: solana.NewWallet().PublicKey(), Verified: true, Share: 100}},
+		IsMutable:            true,
+		TokenStandard:        &standard,
+		Collection:           &MetaplexCollection{Verified: true, Key: solana.NewWallet().PublicKey()},
+	}
+	legacy := full
+	legacy.Creators, legacy.TokenStandard, legacy.Collection = nil, nil, nil
+
+	for name, m := range map[string]MetaplexMetadata{"Full": full, "Legacy": legacy} {
+		t.Run(name, func(t *testing.T) {
+			decoded, err := DecodeMetaplexMetadata(encodeMetadata(t, m))
+			assert.NoError(t, err)
+			assert.Equal(t, m, decoded)
+		})
+	}
+
+	t.Run("Other Account", func(t *testing.T) {
+		data := encodeMetadata(t, full)
+		data[0] = 6
+		_, err := DecodeMetaplexMetadata(data)
+		assert.ErrorIs(t, err, errNotMetadata)
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		_, err := DecodeMetaplexMetadata(encodeMetadata(t, full)[:100])
+		assert.Error(t, err)
+	})
+}
+
+func TestMetadataAddress(t *testing.T) {
+	address, err := MetadataAddress(solana.MustPublicKeyFromBase58("So11111111111111111111111111111111111111112"))
+	assert.NoError(t, err)
+	assert.Equal(t, "6dM4TqWyWJsbx7obrdLcviBkTafD5E8av61zfU6jq57X", address.String())
+}
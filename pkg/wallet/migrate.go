@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// legacyFiles are the files older versions kept in the directory they ran in, with their names
+// in a profile directory. The key file comes first: the others are only moved along with it.
+var legacyFiles = [][2]string{
+	{LegacyKeyFileName, KeyFileName},
+	{PendingFilePath, PendingFilePath},
+	{AuditFilePath, AuditFilePath},
+}
+
+// HasLegacyKeyFile reports whether dir holds a key file written by a version that kept it in the
+// current directory.
+func HasLegacyKeyFile(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, LegacyKeyFileName))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// MigrateLegacyFiles moves the key file older versions kept in from into the profile directory to,
+// together with the pending transactions and audit log next to it, and returns the new paths.
+// The key file must parse, and a key file that already exists in to is never overwritten. Each
+// file is copied and flushed before the original is removed, so an interrupted migration leaves
+// both copies rather than none.
+func MigrateLegacyFiles(from, to string) ([]string, error) {
+	if err := guardWrite(to); err != nil {
+		return nil, err
+	}
+
+	legacyKeyFile := filepath.Join(from, LegacyKeyFileName)
+	fileData, err := os.ReadFile(legacyKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", legacyKeyFile, err)
+	}
+	var data WalletData
+	if err := json.Unmarshal(fileData, &data); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrKeyFileCorrupted, legacyKeyFile, err)
+	}
+	if _, err := os.Stat(filepath.Join(to, KeyFileName)); err == nil {
+		return nil, fmt.Errorf("refusing to replace the existing key file %s", filepath.Join(to, KeyFileName))
+	}
+
+	writer := &IOUtilFileWriter{}
+	var moved []string
+	for _, file := range legacyFiles {
+		source, target := filepath.Join(from, file[0]), filepath.Join(to, file[1])
+
+		fileData, err := os.ReadFile(source)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return moved, fmt.Errorf("error reading %s: %w", source, err)
+		}
+		if _, err := os.Stat(target); err == nil {
+			// Pending transactions or an audit log of the new location are kept.
+			continue
+		}
+
+		if err := writer.WriteFile(target, fileData); err != nil {
+			return moved, err
+		}
+		if err := os.Remove(source); err != nil {
+			return moved, fmt.Errorf("moved %s to %s but could not remove the original: %w", source, target, err)
+		}
+		moved = append(moved, target)
+	}
+
+	return moved, nil
+}
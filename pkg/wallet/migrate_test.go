@@ -0,0 +1,65 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateLegacyFiles(t *testing.T) {
+	workDir := t.TempDir()
+	configDir := filepath.Join(t.TempDir(), "config", "sleeng")
+
+	keyFile := []byte(`{"activeAlias":"main","wallets":{"main":{"publicKey":"Addr1"}}}`)
+	assert.NoError(t, os.WriteFile(filepath.Join(workDir, LegacyKeyFileName), keyFile, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(workDir, PendingFilePath), []byte(`[]`), 0644))
+	assert.True(t, HasLegacyKeyFile(workDir))
+
+	moved, err := MigrateLegacyFiles(workDir, configDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(configDir, KeyFileName), filepath.Join(configDir, PendingFilePath)}, moved)
+	assert.False(t, HasLegacyKeyFile(workDir))
+	assert.NoFileExists(t, filepath.Join(workDir, PendingFilePath))
+
+	info, err := os.Stat(configDir)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(configDir, KeyFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	keyOps := newDiskKeyOps(configDir)
+	aliases, err := keyOps.ListAliases()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main"}, aliases)
+
+	// A second legacy key file never replaces the migrated one.
+	assert.NoError(t, os.WriteFile(filepath.Join(workDir, LegacyKeyFileName), []byte(`{"wallets":{}}`), 0600))
+	_, err = MigrateLegacyFiles(workDir, configDir)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "refusing to replace the existing key file")
+	}
+	assert.True(t, HasLegacyKeyFile(workDir))
+}
+
+func TestMigrateCorruptedLegacyKeyFile(t *testing.T) {
+	workDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(workDir, LegacyKeyFileName), []byte(`{"wallets":`), 0600))
+
+	_, err := MigrateLegacyFiles(workDir, t.TempDir())
+	assert.ErrorIs(t, err, ErrKeyFileCorrupted)
+	assert.True(t, HasLegacyKeyFile(workDir), "a key file that cannot be read is left where it is")
+}
+
+func TestKeyFilePathOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "elsewhere", "ci-keys.json")
+	keyOps := newDiskKeyOps(t.TempDir())
+	keyOps.Path = path
+
+	assert.NoError(t, keyOps.SetNetwork("devnet", ""))
+	assert.FileExists(t, path)
+	assert.NoFileExists(t, filepath.Join(keyOps.Dir, KeyFileName))
+	assert.Equal(t, path, keyOps.KeyFile())
+}
@@ -0,0 +1,572 @@
+package wallet
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var (
+	ErrInvalidMultisig   = errors.New("invalid multisig")
+	ErrMultisigNotFound  = errors.New("multisig not found")
+	ErrNotMultisigSigner = errors.New("the active wallet is not a signer")
+)
+
+// minMultisigSigners is the smallest signer set worth a multisig.
+const minMultisigSigners = 2
+
+// multisigAccountSize is the size of an SPL Token multisig account: m, n, the initialized flag and
+// room for token.MAX_SIGNERS signers.
+const multisigAccountSize = 3 + token.MAX_SIGNERS*32
+
+// Multisig is an SPL Token multisig account recorded in the key file. Tokens whose owner is the
+// account can only be moved with the signatures of Threshold of its Signers.
+type Multisig struct {
+	// Alias is the name the multisig is stored under; it is the key of the map in the key file.
+	Alias     string   `json:"-"`
+	Address   string   `json:"address"`
+	Signers   []string `json:"signers"`
+	Threshold int      `json:"threshold"`
+}
+
+func (m Multisig) String() string {
+	return fmt.Sprintf("%d-of-%d %s", m.Threshold, len(m.Signers), m.Address)
+}
+
+// ValidateSignerSet checks that signers are between 2 and token.MAX_SIGNERS distinct addresses and
+// that threshold is between 1 and their number.
+func ValidateSignerSet(signers []string, threshold int) ([]solana.PublicKey, error) {
+	if len(signers) < minMultisigSigners {
+		return nil, fmt.Errorf("%w: needs at least %d signers, got %d", ErrInvalidMultisig, minMultisigSigners, len(signers))
+	}
+	if len(signers) > token.MAX_SIGNERS {
+		return nil, fmt.Errorf("%w: at most %d signers are allowed, got %d", ErrInvalidMultisig, token.MAX_SIGNERS, len(signers))
+	}
+
+	keys := make([]solana.PublicKey, 0, len(signers))
+	seen := make(map[solana.PublicKey]bool)
+	for _, signer := range signers {
+		key, err := solana.PublicKeyFromBase58(strings.TrimSpace(signer))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid signer %q: %v", ErrInvalidMultisig, signer, err)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidMultisig, key)
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	if threshold < 1 || threshold > len(keys) {
+		return nil, fmt.Errorf("%w: threshold must be between 1 and %d, got %d", ErrInvalidMultisig, len(keys), threshold)
+	}
+	return keys, nil
+}
+
+// approvers returns the signers that approve a transfer proposed by proposer: the proposer first,
+// then the other signers in their stored order until the threshold is reached.
+func (m Multisig) approvers(proposer solana.PublicKey) ([]solana.PublicKey, error) {
+	keys, err := ValidateSignerSet(m.Signers, m.Threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	approvers := []solana.PublicKey{proposer}
+	found := false
+	for _, key := range keys {
+		if key == proposer {
+			found = true
+			continue
+		}
+		if len(approvers) < m.Threshold {
+			approvers = append(approvers, key)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w of %s: %s", ErrNotMultisigSigner, m.Alias, proposer)
+	}
+	return approvers, nil
+}
+
+// multisigInstructions creates and initializes the multisig account with the signer set.
+func multisigInstructions(payer, account solana.PublicKey, signers []solana.PublicKey, threshold int, rent uint64) []solana.Instruction {
+	initialize := token.NewInitializeMultisig2Instruction(uint8(threshold), account, signers)
+	// The signers are only recorded; they do not have to sign the creation.
+	for _, signer := range initialize.Signers {
+		signer.IsSigner = false
+	}
+
+	return []solana.Instruction{
+		system.NewCreateAccountInstruction(rent, multisigAccountSize, solana.TokenProgramID, payer, account).Build(),
+		initialize.Build(),
+	}
+}
+
+// CreateMultisig creates an SPL Token multisig account with the given signers, paid for by the
+// active wallet, and records it in the key file under alias.
+func (w *WalletConfig) CreateMultisig(ctx context.Context, alias string, signers []string, threshold int) (*Multisig, string, error) {
+	keys, err := ValidateSignerSet(signers, threshold)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := validContactName(alias); err != nil {
+		return nil, "", fmt.Errorf("invalid multisig name: %w", err)
+	}
+	if err := w.CheckAliasFree(alias); err != nil {
+		return nil, "", err
+	}
+
+	payer, err := w.currentPrivateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, "", err
+	}
+	client := endpoints.client()
+
+	rent, err := client.GetMinimumBalanceForRentExemption(ctx, multisigAccountSize, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch rent for the multisig account: %w", err)
+	}
+	recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch blockhash: %w", err)
+	}
+
+	account := solana.NewWallet().PrivateKey
+	tx, err := solana.NewTransaction(multisigInstructions(payer.PublicKey(), account.PublicKey(), keys, threshold, rent), recent.Value.Blockhash, solana.TransactionPayer(payer.PublicKey()))
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		switch key {
+		case payer.PublicKey():
+			return &payer
+		case account.PublicKey():
+			return &account
+		}
+		return nil
+	}); err != nil {
+		return nil, "", fmt.Errorf("unable to sign transaction: %w", err)
+	}
+	w.recordKeyUsage(payer.PublicKey(), SignedSend, tx.Signatures[0])
+
+	sig, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create the multisig account: %w", err)
+	}
+	if err := awaitSignature(ctx, client, sig); err != nil {
+		return nil, "", err
+	}
+
+	multisig := Multisig{Alias: alias, Address: account.PublicKey().String(), Threshold: threshold}
+	for _, key := range keys {
+		multisig.Signers = append(multisig.Signers, key.String())
+	}
+	if err := w.KeyOps.AddMultisig(multisig); err != nil {
+		return nil, "", fmt.Errorf("the multisig account %s was created, but could not be stored: %w", multisig.Address, err)
+	}
+	return &multisig, sig.String(), nil
+}
+
+// Multisigs returns the multisigs stored in the key file, sorted by alias.
+func (w *WalletConfig) Multisigs() ([]Multisig, error) {
+	return w.KeyOps.Multisigs()
+}
+
+// multisig returns the stored multisig named alias.
+func (w *WalletConfig) multisig(alias string) (Multisig, error) {
+	multisigs, err := w.Multisigs()
+	if err != nil {
+		return Multisig{}, err
+	}
+	for _, m := range multisigs {
+		if m.Alias == alias {
+			return m, nil
+		}
+	}
+	return Multisig{}, fmt.Errorf("%w: %s", ErrMultisigNotFound, alias)
+}
+
+// AddMultisig records a multisig under m.Alias, which must not name a wallet, contact or multisig.
+func (k *KeyOps) AddMultisig(m Multisig) error {
+	if _, err := ValidateSignerSet(m.Signers, m.Threshold); err != nil {
+		return err
+	}
+
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var data WalletData
+	fileExists, err := k.IsKeyFilePresent()
+	if err != nil {
+		return fmt.Errorf("error checking if keys are already present: %w", err)
+	}
+	if fileExists {
+		data, err = k.readWalletData(k.keyFilePath())
+		if err != nil {
+			return err
+		}
+	} else {
+		data.Wallets = make(map[string]Wallet)
+	}
+
+	if err := data.checkNameFree(m.Alias); err != nil {
+		return err
+	}
+	if data.Multisigs == nil {
+		data.Multisigs = make(map[string]Multisig)
+	}
+	data.Multisigs[m.Alias] = m
+
+	return k.writeWalletData("multisig", data)
+}
+
+// Multisigs returns the stored multisigs sorted by alias. A missing key file has none.
+func (k *KeyOps) Multisigs() ([]Multisig, error) {
+	fileExists, err := k.IsKeyFilePresent()
+	if err != nil || !fileExists {
+		return nil, err
+	}
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	multisigs := make([]Multisig, 0, len(data.Multisigs))
+	for alias, m := range data.Multisigs {
+		m.Alias = alias
+		multisigs = append(multisigs, m)
+	}
+	sort.Slice(multisigs, func(i, j int) bool { return multisigs[i].Alias < multisigs[j].Alias })
+	return multisigs, nil
+}
+
+// PartialTransaction is a multisig transfer signed by some of its approvers, passed from one signer
+// to the next as a file until every approver has signed and it can be broadcast.
+type PartialTransaction struct {
+	Multisig    string  `json:"multisig"`
+	Threshold   int     `json:"threshold"`
+	Network     Network `json:"network"`
+	Description string  `json:"description"`
+	// LastValidBlockHeight is the height after which the blockhash has expired; zero when the
+	// transaction uses a durable nonce and does not expire.
+	LastValidBlockHeight uint64 `json:"lastValidBlockHeight,omitempty"`
+	// Transaction is the base64 wire transaction, with zeroed signatures for approvers yet to sign.
+	Transaction string `json:"transaction"`
+}
+
+// ParsePartialTransaction reads a partially-signed transaction file and checks the signatures it
+// already carries.
+func ParsePartialTransaction(data []byte) (*PartialTransaction, error) {
+	var partial PartialTransaction
+	if err := json.Unmarshal(data, &partial); err != nil {
+		return nil, fmt.Errorf("failed to parse partially-signed transaction: %w", err)
+	}
+	if _, err := partial.signatures(); err != nil {
+		return nil, err
+	}
+	return &partial, nil
+}
+
+// Encode returns the file contents of the partially-signed transaction.
+func (p *PartialTransaction) Encode() ([]byte, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// decode returns the transaction, with one signature slot per required signer.
+func (p *PartialTransaction) decode() (*solana.Transaction, error) {
+	raw, err := base64.StdEncoding.DecodeString(p.Transaction)
+	if err != nil {
+		return nil, fmt.Errorf("invalid partially-signed transaction: %w", err)
+	}
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid partially-signed transaction: %w", err)
+	}
+	if len(tx.Signatures) != int(tx.Message.Header.NumRequiredSignatures) {
+		return nil, fmt.Errorf("invalid partially-signed transaction: %d signatures for %d signers", len(tx.Signatures), tx.Message.Header.NumRequiredSignatures)
+	}
+	return tx, nil
+}
+
+// setTransaction stores tx in the file.
+func (p *PartialTransaction) setTransaction(tx *solana.Transaction) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	p.Transaction = base64.StdEncoding.EncodeToString(raw)
+	return nil
+}
+
+// PartialSignature is the signature slot of one required signer.
+type PartialSignature struct {
+	Signer solana.PublicKey
+	Signed bool
+}
+
+// signatures returns the signature slot of every required signer, verifying those already signed.
+func (p *PartialTransaction) signatures() ([]PartialSignature, error) {
+	tx, err := p.decode()
+	if err != nil {
+		return nil, err
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	slots := make([]PartialSignature, len(tx.Signatures))
+	for i, sig := range tx.Signatures {
+		signer := tx.Message.AccountKeys[i]
+		slots[i] = PartialSignature{Signer: signer, Signed: !sig.IsZero()}
+		if slots[i].Signed && !sig.Verify(signer, message) {
+			return nil, fmt.Errorf("invalid partially-signed transaction: the signature of %s does not match the transaction", signer)
+		}
+	}
+	return slots, nil
+}
+
+// Signatures returns the signature slot of every required signer, in the order of the transaction.
+func (p *PartialTransaction) Signatures() ([]PartialSignature, error) {
+	return p.signatures()
+}
+
+// Missing returns the signers that have yet to sign. The transaction can be broadcast once none are.
+func (p *PartialTransaction) Missing() ([]solana.PublicKey, error) {
+	slots, err := p.signatures()
+	if err != nil {
+		return nil, err
+	}
+	var missing []solana.PublicKey
+	for _, slot := range slots {
+		if !slot.Signed {
+			missing = append(missing, slot.Signer)
+		}
+	}
+	return missing, nil
+}
+
+// sign adds the signature of key, which must be a required signer that has not signed yet.
+func (p *PartialTransaction) sign(key solana.PrivateKey) (solana.Signature, error) {
+	tx, err := p.decode()
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	for i := range tx.Signatures {
+		if tx.Message.AccountKeys[i] != key.PublicKey() {
+			continue
+		}
+		if !tx.Signatures[i].IsZero() {
+			return solana.Signature{}, fmt.Errorf("%s has already signed this transaction", key.PublicKey())
+		}
+		tx.Signatures[i], err = key.Sign(message)
+		if err != nil {
+			return solana.Signature{}, fmt.Errorf("unable to sign transaction: %w", err)
+		}
+		return tx.Signatures[i], p.setTransaction(tx)
+	}
+	return solana.Signature{}, fmt.Errorf("%w of this transaction: %s", ErrNotMultisigSigner, key.PublicKey())
+}
+
+// PrepareMultisigTokenSend quotes a transfer of tokens held by the multisig stored as alias. The
+// active wallet, which must be one of its signers, pays the fee and any rent.
+func (w *WalletConfig) PrepareMultisigTokenSend(ctx context.Context, alias, mint, amount, recipient string) (*TokenQuote, error) {
+	multisig, err := w.multisig(alias)
+	if err != nil {
+		return nil, err
+	}
+	account, err := solana.PublicKeyFromBase58(multisig.Address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid stored address of %s: %v", ErrInvalidMultisig, alias, err)
+	}
+
+	proposer, err := w.currentPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkCanSign(); err != nil {
+		return nil, err
+	}
+	approvers, err := multisig.approvers(proposer)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := w.prepareTokenSend(ctx, mint, amount, recipient, account, proposer)
+	if err != nil {
+		return nil, err
+	}
+	quote.Signers = approvers
+	return quote, nil
+}
+
+// ProposeMultisigSend builds the transfer of a quote from PrepareMultisigTokenSend and signs it with
+// the active wallet. The other approvers add their signatures with CosignTransaction. With a durable
+// nonce account, authorised by the active wallet, the transaction does not expire while it is passed
+// around; otherwise its blockhash expires after about a minute.
+func (w *WalletConfig) ProposeMultisigSend(ctx context.Context, quote *TokenQuote, nonceAccount string) (*PartialTransaction, error) {
+	proposer, err := w.currentPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkRecipientAllowed(quote.Recipient); err != nil {
+		return nil, err
+	}
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	instructions := tokenTransferInstructions(quote)
+	var blockhash solana.Hash
+	var lastValid uint64
+	if nonceAccount != "" {
+		nonceKey, err := solana.PublicKeyFromBase58(nonceAccount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nonce account: %w", err)
+		}
+		nonce, err := fetchNonce(ctx, client, nonceKey)
+		if err != nil {
+			return nil, err
+		}
+		if nonce.AuthorizedPubkey != proposer.PublicKey() {
+			return nil, ErrNonceAuthority
+		}
+		blockhash = solana.Hash(nonce.Nonce)
+		advance := system.NewAdvanceNonceAccountInstruction(nonceKey, solana.SysVarRecentBlockHashesPubkey, proposer.PublicKey()).Build()
+		instructions = append([]solana.Instruction{advance}, instructions...)
+	} else {
+		recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch blockhash: %w", err)
+		}
+		blockhash, lastValid = recent.Value.Blockhash, recent.Value.LastValidBlockHeight
+	}
+
+	tx, err := solana.NewTransaction(instructions, blockhash, solana.TransactionPayer(proposer.PublicKey()))
+	if err != nil {
+		return nil, err
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+
+	network := w.Network
+	if network == "" {
+		network = DefaultNetwork
+	}
+	partial := &PartialTransaction{
+		Multisig:             quote.Owner.String(),
+		Threshold:            len(quote.Signers),
+		Network:              network,
+		Description:          fmt.Sprintf("%s %s to %s", quote.Amount(), quote.Symbol, quote.Recipient),
+		LastValidBlockHeight: lastValid,
+	}
+	if err := partial.setTransaction(tx); err != nil {
+		return nil, err
+	}
+
+	sig, err := partial.sign(proposer)
+	if err != nil {
+		return nil, err
+	}
+	w.recordKeyUsage(proposer.PublicKey(), SignedSend, sig)
+	return partial, nil
+}
+
+// CosignTransaction adds the signature of the active wallet to a partially-signed transaction and,
+// once every approver has signed, broadcasts it and waits for it to be confirmed. It returns the
+// transaction signature when it was broadcast, and an empty one while signatures are missing.
+func (w *WalletConfig) CosignTransaction(ctx context.Context, partial *PartialTransaction) (string, error) {
+	network := w.Network
+	if network == "" {
+		network = DefaultNetwork
+	}
+	if partial.Network != network {
+		return "", fmt.Errorf("the transaction was proposed on %s, but %s is selected", partial.Network, network)
+	}
+
+	key, err := w.currentPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	sig, err := partial.sign(key)
+	if err != nil {
+		return "", err
+	}
+	w.recordKeyUsage(key.PublicKey(), SignedSend, sig)
+
+	missing, err := partial.Missing()
+	if err != nil || len(missing) > 0 {
+		return "", err
+	}
+	return w.BroadcastPartial(ctx, partial)
+}
+
+// BroadcastPartial submits a transaction every approver has signed and waits for it to be confirmed.
+func (w *WalletConfig) BroadcastPartial(ctx context.Context, partial *PartialTransaction) (string, error) {
+	missing, err := partial.Missing()
+	if err != nil {
+		return "", err
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%d signatures are missing, from %s", len(missing), missing[0])
+	}
+	tx, err := partial.decode()
+	if err != nil {
+		return "", err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return "", err
+	}
+	client := endpoints.client()
+
+	if partial.LastValidBlockHeight > 0 {
+		height, err := client.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch block height: %w", err)
+		}
+		if height > partial.LastValidBlockHeight {
+			return "", fmt.Errorf("%w: propose the transfer again, with --nonce-account to give the co-signers time", ErrBlockhashExpired)
+		}
+	}
+
+	sig, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		return "", fmt.Errorf("failed to submit multisig transfer: %w", err)
+	}
+	if err := awaitSignature(ctx, client, sig); err != nil {
+		return "", err
+	}
+
+	_ = w.recordAudit(ctx, "multisig send", fmt.Sprintf("%s from %s (%s)", partial.Description, partial.Multisig, sig))
+	return sig.String(), nil
+}
@@ -0,0 +1,264 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSignerSet(t *testing.T) {
+	alice := solana.NewWallet().PublicKey().String()
+	bob := solana.NewWallet().PublicKey().String()
+	carol := solana.NewWallet().PublicKey().String()
+	tooMany := make([]string, token.MAX_SIGNERS+1)
+	for i := range tooMany {
+		tooMany[i] = solana.NewWallet().PublicKey().String()
+	}
+
+	tests := []struct {
+		name        string
+		signers     []string
+		threshold   int
+		expectedErr string
+	}{
+		{name: "2 Of 2", signers: []string{alice, bob}, threshold: 2},
+		{name: "2 Of 3", signers: []string{alice, bob, carol}, threshold: 2},
+		{name: "1 Of 2", signers: []string{alice, bob}, threshold: 1},
+		{name: "Maximum Signers", signers: tooMany[1:], threshold: token.MAX_SIGNERS},
+		{name: "Single Signer", signers: []string{alice}, threshold: 1, expectedErr: "needs at least 2 signers, got 1"},
+		{name: "Too Many Signers", signers: tooMany, threshold: 2, expectedErr: "at most 11 signers are allowed, got 12"},
+		{name: "Duplicate Signer", signers: []string{alice, bob, alice}, threshold: 2, expectedErr: alice + " is listed twice"},
+		{name: "Invalid Signer", signers: []string{alice, "not-an-address"}, threshold: 2, expectedErr: `invalid signer "not-an-address"`},
+		{name: "Threshold Above Signers", signers: []string{alice, bob}, threshold: 3, expectedErr: "threshold must be between 1 and 2, got 3"},
+		{name: "Zero Threshold", signers: []string{alice, bob}, threshold: 0, expectedErr: "threshold must be between 1 and 2, got 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ValidateSignerSet(tt.signers, tt.threshold)
+			if tt.expectedErr != "" {
+				assert.True(t, errors.Is(err, ErrInvalidMultisig))
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, keys, len(tt.signers))
+		})
+	}
+}
+
+func TestMultisigApprovers(t *testing.T) {
+	alice, bob, carol := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	multisig := Multisig{Alias: "treasury", Signers: []string{alice.String(), bob.String(), carol.String()}, Threshold: 2}
+
+	approvers, err := multisig.approvers(carol)
+	assert.NoError(t, err)
+	assert.Equal(t, []solana.PublicKey{carol, alice}, approvers, "the proposer signs first")
+
+	_, err = multisig.approvers(solana.NewWallet().PublicKey())
+	assert.True(t, errors.Is(err, ErrNotMultisigSigner))
+}
+
+func TestMultisigInstructions(t *testing.T) {
+	payer, account := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	signers := []solana.PublicKey{solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()}
+
+	tx, err := solana.NewTransaction(multisigInstructions(payer, account, signers, 2, 3_000_000), solana.Hash{1}, solana.TransactionPayer(payer))
+	assert.NoError(t, err)
+	// Only the payer and the new account sign the creation; the signers are merely recorded.
+	assert.Equal(t, uint8(2), tx.Message.Header.NumRequiredSignatures)
+	for _, signer := range signers {
+		isSigner := false
+		for i, key := range tx.Message.AccountKeys {
+			if key == signer {
+				isSigner = tx.Message.IsSigner(tx.Message.AccountKeys[i])
+			}
+		}
+		assert.False(t, isSigner, signer)
+	}
+}
+
+func TestAddMultisig(t *testing.T) {
+	files := newMemFiles()
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		Wallets:  map[string]Wallet{"main": {PublicKey: solana.NewWallet().PublicKey().String()}},
+		Contacts: map[string]string{"mum": solana.NewWallet().PublicKey().String()},
+	})))
+	var changes []KeystoreChange
+	keyOps := &KeyOps{FileReader: files, FileWriter: files, OnChange: func(_ string, c []KeystoreChange) { changes = c }}
+
+	multisig := Multisig{
+		Alias:     "treasury",
+		Address:   solana.NewWallet().PublicKey().String(),
+		Signers:   []string{solana.NewWallet().PublicKey().String(), solana.NewWallet().PublicKey().String()},
+		Threshold: 2,
+	}
+	assert.NoError(t, keyOps.AddMultisig(multisig))
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "multisig treasury", changes[0].Field)
+		assert.Empty(t, changes[0].From)
+		assert.True(t, strings.HasPrefix(changes[0].To, "2-of-2 "+multisig.Address))
+	}
+
+	multisigs, err := keyOps.Multisigs()
+	assert.NoError(t, err)
+	assert.Equal(t, []Multisig{multisig}, multisigs)
+
+	for _, name := range []string{"main", "mum", "treasury"} {
+		clash := multisig
+		clash.Alias = name
+		assert.Error(t, keyOps.AddMultisig(clash), name)
+	}
+	assert.EqualError(t, keyOps.AddContact("treasury", multisig.Address), "treasury is already the name of a multisig")
+
+	invalid := multisig
+	invalid.Alias, invalid.Threshold = "vault", 3
+	assert.True(t, errors.Is(keyOps.AddMultisig(invalid), ErrInvalidMultisig))
+}
+
+// multisigCluster is a devnet on which a multisig holds USDC and which records broadcast transactions.
+type multisigCluster struct {
+	t         *testing.T
+	multisig  solana.PublicKey
+	height    uint64
+	broadcast []*solana.Transaction
+}
+
+func (c *multisigCluster) client() *MockClientInterface {
+	source, _, _ := solana.FindAssociatedTokenAddress(c.multisig, usdcMint)
+	fee := uint64(5000)
+	return &MockClientInterface{
+		GetAccountInfoFn: func(_ context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+			if account.Equals(source) {
+				return &rpc.GetAccountInfoResult{Value: &rpc.Account{Data: encodeTokenAccount(c.t, usdcMint, c.multisig, 5_000_000)}}, nil
+			}
+			return &rpc.GetAccountInfoResult{Value: &rpc.Account{Data: encodeTokenAccount(c.t, usdcMint, account, 0)}}, nil
+		},
+		GetBalanceFn: func(context.Context, solana.PublicKey, rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+			return &rpc.GetBalanceResult{Value: 1_000_000_000}, nil
+		},
+		GetLatestBlockhashFn: func(context.Context, rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+			return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}, LastValidBlockHeight: 150}}, nil
+		},
+		GetFeeForMessageFn: func(context.Context, string, rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+			return &rpc.GetFeeForMessageResult{Value: &fee}, nil
+		},
+		GetBlockHeightFn: func(context.Context, rpc.CommitmentType) (uint64, error) {
+			return c.height, nil
+		},
+		SendTransactionFn: func(_ context.Context, tx *solana.Transaction) (solana.Signature, error) {
+			c.broadcast = append(c.broadcast, tx)
+			return tx.Signatures[0], nil
+		},
+		GetSignatureStatusesFn: func(context.Context, bool, ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+			return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}}}, nil
+		},
+	}
+}
+
+func TestMultisigPartialSignatureRoundTrip(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	alice, bob, carol := solana.NewWallet(), solana.NewWallet(), solana.NewWallet()
+	recipient := solana.NewWallet().PublicKey()
+	cluster := &multisigCluster{t: t, multisig: solana.NewWallet().PublicKey(), height: 100}
+	newRPCClient = func(string) ClientInterface { return cluster.client() }
+
+	files := newMemFiles()
+	keyOps := &KeyOps{FileReader: files, FileWriter: files}
+	assert.NoError(t, keyOps.AddMultisig(Multisig{
+		Alias:     "treasury",
+		Address:   cluster.multisig.String(),
+		Signers:   []string{alice.PublicKey().String(), bob.PublicKey().String(), carol.PublicKey().String()},
+		Threshold: 2,
+	}))
+	signer := func(w *solana.Wallet) *WalletConfig { return &WalletConfig{Wallet: w, Network: Devnet, KeyOps: keyOps} }
+
+	quote, err := signer(bob).PrepareMultisigTokenSend(context.Background(), "treasury", "usdc", "1.5", recipient.String())
+	assert.NoError(t, err)
+	assert.Equal(t, cluster.multisig, quote.Owner)
+	assert.Equal(t, bob.PublicKey(), quote.Payer)
+	assert.Equal(t, []solana.PublicKey{bob.PublicKey(), alice.PublicKey()}, quote.Signers)
+
+	partial, err := signer(bob).ProposeMultisigSend(context.Background(), quote, "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(150), partial.LastValidBlockHeight)
+
+	// The file survives being written and read back with the proposer's signature intact.
+	encoded, err := partial.Encode()
+	assert.NoError(t, err)
+	parsed, err := ParsePartialTransaction(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, partial, parsed)
+	missing, err := parsed.Missing()
+	assert.NoError(t, err)
+	assert.Equal(t, []solana.PublicKey{alice.PublicKey()}, missing)
+
+	// Carol is a signer of the multisig, but not one of the approvers the transaction was built for.
+	_, err = signer(carol).CosignTransaction(context.Background(), parsed)
+	assert.True(t, errors.Is(err, ErrNotMultisigSigner))
+	_, err = signer(bob).CosignTransaction(context.Background(), parsed)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "has already signed this transaction")
+	}
+	_, err = (&WalletConfig{Wallet: alice, Network: MainnetBeta}).CosignTransaction(context.Background(), parsed)
+	if assert.Error(t, err) {
+		assert.Equal(t, "the transaction was proposed on devnet, but mainnet-beta is selected", err.Error())
+	}
+	assert.Empty(t, cluster.broadcast)
+
+	sig, err := signer(alice).CosignTransaction(context.Background(), parsed)
+	assert.NoError(t, err)
+	if assert.Len(t, cluster.broadcast, 1) {
+		tx := cluster.broadcast[0]
+		assert.Equal(t, tx.Signatures[0].String(), sig)
+		assert.NoError(t, tx.VerifySignatures())
+	}
+	missing, err = parsed.Missing()
+	assert.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func TestParsePartialTransactionRejectsTampering(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	alice, bob := solana.NewWallet(), solana.NewWallet()
+	cluster := &multisigCluster{t: t, multisig: solana.NewWallet().PublicKey(), height: 200}
+	newRPCClient = func(string) ClientInterface { return cluster.client() }
+
+	files := newMemFiles()
+	keyOps := &KeyOps{FileReader: files, FileWriter: files}
+	assert.NoError(t, keyOps.AddMultisig(Multisig{Alias: "treasury", Address: cluster.multisig.String(), Signers: []string{alice.PublicKey().String(), bob.PublicKey().String()}, Threshold: 2}))
+	wc := &WalletConfig{Wallet: alice, Network: Devnet, KeyOps: keyOps}
+
+	quote, err := wc.PrepareMultisigTokenSend(context.Background(), "treasury", "usdc", "1", solana.NewWallet().PublicKey().String())
+	assert.NoError(t, err)
+	partial, err := wc.ProposeMultisigSend(context.Background(), quote, "")
+	assert.NoError(t, err)
+
+	// Changing the transaction after alice signed invalidates her signature.
+	tx, err := partial.decode()
+	assert.NoError(t, err)
+	tx.Message.RecentBlockhash = solana.Hash{2}
+	tampered := *partial
+	assert.NoError(t, tampered.setTransaction(tx))
+	encoded, err := tampered.Encode()
+	assert.NoError(t, err)
+	_, err = ParsePartialTransaction(encoded)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "the signature of "+alice.PublicKey().String()+" does not match the transaction")
+	}
+
+	// Bob signs after the blockhash expired: nothing is broadcast.
+	_, err = (&WalletConfig{Wallet: bob, Network: Devnet}).CosignTransaction(context.Background(), partial)
+	assert.True(t, errors.Is(err, ErrBlockhashExpired))
+	assert.Empty(t, cluster.broadcast)
+}
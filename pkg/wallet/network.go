@@ -0,0 +1,146 @@
+package wallet
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Network identifies the Solana cluster the wallet talks to.
+type Network string
+
+const (
+	Devnet      Network = "devnet"
+	Testnet     Network = "testnet"
+	MainnetBeta Network = "mainnet-beta"
+	// Localnet is a solana-test-validator on this machine, at its default ports.
+	Localnet Network = "localnet"
+	// Custom is used when only an RPC URL override is given.
+	Custom Network = "custom"
+)
+
+// DefaultNetwork is used when nothing has been selected or persisted yet.
+const DefaultNetwork = Devnet
+
+// knownClusters maps the selectable networks to their public endpoints.
+var knownClusters = map[Network]rpc.Cluster{
+	Devnet:      rpc.DevNet,
+	Testnet:     rpc.TestNet,
+	MainnetBeta: rpc.MainNetBeta,
+	Localnet:    rpc.LocalNet,
+}
+
+// networkAliases are other names accepted for networks, such as the monikers of the Solana CLI.
+var networkAliases = map[string]Network{
+	"localhost": Localnet,
+	"mainnet":   MainnetBeta,
+}
+
+// ParseNetwork validates a network name given on the command line or read from the key file.
+func ParseNetwork(name string) (Network, error) {
+	n := Network(strings.ToLower(strings.TrimSpace(name)))
+	if alias, ok := networkAliases[string(n)]; ok {
+		n = alias
+	}
+	if _, ok := knownClusters[n]; ok {
+		return n, nil
+	}
+	if n == Custom {
+		return n, nil
+	}
+	return "", fmt.Errorf("unknown network %q, expected one of: devnet, testnet, mainnet-beta, localnet", name)
+}
+
+// Endpoints holds the matching RPC and websocket URLs for a cluster.
+type Endpoints struct {
+	RPC string
+	WS  string
+	// Fallbacks are further RPC URLs, in order of preference, used when RPC is unhealthy or unreachable.
+	Fallbacks []string
+}
+
+// resolveEndpoints returns the endpoints for a network, preferring the RPC URL override when set.
+// The override may be a comma-separated list of URLs: the first is the primary endpoint and the
+// others are its fallbacks. The websocket URL is always derived from the same host as the primary
+// RPC URL so the two never diverge.
+func resolveEndpoints(network Network, rpcURL string) (Endpoints, error) {
+	if rpcURL != "" {
+		urls := splitRPCURLs(rpcURL)
+		if len(urls) == 0 {
+			return Endpoints{}, fmt.Errorf("invalid RPC URL %q", rpcURL)
+		}
+		for _, u := range urls[1:] {
+			if _, err := wsURLFromRPC(u); err != nil {
+				return Endpoints{}, err
+			}
+		}
+		ws, err := wsURLFromRPC(urls[0])
+		if err != nil {
+			return Endpoints{}, err
+		}
+		endpoints := Endpoints{RPC: urls[0], WS: ws}
+		if len(urls) > 1 {
+			endpoints.Fallbacks = urls[1:]
+		}
+		return endpoints, nil
+	}
+
+	cluster, ok := knownClusters[network]
+	if !ok {
+		return Endpoints{}, fmt.Errorf("network %q requires an RPC URL", network)
+	}
+
+	return Endpoints{RPC: cluster.RPC, WS: cluster.WS}, nil
+}
+
+// ValidateRPCURL checks an RPC URL, or a comma-separated list of them, as accepted by --rpc-url.
+func ValidateRPCURL(rpcURL string) error {
+	_, err := resolveEndpoints(Custom, rpcURL)
+	return err
+}
+
+// splitRPCURLs splits a comma-separated list of RPC URLs, dropping empty entries and repeats.
+func splitRPCURLs(rpcURL string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, u := range strings.Split(rpcURL, ",") {
+		u = strings.TrimSpace(u)
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// wsURLFromRPC derives the websocket endpoint for an RPC URL the same way the Solana CLI does:
+// http becomes ws, https becomes wss, and an explicit port is bumped by one.
+func wsURLFromRPC(rpcURL string) (string, error) {
+	u, err := url.Parse(rpcURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid RPC URL %q: %w", rpcURL, err)
+	}
+
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("invalid RPC URL %q: scheme must be http or https", rpcURL)
+	}
+
+	if port := u.Port(); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return "", fmt.Errorf("invalid RPC URL %q: %w", rpcURL, err)
+		}
+		u.Host = u.Hostname() + ":" + strconv.Itoa(p+1)
+	}
+
+	return u.String(), nil
+}
@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNetwork(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    Network
+		expectedErr bool
+	}{
+		{name: "Devnet", input: "devnet", expected: Devnet},
+		{name: "Mainnet Mixed Case", input: "Mainnet-Beta", expected: MainnetBeta},
+		{name: "Testnet With Spaces", input: " testnet ", expected: Testnet},
+		{name: "Localnet", input: "localnet", expected: Localnet},
+		{name: "CLI Moniker", input: "localhost", expected: Localnet},
+		{name: "Unknown", input: "moonnet", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNetwork(tt.input)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestResolveEndpoints(t *testing.T) {
+	tests := []struct {
+		name        string
+		network     Network
+		rpcURL      string
+		expected    Endpoints
+		expectedErr bool
+	}{
+		{
+			name:     "Known Cluster",
+			network:  MainnetBeta,
+			expected: Endpoints{RPC: "https://api.mainnet-beta.solana.com", WS: "wss://api.mainnet-beta.solana.com"},
+		},
+		{
+			name:     "Localnet",
+			network:  Localnet,
+			expected: Endpoints{RPC: "http://127.0.0.1:8899", WS: "ws://127.0.0.1:8900"},
+		},
+		{
+			name:     "Custom HTTPS",
+			network:  Custom,
+			rpcURL:   "https://rpc.example.com/key",
+			expected: Endpoints{RPC: "https://rpc.example.com/key", WS: "wss://rpc.example.com/key"},
+		},
+		{
+			name:     "Custom Local Validator",
+			network:  Custom,
+			rpcURL:   "http://127.0.0.1:8899",
+			expected: Endpoints{RPC: "http://127.0.0.1:8899", WS: "ws://127.0.0.1:8900"},
+		},
+		{
+			name:     "Fallback List",
+			network:  MainnetBeta,
+			rpcURL:   "https://rpc.example.com, https://backup.example.com,,https://rpc.example.com",
+			expected: Endpoints{RPC: "https://rpc.example.com", WS: "wss://rpc.example.com", Fallbacks: []string{"https://backup.example.com"}},
+		},
+		{
+			name:        "Bad Fallback",
+			network:     Custom,
+			rpcURL:      "https://rpc.example.com,backup.example.com",
+			expectedErr: true,
+		},
+		{
+			name:        "Custom Without URL",
+			network:     Custom,
+			expectedErr: true,
+		},
+		{
+			name:        "Bad Scheme",
+			network:     Custom,
+			rpcURL:      "ftp://example.com",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveEndpoints(tt.network, tt.rpcURL)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
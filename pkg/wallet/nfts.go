@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// NFT is a Metaplex NFT held by a wallet.
+type NFT struct {
+	Mint    string
+	Account string
+	Name    string
+	Symbol  string
+	URI     string
+	// Collection is the mint of the collection NFT, empty when the NFT names none.
+	Collection         string
+	CollectionVerified bool
+}
+
+// GetNFTs lists the Metaplex NFTs held by a wallet. An empty alias means the active wallet.
+func (w *WalletConfig) GetNFTs(alias string) ([]NFT, error) {
+	owner, err := w.ownerPublicKey(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	return ownedNFTs(context.TODO(), endpoints.client(), owner)
+}
+
+// ownedNFTs lists the NFTs of owner, sorted by collection, name and mint. A token account holding one
+// token of a mint without decimals is an NFT candidate; it is listed when its mint has a Metaplex
+// metadata account that does not describe a fungible token. The metadata accounts are read in one
+// round trip.
+func ownedNFTs(ctx context.Context, client ClientInterface, owner solana.PublicKey) ([]NFT, error) {
+	balances, err := tokenBalances(ctx, client, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []TokenBalance
+	var infos []*rpc.GetAccountInfoResult
+	var calls []*batchCall
+	for _, balance := range balances {
+		if balance.Decimals != 0 || balance.RawAmount != 1 {
+			continue
+		}
+		address, err := MetadataAddress(solana.MustPublicKeyFromBase58(balance.Mint))
+		if err != nil {
+			return nil, fmt.Errorf("metadata address of %s: %w", balance.Mint, err)
+		}
+		info := &rpc.GetAccountInfoResult{}
+		candidates = append(candidates, balance)
+		infos = append(infos, info)
+		calls = append(calls, getAccountInfoCall(info, address))
+	}
+
+	batchRead(ctx, client, calls...)
+	nfts := make([]NFT, 0, len(candidates))
+	for i, candidate := range candidates {
+		if errors.Is(calls[i].err, rpc.ErrNotFound) {
+			continue
+		}
+		if calls[i].err != nil {
+			return nil, fmt.Errorf("get metadata of %s: %w", candidate.Mint, calls[i].err)
+		}
+
+		metadata, err := DecodeMetaplexMetadata(infos[i].Value.Data.GetBinary())
+		if err != nil {
+			return nil, fmt.Errorf("decode metadata of %s: %w", candidate.Mint, err)
+		}
+		if metadata.IsFungible() {
+			continue
+		}
+
+		nft := NFT{
+			Mint:    candidate.Mint,
+			Account: candidate.Account,
+			Name:    metadata.Name,
+			Symbol:  metadata.Symbol,
+			URI:     metadata.URI,
+		}
+		if metadata.Collection != nil {
+			nft.Collection = metadata.Collection.Key.String()
+			nft.CollectionVerified = metadata.Collection.Verified
+		}
+		nfts = append(nfts, nft)
+	}
+
+	sort.Slice(nfts, func(i, j int) bool {
+		if nfts[i].Collection != nfts[j].Collection {
+			return nfts[i].Collection < nfts[j].Collection
+		}
+		if nfts[i].Name != nfts[j].Name {
+			return nfts[i].Name < nfts[j].Name
+		}
+		return nfts[i].Mint < nfts[j].Mint
+	})
+	return nfts, nil
+}
@@ -0,0 +1,80 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNFTs(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	owner := solana.NewWallet()
+	collection := solana.NewWallet().PublicKey()
+	nonFungible, fungible := TokenStandardNonFungible, TokenStandardFungibleAsset
+
+	// Each mint has no decimals and one token in the wallet, so only its metadata decides.
+	metadata := map[string]*MetaplexMetadata{
+		"lad":    {Name: "Mad Lad #8420", Symbol: "MAD", URI: "https://example.com/8420.json", TokenStandard: &nonFungible, Collection: &MetaplexCollection{Verified: true, Key: collection}},
+		"legacy": {Name: "Degen Ape #1", Symbol: "DAPE", URI: "https://example.com/1.json"},
+		"asset":  {Name: "Game Gold", TokenStandard: &fungible},
+		"bare":   nil,
+	}
+	mints := make(map[string]solana.PublicKey)
+	accounts := make(map[solana.PublicKey]*rpc.GetAccountInfoResult)
+	var tokenAccounts []*rpc.TokenAccount
+	for name, m := range metadata {
+		mint := solana.NewWallet().PublicKey()
+		mints[name] = mint
+
+		var buf bytes.Buffer
+		assert.NoError(t, (&token.Mint{Supply: 1, IsInitialized: true}).MarshalWithEncoder(bin.NewBinEncoder(&buf)))
+		accounts[mint] = &rpc.GetAccountInfoResult{Value: &rpc.Account{Data: rpc.DataBytesOrJSONFromBytes(buf.Bytes())}}
+		if m != nil {
+			m.Mint = mint
+			address, err := MetadataAddress(mint)
+			assert.NoError(t, err)
+			accounts[address] = &rpc.GetAccountInfoResult{Value: &rpc.Account{Data: rpc.DataBytesOrJSONFromBytes(encodeMetadata(t, *m))}}
+		}
+		tokenAccounts = append(tokenAccounts, &rpc.TokenAccount{
+			Pubkey:  solana.NewWallet().PublicKey(),
+			Account: rpc.Account{Data: encodeTokenAccount(t, mint, owner.PublicKey(), 1)},
+		})
+	}
+	// A fungible token balance is not even looked up.
+	tokenAccounts = append(tokenAccounts, &rpc.TokenAccount{
+		Pubkey:  solana.NewWallet().PublicKey(),
+		Account: rpc.Account{Data: encodeTokenAccount(t, usdcMint, owner.PublicKey(), 1)},
+	})
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetTokenAccountsByOwnerFn: func(context.Context, solana.PublicKey, *rpc.GetTokenAccountsConfig, *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+				return &rpc.GetTokenAccountsResult{Value: tokenAccounts}, nil
+			},
+			GetAccountInfoFn: func(_ context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+				if info, ok := accounts[account]; ok {
+					return info, nil
+				}
+				return nil, rpc.ErrNotFound
+			},
+		}
+	}
+
+	wc := &WalletConfig{Wallet: owner, Network: Devnet}
+	nfts, err := wc.GetNFTs("")
+	assert.NoError(t, err)
+	if assert.Len(t, nfts, 2) {
+		assert.Equal(t, NFT{Mint: mints["legacy"].String(), Account: nfts[0].Account, Name: "Degen Ape #1", Symbol: "DAPE", URI: "https://example.com/1.json"}, nfts[0])
+		assert.Equal(t, mints["lad"].String(), nfts[1].Mint)
+		assert.Equal(t, "Mad Lad #8420", nfts[1].Name)
+		assert.Equal(t, collection.String(), nfts[1].Collection)
+		assert.True(t, nfts[1].CollectionVerified)
+	}
+}
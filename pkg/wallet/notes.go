@@ -0,0 +1,88 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxNoteLength caps wallet notes, in characters, so listings stay on one line.
+	MaxNoteLength = 80
+	// maxIconBytes leaves room for emoji built from several code points, like flags or skin tones.
+	maxIconBytes = 32
+)
+
+var (
+	ErrNoteTooLong = fmt.Errorf("note is longer than %d characters", MaxNoteLength)
+	ErrInvalidIcon = errors.New("icon must be a single emoji or symbol without spaces")
+)
+
+// WalletNote is a free-text note and an optional emoji attached to a stored wallet.
+type WalletNote struct {
+	Text string
+	Icon string
+}
+
+// Label returns the icon and note as shown next to the alias in listings.
+func (n WalletNote) Label() string {
+	return strings.TrimSpace(n.Icon + " " + n.Text)
+}
+
+// normalize trims the note and checks it against the length and character limits.
+func (n WalletNote) normalize() (WalletNote, error) {
+	n.Text = strings.TrimSpace(n.Text)
+	n.Icon = strings.TrimSpace(n.Icon)
+
+	if utf8.RuneCountInString(n.Text) > MaxNoteLength {
+		return n, ErrNoteTooLong
+	}
+	if strings.IndexFunc(n.Text, unicode.IsControl) >= 0 {
+		return n, errors.New("note must be a single line")
+	}
+
+	if len(n.Icon) > maxIconBytes || strings.IndexFunc(n.Icon, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r) || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) >= 0 {
+		return n, ErrInvalidIcon
+	}
+
+	return n, nil
+}
+
+// SetWalletNote replaces the note and icon of a stored wallet. Empty values clear them.
+func (k *KeyOps) SetWalletNote(alias string, note WalletNote) error {
+	note, err := note.normalize()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return err
+	}
+
+	wallet, exists := data.Wallets[alias]
+	if !exists {
+		return fmt.Errorf("no wallet found for alias: %s", alias)
+	}
+
+	wallet.Note = note.Text
+	wallet.Icon = note.Icon
+	data.Wallets[alias] = wallet
+
+	return k.writeWalletData("note", data)
+}
+
+// SetWalletNote replaces the note and icon of a stored wallet. Empty values clear them.
+func (w *WalletConfig) SetWalletNote(alias string, note WalletNote) error {
+	return w.KeyOps.SetWalletNote(alias, note)
+}
@@ -0,0 +1,60 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetWalletNote(t *testing.T) {
+	tests := []struct {
+		name         string
+		note         WalletNote
+		expected     WalletNote
+		expectedErr  error
+		expectAnyErr bool
+	}{
+		{name: "Note And Icon", note: WalletNote{Text: "  client escrow — do not touch ", Icon: "🏦"}, expected: WalletNote{Text: "client escrow — do not touch", Icon: "🏦"}},
+		{name: "Flag Icon", note: WalletNote{Icon: "🇩🇪"}, expected: WalletNote{Icon: "🇩🇪"}},
+		{name: "Clear", note: WalletNote{}, expected: WalletNote{}},
+		{name: "Longest Note", note: WalletNote{Text: strings.Repeat("é", MaxNoteLength)}, expected: WalletNote{Text: strings.Repeat("é", MaxNoteLength)}},
+		{name: "Too Long", note: WalletNote{Text: strings.Repeat("a", MaxNoteLength+1)}, expectedErr: ErrNoteTooLong},
+		{name: "Icon With Text", note: WalletNote{Icon: "bank"}, expectedErr: ErrInvalidIcon},
+		{name: "Multi Line Note", note: WalletNote{Text: "first\nsecond"}, expectAnyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			ops := &KeyOps{FileReader: files, FileWriter: files}
+			assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+				ActiveAlias: "main",
+				Wallets:     map[string]Wallet{"main": {PublicKey: "address", Note: "old", Icon: "⭐"}},
+			})))
+
+			err := ops.SetWalletNote("main", tt.note)
+			if tt.expectAnyErr {
+				assert.Error(t, err)
+				return
+			}
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			info, err := ops.GetWalletInfo("main")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, info.Note)
+		})
+	}
+}
+
+func TestSetWalletNoteUnknownAlias(t *testing.T) {
+	files := newMemFiles()
+	ops := &KeyOps{FileReader: files, FileWriter: files}
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{Wallets: map[string]Wallet{}})))
+
+	assert.Error(t, ops.SetWalletNote("missing", WalletNote{Text: "note"}))
+}
@@ -0,0 +1,515 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/singleflight"
+)
+
+// OHLCPairSOLEUR is the Kraken pair used for SOL/EUR candles.
+const OHLCPairSOLEUR = "SOLEUR"
+
+// maxCachedCandles bounds how many candles are kept per pair and interval; the oldest are evicted first.
+const maxCachedCandles = 10000
+
+// maxHistoryCandles is how many candles Kraken returns for one OHLC request; a history range is
+// split into at most this many candles so that one request fills it.
+const maxHistoryCandles = 720
+
+// maxHistoryRange is the longest range RateHistory serves.
+const maxHistoryRange = 365 * 24 * time.Hour
+
+// krakenIntervals are the candle lengths in minutes the Kraken OHLC endpoint offers.
+var krakenIntervals = []int{1, 5, 15, 30, 60, 240, 1440, 10080, 21600}
+
+// ohlcRetries is how often a rate-limited or failing OHLC request is retried.
+const ohlcRetries = 3
+
+var ErrCandleNotFound = errors.New("no candle found for the requested time")
+
+// ohlcRetryDelay is the delay before the first retry; it doubles with every attempt.
+var ohlcRetryDelay = time.Second
+
+// Candle is one OHLC candle. Time is the unix time the candle opened at.
+type Candle struct {
+	Time  int64           `json:"time"`
+	Open  decimal.Decimal `json:"open"`
+	High  decimal.Decimal `json:"high"`
+	Low   decimal.Decimal `json:"low"`
+	Close decimal.Decimal `json:"close"`
+}
+
+// OHLCFetcher fetches candles of a pair and interval (in minutes) starting after since.
+type OHLCFetcher func(pair string, interval int, since int64) ([]Candle, error)
+
+// CacheStats counts how an OHLCCache served its lookups.
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Fetches   int64
+	Coalesced int64
+	Corrupted int64
+}
+
+// OHLCCache keeps closed candles on disk, since they never change once the candle has closed.
+// The still-open candle is never stored and always fetched again. Concurrent lookups of the same
+// candle share a single request.
+type OHLCCache struct {
+	FileWriter FileWriter
+	Dir        string
+	Fetch      OHLCFetcher
+	// Now returns the current time; it is a field so tests can decide which candle is still open.
+	Now func() time.Time
+
+	mu    sync.Mutex
+	group singleflight.Group
+	stats CacheStats
+}
+
+// NewOHLCCache creates a cache in dir that fetches missing candles from Kraken.
+func NewOHLCCache(dir string) *OHLCCache {
+	return &OHLCCache{FileWriter: newFileWriter(), Dir: dir, Fetch: fetchKrakenOHLC, Now: time.Now}
+}
+
+var (
+	sharedOHLCCacheOnce sync.Once
+	sharedOHLCCache     *OHLCCache
+)
+
+// defaultOHLCCache returns the process-wide cache kept under the configuration directory.
+func defaultOHLCCache() *OHLCCache {
+	sharedOHLCCacheOnce.Do(func() {
+		dir, err := ConfigDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+		sharedOHLCCache = NewOHLCCache(filepath.Join(dir, "cache", "ohlc"))
+	})
+	return sharedOHLCCache
+}
+
+// OHLCStats returns the counters of the process-wide OHLC cache.
+func OHLCStats() CacheStats {
+	return defaultOHLCCache().Stats()
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *OHLCCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:      atomic.LoadInt64(&c.stats.Hits),
+		Misses:    atomic.LoadInt64(&c.stats.Misses),
+		Fetches:   atomic.LoadInt64(&c.stats.Fetches),
+		Coalesced: atomic.LoadInt64(&c.stats.Coalesced),
+		Corrupted: atomic.LoadInt64(&c.stats.Corrupted),
+	}
+}
+
+// CandleAt returns the candle of a pair and interval (in minutes) that contains t.
+func (c *OHLCCache) CandleAt(pair string, interval int, t time.Time) (Candle, error) {
+	if interval <= 0 {
+		return Candle{}, fmt.Errorf("invalid OHLC interval %d", interval)
+	}
+
+	step := int64(interval) * 60
+	openTime := t.Unix() - t.Unix()%step
+
+	if candle, ok := c.cached(pair, interval, openTime); ok {
+		atomic.AddInt64(&c.stats.Hits, 1)
+		return candle, nil
+	}
+	atomic.AddInt64(&c.stats.Misses, 1)
+
+	key := fmt.Sprintf("%s/%d/%d", pair, interval, openTime)
+	result, err, shared := c.group.Do(key, func() (interface{}, error) {
+		return c.fetchAndStore(pair, interval, openTime)
+	})
+	if shared {
+		atomic.AddInt64(&c.stats.Coalesced, 1)
+	}
+	if err != nil {
+		return Candle{}, err
+	}
+
+	return result.(Candle), nil
+}
+
+// fetchAndStore fetches the candles from openTime onwards, stores the closed ones and returns the requested one.
+func (c *OHLCCache) fetchAndStore(pair string, interval int, openTime int64) (Candle, error) {
+	candles, err := c.fetchCandles(pair, interval, openTime)
+	if err != nil {
+		return Candle{}, err
+	}
+
+	var wanted *Candle
+	for i := range candles {
+		if candles[i].Time == openTime {
+			wanted = &candles[i]
+		}
+	}
+
+	if wanted == nil {
+		return Candle{}, fmt.Errorf("%w: %s at %s", ErrCandleNotFound, pair, time.Unix(openTime, 0).UTC().Format(time.RFC3339))
+	}
+
+	return *wanted, nil
+}
+
+// fetchCandles fetches the candles from openTime onwards and stores the closed ones.
+func (c *OHLCCache) fetchCandles(pair string, interval int, openTime int64) ([]Candle, error) {
+	atomic.AddInt64(&c.stats.Fetches, 1)
+
+	// Kraken returns candles strictly after since, so ask from just before the wanted candle.
+	candles, err := c.Fetch(pair, interval, openTime-1)
+	if err != nil {
+		return nil, err
+	}
+
+	step := int64(interval) * 60
+	now := c.Now().Unix()
+
+	closed := make([]Candle, 0, len(candles))
+	for _, candle := range candles {
+		if candle.Time+step <= now {
+			closed = append(closed, candle)
+		}
+	}
+
+	if err := c.store(pair, interval, closed); err != nil {
+		return nil, err
+	}
+
+	return candles, nil
+}
+
+// Range returns the closed candles of a pair and interval (in minutes) that opened between from
+// and to, oldest first. Once a range has been fetched it is served from disk; the candles missing
+// from the cache are fetched with a single request that concurrent callers share. Candles the
+// exchange has no data for are left out.
+func (c *OHLCCache) Range(pair string, interval int, from, to time.Time) ([]Candle, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid OHLC interval %d", interval)
+	}
+
+	step := int64(interval) * 60
+	first := from.Unix() - from.Unix()%step
+	// The still-open candle changes until it closes, so the range ends with the last closed one.
+	now := c.Now().Unix()
+	last := to.Unix() - to.Unix()%step
+	if open := now - now%step; last >= open {
+		last = open - step
+	}
+
+	c.mu.Lock()
+	stored := c.read(pair, interval)
+	c.mu.Unlock()
+
+	candles := make([]Candle, 0, maxHistoryCandles)
+	missing := int64(-1)
+	for openTime := first; openTime <= last; openTime += step {
+		candle, ok := stored[strconv.FormatInt(openTime, 10)]
+		if !ok {
+			missing = openTime
+			break
+		}
+		candles = append(candles, candle)
+	}
+
+	if missing < 0 {
+		atomic.AddInt64(&c.stats.Hits, 1)
+		return candles, nil
+	}
+	atomic.AddInt64(&c.stats.Misses, 1)
+
+	key := fmt.Sprintf("%s/%d/%d-", pair, interval, missing)
+	result, err, shared := c.group.Do(key, func() (interface{}, error) {
+		return c.fetchCandles(pair, interval, missing)
+	})
+	if shared {
+		atomic.AddInt64(&c.stats.Coalesced, 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candle := range result.([]Candle) {
+		if candle.Time >= missing && candle.Time <= last {
+			candles = append(candles, candle)
+		}
+	}
+	return candles, nil
+}
+
+// RateHistory is the price of SOL in a currency over a range, one candle per Interval.
+type RateHistory struct {
+	Currency Currency
+	Interval time.Duration
+	Candles  []Candle
+}
+
+// History returns the SOL price in currency over the span up to now, in the shortest candles
+// that fit the span into one Kraken request. Currencies Kraken does not quote have no history.
+func (c *OHLCCache) History(currency Currency, span time.Duration) (RateHistory, error) {
+	if currency.Pair() == "" {
+		return RateHistory{}, fmt.Errorf("%w: no price history in %s, which Kraken does not quote", ErrRateUnavailable, currency)
+	}
+	interval := historyInterval(span)
+	now := c.Now()
+
+	candles, err := c.Range(currency.Pair(), interval, now.Add(-span), now)
+	if err != nil {
+		return RateHistory{}, err
+	}
+
+	return RateHistory{Currency: currency.orDefault(), Interval: time.Duration(interval) * time.Minute, Candles: candles}, nil
+}
+
+// historyInterval returns the shortest Kraken interval (in minutes) that covers span in at most
+// maxHistoryCandles candles.
+func historyInterval(span time.Duration) int {
+	for _, interval := range krakenIntervals {
+		if span <= time.Duration(interval*maxHistoryCandles)*time.Minute {
+			return interval
+		}
+	}
+	return krakenIntervals[len(krakenIntervals)-1]
+}
+
+// ParseRateRange parses a history range such as 24h, 7d or 2w: a duration as understood by
+// time.ParseDuration, or a number of days or weeks.
+func ParseRateRange(value string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid range %q: use a duration such as 24h, 7d or 2w", value)
+
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(value, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(value, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+
+	var span time.Duration
+	if unit > 0 {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil {
+			return 0, invalid
+		}
+		span = time.Duration(n) * unit
+	} else {
+		var err error
+		if span, err = time.ParseDuration(value); err != nil {
+			return 0, invalid
+		}
+	}
+
+	if span <= 0 || span > maxHistoryRange {
+		return 0, fmt.Errorf("invalid range %q: must be positive and at most 365d", value)
+	}
+	return span, nil
+}
+
+// RateHistory returns the SOL price in the selected currency over the span up to now, from the
+// candle cache shared by every command.
+func (w *WalletConfig) RateHistory(span time.Duration) (RateHistory, error) {
+	return defaultOHLCCache().History(w.FiatCurrency(), span)
+}
+
+// filePath returns the file holding the candles of a pair and interval.
+func (c *OHLCCache) filePath(pair string, interval int) string {
+	return filepath.Join(c.Dir, fmt.Sprintf("%s-%d.json", pair, interval))
+}
+
+// cached looks up a stored candle.
+func (c *OHLCCache) cached(pair string, interval int, openTime int64) (Candle, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	candles := c.read(pair, interval)
+	candle, ok := candles[strconv.FormatInt(openTime, 10)]
+	return candle, ok
+}
+
+// read loads the stored candles of a pair and interval. A corrupted file is discarded so that it
+// gets rebuilt from fresh fetches instead of failing every lookup. The caller must hold c.mu.
+func (c *OHLCCache) read(pair string, interval int) map[string]Candle {
+	candles := make(map[string]Candle)
+
+	fileData, err := os.ReadFile(c.filePath(pair, interval))
+	if err != nil {
+		return candles
+	}
+
+	if err := json.Unmarshal(fileData, &candles); err != nil {
+		atomic.AddInt64(&c.stats.Corrupted, 1)
+		if !ReadOnlyMode() {
+			_ = os.Remove(c.filePath(pair, interval))
+		}
+		return make(map[string]Candle)
+	}
+
+	return candles
+}
+
+// store merges closed candles into the cache file and evicts the oldest beyond maxCachedCandles.
+func (c *OHLCCache) store(pair string, interval int, closed []Candle) error {
+	// Read-only mode only reads the cache; fetched candles are used but not kept.
+	if len(closed) == 0 || ReadOnlyMode() {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	candles := c.read(pair, interval)
+	for _, candle := range closed {
+		candles[strconv.FormatInt(candle.Time, 10)] = candle
+	}
+
+	if len(candles) > maxCachedCandles {
+		times := make([]int64, 0, len(candles))
+		for _, candle := range candles {
+			times = append(times, candle.Time)
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+		for _, evicted := range times[:len(times)-maxCachedCandles] {
+			delete(candles, strconv.FormatInt(evicted, 10))
+		}
+	}
+
+	updatedData, err := json.Marshal(candles)
+	if err != nil {
+		return fmt.Errorf("error marshaling JSON: %w", err)
+	}
+
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	return c.FileWriter.WriteFile(c.filePath(pair, interval), updatedData)
+}
+
+// krakenOHLCResponse is the response of the Kraken OHLC endpoint. Each candle is an array of
+// time, open, high, low, close, vwap, volume and count.
+type krakenOHLCResponse struct {
+	Error  []string                   `json:"error"`
+	Result map[string]json.RawMessage `json:"result"`
+}
+
+// fetchKrakenOHLC fetches candles from the Kraken OHLC endpoint, retrying when rate limited.
+func fetchKrakenOHLC(pair string, interval int, since int64) ([]Candle, error) {
+	url := fmt.Sprintf("https://api.kraken.com/0/public/OHLC?pair=%s&interval=%d&since=%d", pair, interval, since)
+
+	var lastErr error
+	delay := ohlcRetryDelay
+	for attempt := 0; attempt < ohlcRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		candles, retry, err := requestKrakenOHLC(url, pair)
+		if err == nil {
+			return candles, nil
+		}
+
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+
+	return nil, fmt.Errorf("failed to fetch %s candles: %w", pair, lastErr)
+}
+
+// requestKrakenOHLC performs a single OHLC request and reports whether a failure is worth retrying.
+func requestKrakenOHLC(url, pair string) ([]Candle, bool, error) {
+	resp, err := rateHTTPClient.Get(url)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return nil, true, fmt.Errorf("kraken responded with %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+
+	var response krakenOHLCResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, false, err
+	}
+
+	if len(response.Error) > 0 {
+		// Kraken reports rate limiting in the body as "EAPI:Rate limit exceeded".
+		return nil, true, fmt.Errorf("kraken error: %v", response.Error)
+	}
+
+	raw, ok := response.Result[pair]
+	if !ok {
+		// Kraken sometimes answers with its own pair name, e.g. XSOLZEUR.
+		for name, value := range response.Result {
+			if name != "last" {
+				raw = value
+				break
+			}
+		}
+	}
+
+	candles, err := parseKrakenCandles(raw)
+	return candles, false, err
+}
+
+// parseKrakenCandles decodes Kraken's array-of-arrays candle format.
+func parseKrakenCandles(raw json.RawMessage) ([]Candle, error) {
+	var rows [][]interface{}
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("unexpected data structure from API: %w", err)
+	}
+
+	candles := make([]Candle, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 5 {
+			return nil, errors.New("unexpected data structure from API")
+		}
+
+		openTime, ok := row[0].(float64)
+		if !ok {
+			return nil, errors.New("unexpected candle time from API")
+		}
+
+		candle := Candle{Time: int64(openTime)}
+		for i, field := range []*decimal.Decimal{&candle.Open, &candle.High, &candle.Low, &candle.Close} {
+			value, ok := row[i+1].(string)
+			if !ok {
+				return nil, errors.New("unexpected candle price from API")
+			}
+
+			price, err := decimal.NewFromString(value)
+			if err != nil {
+				return nil, err
+			}
+			*field = price
+		}
+
+		candles = append(candles, candle)
+	}
+
+	return candles, nil
+}
@@ -0,0 +1,257 @@
+package wallet
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// hourlyCandles returns count hourly candles starting at start, closing at their index.
+func hourlyCandles(start int64, count int) []Candle {
+	candles := make([]Candle, 0, count)
+	for i := 0; i < count; i++ {
+		candles = append(candles, Candle{Time: start + int64(i)*3600, Close: decimal.NewFromInt(int64(i))})
+	}
+	return candles
+}
+
+func TestOHLCCacheServesClosedCandlesFromDisk(t *testing.T) {
+	start := int64(1_700_000_000 - 1_700_000_000%3600)
+	var fetches int32
+
+	cache := &OHLCCache{
+		FileWriter: &IOUtilFileWriter{},
+		Dir:        t.TempDir(),
+		Fetch: func(pair string, interval int, since int64) ([]Candle, error) {
+			atomic.AddInt32(&fetches, 1)
+			return hourlyCandles(start, 3), nil
+		},
+		// The third candle is still open.
+		Now: func() time.Time { return time.Unix(start+2*3600+60, 0) },
+	}
+
+	candle, err := cache.CandleAt(OHLCPairSOLEUR, 60, time.Unix(start+1800, 0))
+	assert.NoError(t, err)
+	assert.True(t, decimal.Zero.Equal(candle.Close))
+
+	// The second candle was stored by the first fetch.
+	candle, err = cache.CandleAt(OHLCPairSOLEUR, 60, time.Unix(start+3600, 0))
+	assert.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(1).Equal(candle.Close))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	// The open candle is never stored, so it is fetched every time.
+	_, err = cache.CandleAt(OHLCPairSOLEUR, 60, time.Unix(start+2*3600, 0))
+	assert.NoError(t, err)
+	_, err = cache.CandleAt(OHLCPairSOLEUR, 60, time.Unix(start+2*3600, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fetches))
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(3), stats.Misses)
+}
+
+func TestOHLCCacheEviction(t *testing.T) {
+	start := int64(1_600_000_000 - 1_600_000_000%3600)
+	cache := &OHLCCache{FileWriter: &IOUtilFileWriter{}, Dir: t.TempDir(), Now: time.Now}
+
+	assert.NoError(t, cache.store(OHLCPairSOLEUR, 60, hourlyCandles(start, maxCachedCandles+5)))
+
+	cache.mu.Lock()
+	stored := cache.read(OHLCPairSOLEUR, 60)
+	cache.mu.Unlock()
+
+	assert.Len(t, stored, maxCachedCandles)
+	_, oldestKept := cache.cached(OHLCPairSOLEUR, 60, start)
+	assert.False(t, oldestKept, "the oldest candles are evicted first")
+	_, newestKept := cache.cached(OHLCPairSOLEUR, 60, start+int64(maxCachedCandles+4)*3600)
+	assert.True(t, newestKept)
+}
+
+func TestOHLCCacheWritesThroughFileWriter(t *testing.T) {
+	start := int64(1_600_000_000 - 1_600_000_000%3600)
+	cache := &OHLCCache{FileWriter: ReadOnlyFileWriter{}, Dir: t.TempDir(), Now: time.Now}
+
+	assert.ErrorIs(t, cache.store(OHLCPairSOLEUR, 60, hourlyCandles(start, 2)), ErrReadOnlyMode)
+	entries, err := os.ReadDir(cache.Dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "a refused write leaves no file behind")
+}
+
+func TestOHLCCacheCorruptionRecovery(t *testing.T) {
+	start := int64(1_700_000_000 - 1_700_000_000%3600)
+	cache := &OHLCCache{
+		FileWriter: &IOUtilFileWriter{},
+		Dir:        t.TempDir(),
+		Fetch: func(pair string, interval int, since int64) ([]Candle, error) {
+			return hourlyCandles(start, 1), nil
+		},
+		Now: time.Now,
+	}
+
+	assert.NoError(t, os.WriteFile(cache.filePath(OHLCPairSOLEUR, 60), []byte("{not json"), 0600))
+
+	_, err := cache.CandleAt(OHLCPairSOLEUR, 60, time.Unix(start, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), cache.Stats().Corrupted)
+
+	// The rebuilt file serves the candle again.
+	_, err = cache.CandleAt(OHLCPairSOLEUR, 60, time.Unix(start, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+}
+
+func TestOHLCCacheCoalescesConcurrentLookups(t *testing.T) {
+	start := int64(1_700_000_000 - 1_700_000_000%3600)
+	release := make(chan struct{})
+	var fetches int32
+
+	cache := &OHLCCache{
+		FileWriter: &IOUtilFileWriter{},
+		Dir:        t.TempDir(),
+		Fetch: func(pair string, interval int, since int64) ([]Candle, error) {
+			atomic.AddInt32(&fetches, 1)
+			<-release
+			return hourlyCandles(start, 1), nil
+		},
+		Now: time.Now,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.CandleAt(OHLCPairSOLEUR, 60, time.Unix(start+60, 0))
+			assert.NoError(t, err)
+		}()
+	}
+
+	// Give the lookups time to pile up behind the first fetch before letting it finish.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestParseKrakenCandles(t *testing.T) {
+	candles, err := parseKrakenCandles([]byte(`[[1700000000,"50.1","51.0","49.9","50.5","50.3","12.5",42]]`))
+	assert.NoError(t, err)
+	assert.Len(t, candles, 1)
+	assert.Equal(t, int64(1700000000), candles[0].Time)
+	assert.Equal(t, "50.5", candles[0].Close.String())
+
+	_, err = parseKrakenCandles([]byte(`[[1700000000,"50.1"]]`))
+	assert.Error(t, err)
+}
+
+func TestOHLCCacheRange(t *testing.T) {
+	start := int64(1_700_000_000 - 1_700_000_000%3600)
+	var fetches, since int64
+
+	cache := &OHLCCache{
+		FileWriter: &IOUtilFileWriter{},
+		Dir:        t.TempDir(),
+		Fetch: func(pair string, interval int, from int64) ([]Candle, error) {
+			atomic.AddInt64(&fetches, 1)
+			atomic.StoreInt64(&since, from)
+			var candles []Candle
+			for _, candle := range hourlyCandles(start, 6) {
+				if candle.Time > from {
+					candles = append(candles, candle)
+				}
+			}
+			return candles, nil
+		},
+		// The sixth candle is still open.
+		Now: func() time.Time { return time.Unix(start+5*3600+60, 0) },
+	}
+
+	candles, err := cache.Range(OHLCPairSOLEUR, 60, time.Unix(start+1800, 0), time.Unix(start+5*3600+60, 0))
+	assert.NoError(t, err)
+	assert.Len(t, candles, 5, "the open candle is left out")
+	assert.Equal(t, start, candles[0].Time)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&fetches))
+
+	// A range inside the fetched one is served from disk.
+	candles, err = cache.Range(OHLCPairSOLEUR, 60, time.Unix(start+3600, 0), time.Unix(start+3*3600, 0))
+	assert.NoError(t, err)
+	assert.Len(t, candles, 3)
+	assert.True(t, decimal.NewFromInt(1).Equal(candles[0].Close))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&fetches))
+
+	// Only the candles from the first missing one onwards are fetched.
+	assert.NoError(t, os.Remove(cache.filePath(OHLCPairSOLEUR, 60)))
+	assert.NoError(t, cache.store(OHLCPairSOLEUR, 60, hourlyCandles(start, 2)))
+	candles, err = cache.Range(OHLCPairSOLEUR, 60, time.Unix(start, 0), time.Unix(start+4*3600, 0))
+	assert.NoError(t, err)
+	assert.Len(t, candles, 5)
+	assert.Equal(t, start+2*3600-1, atomic.LoadInt64(&since))
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+}
+
+func TestOHLCCacheHistory(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	var pair string
+	var interval int
+
+	cache := &OHLCCache{
+		FileWriter: &IOUtilFileWriter{},
+		Dir:        t.TempDir(),
+		Fetch: func(p string, i int, since int64) ([]Candle, error) {
+			pair, interval = p, i
+			return nil, nil
+		},
+		Now: func() time.Time { return now },
+	}
+
+	history, err := cache.History("GBP", 7*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, "SOLGBP", pair)
+	assert.Equal(t, 15, interval, "7 days in at most 720 candles")
+	assert.Equal(t, Currency("GBP"), history.Currency)
+	assert.Equal(t, 15*time.Minute, history.Interval)
+	assert.Empty(t, history.Candles)
+}
+
+func TestParseRateRange(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    time.Duration
+		expectedErr string
+	}{
+		{value: "24h", expected: 24 * time.Hour},
+		{value: "90m", expected: 90 * time.Minute},
+		{value: "7d", expected: 7 * 24 * time.Hour},
+		{value: "2w", expected: 14 * 24 * time.Hour},
+		{value: "365d", expected: 365 * 24 * time.Hour},
+		{value: "366d", expectedErr: "at most 365d"},
+		{value: "0d", expectedErr: "must be positive"},
+		{value: "d", expectedErr: "use a duration such as"},
+		{value: "seven days", expectedErr: "use a duration such as"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			span, err := ParseRateRange(tt.value)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, span)
+		})
+	}
+}
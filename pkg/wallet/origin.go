@@ -0,0 +1,65 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+)
+
+// Interfaces an operation can be started from.
+const (
+	InterfaceCLI    = "cli"
+	InterfaceDaemon = "daemon"
+)
+
+// Origin says where an operation was started: the interface, and the command or API method within it,
+// such as "wallet send" on the CLI or "/sleeng.v1.WalletService/Send" on the daemon.
+type Origin struct {
+	Interface string `json:"interface"`
+	Command   string `json:"command"`
+}
+
+// IsZero reports whether the origin is unknown.
+func (o Origin) IsZero() bool {
+	return o == Origin{}
+}
+
+func (o Origin) String() string {
+	if o.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%s via %s", o.Command, o.Interface)
+}
+
+type originKey struct{}
+
+// WithOrigin returns a context carrying origin. Operations run with the context record it in the
+// audit log and their receipts.
+func WithOrigin(ctx context.Context, origin Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+// OriginFrom returns the origin carried by ctx, or the zero Origin when there is none.
+func OriginFrom(ctx context.Context) Origin {
+	origin, _ := ctx.Value(originKey{}).(Origin)
+	return origin
+}
+
+// UseOrigin sets the origin recorded for operations without one in their context, such as changes
+// to the key file. Entry points that serve a single command, like the CLI, set it once.
+func (w *WalletConfig) UseOrigin(origin Origin) {
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok && keyOps.Audit != nil {
+		keyOps.Audit.Origin = origin
+	}
+}
+
+// operationOrigin returns the origin of an operation run with ctx: the one ctx carries, or else the
+// one set with UseOrigin.
+func (w *WalletConfig) operationOrigin(ctx context.Context) Origin {
+	if origin := OriginFrom(ctx); !origin.IsZero() {
+		return origin
+	}
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok && keyOps.Audit != nil {
+		return keyOps.Audit.Origin
+	}
+	return Origin{}
+}
@@ -0,0 +1,39 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditOrigin(t *testing.T) {
+	cli := Origin{Interface: InterfaceCLI, Command: "wallet send"}
+	daemon := Origin{Interface: InterfaceDaemon, Command: "/sleeng.v1.WalletService/Send"}
+
+	keyOps := newUsageKeyOps(t)
+	wc := &WalletConfig{KeyOps: keyOps}
+
+	// Without an origin, entries are written as before.
+	assert.NoError(t, wc.recordAudit(context.Background(), "send", "before"))
+
+	wc.UseOrigin(cli)
+	assert.NoError(t, keyOps.SetActiveKey("savings"))
+	assert.NoError(t, wc.recordAudit(context.Background(), "send", "from the cli"))
+	assert.NoError(t, wc.recordAudit(WithOrigin(context.Background(), daemon), "send", "from the daemon"))
+
+	entries, err := keyOps.Audit.Entries()
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 4) {
+		assert.Nil(t, entries[0].Origin)
+		assert.Equal(t, "switch", entries[1].Action)
+		assert.Equal(t, &cli, entries[1].Origin)
+		assert.Equal(t, &cli, entries[2].Origin)
+		assert.Equal(t, &daemon, entries[3].Origin)
+	}
+
+	assert.Equal(t, daemon, wc.operationOrigin(WithOrigin(context.Background(), daemon)))
+	assert.Equal(t, cli, wc.operationOrigin(context.Background()))
+	assert.Equal(t, "wallet send via cli", cli.String())
+	assert.Equal(t, "", Origin{}.String())
+}
@@ -0,0 +1,157 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+)
+
+// PaymentRequestVersion is the schema version NewPaymentRequest writes and the only one accepted.
+const PaymentRequestVersion = 1
+
+// maxPaymentRequestMemo is the longest memo a payment request may carry, in bytes.
+const maxPaymentRequestMemo = 256
+
+var (
+	ErrInvalidPaymentRequest = errors.New("invalid payment request")
+	ErrPaymentRequestExpired = errors.New("payment request has expired")
+)
+
+// PaymentRequest asks for a transfer to Recipient, as handed out by a merchant. The file is a JSON
+// object with these fields and no others:
+//
+//	version    required, must be 1
+//	recipient  required, base58 address to pay
+//	amount     required, positive decimal string such as "12.50"
+//	unit       required, "sol", "lamports" or a supported currency code such as "EUR"
+//	memo       optional, UTF-8 text of at most 256 bytes
+//	reference  optional, base58 address identifying the payment
+//	expiresAt  optional, RFC 3339 timestamp after which the request is refused
+type PaymentRequest struct {
+	Version   int        `json:"version"`
+	Recipient string     `json:"recipient"`
+	Amount    string     `json:"amount"`
+	Unit      Unit       `json:"unit"`
+	Memo      string     `json:"memo,omitempty"`
+	Reference string     `json:"reference,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// NewPaymentRequest builds a request for amount in unit to recipient. A zero expiresAt never expires.
+func NewPaymentRequest(recipient, amount string, unit Unit, memo, reference string, expiresAt time.Time) (*PaymentRequest, error) {
+	request := &PaymentRequest{
+		Version:   PaymentRequestVersion,
+		Recipient: recipient,
+		Amount:    amount,
+		Unit:      unit,
+		Memo:      memo,
+		Reference: reference,
+	}
+	if !expiresAt.IsZero() {
+		utc := expiresAt.UTC().Truncate(time.Second)
+		request.ExpiresAt = &utc
+	}
+
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// ParsePaymentRequest decodes and validates a payment request, refusing it when it expired before now.
+func ParsePaymentRequest(data []byte, now time.Time) (*PaymentRequest, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var request PaymentRequest
+	if err := decoder.Decode(&request); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPaymentRequest, err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("%w: trailing data after the request", ErrInvalidPaymentRequest)
+	}
+
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if request.Expired(now) {
+		return nil, fmt.Errorf("%w: it expired at %s", ErrPaymentRequestExpired, request.ExpiresAt.Format(time.RFC3339))
+	}
+
+	return &request, nil
+}
+
+// ReadPaymentRequestFile reads a payment request file and parses it with ParsePaymentRequest.
+func ReadPaymentRequestFile(path string, now time.Time) (*PaymentRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payment request: %w", err)
+	}
+
+	return ParsePaymentRequest(data, now)
+}
+
+// Validate checks the request against the schema and normalises its unit. It does not check the expiry.
+func (r *PaymentRequest) Validate() error {
+	if r.Version != PaymentRequestVersion {
+		return fmt.Errorf("%w: unsupported version %d, expected %d", ErrInvalidPaymentRequest, r.Version, PaymentRequestVersion)
+	}
+
+	if r.Recipient == "" {
+		return fmt.Errorf("%w: recipient is required", ErrInvalidPaymentRequest)
+	}
+	if _, err := solana.PublicKeyFromBase58(r.Recipient); err != nil {
+		return fmt.Errorf("%w: recipient %q is not an address: %v", ErrInvalidPaymentRequest, r.Recipient, err)
+	}
+
+	amount, err := decimal.NewFromString(r.Amount)
+	if err != nil {
+		return fmt.Errorf("%w: amount %q is not a number", ErrInvalidPaymentRequest, r.Amount)
+	}
+	if !amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be greater than 0", ErrInvalidPaymentRequest)
+	}
+
+	// "fiat" would mean whatever currency the payer selected, so the request has to name one.
+	unit, err := ParseUnit(string(r.Unit))
+	if err != nil || unit == UnitFiat {
+		return fmt.Errorf("%w: unit %q must be sol, lamports or a currency code", ErrInvalidPaymentRequest, r.Unit)
+	}
+	r.Unit = unit
+
+	if !utf8.ValidString(r.Memo) {
+		return fmt.Errorf("%w: memo is not valid UTF-8", ErrInvalidPaymentRequest)
+	}
+	if len(r.Memo) > maxPaymentRequestMemo {
+		return fmt.Errorf("%w: memo is %d bytes, at most %d are allowed", ErrInvalidPaymentRequest, len(r.Memo), maxPaymentRequestMemo)
+	}
+
+	if r.Reference != "" {
+		if _, err := solana.PublicKeyFromBase58(r.Reference); err != nil {
+			return fmt.Errorf("%w: reference %q is not an address: %v", ErrInvalidPaymentRequest, r.Reference, err)
+		}
+	}
+
+	return nil
+}
+
+// Expired reports whether the request has an expiry at or before now.
+func (r *PaymentRequest) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// Marshal returns the request as an indented JSON file.
+func (r *PaymentRequest) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling JSON: %w", err)
+	}
+	return append(data, '\n'), nil
+}
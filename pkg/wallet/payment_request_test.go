@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testRecipient = "9xQeWvG816bUx9EPjHmaT23yvVM2ZWbrrpZb9PusVFin"
+	testReference = "11111111111111111111111111111111"
+)
+
+func TestParsePaymentRequestSchema(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedUnit Unit
+		expectedErr  error
+	}{
+		{name: "Minimal", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"1.5","unit":"sol"}`, expectedUnit: UnitSOL},
+		{name: "All Fields", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"12.50","unit":"EUR","memo":"invoice 42","reference":"` + testReference + `","expiresAt":"2024-05-02T00:00:00Z"}`, expectedUnit: UnitEUR},
+		{name: "Lamports", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"5000","unit":"lamports"}`, expectedUnit: UnitLamports},
+		{name: "Unknown Field", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"1","unit":"sol","tip":"1"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Wrong Version", body: `{"version":2,"recipient":"` + testRecipient + `","amount":"1","unit":"sol"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Missing Version", body: `{"recipient":"` + testRecipient + `","amount":"1","unit":"sol"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Missing Recipient", body: `{"version":1,"amount":"1","unit":"sol"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Invalid Recipient", body: `{"version":1,"recipient":"not-an-address","amount":"1","unit":"sol"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Amount As Number", body: `{"version":1,"recipient":"` + testRecipient + `","amount":1,"unit":"sol"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Zero Amount", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"0","unit":"sol"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Negative Amount", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"-1","unit":"sol"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Missing Unit", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"1"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Payer Currency Unit", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"1","unit":"fiat"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Unsupported Currency", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"1","unit":"BTC"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Memo Too Long", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"1","unit":"sol","memo":"` + strings.Repeat("x", maxPaymentRequestMemo+1) + `"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Invalid Reference", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"1","unit":"sol","reference":"ref-42"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Invalid Expiry", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"1","unit":"sol","expiresAt":"tomorrow"}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Trailing Data", body: `{"version":1,"recipient":"` + testRecipient + `","amount":"1","unit":"sol"} {}`, expectedErr: ErrInvalidPaymentRequest},
+		{name: "Not JSON", body: `recipient=` + testRecipient, expectedErr: ErrInvalidPaymentRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePaymentRequest([]byte(tt.body), now)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testRecipient, got.Recipient)
+			assert.Equal(t, tt.expectedUnit, got.Unit)
+		})
+	}
+}
+
+func TestParsePaymentRequestExpiry(t *testing.T) {
+	expiresAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"version":1,"recipient":"` + testRecipient + `","amount":"1","unit":"sol","expiresAt":"2024-05-01T12:00:00Z"}`)
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		expired bool
+	}{
+		{name: "Before Expiry", now: expiresAt.Add(-time.Second)},
+		{name: "At Expiry", now: expiresAt, expired: true},
+		{name: "After Expiry", now: expiresAt.Add(time.Hour), expired: true},
+		{name: "Other Time Zone Before Expiry", now: expiresAt.Add(-time.Minute).In(time.FixedZone("CEST", 2*60*60))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePaymentRequest(body, tt.now)
+			if tt.expired {
+				assert.ErrorIs(t, err, ErrPaymentRequestExpired)
+				return
+			}
+			assert.NoError(t, err)
+			assert.False(t, got.Expired(tt.now))
+			assert.True(t, got.Expired(expiresAt))
+		})
+	}
+
+	withoutExpiry, err := ParsePaymentRequest([]byte(`{"version":1,"recipient":"`+testRecipient+`","amount":"1","unit":"sol"}`), expiresAt)
+	assert.NoError(t, err)
+	assert.False(t, withoutExpiry.Expired(expiresAt.AddDate(100, 0, 0)))
+}
+
+func TestPaymentRequestRoundTrip(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	request, err := NewPaymentRequest(testRecipient, "12.50", Unit("GBP"), "invoice 42", testReference, now.Add(24*time.Hour))
+	assert.NoError(t, err)
+
+	data, err := request.Marshal()
+	assert.NoError(t, err)
+
+	got, err := ParsePaymentRequest(data, now)
+	assert.NoError(t, err)
+	assert.Equal(t, request, got)
+	assert.Equal(t, Unit("gbp"), got.Unit)
+
+	_, err = NewPaymentRequest(testRecipient, "12.50", UnitFiat, "", "", time.Time{})
+	assert.ErrorIs(t, err, ErrInvalidPaymentRequest)
+}
@@ -0,0 +1,355 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const PendingFilePath = "sleeng.pending.json"
+
+// pendingIDLength is how many characters of the signature are used as the pending ID.
+const pendingIDLength = 8
+
+// cancelPollInterval is how often the status of a cancellation is polled.
+const cancelPollInterval = 2 * time.Second
+
+var (
+	ErrPendingNotFound    = errors.New("no pending transaction found")
+	ErrNotCancellable     = errors.New("transaction did not use a durable nonce; it can only be awaited, not cancelled")
+	ErrAlreadyLanded      = errors.New("transaction already landed on chain")
+	ErrNotPending         = errors.New("transaction is no longer pending")
+	ErrNonceAuthority     = errors.New("active wallet is not the nonce authority")
+	ErrNonceUninitialized = errors.New("nonce account has not been initialized")
+)
+
+// PendingStatus describes where a sent transaction is in its lifecycle.
+type PendingStatus string
+
+const (
+	PendingSubmitted PendingStatus = "submitted"
+	PendingConfirmed PendingStatus = "confirmed"
+	PendingCancelled PendingStatus = "cancelled"
+	// PendingExpired transactions can no longer land because their blockhash expired; the send
+	// was signed again with a fresh one.
+	PendingExpired PendingStatus = "expired"
+)
+
+// PendingTransaction is a transfer that was signed and handed to the network.
+type PendingTransaction struct {
+	ID              string        `json:"id"`
+	Signature       string        `json:"signature"`
+	From            string        `json:"from"`
+	To              string        `json:"to"`
+	Lamports        uint64        `json:"lamports"`
+	NonceAccount    string        `json:"nonceAccount,omitempty"`
+	Nonce           string        `json:"nonce,omitempty"`
+	Network         string        `json:"network"`
+	Status          PendingStatus `json:"status"`
+	CancelSignature string        `json:"cancelSignature,omitempty"`
+	CreatedAt       time.Time     `json:"createdAt"`
+	// PriorityFee is the compute-unit price the transfer paid in micro-lamports, 0 without a priority fee.
+	PriorityFee uint64 `json:"priorityFee,omitempty"`
+	// Latency is how long the transfer took to reach each commitment level, when it was measured.
+	Latency *ConfirmationLatency `json:"latency,omitempty"`
+}
+
+// pendingSchema is the schema of the pending transactions file.
+var pendingSchema = registerStoreSchema(storeSchema{
+	Kind:       StorePending,
+	Version:    1,
+	Migrations: map[int]storeMigration{0: bareStorePayload},
+})
+
+// pendingRecords is the payload of the pending transactions file: the transactions by ID.
+type pendingRecords map[string]PendingTransaction
+
+func (p pendingRecords) validate() error {
+	for id, tx := range p {
+		if id == "" || tx.ID != id {
+			return fmt.Errorf("transaction %q is stored under ID %q", tx.ID, id)
+		}
+		switch tx.Status {
+		case PendingSubmitted, PendingConfirmed, PendingCancelled, PendingExpired:
+		default:
+			return fmt.Errorf("transaction %s has unknown status %q", id, tx.Status)
+		}
+	}
+	return nil
+}
+
+// PendingStore keeps track of sent transactions in a file next to the key file.
+type PendingStore struct {
+	FileReader FileReader
+	FileWriter FileWriter
+	// Dir is the profile directory holding the store. Empty means the current directory.
+	Dir string
+}
+
+// filePath returns the location of the pending transactions file for this profile.
+func (s *PendingStore) filePath() string {
+	return filepath.Join(s.Dir, PendingFilePath)
+}
+
+// readPending reads all pending transactions, returning an empty map when the file does not exist.
+func (s *PendingStore) readPending() (pendingRecords, error) {
+	pending := make(pendingRecords)
+	if err := readStore(s.FileReader, s.filePath(), pendingSchema, &pending); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return pending, nil
+		}
+		return nil, err
+	}
+	return pending, nil
+}
+
+// Put adds or replaces a pending transaction.
+func (s *PendingStore) Put(tx PendingTransaction) error {
+	pending, err := s.readPending()
+	if err != nil {
+		return err
+	}
+
+	pending[tx.ID] = tx
+	return writeStore(s.FileReader, s.FileWriter, s.filePath(), pendingSchema, pending)
+}
+
+// Get returns the pending transaction with the given ID.
+func (s *PendingStore) Get(id string) (PendingTransaction, error) {
+	pending, err := s.readPending()
+	if err != nil {
+		return PendingTransaction{}, err
+	}
+
+	tx, exists := pending[id]
+	if !exists {
+		return PendingTransaction{}, fmt.Errorf("%w: %s", ErrPendingNotFound, id)
+	}
+
+	return tx, nil
+}
+
+// List returns all recorded transactions, newest first.
+func (s *PendingStore) List() ([]PendingTransaction, error) {
+	pending, err := s.readPending()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]PendingTransaction, 0, len(pending))
+	for _, tx := range pending {
+		list = append(list, tx)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+
+	return list, nil
+}
+
+// setStatus updates the status of a pending transaction.
+func (s *PendingStore) setStatus(id string, status PendingStatus) error {
+	tx, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	tx.Status = status
+	return s.Put(tx)
+}
+
+// pendingID derives the short ID a pending transaction is referred to by.
+func pendingID(sig solana.Signature) string {
+	return sig.String()[:pendingIDLength]
+}
+
+// fetchNonce reads the current nonce value stored in a durable nonce account.
+func fetchNonce(ctx context.Context, client ClientInterface, nonceAccount solana.PublicKey) (*system.NonceAccount, error) {
+	info, err := client.GetAccountInfo(ctx, nonceAccount)
+	if err != nil {
+		return nil, fmt.Errorf("get nonce account: %w", err)
+	}
+
+	if info.Value == nil {
+		return nil, fmt.Errorf("nonce account %s not found", nonceAccount)
+	}
+
+	var account system.NonceAccount
+	if err := account.UnmarshalWithDecoder(bin.NewBinDecoder(info.Value.Data.GetBinary())); err != nil {
+		return nil, fmt.Errorf("decode nonce account: %w", err)
+	}
+
+	if account.State == 0 {
+		return nil, ErrNonceUninitialized
+	}
+
+	return &account, nil
+}
+
+// signatureLanded reports whether a signature has been processed successfully by the cluster.
+func signatureLanded(ctx context.Context, client ClientInterface, sig solana.Signature) (bool, error) {
+	statuses, err := client.GetSignatureStatuses(ctx, true, sig)
+	if err != nil {
+		return false, fmt.Errorf("get signature status: %w", err)
+	}
+
+	if len(statuses.Value) == 0 || statuses.Value[0] == nil {
+		return false, nil
+	}
+
+	return statuses.Value[0].Err == nil, nil
+}
+
+// ListPending returns all transactions recorded by sends, newest first.
+func (w *WalletConfig) ListPending() ([]PendingTransaction, error) {
+	return w.Pending.List()
+}
+
+// CancelPending invalidates a stuck transaction that was sent with a durable nonce by submitting
+// a zero-lamport self-transfer that advances the same nonce. It returns the signature of the
+// cancelling transaction. If the original transaction lands first, ErrAlreadyLanded is returned
+// and the store records it as confirmed.
+func (w *WalletConfig) CancelPending(ctx context.Context, id string) (string, error) {
+	pending, err := w.Pending.Get(id)
+	if err != nil {
+		return "", err
+	}
+
+	if pending.Status != PendingSubmitted {
+		return "", fmt.Errorf("%w: %s is %s", ErrNotPending, id, pending.Status)
+	}
+
+	if pending.NonceAccount == "" {
+		return "", ErrNotCancellable
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return "", err
+	}
+	client := endpoints.client()
+
+	originalSig, err := solana.SignatureFromBase58(pending.Signature)
+	if err != nil {
+		return "", fmt.Errorf("invalid stored signature: %w", err)
+	}
+
+	landed, err := signatureLanded(ctx, client, originalSig)
+	if err != nil {
+		return "", err
+	}
+	if landed {
+		return "", w.markLanded(id)
+	}
+
+	accountFrom, err := w.currentPrivateKey()
+	if err != nil {
+		return "", err
+	}
+
+	if accountFrom.PublicKey().String() != pending.From {
+		return "", fmt.Errorf("transaction %s was sent from %s, switch to that wallet to cancel it", id, pending.From)
+	}
+
+	nonceAccount, err := solana.PublicKeyFromBase58(pending.NonceAccount)
+	if err != nil {
+		return "", fmt.Errorf("invalid stored nonce account: %w", err)
+	}
+
+	nonce, err := fetchNonce(ctx, client, nonceAccount)
+	if err != nil {
+		return "", err
+	}
+
+	if nonce.Nonce.String() != pending.Nonce {
+		// The nonce moved on, so either the original landed or something else consumed the nonce.
+		return "", w.resolveAdvancedNonce(ctx, client, id, originalSig)
+	}
+
+	tx, err := buildTransfer(accountFrom, accountFrom.PublicKey(), 0, solana.Hash(nonce.Nonce), nonceAccount, transferExtras{})
+	if err != nil {
+		return "", err
+	}
+	w.recordKeyUsage(accountFrom.PublicKey(), SignedSend, tx.Signatures[0])
+
+	cancelSig, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		// Losing the race to the original transaction makes the nonce invalid for our cancellation.
+		if landed, statusErr := signatureLanded(ctx, client, originalSig); statusErr == nil && landed {
+			return "", w.markLanded(id)
+		}
+		return "", fmt.Errorf("failed to submit cancellation: %w", err)
+	}
+
+	if err := awaitSignature(ctx, client, cancelSig); err != nil {
+		return "", err
+	}
+
+	pending.Status = PendingCancelled
+	pending.CancelSignature = cancelSig.String()
+	if err := w.Pending.Put(pending); err != nil {
+		return "", err
+	}
+
+	return cancelSig.String(), nil
+}
+
+// markLanded records a pending transaction as confirmed and returns ErrAlreadyLanded.
+func (w *WalletConfig) markLanded(id string) error {
+	if err := w.Pending.setStatus(id, PendingConfirmed); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrAlreadyLanded, id)
+}
+
+// resolveAdvancedNonce works out what happened to a transaction whose nonce was advanced elsewhere.
+func (w *WalletConfig) resolveAdvancedNonce(ctx context.Context, client ClientInterface, id string, sig solana.Signature) error {
+	landed, err := signatureLanded(ctx, client, sig)
+	if err != nil {
+		return err
+	}
+	if landed {
+		return w.markLanded(id)
+	}
+
+	// The original can never land with a stale nonce, so it is effectively cancelled.
+	if err := w.Pending.setStatus(id, PendingCancelled); err != nil {
+		return err
+	}
+	return fmt.Errorf("nonce for %s was already advanced by another transaction; the original can no longer land", id)
+}
+
+// awaitSignature polls until a signature is confirmed or the context is done.
+func awaitSignature(ctx context.Context, client ClientInterface, sig solana.Signature) error {
+	for {
+		statuses, err := client.GetSignatureStatuses(ctx, false, sig)
+		if err != nil {
+			return fmt.Errorf("get signature status: %w", err)
+		}
+
+		if len(statuses.Value) > 0 && statuses.Value[0] != nil {
+			status := statuses.Value[0]
+			if status.Err != nil {
+				return fmt.Errorf("transaction %s failed: %v", sig, status.Err)
+			}
+			if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s: %w", sig, ctx.Err())
+		case <-time.After(cancelPollInterval):
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// memFiles is an in-memory FileReader and FileWriter.
+type memFiles struct {
+	files map[string][]byte
+}
+
+func newMemFiles() *memFiles {
+	return &memFiles{files: make(map[string][]byte)}
+}
+
+func (m *memFiles) ReadFile(filename string) ([]byte, error) {
+	data, ok := m.files[filename]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (m *memFiles) WriteFile(filename string, data []byte) error {
+	m.files[filename] = data
+	return nil
+}
+
+// nonceAccountInfo encodes an initialized nonce account holding the given nonce.
+func nonceAccountInfo(t *testing.T, authority solana.PublicKey, nonce solana.Hash) *rpc.GetAccountInfoResult {
+	t.Helper()
+
+	data, err := bin.MarshalBin(system.NonceAccount{
+		Version:          1,
+		State:            1,
+		AuthorizedPubkey: authority,
+		Nonce:            solana.PublicKey(nonce),
+	})
+	if err != nil {
+		t.Fatalf("could not encode nonce account: %v", err)
+	}
+
+	return &rpc.GetAccountInfoResult{Value: &rpc.Account{Data: rpc.DataBytesOrJSONFromBytes(data)}}
+}
+
+func signatureStatus(landed bool) *rpc.GetSignatureStatusesResult {
+	if !landed {
+		return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{nil}}
+	}
+	return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{
+		{ConfirmationStatus: rpc.ConfirmationStatusFinalized},
+	}}
+}
+
+func TestCancelPending(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	sender := solana.NewWallet()
+	nonceAccount := solana.NewWallet().PublicKey()
+	storedNonce := solana.Hash(solana.NewWallet().PublicKey())
+	originalSig := solana.Signature{1, 2, 3}
+	cancelSig := solana.Signature{4, 5, 6}
+
+	tests := []struct {
+		name              string
+		nonceAccount      string
+		currentNonce      solana.Hash
+		originalLanded    []bool // status of the original on each lookup
+		sendErr           error
+		expectedErr       error
+		expectedStatus    PendingStatus
+		expectedCancelSig string
+	}{
+		{
+			name:           "Not Cancellable Without Nonce",
+			originalLanded: []bool{false},
+			expectedErr:    ErrNotCancellable,
+			expectedStatus: PendingSubmitted,
+		},
+		{
+			name:           "Original Landed First",
+			nonceAccount:   nonceAccount.String(),
+			currentNonce:   storedNonce,
+			originalLanded: []bool{true},
+			expectedErr:    ErrAlreadyLanded,
+			expectedStatus: PendingConfirmed,
+		},
+		{
+			name:           "Original Landed While Cancelling",
+			nonceAccount:   nonceAccount.String(),
+			currentNonce:   storedNonce,
+			originalLanded: []bool{false, true},
+			sendErr:        errors.New("Transaction simulation failed: Blockhash not found"),
+			expectedErr:    ErrAlreadyLanded,
+			expectedStatus: PendingConfirmed,
+		},
+		{
+			name:           "Nonce Already Advanced By Original",
+			nonceAccount:   nonceAccount.String(),
+			currentNonce:   solana.Hash{9},
+			originalLanded: []bool{false, true},
+			expectedErr:    ErrAlreadyLanded,
+			expectedStatus: PendingConfirmed,
+		},
+		{
+			name:              "Cancelled",
+			nonceAccount:      nonceAccount.String(),
+			currentNonce:      storedNonce,
+			originalLanded:    []bool{false},
+			expectedStatus:    PendingCancelled,
+			expectedCancelSig: cancelSig.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			store := &PendingStore{FileReader: files, FileWriter: files}
+
+			pending := PendingTransaction{
+				ID:           "orig1234",
+				Signature:    originalSig.String(),
+				From:         sender.PublicKey().String(),
+				To:           solana.NewWallet().PublicKey().String(),
+				Lamports:     1000,
+				NonceAccount: tt.nonceAccount,
+				Status:       PendingSubmitted,
+				CreatedAt:    time.Now(),
+			}
+			if tt.nonceAccount != "" {
+				pending.Nonce = solana.PublicKey(storedNonce).String()
+			}
+			assert.NoError(t, store.Put(pending))
+
+			lookups := 0
+			var submitted *solana.Transaction
+			newRPCClient = func(string) ClientInterface {
+				return &MockClientInterface{
+					GetAccountInfoFn: func(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+						return nonceAccountInfo(t, sender.PublicKey(), tt.currentNonce), nil
+					},
+					GetSignatureStatusesFn: func(ctx context.Context, _ bool, sigs ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+						if sigs[0] == cancelSig {
+							return signatureStatus(true), nil
+						}
+						landed := tt.originalLanded[len(tt.originalLanded)-1]
+						if lookups < len(tt.originalLanded) {
+							landed = tt.originalLanded[lookups]
+						}
+						lookups++
+						return signatureStatus(landed), nil
+					},
+					SendTransactionFn: func(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+						submitted = tx
+						if tt.sendErr != nil {
+							return solana.Signature{}, tt.sendErr
+						}
+						return cancelSig, nil
+					},
+				}
+			}
+
+			wc := &WalletConfig{Wallet: sender, Pending: store, Network: Devnet}
+			got, err := wc.CancelPending(context.Background(), pending.ID)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedCancelSig, got)
+
+			stored, err := store.Get(pending.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, stored.Status)
+			assert.Equal(t, tt.expectedCancelSig, stored.CancelSignature)
+
+			if tt.expectedCancelSig != "" {
+				// The cancellation must advance the same nonce and only move funds back to the sender.
+				assert.Equal(t, storedNonce, submitted.Message.RecentBlockhash)
+				assert.Len(t, submitted.Message.Instructions, 2)
+			}
+		})
+	}
+}
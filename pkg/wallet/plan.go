@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrPlanStale = errors.New("the key file changed after the preview was made; run the command again")
+
+// KeystorePlan is a change to the key file that has been worked out but not written, so it can be
+// previewed with --dry-run or shown before a confirmation prompt. Apply it with ApplyPlan.
+type KeystorePlan struct {
+	Action string
+	// Changes is what applying the plan changes, as reported by DiffWalletData.
+	Changes []KeystoreChange
+	// ActiveAlias is the active wallet once the plan is applied.
+	ActiveAlias string
+
+	// planned is the key file the plan was made from; ApplyPlan refuses to write over anything else.
+	planned []byte
+	after   WalletData
+}
+
+// Empty reports whether applying the plan would change nothing.
+func (p *KeystorePlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Aliases returns the wallets the plan touches, in alphabetical order.
+func (p *KeystorePlan) Aliases() []string {
+	seen := make(map[string]bool)
+	var aliases []string
+	for _, change := range p.Changes {
+		if change.Alias != "" && !seen[change.Alias] {
+			seen[change.Alias] = true
+			aliases = append(aliases, change.Alias)
+		}
+	}
+
+	sort.Strings(aliases)
+	return aliases
+}
+
+// planChange reads the key file, lets change edit a copy of it and diffs the result. Nothing is written.
+func (k *KeyOps) planChange(action string, change func(data *WalletData) error) (*KeystorePlan, error) {
+	fileData, err := k.FileReader.ReadFile(k.keyFilePath())
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	return planFrom(action, fileData, change)
+}
+
+// planFrom lets change edit a copy of the key file contents in fileData and diffs the result.
+func planFrom(action string, fileData []byte, change func(data *WalletData) error) (*KeystorePlan, error) {
+	// Parsing twice gives change a copy that shares nothing with before.
+	before, err := parseWalletData(fileData)
+	if err != nil {
+		return nil, err
+	}
+	after, err := parseWalletData(fileData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := change(&after); err != nil {
+		return nil, err
+	}
+
+	return &KeystorePlan{
+		Action:      action,
+		Changes:     DiffWalletData(before, after),
+		ActiveAlias: after.ActiveAlias,
+		planned:     fileData,
+		after:       after,
+	}, nil
+}
+
+// ApplyPlan writes a plan made by one of the Plan methods. It fails with ErrPlanStale when the key file
+// was changed in between, since the previewed changes would no longer be what gets written.
+func (k *KeyOps) ApplyPlan(plan *KeystorePlan) error {
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fileData, err := k.FileReader.ReadFile(k.keyFilePath())
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+	if !bytes.Equal(fileData, plan.planned) {
+		return ErrPlanStale
+	}
+
+	if plan.Empty() {
+		return nil
+	}
+
+	return k.writeWalletData(plan.Action, plan.after)
+}
@@ -0,0 +1,95 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlansDoNotWrite(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+
+	tests := []struct {
+		name            string
+		plan            func(ops *KeyOps) (*KeystorePlan, error)
+		expectedChanges []KeystoreChange
+		expectedActive  string
+	}{
+		{
+			name: "Delete Active Wallet",
+			plan: func(ops *KeyOps) (*KeystorePlan, error) { return ops.PlanDeleteKey("savings") },
+			expectedChanges: []KeystoreChange{
+				{Kind: ChangeRemoved, Alias: "savings", From: "savingsAddress"},
+				{Kind: ChangeActive, From: "savings", To: "main"},
+			},
+			expectedActive: "main",
+		},
+		{
+			name: "Encrypt Keys",
+			plan: func(ops *KeyOps) (*KeystorePlan, error) { return ops.PlanEncryptKeys("correct horse") },
+			expectedChanges: []KeystoreChange{
+				{Kind: ChangeField, Alias: "main", Field: "key"},
+				{Kind: ChangeField, Alias: "main", Field: "encrypted", From: "false", To: "true"},
+				{Kind: ChangeField, Alias: "savings", Field: "key"},
+				{Kind: ChangeField, Alias: "savings", Field: "encrypted", From: "false", To: "true"},
+			},
+			expectedActive: "savings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			ops := &KeyOps{FileReader: files, FileWriter: files}
+			assert.NoError(t, ops.WriteKeyToFile("main", key, "mainAddress"))
+			assert.NoError(t, ops.WriteKeyToFile("savings", key, "savingsAddress"))
+			original := append([]byte(nil), files.files[KeyFileName]...)
+
+			plan, err := tt.plan(ops)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedChanges, plan.Changes)
+			assert.Equal(t, tt.expectedActive, plan.ActiveAlias)
+			assert.Equal(t, original, files.files[KeyFileName])
+
+			assert.NoError(t, ops.ApplyPlan(plan))
+			after, err := ops.readWalletData(KeyFileName)
+			assert.NoError(t, err)
+			before, err := parseWalletData(original)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedChanges, DiffWalletData(before, after))
+		})
+	}
+}
+
+func TestApplyPlan(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+
+	t.Run("Stale Plan", func(t *testing.T) {
+		files := newMemFiles()
+		ops := &KeyOps{FileReader: files, FileWriter: files}
+		assert.NoError(t, ops.WriteKeyToFile("main", key, "mainAddress"))
+		assert.NoError(t, ops.WriteKeyToFile("savings", key, "savingsAddress"))
+
+		plan, err := ops.PlanDeleteKey("main")
+		assert.NoError(t, err)
+
+		assert.NoError(t, ops.SetActiveKey("main"))
+		changed := append([]byte(nil), files.files[KeyFileName]...)
+
+		assert.ErrorIs(t, ops.ApplyPlan(plan), ErrPlanStale)
+		assert.Equal(t, changed, files.files[KeyFileName])
+	})
+
+	t.Run("Empty Plan", func(t *testing.T) {
+		files := newMemFiles()
+		ops := &KeyOps{FileReader: files, FileWriter: files, Passphrase: func(string, bool) (string, error) { return "pw", nil }}
+		assert.NoError(t, ops.WriteKeyToFile("main", key, "mainAddress"))
+
+		plan, err := ops.PlanEncryptKeys("pw")
+		assert.NoError(t, err)
+		assert.True(t, plan.Empty())
+		assert.Nil(t, plan.Aliases())
+		assert.NoError(t, ops.ApplyPlan(plan))
+	})
+}
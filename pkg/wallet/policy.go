@@ -0,0 +1,23 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrSpendLimitExceeded = errors.New("spend limit exceeded")
+
+// SpendPolicy bounds the SOL transfers PrepareSend accepts. The zero value allows everything.
+type SpendPolicy struct {
+	// MaxLamports is the largest single transfer allowed, or 0 for no limit.
+	MaxLamports uint64
+}
+
+// Check returns an error wrapping ErrSpendLimitExceeded when a quoted transfer breaks the policy.
+func (p SpendPolicy) Check(lamports uint64) error {
+	if p.MaxLamports > 0 && lamports > p.MaxLamports {
+		return fmt.Errorf("%w: %s SOL is more than the %s SOL allowed per transfer",
+			ErrSpendLimitExceeded, LamportsToSOL(lamports), LamportsToSOL(p.MaxLamports))
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpendPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   SpendPolicy
+		lamports uint64
+		wantErr  bool
+	}{
+		{name: "no limit", policy: SpendPolicy{}, lamports: 1000 * LamportsInOneSol},
+		{name: "under the limit", policy: SpendPolicy{MaxLamports: LamportsInOneSol}, lamports: LamportsInOneSol - 1},
+		{name: "at the limit", policy: SpendPolicy{MaxLamports: LamportsInOneSol}, lamports: LamportsInOneSol},
+		{name: "over the limit", policy: SpendPolicy{MaxLamports: LamportsInOneSol}, lamports: LamportsInOneSol + 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.lamports)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrSpendLimitExceeded)
+				assert.Contains(t, err.Error(), "1.000000001 SOL is more than the 1 SOL allowed")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package wallet
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// InsufficientFundsError is returned when the sender cannot cover the amount plus the fee.
+// It matches ErrInsufficientFunds with errors.Is.
+type InsufficientFundsError struct {
+	// Have is the sender's balance in lamports.
+	Have uint64
+	// Need is the amount plus the estimated fee in lamports.
+	Need uint64
+	// Fee is the estimated fee in lamports.
+	Fee uint64
+	// Rate is the SOL rate in Currency, or nil when it was not available.
+	Rate     *decimal.Decimal
+	Currency Currency
+	// AutoFundErr explains why a requested devnet top-up did not cover the shortfall.
+	AutoFundErr error
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient funds: have %s SOL, need %s SOL including a fee of %s SOL",
+		LamportsToSOL(e.Have), LamportsToSOL(e.Need), LamportsToSOL(e.Fee))
+}
+
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == ErrInsufficientFunds
+}
+
+var ErrBelowRentExemption = errors.New("transfer is below the rent-exempt minimum of a new account")
+
+// BelowRentExemptionError is returned when a transfer to an address that holds no SOL yet would
+// create its account with less than the rent-exempt minimum, which nodes may reject and which leaves
+// an account that can be purged. It matches ErrBelowRentExemption with errors.Is.
+type BelowRentExemptionError struct {
+	Recipient solana.PublicKey
+	// Lamports is the amount of the transfer.
+	Lamports uint64
+	// Minimum is the rent-exempt minimum of an account without data, in lamports.
+	Minimum uint64
+}
+
+func (e *BelowRentExemptionError) Error() string {
+	return fmt.Sprintf("%s holds no SOL yet, so the transfer creates its account, and %s SOL is below the rent-exempt minimum of %s SOL",
+		e.Recipient, LamportsToSOL(e.Lamports), LamportsToSOL(e.Minimum))
+}
+
+func (e *BelowRentExemptionError) Is(target error) bool {
+	return target == ErrBelowRentExemption
+}
+
+// MaxSendable returns the largest amount in lamports that can be sent after paying the fee.
+func (e *InsufficientFundsError) MaxSendable() uint64 {
+	if e.Have <= e.Fee {
+		return 0
+	}
+	return e.Have - e.Fee
+}
+
+// SendQuote describes a checked transfer before it is signed.
+type SendQuote struct {
+	From     solana.PublicKey
+	To       solana.PublicKey
+	Lamports uint64
+	// Fee is the estimated network fee in lamports.
+	Fee uint64
+	// Balance is the sender's balance in lamports when the quote was made.
+	Balance uint64
+	// Rate is the SOL rate in Currency, or nil when it was not available.
+	Rate     *decimal.Decimal
+	Currency Currency
+	// AutoFunded is the number of lamports airdropped to cover the transfer, if any.
+	AutoFunded uint64
+	Memo       string
+	// ComputeUnitPrice is the priority fee in micro-lamports per compute unit, resolved when the
+	// options asked for the auto mode.
+	ComputeUnitPrice uint64
+	// PriorityFee is the part of Fee paid for priority, in lamports.
+	PriorityFee uint64
+	// Expression is the amount as it was given when it was more than a number, such as "50%".
+	Expression string
+	// NewRecipient is true when the recipient holds no SOL, so the transfer creates its account.
+	NewRecipient bool
+	// RentExemptMinimum is the least a new recipient account has to receive, in lamports. It is only
+	// set for a NewRecipient.
+	RentExemptMinimum uint64
+}
+
+// SOL returns the amount to send in SOL.
+func (q *SendQuote) SOL() decimal.Decimal {
+	return LamportsToSOL(q.Lamports)
+}
+
+// Total returns what the transfer takes from the balance: the amount plus the fee.
+func (q *SendQuote) Total() uint64 {
+	return q.Lamports + q.Fee
+}
+
+// TotalFiat returns the value of Total in Currency, or nil when no rate is available.
+func (q *SendQuote) TotalFiat() *decimal.Decimal {
+	if q.Rate == nil {
+		return nil
+	}
+	fiat := LamportsToSOL(q.Total()).Mul(*q.Rate).Round(2)
+	return &fiat
+}
+
+// Spendable returns the balance left after the fee, which percentages and max are shares of.
+func (q *SendQuote) Spendable() uint64 {
+	return spendableAfter(q.Balance, q.Fee)
+}
+
+// spendableAfter returns balance minus fee, or 0 when the fee takes all of it.
+func spendableAfter(balance, fee uint64) uint64 {
+	if balance <= fee {
+		return 0
+	}
+	return balance - fee
+}
+
+// Fiat returns the value of the amount in Currency, or nil when no rate is available.
+func (q *SendQuote) Fiat() *decimal.Decimal {
+	if q.Rate == nil {
+		return nil
+	}
+	fiat := q.SOL().Mul(*q.Rate).Round(2)
+	return &fiat
+}
+
+// PrepareSend converts the amount, estimates the fee and checks the sender can afford the transfer
+// before anything is signed. It returns an *InsufficientFundsError when the balance is too low
+// and an error wrapping ErrSpendLimitExceeded when w.Policy does not allow the amount.
+// With opts.AutoFund on devnet the shortfall is airdropped first. The amount is parsed with
+// ParseAmountExpression; percentages and max are shares of the balance left after the fee.
+// A transfer that would create the recipient's account below the rent-exempt minimum fails with a
+// *BelowRentExemptionError unless opts.AllowBelowRent is set.
+func (w *WalletConfig) PrepareSend(ctx context.Context, amount, recipient string, opts SendOptions) (*SendQuote, error) {
+	to, err := solana.PublicKeyFromBase58(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidRecipient, recipient, err)
+	}
+	if err := w.checkRecipientAllowed(to); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateMemo(opts.Memo); err != nil {
+		return nil, err
+	}
+
+	var nonceAccount solana.PublicKey
+	if opts.NonceAccount != "" {
+		nonceAccount, err = solana.PublicKeyFromBase58(opts.NonceAccount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nonce account: %w", err)
+		}
+	}
+
+	expr, err := ParseAmountExpression(amount, opts.Unit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fixed amounts are checked before any request is made; amounts relative to the spendable
+	// balance once the balance and fee are known.
+	var lamports uint64
+	var rate *decimal.Decimal
+	var currency Currency
+	if !expr.Relative() {
+		lamports, rate, currency, err = w.quoteAmount(expr, opts.Unit, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.Policy.Check(lamports); err != nil {
+			return nil, err
+		}
+	}
+
+	from, err := w.currentPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkCanSign(); err != nil {
+		return nil, err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	// The balances, the blockhash for the fee estimate and the rent-exempt minimum, which is only
+	// needed for a new recipient, are read in one round trip.
+	var balance, recipientBalance rpc.GetBalanceResult
+	var recent rpc.GetLatestBlockhashResult
+	var rent uint64
+	balanceCall := getBalanceCall(&balance, from, rpc.CommitmentConfirmed)
+	recipientCall := getBalanceCall(&recipientBalance, to, rpc.CommitmentConfirmed)
+	blockhashCall := getLatestBlockhashCall(&recent, rpc.CommitmentFinalized)
+	rentCall := getRentCall(&rent, 0, rpc.CommitmentConfirmed)
+	rentCall.speculative = true
+	batchRead(ctx, client, balanceCall, recipientCall, blockhashCall, rentCall)
+	if balanceCall.err != nil {
+		return nil, fmt.Errorf("failed to fetch balance: %w", balanceCall.err)
+	}
+
+	price, err := resolvePriorityFee(ctx, client, opts.PriorityFee, from, to)
+	if err != nil {
+		return nil, err
+	}
+	extras := transferExtras{Memo: opts.Memo, ComputeUnitPrice: price}
+	fee := lamportsPerSignature + extras.priorityFee()
+	if blockhashCall.err == nil {
+		// The fee does not depend on the amount, so a relative amount is estimated with the balance.
+		estimated := lamports
+		if expr.Relative() {
+			estimated = balance.Value
+		}
+		fee = estimateFeeAt(ctx, client, recent.Value.Blockhash, transferInstructions(from, to, estimated, nonceAccount, extras), from, extras.priorityFee())
+	}
+
+	if expr.Relative() {
+		lamports, rate, currency, err = w.quoteAmount(expr, opts.Unit, spendableAfter(balance.Value, fee))
+		if err != nil {
+			return nil, err
+		}
+		if err := w.Policy.Check(lamports); err != nil {
+			return nil, err
+		}
+	}
+
+	quote := &SendQuote{
+		From: from, To: to, Lamports: lamports, Fee: fee, Balance: balance.Value, Rate: rate, Currency: currency,
+		Memo: opts.Memo, ComputeUnitPrice: price, PriorityFee: extras.priorityFee(),
+	}
+	if !expr.Simple() {
+		quote.Expression = expr.String()
+	}
+
+	// A recipient whose balance cannot be read is not held against the transfer.
+	if recipientCall.err == nil && recipientBalance.Value == 0 {
+		quote.NewRecipient = true
+		if err := rentCall.ensure(ctx, client); err != nil && !opts.AllowBelowRent {
+			return nil, fmt.Errorf("failed to fetch the rent-exempt minimum for the new recipient account: %w", err)
+		}
+		quote.RentExemptMinimum = rent
+		if lamports < rent && !opts.AllowBelowRent {
+			return nil, &BelowRentExemptionError{Recipient: to, Lamports: lamports, Minimum: rent}
+		}
+	}
+	if balance.Value < lamports || balance.Value-lamports < fee {
+		insufficient := &InsufficientFundsError{Have: balance.Value, Need: lamports + fee, Fee: fee, Rate: rate, Currency: currency}
+		if !opts.AutoFund {
+			return nil, insufficient
+		}
+
+		funded, err := w.autoFund(ctx, client, from, insufficient.Need-insufficient.Have)
+		if err != nil {
+			insufficient.AutoFundErr = err
+			return nil, insufficient
+		}
+		if funded < insufficient.Need {
+			insufficient.Have = funded
+			insufficient.AutoFundErr = errors.New("the airdrop did not cover the shortfall")
+			return nil, insufficient
+		}
+
+		quote.Balance = funded
+		quote.AutoFunded = funded - balance.Value
+	}
+
+	return quote, nil
+}
+
+// estimateFee asks the cluster what a message with these instructions would cost, which includes
+// the priority fee of its compute budget instructions. The estimate falls back to the base
+// signature fee plus priorityFee when the cluster cannot be asked.
+func estimateFee(ctx context.Context, client ClientInterface, instructions []solana.Instruction, payer solana.PublicKey, priorityFee uint64) uint64 {
+	recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return lamportsPerSignature + priorityFee
+	}
+	return estimateFeeAt(ctx, client, recent.Value.Blockhash, instructions, payer, priorityFee)
+}
+
+// estimateFeeAt is estimateFee for a blockhash that has already been fetched.
+func estimateFeeAt(ctx context.Context, client ClientInterface, blockhash solana.Hash, instructions []solana.Instruction, payer solana.PublicKey, priorityFee uint64) uint64 {
+	tx, err := solana.NewTransaction(instructions, blockhash, solana.TransactionPayer(payer))
+	if err != nil {
+		return lamportsPerSignature + priorityFee
+	}
+
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return lamportsPerSignature + priorityFee
+	}
+
+	fee, err := client.GetFeeForMessage(ctx, base64.StdEncoding.EncodeToString(message), rpc.CommitmentConfirmed)
+	if err != nil || fee.Value == nil {
+		return lamportsPerSignature*uint64(tx.Message.Header.NumRequiredSignatures) + priorityFee
+	}
+
+	return *fee.Value
+}
+
+// currentPublicKey returns the public key of the in-memory wallet or the active wallet on disk
+// without decrypting its private key.
+func (w *WalletConfig) currentPublicKey() (solana.PublicKey, error) {
+	if w.Wallet != nil {
+		return w.Wallet.PublicKey(), nil
+	}
+
+	publicKey, err := w.KeyOps.GetCurrentPublicKey()
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to get current public key: %w", err)
+	}
+
+	return solana.PublicKeyFromBase58(publicKey)
+}
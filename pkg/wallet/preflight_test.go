@@ -0,0 +1,266 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareSend(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	sender := solana.NewWallet()
+	recipient := solana.NewWallet().PublicKey().String()
+	fee := uint64(5000)
+
+	tests := []struct {
+		name         string
+		balance      uint64
+		amount       string
+		feeErr       error
+		recipient    string
+		expectedFee  uint64
+		expectedMax  uint64
+		expectedErr  error
+		expectAnyErr bool
+	}{
+		{name: "Enough Funds", balance: 1_000_000, amount: "500000", expectedFee: fee},
+		{name: "Exact Balance Plus Fee", balance: 505_000, amount: "500000", expectedFee: fee},
+		{name: "More Than Balance", balance: 100_000, amount: "500000", expectedErr: ErrInsufficientFunds, expectedMax: 95_000},
+		{name: "Entire Balance Leaves No Fee", balance: 500_000, amount: "500000", expectedErr: ErrInsufficientFunds, expectedMax: 495_000},
+		{name: "Fee Lookup Fails Falls Back To Base Fee", balance: 1_000_000, amount: "500000", feeErr: errors.New("method not found"), expectedFee: lamportsPerSignature},
+		{name: "Invalid Recipient", balance: 1_000_000, amount: "500000", recipient: "not-an-address", expectAnyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newRPCClient = func(string) ClientInterface {
+				return &MockClientInterface{
+					GetBalanceFn: func(ctx context.Context, publicKey solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+						return &rpc.GetBalanceResult{Value: tt.balance}, nil
+					},
+					GetLatestBlockhashFn: func(ctx context.Context, _ rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+						return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}}}, nil
+					},
+					GetFeeForMessageFn: func(ctx context.Context, message string, _ rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+						if tt.feeErr != nil {
+							return nil, tt.feeErr
+						}
+						return &rpc.GetFeeForMessageResult{Value: &fee}, nil
+					},
+				}
+			}
+
+			to := recipient
+			if tt.recipient != "" {
+				to = tt.recipient
+			}
+
+			wc := &WalletConfig{Wallet: sender, Network: Devnet}
+			quote, err := wc.PrepareSend(context.Background(), tt.amount, to, SendOptions{Unit: UnitLamports})
+
+			if tt.expectAnyErr {
+				assert.Error(t, err)
+				return
+			}
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+
+				var insufficient *InsufficientFundsError
+				assert.True(t, errors.As(err, &insufficient))
+				assert.Equal(t, tt.balance, insufficient.Have)
+				assert.Equal(t, tt.expectedMax, insufficient.MaxSendable())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(500_000), quote.Lamports)
+			assert.Equal(t, tt.expectedFee, quote.Fee)
+			assert.Equal(t, sender.PublicKey(), quote.From)
+		})
+	}
+}
+
+func TestPrepareSendRelativeAmount(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	sender := solana.NewWallet()
+	recipient := solana.NewWallet().PublicKey().String()
+	fee := uint64(5000)
+	balance := uint64(1_000_005_000)
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(ctx context.Context, publicKey solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				return &rpc.GetBalanceResult{Value: balance}, nil
+			},
+			GetLatestBlockhashFn: func(ctx context.Context, _ rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+				return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}}}, nil
+			},
+			GetFeeForMessageFn: func(ctx context.Context, message string, _ rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+				return &rpc.GetFeeForMessageResult{Value: &fee}, nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		amount      string
+		policy      SpendPolicy
+		expected    uint64
+		expectedErr error
+	}{
+		{name: "Half Of Spendable", amount: "50%", expected: 500_000_000},
+		{name: "Max Leaves The Fee", amount: "max", expected: 1_000_000_000},
+		{name: "Max Minus SOL", amount: "max-0.1", expected: 900_000_000},
+		{name: "Fixed Sum", amount: "0.25+0.25", expected: 500_000_000},
+		{name: "More Than Spendable", amount: "max+1lamports", expectedErr: ErrInsufficientFunds},
+		{name: "Resolved Amount Is Checked Against The Policy", amount: "max", policy: SpendPolicy{MaxLamports: 1_000}, expectedErr: ErrSpendLimitExceeded},
+		{name: "Division Is Rejected", amount: "max/2", expectedErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc := &WalletConfig{Wallet: sender, Network: Devnet, Policy: tt.policy}
+			quote, err := wc.PrepareSend(context.Background(), tt.amount, recipient, SendOptions{Unit: UnitSOL})
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, quote.Lamports)
+			assert.Equal(t, fee, quote.Fee)
+			assert.Equal(t, tt.amount, quote.Expression)
+		})
+	}
+}
+
+// testRentExemptMinimum is the rent-exempt minimum of an account without data on the real clusters.
+const testRentExemptMinimum = 890_880
+
+func rentExemptMinimum(context.Context, uint64, rpc.CommitmentType) (uint64, error) {
+	return testRentExemptMinimum, nil
+}
+
+func TestPrepareSendRentExemption(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	sender := solana.NewWallet()
+	recipient := solana.NewWallet().PublicKey()
+	fee := uint64(5000)
+
+	tests := []struct {
+		name             string
+		recipientBalance uint64
+		amount           string
+		allowBelowRent   bool
+		expectedNew      bool
+		expectedErr      error
+	}{
+		{name: "Existing Recipient", recipientBalance: 1, amount: "1000", expectedNew: false},
+		{name: "New Recipient Above Minimum", amount: "890880", expectedNew: true},
+		{name: "New Recipient Below Minimum", amount: "100000", expectedErr: ErrBelowRentExemption},
+		{name: "New Recipient Below Minimum Allowed", amount: "100000", allowBelowRent: true, expectedNew: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rentCalls := 0
+			newRPCClient = func(string) ClientInterface {
+				return &MockClientInterface{
+					GetBalanceFn: func(ctx context.Context, publicKey solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+						if publicKey.Equals(recipient) {
+							return &rpc.GetBalanceResult{Value: tt.recipientBalance}, nil
+						}
+						return &rpc.GetBalanceResult{Value: solana.LAMPORTS_PER_SOL}, nil
+					},
+					GetLatestBlockhashFn: func(ctx context.Context, _ rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+						return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}}}, nil
+					},
+					GetFeeForMessageFn: func(ctx context.Context, message string, _ rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+						return &rpc.GetFeeForMessageResult{Value: &fee}, nil
+					},
+					GetMinimumBalanceForRentExemptionFn: func(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error) {
+						rentCalls++
+						assert.Zero(t, dataSize)
+						return rentExemptMinimum(ctx, dataSize, commitment)
+					},
+				}
+			}
+
+			wc := &WalletConfig{Wallet: sender, Network: Devnet}
+			quote, err := wc.PrepareSend(context.Background(), tt.amount, recipient.String(), SendOptions{Unit: UnitLamports, AllowBelowRent: tt.allowBelowRent})
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				var below *BelowRentExemptionError
+				if assert.True(t, errors.As(err, &below)) {
+					assert.Equal(t, recipient, below.Recipient)
+					assert.Equal(t, uint64(100_000), below.Lamports)
+					assert.Equal(t, uint64(testRentExemptMinimum), below.Minimum)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedNew, quote.NewRecipient)
+			if tt.expectedNew {
+				assert.Equal(t, uint64(testRentExemptMinimum), quote.RentExemptMinimum)
+			} else {
+				// The minimum is only fetched for a new recipient.
+				assert.Zero(t, rentCalls)
+			}
+		})
+	}
+}
+
+// blockingRates never answers, like a price API that stopped responding.
+type blockingRates struct{}
+
+func (blockingRates) Name() string { return "blocking" }
+
+func (blockingRates) SOLRate(ctx context.Context, _ Currency) (decimal.Decimal, error) {
+	<-ctx.Done()
+	return decimal.Zero, ctx.Err()
+}
+
+func TestPrepareSendInSOLDoesNotWaitForRate(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	defer func(timeout time.Duration) { valueRateTimeout = timeout }(valueRateTimeout)
+	valueRateTimeout = 10 * time.Millisecond
+
+	fee := uint64(5000)
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(context.Context, solana.PublicKey, rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				return &rpc.GetBalanceResult{Value: 1_000_000_000}, nil
+			},
+			GetLatestBlockhashFn: func(context.Context, rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+				return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}}}, nil
+			},
+			GetFeeForMessageFn: func(context.Context, string, rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+				return &rpc.GetFeeForMessageResult{Value: &fee}, nil
+			},
+		}
+	}
+
+	wc := &WalletConfig{Wallet: solana.NewWallet(), Network: Devnet, Rates: blockingRates{}}
+	recipient := solana.NewWallet().PublicKey().String()
+	for _, opts := range []struct {
+		amount string
+		unit   Unit
+	}{{"0.5", UnitSOL}, {"500000000", UnitLamports}, {"0.5 sol", UnitFiat}} {
+		quote, err := wc.PrepareSend(context.Background(), opts.amount, recipient, SendOptions{Unit: opts.unit})
+		assert.NoError(t, err, opts.amount)
+		assert.Equal(t, uint64(500_000_000), quote.Lamports, opts.amount)
+		assert.Nil(t, quote.Rate, opts.amount)
+	}
+}
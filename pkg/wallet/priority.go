@@ -0,0 +1,137 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+)
+
+// MaxMemoBytes is the longest memo a transfer accepts. The memo program itself allows more, but a
+// longer memo leaves little room in a transaction for a durable nonce and compute budget.
+const MaxMemoBytes = 256
+
+// Compute units requested with a priority fee. The priority fee is the unit price times the
+// requested units, so they are kept close to what a transfer needs: the transfer and compute
+// budget instructions stay under transferComputeUnits, and the memo program needs more for its
+// UTF-8 check and log.
+const (
+	transferComputeUnits = 1_000
+	memoComputeUnits     = 25_000
+)
+
+// autoPriorityPercentile is the percentile of recent prioritization fees the auto mode pays, so that
+// the transfer outbids most transactions that touched the same accounts.
+const autoPriorityPercentile = 75
+
+var ErrInvalidMemo = errors.New("invalid memo")
+
+// PriorityFee is the price paid per compute unit on top of the signature fee, in micro-lamports.
+// With Auto the price is taken from the fees recently paid for the accounts of the transfer.
+type PriorityFee struct {
+	MicroLamports uint64
+	Auto          bool
+}
+
+// ParsePriorityFee parses a price in micro-lamports per compute unit, or "auto".
+func ParsePriorityFee(value string) (PriorityFee, error) {
+	if strings.EqualFold(value, "auto") {
+		return PriorityFee{Auto: true}, nil
+	}
+
+	microLamports, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return PriorityFee{}, fmt.Errorf("invalid priority fee %q, expected micro-lamports per compute unit or auto", value)
+	}
+	return PriorityFee{MicroLamports: microLamports}, nil
+}
+
+// ValidateMemo checks a memo is valid UTF-8 of at most MaxMemoBytes bytes.
+func ValidateMemo(memo string) error {
+	if !utf8.ValidString(memo) {
+		return fmt.Errorf("%w: not valid UTF-8", ErrInvalidMemo)
+	}
+	if len(memo) > MaxMemoBytes {
+		return fmt.Errorf("%w: %d bytes, at most %d allowed", ErrInvalidMemo, len(memo), MaxMemoBytes)
+	}
+	return nil
+}
+
+// transferExtras are the optional parts of a transfer besides the transfer itself. The zero value
+// adds nothing, so the transaction is the same as a plain transfer.
+type transferExtras struct {
+	Memo string
+	// ComputeUnitPrice is the priority fee in micro-lamports per compute unit.
+	ComputeUnitPrice uint64
+}
+
+// computeUnitLimit is how many compute units are requested for the transfer.
+func (e transferExtras) computeUnitLimit() uint32 {
+	if e.Memo != "" {
+		return transferComputeUnits + memoComputeUnits
+	}
+	return transferComputeUnits
+}
+
+// priorityFee is the priority fee of the transfer in lamports, rounded up like the runtime does.
+func (e transferExtras) priorityFee() uint64 {
+	if e.ComputeUnitPrice == 0 {
+		return 0
+	}
+	microLamports := e.ComputeUnitPrice * uint64(e.computeUnitLimit())
+	return (microLamports + 999_999) / 1_000_000
+}
+
+// computeBudgetInstructions sets the compute unit limit and price when a priority fee is paid.
+func (e transferExtras) computeBudgetInstructions() []solana.Instruction {
+	if e.ComputeUnitPrice == 0 {
+		return nil
+	}
+	return []solana.Instruction{
+		computebudget.NewSetComputeUnitLimitInstruction(e.computeUnitLimit()).Build(),
+		computebudget.NewSetComputeUnitPriceInstruction(e.ComputeUnitPrice).Build(),
+	}
+}
+
+// memoInstruction records the memo, signed by the sender, or returns nil without one.
+func (e transferExtras) memoInstruction(signer solana.PublicKey) solana.Instruction {
+	if e.Memo == "" {
+		return nil
+	}
+	return solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{solana.Meta(signer).SIGNER()}, []byte(e.Memo))
+}
+
+// resolvePriorityFee returns the unit price of fee, looking up the recent prioritization fees of
+// accounts in auto mode. A node that cannot answer is an error, since the user asked for a priority fee.
+func resolvePriorityFee(ctx context.Context, client ClientInterface, fee PriorityFee, accounts ...solana.PublicKey) (uint64, error) {
+	if !fee.Auto {
+		return fee.MicroLamports, nil
+	}
+
+	recent, err := client.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch recent prioritization fees: %w", err)
+	}
+
+	prices := make([]uint64, 0, len(recent))
+	for _, r := range recent {
+		prices = append(prices, r.PrioritizationFee)
+	}
+	return percentile(prices, autoPriorityPercentile), nil
+}
+
+// percentile returns the p-th percentile of values, 0 for no values.
+func percentile(values []uint64, p int) uint64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]uint64{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)-1)*p/100]
+}
@@ -0,0 +1,174 @@
+package wallet
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePriorityFee(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    PriorityFee
+		expectedErr string
+	}{
+		{value: "1000", expected: PriorityFee{MicroLamports: 1000}},
+		{value: "auto", expected: PriorityFee{Auto: true}},
+		{value: "AUTO", expected: PriorityFee{Auto: true}},
+		{value: "-5", expectedErr: `invalid priority fee "-5"`},
+		{value: "fast", expectedErr: `invalid priority fee "fast"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			fee, err := ParsePriorityFee(tt.value)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, fee)
+		})
+	}
+}
+
+func TestValidateMemo(t *testing.T) {
+	tests := []struct {
+		name        string
+		memo        string
+		expectedErr string
+	}{
+		{name: "Empty", memo: ""},
+		{name: "Invoice", memo: "invoice 42"},
+		{name: "Multibyte At The Limit", memo: strings.Repeat("é", MaxMemoBytes/2)},
+		{name: "Too Long", memo: strings.Repeat("a", MaxMemoBytes+1), expectedErr: "257 bytes, at most 256 allowed"},
+		{name: "Invalid UTF-8", memo: "\xff\xfe", expectedErr: "not valid UTF-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMemo(tt.memo)
+			if tt.expectedErr != "" {
+				assert.ErrorIs(t, err, ErrInvalidMemo)
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTransferInstructions(t *testing.T) {
+	from := solana.NewWallet().PublicKey()
+	to := solana.NewWallet().PublicKey()
+	nonceAccount := solana.NewWallet().PublicKey()
+
+	t.Run("Plain Transfer Is Unchanged", func(t *testing.T) {
+		plain, err := solana.NewTransaction([]solana.Instruction{
+			system.NewTransferInstruction(1_000, from, to).Build(),
+		}, solana.Hash{1}, solana.TransactionPayer(from))
+		assert.NoError(t, err)
+		tx, err := solana.NewTransaction(transferInstructions(from, to, 1_000, solana.PublicKey{}, transferExtras{}), solana.Hash{1}, solana.TransactionPayer(from))
+		assert.NoError(t, err)
+
+		expected, err := plain.Message.MarshalBinary()
+		assert.NoError(t, err)
+		got, err := tx.Message.MarshalBinary()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("Nonce First, Memo Last", func(t *testing.T) {
+		instructions := transferInstructions(from, to, 1_000, nonceAccount, transferExtras{Memo: "invoice 42", ComputeUnitPrice: 5_000})
+		if !assert.Len(t, instructions, 5) {
+			return
+		}
+
+		programs := make([]solana.PublicKey, len(instructions))
+		for i, instruction := range instructions {
+			programs[i] = instruction.ProgramID()
+		}
+		assert.Equal(t, []solana.PublicKey{
+			solana.SystemProgramID, computebudget.ProgramID, computebudget.ProgramID, solana.SystemProgramID, solana.MemoProgramID,
+		}, programs)
+
+		memo, err := instructions[4].Data()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("invoice 42"), memo)
+		assert.True(t, instructions[4].Accounts()[0].IsSigner)
+	})
+}
+
+func TestPriorityFeeLamports(t *testing.T) {
+	assert.Equal(t, uint64(0), transferExtras{}.priorityFee())
+	// 1 micro-lamport for 1000 units is a thousandth of a lamport, rounded up.
+	assert.Equal(t, uint64(1), transferExtras{ComputeUnitPrice: 1}.priorityFee())
+	assert.Equal(t, uint64(5), transferExtras{ComputeUnitPrice: 5_000}.priorityFee())
+	assert.Equal(t, uint64(130), transferExtras{ComputeUnitPrice: 5_000, Memo: "invoice 42"}.priorityFee())
+}
+
+func TestPrepareSendWithMemoAndPriorityFee(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	sender := solana.NewWallet()
+	recipient := solana.NewWallet().PublicKey()
+	baseFee := uint64(5_000)
+
+	var queried solana.PublicKeySlice
+	var estimated *solana.Message
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(ctx context.Context, _ solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				return &rpc.GetBalanceResult{Value: 1_000_000}, nil
+			},
+			GetLatestBlockhashFn: func(ctx context.Context, _ rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+				return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}}}, nil
+			},
+			GetFeeForMessageFn: func(ctx context.Context, message string, _ rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+				raw, err := base64.StdEncoding.DecodeString(message)
+				assert.NoError(t, err)
+				estimated = new(solana.Message)
+				assert.NoError(t, estimated.UnmarshalWithDecoder(bin.NewBinDecoder(raw)))
+				fee := baseFee + 130
+				return &rpc.GetFeeForMessageResult{Value: &fee}, nil
+			},
+			GetRecentPrioritizationFeesFn: func(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+				queried = accounts
+				return []rpc.PriorizationFeeResult{{PrioritizationFee: 0}, {PrioritizationFee: 1_000}, {PrioritizationFee: 5_000}, {PrioritizationFee: 90_000}}, nil
+			},
+		}
+	}
+
+	wc := &WalletConfig{Wallet: sender, Network: Devnet, Rates: StaticRate(decimal.NewFromInt(100))}
+	quote, err := wc.PrepareSend(context.Background(), "500000", recipient.String(), SendOptions{
+		Unit:        UnitLamports,
+		Memo:        "invoice 42",
+		PriorityFee: PriorityFee{Auto: true},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, solana.PublicKeySlice{sender.PublicKey(), recipient}, queried)
+	assert.Equal(t, uint64(5_000), quote.ComputeUnitPrice, "the 75th percentile of the recent fees")
+	assert.Equal(t, uint64(130), quote.PriorityFee)
+	assert.Equal(t, baseFee+130, quote.Fee)
+	assert.Equal(t, "invoice 42", quote.Memo)
+	if assert.NotNil(t, estimated) {
+		assert.Len(t, estimated.Instructions, 4, "the fee is estimated for the transaction that is sent")
+	}
+
+	_, err = wc.PrepareSend(context.Background(), "500000", recipient.String(), SendOptions{Unit: UnitLamports, Memo: strings.Repeat("a", MaxMemoBytes+1)})
+	assert.ErrorIs(t, err, ErrInvalidMemo)
+}
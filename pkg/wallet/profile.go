@@ -0,0 +1,416 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+)
+
+// DefaultProfile is the profile used when none is selected. It keeps its files directly in the
+// configuration directory.
+const DefaultProfile = "default"
+
+// ConfigDirEnv overrides the configuration directory, mainly for tests and multiple installs. Unless
+// DataDirEnv is set too, the profiles are kept in it as well.
+const ConfigDirEnv = "SLEENG_CONFIG_DIR"
+
+// DataDirEnv overrides the directory the profiles, with their key files, are kept in.
+const DataDirEnv = "SLEENG_DATA_DIR"
+
+// RootConfigFile is the name of the file holding settings shared by all profiles.
+const RootConfigFile = "config.json"
+
+var (
+	ErrProfileNotFound = errors.New("profile does not exist")
+	ErrProfileExists   = errors.New("profile already exists")
+)
+
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,31}$`)
+
+// RootConfig holds settings shared by all profiles.
+type RootConfig struct {
+	DefaultProfile string `json:"defaultProfile,omitempty"`
+	// Currency is the stored default currency, used when --currency is not given.
+	Currency Currency `json:"currency,omitempty"`
+	// Locale is the stored locale amounts are shown in, used when --locale is not given.
+	Locale Locale `json:"locale,omitempty"`
+	// RPCEndpoints are the registered RPC providers, used as the failover pool of their network.
+	RPCEndpoints []RPCEndpoint `json:"rpcEndpoints,omitempty"`
+	// KeyBackend is where new private keys are stored, the key file when empty.
+	KeyBackend KeyBackend `json:"keyBackend,omitempty"`
+}
+
+// ConfigDir returns the directory sleeng keeps its configuration and profiles in.
+func ConfigDir() (string, error) {
+	if dir := os.Getenv(ConfigDirEnv); dir != "" {
+		return dir, nil
+	}
+
+	userDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate user config directory: %w", err)
+	}
+
+	return filepath.Join(userDir, "sleeng"), nil
+}
+
+// DataDir returns the directory sleeng keeps its profiles in, with their key files. It follows the XDG
+// base directory spec where that applies: $XDG_DATA_HOME/sleeng, or ~/.local/share/sleeng. On macOS
+// and Windows the configuration directory already is the place for application data, so it is used.
+// Installs that keep their wallets in the configuration directory, as versions before the data
+// directory did, go on using it.
+func DataDir() (string, error) {
+	if dir := os.Getenv(DataDirEnv); dir != "" {
+		return dir, nil
+	}
+
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if os.Getenv(ConfigDirEnv) != "" {
+		return configDir, nil
+	}
+
+	dataDir, err := userDataDir()
+	if err != nil || dataDir == "" {
+		return configDir, err
+	}
+	dataDir = filepath.Join(dataDir, "sleeng")
+	if hasProfiles(configDir) && !hasProfiles(dataDir) {
+		return configDir, nil
+	}
+	return dataDir, nil
+}
+
+// userDataDir returns the XDG data directory, or an empty string on platforms without one.
+func userDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios", "plan9":
+		return "", nil
+	}
+
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate user data directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// hasProfiles reports whether dir holds the key file of the default profile or other profiles.
+func hasProfiles(dir string) bool {
+	for _, name := range []string{KeyFileName, "profiles"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateProfileName checks that a profile name is safe to use as a directory name.
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use up to 32 letters, digits, dashes or underscores", name)
+	}
+	return nil
+}
+
+// ProfileManager creates, lists and switches profiles. Settings shared by all profiles are kept in
+// ConfigDir, the profiles themselves in DataDir.
+type ProfileManager struct {
+	ConfigDir string
+	// DataDir holds the profiles. Empty means ConfigDir.
+	DataDir    string
+	FileReader FileReader
+	FileWriter FileWriter
+}
+
+// NewProfileManager initializes a ProfileManager for the user's configuration and data directories.
+func NewProfileManager() (*ProfileManager, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProfileManager{
+		ConfigDir:  dir,
+		DataDir:    dataDir,
+		FileReader: &IOUtilFileReader{},
+		FileWriter: newFileWriter(),
+	}, nil
+}
+
+// Dir returns the directory holding the files of a profile.
+func (p *ProfileManager) Dir(name string) (string, error) {
+	if name == DefaultProfile {
+		return p.dataDir(), nil
+	}
+
+	if err := ValidateProfileName(name); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(p.dataDir(), "profiles", name), nil
+}
+
+// dataDir returns the directory holding the profiles.
+func (p *ProfileManager) dataDir() string {
+	if p.DataDir != "" {
+		return p.DataDir
+	}
+	return p.ConfigDir
+}
+
+// Exists reports whether a profile has been created. The default profile always exists.
+func (p *ProfileManager) Exists(name string) (bool, error) {
+	if name == DefaultProfile {
+		return true, nil
+	}
+
+	dir, err := p.Dir(name)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error accessing profile %s: %w", name, err)
+	}
+
+	return info.IsDir(), nil
+}
+
+// Create makes the directory for a new profile, readable only by the current user.
+func (p *ProfileManager) Create(name string) error {
+	exists, err := p.Exists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: %s", ErrProfileExists, name)
+	}
+
+	dir, err := p.Dir(name)
+	if err != nil {
+		return err
+	}
+
+	if err := guardWrite(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating profile %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// List returns the names of all profiles, including the default one.
+func (p *ProfileManager) List() ([]string, error) {
+	names := []string{DefaultProfile}
+
+	entries, err := os.ReadDir(filepath.Join(p.dataDir(), "profiles"))
+	if errors.Is(err, os.ErrNotExist) {
+		return names, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing profiles: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && ValidateProfileName(entry.Name()) == nil && entry.Name() != DefaultProfile {
+			names = append(names, entry.Name())
+		}
+	}
+
+	sort.Strings(names[1:])
+	return names, nil
+}
+
+// Active returns the profile selected in the root config, or the default profile.
+func (p *ProfileManager) Active() (string, error) {
+	config, err := p.readRootConfig()
+	if err != nil {
+		return "", err
+	}
+
+	if config.DefaultProfile == "" {
+		return DefaultProfile, nil
+	}
+
+	return config.DefaultProfile, nil
+}
+
+// Switch makes an existing profile the default for future invocations.
+func (p *ProfileManager) Switch(name string) error {
+	exists, err := p.Exists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	config, err := p.readRootConfig()
+	if err != nil {
+		return err
+	}
+
+	config.DefaultProfile = name
+	if name == DefaultProfile {
+		config.DefaultProfile = ""
+	}
+
+	return p.writeRootConfig(config)
+}
+
+// Currency returns the stored default currency, DefaultCurrency when none has been stored.
+func (p *ProfileManager) Currency() (Currency, error) {
+	config, err := p.readRootConfig()
+	if err != nil {
+		return "", err
+	}
+
+	return config.Currency.orDefault(), nil
+}
+
+// SetCurrency stores the default currency for all profiles.
+func (p *ProfileManager) SetCurrency(currency Currency) error {
+	config, err := p.readRootConfig()
+	if err != nil {
+		return err
+	}
+
+	config.Currency = currency
+	return p.writeRootConfig(config)
+}
+
+// KeyBackend returns where new private keys are stored, BackendFile when nothing has been stored.
+func (p *ProfileManager) KeyBackend() (KeyBackend, error) {
+	config, err := p.readRootConfig()
+	if err != nil {
+		return "", err
+	}
+
+	return config.KeyBackend.orDefault(), nil
+}
+
+// SetKeyBackend stores where new private keys are stored for all profiles.
+func (p *ProfileManager) SetKeyBackend(backend KeyBackend) error {
+	config, err := p.readRootConfig()
+	if err != nil {
+		return err
+	}
+
+	config.KeyBackend = backend
+	return p.writeRootConfig(config)
+}
+
+// Locale returns the stored locale, DefaultLocale when none has been stored.
+func (p *ProfileManager) Locale() (Locale, error) {
+	config, err := p.readRootConfig()
+	if err != nil {
+		return "", err
+	}
+
+	return config.Locale.orDefault(), nil
+}
+
+// SetLocale stores the locale amounts are shown in for all profiles.
+func (p *ProfileManager) SetLocale(locale Locale) error {
+	config, err := p.readRootConfig()
+	if err != nil {
+		return err
+	}
+
+	config.Locale = locale
+	return p.writeRootConfig(config)
+}
+
+// writeRootConfig replaces the root config, creating the configuration directory if needed.
+func (p *ProfileManager) writeRootConfig(config RootConfig) error {
+	updatedData, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("error marshaling JSON: %w", err)
+	}
+
+	if err := guardWrite(p.ConfigDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.ConfigDir, 0700); err != nil {
+		return fmt.Errorf("error creating config directory: %w", err)
+	}
+
+	return p.FileWriter.WriteFile(filepath.Join(p.ConfigDir, RootConfigFile), updatedData)
+}
+
+// readRootConfig reads the root config, returning an empty one when it does not exist yet.
+func (p *ProfileManager) readRootConfig() (RootConfig, error) {
+	var config RootConfig
+
+	fileData, err := p.FileReader.ReadFile(filepath.Join(p.ConfigDir, RootConfigFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("error reading file: %w", err)
+	}
+
+	if err := json.Unmarshal(fileData, &config); err != nil {
+		return config, fmt.Errorf("error unmarshaling JSON: %w", err)
+	}
+
+	return config, nil
+}
+
+// WalletConfig returns a WalletConfig whose files live in the given profile.
+func (p *ProfileManager) WalletConfig(name string) (*WalletConfig, error) {
+	dir, err := p.existingDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	wc := NewWalletConfigInDir(dir)
+	wc.Profile = name
+	return wc, nil
+}
+
+// ReadOnlyContext returns a ReadOnlyContext for the given profile, for commands that never need a key.
+func (p *ProfileManager) ReadOnlyContext(name string) (*ReadOnlyContext, error) {
+	dir, err := p.existingDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := NewReadOnlyContextInDir(dir)
+	rc.Profile = name
+	return rc, nil
+}
+
+// existingDir returns the directory of a profile, failing when the profile has not been created.
+func (p *ProfileManager) existingDir(name string) (string, error) {
+	exists, err := p.Exists(name)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("%w: %s (create it with `wallet profile create %s`)", ErrProfileNotFound, name, name)
+	}
+
+	return p.Dir(name)
+}
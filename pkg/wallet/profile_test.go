@@ -0,0 +1,159 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestProfileManager(t *testing.T) *ProfileManager {
+	t.Helper()
+	return &ProfileManager{
+		ConfigDir:  t.TempDir(),
+		FileReader: &IOUtilFileReader{},
+		FileWriter: &IOUtilFileWriter{},
+	}
+}
+
+func TestProfileDir(t *testing.T) {
+	profiles := &ProfileManager{ConfigDir: "/home/me/.config/sleeng"}
+
+	tests := []struct {
+		name        string
+		profile     string
+		expectedDir string
+		expectedErr bool
+	}{
+		{name: "Default Profile Uses Config Directory", profile: DefaultProfile, expectedDir: "/home/me/.config/sleeng"},
+		{name: "Named Profile", profile: "work", expectedDir: "/home/me/.config/sleeng/profiles/work"},
+		{name: "Path Traversal", profile: "../work", expectedErr: true},
+		{name: "Nested Path", profile: "work/keys", expectedErr: true},
+		{name: "Empty", profile: "", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := profiles.Dir(tt.profile)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDir, got)
+		})
+	}
+
+	profiles.DataDir = "/home/me/.local/share/sleeng"
+	dir, err := profiles.Dir("work")
+	assert.NoError(t, err)
+	assert.Equal(t, "/home/me/.local/share/sleeng/profiles/work", dir)
+}
+
+func TestDataDir(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("profiles are kept in the configuration directory")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv(ConfigDirEnv, "")
+	t.Setenv(DataDirEnv, "")
+	configDir, dataDir := filepath.Join(home, "config", "sleeng"), filepath.Join(home, ".local", "share", "sleeng")
+
+	dir, err := DataDir()
+	assert.NoError(t, err)
+	assert.Equal(t, dataDir, dir)
+
+	// An install from before the data directory keeps its wallets where they are.
+	assert.NoError(t, os.MkdirAll(configDir, 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(configDir, KeyFileName), []byte("{}"), 0600))
+	dir, err = DataDir()
+	assert.NoError(t, err)
+	assert.Equal(t, configDir, dir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dataDir, "profiles"), 0700))
+	dir, err = DataDir()
+	assert.NoError(t, err)
+	assert.Equal(t, dataDir, dir)
+
+	assert.NoError(t, os.Remove(filepath.Join(configDir, KeyFileName)))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	dir, err = DataDir()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "data", "sleeng"), dir)
+
+	t.Setenv(ConfigDirEnv, filepath.Join(home, "install"))
+	dir, err = DataDir()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "install"), dir, "an overridden configuration directory holds the profiles too")
+
+	t.Setenv(DataDirEnv, filepath.Join(home, "wallets"))
+	dir, err = DataDir()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "wallets"), dir)
+}
+
+func TestProfileLifecycle(t *testing.T) {
+	profiles := newTestProfileManager(t)
+
+	active, err := profiles.Active()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultProfile, active)
+
+	assert.ErrorIs(t, profiles.Switch("work"), ErrProfileNotFound)
+
+	assert.NoError(t, profiles.Create("work"))
+	assert.NoError(t, profiles.Create("personal"))
+	assert.ErrorIs(t, profiles.Create("work"), ErrProfileExists)
+
+	names, err := profiles.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfile, "personal", "work"}, names)
+
+	assert.NoError(t, profiles.Switch("work"))
+	active, err = profiles.Active()
+	assert.NoError(t, err)
+	assert.Equal(t, "work", active)
+
+	assert.NoError(t, profiles.Switch(DefaultProfile))
+	active, err = profiles.Active()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultProfile, active)
+}
+
+func TestProfileIsolation(t *testing.T) {
+	profiles := newTestProfileManager(t)
+	assert.NoError(t, profiles.Create("work"))
+	assert.NoError(t, profiles.Create("personal"))
+
+	work, err := profiles.WalletConfig("work")
+	assert.NoError(t, err)
+	personal, err := profiles.WalletConfig("personal")
+	assert.NoError(t, err)
+
+	address, err := work.CreateNewWallet("client-escrow")
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(profiles.ConfigDir, "profiles", "work", KeyFileName))
+
+	hasWallets, err := personal.HasWallets()
+	assert.NoError(t, err)
+	assert.False(t, hasWallets, "a wallet created in one profile must not be visible in another")
+
+	_, err = personal.RetrieveWalletAddressByAlias("client-escrow")
+	assert.Error(t, err)
+
+	got, err := work.RetrieveCurrentWalletAddress()
+	assert.NoError(t, err)
+	assert.Equal(t, address, got)
+
+	_, err = solana.PublicKeyFromBase58(got)
+	assert.NoError(t, err)
+
+	_, err = profiles.WalletConfig("missing")
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+}
@@ -0,0 +1,173 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	// Phones save photos and screenshots as JPEG or PNG; GIF costs nothing to accept as well.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/makiuchi-d/gozxing"
+	multiqrcode "github.com/makiuchi-d/gozxing/multi/qrcode"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/mr-tron/base58"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/ed25519"
+)
+
+var (
+	ErrNoQRCode         = errors.New("no readable QR code found in the image; retake the photo sharper, closer and straight on")
+	ErrMultipleQRCodes  = errors.New("the image holds more than one QR code; crop it to the one to import")
+	ErrUnknownQRPayload = errors.New("the QR code holds neither a private key, a seed phrase nor a solana: URI")
+)
+
+// QRPayloadKind is what a QR code handed to `init --from-qr` turned out to hold.
+type QRPayloadKind string
+
+const (
+	QRPrivateKey QRPayloadKind = "private key"
+	QRSeedPhrase QRPayloadKind = "seed phrase"
+	// QRAddress is a public address, on its own or in a solana: URI. It can only be watched.
+	QRAddress QRPayloadKind = "address"
+)
+
+// QRPayload is the classified content of a QR code. Value is secret for a private key or seed
+// phrase, so it never appears in errors or in Describe.
+type QRPayload struct {
+	Kind QRPayloadKind
+	// Value is the private key in base58, the seed phrase with single spaces, or the address.
+	Value string
+	// Address is the address the payload controls or names; it is empty for a seed phrase, whose
+	// address depends on the account imported.
+	Address string
+}
+
+// Describe says what the payload holds without revealing any secret.
+func (p QRPayload) Describe() string {
+	switch p.Kind {
+	case QRPrivateKey:
+		return "the private key of " + p.Address
+	case QRSeedPhrase:
+		return fmt.Sprintf("a %d-word seed phrase", len(strings.Fields(p.Value)))
+	default:
+		return "the address " + p.Address
+	}
+}
+
+// DecodeQRImage reads the QR code in a PNG, JPEG or GIF image and returns its text. An image
+// without a readable code returns ErrNoQRCode, and one with several different codes
+// ErrMultipleQRCodes, since importing the wrong one of them would be easy to miss.
+func DecodeQRImage(r io.Reader) (string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the image: %w", err)
+	}
+
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the image: %w", err)
+	}
+	hints := map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_TRY_HARDER: true}
+
+	results, _ := multiqrcode.NewQRCodeMultiReader().DecodeMultiple(bitmap, hints)
+	texts := make(map[string]bool, len(results))
+	for _, result := range results {
+		texts[result.GetText()] = true
+	}
+	switch {
+	case len(texts) > 1:
+		return "", fmt.Errorf("%w (found %d)", ErrMultipleQRCodes, len(texts))
+	case len(texts) == 1:
+		return results[0].GetText(), nil
+	}
+
+	// The multi reader skips codes whose finder patterns it cannot group; the single reader is
+	// more forgiving with a lone, slightly skewed code.
+	result, err := qrcode.NewQRCodeReader().Decode(bitmap, hints)
+	if err != nil {
+		return "", ErrNoQRCode
+	}
+	return result.GetText(), nil
+}
+
+// ClassifyQRPayload tells what the text of a QR code holds: a private key in base58 or in the
+// solana-keygen JSON format, a BIP-39 seed phrase, or an address, on its own or as a solana: URI.
+func ClassifyQRPayload(text string) (QRPayload, error) {
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(strings.ToLower(text), "solana:") {
+		uri, err := url.Parse(text)
+		if err != nil || uri.Opaque == "" {
+			return QRPayload{}, fmt.Errorf("%w: the solana: URI names no account", ErrUnknownQRPayload)
+		}
+		return classifyBase58(uri.Opaque)
+	}
+
+	if strings.HasPrefix(text, "[") {
+		key, err := ParseKeypair([]byte(text))
+		if err != nil {
+			return QRPayload{}, err
+		}
+		return privateKeyPayload(key), nil
+	}
+
+	if words := strings.Fields(strings.ToLower(text)); len(words) > 1 {
+		phrase := strings.Join(words, " ")
+		if !bip39.IsMnemonicValid(phrase) {
+			return QRPayload{}, fmt.Errorf("%w: the %d words are not a valid seed phrase", ErrUnknownQRPayload, len(words))
+		}
+		return QRPayload{Kind: QRSeedPhrase, Value: phrase}, nil
+	}
+
+	return classifyBase58(text)
+}
+
+// classifyBase58 tells a 64-byte private key from a 32-byte address.
+func classifyBase58(text string) (QRPayload, error) {
+	decoded, err := base58.Decode(text)
+	if err != nil {
+		return QRPayload{}, ErrUnknownQRPayload
+	}
+
+	switch len(decoded) {
+	case ed25519.PrivateKeySize:
+		key := ed25519.PrivateKey(decoded)
+		if !bytes.Equal(ed25519.NewKeyFromSeed(key.Seed()), key) {
+			return QRPayload{}, ErrKeypairMismatch
+		}
+		return privateKeyPayload(key), nil
+	case ed25519.PublicKeySize:
+		address := solana.PublicKeyFromBytes(decoded).String()
+		return QRPayload{Kind: QRAddress, Value: address, Address: address}, nil
+	default:
+		return QRPayload{}, fmt.Errorf("%w: %d bytes are neither a key nor an address", ErrUnknownQRPayload, len(decoded))
+	}
+}
+
+func privateKeyPayload(key ed25519.PrivateKey) QRPayload {
+	privateKey := solana.PrivateKey(key)
+	return QRPayload{Kind: QRPrivateKey, Value: privateKey.String(), Address: privateKey.PublicKey().String()}
+}
+
+// ReadQRFile decodes the QR code in an image file and classifies its payload.
+func ReadQRFile(path string) (QRPayload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return QRPayload{}, fmt.Errorf("failed to open the image: %w", err)
+	}
+	defer f.Close()
+
+	text, err := DecodeQRImage(f)
+	if err != nil {
+		return QRPayload{}, err
+	}
+	return ClassifyQRPayload(text)
+}
@@ -0,0 +1,183 @@
+package wallet
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// qrSize is the width and height of each generated QR code in pixels.
+const qrSize = 240
+
+// qrFixture renders texts as QR codes next to each other on a white image, the way a photo of
+// one or more phone screens would show them.
+func qrFixture(t *testing.T, texts ...string) *image.Gray {
+	t.Helper()
+	canvas := image.NewGray(image.Rect(0, 0, qrSize*len(texts)+40*(len(texts)-1), qrSize))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+
+	for i, text := range texts {
+		code, err := qrcode.NewQRCodeWriter().Encode(text, gozxing.BarcodeFormat_QR_CODE, qrSize, qrSize, nil)
+		assert.NoError(t, err)
+		offset := image.Pt(i*(qrSize+40), 0)
+		draw.Draw(canvas, code.Bounds().Add(offset), code, image.Point{}, draw.Src)
+	}
+	return canvas
+}
+
+// blur averages every pixel with its neighbours within radius, like an out-of-focus photo.
+func blur(img *image.Gray, radius int) *image.Gray {
+	bounds := img.Bounds()
+	out := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			var sum, n int
+			for dy := -radius; dy <= radius; dy++ {
+				for dx := -radius; dx <= radius; dx++ {
+					if p := image.Pt(x+dx, y+dy); p.In(bounds) {
+						sum += int(img.GrayAt(p.X, p.Y).Y)
+						n++
+					}
+				}
+			}
+			out.SetGray(x, y, color.Gray{Y: uint8(sum / n)})
+		}
+	}
+	return out
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestDecodeQRImage(t *testing.T) {
+	address := solana.NewWallet().PublicKey().String()
+
+	var photo bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&photo, qrFixture(t, address), &jpeg.Options{Quality: 80}))
+
+	tests := []struct {
+		name        string
+		image       []byte
+		expected    string
+		expectedErr error
+	}{
+		{name: "PNG", image: encodePNG(t, qrFixture(t, address)), expected: address},
+		{name: "JPEG Photo", image: photo.Bytes(), expected: address},
+		{name: "Same Code Twice", image: encodePNG(t, qrFixture(t, address, address)), expected: address},
+		{name: "Two Different Codes", image: encodePNG(t, qrFixture(t, address, solana.NewWallet().PublicKey().String())), expectedErr: ErrMultipleQRCodes},
+		{name: "Blurry", image: encodePNG(t, blur(qrFixture(t, address), 6)), expectedErr: ErrNoQRCode},
+		{name: "No Code", image: encodePNG(t, image.NewGray(image.Rect(0, 0, qrSize, qrSize))), expectedErr: ErrNoQRCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := DecodeQRImage(bytes.NewReader(tt.image))
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, text)
+		})
+	}
+
+	_, err := DecodeQRImage(strings.NewReader("not an image"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read the image")
+}
+
+func TestClassifyQRPayload(t *testing.T) {
+	key := solana.NewWallet().PrivateKey
+	address := key.PublicKey().String()
+	entropy, err := bip39.NewEntropy(128)
+	assert.NoError(t, err)
+	seed, err := bip39.NewMnemonic(entropy)
+	assert.NoError(t, err)
+
+	values := make([]int, len(key))
+	for i, b := range key {
+		values[i] = int(b)
+	}
+	keypairJSON := string(jsonMarshalValue(t, values))
+
+	tampered := make(solana.PrivateKey, len(key))
+	copy(tampered, key)
+	copy(tampered[32:], solana.NewWallet().PublicKey().Bytes())
+
+	tests := []struct {
+		name        string
+		text        string
+		expected    QRPayload
+		expectedErr error
+		// secret payloads must never end up in an error.
+		secret bool
+	}{
+		{name: "Base58 Private Key", text: key.String(), expected: QRPayload{Kind: QRPrivateKey, Value: key.String(), Address: address}},
+		{name: "Keypair JSON", text: keypairJSON, expected: QRPayload{Kind: QRPrivateKey, Value: key.String(), Address: address}},
+		{name: "Seed Phrase", text: "  " + strings.ToUpper(seed) + "\n", expected: QRPayload{Kind: QRSeedPhrase, Value: seed}},
+		{name: "Address", text: address, expected: QRPayload{Kind: QRAddress, Value: address, Address: address}},
+		{name: "Solana Pay URI", text: "solana:" + address + "?amount=1.5&label=Coffee", expected: QRPayload{Kind: QRAddress, Value: address, Address: address}},
+		{name: "Solana URI With A Key", text: "solana:" + key.String(), expected: QRPayload{Kind: QRPrivateKey, Value: key.String(), Address: address}},
+		{name: "Key Of Another Address", text: tampered.String(), expectedErr: ErrKeypairMismatch, secret: true},
+		{name: "Invalid Seed Phrase", text: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", expectedErr: ErrUnknownQRPayload, secret: true},
+		{name: "Website", text: "https://example.com", expectedErr: ErrUnknownQRPayload},
+		{name: "Empty Solana URI", text: "solana:", expectedErr: ErrUnknownQRPayload},
+		{name: "Short Base58", text: "3yZe7d", expectedErr: ErrUnknownQRPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := ClassifyQRPayload(tt.text)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				if tt.secret {
+					assert.NotContains(t, err.Error(), tt.text)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, payload)
+		})
+	}
+}
+
+func TestQRPayloadDescribeHidesSecrets(t *testing.T) {
+	key := solana.NewWallet().PrivateKey
+	payload, err := ClassifyQRPayload(key.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "the private key of "+key.PublicKey().String(), payload.Describe())
+
+	seed := QRPayload{Kind: QRSeedPhrase, Value: strings.Repeat("word ", 23) + "word"}
+	assert.Equal(t, "a 24-word seed phrase", seed.Describe())
+}
+
+func TestReadQRFile(t *testing.T) {
+	key := solana.NewWallet().PrivateKey
+	path := filepath.Join(t.TempDir(), "photo.png")
+	assert.NoError(t, os.WriteFile(path, encodePNG(t, qrFixture(t, key.String())), 0o600))
+
+	payload, err := ReadQRFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, QRPrivateKey, payload.Kind)
+	assert.Equal(t, key.PublicKey().String(), payload.Address)
+
+	_, err = ReadQRFile(filepath.Join(t.TempDir(), "missing.png"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,285 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Defaults of the quickstart.
+const (
+	DefaultQuickstartAlias   = "quickstart"
+	DefaultScratchAlias      = "quickstart-scratch"
+	DefaultQuickstartAirdrop = solana.LAMPORTS_PER_SOL
+	// DefaultQuickstartAmount is sent to the scratch wallet. It is above the rent-exempt minimum of
+	// an empty account, which a transfer to a new account has to cover.
+	DefaultQuickstartAmount = solana.LAMPORTS_PER_SOL / 1000
+)
+
+var ErrQuickstartMainnet = errors.New("the quickstart airdrops and sends test funds, so it does not run on mainnet-beta")
+
+// quickstartHistoryTimeout bounds how long the quickstart waits for the transfer to show up in the
+// history, which only lists finalized transactions.
+var quickstartHistoryTimeout = time.Minute
+
+// quickstartPollInterval is how often the history is checked for the transfer.
+var quickstartPollInterval = 2 * time.Second
+
+// QuickstartStep is a step of the quickstart, in the order they run.
+type QuickstartStep string
+
+const (
+	QuickstartWallet  QuickstartStep = "wallet"
+	QuickstartAirdrop QuickstartStep = "airdrop"
+	QuickstartScratch QuickstartStep = "scratch"
+	QuickstartSend    QuickstartStep = "send"
+	QuickstartHistory QuickstartStep = "history"
+)
+
+// QuickstartSteps lists the steps of the quickstart in order.
+var QuickstartSteps = []QuickstartStep{QuickstartWallet, QuickstartAirdrop, QuickstartScratch, QuickstartSend, QuickstartHistory}
+
+// QuickstartOptions controls Quickstart. Zero values select the defaults.
+type QuickstartOptions struct {
+	// Alias is the wallet to fund and send from. Empty means the active wallet, or a new wallet named
+	// DefaultQuickstartAlias when there is none.
+	Alias string
+	// ScratchAlias names the wallet the transfer is sent to. It is created unless it exists.
+	ScratchAlias string
+	// Airdrop is how many lamports to request from the faucet.
+	Airdrop uint64
+	// Amount is how many lamports to send to the scratch wallet.
+	Amount uint64
+}
+
+func (o QuickstartOptions) withDefaults() QuickstartOptions {
+	if o.ScratchAlias == "" {
+		o.ScratchAlias = DefaultScratchAlias
+	}
+	if o.Airdrop == 0 {
+		o.Airdrop = DefaultQuickstartAirdrop
+	}
+	if o.Amount == 0 {
+		o.Amount = DefaultQuickstartAmount
+	}
+	return o
+}
+
+// QuickstartResult is what the quickstart did, as far as it got.
+type QuickstartResult struct {
+	// Completed lists the steps that finished, in order.
+	Completed []QuickstartStep
+	Alias     string
+	Address   string
+	// Created is true when the wallet was created by the quickstart.
+	Created bool
+	// Airdropped is what the faucet sent, 0 when the wallet already held enough and none was requested.
+	Airdropped       uint64
+	AirdropSignature string
+	// Balance is the balance of the wallet after the airdrop.
+	Balance        uint64
+	ScratchAlias   string
+	ScratchAddress string
+	// ScratchCreated is true when the scratch wallet was created by the quickstart.
+	ScratchCreated bool
+	Receipt        *SendReceipt
+	// Transaction is the transfer as the history shows it, nil when it was not finalized in time.
+	Transaction *Transaction
+}
+
+// Quickstart walks a new user through the first steps on a test cluster with the same operations
+// the commands use: it selects or creates a wallet, airdrops SOL to it, creates a scratch wallet,
+// sends a small amount to it and finds the transfer in the history. step is called as each step
+// starts. Cancelling ctx stops the quickstart after the step in progress; the result lists what
+// was done so far.
+func (w *WalletConfig) Quickstart(ctx context.Context, opts QuickstartOptions, step func(QuickstartStep)) (*QuickstartResult, error) {
+	if w.NetworkName() == string(MainnetBeta) {
+		return nil, ErrQuickstartMainnet
+	}
+	opts = opts.withDefaults()
+	if opts.Airdrop > maxAirdropLamports {
+		return nil, fmt.Errorf("an airdrop of %s SOL exceeds the faucet limit of %s SOL", LamportsToSOL(opts.Airdrop), LamportsToSOL(maxAirdropLamports))
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	result := &QuickstartResult{}
+	steps := map[QuickstartStep]func() error{
+		QuickstartWallet:  func() error { return w.quickstartWallet(opts, result) },
+		QuickstartAirdrop: func() error { return w.quickstartAirdrop(ctx, client, opts, result) },
+		QuickstartScratch: func() error { return w.quickstartScratch(opts, result) },
+		QuickstartSend:    func() error { return w.quickstartSend(ctx, opts, result) },
+		QuickstartHistory: func() error { return w.quickstartHistory(ctx, result) },
+	}
+	for _, name := range QuickstartSteps {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if step != nil {
+			step(name)
+		}
+		if err := steps[name](); err != nil {
+			return result, fmt.Errorf("%s step failed: %w", name, err)
+		}
+		result.Completed = append(result.Completed, name)
+	}
+	return result, nil
+}
+
+// quickstartWallet selects the wallet to use, creating one when there is none, and makes it active
+// so the send goes out from it.
+func (w *WalletConfig) quickstartWallet(opts QuickstartOptions, result *QuickstartResult) error {
+	info, err := w.KeyOps.GetWalletInfo(opts.Alias)
+	switch {
+	case err == nil && info.WatchOnly:
+		return fmt.Errorf("%s: %w; pick a wallet with its key", info.Alias, ErrWatchOnlyWallet)
+	case err == nil:
+		result.Alias, result.Address = info.Alias, info.PublicKey
+		return w.KeyOps.SetActiveKey(info.Alias)
+	case opts.Alias != "" || !(errors.Is(err, ErrActiveWalletNotFound) || errors.Is(err, os.ErrNotExist)):
+		return err
+	}
+
+	address, err := w.CreateNewWallet(DefaultQuickstartAlias)
+	if err != nil {
+		return err
+	}
+	result.Alias, result.Address, result.Created = DefaultQuickstartAlias, address, true
+	return nil
+}
+
+// quickstartAirdrop requests an airdrop unless the wallet already holds the amount, and waits for it.
+func (w *WalletConfig) quickstartAirdrop(ctx context.Context, client ClientInterface, opts QuickstartOptions, result *QuickstartResult) error {
+	account, err := solana.PublicKeyFromBase58(result.Address)
+	if err != nil {
+		return fmt.Errorf("invalid wallet address: %w", err)
+	}
+
+	balance, err := client.GetBalance(ctx, account, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to fetch balance: %w", err)
+	}
+	result.Balance = balance.Value
+	if balance.Value >= opts.Airdrop {
+		return nil
+	}
+
+	sig, err := requestAirdrop(ctx, client, account, opts.Airdrop)
+	if err != nil {
+		return err
+	}
+	result.AirdropSignature = sig.String()
+
+	waitCtx, cancel := context.WithTimeout(ctx, airdropTimeout)
+	defer cancel()
+	if err := awaitSignature(waitCtx, client, sig); err != nil {
+		return fmt.Errorf("airdrop was not confirmed: %w", err)
+	}
+	result.Airdropped = opts.Airdrop
+
+	balance, err = client.GetBalance(ctx, account, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to fetch balance: %w", err)
+	}
+	result.Balance = balance.Value
+	return nil
+}
+
+// quickstartScratch creates the scratch wallet, or reuses it from an earlier run. Storing a wallet
+// makes it active, so the quickstart wallet is made active again afterwards.
+func (w *WalletConfig) quickstartScratch(opts QuickstartOptions, result *QuickstartResult) error {
+	result.ScratchAlias = opts.ScratchAlias
+	if address, err := w.KeyOps.GetPublicKeyByAlias(opts.ScratchAlias); err == nil {
+		result.ScratchAddress = address
+		return nil
+	}
+
+	address, err := w.CreateNewWallet(opts.ScratchAlias)
+	if err != nil {
+		return err
+	}
+	result.ScratchAddress, result.ScratchCreated = address, true
+	return w.KeyOps.SetActiveKey(result.Alias)
+}
+
+// quickstartSend sends the amount to the scratch wallet the way the send command does.
+func (w *WalletConfig) quickstartSend(ctx context.Context, opts QuickstartOptions, result *QuickstartResult) error {
+	sendOpts := SendOptions{Unit: UnitLamports}
+	quote, err := w.PrepareSend(ctx, strconv.FormatUint(opts.Amount, 10), result.ScratchAddress, sendOpts)
+	if err != nil {
+		return err
+	}
+
+	result.Receipt, err = w.ExecuteSend(ctx, quote, sendOpts)
+	return err
+}
+
+// quickstartHistory waits for the transfer to show up in the history of the wallet. The history
+// only lists finalized transactions, so one that takes longer than quickstartHistoryTimeout is left
+// out rather than failing the quickstart.
+func (w *WalletConfig) quickstartHistory(ctx context.Context, result *QuickstartResult) error {
+	deadline := time.Now().Add(quickstartHistoryTimeout)
+	for {
+		transactions, err := w.GetTransactionHistoryWithOpts(GetTransactionHistoryOpts{Limit: 5})
+		if err != nil {
+			return err
+		}
+		for _, tx := range transactions {
+			if tx.Signature.String() == result.Receipt.Signature {
+				result.Transaction = tx
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(quickstartPollInterval):
+		}
+	}
+}
+
+// RemoveQuickstartScratch sends the balance of the scratch wallet back to the quickstart wallet and
+// removes the scratch wallet from the key file. It returns what was sent back.
+func (w *WalletConfig) RemoveQuickstartScratch(ctx context.Context, result *QuickstartResult) (*SendReceipt, error) {
+	encoded, err := w.KeyOps.GetPrivateKeyByAlias(result.ScratchAlias)
+	if err != nil {
+		return nil, err
+	}
+	key, err := getPrivateKeyFromSolCLICompStr(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key for %s: %w", result.ScratchAlias, err)
+	}
+
+	// Send from the scratch wallet for this transfer only, like --key does.
+	defer func(previous *solana.Wallet) { w.Wallet = previous }(w.Wallet)
+	w.Wallet = &solana.Wallet{PrivateKey: solana.PrivateKey(key)}
+
+	sendOpts := SendOptions{Unit: UnitLamports}
+	quote, err := w.PrepareSend(ctx, MaxKeyword, result.Address, sendOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to return the funds of %s: %w", result.ScratchAlias, err)
+	}
+	receipt, err := w.ExecuteSend(ctx, quote, sendOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to return the funds of %s: %w", result.ScratchAlias, err)
+	}
+
+	if _, err := w.KeyOps.DeleteKey(result.ScratchAlias); err != nil {
+		return receipt, err
+	}
+	return receipt, nil
+}
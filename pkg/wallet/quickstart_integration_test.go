@@ -0,0 +1,73 @@
+//go:build integration
+
+package wallet
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// testValidatorEnv points the integration tests at a validator other than a local
+// solana-test-validator. Run them with:
+//
+//	solana-test-validator --reset --quiet &
+//	go test -tags integration ./pkg/wallet -run Integration
+const testValidatorEnv = "SLEENG_TEST_VALIDATOR"
+
+// testValidator returns the RPC URL of the validator, skipping the test when it does not answer.
+func testValidator(t *testing.T) string {
+	url := os.Getenv(testValidatorEnv)
+	if url == "" {
+		url = "http://127.0.0.1:8899"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := rpc.New(url).GetHealth(ctx); err != nil {
+		t.Skipf("no validator at %s (%v); start solana-test-validator or set %s", url, err, testValidatorEnv)
+	}
+	return url
+}
+
+func TestQuickstartIntegration(t *testing.T) {
+	url := testValidator(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	wc := NewWalletConfigInDir(t.TempDir())
+	assert.NoError(t, wc.UseNetwork("", url))
+
+	var steps []QuickstartStep
+	result, err := wc.Quickstart(ctx, QuickstartOptions{}, func(step QuickstartStep) { steps = append(steps, step) })
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, QuickstartSteps, steps)
+	assert.True(t, result.Created)
+	assert.Equal(t, uint64(DefaultQuickstartAirdrop), result.Airdropped)
+	if assert.NotNil(t, result.Transaction, "the transfer was not finalized in time") {
+		assert.Equal(t, result.Receipt.Signature, result.Transaction.Signature.String())
+		assert.Equal(t, solana.MustPublicKeyFromBase58(result.ScratchAddress), result.Transaction.To)
+	}
+
+	client := rpc.New(url)
+	scratch, err := client.GetBalance(ctx, solana.MustPublicKeyFromBase58(result.ScratchAddress), rpc.CommitmentConfirmed)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(DefaultQuickstartAmount), scratch.Value)
+
+	// Cleaning up returns the scratch balance, less the fee, and forgets the scratch wallet.
+	receipt, err := wc.RemoveQuickstartScratch(ctx, result)
+	assert.NoError(t, err)
+	if assert.NotNil(t, receipt) {
+		assert.Less(t, receipt.Lamports, uint64(DefaultQuickstartAmount))
+	}
+	aliases, err := wc.KeyOps.ListAliases()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultQuickstartAlias}, aliases)
+}
@@ -0,0 +1,176 @@
+package wallet
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// quickstartCluster is an RPC client that lands every transaction at once: airdrops credit the
+// account and sent transactions show up in the history of every address.
+type quickstartCluster struct {
+	mu        sync.Mutex
+	balances  map[solana.PublicKey]uint64
+	airdrops  int
+	history   []*rpc.TransactionSignature
+	transfers map[solana.Signature][2]solana.PublicKey
+}
+
+func (c *quickstartCluster) client(t *testing.T) *MockClientInterface {
+	fee := uint64(5000)
+	confirmed := func(_ context.Context, _ bool, sigs ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+		return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{{ConfirmationStatus: rpc.ConfirmationStatusFinalized}}}, nil
+	}
+
+	return &MockClientInterface{
+		GetBalanceFn: func(_ context.Context, account solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return &rpc.GetBalanceResult{Value: c.balances[account]}, nil
+		},
+		RequestAirdropFn: func(_ context.Context, account solana.PublicKey, lamports uint64, _ rpc.CommitmentType) (solana.Signature, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.airdrops++
+			c.balances[account] += lamports
+			return solana.Signature{byte(c.airdrops), 7}, nil
+		},
+		GetLatestBlockhashFn: func(context.Context, rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+			return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}, LastValidBlockHeight: 100}}, nil
+		},
+		GetFeeForMessageFn: func(context.Context, string, rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+			return &rpc.GetFeeForMessageResult{Value: &fee}, nil
+		},
+		SendTransactionWithOptsFn: func(_ context.Context, tx *solana.Transaction, _ rpc.TransactionOpts) (solana.Signature, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			keys := tx.Message.AccountKeys
+			c.balances[keys[0]] -= fee
+			for _, inst := range tx.Message.Instructions {
+				// A system transfer is the instruction index 2 followed by the lamports.
+				if keys[inst.ProgramIDIndex].Equals(solana.SystemProgramID) && len(inst.Data) == 12 {
+					from, to := keys[inst.Accounts[0]], keys[inst.Accounts[1]]
+					lamports := binary.LittleEndian.Uint64(inst.Data[4:])
+					c.balances[from] -= lamports
+					c.balances[to] += lamports
+					c.transfers[tx.Signatures[0]] = [2]solana.PublicKey{from, to}
+				}
+			}
+			c.history = append([]*rpc.TransactionSignature{{Signature: tx.Signatures[0], Slot: uint64(len(c.history) + 1)}}, c.history...)
+			return tx.Signatures[0], nil
+		},
+		GetSignatureStatusesFn: confirmed,
+		GetBlockHeightFn: func(context.Context, rpc.CommitmentType) (uint64, error) {
+			return 1, nil
+		},
+		GetSignaturesForAddressWithOptsFn: func(context.Context, solana.PublicKey, *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.history, nil
+		},
+		GetTokenAccountsByOwnerFn: func(context.Context, solana.PublicKey, *rpc.GetTokenAccountsConfig, *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+			return &rpc.GetTokenAccountsResult{}, nil
+		},
+		GetTransactionFn: func(_ context.Context, sig solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			transfer := c.transfers[sig]
+			return transferResultTo(t, transfer[0], transfer[1], transfer[0]), nil
+		},
+		GetMinimumBalanceForRentExemptionFn: rentExemptMinimum,
+	}
+}
+
+func TestQuickstart(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	defer func(interval time.Duration) { confirmPollInterval = interval }(confirmPollInterval)
+	confirmPollInterval = time.Millisecond
+
+	tests := []struct {
+		name            string
+		existing        bool
+		expectedAlias   string
+		expectedAirdrop uint64
+	}{
+		{name: "Creates A Wallet", expectedAlias: DefaultQuickstartAlias, expectedAirdrop: DefaultQuickstartAirdrop},
+		{name: "Uses The Funded Active Wallet", existing: true, expectedAlias: "main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			keyOps := &KeyOps{FileReader: files, FileWriter: files}
+			cluster := &quickstartCluster{balances: make(map[solana.PublicKey]uint64), transfers: make(map[solana.Signature][2]solana.PublicKey)}
+			if tt.existing {
+				main := solana.NewWallet()
+				assert.NoError(t, keyOps.WriteKeyToFile("main", []byte(main.PrivateKey), main.PublicKey().String()))
+				cluster.balances[main.PublicKey()] = 5 * solana.LAMPORTS_PER_SOL
+			}
+			newRPCClient = func(string) ClientInterface { return cluster.client(t) }
+
+			wc := &WalletConfig{KeyOps: keyOps, Network: Devnet, Pending: &PendingStore{FileReader: files, FileWriter: files}}
+			var steps []QuickstartStep
+			result, err := wc.Quickstart(context.Background(), QuickstartOptions{}, func(step QuickstartStep) { steps = append(steps, step) })
+			assert.NoError(t, err)
+			assert.Equal(t, QuickstartSteps, steps)
+			assert.Equal(t, QuickstartSteps, result.Completed)
+
+			assert.Equal(t, tt.expectedAlias, result.Alias)
+			assert.Equal(t, !tt.existing, result.Created)
+			assert.Equal(t, tt.expectedAirdrop, result.Airdropped)
+			assert.True(t, result.ScratchCreated)
+			assert.Equal(t, DefaultQuickstartAmount, result.Receipt.Lamports)
+			if assert.NotNil(t, result.Transaction) {
+				assert.Equal(t, result.Receipt.Signature, result.Transaction.Signature.String())
+			}
+
+			// The quickstart wallet stays active although the scratch wallet was stored after it.
+			active, err := keyOps.GetCurrentPublicKey()
+			assert.NoError(t, err)
+			assert.Equal(t, result.Address, active)
+
+			receipt, err := wc.RemoveQuickstartScratch(context.Background(), result)
+			assert.NoError(t, err)
+			if assert.NotNil(t, receipt) {
+				assert.Equal(t, DefaultQuickstartAmount-5000, receipt.Lamports)
+			}
+			scratch := solana.MustPublicKeyFromBase58(result.ScratchAddress)
+			assert.Zero(t, cluster.balances[scratch])
+			_, err = keyOps.GetPublicKeyByAlias(DefaultScratchAlias)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestQuickstartRefusesMainnet(t *testing.T) {
+	wc := &WalletConfig{Network: MainnetBeta}
+	_, err := wc.Quickstart(context.Background(), QuickstartOptions{}, nil)
+	assert.ErrorIs(t, err, ErrQuickstartMainnet)
+}
+
+func TestQuickstartStopsWhenCancelled(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	files := newMemFiles()
+	cluster := &quickstartCluster{balances: make(map[solana.PublicKey]uint64), transfers: make(map[solana.Signature][2]solana.PublicKey)}
+	newRPCClient = func(string) ClientInterface { return cluster.client(t) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}, Network: Devnet}
+	result, err := wc.Quickstart(ctx, QuickstartOptions{}, func(step QuickstartStep) {
+		// The step that is starting still runs.
+		if step == QuickstartScratch {
+			cancel()
+		}
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []QuickstartStep{QuickstartWallet, QuickstartAirdrop, QuickstartScratch}, result.Completed)
+	assert.Empty(t, cluster.history)
+}
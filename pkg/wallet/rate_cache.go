@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RateCacheFileName is the file, in the cache directory, holding the last rate fetched in each currency.
+const RateCacheFileName = "rates.json"
+
+// DefaultRateCacheTTL is how long a rate fetched by one command is reused by the next ones.
+const DefaultRateCacheTTL = 5 * time.Minute
+
+// RateCacheTTLEnv sets how long rates are reused across commands, as a duration such as 10m.
+const RateCacheTTLEnv = "SLEENG_RATE_TTL"
+
+// DiskRates keeps the rates of Provider on disk for TTL, so commands run within a few minutes of
+// each other fetch a rate once. Like GenesisCache it is only a cache: an unreadable file counts as
+// empty, failing to store a rate only means it is fetched again, and nothing is stored in read-only
+// mode.
+type DiskRates struct {
+	Provider   RateProvider
+	FileReader FileReader
+	FileWriter FileWriter
+	// Dir is the cache directory. Empty means the cache directory under ConfigDir.
+	Dir string
+	// TTL is how long a stored rate is reused. Zero turns the cache off.
+	TTL time.Duration
+	// Fresh fetches every rate instead of reusing a stored one, and stores the result.
+	Fresh bool
+	// Now returns the current time; it is a field so tests can expire entries.
+	Now func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewDiskRates caches the rates of provider under the configuration directory for DefaultRateCacheTTL.
+func NewDiskRates(provider RateProvider) *DiskRates {
+	return &DiskRates{Provider: provider, FileReader: &IOUtilFileReader{}, FileWriter: newFileWriter(), TTL: DefaultRateCacheTTL, Now: time.Now}
+}
+
+// defaultDiskRates is the disk layer of DefaultRates, configured with SetRateCache.
+var defaultDiskRates = NewDiskRates(FallbackRates{&KrakenRates{}, &CoinGeckoRates{}})
+
+// SetRateCache configures how long DefaultRates reuses rates fetched by earlier commands, and whether
+// this process fetches them afresh. Call it before the first rate is fetched.
+func SetRateCache(ttl time.Duration, fresh bool) {
+	defaultDiskRates.mu.Lock()
+	defer defaultDiskRates.mu.Unlock()
+	defaultDiskRates.TTL = ttl
+	defaultDiskRates.Fresh = fresh
+}
+
+// rateCacheEntry is a rate and when it was fetched.
+type rateCacheEntry struct {
+	Rate    decimal.Decimal `json:"rate"`
+	Fetched time.Time       `json:"fetched"`
+}
+
+func (d *DiskRates) Name() string {
+	return d.Provider.Name()
+}
+
+// SOLRate returns the stored rate in currency while it is fresh, and otherwise fetches and stores it.
+func (d *DiskRates) SOLRate(ctx context.Context, currency Currency) (decimal.Decimal, error) {
+	d.mu.Lock()
+	reuse := d.TTL > 0 && !d.Fresh
+	d.mu.Unlock()
+
+	if reuse {
+		if rate, ok := d.lookup(currency); ok {
+			return rate, nil
+		}
+	}
+	return d.Refresh(ctx, currency)
+}
+
+// Refresh fetches the rate past the stored one and stores it.
+func (d *DiskRates) Refresh(ctx context.Context, currency Currency) (decimal.Decimal, error) {
+	rate, err := d.Provider.SOLRate(ctx, currency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	d.store(currency, rate)
+	return rate, nil
+}
+
+func (d *DiskRates) filePath() string {
+	dir := d.Dir
+	if dir == "" {
+		configDir, err := ConfigDir()
+		if err != nil {
+			configDir = os.TempDir()
+		}
+		dir = filepath.Join(configDir, "cache")
+	}
+	return filepath.Join(dir, RateCacheFileName)
+}
+
+// entries reads the cache. A missing or unreadable cache is empty.
+func (d *DiskRates) entries() map[Currency]rateCacheEntry {
+	entries := make(map[Currency]rateCacheEntry)
+	data, err := d.FileReader.ReadFile(d.filePath())
+	if err == nil {
+		_ = json.Unmarshal(data, &entries)
+	}
+	return entries
+}
+
+// lookup returns the stored rate in currency while it is fresh.
+func (d *DiskRates) lookup(currency Currency) (decimal.Decimal, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.entries()[currency]
+	age := d.Now().Sub(entry.Fetched)
+	if !ok || !entry.Rate.IsPositive() || age < 0 || age > d.TTL {
+		return decimal.Zero, false
+	}
+	return entry.Rate, true
+}
+
+// store records the rate in currency, unless the cache is off.
+func (d *DiskRates) store(currency Currency, rate decimal.Decimal) {
+	if ReadOnlyMode() {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.TTL <= 0 {
+		return
+	}
+
+	entries := d.entries()
+	entries[currency] = rateCacheEntry{Rate: rate, Fetched: d.Now().UTC()}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	path := d.filePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	_ = d.FileWriter.WriteFile(path, data)
+}
@@ -0,0 +1,68 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskRates(t *testing.T) {
+	dir := t.TempDir()
+	provider := &countingRates{rate: decimal.NewFromInt(140)}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	newCache := func() *DiskRates {
+		cache := NewDiskRates(provider)
+		cache.Dir = dir
+		cache.Now = func() time.Time { return now }
+		return cache
+	}
+
+	rate, err := newCache().SOLRate(context.Background(), "EUR")
+	assert.NoError(t, err)
+	assert.True(t, provider.rate.Equal(rate))
+
+	// The next command reuses the rate the first one stored.
+	provider.rate = decimal.NewFromInt(150)
+	now = now.Add(4 * time.Minute)
+	rate, err = newCache().SOLRate(context.Background(), "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, "140", rate.String())
+	assert.Equal(t, 1, provider.calls)
+
+	_, err = newCache().SOLRate(context.Background(), "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, provider.calls, "each currency is cached separately")
+
+	fresh := newCache()
+	fresh.Fresh = true
+	rate, err = fresh.SOLRate(context.Background(), "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, "150", rate.String(), "--fresh fetches the rate again")
+	assert.Equal(t, 3, provider.calls)
+
+	now = now.Add(DefaultRateCacheTTL + time.Second)
+	provider.rate = decimal.NewFromInt(160)
+	rate, err = newCache().SOLRate(context.Background(), "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, "160", rate.String(), "an expired rate is fetched again")
+
+	off := newCache()
+	off.TTL = 0
+	provider.rate = decimal.NewFromInt(170)
+	rate, err = off.SOLRate(context.Background(), "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, "170", rate.String())
+	rate, err = newCache().SOLRate(context.Background(), "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, "160", rate.String(), "a zero TTL neither reuses nor stores rates")
+
+	// The daemon's refresher gets past both caches.
+	calls := provider.calls
+	rate, err = NewCachedRates(newCache(), time.Minute).Refresh(context.Background(), "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, "170", rate.String())
+	assert.Equal(t, calls+1, provider.calls)
+}
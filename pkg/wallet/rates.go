@@ -0,0 +1,160 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// rateHTTPTimeout bounds every request to a price API, so an unresponsive provider fails over
+// instead of hanging the command.
+const rateHTTPTimeout = 10 * time.Second
+
+// rateCacheTTL is how long a fetched rate is reused. It is long enough for one CLI invocation to
+// fetch each rate only once and short enough for the daemon to stay current.
+const rateCacheTTL = time.Minute
+
+// valueRateTimeout bounds the rate lookup that only values an amount given in SOL or lamports, so an
+// unresponsive price API delays such a transfer by at most this long.
+var valueRateTimeout = 2 * time.Second
+
+// rateHTTPClient is shared by the price APIs.
+var rateHTTPClient = &http.Client{Timeout: rateHTTPTimeout}
+
+var ErrRateUnavailable = errors.New("SOL exchange rate unavailable")
+
+// RateProvider fetches the current price of one SOL in a currency.
+type RateProvider interface {
+	// Name identifies the provider in error messages.
+	Name() string
+	SOLRate(ctx context.Context, currency Currency) (decimal.Decimal, error)
+}
+
+// DefaultRates is used wherever no RateProvider is set: Kraken, falling back to CoinGecko, cached
+// for the whole process and on disk for the commands that follow; see SetRateCache.
+var DefaultRates RateProvider = NewCachedRates(defaultDiskRates, rateCacheTTL)
+
+// ratesOrDefault returns provider, or DefaultRates when it is nil, timed as PhaseRate.
+func ratesOrDefault(provider RateProvider) RateProvider {
+	if provider == nil {
+		provider = DefaultRates
+	}
+	return timedRates{provider}
+}
+
+// timedRates measures the lookups of a RateProvider as PhaseRate.
+type timedRates struct {
+	RateProvider
+}
+
+func (t timedRates) SOLRate(ctx context.Context, currency Currency) (decimal.Decimal, error) {
+	defer TimePhase(PhaseRate)()
+	return t.RateProvider.SOLRate(ctx, currency)
+}
+
+// FallbackRates asks each provider in turn and returns the first rate one of them has.
+type FallbackRates []RateProvider
+
+func (f FallbackRates) Name() string {
+	names := make([]string, 0, len(f))
+	for _, provider := range f {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// SOLRate fails with ErrRateUnavailable, listing every provider's error, when none of them has the rate.
+func (f FallbackRates) SOLRate(ctx context.Context, currency Currency) (decimal.Decimal, error) {
+	failures := make([]string, 0, len(f))
+	for _, provider := range f {
+		rate, err := provider.SOLRate(ctx, currency)
+		if err == nil {
+			return rate, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", provider.Name(), err))
+	}
+
+	return decimal.Zero, fmt.Errorf("%w in %s (%s)", ErrRateUnavailable, currency, strings.Join(failures, "; "))
+}
+
+// CachedRates remembers the rates of Provider for TTL. Failures are not cached, so the next call
+// tries again.
+type CachedRates struct {
+	Provider RateProvider
+	TTL      time.Duration
+
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[Currency]cachedRate
+}
+
+type cachedRate struct {
+	rate    decimal.Decimal
+	fetched time.Time
+}
+
+// NewCachedRates caches the rates of provider for ttl.
+func NewCachedRates(provider RateProvider, ttl time.Duration) *CachedRates {
+	return &CachedRates{Provider: provider, TTL: ttl, now: time.Now, entries: make(map[Currency]cachedRate)}
+}
+
+func (c *CachedRates) Name() string {
+	return c.Provider.Name()
+}
+
+// SOLRate returns the cached rate while it is fresh. Concurrent callers wait for a single fetch.
+func (c *CachedRates) SOLRate(ctx context.Context, currency Currency) (decimal.Decimal, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[currency]; ok && c.now().Sub(entry.fetched) < c.TTL {
+		return entry.rate, nil
+	}
+
+	rate, err := c.Provider.SOLRate(ctx, currency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	c.entries[currency] = cachedRate{rate: rate, fetched: c.now()}
+	return rate, nil
+}
+
+// Refresh fetches the rate even when the cached one is still fresh and caches the result, so
+// that a background refresher keeps lookups from ever waiting for the provider. A Provider that
+// caches too is refreshed past its cache.
+func (c *CachedRates) Refresh(ctx context.Context, currency Currency) (decimal.Decimal, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fetch := c.Provider.SOLRate
+	if refresher, ok := c.Provider.(interface {
+		Refresh(context.Context, Currency) (decimal.Decimal, error)
+	}); ok {
+		fetch = refresher.Refresh
+	}
+	rate, err := fetch(ctx, currency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	c.entries[currency] = cachedRate{rate: rate, fetched: c.now()}
+	return rate, nil
+}
+
+// StaticRate is a fixed rate in every currency, for tests and offline use.
+type StaticRate decimal.Decimal
+
+func (s StaticRate) Name() string {
+	return "static"
+}
+
+func (s StaticRate) SOLRate(context.Context, Currency) (decimal.Decimal, error) {
+	return decimal.Decimal(s), nil
+}
@@ -0,0 +1,194 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingRates never has a rate.
+type failingRates struct{}
+
+func (failingRates) Name() string { return "failing" }
+
+func (failingRates) SOLRate(context.Context, Currency) (decimal.Decimal, error) {
+	return decimal.Zero, errors.New("provider is down")
+}
+
+// countingRates returns a fixed rate and counts how often it was asked.
+type countingRates struct {
+	rate  decimal.Decimal
+	calls int
+}
+
+func (c *countingRates) Name() string { return "counting" }
+
+func (c *countingRates) SOLRate(context.Context, Currency) (decimal.Decimal, error) {
+	c.calls++
+	return c.rate, nil
+}
+
+func TestFallbackRates(t *testing.T) {
+	tests := []struct {
+		name        string
+		providers   FallbackRates
+		expected    decimal.Decimal
+		expectedErr error
+	}{
+		{name: "First Provider", providers: FallbackRates{StaticRate(decimal.NewFromInt(140)), StaticRate(decimal.NewFromInt(150))}, expected: decimal.NewFromInt(140)},
+		{name: "Falls Back", providers: FallbackRates{failingRates{}, StaticRate(decimal.NewFromInt(150))}, expected: decimal.NewFromInt(150)},
+		{name: "All Fail", providers: FallbackRates{failingRates{}, failingRates{}}, expectedErr: ErrRateUnavailable},
+		{name: "No Providers", providers: FallbackRates{}, expectedErr: ErrRateUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.providers.SOLRate(context.Background(), "EUR")
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tt.expected.Equal(got))
+		})
+	}
+}
+
+func TestCachedRates(t *testing.T) {
+	provider := &countingRates{rate: decimal.NewFromInt(140)}
+	cache := NewCachedRates(provider, time.Minute)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		rate, err := cache.SOLRate(context.Background(), "EUR")
+		assert.NoError(t, err)
+		assert.True(t, provider.rate.Equal(rate))
+	}
+	assert.Equal(t, 1, provider.calls, "a fresh rate is fetched once")
+
+	_, err := cache.SOLRate(context.Background(), "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, provider.calls, "each currency is cached separately")
+
+	now = now.Add(time.Minute)
+	_, err = cache.SOLRate(context.Background(), "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, provider.calls, "an expired rate is fetched again")
+
+	failing := NewCachedRates(failingRates{}, time.Minute)
+	_, err = failing.SOLRate(context.Background(), "EUR")
+	assert.Error(t, err)
+	assert.Empty(t, failing.entries, "failures are not cached")
+}
+
+func TestCachedRatesRefresh(t *testing.T) {
+	provider := &countingRates{rate: decimal.NewFromInt(140)}
+	cache := NewCachedRates(provider, time.Minute)
+
+	_, err := cache.SOLRate(context.Background(), "EUR")
+	assert.NoError(t, err)
+
+	provider.rate = decimal.NewFromInt(150)
+	rate, err := cache.Refresh(context.Background(), "EUR")
+	assert.NoError(t, err)
+	assert.True(t, provider.rate.Equal(rate), "a refresh skips the fresh cached rate")
+
+	rate, err = cache.SOLRate(context.Background(), "EUR")
+	assert.NoError(t, err)
+	assert.True(t, provider.rate.Equal(rate))
+	assert.Equal(t, 2, provider.calls, "lookups are served from the refreshed rate")
+}
+
+func TestKrakenFallsBackToCoinGecko(t *testing.T) {
+	kraken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer kraken.Close()
+
+	var requested string
+	coinGecko := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Query().Get("vs_currencies")
+		w.Write([]byte(`{"solana":{"usd":151.37}}`))
+	}))
+	defer coinGecko.Close()
+
+	wc := &WalletConfig{
+		Currency: "USD",
+		Rates: FallbackRates{
+			&KrakenRates{URL: kraken.URL + "/?pair="},
+			&CoinGeckoRates{URL: coinGecko.URL + "/?ids=solana&vs_currencies="},
+		},
+	}
+
+	rate, err := wc.FetchSOLRate()
+	assert.NoError(t, err)
+	assert.Equal(t, "151.37", rate.String())
+	assert.Equal(t, "usd", requested)
+}
+
+func TestRateRequestTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	provider := &KrakenRates{Client: &http.Client{Timeout: 10 * time.Millisecond}, URL: slow.URL + "/?pair="}
+	_, err := provider.SOLRate(context.Background(), "EUR")
+	assert.Error(t, err)
+}
+
+func TestParseCoinGeckoPrice(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		code        string
+		expected    string
+		expectedErr bool
+	}{
+		{name: "Price", body: `{"solana":{"eur":140.22}}`, code: "eur", expected: "140.22"},
+		{name: "Other Currency Only", body: `{"solana":{"usd":151.37}}`, code: "eur", expectedErr: true},
+		{name: "Empty", body: `{}`, code: "eur", expectedErr: true},
+		{name: "Not JSON", body: `rate limited`, code: "eur", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCoinGeckoPrice([]byte(tt.body), tt.code)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got.String())
+		})
+	}
+}
+
+func TestGetWalletBalanceWithoutRate(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{GetBalanceFn: func(context.Context, solana.PublicKey, rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+			return &rpc.GetBalanceResult{Value: 2_500_000_000}, nil
+		}}
+	}
+
+	wc := &WalletConfig{Wallet: solana.NewWallet(), Network: Devnet, Rates: failingRates{}}
+
+	balance, err := wc.GetWalletBalance("")
+	assert.NoError(t, err)
+	assert.Equal(t, "2.5", balance.SOL.String())
+	assert.Error(t, balance.RateErr)
+	assert.True(t, balance.Fiat.IsZero())
+}
@@ -0,0 +1,60 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+)
+
+// ReadOnlyContext is what commands that never need a key work with: the profile's pending store,
+// its audit log and the exchange rate. It has no KeyStore, so it never opens the key file and
+// works on machines without one.
+type ReadOnlyContext struct {
+	Profile string
+	Pending *PendingStore
+	Audit   *AuditLog
+	// Currency is the fiat currency rates are fetched in. Empty means DefaultCurrency.
+	Currency Currency
+	// Rates fetches exchange rates. Nil means DefaultRates.
+	Rates RateProvider
+}
+
+// NewReadOnlyContextInDir initializes a ReadOnlyContext whose files live in dir, such as a profile directory.
+func NewReadOnlyContextInDir(dir string) *ReadOnlyContext {
+	return &ReadOnlyContext{
+		Pending: &PendingStore{
+			FileReader: &IOUtilFileReader{},
+			FileWriter: newFileWriter(),
+			Dir:        dir,
+		},
+		Audit: &AuditLog{
+			FileReader: &IOUtilFileReader{},
+			FileWriter: newFileWriter(),
+			Dir:        dir,
+		},
+	}
+}
+
+// ListPending returns all transactions recorded by sends, newest first.
+func (r *ReadOnlyContext) ListPending() ([]PendingTransaction, error) {
+	return r.Pending.List()
+}
+
+// AuditEntries returns the audit log of the key file, oldest first, without reading the key file itself.
+func (r *ReadOnlyContext) AuditEntries() ([]AuditEntry, error) {
+	return r.Audit.Entries()
+}
+
+// FetchSOLRate fetches the current rate of SOL in the selected currency.
+func (r *ReadOnlyContext) FetchSOLRate() (decimal.Decimal, error) {
+	return ratesOrDefault(r.Rates).SOLRate(context.TODO(), r.Currency.orDefault())
+}
+
+// LatencyStats summarises the confirmation latency of the recent most recent measured sends, or all of them when recent is 0.
+func (r *ReadOnlyContext) LatencyStats(recent int) ([]LatencyStats, error) {
+	records, err := r.Pending.List()
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeLatency(records, recent), nil
+}
@@ -0,0 +1,48 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+var ErrReadOnlyMode = errors.New("refusing to write in read-only mode")
+
+// readOnlyMode is set by SetReadOnlyMode.
+var readOnlyMode atomic.Bool
+
+// SetReadOnlyMode switches read-only mode on or off. While it is on, the constructors in this package
+// give every store a ReadOnlyFileWriter, the OHLC cache neither stores nor discards files, and the
+// network selection is used without being remembered, so nothing on disk changes. Switch it on before
+// creating a WalletConfig, ReadOnlyContext or ProfileManager.
+func SetReadOnlyMode(on bool) {
+	readOnlyMode.Store(on)
+}
+
+// ReadOnlyMode reports whether read-only mode is on.
+func ReadOnlyMode() bool {
+	return readOnlyMode.Load()
+}
+
+// guardWrite returns an error wrapping ErrReadOnlyMode for a write to path in read-only mode.
+func guardWrite(path string) error {
+	if ReadOnlyMode() {
+		return fmt.Errorf("%w: %s", ErrReadOnlyMode, path)
+	}
+	return nil
+}
+
+// ReadOnlyFileWriter is a FileWriter that refuses every write with ErrReadOnlyMode.
+type ReadOnlyFileWriter struct{}
+
+func (ReadOnlyFileWriter) WriteFile(filename string, _ []byte) error {
+	return fmt.Errorf("%w: %s", ErrReadOnlyMode, filename)
+}
+
+// newFileWriter returns the FileWriter stores are built with: a ReadOnlyFileWriter in read-only mode.
+func newFileWriter() FileWriter {
+	if ReadOnlyMode() {
+		return ReadOnlyFileWriter{}
+	}
+	return &IOUtilFileWriter{}
+}
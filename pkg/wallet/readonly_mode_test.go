@@ -0,0 +1,51 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyModeGuardsConstructors(t *testing.T) {
+	SetReadOnlyMode(true)
+	defer SetReadOnlyMode(false)
+
+	dir := t.TempDir()
+	wc := NewWalletConfigInDir(dir)
+	keyOps := wc.KeyOps.(*KeyOps)
+
+	for name, writer := range map[string]FileWriter{
+		"key file": keyOps.FileWriter,
+		"audit":    keyOps.Audit.FileWriter,
+		"pending":  wc.Pending.FileWriter,
+	} {
+		assert.IsType(t, ReadOnlyFileWriter{}, writer, name)
+	}
+
+	_, err := wc.CreateNewWallet("main")
+	assert.ErrorIs(t, err, ErrReadOnlyMode)
+	assert.NoFileExists(t, filepath.Join(dir, KeyFileName))
+
+	profiles := &ProfileManager{ConfigDir: filepath.Join(t.TempDir(), "config"), FileReader: &IOUtilFileReader{}, FileWriter: newFileWriter()}
+	assert.ErrorIs(t, profiles.Create("ci"), ErrReadOnlyMode)
+	assert.ErrorIs(t, profiles.SetCurrency(Currency("USD")), ErrReadOnlyMode)
+	_, err = os.Stat(profiles.ConfigDir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadOnlyModeUsesNetworkWithoutStoringIt(t *testing.T) {
+	files := newMemFiles()
+	original := jsonMarshal(t, WalletData{Wallets: map[string]Wallet{}})
+	assert.NoError(t, files.WriteFile(KeyFileName, original))
+
+	SetReadOnlyMode(true)
+	defer SetReadOnlyMode(false)
+
+	// The writer is not a guard, so only the mode check keeps the selection out of the key file.
+	wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}}
+	assert.NoError(t, wc.UseNetwork("testnet", ""))
+	assert.Equal(t, Testnet, wc.Network)
+	assert.Equal(t, original, files.files[KeyFileName])
+}
@@ -0,0 +1,90 @@
+package wallet
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/makiuchi-d/gozxing/qrcode/decoder"
+	"github.com/shopspring/decimal"
+)
+
+// receiveQRMargin is the light border, in modules, around a QR code printed to the terminal. Scanners
+// need some of it to find the code; the 4 modules of the standard take more room than most need.
+const receiveQRMargin = 2
+
+// SolanaPayURL returns the Solana Pay transfer request asking for lamports to be paid to recipient,
+// which phone wallets open when they scan it. Zero lamports leaves the amount to the payer; label
+// and message are shown to the payer when set.
+func SolanaPayURL(recipient solana.PublicKey, lamports uint64, label, message string) string {
+	query := url.Values{}
+	if lamports > 0 {
+		query.Set("amount", LamportsToSOL(lamports).String())
+	}
+	if label != "" {
+		query.Set("label", label)
+	}
+	if message != "" {
+		query.Set("message", message)
+	}
+
+	link := "solana:" + recipient.String()
+	if len(query) > 0 {
+		// Solana Pay wants spaces encoded as %20, which url.Values writes as +.
+		link += "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+	return link
+}
+
+// ReceiveLamports converts an amount to ask for, given in unit, to lamports. Fiat amounts are
+// converted at the current rate.
+func (w *WalletConfig) ReceiveLamports(amount string, unit Unit) (uint64, error) {
+	rate := decimal.Zero
+	if unit.NeedsRate() {
+		var err error
+		if rate, err = w.fetchRate(unit.Currency(w.FiatCurrency())); err != nil {
+			return 0, err
+		}
+	}
+	return amountToLamports(amount, unit, rate)
+}
+
+// RenderQRCode draws text as a QR code of text characters, two modules per character cell, for
+// terminals with a dark background. Invert draws it for a light background instead.
+func RenderQRCode(text string, invert bool) (string, error) {
+	hints := map[gozxing.EncodeHintType]interface{}{
+		gozxing.EncodeHintType_ERROR_CORRECTION: decoder.ErrorCorrectionLevel_M,
+		gozxing.EncodeHintType_MARGIN:           receiveQRMargin,
+	}
+	matrix, err := qrcode.NewQRCodeWriter().Encode(text, gozxing.BarcodeFormat_QR_CODE, 0, 0, hints)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the QR code: %w", err)
+	}
+
+	// Light modules are drawn and the terminal background stands in for the dark ones, or the other
+	// way around when inverted. Past the last row is background.
+	drawn := func(x, y int) bool {
+		return y < matrix.GetHeight() && matrix.Get(x, y) == invert
+	}
+
+	var b strings.Builder
+	for y := 0; y < matrix.GetHeight(); y += 2 {
+		for x := 0; x < matrix.GetWidth(); x++ {
+			switch top, bottom := drawn(x, y), drawn(x, y+1); {
+			case top && bottom:
+				b.WriteRune('█')
+			case top:
+				b.WriteRune('▀')
+			case bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
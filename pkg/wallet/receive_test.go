@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolanaPayURL(t *testing.T) {
+	recipient := solana.MustPublicKeyFromBase58("9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM")
+
+	tests := []struct {
+		name     string
+		lamports uint64
+		label    string
+		message  string
+		expected string
+	}{
+		{name: "Address Only", expected: "solana:9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"},
+		{name: "Amount", lamports: 1_500_000_000, expected: "solana:9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM?amount=1.5"},
+		{name: "Smallest Amount", lamports: 1, expected: "solana:9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM?amount=0.000000001"},
+		{
+			name:     "Label And Message",
+			lamports: 10_000_000,
+			label:    "Corner Café",
+			message:  "Order #42 & tip",
+			expected: "solana:9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM?amount=0.01&label=Corner%20Caf%C3%A9&message=Order%20%2342%20%26%20tip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SolanaPayURL(recipient, tt.lamports, tt.label, tt.message))
+		})
+	}
+}
+
+func TestReceiveLamports(t *testing.T) {
+	wc := &WalletConfig{Currency: Currency("EUR"), Rates: StaticRate(decimal.NewFromInt(100))}
+
+	lamports, err := wc.ReceiveLamports("25", UnitFiat)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(250_000_000), lamports)
+
+	lamports, err = wc.ReceiveLamports("1.5", UnitSOL)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1_500_000_000), lamports)
+
+	_, err = wc.ReceiveLamports("0", UnitSOL)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+// rasterizeQRCode draws a code printed by RenderQRCode as a PNG, each module a square of pixels, the
+// way a phone sees it on a terminal with the background invert is meant for.
+func rasterizeQRCode(t *testing.T, code string, invert bool) []byte {
+	const scale = 4
+	lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+	width := len([]rune(lines[0]))
+	// Room around the code stands for the rest of the terminal.
+	img := image.NewGray(image.Rect(0, 0, (width+8)*scale, (2*len(lines)+8)*scale))
+
+	drawnColor, background := color.Gray{Y: 255}, color.Gray{Y: 0}
+	if invert {
+		drawnColor, background = background, drawnColor
+	}
+	for x := 0; x < img.Bounds().Dx(); x++ {
+		for y := 0; y < img.Bounds().Dy(); y++ {
+			img.SetGray(x, y, background)
+		}
+	}
+
+	set := func(col, row int) {
+		for dx := 0; dx < scale; dx++ {
+			for dy := 0; dy < scale; dy++ {
+				img.SetGray((col+4)*scale+dx, (row+4)*scale+dy, drawnColor)
+			}
+		}
+	}
+	for i, line := range lines {
+		for col, r := range []rune(line) {
+			if r == '█' || r == '▀' {
+				set(col, 2*i)
+			}
+			if r == '█' || r == '▄' {
+				set(col, 2*i+1)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestRenderQRCode(t *testing.T) {
+	text := "solana:9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM?amount=1.5&label=Corner%20Caf%C3%A9"
+
+	for _, invert := range []bool{false, true} {
+		code, err := RenderQRCode(text, invert)
+		assert.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+		for _, line := range lines {
+			assert.Equal(t, len([]rune(lines[0])), len([]rune(line)))
+		}
+
+		decoded, err := DecodeQRImage(bytes.NewReader(rasterizeQRCode(t, code, invert)))
+		assert.NoError(t, err)
+		assert.Equal(t, text, decoded)
+	}
+
+	_, err := RenderQRCode("", false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,172 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// RetryPolicy controls how RPC calls that fail with rate-limit or transient network errors are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry; it doubles with every attempt up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy is used when no policy is configured.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, BaseDelay: 500 * time.Millisecond, MaxDelay: 8 * time.Second}
+
+// withRetry calls fn until it succeeds, fails with an error that is not worth retrying, runs out of
+// attempts or ctx is done. onRetry, when set, is called before every retry with the error that caused it.
+func withRetry(ctx context.Context, policy RetryPolicy, onRetry func(error), fn func(ctx context.Context) error) error {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if onRetry != nil {
+				onRetry(err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(backoff(policy, attempt)):
+			}
+		}
+
+		err = fn(ctx)
+		if err == nil || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// backoff returns the delay before the given retry: exponential with full jitter, so concurrent
+// requests that were rate limited together do not all come back at the same moment.
+func backoff(policy RetryPolicy, attempt int) time.Duration {
+	delay := policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || (policy.MaxDelay > 0 && delay > policy.MaxDelay) {
+		delay = policy.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// isRetryable reports whether an RPC error is a rate limit or a transient failure that may succeed
+// when tried again. Errors such as an invalid signature or a missing transaction are permanent.
+func isRetryable(err error) bool {
+	if isRateLimited(err) {
+		return true
+	}
+
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code >= 500 {
+		return true
+	}
+
+	// A single request running into its own timeout is transient.
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// isRateLimited reports whether an RPC error means the node or faucet asked us to slow down.
+func isRateLimited(err error) bool {
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code == 429 {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "429") || strings.Contains(message, "too many requests") ||
+		strings.Contains(message, "rate limit") || strings.Contains(message, "airdrop limit")
+}
+
+// adaptiveLimiter bounds the number of concurrent requests and lowers the bound when the node
+// starts rate limiting, so a long history is fetched more slowly instead of failing.
+type adaptiveLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	inFlight int
+	wake     chan struct{}
+}
+
+func newAdaptiveLimiter(limit int) *adaptiveLimiter {
+	if limit < 1 {
+		limit = 1
+	}
+	return &adaptiveLimiter{limit: limit, wake: make(chan struct{})}
+}
+
+// Acquire waits for a free slot or until ctx is done.
+func (l *adaptiveLimiter) Acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inFlight < l.limit {
+			l.inFlight++
+			l.mu.Unlock()
+			return nil
+		}
+		wake := l.wake
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// Release frees a slot taken with Acquire.
+func (l *adaptiveLimiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// Throttle halves the number of concurrent requests, down to one.
+func (l *adaptiveLimiter) Throttle() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit > 1 {
+		l.limit /= 2
+	}
+}
+
+// Limit returns the current bound.
+func (l *adaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
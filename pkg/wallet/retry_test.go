@@ -0,0 +1,164 @@
+package wallet
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+var fastRetries = RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+func TestWithRetry(t *testing.T) {
+	rateLimited := errors.New("HTTP 429 Too Many Requests")
+	permanent := errors.New("invalid signature")
+
+	tests := []struct {
+		name          string
+		errs          []error
+		expectedCalls int
+		expectedErr   error
+	}{
+		{name: "Succeeds First Time", expectedCalls: 1},
+		{name: "Recovers From Rate Limit", errs: []error{rateLimited, rateLimited}, expectedCalls: 3},
+		{name: "Recovers From Request Timeout", errs: []error{context.DeadlineExceeded}, expectedCalls: 2},
+		{name: "Gives Up After Max Attempts", errs: []error{rateLimited, rateLimited, rateLimited, rateLimited, rateLimited}, expectedCalls: 4, expectedErr: rateLimited},
+		{name: "Permanent Error Fails Fast", errs: []error{permanent}, expectedCalls: 1, expectedErr: permanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, retries := 0, 0
+			err := withRetry(context.Background(), fastRetries, func(error) { retries++ }, func(ctx context.Context) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+
+			assert.Equal(t, tt.expectedCalls, calls)
+			assert.Equal(t, tt.expectedCalls-1, retries)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWithRetryHonorsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+
+	err := withRetry(ctx, RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour, MaxDelay: time.Hour}, func(error) { cancel() }, func(ctx context.Context) error {
+		calls++
+		return errors.New("429")
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestAdaptiveLimiterThrottle(t *testing.T) {
+	limiter := newAdaptiveLimiter(8)
+	limiter.Throttle()
+	assert.Equal(t, 4, limiter.Limit())
+	for i := 0; i < 5; i++ {
+		limiter.Throttle()
+	}
+	assert.Equal(t, 1, limiter.Limit())
+
+	assert.NoError(t, limiter.Acquire(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Acquire(ctx), context.DeadlineExceeded)
+
+	limiter.Release()
+	assert.NoError(t, limiter.Acquire(context.Background()))
+}
+
+func TestFetchTransactionsReportsFailures(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	defer func(policy RetryPolicy) { DefaultRetryPolicy = policy }(DefaultRetryPolicy)
+	DefaultRetryPolicy = fastRetries
+
+	owner := solana.NewWallet().PublicKey()
+	signatures := make([]*rpc.TransactionSignature, 10)
+	for i := range signatures {
+		signatures[i] = &rpc.TransactionSignature{Signature: solana.Signature{byte(i + 1)}, Slot: uint64(100 - i)}
+	}
+	broken := signatures[3].Signature
+	var rateLimitedOnce int32
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetTokenAccountsByOwnerFn: func(ctx context.Context, _ solana.PublicKey, _ *rpc.GetTokenAccountsConfig, _ *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+				return &rpc.GetTokenAccountsResult{}, nil
+			},
+			GetSignaturesForAddressWithOptsFn: func(ctx context.Context, _ solana.PublicKey, _ *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+				return signatures, nil
+			},
+			GetTransactionFn: func(ctx context.Context, sig solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+				if sig == broken {
+					return nil, errors.New("transaction not found")
+				}
+				if atomic.CompareAndSwapInt32(&rateLimitedOnce, 0, 1) {
+					return nil, errors.New("429 Too Many Requests")
+				}
+				return transferResult(t, owner), nil
+			},
+		}
+	}
+
+	transactions, err := fetchTransactions(Endpoints{RPC: "http://rpc"}, owner.String(), GetTransactionHistoryOpts{Limit: 10, MaxAttempts: 2}, nil)
+
+	// The rate-limited request is retried; only the missing transaction is reported.
+	var partial *HistoryFetchError
+	assert.True(t, errors.As(err, &partial))
+	assert.Equal(t, 10, partial.Total)
+	assert.Equal(t, 1, partial.Failed)
+	assert.Contains(t, err.Error(), "could not fetch 1 of 10 transactions")
+	assert.Len(t, transactions, 9)
+	for _, tx := range transactions {
+		assert.False(t, tx.Signature.IsZero())
+		assert.NotEqual(t, broken, tx.Signature)
+	}
+}
+
+// transferResult returns a getTransaction response holding a SOL transfer sent by from.
+func transferResult(t *testing.T, from solana.PublicKey) *rpc.GetTransactionResult {
+	return transferResultTo(t, from, solana.NewWallet().PublicKey(), from)
+}
+
+// transferResultTo returns a getTransaction response holding a SOL transfer from one address to
+// another, with the fee paid by payer.
+func transferResultTo(t *testing.T, from, to, payer solana.PublicKey) *rpc.GetTransactionResult {
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1_000, from, to).Build(),
+	}, solana.Hash{1}, solana.TransactionPayer(payer))
+	assert.NoError(t, err)
+
+	raw, err := tx.MarshalBinary()
+	assert.NoError(t, err)
+
+	envelope, err := json.Marshal([]string{base64.StdEncoding.EncodeToString(raw), "base64"})
+	assert.NoError(t, err)
+
+	result := &rpc.GetTransactionResult{Transaction: &rpc.TransactionResultEnvelope{}}
+	assert.NoError(t, json.Unmarshal(envelope, result.Transaction))
+
+	blockTime := solana.UnixTimeSeconds(1_700_000_000)
+	result.BlockTime = &blockTime
+	return result
+}
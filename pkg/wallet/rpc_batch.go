@@ -0,0 +1,206 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// errBatchUnsupported is returned by clients whose endpoint does not accept JSON-RPC batches.
+var errBatchUnsupported = errors.New("the RPC endpoint does not accept batch requests")
+
+// batchClient is a client that can send several JSON-RPC requests in one HTTP request.
+type batchClient interface {
+	RPCCallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error)
+}
+
+// canBatch reports whether client can send batch requests at all.
+func canBatch(client ClientInterface) bool {
+	_, ok := client.(batchClient)
+	return ok
+}
+
+// batchCall is one read of a batch: the JSON-RPC request, the value its result is decoded into, and
+// the same read made as a single call, used when the endpoint does not accept batches. After
+// batchRead, err holds the error the single call would have returned.
+type batchCall struct {
+	method string
+	params []interface{}
+	result interface{}
+	single func(ctx context.Context, client ClientInterface) error
+	// notFound reports whether the decoded result is a missing account, which single calls report as
+	// rpc.ErrNotFound. Nil for methods without that case.
+	notFound func() bool
+	// speculative calls are only needed in some cases. They ride along in a batch for free, but are
+	// left out of single calls until ensure asks for them.
+	speculative bool
+	read        bool
+	err         error
+}
+
+// ensure makes the call on its own unless batchRead already made it, and returns its error.
+func (c *batchCall) ensure(ctx context.Context, client ClientInterface) error {
+	if !c.read {
+		c.err, c.read = c.single(ctx, client), true
+	}
+	return c.err
+}
+
+// batchRead makes independent reads in one round trip when the client can batch them, and one after
+// the other when it cannot or the endpoint rejects batches. Every call gets its own result and error,
+// exactly as if it had been made on its own.
+func batchRead(ctx context.Context, client ClientInterface, calls ...*batchCall) {
+	batcher, ok := client.(batchClient)
+	if !ok || len(calls) < 2 {
+		readSequentially(ctx, client, calls)
+		return
+	}
+
+	requests := make(jsonrpc.RPCRequests, len(calls))
+	for i, call := range calls {
+		requests[i] = &jsonrpc.RPCRequest{Method: call.method, Params: call.params}
+	}
+
+	responses, err := batcher.RPCCallBatch(ctx, requests)
+	if errors.Is(err, errBatchUnsupported) {
+		readSequentially(ctx, client, calls)
+		return
+	}
+	if err != nil {
+		// The batch never reached a node, so none of its calls did.
+		for _, call := range calls {
+			call.err, call.read = err, true
+		}
+		return
+	}
+
+	// CallBatch numbers the requests by their index; nodes may answer in any order.
+	byID := responses.AsMap()
+	for i, call := range calls {
+		response, ok := byID[i]
+		if !ok {
+			if !call.speculative {
+				call.ensure(ctx, client)
+			}
+			continue
+		}
+		call.err, call.read = decodeBatchResponse(call, response), true
+	}
+}
+
+// readSequentially makes each call on its own, except the speculative ones.
+func readSequentially(ctx context.Context, client ClientInterface, calls []*batchCall) {
+	for _, call := range calls {
+		if !call.speculative {
+			call.ensure(ctx, client)
+		}
+	}
+}
+
+// decodeBatchResponse stores the result of one response of a batch, mapping its error the way a
+// single call does.
+func decodeBatchResponse(call *batchCall, response *jsonrpc.RPCResponse) error {
+	if response.Error != nil {
+		return response.Error
+	}
+	if err := json.Unmarshal(response.Result, call.result); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", call.method, err)
+	}
+	if call.notFound != nil && call.notFound() {
+		return rpc.ErrNotFound
+	}
+	return nil
+}
+
+// isBatchRejected reports whether a batch failed because the endpoint does not accept batches, rather
+// than because it could not be reached or asked us to slow down: it answered, but not with a list of
+// responses.
+func isBatchRejected(ctx context.Context, err error) bool {
+	return err != nil && !isEndpointFailure(ctx, err) && !isRateLimited(err)
+}
+
+// getBalanceCall reads the balance of account into out.
+func getBalanceCall(out *rpc.GetBalanceResult, account solana.PublicKey, commitment rpc.CommitmentType) *batchCall {
+	params := []interface{}{account}
+	if commitment != "" {
+		params = append(params, rpc.M{"commitment": commitment})
+	}
+	return &batchCall{
+		method: "getBalance",
+		params: params,
+		result: out,
+		single: func(ctx context.Context, client ClientInterface) error {
+			result, err := client.GetBalance(ctx, account, commitment)
+			if err == nil {
+				*out = *result
+			}
+			return err
+		},
+	}
+}
+
+// getAccountInfoCall reads account, base64 encoded, into out. A missing account is rpc.ErrNotFound.
+func getAccountInfoCall(out *rpc.GetAccountInfoResult, account solana.PublicKey) *batchCall {
+	return &batchCall{
+		method: "getAccountInfo",
+		params: []interface{}{account, rpc.M{"encoding": solana.EncodingBase64}},
+		result: out,
+		single: func(ctx context.Context, client ClientInterface) error {
+			result, err := client.GetAccountInfo(ctx, account)
+			if err != nil {
+				return err
+			}
+			if result == nil || result.Value == nil {
+				return rpc.ErrNotFound
+			}
+			*out = *result
+			return nil
+		},
+		notFound: func() bool { return out.Value == nil },
+	}
+}
+
+// getLatestBlockhashCall reads the latest blockhash into out.
+func getLatestBlockhashCall(out *rpc.GetLatestBlockhashResult, commitment rpc.CommitmentType) *batchCall {
+	var params []interface{}
+	if commitment != "" {
+		params = append(params, rpc.M{"commitment": commitment})
+	}
+	return &batchCall{
+		method: "getLatestBlockhash",
+		params: params,
+		result: out,
+		single: func(ctx context.Context, client ClientInterface) error {
+			result, err := client.GetLatestBlockhash(ctx, commitment)
+			if err == nil {
+				*out = *result
+			}
+			return err
+		},
+	}
+}
+
+// getRentCall reads the minimum balance for an account of dataSize bytes to be rent exempt into out.
+func getRentCall(out *uint64, dataSize uint64, commitment rpc.CommitmentType) *batchCall {
+	params := []interface{}{dataSize}
+	if commitment != "" {
+		params = append(params, rpc.M{"commitment": commitment})
+	}
+	return &batchCall{
+		method: "getMinimumBalanceForRentExemption",
+		params: params,
+		result: out,
+		single: func(ctx context.Context, client ClientInterface) error {
+			result, err := client.GetMinimumBalanceForRentExemption(ctx, dataSize, commitment)
+			if err == nil {
+				*out = result
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,185 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// jsonRPCServer fakes an RPC node that answers getBalance, getAccountInfo for a missing account and
+// fails getLatestBlockhash. It answers batches unless rejectBatches is set, and counts HTTP requests.
+type jsonRPCServer struct {
+	*httptest.Server
+	rejectBatches bool
+
+	mu       sync.Mutex
+	requests int
+	batches  int
+}
+
+type jsonRPCRequest struct {
+	ID     int    `json:"id"`
+	Method string `json:"method"`
+}
+
+func newJSONRPCServer(t *testing.T, rejectBatches bool) *jsonRPCServer {
+	s := &jsonRPCServer{rejectBatches: rejectBatches}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		s.requests++
+		s.mu.Unlock()
+
+		var batch []jsonRPCRequest
+		if err := json.Unmarshal(body, &batch); err == nil {
+			s.mu.Lock()
+			s.batches++
+			s.mu.Unlock()
+			if s.rejectBatches {
+				_, _ = w.Write([]byte(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"batch requests are not supported"},"id":null}`))
+				return
+			}
+			// Answer in reverse order: responses are matched by id.
+			responses := make([]json.RawMessage, 0, len(batch))
+			for i := len(batch) - 1; i >= 0; i-- {
+				responses = append(responses, jsonRPCResponse(batch[i]))
+			}
+			_ = json.NewEncoder(w).Encode(responses)
+			return
+		}
+
+		var single jsonRPCRequest
+		if err := json.Unmarshal(body, &single); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(jsonRPCResponse(single))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func jsonRPCResponse(req jsonRPCRequest) json.RawMessage {
+	var response map[string]interface{}
+	switch req.Method {
+	case "getBalance":
+		response = map[string]interface{}{"result": map[string]interface{}{"context": map[string]uint64{"slot": 7}, "value": 1_500_000_000}}
+	case "getAccountInfo":
+		response = map[string]interface{}{"result": map[string]interface{}{"context": map[string]uint64{"slot": 7}, "value": nil}}
+	case "getLatestBlockhash":
+		response = map[string]interface{}{"error": map[string]interface{}{"code": -32005, "message": "Node is behind"}}
+	default:
+		response = map[string]interface{}{"error": map[string]interface{}{"code": -32601, "message": "Method not found"}}
+	}
+	response["jsonrpc"], response["id"] = "2.0", req.ID
+	data, _ := json.Marshal(response)
+	return data
+}
+
+// readThree reads a balance, a missing account and a blockhash the node fails to serve.
+func readThree(client ClientInterface) (uint64, error, error, error) {
+	var balance rpc.GetBalanceResult
+	var info rpc.GetAccountInfoResult
+	var recent rpc.GetLatestBlockhashResult
+	calls := []*batchCall{
+		getBalanceCall(&balance, solana.NewWallet().PublicKey(), rpc.CommitmentConfirmed),
+		getAccountInfoCall(&info, solana.NewWallet().PublicKey()),
+		getLatestBlockhashCall(&recent, rpc.CommitmentFinalized),
+	}
+	batchRead(context.Background(), client, calls...)
+	return balance.Value, calls[0].err, calls[1].err, calls[2].err
+}
+
+func TestBatchReadMapsEachCall(t *testing.T) {
+	for _, rejectBatches := range []bool{false, true} {
+		server := newJSONRPCServer(t, rejectBatches)
+		session := newNodeSession(NodeOptions{})
+		client := session.client(server.URL)
+
+		balance, balanceErr, accountErr, blockhashErr := readThree(client)
+
+		// Batched or not, every call gets the result and error a single call would have.
+		assert.NoError(t, balanceErr)
+		assert.Equal(t, uint64(1_500_000_000), balance)
+		assert.True(t, errors.Is(accountErr, rpc.ErrNotFound), accountErr)
+		var rpcErr *jsonrpc.RPCError
+		if assert.True(t, errors.As(blockhashErr, &rpcErr), blockhashErr) {
+			assert.Equal(t, -32005, rpcErr.Code)
+		}
+
+		if rejectBatches {
+			// One rejected batch, then the three calls one by one.
+			assert.Equal(t, 4, server.requests)
+			assert.Equal(t, 1, server.batches)
+
+			// The rejection is remembered: the next reads go straight to single calls.
+			readThree(client)
+			assert.Equal(t, 7, server.requests)
+			assert.Equal(t, 1, server.batches)
+		} else {
+			assert.Equal(t, 1, server.requests, "the three reads take one round trip")
+		}
+	}
+}
+
+func TestBatchReadThroughFailoverClient(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	server := newJSONRPCServer(t, false)
+	session := newNodeSession(NodeOptions{})
+	newRPCClient = session.client
+	healthProbes = &healthCache{entries: make(map[string]healthEntry), now: time.Now}
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	healthProbes.set(down.URL, true)
+
+	client := newFailoverClient([]string{down.URL, server.URL}, &endpointTracker{})
+	balance, balanceErr, _, _ := readThree(client)
+	assert.NoError(t, balanceErr)
+	assert.Equal(t, uint64(1_500_000_000), balance)
+	assert.Equal(t, 1, server.requests, "the batch moved to the healthy endpoint as a whole")
+}
+
+func TestBatchReadFallsBackForClientsWithoutBatches(t *testing.T) {
+	balanceCalls := 0
+	client := &MockClientInterface{
+		GetBalanceFn: func(context.Context, solana.PublicKey, rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+			balanceCalls++
+			return &rpc.GetBalanceResult{Value: 42}, nil
+		},
+		GetMinimumBalanceForRentExemptionFn: func(context.Context, uint64, rpc.CommitmentType) (uint64, error) {
+			return 2_039_280, nil
+		},
+	}
+
+	var balance rpc.GetBalanceResult
+	var rent uint64
+	balanceCall := getBalanceCall(&balance, solana.NewWallet().PublicKey(), "")
+	rentCall := getRentCall(&rent, tokenAccountSize, "")
+	rentCall.speculative = true
+	batchRead(context.Background(), client, balanceCall, rentCall)
+
+	assert.NoError(t, balanceCall.err)
+	assert.Equal(t, uint64(42), balance.Value)
+	assert.Equal(t, 1, balanceCalls)
+	assert.False(t, rentCall.read, "speculative calls are not made one by one")
+	assert.NoError(t, rentCall.ensure(context.Background(), client))
+	assert.Equal(t, uint64(2_039_280), rent)
+}
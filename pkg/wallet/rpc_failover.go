@@ -0,0 +1,471 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// healthProbeTTL is how long the outcome of a getHealth probe is trusted. It is short: the probe only
+// picks the endpoint to start with, and failing requests move on to the next one anyway.
+const healthProbeTTL = 30 * time.Second
+
+// healthProbeTimeout bounds a single getHealth probe, so a dead endpoint costs little before the next is tried.
+const healthProbeTimeout = 3 * time.Second
+
+// ErrRPCUnavailable is wrapped by errors of requests no RPC endpoint could serve: every endpoint tried
+// refused or dropped the connection, timed out or answered with a server error.
+var ErrRPCUnavailable = errors.New("RPC endpoint unavailable")
+
+// unavailableError marks an endpoint failure as ErrRPCUnavailable, keeping its message and its cause
+// for the retry policy.
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unavailableError) Unwrap() []error {
+	return []error{ErrRPCUnavailable, e.err}
+}
+
+// unavailable wraps err in an unavailableError when it is an endpoint failure.
+func unavailable(ctx context.Context, err error) error {
+	if !isEndpointFailure(ctx, err) {
+		return err
+	}
+	return &unavailableError{err: err}
+}
+
+// healthProbes caches the health of endpoints for every failover client of the process.
+var healthProbes = &healthCache{entries: make(map[string]healthEntry), now: time.Now}
+
+type healthEntry struct {
+	healthy bool
+	probed  time.Time
+}
+
+// healthCache remembers which endpoints answered getHealth with "ok", and which failed a request.
+type healthCache struct {
+	mu      sync.Mutex
+	entries map[string]healthEntry
+	// now returns the current time; it is a field so tests can expire entries.
+	now func() time.Time
+}
+
+// healthy reports whether endpoint is healthy, probing it with getHealth unless a recent outcome is cached.
+func (c *healthCache) healthy(ctx context.Context, endpoint string, client ClientInterface) bool {
+	c.mu.Lock()
+	entry, ok := c.entries[endpoint]
+	fresh := ok && c.now().Sub(entry.probed) <= healthProbeTTL
+	c.mu.Unlock()
+	if fresh {
+		return entry.healthy
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+	health, err := client.GetHealth(ctx)
+	healthy := err == nil && health == rpc.HealthOk
+
+	c.set(endpoint, healthy)
+	return healthy
+}
+
+// set records the health of endpoint.
+func (c *healthCache) set(endpoint string, healthy bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[endpoint] = healthEntry{healthy: healthy, probed: c.now()}
+}
+
+// EndpointUsage counts the requests an RPC endpoint served during the command.
+type EndpointUsage struct {
+	Endpoint string
+	Requests int
+	// Failed counts the requests that failed at the connection level, or were rate limited while
+	// another endpoint was available, and were moved to another endpoint.
+	Failed int
+}
+
+// endpointTracker records which endpoints served the requests of the process, in order of first use.
+type endpointTracker struct {
+	mu    sync.Mutex
+	usage []EndpointUsage
+	last  string
+}
+
+func (t *endpointTracker) record(endpoint string, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !failed {
+		t.last = endpoint
+	}
+	for i := range t.usage {
+		if t.usage[i].Endpoint == endpoint {
+			if failed {
+				t.usage[i].Failed++
+			} else {
+				t.usage[i].Requests++
+			}
+			return
+		}
+	}
+	usage := EndpointUsage{Endpoint: endpoint, Requests: 1}
+	if failed {
+		usage = EndpointUsage{Endpoint: endpoint, Failed: 1}
+	}
+	t.usage = append(t.usage, usage)
+}
+
+// ServedEndpoints returns the RPC endpoints that served requests since SetNodeOptions was last
+// called, in order of first use.
+func ServedEndpoints() []EndpointUsage {
+	t := currentNodeSession().endpoints
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]EndpointUsage(nil), t.usage...)
+}
+
+// lastServedEndpoint returns the endpoint that served the most recent successful request, if any.
+func lastServedEndpoint() string {
+	t := currentNodeSession().endpoints
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
+
+// client returns the RPC client for the endpoints. With fallbacks it starts on the first healthy
+// endpoint and moves reads to the next one when an endpoint cannot be reached.
+func (e Endpoints) client() ClientInterface {
+	return newFailoverClient(append([]string{e.RPC}, e.Fallbacks...), currentNodeSession().endpoints)
+}
+
+// failoverClient sends requests to the current endpoint of an ordered list. A read that fails at the
+// connection level, or is rate limited, is issued again against the next endpoint. A send or airdrop
+// is never issued twice: the node may have received it before the connection failed, so the error is
+// returned and only later requests move on.
+type failoverClient struct {
+	endpoints []string
+	tracker   *endpointTracker
+
+	mu      sync.Mutex
+	clients []ClientInterface
+	current int
+	probed  bool
+}
+
+func newFailoverClient(endpoints []string, tracker *endpointTracker) *failoverClient {
+	clients := make([]ClientInterface, len(endpoints))
+	clients[0] = newRPCClient(endpoints[0])
+	return &failoverClient{endpoints: endpoints, tracker: tracker, clients: clients}
+}
+
+// clientAt returns the client of endpoint i, creating it on first use.
+func (c *failoverClient) clientAt(i int) ClientInterface {
+	if c.clients[i] == nil {
+		c.clients[i] = newRPCClient(c.endpoints[i])
+	}
+	return c.clients[i]
+}
+
+// pick returns the endpoint to use. The first call probes the endpoints in order and settles on the
+// first healthy one, or the first endpoint when none is.
+func (c *failoverClient) pick(ctx context.Context) (int, ClientInterface) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.probed && len(c.endpoints) > 1 {
+		c.probed = true
+		for i := range c.endpoints {
+			if healthProbes.healthy(ctx, c.endpoints[i], c.clientAt(i)) {
+				c.current = i
+				break
+			}
+		}
+	}
+	return c.current, c.clientAt(c.current)
+}
+
+// failed marks endpoint i unhealthy and moves to the next endpoint. It reports whether there is one
+// that has not failed during this request.
+func (c *failoverClient) failed(i, start int) bool {
+	healthProbes.set(c.endpoints[i], false)
+	c.tracker.record(c.endpoints[i], true)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := (i + 1) % len(c.endpoints)
+	if c.current == i {
+		c.current = next
+	}
+	return next != start
+}
+
+// movesOn reports whether err should move requests to the next endpoint: the endpoint failed, or it
+// is rate limiting and another endpoint may not be. With a single endpoint a rate limit is left to
+// the retry policy, which backs off.
+func (c *failoverClient) movesOn(ctx context.Context, err error) bool {
+	if isEndpointFailure(ctx, err) {
+		return true
+	}
+	return len(c.endpoints) > 1 && ctx.Err() == nil && isRateLimited(err)
+}
+
+// read calls fn against the current endpoint, and again against the following ones for as long as
+// endpoints fail at the connection level or are rate limited.
+func (c *failoverClient) read(ctx context.Context, fn func(ClientInterface) error) error {
+	defer TimePhase(PhaseRPC)()
+
+	start, client := c.pick(ctx)
+	i := start
+	for {
+		err := fn(client)
+		if err == nil || !c.movesOn(ctx, err) {
+			c.tracker.record(c.endpoints[i], false)
+			return err
+		}
+		if !c.failed(i, start) {
+			return unavailable(ctx, err)
+		}
+		i = (i + 1) % len(c.endpoints)
+		c.mu.Lock()
+		client = c.clientAt(i)
+		c.mu.Unlock()
+	}
+}
+
+// write calls fn against the current endpoint once. A connection-level failure or a rate limit moves
+// later requests to the next endpoint, but fn is not called again.
+func (c *failoverClient) write(ctx context.Context, fn func(ClientInterface) error) error {
+	defer TimePhase(PhaseRPC)()
+
+	i, client := c.pick(ctx)
+	err := fn(client)
+	if err != nil && c.movesOn(ctx, err) {
+		c.failed(i, i)
+		return unavailable(ctx, err)
+	}
+	c.tracker.record(c.endpoints[i], false)
+	return err
+}
+
+// isEndpointFailure reports whether err means the endpoint could not serve the request at all: it
+// refused or dropped the connection, timed out, or its gateway answered with a server error. Errors
+// returned by a working node, such as a rate limit or an invalid transaction, are not, and neither
+// is ctx being done.
+func isEndpointFailure(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code >= 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func (c *failoverClient) GetBalance(ctx context.Context, publicKey solana.PublicKey, commitment rpc.CommitmentType) (out *rpc.GetBalanceResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetBalance(ctx, publicKey, commitment)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetAccountInfo(ctx context.Context, account solana.PublicKey) (out *rpc.GetAccountInfoResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetAccountInfo(ctx, account)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, transactionSignatures ...solana.Signature) (out *rpc.GetSignatureStatusesResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetSignatureStatuses(ctx, searchTransactionHistory, transactionSignatures...)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) SendTransaction(ctx context.Context, transaction *solana.Transaction) (out solana.Signature, err error) {
+	err = c.write(ctx, func(client ClientInterface) error {
+		out, err = client.SendTransaction(ctx, transaction)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) SendTransactionWithOpts(ctx context.Context, transaction *solana.Transaction, opts rpc.TransactionOpts) (out solana.Signature, err error) {
+	err = c.write(ctx, func(client ClientInterface) error {
+		out, err = client.SendTransactionWithOpts(ctx, transaction, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (out uint64, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetBlockHeight(ctx, commitment)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (out *rpc.GetLatestBlockhashResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetLatestBlockhash(ctx, commitment)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetFeeForMessage(ctx context.Context, message string, commitment rpc.CommitmentType) (out *rpc.GetFeeForMessageResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetFeeForMessage(ctx, message, commitment)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (out *rpc.GetTokenAccountsResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetTokenAccountsByOwner(ctx, owner, conf, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (out uint64, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetMinimumBalanceForRentExemption(ctx, dataSize, commitment)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) (out []*rpc.TransactionSignature, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetSignaturesForAddressWithOpts(ctx, account, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetTransaction(ctx context.Context, txSig solana.Signature, opts *rpc.GetTransactionOpts) (out *rpc.GetTransactionResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetTransaction(ctx, txSig, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetBlockTime(ctx context.Context, block uint64) (out *solana.UnixTimeSeconds, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetBlockTime(ctx, block)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) RequestAirdrop(ctx context.Context, account solana.PublicKey, lamports uint64, commitment rpc.CommitmentType) (out solana.Signature, err error) {
+	err = c.write(ctx, func(client ClientInterface) error {
+		out, err = client.RequestAirdrop(ctx, account, lamports, commitment)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) (out []rpc.PriorizationFeeResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetRecentPrioritizationFees(ctx, accounts)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetGenesisHash(ctx context.Context) (out solana.Hash, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetGenesisHash(ctx)
+		return err
+	})
+	return out, err
+}
+
+// RPCCallBatch sends requests in one batch to the current endpoint, moving to the next one like any
+// other read.
+func (c *failoverClient) RPCCallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (out jsonrpc.RPCResponses, err error) {
+	if _, client := c.pick(ctx); !canBatch(client) {
+		return nil, errBatchUnsupported
+	}
+	err = c.read(ctx, func(client ClientInterface) error {
+		batcher, ok := client.(batchClient)
+		if !ok {
+			return errBatchUnsupported
+		}
+		out, err = batcher.RPCCallBatch(ctx, requests)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetHealth(ctx context.Context) (out string, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetHealth(ctx)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetProgramAccountsWithOpts(ctx context.Context, publicKey solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (out rpc.GetProgramAccountsResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetProgramAccountsWithOpts(ctx, publicKey, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetEpochInfo(ctx context.Context, commitment rpc.CommitmentType) (out *rpc.GetEpochInfoResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetEpochInfo(ctx, commitment)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) GetInflationReward(ctx context.Context, addresses []solana.PublicKey, opts *rpc.GetInflationRewardOpts) (out []*rpc.GetInflationRewardResult, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.GetInflationReward(ctx, addresses, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *failoverClient) SimulateTransactionWithOpts(ctx context.Context, transaction *solana.Transaction, opts *rpc.SimulateTransactionOpts) (out *rpc.SimulateTransactionResponse, err error) {
+	err = c.read(ctx, func(client ClientInterface) error {
+		out, err = client.SimulateTransactionWithOpts(ctx, transaction, opts)
+		return err
+	})
+	return out, err
+}
@@ -0,0 +1,221 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsEndpointFailure(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		err      error
+		expected bool
+	}{
+		{name: "Connection Refused", err: fmt.Errorf("rpc: %w", syscall.ECONNREFUSED), expected: true},
+		{name: "Connection Reset", err: syscall.ECONNRESET, expected: true},
+		{name: "Dial Error", err: &net.OpError{Op: "dial", Err: errors.New("no such host")}, expected: true},
+		{name: "Dropped Response", err: io.ErrUnexpectedEOF, expected: true},
+		{name: "Request Timeout", err: context.DeadlineExceeded, expected: true},
+		{name: "Bad Gateway", err: &jsonrpc.HTTPError{Code: 502}, expected: true},
+		{name: "Rate Limited", err: &jsonrpc.HTTPError{Code: 429}},
+		{name: "RPC Error", err: &jsonrpc.RPCError{Code: -32002, Message: "Transaction simulation failed"}},
+		{name: "Not Found", err: rpc.ErrNotFound},
+		{name: "Caller Gave Up", ctx: cancelled, err: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			assert.Equal(t, tt.expected, isEndpointFailure(ctx, tt.err))
+		})
+	}
+}
+
+// fakeEndpoints serves a fake node per endpoint URL and counts the calls each one received.
+type fakeEndpoints struct {
+	health map[string]string
+	down   map[string]bool
+	// limited endpoints answer every request with 429 Too Many Requests.
+	limited map[string]bool
+	calls   map[string]int
+	sends   map[string]int
+}
+
+func newFakeEndpoints(t *testing.T) *fakeEndpoints {
+	previous := newRPCClient
+	t.Cleanup(func() { newRPCClient = previous })
+
+	f := &fakeEndpoints{health: make(map[string]string), down: make(map[string]bool), limited: make(map[string]bool), calls: make(map[string]int), sends: make(map[string]int)}
+	newRPCClient = func(endpoint string) ClientInterface {
+		fail := func() error {
+			f.calls[endpoint]++
+			if f.down[endpoint] {
+				return &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+			}
+			if f.limited[endpoint] {
+				return &jsonrpc.HTTPError{Code: 429}
+			}
+			return nil
+		}
+		return &MockClientInterface{
+			GetHealthFn: func(context.Context) (string, error) {
+				if err := fail(); err != nil {
+					return "", err
+				}
+				if health, ok := f.health[endpoint]; ok {
+					return health, nil
+				}
+				return rpc.HealthOk, nil
+			},
+			GetBalanceFn: func(context.Context, solana.PublicKey, rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				if err := fail(); err != nil {
+					return nil, err
+				}
+				return &rpc.GetBalanceResult{Value: uint64(len(endpoint))}, nil
+			},
+			SendTransactionFn: func(context.Context, *solana.Transaction) (solana.Signature, error) {
+				f.sends[endpoint]++
+				if err := fail(); err != nil {
+					return solana.Signature{}, err
+				}
+				return solana.Signature{1}, nil
+			},
+		}
+	}
+	return f
+}
+
+func TestFailoverClientStartsOnFirstHealthyEndpoint(t *testing.T) {
+	fake := newFakeEndpoints(t)
+	healthProbes = &healthCache{entries: make(map[string]healthEntry), now: time.Now}
+	fake.health["http://primary"] = "behind"
+
+	tracker := &endpointTracker{}
+	client := newFailoverClient([]string{"http://primary", "http://secondary", "http://tertiary"}, tracker)
+	balance, err := client.GetBalance(context.Background(), solana.PublicKey{}, rpc.CommitmentConfirmed)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(len("http://secondary")), balance.Value)
+
+	// Only the endpoints up to the first healthy one were probed, and the lagging one was not used.
+	assert.Equal(t, 1, fake.calls["http://primary"])
+	assert.Equal(t, 2, fake.calls["http://secondary"])
+	assert.Zero(t, fake.calls["http://tertiary"])
+	assert.Equal(t, []EndpointUsage{{Endpoint: "http://secondary", Requests: 1}}, tracker.usage)
+}
+
+func TestFailoverClientMovesReadsToNextEndpoint(t *testing.T) {
+	fake := newFakeEndpoints(t)
+	healthProbes = &healthCache{entries: make(map[string]healthEntry), now: time.Now}
+	tracker := &endpointTracker{}
+	client := newFailoverClient([]string{"http://primary", "http://secondary"}, tracker)
+
+	_, err := client.GetBalance(context.Background(), solana.PublicKey{}, rpc.CommitmentConfirmed)
+	assert.NoError(t, err)
+
+	// The primary goes down mid-command: the read is issued again against the secondary.
+	fake.down["http://primary"] = true
+	balance, err := client.GetBalance(context.Background(), solana.PublicKey{}, rpc.CommitmentConfirmed)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(len("http://secondary")), balance.Value)
+	assert.Equal(t, []EndpointUsage{
+		{Endpoint: "http://primary", Requests: 1, Failed: 1},
+		{Endpoint: "http://secondary", Requests: 1},
+	}, tracker.usage)
+	assert.Equal(t, "http://secondary", tracker.last)
+
+	// With every endpoint down the read fails after trying each once.
+	fake.down["http://secondary"] = true
+	before := fake.calls["http://primary"] + fake.calls["http://secondary"]
+	_, err = client.GetBalance(context.Background(), solana.PublicKey{}, rpc.CommitmentConfirmed)
+	assert.True(t, errors.Is(err, syscall.ECONNREFUSED))
+	assert.ErrorIs(t, err, ErrRPCUnavailable)
+	assert.Equal(t, before+2, fake.calls["http://primary"]+fake.calls["http://secondary"])
+}
+
+func TestFailoverClientMovesOffRateLimitedEndpoint(t *testing.T) {
+	fake := newFakeEndpoints(t)
+	healthProbes = &healthCache{entries: make(map[string]healthEntry), now: time.Now}
+	tracker := &endpointTracker{}
+	client := newFailoverClient([]string{"http://helius", "http://quicknode"}, tracker)
+
+	_, err := client.GetBalance(context.Background(), solana.PublicKey{}, rpc.CommitmentConfirmed)
+	assert.NoError(t, err)
+
+	// The provider starts rate limiting mid-command: the read is issued again against the next one.
+	fake.limited["http://helius"] = true
+	balance, err := client.GetBalance(context.Background(), solana.PublicKey{}, rpc.CommitmentConfirmed)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(len("http://quicknode")), balance.Value)
+	assert.Equal(t, []EndpointUsage{
+		{Endpoint: "http://helius", Requests: 1, Failed: 1},
+		{Endpoint: "http://quicknode", Requests: 1},
+	}, tracker.usage)
+
+	// A single endpoint keeps the rate limit for the retry policy to back off from.
+	before := fake.calls["http://helius"]
+	single := newFailoverClient([]string{"http://helius"}, &endpointTracker{})
+	_, err = single.GetBalance(context.Background(), solana.PublicKey{}, rpc.CommitmentConfirmed)
+	assert.True(t, isRateLimited(err))
+	assert.Equal(t, before+1, fake.calls["http://helius"])
+}
+
+func TestFailoverClientNeverRebroadcastsSends(t *testing.T) {
+	fake := newFakeEndpoints(t)
+	healthProbes = &healthCache{entries: make(map[string]healthEntry), now: time.Now}
+	tracker := &endpointTracker{}
+	client := newFailoverClient([]string{"http://primary", "http://secondary"}, tracker)
+
+	_, err := client.GetBalance(context.Background(), solana.PublicKey{}, rpc.CommitmentConfirmed)
+	assert.NoError(t, err)
+	fake.down["http://primary"] = true
+
+	_, err = client.SendTransaction(context.Background(), &solana.Transaction{})
+	assert.True(t, errors.Is(err, syscall.ECONNREFUSED))
+	assert.Equal(t, 1, fake.sends["http://primary"])
+	assert.Zero(t, fake.sends["http://secondary"], "the failed send is not issued again elsewhere")
+
+	// The next send goes to the secondary.
+	_, err = client.SendTransaction(context.Background(), &solana.Transaction{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.sends["http://primary"])
+	assert.Equal(t, 1, fake.sends["http://secondary"])
+}
+
+func TestHealthCacheExpires(t *testing.T) {
+	fake := newFakeEndpoints(t)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cache := &healthCache{entries: make(map[string]healthEntry), now: func() time.Time { return now }}
+	client := newRPCClient("http://primary")
+
+	assert.True(t, cache.healthy(context.Background(), "http://primary", client))
+	fake.health["http://primary"] = "behind"
+	assert.True(t, cache.healthy(context.Background(), "http://primary", client), "the cached outcome is used")
+	assert.Equal(t, 1, fake.calls["http://primary"])
+
+	now = now.Add(healthProbeTTL + time.Second)
+	assert.False(t, cache.healthy(context.Background(), "http://primary", client))
+	assert.Equal(t, 2, fake.calls["http://primary"])
+
+	// A failed request marks the endpoint unhealthy without a probe.
+	cache.set("http://secondary", false)
+	assert.False(t, cache.healthy(context.Background(), "http://secondary", newRPCClient("http://secondary")))
+	assert.Zero(t, fake.calls["http://secondary"])
+}
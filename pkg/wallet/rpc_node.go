@@ -0,0 +1,383 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// nodeHeaders are response headers that RPC providers and load balancers use to name the backend
+// that answered a request.
+var nodeHeaders = []string{"X-Node-Id", "X-Served-By", "X-Backend-Server"}
+
+// NodeOptions controls how the RPC clients of this process attribute responses to nodes.
+type NodeOptions struct {
+	// Pin resolves the host of each RPC endpoint once and sends every request to that address,
+	// keeping sticky-session cookies, so a load balancer serves the whole command from one backend
+	// where it can.
+	Pin bool
+	// OnNode is called with every node seen serving an endpoint: once per endpoint with its identity
+	// and, when pinned, its address, and again whenever a node names itself in a response header.
+	// Identities are only looked up when OnNode is set.
+	OnNode func(NodeInfo)
+	// OnSlotRegression is called when a response reports an older slot than a response at the same
+	// commitment did before, meaning the two came from nodes that disagree.
+	OnSlotRegression func(SlotRegression)
+}
+
+// NodeInfo names an RPC node that served requests.
+type NodeInfo struct {
+	Endpoint string
+	// Address is the IP requests are pinned to, empty unless pinned.
+	Address string
+	// Identity is the identity public key the node reported with getIdentity.
+	Identity string
+	// Header is the name the node gave itself in a response header.
+	Header string
+}
+
+func (n NodeInfo) String() string {
+	node := n.Endpoint
+	if n.Address != "" {
+		node += " pinned to " + n.Address
+	}
+	if n.Identity != "" {
+		node += ", identity " + n.Identity
+	}
+	if n.Header != "" {
+		node += ", served by " + n.Header
+	}
+	return node
+}
+
+// SlotRegression is a response that reported an older slot than one seen before at the same commitment.
+type SlotRegression struct {
+	Method     string
+	Commitment string
+	Slot       uint64
+	Highest    uint64
+	// Node is the node that last named itself in a response header, if any.
+	Node string
+}
+
+func (r SlotRegression) String() string {
+	message := fmt.Sprintf("%s returned %s data at slot %d, %d slots behind a previous response", r.Method, r.Commitment, r.Slot, r.Highest-r.Slot)
+	if r.Node != "" {
+		message += " (served by " + r.Node + ")"
+	}
+	return message
+}
+
+// nodeSession is the state RPC clients share for one process: one HTTP client per endpoint, so
+// pinning and cookies hold across clients, and the slots seen so far.
+type nodeSession struct {
+	opts    NodeOptions
+	tracker *slotTracker
+	// endpoints records which RPC endpoints served requests.
+	endpoints *endpointTracker
+
+	mu          sync.Mutex
+	httpClients map[string]*http.Client
+	headerNodes map[string]bool
+	// noBatch holds the endpoints that rejected a batch request.
+	noBatch map[string]bool
+
+	// pinned maps each host to the address it was first resolved to.
+	pinMu  sync.Mutex
+	pinned map[string]string
+}
+
+func newNodeSession(opts NodeOptions) *nodeSession {
+	return &nodeSession{
+		opts:        opts,
+		tracker:     &slotTracker{highest: make(map[string]uint64), onRegression: opts.OnSlotRegression},
+		endpoints:   &endpointTracker{},
+		httpClients: make(map[string]*http.Client),
+		headerNodes: make(map[string]bool),
+		noBatch:     make(map[string]bool),
+		pinned:      make(map[string]string),
+	}
+}
+
+var (
+	nodeSessionMu sync.Mutex
+	rpcNodes      = newNodeSession(NodeOptions{})
+)
+
+// SetNodeOptions configures node tracking for the RPC clients created after it, forgetting the
+// nodes, slots and served endpoints seen so far. Call it before creating a WalletConfig.
+func SetNodeOptions(opts NodeOptions) {
+	nodeSessionMu.Lock()
+	defer nodeSessionMu.Unlock()
+	rpcNodes = newNodeSession(opts)
+}
+
+func currentNodeSession() *nodeSession {
+	nodeSessionMu.Lock()
+	defer nodeSessionMu.Unlock()
+	return rpcNodes
+}
+
+// client returns an RPC client for endpoint whose responses are checked for slot regressions.
+func (s *nodeSession) client(endpoint string) ClientInterface {
+	httpClient, isNew := s.httpClient(endpoint)
+	client := rpc.NewWithCustomRPCClient(jsonrpc.NewClientWithOpts(endpoint, &jsonrpc.RPCClientOpts{HTTPClient: httpClient}))
+
+	if isNew && s.opts.OnNode != nil {
+		s.identify(endpoint, client)
+	}
+	return &slotCheckingClient{ClientInterface: client, tracker: s.tracker, rpc: client, endpoint: endpoint, session: s}
+}
+
+// batches reports whether endpoint is not known to reject batch requests.
+func (s *nodeSession) batches(endpoint string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.noBatch[endpoint]
+}
+
+// rejectBatches remembers that endpoint rejected a batch request, so later reads skip straight to single calls.
+func (s *nodeSession) rejectBatches(endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noBatch[endpoint] = true
+}
+
+// identify reports the node serving endpoint, by the identity it returns from getIdentity and the
+// address requests are pinned to. Failing to look it up only leaves the identity out.
+func (s *nodeSession) identify(endpoint string, client *rpc.Client) {
+	info := NodeInfo{Endpoint: endpoint}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	if identity, err := client.GetIdentity(ctx); err == nil {
+		info.Identity = identity.Identity.String()
+	}
+
+	if s.opts.Pin {
+		info.Address = s.pinnedAddress(endpoint)
+	}
+	s.opts.OnNode(info)
+}
+
+// httpClient returns the HTTP client shared by every RPC client of endpoint, creating it on first use.
+func (s *nodeSession) httpClient(endpoint string) (*http.Client, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if client, ok := s.httpClients[endpoint]; ok {
+		return client, false
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	client := &http.Client{Timeout: 5 * time.Minute}
+	if s.opts.Pin {
+		transport.DialContext = s.pinnedDial(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 3 * time.Minute})
+		// Load balancers that support sticky sessions keep them with a cookie.
+		client.Jar, _ = cookiejar.New(nil)
+	}
+	client.Transport = &nodeHeaderTransport{base: transport, onNode: func(node string) { s.headerNode(endpoint, node) }}
+
+	s.httpClients[endpoint] = client
+	return client, true
+}
+
+// pinnedAddress returns the address requests to endpoint are pinned to, once one has been resolved.
+func (s *nodeSession) pinnedAddress(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+
+	s.pinMu.Lock()
+	defer s.pinMu.Unlock()
+	return s.pinned[u.Hostname()]
+}
+
+// pinnedDial dials the address a host was first resolved to, for every connection of the process, so
+// DNS-level load balancing cannot move later requests to another node.
+func (s *nodeSession) pinnedDial(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		s.pinMu.Lock()
+		ip, ok := s.pinned[host]
+		s.pinMu.Unlock()
+
+		if !ok {
+			addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			if len(addrs) == 0 {
+				return nil, fmt.Errorf("no addresses for %s", host)
+			}
+
+			// Concurrent first dials may resolve differently; the first one stored wins.
+			s.pinMu.Lock()
+			if ip, ok = s.pinned[host]; !ok {
+				ip = addrs[0]
+				s.pinned[host] = ip
+			}
+			s.pinMu.Unlock()
+		}
+
+		return dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+	}
+}
+
+// headerNode records the node named in a response header and reports it the first time it is seen.
+func (s *nodeSession) headerNode(endpoint, node string) {
+	s.tracker.setNode(node)
+
+	s.mu.Lock()
+	seen := s.headerNodes[endpoint+" "+node]
+	s.headerNodes[endpoint+" "+node] = true
+	s.mu.Unlock()
+
+	if !seen && s.opts.OnNode != nil {
+		s.opts.OnNode(NodeInfo{Endpoint: endpoint, Header: node})
+	}
+}
+
+// nodeHeaderTransport reports the node named in the headers of every response.
+type nodeHeaderTransport struct {
+	base   http.RoundTripper
+	onNode func(node string)
+}
+
+func (t *nodeHeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range nodeHeaders {
+		if node := resp.Header.Get(header); node != "" {
+			t.onNode(node)
+			break
+		}
+	}
+	return resp, nil
+}
+
+// defaultCommitment is the commitment a node applies to requests that do not set one.
+const defaultCommitment = rpc.CommitmentFinalized
+
+// slotTracker remembers the highest slot responses reported at each commitment. Slots are only
+// comparable at the same commitment: finalized data always trails confirmed data by some slots.
+type slotTracker struct {
+	mu           sync.Mutex
+	highest      map[string]uint64
+	node         string
+	onRegression func(SlotRegression)
+}
+
+// observe records the slot of a response and reports it when it is older than one seen before.
+// Responses without a slot are ignored.
+func (t *slotTracker) observe(method string, commitment rpc.CommitmentType, slot uint64) {
+	if slot == 0 {
+		return
+	}
+	if commitment == "" {
+		commitment = defaultCommitment
+	}
+
+	t.mu.Lock()
+	highest := t.highest[string(commitment)]
+	if slot > highest {
+		t.highest[string(commitment)] = slot
+	}
+	node := t.node
+	t.mu.Unlock()
+
+	if slot < highest && t.onRegression != nil {
+		t.onRegression(SlotRegression{Method: method, Commitment: string(commitment), Slot: slot, Highest: highest, Node: node})
+	}
+}
+
+func (t *slotTracker) setNode(node string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.node = node
+}
+
+// slotCheckingClient passes the slot of every response that has one to a slotTracker.
+type slotCheckingClient struct {
+	ClientInterface
+	tracker *slotTracker
+
+	rpc      *rpc.Client
+	endpoint string
+	session  *nodeSession
+}
+
+// RPCCallBatch sends requests in one batch, or returns errBatchUnsupported when the endpoint rejects
+// batches. The slots of batched responses are not checked.
+func (c *slotCheckingClient) RPCCallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	if !c.session.batches(c.endpoint) {
+		return nil, errBatchUnsupported
+	}
+
+	responses, err := c.rpc.RPCCallBatch(ctx, requests)
+	if isBatchRejected(ctx, err) {
+		c.session.rejectBatches(c.endpoint)
+		return nil, fmt.Errorf("%w: %v", errBatchUnsupported, err)
+	}
+	return responses, err
+}
+
+func (c *slotCheckingClient) GetBalance(ctx context.Context, publicKey solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+	out, err := c.ClientInterface.GetBalance(ctx, publicKey, commitment)
+	if err == nil && out != nil {
+		c.tracker.observe("getBalance", commitment, out.Context.Slot)
+	}
+	return out, err
+}
+
+func (c *slotCheckingClient) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	out, err := c.ClientInterface.GetAccountInfo(ctx, account)
+	if err == nil && out != nil {
+		c.tracker.observe("getAccountInfo", defaultCommitment, out.Context.Slot)
+	}
+	return out, err
+}
+
+func (c *slotCheckingClient) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	out, err := c.ClientInterface.GetLatestBlockhash(ctx, commitment)
+	if err == nil && out != nil {
+		c.tracker.observe("getLatestBlockhash", commitment, out.Context.Slot)
+	}
+	return out, err
+}
+
+func (c *slotCheckingClient) GetFeeForMessage(ctx context.Context, message string, commitment rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+	out, err := c.ClientInterface.GetFeeForMessage(ctx, message, commitment)
+	if err == nil && out != nil {
+		c.tracker.observe("getFeeForMessage", commitment, out.Context.Slot)
+	}
+	return out, err
+}
+
+func (c *slotCheckingClient) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+	out, err := c.ClientInterface.GetTokenAccountsByOwner(ctx, owner, conf, opts)
+	if err == nil && out != nil {
+		var commitment rpc.CommitmentType
+		if opts != nil {
+			commitment = opts.Commitment
+		}
+		c.tracker.observe("getTokenAccountsByOwner", commitment, out.Context.Slot)
+	}
+	return out, err
+}
@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// nodeAt fakes a node whose responses report slot at confirmed commitment and slot-32 at finalized.
+func nodeAt(slot uint64) *MockClientInterface {
+	slotAt := func(commitment rpc.CommitmentType) uint64 {
+		if commitment == rpc.CommitmentConfirmed {
+			return slot
+		}
+		return slot - 32
+	}
+	return &MockClientInterface{
+		GetBalanceFn: func(_ context.Context, _ solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+			return &rpc.GetBalanceResult{RPCContext: rpc.RPCContext{Context: rpc.Context{Slot: slotAt(commitment)}}}, nil
+		},
+		GetLatestBlockhashFn: func(_ context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+			return &rpc.GetLatestBlockhashResult{RPCContext: rpc.RPCContext{Context: rpc.Context{Slot: slotAt(commitment)}}}, nil
+		},
+		GetAccountInfoFn: func(context.Context, solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+			return &rpc.GetAccountInfoResult{RPCContext: rpc.RPCContext{Context: rpc.Context{Slot: slotAt(rpc.CommitmentFinalized)}}}, nil
+		},
+	}
+}
+
+func TestSlotRegressions(t *testing.T) {
+	account := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name  string
+		calls func(ctx context.Context, ahead, behind ClientInterface)
+		want  []SlotRegression
+	}{
+		{
+			name: "same node moving forward",
+			calls: func(ctx context.Context, ahead, _ ClientInterface) {
+				_, _ = ahead.GetBalance(ctx, account, rpc.CommitmentFinalized)
+				_, _ = ahead.GetAccountInfo(ctx, account)
+				_, _ = ahead.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+			},
+		},
+		{
+			name: "lagging node at the same commitment",
+			calls: func(ctx context.Context, ahead, behind ClientInterface) {
+				_, _ = ahead.GetBalance(ctx, account, rpc.CommitmentFinalized)
+				_, _ = behind.GetAccountInfo(ctx, account)
+			},
+			want: []SlotRegression{{Method: "getAccountInfo", Commitment: "finalized", Slot: 968, Highest: 1068}},
+		},
+		{
+			name: "unset commitment compares with finalized",
+			calls: func(ctx context.Context, ahead, behind ClientInterface) {
+				_, _ = ahead.GetBalance(ctx, account, "")
+				_, _ = behind.GetBalance(ctx, account, rpc.CommitmentFinalized)
+			},
+			want: []SlotRegression{{Method: "getBalance", Commitment: "finalized", Slot: 968, Highest: 1068}},
+		},
+		{
+			name: "different commitments are not compared",
+			calls: func(ctx context.Context, ahead, behind ClientInterface) {
+				_, _ = ahead.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+				_, _ = behind.GetBalance(ctx, account, rpc.CommitmentFinalized)
+				_, _ = behind.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+			},
+			want: []SlotRegression{{Method: "getLatestBlockhash", Commitment: "confirmed", Slot: 1000, Highest: 1100}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []SlotRegression
+			tracker := &slotTracker{highest: make(map[string]uint64), onRegression: func(r SlotRegression) { got = append(got, r) }}
+
+			ahead := &slotCheckingClient{ClientInterface: nodeAt(1100), tracker: tracker}
+			behind := &slotCheckingClient{ClientInterface: nodeAt(1000), tracker: tracker}
+			tt.calls(context.Background(), ahead, behind)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSlotRegressionString(t *testing.T) {
+	regression := SlotRegression{Method: "getBalance", Commitment: "finalized", Slot: 968, Highest: 1068, Node: "rpc-2"}
+	assert.Equal(t, "getBalance returned finalized data at slot 968, 100 slots behind a previous response (served by rpc-2)", regression.String())
+}
+
+func TestNodeSession(t *testing.T) {
+	identity := solana.NewWallet().PublicKey()
+
+	var (
+		mu    sync.Mutex
+		slots = []uint64{2000, 1990}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     interface{} `json:"id"`
+			Method string      `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "getIdentity":
+			result = map[string]string{"identity": identity.String()}
+		case "getBalance":
+			mu.Lock()
+			slot := slots[0]
+			slots = slots[1:]
+			mu.Unlock()
+			result = map[string]interface{}{"context": map[string]uint64{"slot": slot}, "value": 5}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Served-By", "rpc-2")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	defer server.Close()
+
+	var (
+		nodes       []NodeInfo
+		regressions []SlotRegression
+	)
+	session := newNodeSession(NodeOptions{
+		Pin:              true,
+		OnNode:           func(node NodeInfo) { nodes = append(nodes, node) },
+		OnSlotRegression: func(r SlotRegression) { regressions = append(regressions, r) },
+	})
+
+	// Clients of the same endpoint share a connection, so the node is identified once.
+	first, second := session.client(server.URL), session.client(server.URL)
+	_, err := first.GetBalance(context.Background(), identity, rpc.CommitmentFinalized)
+	assert.NoError(t, err)
+	_, err = second.GetBalance(context.Background(), identity, rpc.CommitmentFinalized)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []NodeInfo{
+		{Endpoint: server.URL, Header: "rpc-2"},
+		{Endpoint: server.URL, Address: "127.0.0.1", Identity: identity.String()},
+	}, nodes)
+	assert.Equal(t, []SlotRegression{{Method: "getBalance", Commitment: "finalized", Slot: 1990, Highest: 2000, Node: "rpc-2"}}, regressions)
+}
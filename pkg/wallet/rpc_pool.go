@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrEndpointExists   = errors.New("RPC endpoint already registered")
+	ErrEndpointNotFound = errors.New("RPC endpoint not registered")
+)
+
+// RPCEndpoint is an RPC provider registered in the root config, such as a Helius or QuickNode URL.
+// The endpoints registered for a network form its failover pool, tried in the order they were added
+// and ahead of the public endpoint of the network.
+type RPCEndpoint struct {
+	Name    string  `json:"name"`
+	URL     string  `json:"url"`
+	Network Network `json:"network"`
+}
+
+// RPCEndpoints returns the registered endpoints in the order they were added.
+func (p *ProfileManager) RPCEndpoints() ([]RPCEndpoint, error) {
+	config, err := p.readRootConfig()
+	if err != nil {
+		return nil, err
+	}
+	return config.RPCEndpoints, nil
+}
+
+// AddRPCEndpoint registers an endpoint for all profiles. Names are unique, and a URL is registered
+// at most once per network.
+func (p *ProfileManager) AddRPCEndpoint(endpoint RPCEndpoint) error {
+	if !profileNamePattern.MatchString(endpoint.Name) {
+		return fmt.Errorf("invalid endpoint name %q: use up to 32 letters, digits, dashes or underscores", endpoint.Name)
+	}
+	if _, ok := knownClusters[endpoint.Network]; !ok {
+		return fmt.Errorf("endpoints can only be registered for devnet, testnet, mainnet-beta or localnet, not %q", endpoint.Network)
+	}
+	// The pool is passed on as a comma-separated list, like --rpc-url.
+	if strings.Contains(endpoint.URL, ",") {
+		return fmt.Errorf("invalid RPC URL %q: it must not contain a comma", endpoint.URL)
+	}
+	if _, err := wsURLFromRPC(endpoint.URL); err != nil {
+		return err
+	}
+
+	config, err := p.readRootConfig()
+	if err != nil {
+		return err
+	}
+	for _, registered := range config.RPCEndpoints {
+		if registered.Name == endpoint.Name {
+			return fmt.Errorf("%w: %s", ErrEndpointExists, endpoint.Name)
+		}
+		if registered.URL == endpoint.URL && registered.Network == endpoint.Network {
+			return fmt.Errorf("%w: the URL is registered for %s as %s", ErrEndpointExists, endpoint.Network, registered.Name)
+		}
+	}
+
+	config.RPCEndpoints = append(config.RPCEndpoints, endpoint)
+	return p.writeRootConfig(config)
+}
+
+// RemoveRPCEndpoint unregisters the endpoint with name and returns it.
+func (p *ProfileManager) RemoveRPCEndpoint(name string) (RPCEndpoint, error) {
+	config, err := p.readRootConfig()
+	if err != nil {
+		return RPCEndpoint{}, err
+	}
+	for i, registered := range config.RPCEndpoints {
+		if registered.Name == name {
+			config.RPCEndpoints = append(config.RPCEndpoints[:i], config.RPCEndpoints[i+1:]...)
+			return registered, p.writeRootConfig(config)
+		}
+	}
+	return RPCEndpoint{}, fmt.Errorf("%w: %s", ErrEndpointNotFound, name)
+}
+
+// UseRPCPool sets the registered endpoints. Those of the selected network are used when no RPC URL
+// is given, followed by the public endpoint of the network as the last fallback.
+func (w *WalletConfig) UseRPCPool(pool []RPCEndpoint) {
+	w.RPCPool = pool
+}
+
+// poolFor returns the endpoints of the pool registered for network, in order.
+func (w *WalletConfig) poolFor(network Network) []RPCEndpoint {
+	var endpoints []RPCEndpoint
+	for _, endpoint := range w.RPCPool {
+		if endpoint.Network == network {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// poolRPCURL returns the pool of network as an RPC URL list ending in the public endpoint of the
+// network, or "" when nothing is registered for it.
+func (w *WalletConfig) poolRPCURL(network Network) string {
+	pool := w.poolFor(network)
+	cluster, ok := knownClusters[network]
+	if len(pool) == 0 || !ok {
+		return ""
+	}
+
+	urls := make([]string, 0, len(pool)+1)
+	for _, endpoint := range pool {
+		urls = append(urls, endpoint.URL)
+	}
+	return strings.Join(append(urls, cluster.RPC), ",")
+}
@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRPCEndpointRegistry(t *testing.T) {
+	profiles := newTestProfileManager(t)
+
+	helius := RPCEndpoint{Name: "helius", URL: "https://mainnet.helius-rpc.com/?api-key=k", Network: MainnetBeta}
+	quicknode := RPCEndpoint{Name: "quicknode", URL: "https://example.quiknode.pro/k/", Network: MainnetBeta}
+	assert.NoError(t, profiles.AddRPCEndpoint(helius))
+	assert.NoError(t, profiles.AddRPCEndpoint(quicknode))
+
+	assert.ErrorIs(t, profiles.AddRPCEndpoint(RPCEndpoint{Name: "helius", URL: "https://other.example", Network: Devnet}), ErrEndpointExists)
+	assert.ErrorIs(t, profiles.AddRPCEndpoint(RPCEndpoint{Name: "again", URL: helius.URL, Network: MainnetBeta}), ErrEndpointExists)
+	assert.Error(t, profiles.AddRPCEndpoint(RPCEndpoint{Name: "custom", URL: "https://rpc.example", Network: Custom}))
+	assert.Error(t, profiles.AddRPCEndpoint(RPCEndpoint{Name: "ftp", URL: "ftp://rpc.example", Network: Devnet}))
+	assert.Error(t, profiles.AddRPCEndpoint(RPCEndpoint{Name: "../x", URL: "https://rpc.example", Network: Devnet}))
+
+	endpoints, err := profiles.RPCEndpoints()
+	assert.NoError(t, err)
+	assert.Equal(t, []RPCEndpoint{helius, quicknode}, endpoints)
+
+	removed, err := profiles.RemoveRPCEndpoint("helius")
+	assert.NoError(t, err)
+	assert.Equal(t, helius, removed)
+	_, err = profiles.RemoveRPCEndpoint("helius")
+	assert.ErrorIs(t, err, ErrEndpointNotFound)
+}
+
+func TestEndpointsUseThePoolOfTheNetwork(t *testing.T) {
+	pool := []RPCEndpoint{
+		{Name: "helius", URL: "https://mainnet.helius-rpc.com/?api-key=k", Network: MainnetBeta},
+		{Name: "devnet-helius", URL: "https://devnet.helius-rpc.com/?api-key=k", Network: Devnet},
+		{Name: "quicknode", URL: "https://example.quiknode.pro/k/", Network: MainnetBeta},
+	}
+
+	tests := []struct {
+		name     string
+		network  Network
+		rpcURL   string
+		expected Endpoints
+		display  string
+	}{
+		{
+			name:    "Pool Then Public Endpoint",
+			network: MainnetBeta,
+			expected: Endpoints{
+				RPC:       "https://mainnet.helius-rpc.com/?api-key=k",
+				WS:        "wss://mainnet.helius-rpc.com/?api-key=k",
+				Fallbacks: []string{"https://example.quiknode.pro/k/", "https://api.mainnet-beta.solana.com"},
+			},
+			display: "mainnet-beta (helius, quicknode)",
+		},
+		{
+			name:     "Nothing Registered",
+			network:  Testnet,
+			expected: Endpoints{RPC: "https://api.testnet.solana.com", WS: "wss://api.testnet.solana.com"},
+			display:  "testnet",
+		},
+		{
+			name:     "RPC URL Takes Precedence",
+			network:  MainnetBeta,
+			rpcURL:   "https://rpc.example",
+			expected: Endpoints{RPC: "https://rpc.example", WS: "wss://rpc.example"},
+			display:  "mainnet-beta (https://rpc.example)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc := &WalletConfig{Network: tt.network, RPCURL: tt.rpcURL}
+			wc.UseRPCPool(pool)
+			endpoints, err := wc.Endpoints()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, endpoints)
+			assert.Equal(t, tt.display, wc.NetworkName())
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"golang.org/x/crypto/ed25519"
+)
+
+// DefaultGapLimit is how many consecutive unused accounts end a seed scan, as in BIP-44 account
+// discovery.
+const DefaultGapLimit = 20
+
+// seedScanConcurrency bounds how many accounts ScanSeed looks up at once.
+const seedScanConcurrency = 8
+
+// SeedScanOptions controls ScanSeed.
+type SeedScanOptions struct {
+	// Passphrase is the optional BIP-39 passphrase of the seed phrase.
+	Passphrase string
+	// GapLimit is how many consecutive unused accounts end the scan; 0 means DefaultGapLimit.
+	GapLimit uint32
+}
+
+// ScannedAccount is an account of a seed phrase that has been used.
+type ScannedAccount struct {
+	Index     uint32 `json:"index"`
+	Path      string `json:"path"`
+	PublicKey string `json:"publicKey"`
+	Lamports  uint64 `json:"lamports"`
+	// HasHistory is true when the account appears in any transaction, even if it is empty now.
+	HasHistory bool `json:"hasHistory"`
+}
+
+// Funded reports whether the account holds SOL.
+func (a ScannedAccount) Funded() bool {
+	return a.Lamports > 0
+}
+
+// used reports whether the account holds SOL or ever took part in a transaction.
+func (a ScannedAccount) used() bool {
+	return a.Funded() || a.HasHistory
+}
+
+// SeedScan is the result of ScanSeed.
+type SeedScan struct {
+	// Used lists the accounts with a balance or transaction history, by index.
+	Used []ScannedAccount `json:"used"`
+	// Scanned is how many accounts were looked up: indexes 0 to Scanned-1.
+	Scanned uint32 `json:"scanned"`
+	// TotalLamports is the balance of all accounts together.
+	TotalLamports uint64 `json:"totalLamports"`
+}
+
+// Funded returns the used accounts that hold SOL.
+func (s *SeedScan) Funded() []ScannedAccount {
+	var funded []ScannedAccount
+	for _, account := range s.Used {
+		if account.Funded() {
+			funded = append(funded, account)
+		}
+	}
+	return funded
+}
+
+// ScanSeed derives the accounts of a seed phrase along m/44'/501'/<index>'/0' and looks each up for a
+// balance and transaction history, until GapLimit consecutive accounts after the last used one are
+// unused. It finds the accounts of wallets that rotate through receive addresses, so they can be
+// recovered. A lookup that fails after retries stops the scan, since skipping the account could hide
+// funds behind it.
+func (w *WalletConfig) ScanSeed(ctx context.Context, mnemonic string, opts SeedScanOptions) (*SeedScan, error) {
+	// A bad seed phrase fails before any request is made.
+	if _, err := deriveKeyFromMnemonic(mnemonic, SeedOptions{Passphrase: opts.Passphrase}); err != nil {
+		return nil, err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	gap := opts.GapLimit
+	if gap == 0 {
+		gap = DefaultGapLimit
+	}
+	derive := func(index uint32) (solana.PublicKey, error) {
+		key, err := deriveKeyFromMnemonic(mnemonic, SeedOptions{Passphrase: opts.Passphrase, AccountIndex: index})
+		if err != nil {
+			return solana.PublicKey{}, err
+		}
+		return solana.PrivateKey(key).PublicKey(), nil
+	}
+	return scanAccounts(ctx, endpoints.client(), derive, gap, seedScanConcurrency)
+}
+
+// scanAccounts looks up the accounts derive returns for increasing indexes, concurrency at a time,
+// and stops once gap consecutive accounts after the last used one are unused. No account past that
+// point is ever looked up, so the scan makes the same requests however the lookups interleave.
+func scanAccounts(ctx context.Context, client ClientInterface, derive func(uint32) (solana.PublicKey, error), gap uint32, concurrency int) (*SeedScan, error) {
+	scan := &SeedScan{}
+	limiter := newAdaptiveLimiter(concurrency)
+
+	// end is one past the last index the gap limit lets the scan reach.
+	end := uint64(gap)
+	for next := uint64(0); next < end; {
+		window := uint64(concurrency)
+		if next+window > end {
+			window = end - next
+		}
+
+		accounts := make([]ScannedAccount, window)
+		errs := make([]error, window)
+		var wg sync.WaitGroup
+		for i := range accounts {
+			index := uint32(next) + uint32(i)
+			publicKey, err := derive(index)
+			if err != nil {
+				return nil, err
+			}
+			if err := limiter.Acquire(ctx); err != nil {
+				return nil, fmt.Errorf("failed to acquire request slot: %w", err)
+			}
+
+			i := i // pin
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer limiter.Release()
+				accounts[i], errs[i] = lookupAccount(ctx, client, publicKey, limiter)
+				accounts[i].Index, accounts[i].Path = index, SolanaDerivationPath(index)
+			}()
+		}
+		wg.Wait()
+
+		for i, account := range accounts {
+			if errs[i] != nil {
+				return nil, fmt.Errorf("failed to look up account %d (%s): %w", account.Index, account.PublicKey, errs[i])
+			}
+			if account.used() {
+				scan.Used = append(scan.Used, account)
+				scan.TotalLamports += account.Lamports
+				end = uint64(account.Index) + 1 + uint64(gap)
+			}
+		}
+		next += window
+		scan.Scanned = uint32(next)
+	}
+
+	return scan, nil
+}
+
+// lookupAccount reads the balance of an account and whether it has any transaction history.
+func lookupAccount(ctx context.Context, client ClientInterface, publicKey solana.PublicKey, limiter *adaptiveLimiter) (ScannedAccount, error) {
+	account := ScannedAccount{PublicKey: publicKey.String()}
+	onRetry := func(err error) {
+		if isRateLimited(err) {
+			limiter.Throttle()
+		}
+	}
+
+	err := withRetry(ctx, DefaultRetryPolicy, onRetry, func(ctx context.Context) error {
+		balance, err := client.GetBalance(ctx, publicKey, rpc.CommitmentFinalized)
+		if err == nil {
+			account.Lamports = balance.Value
+		}
+		return err
+	})
+	if err != nil {
+		return account, fmt.Errorf("failed to fetch balance: %w", err)
+	}
+
+	limit := 1
+	err = withRetry(ctx, DefaultRetryPolicy, onRetry, func(ctx context.Context) error {
+		signatures, err := client.GetSignaturesForAddressWithOpts(ctx, publicKey, &rpc.GetSignaturesForAddressOpts{Limit: &limit})
+		if err == nil {
+			account.HasHistory = len(signatures) > 0
+		}
+		return err
+	})
+	if err != nil {
+		return account, fmt.Errorf("failed to fetch transaction history: %w", err)
+	}
+
+	return account, nil
+}
+
+// ImportSeedAccount stores the account at index of a seed phrase under alias, with its derivation,
+// and returns its address. Unlike ImportWalletFromSeedWithOptions it leaves the wallet in use alone,
+// so several accounts can be imported in a row.
+func (w *WalletConfig) ImportSeedAccount(mnemonic, passphrase string, index uint32, alias string) (string, error) {
+	opts := SeedOptions{Passphrase: passphrase, AccountIndex: index}
+	key, err := deriveKeyFromMnemonic(mnemonic, opts)
+	if err != nil {
+		return "", err
+	}
+
+	address := solana.PrivateKey(key).PublicKey().String()
+	if err := w.KeyOps.WriteDerivedKeyToFile(alias, ed25519.PrivateKey(key), address, opts.derivation()); err != nil {
+		return "", err
+	}
+	return address, nil
+}
@@ -0,0 +1,186 @@
+package wallet
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// sparseAccounts is a fake cluster where only some account indexes were ever used.
+type sparseAccounts struct {
+	funded  map[uint32]uint64
+	history map[uint32]bool
+	failAt  map[uint32]bool
+
+	mu     sync.Mutex
+	looked map[uint32]bool
+}
+
+// fakeAccountKey stands in for the key derived at index.
+func fakeAccountKey(index uint32) solana.PublicKey {
+	var key solana.PublicKey
+	binary.BigEndian.PutUint32(key[:4], index)
+	key[31] = 1
+	return key
+}
+
+func fakeAccountIndex(key solana.PublicKey) uint32 {
+	return binary.BigEndian.Uint32(key[:4])
+}
+
+func (s *sparseAccounts) client() ClientInterface {
+	s.looked = make(map[uint32]bool)
+	return &MockClientInterface{
+		GetBalanceFn: func(ctx context.Context, publicKey solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+			index := fakeAccountIndex(publicKey)
+			s.mu.Lock()
+			s.looked[index] = true
+			s.mu.Unlock()
+			if s.failAt[index] {
+				return nil, errors.New("account index not found")
+			}
+			return &rpc.GetBalanceResult{Value: s.funded[index]}, nil
+		},
+		GetSignaturesForAddressWithOptsFn: func(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+			if s.history[fakeAccountIndex(account)] {
+				return []*rpc.TransactionSignature{{}}, nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+func TestScanAccounts(t *testing.T) {
+	derive := func(index uint32) (solana.PublicKey, error) { return fakeAccountKey(index), nil }
+
+	tests := []struct {
+		name            string
+		accounts        *sparseAccounts
+		gap             uint32
+		expectedUsed    []uint32
+		expectedScanned uint32
+		expectedTotal   uint64
+		expectErr       bool
+	}{
+		{name: "Nothing Used", accounts: &sparseAccounts{}, gap: 20, expectedScanned: 20},
+		{name: "First Account Only", accounts: &sparseAccounts{funded: map[uint32]uint64{0: 5}}, gap: 20, expectedUsed: []uint32{0}, expectedScanned: 21, expectedTotal: 5},
+		{
+			name:            "Sparse Within The Gap",
+			accounts:        &sparseAccounts{funded: map[uint32]uint64{0: 1, 3: 2, 22: 4}},
+			gap:             20,
+			expectedUsed:    []uint32{0, 3, 22},
+			expectedScanned: 43,
+			expectedTotal:   7,
+		},
+		{
+			name:            "Account Past The Gap Is Not Found",
+			accounts:        &sparseAccounts{funded: map[uint32]uint64{0: 1, 3: 2, 24: 4}},
+			gap:             20,
+			expectedUsed:    []uint32{0, 3},
+			expectedScanned: 24,
+			expectedTotal:   3,
+		},
+		{
+			name:            "Larger Gap Finds It",
+			accounts:        &sparseAccounts{funded: map[uint32]uint64{0: 1, 3: 2, 24: 4}},
+			gap:             25,
+			expectedUsed:    []uint32{0, 3, 24},
+			expectedScanned: 50,
+			expectedTotal:   7,
+		},
+		{
+			name:            "Emptied Account Extends The Scan",
+			accounts:        &sparseAccounts{funded: map[uint32]uint64{30: 9}, history: map[uint32]bool{15: true}},
+			gap:             20,
+			expectedUsed:    []uint32{15, 30},
+			expectedScanned: 51,
+			expectedTotal:   9,
+		},
+		{name: "Gap Of One", accounts: &sparseAccounts{funded: map[uint32]uint64{0: 1, 1: 1, 3: 1}}, gap: 1, expectedUsed: []uint32{0, 1}, expectedScanned: 3, expectedTotal: 2},
+		{name: "Failed Lookup Stops The Scan", accounts: &sparseAccounts{funded: map[uint32]uint64{0: 1}, failAt: map[uint32]bool{5: true}}, gap: 20, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		for _, concurrency := range []int{1, 3, 8} {
+			t.Run(tt.name, func(t *testing.T) {
+				scan, err := scanAccounts(context.Background(), tt.accounts.client(), derive, tt.gap, concurrency)
+				if tt.expectErr {
+					assert.Error(t, err)
+					return
+				}
+				assert.NoError(t, err)
+
+				var used []uint32
+				for _, account := range scan.Used {
+					used = append(used, account.Index)
+					assert.Equal(t, fakeAccountKey(account.Index).String(), account.PublicKey)
+					assert.Equal(t, SolanaDerivationPath(account.Index), account.Path)
+				}
+				assert.Equal(t, tt.expectedUsed, used)
+				assert.Equal(t, tt.expectedScanned, scan.Scanned)
+				assert.Equal(t, tt.expectedTotal, scan.TotalLamports)
+
+				// Every index up to the end of the gap is looked up exactly, and none past it.
+				assert.Len(t, tt.accounts.looked, int(tt.expectedScanned))
+				for index := range tt.accounts.looked {
+					assert.Less(t, index, tt.expectedScanned)
+				}
+			})
+		}
+	}
+}
+
+func TestScanSeed(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	derived := make(map[solana.PublicKey]uint32)
+	for index := uint32(0); index < 5; index++ {
+		key, err := deriveKeyFromMnemonic(mnemonic, SeedOptions{AccountIndex: index})
+		assert.NoError(t, err)
+		derived[solana.PrivateKey(key).PublicKey()] = index
+	}
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(ctx context.Context, publicKey solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				if index, ok := derived[publicKey]; ok && index == 2 {
+					return &rpc.GetBalanceResult{Value: 1_500_000_000}, nil
+				}
+				return &rpc.GetBalanceResult{}, nil
+			},
+			GetSignaturesForAddressWithOptsFn: func(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+				return nil, nil
+			},
+		}
+	}
+
+	files := newMemFiles()
+	wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}, Network: Devnet}
+
+	_, err := wc.ScanSeed(context.Background(), "not a seed phrase", SeedScanOptions{})
+	assert.Error(t, err)
+
+	scan, err := wc.ScanSeed(context.Background(), mnemonic, SeedScanOptions{GapLimit: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(6), scan.Scanned)
+	funded := scan.Funded()
+	assert.Len(t, funded, 1)
+	assert.Equal(t, uint32(2), funded[0].Index)
+
+	address, err := wc.ImportSeedAccount(mnemonic, "", funded[0].Index, "seed-2")
+	assert.NoError(t, err)
+	assert.Equal(t, funded[0].PublicKey, address)
+
+	info, err := wc.GetWalletInfo("seed-2")
+	assert.NoError(t, err)
+	assert.Equal(t, address, info.PublicKey)
+	assert.Equal(t, SolanaDerivationPath(2), info.Derivation.Path)
+	assert.Nil(t, wc.Wallet, "importing leaves the wallet in use alone")
+}
@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// MultiSendItem is one recipient of a multi-recipient send and the amount it gets, as given to send.
+type MultiSendItem struct {
+	Recipient Recipient
+	Amount    string
+}
+
+// MultiSendQuote is a checked multi-recipient send: one quote per recipient, in order.
+type MultiSendQuote struct {
+	Items  []MultiSendItem
+	Quotes []*SendQuote
+}
+
+// Total returns the lamports sent to all recipients, without fees.
+func (q *MultiSendQuote) Total() uint64 {
+	var total uint64
+	for _, quote := range q.Quotes {
+		total += quote.Lamports
+	}
+	return total
+}
+
+// Fees returns the estimated fees of all transfers in lamports.
+func (q *MultiSendQuote) Fees() uint64 {
+	var fees uint64
+	for _, quote := range q.Quotes {
+		fees += quote.Fee
+	}
+	return fees
+}
+
+// PriorityFees returns the part of Fees paid for priority, in lamports.
+func (q *MultiSendQuote) PriorityFees() uint64 {
+	var fees uint64
+	for _, quote := range q.Quotes {
+		fees += quote.PriorityFee
+	}
+	return fees
+}
+
+// Fiat returns the value of the total in the quoted currency, or nil when no rate is available.
+func (q *MultiSendQuote) Fiat() *decimal.Decimal {
+	if len(q.Quotes) == 0 || q.Quotes[0].Rate == nil {
+		return nil
+	}
+	fiat := LamportsToSOL(q.Total()).Mul(*q.Quotes[0].Rate).Round(2)
+	return &fiat
+}
+
+// MultiSendError reports a multi-recipient send that stopped part way. The transfers in Sent landed.
+type MultiSendError struct {
+	Sent []*SendReceipt
+	// Failed is the recipient whose transfer failed; the ones after it were not sent.
+	Failed Recipient
+	Err    error
+}
+
+func (e *MultiSendError) Error() string {
+	return fmt.Sprintf("transfer to %s failed after %d were sent: %v", e.Failed, len(e.Sent), e.Err)
+}
+
+func (e *MultiSendError) Unwrap() error {
+	return e.Err
+}
+
+// PrepareSendMulti checks every transfer of a multi-recipient send with PrepareSend, then that the
+// balance covers all of them together. It returns an *InsufficientFundsError when it does not.
+func (w *WalletConfig) PrepareSendMulti(ctx context.Context, items []MultiSendItem, opts SendOptions) (*MultiSendQuote, error) {
+	if len(items) == 0 {
+		return nil, errors.New("no recipients to send to")
+	}
+
+	quote := &MultiSendQuote{Items: items}
+	for _, item := range items {
+		q, err := w.PrepareSend(ctx, item.Amount, item.Recipient.Address, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", item.Recipient, err)
+		}
+		quote.Quotes = append(quote.Quotes, q)
+	}
+
+	last := quote.Quotes[len(quote.Quotes)-1]
+	need := quote.Total() + quote.Fees()
+	if need > last.Balance {
+		return nil, &InsufficientFundsError{Have: last.Balance, Need: need, Fee: quote.Fees(), Rate: last.Rate, Currency: last.Currency}
+	}
+	return quote, nil
+}
+
+// SendFundsMulti sends the transfers of a quote made with PrepareSendMulti one after the other, each
+// the way ExecuteSend does. It stops at the first transfer that fails and returns a *MultiSendError
+// listing the ones that were sent.
+func (w *WalletConfig) SendFundsMulti(ctx context.Context, quote *MultiSendQuote, opts SendOptions) ([]*SendReceipt, error) {
+	receipts := make([]*SendReceipt, 0, len(quote.Quotes))
+	for i, q := range quote.Quotes {
+		receipt, err := w.ExecuteSend(ctx, q, opts)
+		if err != nil {
+			return receipts, &MultiSendError{Sent: receipts, Failed: quote.Items[i].Recipient, Err: err}
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
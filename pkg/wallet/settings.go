@@ -0,0 +1,135 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SettingsFile is the name of the YAML file in the configuration directory holding defaults for the
+// persistent flags, such as the cluster and the output format. Unlike RootConfigFile it is meant to be
+// edited by hand as well.
+const SettingsFile = "config.yaml"
+
+// Settings are the values of the settings file by key.
+type Settings map[string]string
+
+// SettingsPath returns the path of the settings file.
+func (p *ProfileManager) SettingsPath() string {
+	return filepath.Join(p.ConfigDir, SettingsFile)
+}
+
+// Settings reads the settings file, returning no settings when it does not exist yet. The file must
+// be a mapping of keys to scalar values.
+func (p *ProfileManager) Settings() (Settings, error) {
+	doc, err := p.readSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(Settings)
+	mapping := settingsMapping(doc)
+	if mapping == nil {
+		return settings, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s line %d: %s must be a single value", SettingsFile, value.Line, key.Value)
+		}
+		if value.Tag == "!!null" {
+			continue
+		}
+		settings[key.Value] = value.Value
+	}
+	return settings, nil
+}
+
+// SetSetting stores value under key in the settings file, or removes key when value is empty. The
+// rest of the file, including comments, is left as it is.
+func (p *ProfileManager) SetSetting(key, value string) error {
+	doc, err := p.readSettings()
+	if err != nil {
+		return err
+	}
+
+	mapping := settingsMapping(doc)
+	if mapping == nil {
+		mapping = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{mapping}}
+	}
+
+	found := false
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		found = true
+		if value == "" {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			break
+		}
+		// The style is reset so a value that needs quoting gets it.
+		mapping.Content[i+1].SetString(value)
+		break
+	}
+	if !found && value != "" {
+		var node yaml.Node
+		node.SetString(value)
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &node)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("error encoding %s: %w", SettingsFile, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("error encoding %s: %w", SettingsFile, err)
+	}
+
+	if err := guardWrite(p.ConfigDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.ConfigDir, 0700); err != nil {
+		return fmt.Errorf("error creating config directory: %w", err)
+	}
+
+	return p.FileWriter.WriteFile(p.SettingsPath(), buf.Bytes())
+}
+
+// readSettings parses the settings file, returning nil when it does not exist or is empty.
+func (p *ProfileManager) readSettings() (*yaml.Node, error) {
+	fileData, err := p.FileReader.ReadFile(p.SettingsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(fileData, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", SettingsFile, err)
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return nil, nil
+	}
+	if mapping := settingsMapping(&doc); mapping == nil {
+		return nil, fmt.Errorf("error parsing %s: expected a mapping of settings", SettingsFile)
+	}
+	return &doc, nil
+}
+
+// settingsMapping returns the top-level mapping of a parsed settings file, or nil when there is none.
+func settingsMapping(doc *yaml.Node) *yaml.Node {
+	if doc == nil || doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc.Content[0]
+}
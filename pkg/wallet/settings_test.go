@@ -0,0 +1,40 @@
+package wallet
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettings(t *testing.T) {
+	profiles := &ProfileManager{ConfigDir: t.TempDir(), FileReader: &IOUtilFileReader{}, FileWriter: newFileWriter()}
+
+	settings, err := profiles.Settings()
+	assert.NoError(t, err)
+	assert.Empty(t, settings, "a missing file has no settings")
+
+	file := "# Defaults for every profile.\nnetwork: mainnet-beta # the real money\noutput:\n"
+	assert.NoError(t, os.WriteFile(profiles.SettingsPath(), []byte(file), 0600))
+	settings, err = profiles.Settings()
+	assert.NoError(t, err)
+	assert.Equal(t, Settings{"network": "mainnet-beta"}, settings, "an empty value is not set")
+
+	assert.NoError(t, profiles.SetSetting("currency", "USD"))
+	assert.NoError(t, profiles.SetSetting("network", "testnet"))
+	assert.NoError(t, profiles.SetSetting("output", ""))
+	settings, err = profiles.Settings()
+	assert.NoError(t, err)
+	assert.Equal(t, Settings{"network": "testnet", "currency": "USD"}, settings)
+
+	data, err := os.ReadFile(profiles.SettingsPath())
+	assert.NoError(t, err)
+	assert.Equal(t, "# Defaults for every profile.\nnetwork: testnet # the real money\ncurrency: USD\n", string(data), "comments are kept")
+
+	assert.NoError(t, os.WriteFile(profiles.SettingsPath(), []byte("rpc-url:\n  - https://a\n"), 0600))
+	_, err = profiles.Settings()
+	assert.Error(t, err)
+	assert.NoError(t, os.WriteFile(profiles.SettingsPath(), []byte("- network\n"), 0600))
+	_, err = profiles.Settings()
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// ErrSimulationFailed is returned when the cluster ran a simulated transfer and it failed.
+var ErrSimulationFailed = errors.New("the simulated transfer failed")
+
+// BalanceChange is the SOL balance of an account before a transfer and after it was simulated.
+type BalanceChange struct {
+	Account solana.PublicKey
+	Before  uint64
+	After   uint64
+}
+
+// Change returns the difference the transfer makes to the balance, in lamports.
+func (c BalanceChange) Change() int64 {
+	return int64(c.After) - int64(c.Before)
+}
+
+// SendSimulation is what the cluster reported for a transfer it ran without committing it.
+type SendSimulation struct {
+	// Changes are the balances of the sender and the recipient, and of the nonce account for a
+	// durable nonce transfer, in that order.
+	Changes []BalanceChange
+	// UnitsConsumed is the compute units the transfer used, zero when the node does not say.
+	UnitsConsumed uint64
+	Logs          []string
+	// Err is why the transfer failed, nil when it succeeded.
+	Err error
+}
+
+// SimulateSend runs the transfer of quote on the cluster without sending it, for --dry-run. It builds
+// the same instructions ExecuteSend signs, but is not signed itself, so it needs no private key: the
+// node checks neither signatures nor the blockhash, which it replaces with its latest. A transfer the
+// cluster rejects is returned along with ErrSimulationFailed.
+func (w *WalletConfig) SimulateSend(ctx context.Context, quote *SendQuote, opts SendOptions) (*SendSimulation, error) {
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	var nonceAccount solana.PublicKey
+	if opts.NonceAccount != "" {
+		if nonceAccount, err = solana.PublicKeyFromBase58(opts.NonceAccount); err != nil {
+			return nil, fmt.Errorf("invalid nonce account %q: %w", opts.NonceAccount, err)
+		}
+	}
+	extras := transferExtras{Memo: quote.Memo, ComputeUnitPrice: quote.ComputeUnitPrice}
+	tx, err := solana.NewTransaction(transferInstructions(quote.From, quote.To, quote.Lamports, nonceAccount, extras), solana.Hash{}, solana.TransactionPayer(quote.From))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build transaction: %w", err)
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+
+	accounts := []solana.PublicKey{quote.From, quote.To}
+	if !nonceAccount.IsZero() {
+		accounts = append(accounts, nonceAccount)
+	}
+	simulation := &SendSimulation{Changes: make([]BalanceChange, len(accounts))}
+	for i, account := range accounts {
+		balance, err := client.GetBalance(ctx, account, rpc.CommitmentConfirmed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch balance of %s: %w", account, err)
+		}
+		simulation.Changes[i] = BalanceChange{Account: account, Before: balance.Value, After: balance.Value}
+	}
+
+	result, err := client.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		Commitment:             rpc.CommitmentConfirmed,
+		ReplaceRecentBlockhash: true,
+		Accounts:               &rpc.SimulateTransactionAccountsOpts{Encoding: solana.EncodingBase64, Addresses: accounts},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+	if result == nil || result.Value == nil {
+		return nil, errors.New("failed to simulate transaction: the node returned no result")
+	}
+
+	simulation.Logs = result.Value.Logs
+	if result.Value.UnitsConsumed != nil {
+		simulation.UnitsConsumed = *result.Value.UnitsConsumed
+	}
+	if result.Value.Err != nil {
+		// A failed transfer changes no balance, and the node reports no accounts for it.
+		simulation.Err = fmt.Errorf("%w: %v", ErrSimulationFailed, result.Value.Err)
+		return simulation, simulation.Err
+	}
+	// An account missing after the transfer was emptied by it.
+	for i, account := range result.Value.Accounts {
+		if i >= len(simulation.Changes) {
+			break
+		}
+		simulation.Changes[i].After = 0
+		if account != nil {
+			simulation.Changes[i].After = account.Lamports
+		}
+	}
+	return simulation, nil
+}
@@ -0,0 +1,98 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulateSend(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	from, to := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	balances := map[solana.PublicKey]uint64{from: 2_000_000_000, to: 0}
+	units := uint64(450)
+
+	tests := []struct {
+		name     string
+		quote    SendQuote
+		failure  interface{}
+		expected []BalanceChange
+	}{
+		{
+			name:  "Transfer",
+			quote: SendQuote{From: from, To: to, Lamports: 500_000_000, Fee: 5000},
+			expected: []BalanceChange{
+				{Account: from, Before: 2_000_000_000, After: 1_499_995_000},
+				{Account: to, Before: 0, After: 500_000_000},
+			},
+		},
+		{
+			name:  "Sweep Empties The Sender",
+			quote: SendQuote{From: from, To: to, Lamports: 1_999_995_000, Fee: 5000},
+			expected: []BalanceChange{
+				{Account: from, Before: 2_000_000_000, After: 0},
+				{Account: to, Before: 0, After: 1_999_995_000},
+			},
+		},
+		{
+			name:    "Failure",
+			quote:   SendQuote{From: from, To: to, Lamports: 500_000_000, Fee: 5000, Memo: "invoice 7"},
+			failure: map[string]interface{}{"InstructionError": []interface{}{0, "Custom"}},
+			expected: []BalanceChange{
+				{Account: from, Before: 2_000_000_000, After: 2_000_000_000},
+				{Account: to, Before: 0, After: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newRPCClient = func(string) ClientInterface {
+				return &MockClientInterface{
+					GetBalanceFn: func(_ context.Context, account solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+						return &rpc.GetBalanceResult{Value: balances[account]}, nil
+					},
+					SimulateTransactionWithOptsFn: func(_ context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+						// The transfer is simulated unsigned, against the node's latest blockhash.
+						assert.Len(t, tx.Signatures, 1)
+						assert.Equal(t, solana.Signature{}, tx.Signatures[0])
+						assert.True(t, opts.ReplaceRecentBlockhash)
+						assert.False(t, opts.SigVerify)
+						assert.Equal(t, []solana.PublicKey{from, to}, opts.Accounts.Addresses)
+						assert.Len(t, tx.Message.Instructions, map[bool]int{true: 2, false: 1}[tt.quote.Memo != ""])
+
+						value := &rpc.SimulateTransactionResult{Err: tt.failure, Logs: []string{"Program 11111111111111111111111111111111 invoke [1]"}, UnitsConsumed: &units}
+						if tt.failure == nil {
+							for _, change := range tt.expected {
+								if change.After == 0 {
+									value.Accounts = append(value.Accounts, nil)
+								} else {
+									value.Accounts = append(value.Accounts, &rpc.Account{Lamports: change.After})
+								}
+							}
+						}
+						return &rpc.SimulateTransactionResponse{Value: value}, nil
+					},
+				}
+			}
+
+			wc := &WalletConfig{Network: Devnet}
+			quote := tt.quote
+			simulation, err := wc.SimulateSend(context.Background(), &quote, SendOptions{})
+			if tt.failure != nil {
+				assert.ErrorIs(t, err, ErrSimulationFailed)
+				assert.ErrorIs(t, simulation.Err, ErrSimulationFailed)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, simulation.Err)
+			}
+			assert.Equal(t, tt.expected, simulation.Changes)
+			assert.Equal(t, units, simulation.UnitsConsumed)
+			assert.Len(t, simulation.Logs, 1)
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package wallet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// SnapshotSchemaVersion is the version of the Snapshot document. It changes whenever a field is
+// renamed, removed or changes meaning, so tools diffing snapshots can tell the formats apart.
+const SnapshotSchemaVersion = 1
+
+// snapshotConcurrency bounds how many wallets are fetched at once.
+const snapshotConcurrency = 4
+
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// Snapshot is the state of every stored wallet at one moment, for external audits. It never holds a
+// private key. ContentHash is the SHA-256 of the document encoded with an empty ContentHash, so a
+// copy can be checked with VerifySnapshot.
+type Snapshot struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	CreatedAt     time.Time            `json:"createdAt"`
+	Config        SnapshotConfig       `json:"config"`
+	Rate          *SnapshotRate        `json:"rate,omitempty"`
+	RateError     string               `json:"rateError,omitempty"`
+	Wallets       []WalletSnapshot     `json:"wallets"`
+	Pending       []PendingTransaction `json:"pending"`
+	Counters      SnapshotCounters     `json:"counters"`
+	ContentHash   string               `json:"contentHash"`
+}
+
+// SnapshotConfig summarises the settings the snapshot was taken with.
+type SnapshotConfig struct {
+	Profile     string `json:"profile"`
+	Network     string `json:"network"`
+	RPCURL      string `json:"rpcUrl,omitempty"`
+	Currency    string `json:"currency"`
+	ActiveAlias string `json:"activeAlias"`
+	// SpendLimit is the largest single transfer allowed in lamports, 0 for no limit.
+	SpendLimit uint64 `json:"spendLimit"`
+}
+
+// SnapshotRate is the exchange rate the fiat values in a snapshot were computed with.
+type SnapshotRate struct {
+	Currency  string          `json:"currency"`
+	Rate      decimal.Decimal `json:"rate"`
+	Source    string          `json:"source"`
+	FetchedAt time.Time       `json:"fetchedAt"`
+}
+
+// WalletSnapshot is one stored wallet in a Snapshot. Balance and Tokens are missing when they could
+// not be fetched; Error says why.
+type WalletSnapshot struct {
+	Alias      string           `json:"alias"`
+	PublicKey  string           `json:"publicKey"`
+	Network    string           `json:"network"`
+	Active     bool             `json:"active"`
+	WatchOnly  bool             `json:"watchOnly"`
+	Encrypted  bool             `json:"encrypted"`
+	Derivation string           `json:"derivation"`
+	Usage      KeyUsage         `json:"usage"`
+	Balance    *BalanceSnapshot `json:"balance,omitempty"`
+	Tokens     []TokenSnapshot  `json:"tokens,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// BalanceSnapshot is the SOL balance of a wallet and its value in the snapshot's currency.
+type BalanceSnapshot struct {
+	Lamports  uint64           `json:"lamports"`
+	SOL       decimal.Decimal  `json:"sol"`
+	Fiat      *decimal.Decimal `json:"fiat,omitempty"`
+	FetchedAt time.Time        `json:"fetchedAt"`
+}
+
+// TokenSnapshot is the balance of one SPL token account.
+type TokenSnapshot struct {
+	Mint    string          `json:"mint"`
+	Symbol  string          `json:"symbol,omitempty"`
+	Account string          `json:"account"`
+	Amount  decimal.Decimal `json:"amount"`
+}
+
+// SnapshotCounters totals a Snapshot so audits can spot changes at a glance.
+type SnapshotCounters struct {
+	Wallets        int              `json:"wallets"`
+	WatchOnly      int              `json:"watchOnly"`
+	Encrypted      int              `json:"encrypted"`
+	FetchErrors    int              `json:"fetchErrors"`
+	TotalLamports  uint64           `json:"totalLamports"`
+	SendsSigned    uint64           `json:"sendsSigned"`
+	MessagesSigned uint64           `json:"messagesSigned"`
+	Pending        map[string]int   `json:"pending"`
+	TotalFiat      *decimal.Decimal `json:"totalFiat,omitempty"`
+}
+
+// Snapshot fetches the balances and token accounts of every stored wallet concurrently and
+// describes them together with the pending transactions and settings. A wallet that cannot be
+// fetched is reported in its Error field instead of failing the snapshot; only reading the key file
+// or the pending transactions fails it. now timestamps the snapshot and every fetch.
+func (w *WalletConfig) Snapshot(ctx context.Context, now func() time.Time) (*Snapshot, error) {
+	infos, err := w.ListWallets()
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := w.ListPending()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending transactions: %w", err)
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	snapshot := &Snapshot{
+		SchemaVersion: SnapshotSchemaVersion,
+		CreatedAt:     now().UTC(),
+		Config: SnapshotConfig{
+			Profile:    w.Profile,
+			Network:    string(w.Network),
+			RPCURL:     w.RPCURL,
+			Currency:   string(w.FiatCurrency()),
+			SpendLimit: w.Policy.MaxLamports,
+		},
+		Wallets: make([]WalletSnapshot, len(infos)),
+		Pending: pending,
+	}
+	if snapshot.Pending == nil {
+		snapshot.Pending = []PendingTransaction{}
+	}
+
+	var rate *decimal.Decimal
+	if value, err := w.fetchRate(w.FiatCurrency()); err != nil {
+		snapshot.RateError = err.Error()
+	} else {
+		rate = &value
+		snapshot.Rate = &SnapshotRate{
+			Currency:  string(w.FiatCurrency()),
+			Rate:      value,
+			Source:    ratesOrDefault(w.Rates).Name(),
+			FetchedAt: now().UTC(),
+		}
+	}
+
+	limiter := newAdaptiveLimiter(snapshotConcurrency)
+	var wg sync.WaitGroup
+	for i, info := range infos {
+		if info.Active {
+			snapshot.Config.ActiveAlias = info.Alias
+		}
+
+		if err := limiter.Acquire(ctx); err != nil {
+			return nil, fmt.Errorf("failed to acquire request slot: %w", err)
+		}
+
+		i, info := i, info // pin
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer limiter.Release()
+			snapshot.Wallets[i] = snapshotWallet(ctx, client, info, string(w.Network), rate, now)
+		}()
+	}
+	wg.Wait()
+
+	snapshot.Counters = countSnapshot(snapshot.Wallets, pending, rate != nil)
+
+	snapshot.ContentHash, err = snapshot.hash()
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// snapshotWallet fetches the balance and token accounts of one wallet.
+func snapshotWallet(ctx context.Context, client ClientInterface, info WalletInfo, network string, rate *decimal.Decimal, now func() time.Time) WalletSnapshot {
+	ws := WalletSnapshot{
+		Alias:      info.Alias,
+		PublicKey:  info.PublicKey,
+		Network:    network,
+		Active:     info.Active,
+		WatchOnly:  info.WatchOnly,
+		Encrypted:  info.Encrypted,
+		Derivation: info.Derivation.String(),
+		Usage:      info.Usage,
+	}
+
+	owner, err := solana.PublicKeyFromBase58(info.PublicKey)
+	if err != nil {
+		ws.Error = fmt.Sprintf("invalid public key: %v", err)
+		return ws
+	}
+
+	var balance *rpc.GetBalanceResult
+	err = withRetry(ctx, DefaultRetryPolicy, nil, func(ctx context.Context) error {
+		balance, err = client.GetBalance(ctx, owner, rpc.CommitmentFinalized)
+		return err
+	})
+	if err != nil {
+		ws.Error = fmt.Sprintf("failed to fetch balance: %v", err)
+		return ws
+	}
+
+	sol := LamportsToSOL(balance.Value)
+	ws.Balance = &BalanceSnapshot{Lamports: balance.Value, SOL: sol, FetchedAt: now().UTC()}
+	if rate != nil {
+		fiat := sol.Mul(*rate).Round(2)
+		ws.Balance.Fiat = &fiat
+	}
+
+	tokens, err := tokenBalances(ctx, client, owner)
+	if err != nil {
+		ws.Error = err.Error()
+		return ws
+	}
+	ws.Tokens = make([]TokenSnapshot, 0, len(tokens))
+	for _, token := range tokens {
+		ws.Tokens = append(ws.Tokens, TokenSnapshot{Mint: token.Mint, Symbol: token.Symbol, Account: token.Account, Amount: token.Amount()})
+	}
+
+	return ws
+}
+
+// countSnapshot totals the wallets and pending transactions of a snapshot.
+func countSnapshot(wallets []WalletSnapshot, pending []PendingTransaction, withFiat bool) SnapshotCounters {
+	counters := SnapshotCounters{Wallets: len(wallets), Pending: make(map[string]int)}
+	totalFiat := decimal.Zero
+
+	for _, ws := range wallets {
+		if ws.WatchOnly {
+			counters.WatchOnly++
+		}
+		if ws.Encrypted {
+			counters.Encrypted++
+		}
+		if ws.Error != "" {
+			counters.FetchErrors++
+		}
+		counters.SendsSigned += ws.Usage.SendsSigned
+		counters.MessagesSigned += ws.Usage.MessagesSigned
+		if ws.Balance != nil {
+			counters.TotalLamports += ws.Balance.Lamports
+			if ws.Balance.Fiat != nil {
+				totalFiat = totalFiat.Add(*ws.Balance.Fiat)
+			}
+		}
+	}
+
+	for _, tx := range pending {
+		counters.Pending[string(tx.Status)]++
+	}
+
+	if withFiat {
+		counters.TotalFiat = &totalFiat
+	}
+	return counters
+}
+
+// Marshal returns the snapshot as an indented JSON document.
+func (s *Snapshot) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling JSON: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// hash returns the SHA-256 of the snapshot encoded with an empty ContentHash.
+func (s *Snapshot) hash() (string, error) {
+	unhashed := *s
+	unhashed.ContentHash = ""
+
+	data, err := json.Marshal(unhashed)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling JSON: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// VerifySnapshot decodes a snapshot document and checks its schema version and content hash, so an
+// auditor can tell whether a file was edited after it was written.
+func VerifySnapshot(data []byte) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+
+	if snapshot.SchemaVersion != SnapshotSchemaVersion {
+		return nil, fmt.Errorf("%w: unsupported schema version %d, expected %d", ErrInvalidSnapshot, snapshot.SchemaVersion, SnapshotSchemaVersion)
+	}
+
+	hash, err := snapshot.hash()
+	if err != nil {
+		return nil, err
+	}
+	if hash != snapshot.ContentHash {
+		return nil, fmt.Errorf("%w: content hash %s does not match the content (%s)", ErrInvalidSnapshot, snapshot.ContentHash, hash)
+	}
+
+	return &snapshot, nil
+}
@@ -0,0 +1,159 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// snapshotFixture returns a wallet config with three stored wallets, one of them watch-only and one
+// whose balance cannot be fetched, a pending transfer and fake RPC and rate providers.
+func snapshotFixture(t *testing.T) *WalletConfig {
+	previous := newRPCClient
+	t.Cleanup(func() { newRPCClient = previous })
+
+	keys := make([]solana.PublicKey, 3)
+	for i := range keys {
+		seed := make([]byte, ed25519.SeedSize)
+		seed[0] = byte(i + 1)
+		keys[i] = solana.PublicKeyFromBytes(ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey))
+	}
+	main, ledger, broken := keys[0], keys[1], keys[2]
+	tokenAccount := solana.MustPublicKeyFromBase58("9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM")
+	lastUsed := time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC)
+
+	files := newMemFiles()
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		ActiveAlias: "main",
+		Wallets: map[string]Wallet{
+			"main": {
+				PrivateKey: "secret-that-must-not-appear",
+				PublicKey:  main.String(),
+				Derivation: &KeyDerivation{Path: DerivationNone},
+				Usage:      &KeyUsage{SendsSigned: 3, LastSignature: "5sig", LastUsed: lastUsed},
+			},
+			"ledger-main": {PublicKey: ledger.String(), WatchOnly: true, Derivation: &KeyDerivation{Path: DerivationNone}},
+			"broken":      {PrivateKey: "another-secret", PublicKey: broken.String(), Encrypted: true},
+		},
+	})))
+
+	pending := &PendingStore{FileReader: files, FileWriter: files}
+	assert.NoError(t, pending.Put(PendingTransaction{
+		ID:        "abc123",
+		Signature: "5sig",
+		From:      main.String(),
+		To:        ledger.String(),
+		Lamports:  250_000_000,
+		Network:   string(Devnet),
+		Status:    PendingConfirmed,
+		CreatedAt: time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC),
+	}))
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(_ context.Context, account solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				switch account {
+				case main:
+					return &rpc.GetBalanceResult{Value: 1_500_000_000}, nil
+				case ledger:
+					return &rpc.GetBalanceResult{Value: 20_000_000_000}, nil
+				}
+				return nil, errors.New("node unreachable")
+			},
+			GetTokenAccountsByOwnerFn: func(_ context.Context, owner solana.PublicKey, _ *rpc.GetTokenAccountsConfig, _ *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+				if owner != main {
+					return &rpc.GetTokenAccountsResult{}, nil
+				}
+				return &rpc.GetTokenAccountsResult{Value: []*rpc.TokenAccount{{
+					Pubkey:  tokenAccount,
+					Account: rpc.Account{Data: encodeTokenAccount(t, usdcMint, owner, 12_500_000)},
+				}}}, nil
+			},
+		}
+	}
+
+	return &WalletConfig{
+		KeyOps:   &KeyOps{FileReader: files, FileWriter: files},
+		Pending:  pending,
+		Network:  Devnet,
+		Profile:  DefaultProfile,
+		Policy:   SpendPolicy{MaxLamports: 5_000_000_000},
+		Currency: Currency("USD"),
+		Rates:    StaticRate(decimal.RequireFromString("142.5")),
+	}
+}
+
+func TestSnapshotGolden(t *testing.T) {
+	defer func(policy RetryPolicy) { DefaultRetryPolicy = policy }(DefaultRetryPolicy)
+	DefaultRetryPolicy = fastRetries
+
+	wc := snapshotFixture(t)
+	now := func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	snapshot, err := wc.Snapshot(context.Background(), now)
+	assert.NoError(t, err)
+	got, err := snapshot.Marshal()
+	assert.NoError(t, err)
+
+	golden := filepath.Join("testdata", "snapshot.golden.json")
+	if *updateGolden {
+		assert.NoError(t, os.WriteFile(golden, got, 0644))
+	}
+	want, err := os.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+
+	assert.NotContains(t, string(got), "secret")
+	assert.Equal(t, 1, snapshot.Counters.FetchErrors)
+	assert.Equal(t, uint64(21_500_000_000), snapshot.Counters.TotalLamports)
+}
+
+func TestVerifySnapshot(t *testing.T) {
+	valid, err := os.ReadFile(filepath.Join("testdata", "snapshot.golden.json"))
+	assert.NoError(t, err)
+
+	snapshot, err := VerifySnapshot(valid)
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Wallets, 3)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{name: "Edited Balance", data: []byte(replaceOnce(t, string(valid), `"lamports": 1500000000`, `"lamports": 1600000000`)), expected: "does not match the content"},
+		{name: "Unknown Schema", data: []byte(replaceOnce(t, string(valid), `"schemaVersion": 1`, `"schemaVersion": 2`)), expected: "unsupported schema version 2"},
+		{name: "Not JSON", data: []byte("snapshot"), expected: "invalid snapshot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifySnapshot(tt.data)
+			assert.ErrorIs(t, err, ErrInvalidSnapshot)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.expected)
+			}
+		})
+	}
+}
+
+// replaceOnce replaces old in s, failing the test when it does not occur.
+func replaceOnce(t *testing.T, s, old, new string) string {
+	if !strings.Contains(s, old) {
+		t.Fatalf("%q not found", old)
+	}
+	return strings.Replace(s, old, new, 1)
+}
@@ -12,12 +12,52 @@ const LamportsInOneSol = 1000000000 // Lamports in one SOL
 
 type ClientInterface interface {
 	GetBalance(ctx context.Context, publicKey solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error)
+	GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error)
+	GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, transactionSignatures ...solana.Signature) (*rpc.GetSignatureStatusesResult, error)
+	SendTransaction(ctx context.Context, transaction *solana.Transaction) (solana.Signature, error)
+	SendTransactionWithOpts(ctx context.Context, transaction *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error)
+	GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+	GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error)
+	GetFeeForMessage(ctx context.Context, message string, commitment rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error)
+	GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error)
+	GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error)
+	GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+	GetTransaction(ctx context.Context, txSig solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+	GetBlockTime(ctx context.Context, block uint64) (*solana.UnixTimeSeconds, error)
+	RequestAirdrop(ctx context.Context, account solana.PublicKey, lamports uint64, commitment rpc.CommitmentType) (solana.Signature, error)
+	GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error)
+	GetGenesisHash(ctx context.Context) (solana.Hash, error)
+	GetHealth(ctx context.Context) (string, error)
+	GetProgramAccountsWithOpts(ctx context.Context, publicKey solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error)
+	GetEpochInfo(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetEpochInfoResult, error)
+	GetInflationReward(ctx context.Context, addresses []solana.PublicKey, opts *rpc.GetInflationRewardOpts) ([]*rpc.GetInflationRewardResult, error)
+	SimulateTransactionWithOpts(ctx context.Context, transaction *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error)
 }
 
-var rpcClient ClientInterface = rpc.New(rpc.DevNet_RPC) // Create a global RPC client (makes my life easier when testing)
+// newRPCClient creates the RPC client for an endpoint (a package variable so tests can swap it out).
+// Clients share the node tracking configured with SetNodeOptions.
+var newRPCClient = func(endpoint string) ClientInterface {
+	return currentNodeSession().client(endpoint)
+}
 
 // fetchSolBalance fetches the SOL balance of a given wallet.
 func (w *WalletConfig) fetchSolBalance(alias string, keyStore KeyStore) (decimal.Decimal, error) {
+	lamports, err := w.fetchLamportBalance(context.TODO(), alias, keyStore)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	// Convert lamports to SOL
+	return decimal.NewFromInt(int64(lamports)).Div(decimal.NewFromInt(LamportsInOneSol)), nil
+}
+
+// GetLamportBalance returns the balance of a stored wallet in lamports. An empty alias means the active wallet.
+func (w *WalletConfig) GetLamportBalance(ctx context.Context, alias string) (uint64, error) {
+	return w.fetchLamportBalance(ctx, alias, w.KeyOps)
+}
+
+// fetchLamportBalance fetches the balance of a given wallet in lamports.
+func (w *WalletConfig) fetchLamportBalance(ctx context.Context, alias string, keyStore KeyStore) (uint64, error) {
 	var publicKey solana.PublicKey
 	var err error
 
@@ -30,45 +70,44 @@ func (w *WalletConfig) fetchSolBalance(alias string, keyStore KeyStore) (decimal
 	}
 
 	if err != nil {
-		return decimal.Decimal{}, fmt.Errorf("failed to fetch public key: %w", err)
+		return 0, fmt.Errorf("failed to fetch public key: %w", err)
 	}
 
-	balanceResp, err := rpcClient.GetBalance(context.TODO(), publicKey, rpc.CommitmentFinalized)
+	endpoints, err := w.Endpoints()
 	if err != nil {
-		return decimal.Decimal{}, fmt.Errorf("failed to fetch balance: %w", err)
+		return 0, err
 	}
 
-	lamportValue := decimal.NewFromInt(int64(balanceResp.Value))
-	fin := lamportValue.Div(decimal.NewFromInt(LamportsInOneSol))
-	// Convert lamports to SOL
-	return fin, nil
+	client := endpoints.client()
+	var balanceResp *rpc.GetBalanceResult
+	err = withRetry(ctx, DefaultRetryPolicy, nil, func(ctx context.Context) error {
+		balanceResp, err = client.GetBalance(ctx, publicKey, rpc.CommitmentFinalized)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch balance: %w", err)
+	}
+
+	return balanceResp.Value, nil
 }
 
-// fetchPublicKeyByAlias fetches the public key by alias from the key store.
+// fetchPublicKeyByAlias fetches the public key by alias from the key store. It works for watch-only
+// wallets and never decrypts a private key.
 func fetchPublicKeyByAlias(alias string, keyStore KeyStore) (solana.PublicKey, error) {
-	privateKey, err := keyStore.GetPrivateKeyByAlias(alias)
+	publicKey, err := keyStore.GetPublicKeyByAlias(alias)
 	if err != nil {
 		return solana.PublicKey{}, err
 	}
 
-	return fetchPublicKeyFromPrivateKey(privateKey)
+	return solana.PublicKeyFromBase58(publicKey)
 }
 
 // fetchCurrentPublicKey fetches the current public key from the key store.
 func fetchCurrentPublicKey(keyStore KeyStore) (solana.PublicKey, error) {
-	privateKey, err := keyStore.GetCurrentPrivateKey()
+	publicKey, err := keyStore.GetCurrentPublicKey()
 	if err != nil {
 		return solana.PublicKey{}, err
 	}
 
-	return fetchPublicKeyFromPrivateKey(privateKey)
-}
-
-// fetchPublicKeyFromPrivateKey fetches the public key given a private key.
-func fetchPublicKeyFromPrivateKey(privateKey string) (solana.PublicKey, error) {
-	account, err := solana.WalletFromPrivateKeyBase58(privateKey)
-	if err != nil {
-		return solana.PublicKey{}, err
-	}
-	return account.PublicKey(), nil
+	return solana.PublicKeyFromBase58(publicKey)
 }
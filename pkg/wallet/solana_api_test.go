@@ -13,28 +13,131 @@ import (
 )
 
 type MockClientInterface struct {
-	GetBalanceFn func(ctx context.Context, publicKey solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error)
+	GetBalanceFn                        func(ctx context.Context, publicKey solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error)
+	GetAccountInfoFn                    func(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error)
+	GetSignatureStatusesFn              func(ctx context.Context, searchTransactionHistory bool, transactionSignatures ...solana.Signature) (*rpc.GetSignatureStatusesResult, error)
+	SendTransactionFn                   func(ctx context.Context, transaction *solana.Transaction) (solana.Signature, error)
+	SendTransactionWithOptsFn           func(ctx context.Context, transaction *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error)
+	GetBlockHeightFn                    func(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+	GetLatestBlockhashFn                func(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error)
+	GetFeeForMessageFn                  func(ctx context.Context, message string, commitment rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error)
+	GetTokenAccountsByOwnerFn           func(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error)
+	GetMinimumBalanceForRentExemptionFn func(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error)
+	GetSignaturesForAddressWithOptsFn   func(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+	GetTransactionFn                    func(ctx context.Context, txSig solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+	GetBlockTimeFn                      func(ctx context.Context, block uint64) (*solana.UnixTimeSeconds, error)
+	RequestAirdropFn                    func(ctx context.Context, account solana.PublicKey, lamports uint64, commitment rpc.CommitmentType) (solana.Signature, error)
+	GetRecentPrioritizationFeesFn       func(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error)
+	GetGenesisHashFn                    func(ctx context.Context) (solana.Hash, error)
+	GetHealthFn                         func(ctx context.Context) (string, error)
+	GetProgramAccountsWithOptsFn        func(ctx context.Context, publicKey solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error)
+	GetEpochInfoFn                      func(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetEpochInfoResult, error)
+	GetInflationRewardFn                func(ctx context.Context, addresses []solana.PublicKey, opts *rpc.GetInflationRewardOpts) ([]*rpc.GetInflationRewardResult, error)
+	SimulateTransactionWithOptsFn       func(ctx context.Context, transaction *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error)
+	ClientInterface
 }
 
 func (m *MockClientInterface) GetBalance(ctx context.Context, publicKey solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
 	return m.GetBalanceFn(ctx, publicKey, commitment)
 }
 
+func (m *MockClientInterface) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	return m.GetAccountInfoFn(ctx, account)
+}
+
+func (m *MockClientInterface) GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, transactionSignatures ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+	return m.GetSignatureStatusesFn(ctx, searchTransactionHistory, transactionSignatures...)
+}
+
+func (m *MockClientInterface) SendTransaction(ctx context.Context, transaction *solana.Transaction) (solana.Signature, error) {
+	return m.SendTransactionFn(ctx, transaction)
+}
+
+func (m *MockClientInterface) SendTransactionWithOpts(ctx context.Context, transaction *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	return m.SendTransactionWithOptsFn(ctx, transaction, opts)
+}
+
+func (m *MockClientInterface) GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	return m.GetBlockHeightFn(ctx, commitment)
+}
+
+func (m *MockClientInterface) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	return m.GetLatestBlockhashFn(ctx, commitment)
+}
+
+func (m *MockClientInterface) GetFeeForMessage(ctx context.Context, message string, commitment rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+	return m.GetFeeForMessageFn(ctx, message, commitment)
+}
+
+func (m *MockClientInterface) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+	return m.GetTokenAccountsByOwnerFn(ctx, owner, conf, opts)
+}
+
+func (m *MockClientInterface) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error) {
+	return m.GetMinimumBalanceForRentExemptionFn(ctx, dataSize, commitment)
+}
+
+func (m *MockClientInterface) GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+	return m.GetSignaturesForAddressWithOptsFn(ctx, account, opts)
+}
+
+func (m *MockClientInterface) GetTransaction(ctx context.Context, txSig solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+	return m.GetTransactionFn(ctx, txSig, opts)
+}
+
+func (m *MockClientInterface) GetBlockTime(ctx context.Context, block uint64) (*solana.UnixTimeSeconds, error) {
+	return m.GetBlockTimeFn(ctx, block)
+}
+
+func (m *MockClientInterface) RequestAirdrop(ctx context.Context, account solana.PublicKey, lamports uint64, commitment rpc.CommitmentType) (solana.Signature, error) {
+	return m.RequestAirdropFn(ctx, account, lamports, commitment)
+}
+
+func (m *MockClientInterface) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	return m.GetRecentPrioritizationFeesFn(ctx, accounts)
+}
+
+func (m *MockClientInterface) GetGenesisHash(ctx context.Context) (solana.Hash, error) {
+	return m.GetGenesisHashFn(ctx)
+}
+
+func (m *MockClientInterface) GetHealth(ctx context.Context) (string, error) {
+	return m.GetHealthFn(ctx)
+}
+
+func (m *MockClientInterface) GetProgramAccountsWithOpts(ctx context.Context, publicKey solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	return m.GetProgramAccountsWithOptsFn(ctx, publicKey, opts)
+}
+
+func (m *MockClientInterface) GetEpochInfo(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetEpochInfoResult, error) {
+	return m.GetEpochInfoFn(ctx, commitment)
+}
+
+func (m *MockClientInterface) GetInflationReward(ctx context.Context, addresses []solana.PublicKey, opts *rpc.GetInflationRewardOpts) ([]*rpc.GetInflationRewardResult, error) {
+	return m.GetInflationRewardFn(ctx, addresses, opts)
+}
+
+func (m *MockClientInterface) SimulateTransactionWithOpts(ctx context.Context, transaction *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+	return m.SimulateTransactionWithOptsFn(ctx, transaction, opts)
+}
+
 type MockKeyStore struct {
-	GetCurrentPrivateKeyFn func() (string, error)
-	GetPrivateKeyByAliasFn func(string) (string, error)
+	GetCurrentPublicKeyFn func() (string, error)
+	GetPublicKeyByAliasFn func(string) (string, error)
 	KeyStore
 }
 
-func (m *MockKeyStore) GetCurrentPrivateKey() (string, error) {
-	return m.GetCurrentPrivateKeyFn()
+func (m *MockKeyStore) GetCurrentPublicKey() (string, error) {
+	return m.GetCurrentPublicKeyFn()
 }
 
-func (m *MockKeyStore) GetPrivateKeyByAlias(alias string) (string, error) {
-	return m.GetPrivateKeyByAliasFn(alias)
+func (m *MockKeyStore) GetPublicKeyByAlias(alias string) (string, error) {
+	return m.GetPublicKeyByAliasFn(alias)
 }
 
 func TestFetchSolBalance(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
 	mockWallet := solana.NewWallet()
 
 	tests := []struct {
@@ -108,14 +211,14 @@ func TestFetchSolBalance(t *testing.T) {
 			expectedValue: decimal.NewFromInt(0),
 		},
 		{
-			name: "Failure due to GetCurrentPrivateKey error",
+			name: "Failure due to GetCurrentPublicKey error",
 			walletConfig: &WalletConfig{
 				Wallet: nil,
 			},
 			alias:         "",
 			mockResponse:  nil,
 			mockError:     nil,
-			expectedError: "failed to fetch public key: GetCurrentPrivateKey error",
+			expectedError: "failed to fetch public key: GetCurrentPublicKey error",
 			expectedValue: decimal.NewFromInt(0),
 		},
 	}
@@ -128,19 +231,19 @@ func TestFetchSolBalance(t *testing.T) {
 					return tt.mockResponse, tt.mockError
 				},
 			}
-			rpcClient = mockClient
+			newRPCClient = func(string) ClientInterface { return mockClient }
 
 			// Mock KeyStore
 			mockKeyStore := &MockKeyStore{
-				GetCurrentPrivateKeyFn: func() (string, error) {
-					if tt.name == "Failure due to GetCurrentPrivateKey error" {
-						return "", errors.New("GetCurrentPrivateKey error")
+				GetCurrentPublicKeyFn: func() (string, error) {
+					if tt.name == "Failure due to GetCurrentPublicKey error" {
+						return "", errors.New("GetCurrentPublicKey error")
 					}
-					return "23YcmrXnN9C74zNP6pzkqfCqQKVTNk93rGu8C5fVyw4KPsXeQgqtC7YTPkx1vZJrg6mqYuEUgAFdoxXiU2UrBPZe", nil
+					return mockWallet.PublicKey().String(), nil
 				},
-				GetPrivateKeyByAliasFn: func(alias string) (string, error) {
+				GetPublicKeyByAliasFn: func(alias string) (string, error) {
 					if alias == "validAlias" {
-						return "23YcmrXnN9C74zNP6pzkqfCqQKVTNk93rGu8C5fVyw4KPsXeQgqtC7YTPkx1vZJrg6mqYuEUgAFdoxXiU2UrBPZe", nil
+						return mockWallet.PublicKey().String(), nil
 					}
 					return "", errors.New("invalid alias")
 				},
@@ -160,3 +263,57 @@ func TestFetchSolBalance(t *testing.T) {
 		})
 	}
 }
+
+func TestRemovalRisks(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	active := solana.NewWallet().PublicKey()
+	idle := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name         string
+		alias        string
+		lamports     uint64
+		balanceErr   error
+		expected     []string
+		expectAnyErr bool
+	}{
+		{name: "Empty Inactive Wallet", alias: "idle"},
+		{name: "Active Wallet", alias: "main", expected: []string{"it is the active wallet"}},
+		{name: "Funded Wallet", alias: "idle", lamports: 1_500_000_000, expected: []string{"it still holds 1.5 SOL"}},
+		{name: "Balance Unknown", alias: "main", balanceErr: errors.New("node unreachable"), expected: []string{"it is the active wallet"}, expectAnyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+				ActiveAlias: "main",
+				Wallets: map[string]Wallet{
+					"main": {PublicKey: active.String()},
+					"idle": {PublicKey: idle.String()},
+				},
+			})))
+
+			newRPCClient = func(string) ClientInterface {
+				return &MockClientInterface{
+					GetBalanceFn: func(ctx context.Context, _ solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+						if tt.balanceErr != nil {
+							return nil, tt.balanceErr
+						}
+						return &rpc.GetBalanceResult{Value: tt.lamports}, nil
+					},
+				}
+			}
+
+			wc := &WalletConfig{KeyOps: &KeyOps{FileReader: files, FileWriter: files}, Network: Devnet}
+			risks, err := wc.RemovalRisks(context.Background(), tt.alias)
+			if tt.expectAnyErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, risks)
+		})
+	}
+}
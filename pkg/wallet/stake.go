@@ -0,0 +1,293 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// stakeAccountSize is the size of a stake account, which determines its rent-exempt reserve.
+const stakeAccountSize = 200
+
+// StakeConfigID is the stake config account the delegate instruction still expects.
+var StakeConfigID = solana.MustPublicKeyFromBase58("StakeConfig11111111111111111111111111111111")
+
+var ErrNotVoteAccount = errors.New("not a vote account")
+
+// Stake program instructions, numbered as the program numbers them.
+const (
+	stakeInitialize    uint32 = 0
+	stakeDelegateStake uint32 = 2
+	stakeWithdraw      uint32 = 4
+	stakeDeactivate    uint32 = 5
+)
+
+// newStakeInstruction builds an instruction of the stake program: the instruction number followed by
+// its arguments, little endian.
+func newStakeInstruction(kind uint32, accounts solana.AccountMetaSlice, args ...interface{}) solana.Instruction {
+	var data bytes.Buffer
+	_ = binary.Write(&data, binary.LittleEndian, kind)
+	for _, arg := range args {
+		_ = binary.Write(&data, binary.LittleEndian, arg)
+	}
+	return solana.NewInstruction(solana.StakeProgramID, accounts, data.Bytes())
+}
+
+// stakeInitializeInstruction initializes stakeAccount with authority as both its staker and withdrawer
+// and no lockup.
+func stakeInitializeInstruction(stakeAccount, authority solana.PublicKey) solana.Instruction {
+	// The lockup is a unix timestamp, an epoch and a custodian, all zero.
+	var lockup struct {
+		UnixTimestamp int64
+		Epoch         uint64
+		Custodian     solana.PublicKey
+	}
+	return newStakeInstruction(stakeInitialize, solana.AccountMetaSlice{
+		solana.Meta(stakeAccount).WRITE(),
+		solana.Meta(solana.SysVarRentPubkey),
+	}, authority, authority, lockup)
+}
+
+// stakeDelegateInstruction delegates stakeAccount to the validator voting with voteAccount.
+func stakeDelegateInstruction(stakeAccount, voteAccount, staker solana.PublicKey) solana.Instruction {
+	return newStakeInstruction(stakeDelegateStake, solana.AccountMetaSlice{
+		solana.Meta(stakeAccount).WRITE(),
+		solana.Meta(voteAccount),
+		solana.Meta(solana.SysVarClockPubkey),
+		solana.Meta(solana.SysVarStakeHistoryPubkey),
+		solana.Meta(StakeConfigID),
+		solana.Meta(staker).SIGNER(),
+	})
+}
+
+// stakeDeactivateInstruction deactivates the delegated stakeAccount, which becomes withdrawable once
+// its cooldown ends at an epoch boundary.
+func stakeDeactivateInstruction(stakeAccount, staker solana.PublicKey) solana.Instruction {
+	return newStakeInstruction(stakeDeactivate, solana.AccountMetaSlice{
+		solana.Meta(stakeAccount).WRITE(),
+		solana.Meta(solana.SysVarClockPubkey),
+		solana.Meta(staker).SIGNER(),
+	})
+}
+
+// stakeWithdrawInstruction moves lamports out of stakeAccount to recipient. Withdrawing the whole
+// balance closes the account.
+func stakeWithdrawInstruction(stakeAccount, recipient, withdrawer solana.PublicKey, lamports uint64) solana.Instruction {
+	return newStakeInstruction(stakeWithdraw, solana.AccountMetaSlice{
+		solana.Meta(stakeAccount).WRITE(),
+		solana.Meta(recipient).WRITE(),
+		solana.Meta(solana.SysVarClockPubkey),
+		solana.Meta(solana.SysVarStakeHistoryPubkey),
+		solana.Meta(withdrawer).SIGNER(),
+	}, lamports)
+}
+
+// stakeDelegationInstructions create a stake account holding lamports, paid for by owner, which
+// becomes its staker and withdrawer, and delegate it to voteAccount.
+func stakeDelegationInstructions(owner, stakeAccount, voteAccount solana.PublicKey, lamports uint64) []solana.Instruction {
+	return []solana.Instruction{
+		system.NewCreateAccountInstruction(lamports, stakeAccountSize, solana.StakeProgramID, owner, stakeAccount).Build(),
+		stakeInitializeInstruction(stakeAccount, owner),
+		stakeDelegateInstruction(stakeAccount, voteAccount, owner),
+	}
+}
+
+// StakeQuote describes a checked delegation before it is signed.
+type StakeQuote struct {
+	From solana.PublicKey
+	// Vote is the vote account of the validator the stake is delegated to.
+	Vote solana.PublicKey
+	// Lamports funds the new stake account, including its rent-exempt Reserve, which is not staked.
+	Lamports uint64
+	Reserve  uint64
+	// Fee is the estimated network fee in lamports.
+	Fee uint64
+	// Balance is the wallet's balance in lamports when the quote was made.
+	Balance uint64
+	// Rate is the SOL rate in Currency, or nil when it was not available.
+	Rate     *decimal.Decimal
+	Currency Currency
+	// Expression is the amount as it was given when it was more than a number, such as "50%".
+	Expression string
+}
+
+// SOL returns the amount moved into the stake account in SOL.
+func (q *StakeQuote) SOL() decimal.Decimal {
+	return LamportsToSOL(q.Lamports)
+}
+
+// Staked returns the lamports that are delegated, the amount less the rent-exempt reserve.
+func (q *StakeQuote) Staked() uint64 {
+	return q.Lamports - q.Reserve
+}
+
+// Spendable returns the balance left after the fee, which percentages and max are shares of.
+func (q *StakeQuote) Spendable() uint64 {
+	return spendableAfter(q.Balance, q.Fee)
+}
+
+// Fiat returns the value of the amount in Currency, or nil when no rate is available.
+func (q *StakeQuote) Fiat() *decimal.Decimal {
+	if q.Rate == nil {
+		return nil
+	}
+	fiat := q.SOL().Mul(*q.Rate).Round(2)
+	return &fiat
+}
+
+// StakeReceipt describes a completed delegation.
+type StakeReceipt struct {
+	Signature    string
+	StakeAccount string
+}
+
+// PrepareStake converts the amount the way PrepareSend does, checks that validator is a vote account
+// and that the active wallet can fund a stake account with the amount, and estimates the fee. The
+// amount includes the rent-exempt reserve of the stake account, so it has to exceed it. It returns an
+// *InsufficientFundsError when the balance is too low and an error wrapping ErrSpendLimitExceeded when
+// w.Policy does not allow the amount.
+func (w *WalletConfig) PrepareStake(ctx context.Context, amount, validator string, unit Unit) (*StakeQuote, error) {
+	vote, err := solana.PublicKeyFromBase58(validator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vote account address %q: %w", validator, err)
+	}
+
+	expr, err := ParseAmountExpression(amount, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := w.currentPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkCanSign(); err != nil {
+		return nil, err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	var balance rpc.GetBalanceResult
+	var recent rpc.GetLatestBlockhashResult
+	var reserve uint64
+	var voteInfo rpc.GetAccountInfoResult
+	balanceCall := getBalanceCall(&balance, from, rpc.CommitmentConfirmed)
+	blockhashCall := getLatestBlockhashCall(&recent, rpc.CommitmentFinalized)
+	reserveCall := getRentCall(&reserve, stakeAccountSize, rpc.CommitmentConfirmed)
+	voteCall := getAccountInfoCall(&voteInfo, vote)
+	batchRead(ctx, client, balanceCall, blockhashCall, reserveCall, voteCall)
+	if balanceCall.err != nil {
+		return nil, fmt.Errorf("failed to fetch balance: %w", balanceCall.err)
+	}
+	if reserveCall.err != nil {
+		return nil, fmt.Errorf("failed to fetch rent for the stake account: %w", reserveCall.err)
+	}
+	if errors.Is(voteCall.err, rpc.ErrNotFound) || (voteCall.err == nil && !voteInfo.Value.Owner.Equals(solana.VoteProgramID)) {
+		return nil, fmt.Errorf("%s: %w", vote, ErrNotVoteAccount)
+	}
+	if voteCall.err != nil {
+		return nil, fmt.Errorf("failed to fetch vote account: %w", voteCall.err)
+	}
+
+	fee := uint64(2 * lamportsPerSignature)
+	if blockhashCall.err == nil {
+		// The stake account only signs, so any address gives the same estimate.
+		instructions := stakeDelegationInstructions(from, solana.NewWallet().PublicKey(), vote, balance.Value)
+		fee = estimateFeeAt(ctx, client, recent.Value.Blockhash, instructions, from, 0)
+	}
+
+	lamports, rate, currency, err := w.quoteAmount(expr, unit, spendableAfter(balance.Value, fee))
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Policy.Check(lamports); err != nil {
+		return nil, err
+	}
+	if lamports <= reserve {
+		return nil, fmt.Errorf("%s SOL does not exceed the rent-exempt reserve of %s SOL a stake account keeps, so nothing would be staked",
+			LamportsToSOL(lamports), LamportsToSOL(reserve))
+	}
+	if balance.Value < lamports || balance.Value-lamports < fee {
+		return nil, &InsufficientFundsError{Have: balance.Value, Need: lamports + fee, Fee: fee, Rate: rate, Currency: currency}
+	}
+
+	quote := &StakeQuote{
+		From: from, Vote: vote, Lamports: lamports, Reserve: reserve, Fee: fee,
+		Balance: balance.Value, Rate: rate, Currency: currency,
+	}
+	if !expr.Simple() {
+		quote.Expression = expr.String()
+	}
+	return quote, nil
+}
+
+// ExecuteStake creates the stake account of a prepared delegation, signed by the active wallet and a
+// new stake account key, delegates it and waits for the transaction to confirm. The stake account
+// key is not kept: the active wallet is its staker and withdrawer.
+func (w *WalletConfig) ExecuteStake(ctx context.Context, quote *StakeQuote) (*StakeReceipt, error) {
+	owner, err := w.currentPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	stakeAccount := solana.NewWallet().PrivateKey
+	instructions := stakeDelegationInstructions(owner.PublicKey(), stakeAccount.PublicKey(), quote.Vote, quote.Lamports)
+	sig, err := w.submitStakeTransaction(ctx, owner, instructions, stakeAccount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit delegation: %w", err)
+	}
+
+	// The stake is delegated, so a failure to log it is not reported as a failed delegation.
+	_ = w.recordAudit(ctx, "stake", fmt.Sprintf("%s SOL in %s delegated to %s (%s)", quote.SOL(), stakeAccount.PublicKey(), quote.Vote, sig))
+
+	return &StakeReceipt{Signature: sig.String(), StakeAccount: stakeAccount.PublicKey().String()}, nil
+}
+
+// submitStakeTransaction signs instructions with owner, who pays the fee, and any other signers,
+// submits them and waits for the transaction to confirm.
+func (w *WalletConfig) submitStakeTransaction(ctx context.Context, owner solana.PrivateKey, instructions []solana.Instruction, signers ...solana.PrivateKey) (solana.Signature, error) {
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	client := endpoints.client()
+
+	recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to fetch blockhash: %w", err)
+	}
+
+	tx, err := solana.NewTransaction(instructions, recent.Value.Blockhash, solana.TransactionPayer(owner.PublicKey()))
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	signers = append(signers, owner)
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		for i := range signers {
+			if signers[i].PublicKey().Equals(key) {
+				return &signers[i]
+			}
+		}
+		return nil
+	}); err != nil {
+		return solana.Signature{}, fmt.Errorf("unable to sign transaction: %w", err)
+	}
+	w.recordKeyUsage(owner.PublicKey(), SignedSend, tx.Signatures[0])
+
+	sig, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	return sig, awaitSignature(ctx, client, sig)
+}
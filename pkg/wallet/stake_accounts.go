@@ -0,0 +1,346 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var (
+	ErrNotStakeAccount     = errors.New("not a stake account")
+	ErrNotStakeAuthority   = errors.New("the active wallet is not the authority of this stake account")
+	ErrStakeNotActive      = errors.New("the stake account is not delegated or already deactivating")
+	ErrStakeLocked         = errors.New("the stake account is locked up")
+	ErrNothingToWithdraw   = errors.New("nothing can be withdrawn from the stake account yet")
+	ErrWithdrawExceedsFree = errors.New("amount exceeds what can be withdrawn from the stake account")
+)
+
+// Offsets of the authorities in a stake account, after the state and the rent-exempt reserve. They
+// are used to find the stake accounts of a wallet.
+const (
+	stakeStakerOffset     = 12
+	stakeWithdrawerOffset = 44
+)
+
+// Stake account states, as stored in the first four bytes.
+const (
+	stakeStateInitialized uint32 = 1
+	stakeStateDelegated   uint32 = 2
+)
+
+// StakeActivation is where the stake of an account is in its life cycle. Stake activates and
+// deactivates at epoch boundaries.
+type StakeActivation string
+
+const (
+	// StakeInactive accounts are not delegated, or their stake has cooled down; all of it can be
+	// withdrawn.
+	StakeInactive     StakeActivation = "inactive"
+	StakeActivating   StakeActivation = "activating"
+	StakeActive       StakeActivation = "active"
+	StakeDeactivating StakeActivation = "deactivating"
+)
+
+// StakeLockup keeps the stake of an account from being withdrawn before a date or epoch, except by
+// its custodian.
+type StakeLockup struct {
+	UnixTimestamp int64
+	Epoch         uint64
+	Custodian     solana.PublicKey
+}
+
+// InForce reports whether the lockup still applies at now in epoch.
+func (l StakeLockup) InForce(now time.Time, epoch uint64) bool {
+	return l.UnixTimestamp > now.Unix() || l.Epoch > epoch
+}
+
+// IsZero reports whether the account has no lockup at all.
+func (l StakeLockup) IsZero() bool {
+	return l == StakeLockup{}
+}
+
+// StakeAccount is a decoded stake account.
+type StakeAccount struct {
+	Address  solana.PublicKey
+	Lamports uint64
+	// Reserve is the rent-exempt reserve, which stays in the account until it is closed.
+	Reserve    uint64
+	Staker     solana.PublicKey
+	Withdrawer solana.PublicKey
+	Lockup     StakeLockup
+	// Validator is the vote account the stake is delegated to, zero when it never was.
+	Validator solana.PublicKey
+	// Delegated is the delegated stake in lamports.
+	Delegated         uint64
+	ActivationEpoch   uint64
+	DeactivationEpoch uint64
+	// State is the activation state at the epoch the account was read in.
+	State StakeActivation
+}
+
+// Withdrawable returns the lamports that can be withdrawn now: everything once the stake is
+// inactive, otherwise what the account holds beyond its delegated stake and reserve.
+func (a StakeAccount) Withdrawable() uint64 {
+	if a.State == StakeInactive {
+		return a.Lamports
+	}
+	locked := a.Delegated + a.Reserve
+	if a.Lamports <= locked {
+		return 0
+	}
+	return a.Lamports - locked
+}
+
+// decodeStakeAccount decodes the data of a stake account holding lamports, read in epoch.
+func decodeStakeAccount(address solana.PublicKey, lamports uint64, data []byte, epoch uint64) (StakeAccount, error) {
+	account := StakeAccount{Address: address, Lamports: lamports, State: StakeInactive}
+	dec := bin.NewBinDecoder(data)
+
+	state, err := dec.ReadUint32(bin.LE)
+	if err != nil {
+		return account, fmt.Errorf("%s: %w", address, ErrNotStakeAccount)
+	}
+	if state != stakeStateInitialized && state != stakeStateDelegated {
+		return account, fmt.Errorf("%s: %w", address, ErrNotStakeAccount)
+	}
+
+	if account.Reserve, err = dec.ReadUint64(bin.LE); err != nil {
+		return account, fmt.Errorf("decode stake account %s: %w", address, err)
+	}
+	for _, key := range []*solana.PublicKey{&account.Staker, &account.Withdrawer} {
+		if err := readPublicKey(dec, key); err != nil {
+			return account, fmt.Errorf("decode stake account %s: %w", address, err)
+		}
+	}
+	if account.Lockup.UnixTimestamp, err = dec.ReadInt64(bin.LE); err != nil {
+		return account, fmt.Errorf("decode stake account %s: %w", address, err)
+	}
+	if account.Lockup.Epoch, err = dec.ReadUint64(bin.LE); err != nil {
+		return account, fmt.Errorf("decode stake account %s: %w", address, err)
+	}
+	if err := readPublicKey(dec, &account.Lockup.Custodian); err != nil {
+		return account, fmt.Errorf("decode stake account %s: %w", address, err)
+	}
+	if state == stakeStateInitialized {
+		return account, nil
+	}
+
+	if err := readPublicKey(dec, &account.Validator); err != nil {
+		return account, fmt.Errorf("decode stake account %s: %w", address, err)
+	}
+	for _, value := range []*uint64{&account.Delegated, &account.ActivationEpoch, &account.DeactivationEpoch} {
+		if *value, err = dec.ReadUint64(bin.LE); err != nil {
+			return account, fmt.Errorf("decode stake account %s: %w", address, err)
+		}
+	}
+
+	account.State = stakeActivation(account.ActivationEpoch, account.DeactivationEpoch, epoch)
+	return account, nil
+}
+
+// stakeActivation derives the state of a delegation from its epochs. Stake that activates or
+// deactivates in an epoch does so at the boundary to the next one. Warmup and cooldown limits, which
+// can spread a large change over several epochs, are not taken into account.
+func stakeActivation(activation, deactivation, epoch uint64) StakeActivation {
+	switch {
+	case deactivation != math.MaxUint64 && deactivation < epoch:
+		return StakeInactive
+	case deactivation != math.MaxUint64:
+		return StakeDeactivating
+	// Stake delegated at genesis has the largest activation epoch.
+	case activation != math.MaxUint64 && activation >= epoch:
+		return StakeActivating
+	default:
+		return StakeActive
+	}
+}
+
+// StakeAccounts lists the stake accounts the active wallet is the staker or withdrawer of, sorted by
+// address.
+func (w *WalletConfig) StakeAccounts(ctx context.Context) ([]StakeAccount, error) {
+	owner, err := w.currentPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, _, err := stakeAccountsOf(ctx, endpoints.client(), owner)
+	return accounts, err
+}
+
+// stakeAccountsOf returns the stake accounts owner is the staker or withdrawer of, sorted by address,
+// and the epoch their state was read in.
+func stakeAccountsOf(ctx context.Context, client ClientInterface, owner solana.PublicKey) ([]StakeAccount, uint64, error) {
+	epoch, err := client.GetEpochInfo(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch epoch: %w", err)
+	}
+
+	found := make(map[solana.PublicKey]StakeAccount)
+	for _, offset := range []uint64{stakeStakerOffset, stakeWithdrawerOffset} {
+		accounts, err := client.GetProgramAccountsWithOpts(ctx, solana.StakeProgramID, &rpc.GetProgramAccountsOpts{
+			Commitment: rpc.CommitmentConfirmed,
+			Filters: []rpc.RPCFilter{
+				{DataSize: stakeAccountSize},
+				{Memcmp: &rpc.RPCFilterMemcmp{Offset: offset, Bytes: owner[:]}},
+			},
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to fetch stake accounts: %w", err)
+		}
+		for _, keyed := range accounts {
+			if _, ok := found[keyed.Pubkey]; ok || keyed.Account == nil {
+				continue
+			}
+			account, err := decodeStakeAccount(keyed.Pubkey, keyed.Account.Lamports, keyed.Account.Data.GetBinary(), epoch.Epoch)
+			if err != nil {
+				return nil, 0, err
+			}
+			found[keyed.Pubkey] = account
+		}
+	}
+
+	accounts := make([]StakeAccount, 0, len(found))
+	for _, account := range found {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Address.String() < accounts[j].Address.String() })
+	return accounts, epoch.Epoch, nil
+}
+
+// StakeAccount reads the stake account at address.
+func (w *WalletConfig) StakeAccount(ctx context.Context, address string) (StakeAccount, error) {
+	key, err := solana.PublicKeyFromBase58(address)
+	if err != nil {
+		return StakeAccount{}, fmt.Errorf("invalid stake account address %q: %w", address, err)
+	}
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return StakeAccount{}, err
+	}
+	client := endpoints.client()
+
+	epoch, err := client.GetEpochInfo(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return StakeAccount{}, fmt.Errorf("failed to fetch epoch: %w", err)
+	}
+	info, err := client.GetAccountInfo(ctx, key)
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && (info == nil || info.Value == nil)) {
+		return StakeAccount{}, fmt.Errorf("%s: %w", key, ErrNotStakeAccount)
+	}
+	if err != nil {
+		return StakeAccount{}, fmt.Errorf("failed to fetch stake account: %w", err)
+	}
+	if !info.Value.Owner.Equals(solana.StakeProgramID) {
+		return StakeAccount{}, fmt.Errorf("%s: %w", key, ErrNotStakeAccount)
+	}
+
+	return decodeStakeAccount(key, info.Value.Lamports, info.Value.Data.GetBinary(), epoch.Epoch)
+}
+
+// DeactivateStake deactivates the delegated stake of account, signed by the active wallet as its
+// staker. The stake can be withdrawn once it has cooled down at the end of the epoch.
+func (w *WalletConfig) DeactivateStake(ctx context.Context, account StakeAccount) (string, error) {
+	if account.State != StakeActive && account.State != StakeActivating {
+		return "", fmt.Errorf("%s is %s: %w", account.Address, account.State, ErrStakeNotActive)
+	}
+	owner, err := w.currentPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	if !account.Staker.Equals(owner.PublicKey()) {
+		return "", fmt.Errorf("%w: its staker is %s", ErrNotStakeAuthority, account.Staker)
+	}
+
+	sig, err := w.submitStakeTransaction(ctx, owner, []solana.Instruction{stakeDeactivateInstruction(account.Address, owner.PublicKey())})
+	if err != nil {
+		return "", fmt.Errorf("failed to submit deactivation: %w", err)
+	}
+
+	_ = w.recordAudit(ctx, "unstake", fmt.Sprintf("deactivated %s SOL in %s (%s)", LamportsToSOL(account.Delegated), account.Address, sig))
+	return sig.String(), nil
+}
+
+// StakeWithdrawalAmount resolves amount, in unit, to lamports against what can be withdrawn from
+// account, so that "max" or "50%" are read from the stake account rather than the wallet.
+func (w *WalletConfig) StakeWithdrawalAmount(account StakeAccount, amount string, unit Unit) (uint64, error) {
+	expr, err := ParseAmountExpression(amount, unit)
+	if err != nil {
+		return 0, err
+	}
+	lamports, _, _, err := w.quoteAmount(expr, unit, account.Withdrawable())
+	return lamports, err
+}
+
+// WithdrawStake moves lamports out of account to the recipient address, or to the active wallet when
+// it is empty, signed by the active wallet as its withdrawer. Withdrawing everything from an inactive
+// account closes it. A lockup in force refuses the withdrawal unless the active wallet is its custodian.
+func (w *WalletConfig) WithdrawStake(ctx context.Context, account StakeAccount, to string, lamports uint64, now time.Time) (string, error) {
+	owner, err := w.currentPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	if !account.Withdrawer.Equals(owner.PublicKey()) {
+		return "", fmt.Errorf("%w: its withdrawer is %s", ErrNotStakeAuthority, account.Withdrawer)
+	}
+	recipient := owner.PublicKey()
+	if to != "" {
+		if recipient, err = solana.PublicKeyFromBase58(to); err != nil {
+			return "", fmt.Errorf("%w %q: %w", ErrInvalidRecipient, to, err)
+		}
+	}
+	if err := w.checkRecipientAllowed(recipient); err != nil {
+		return "", err
+	}
+
+	withdrawable := account.Withdrawable()
+	if withdrawable == 0 {
+		return "", fmt.Errorf("%s is %s: %w", account.Address, account.State, ErrNothingToWithdraw)
+	}
+	if lamports > withdrawable {
+		return "", fmt.Errorf("%w: %s SOL is available", ErrWithdrawExceedsFree, LamportsToSOL(withdrawable))
+	}
+
+	instruction := stakeWithdrawInstruction(account.Address, recipient, owner.PublicKey(), lamports)
+	if account.Lockup.InForce(now, w.stakeEpoch(ctx)) {
+		if !account.Lockup.Custodian.Equals(owner.PublicKey()) {
+			return "", fmt.Errorf("%w until %s or epoch %d; only its custodian %s can withdraw",
+				ErrStakeLocked, time.Unix(account.Lockup.UnixTimestamp, 0).UTC().Format(time.RFC3339), account.Lockup.Epoch, account.Lockup.Custodian)
+		}
+		// The custodian signs as an extra account after the withdrawer.
+		generic := instruction.(*solana.GenericInstruction)
+		generic.AccountValues = append(generic.AccountValues, solana.Meta(owner.PublicKey()).SIGNER())
+	}
+
+	sig, err := w.submitStakeTransaction(ctx, owner, []solana.Instruction{instruction})
+	if err != nil {
+		return "", fmt.Errorf("failed to submit withdrawal: %w", err)
+	}
+
+	_ = w.recordAudit(ctx, "withdraw-stake", fmt.Sprintf("%s SOL from %s to %s (%s)", LamportsToSOL(lamports), account.Address, recipient, sig))
+	return sig.String(), nil
+}
+
+// stakeEpoch returns the current epoch, or 0 when it cannot be read, in which case only the date of a
+// lockup is checked before the stake program checks both.
+func (w *WalletConfig) stakeEpoch(ctx context.Context) uint64 {
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return 0
+	}
+	epoch, err := endpoints.client().GetEpochInfo(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return 0
+	}
+	return epoch.Epoch
+}
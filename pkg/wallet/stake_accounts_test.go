@@ -0,0 +1,198 @@
+package wallet
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeStakeAccount returns the data of a stake account, delegated when its validator is set.
+func encodeStakeAccount(a StakeAccount) []byte {
+	data := make([]byte, stakeAccountSize)
+	state := stakeStateInitialized
+	if !a.Validator.IsZero() {
+		state = stakeStateDelegated
+	}
+	binary.LittleEndian.PutUint32(data, state)
+	binary.LittleEndian.PutUint64(data[4:], a.Reserve)
+	copy(data[stakeStakerOffset:], a.Staker[:])
+	copy(data[stakeWithdrawerOffset:], a.Withdrawer[:])
+	binary.LittleEndian.PutUint64(data[76:], uint64(a.Lockup.UnixTimestamp))
+	binary.LittleEndian.PutUint64(data[84:], a.Lockup.Epoch)
+	copy(data[92:], a.Lockup.Custodian[:])
+	if state == stakeStateDelegated {
+		copy(data[124:], a.Validator[:])
+		binary.LittleEndian.PutUint64(data[156:], a.Delegated)
+		binary.LittleEndian.PutUint64(data[164:], a.ActivationEpoch)
+		binary.LittleEndian.PutUint64(data[172:], a.DeactivationEpoch)
+	}
+	return data
+}
+
+func TestDecodeStakeAccount(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	delegated := StakeAccount{
+		Address:           solana.NewWallet().PublicKey(),
+		Lamports:          3_000_000_000,
+		Reserve:           2_282_880,
+		Staker:            owner,
+		Withdrawer:        owner,
+		Lockup:            StakeLockup{UnixTimestamp: 1_700_000_000, Epoch: 10, Custodian: solana.NewWallet().PublicKey()},
+		Validator:         solana.NewWallet().PublicKey(),
+		Delegated:         2_000_000_000,
+		ActivationEpoch:   500,
+		DeactivationEpoch: math.MaxUint64,
+		State:             StakeActive,
+	}
+	decoded, err := decodeStakeAccount(delegated.Address, delegated.Lamports, encodeStakeAccount(delegated), 600)
+	assert.NoError(t, err)
+	assert.Equal(t, delegated, decoded)
+	assert.Equal(t, delegated.Lamports-delegated.Delegated-delegated.Reserve, decoded.Withdrawable())
+
+	initialized := StakeAccount{Address: solana.NewWallet().PublicKey(), Lamports: 5_000_000, Reserve: 2_282_880, Staker: owner, Withdrawer: owner, State: StakeInactive}
+	decoded, err = decodeStakeAccount(initialized.Address, initialized.Lamports, encodeStakeAccount(initialized), 600)
+	assert.NoError(t, err)
+	assert.Equal(t, initialized, decoded)
+	assert.Equal(t, initialized.Lamports, decoded.Withdrawable())
+
+	_, err = decodeStakeAccount(owner, 0, make([]byte, stakeAccountSize), 600)
+	assert.ErrorIs(t, err, ErrNotStakeAccount)
+	_, err = decodeStakeAccount(owner, 0, encodeStakeAccount(delegated)[:100], 600)
+	assert.Error(t, err)
+}
+
+func TestStakeActivation(t *testing.T) {
+	for _, tt := range []struct {
+		activation, deactivation, epoch uint64
+		want                            StakeActivation
+	}{
+		{activation: 10, deactivation: math.MaxUint64, epoch: 10, want: StakeActivating},
+		{activation: 10, deactivation: math.MaxUint64, epoch: 11, want: StakeActive},
+		{activation: math.MaxUint64, deactivation: math.MaxUint64, epoch: 0, want: StakeActive},
+		{activation: 10, deactivation: 20, epoch: 20, want: StakeDeactivating},
+		{activation: 10, deactivation: 20, epoch: 21, want: StakeInactive},
+	} {
+		assert.Equal(t, tt.want, stakeActivation(tt.activation, tt.deactivation, tt.epoch), "%+v", tt)
+	}
+}
+
+func TestStakeLockup(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	assert.False(t, StakeLockup{}.InForce(now, 100))
+	assert.True(t, StakeLockup{UnixTimestamp: now.Unix() + 1}.InForce(now, 100))
+	assert.True(t, StakeLockup{Epoch: 101}.InForce(now, 100))
+	assert.False(t, StakeLockup{UnixTimestamp: now.Unix(), Epoch: 100}.InForce(now, 100))
+}
+
+func TestStakeAccountsDeactivateAndWithdraw(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	owner := solana.NewWallet()
+	other := solana.NewWallet().PublicKey()
+	vote := solana.NewWallet().PublicKey()
+	const epoch = 600
+
+	active := StakeAccount{Address: solana.NewWallet().PublicKey(), Lamports: 2_002_282_880, Reserve: 2_282_880, Staker: owner.PublicKey(), Withdrawer: owner.PublicKey(),
+		Validator: vote, Delegated: 2_000_000_000, ActivationEpoch: 500, DeactivationEpoch: math.MaxUint64}
+	cooled := StakeAccount{Address: solana.NewWallet().PublicKey(), Lamports: 1_002_282_880, Reserve: 2_282_880, Staker: other, Withdrawer: owner.PublicKey(),
+		Validator: vote, Delegated: 1_000_000_000, ActivationEpoch: 500, DeactivationEpoch: 550}
+	locked := cooled
+	locked.Address = solana.NewWallet().PublicKey()
+	locked.Lockup = StakeLockup{Epoch: epoch + 10, Custodian: other}
+
+	byOffset := map[uint64][]StakeAccount{
+		stakeStakerOffset:     {active},
+		stakeWithdrawerOffset: {active, cooled, locked},
+	}
+	var sent []*solana.Transaction
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetEpochInfoFn: func(context.Context, rpc.CommitmentType) (*rpc.GetEpochInfoResult, error) {
+				return &rpc.GetEpochInfoResult{Epoch: epoch}, nil
+			},
+			GetProgramAccountsWithOptsFn: func(_ context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+				assert.Equal(t, solana.StakeProgramID, program)
+				assert.Equal(t, uint64(stakeAccountSize), opts.Filters[0].DataSize)
+				memcmp := opts.Filters[1].Memcmp
+				assert.Equal(t, solana.Base58(owner.PublicKey().Bytes()), memcmp.Bytes)
+				var result rpc.GetProgramAccountsResult
+				for _, a := range byOffset[memcmp.Offset] {
+					result = append(result, &rpc.KeyedAccount{Pubkey: a.Address, Account: &rpc.Account{
+						Lamports: a.Lamports, Owner: solana.StakeProgramID, Data: rpc.DataBytesOrJSONFromBytes(encodeStakeAccount(a)),
+					}})
+				}
+				return result, nil
+			},
+			GetLatestBlockhashFn: func(context.Context, rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+				return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}}}, nil
+			},
+			SendTransactionFn: func(_ context.Context, tx *solana.Transaction) (solana.Signature, error) {
+				sent = append(sent, tx)
+				return tx.Signatures[0], nil
+			},
+			GetSignatureStatusesFn: func(context.Context, bool, ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+				return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}}}, nil
+			},
+		}
+	}
+
+	wc := &WalletConfig{Wallet: owner, Network: Devnet}
+	ctx := context.Background()
+
+	accounts, err := wc.StakeAccounts(ctx)
+	assert.NoError(t, err)
+	if !assert.Len(t, accounts, 3) {
+		return
+	}
+	states := make(map[solana.PublicKey]StakeAccount)
+	for _, a := range accounts {
+		states[a.Address] = a
+	}
+	active, cooled, locked = states[active.Address], states[cooled.Address], states[locked.Address]
+	assert.Equal(t, StakeActive, active.State)
+	assert.Equal(t, StakeInactive, cooled.State)
+
+	_, err = wc.DeactivateStake(ctx, cooled)
+	assert.ErrorIs(t, err, ErrStakeNotActive)
+	deactivated := active
+	deactivated.Staker = other
+	_, err = wc.DeactivateStake(ctx, deactivated)
+	assert.ErrorIs(t, err, ErrNotStakeAuthority)
+
+	sig, err := wc.DeactivateStake(ctx, active)
+	assert.NoError(t, err)
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, sent[0].Signatures[0].String(), sig)
+		assert.Equal(t, stakeDeactivate, binary.LittleEndian.Uint32(sent[0].Message.Instructions[0].Data))
+		assert.NoError(t, sent[0].VerifySignatures())
+	}
+
+	_, err = wc.WithdrawStake(ctx, active, "", 1, time.Now())
+	assert.ErrorIs(t, err, ErrNothingToWithdraw)
+	_, err = wc.WithdrawStake(ctx, cooled, "", cooled.Lamports+1, time.Now())
+	assert.ErrorIs(t, err, ErrWithdrawExceedsFree)
+	_, err = wc.WithdrawStake(ctx, locked, "", locked.Lamports, time.Now())
+	assert.ErrorIs(t, err, ErrStakeLocked)
+
+	sig, err = wc.WithdrawStake(ctx, cooled, other.String(), cooled.Lamports, time.Now())
+	assert.NoError(t, err)
+	if assert.Len(t, sent, 2) {
+		tx := sent[1]
+		assert.Equal(t, tx.Signatures[0].String(), sig)
+		assert.NoError(t, tx.VerifySignatures())
+		instruction := tx.Message.Instructions[0]
+		program, err := tx.Message.Program(instruction.ProgramIDIndex)
+		assert.NoError(t, err)
+		assert.Equal(t, solana.StakeProgramID, program)
+		assert.Equal(t, stakeWithdraw, binary.LittleEndian.Uint32(instruction.Data))
+		assert.Equal(t, cooled.Lamports, binary.LittleEndian.Uint64(instruction.Data[4:]))
+		assert.Equal(t, cooled.Address, tx.Message.AccountKeys[instruction.Accounts[0]])
+		assert.Equal(t, other, tx.Message.AccountKeys[instruction.Accounts[1]])
+	}
+}
@@ -0,0 +1,131 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// DefaultRewardEpochs is how many of the latest completed epochs staking rewards are looked up in.
+// Each epoch takes one getInflationReward request.
+const DefaultRewardEpochs = 10
+
+// StakingReward is the inflation reward a stake account earned in an epoch. It is paid into the
+// stake account, where it is staked too, at the start of the next epoch.
+type StakingReward struct {
+	StakeAccount solana.PublicKey
+	Epoch        uint64
+	// Slot is the slot the reward was paid in, and Timestamp its block time.
+	Slot      uint64
+	Timestamp time.Time
+	Lamports  uint64
+	// PostBalance is the balance of the stake account after the reward, in lamports.
+	PostBalance uint64
+	// Commission is the commission the validator took, in percent, when the node reports it.
+	Commission *uint8
+}
+
+// StakingRewards returns the rewards the stake accounts of the active wallet earned in each of the
+// last epochs completed epochs, newest first. Stake accounts that were closed since are not found, so
+// their rewards are left out.
+func (w *WalletConfig) StakingRewards(ctx context.Context, epochs int) ([]StakingReward, error) {
+	owner, err := w.currentPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	return stakingRewardsOf(ctx, endpoints.client(), owner, epochs)
+}
+
+// stakingRewardsOf returns the rewards of the stake accounts of owner in each of the last epochs
+// completed epochs, newest first.
+func stakingRewardsOf(ctx context.Context, client ClientInterface, owner solana.PublicKey, epochs int) ([]StakingReward, error) {
+	if epochs < 0 {
+		return nil, fmt.Errorf("invalid number of epochs %d", epochs)
+	}
+	accounts, current, err := stakeAccountsOf(ctx, client, owner)
+	if err != nil || len(accounts) == 0 {
+		return nil, err
+	}
+	addresses := make([]solana.PublicKey, len(accounts))
+	for i, account := range accounts {
+		addresses[i] = account.Address
+	}
+
+	var rewards []StakingReward
+	blockTimes := make(map[uint64]time.Time)
+	for epoch := current; epoch > 0 && current-epoch < uint64(epochs); epoch-- {
+		// Rewards for an epoch are paid once it has ended, so the current epoch has none yet.
+		rewardEpoch := epoch - 1
+		results, err := client.GetInflationReward(ctx, addresses, &rpc.GetInflationRewardOpts{Commitment: rpc.CommitmentFinalized, Epoch: &rewardEpoch})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch rewards of epoch %d: %w", rewardEpoch, err)
+		}
+
+		// Results are in the order of the addresses, nil for an account without a reward.
+		for i, result := range results {
+			if result == nil || i >= len(addresses) || result.Amount == 0 {
+				continue
+			}
+			timestamp, ok := blockTimes[result.EffectiveSlot]
+			if !ok {
+				blockTime, err := client.GetBlockTime(ctx, result.EffectiveSlot)
+				if err != nil {
+					return nil, fmt.Errorf("get block time: %w", err)
+				}
+				if blockTime != nil {
+					timestamp = blockTime.Time()
+				}
+				blockTimes[result.EffectiveSlot] = timestamp
+			}
+			rewards = append(rewards, StakingReward{
+				StakeAccount: addresses[i],
+				Epoch:        result.Epoch,
+				Slot:         result.EffectiveSlot,
+				Timestamp:    timestamp,
+				Lamports:     result.Amount,
+				PostBalance:  result.PostBalance,
+				Commission:   result.Commission,
+			})
+		}
+	}
+
+	sort.SliceStable(rewards, func(i, j int) bool {
+		if rewards[i].Epoch != rewards[j].Epoch {
+			return rewards[i].Epoch > rewards[j].Epoch
+		}
+		return rewards[i].StakeAccount.String() < rewards[j].StakeAccount.String()
+	})
+	return rewards, nil
+}
+
+// rewardTransactions returns rewards as history entries within the time bounds of opts.
+func rewardTransactions(rewards []StakingReward, opts GetTransactionHistoryOpts) []*Transaction {
+	var transactions []*Transaction
+	for _, reward := range rewards {
+		if !opts.Since.IsZero() && reward.Timestamp.Before(opts.Since) {
+			continue
+		}
+		if !opts.Until.IsZero() && reward.Timestamp.After(opts.Until) {
+			continue
+		}
+		transactions = append(transactions, &Transaction{
+			Slot:      reward.Slot,
+			Amount:    reward.Lamports,
+			From:      reward.StakeAccount,
+			To:        reward.StakeAccount,
+			Timestamp: reward.Timestamp,
+			Reward:    true,
+			Epoch:     reward.Epoch,
+		})
+	}
+	return transactions
+}
@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStakingRewards(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	owner := solana.NewWallet()
+	vote := solana.NewWallet().PublicKey()
+	first := StakeAccount{Address: solana.NewWallet().PublicKey(), Lamports: 2_000_000_000, Reserve: 2_282_880, Staker: owner.PublicKey(), Withdrawer: owner.PublicKey(),
+		Validator: vote, Delegated: 1_997_717_120, ActivationEpoch: 500, DeactivationEpoch: math.MaxUint64}
+	second := first
+	second.Address = solana.NewWallet().PublicKey()
+	commission := uint8(7)
+	paid := map[uint64]time.Time{
+		600_000: time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC),
+		599_000: time.Date(2024, 2, 29, 22, 0, 0, 0, time.UTC),
+	}
+
+	var epochs []uint64
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetEpochInfoFn: func(context.Context, rpc.CommitmentType) (*rpc.GetEpochInfoResult, error) {
+				return &rpc.GetEpochInfoResult{Epoch: 600}, nil
+			},
+			GetProgramAccountsWithOptsFn: func(_ context.Context, _ solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+				if opts.Filters[1].Memcmp.Offset != stakeStakerOffset {
+					return nil, nil
+				}
+				var result rpc.GetProgramAccountsResult
+				for _, a := range []StakeAccount{first, second} {
+					result = append(result, &rpc.KeyedAccount{Pubkey: a.Address, Account: &rpc.Account{Lamports: a.Lamports, Data: rpc.DataBytesOrJSONFromBytes(encodeStakeAccount(a))}})
+				}
+				return result, nil
+			},
+			GetInflationRewardFn: func(_ context.Context, addresses []solana.PublicKey, opts *rpc.GetInflationRewardOpts) ([]*rpc.GetInflationRewardResult, error) {
+				epochs = append(epochs, *opts.Epoch)
+				results := make([]*rpc.GetInflationRewardResult, len(addresses))
+				for i, address := range addresses {
+					// The second account only started earning in epoch 599.
+					if address.Equals(second.Address) && *opts.Epoch < 599 {
+						continue
+					}
+					results[i] = &rpc.GetInflationRewardResult{Epoch: *opts.Epoch, EffectiveSlot: (*opts.Epoch + 1) * 1000, Amount: 250_000 + *opts.Epoch, Commission: &commission}
+				}
+				return results, nil
+			},
+			GetBlockTimeFn: func(_ context.Context, slot uint64) (*solana.UnixTimeSeconds, error) {
+				ts := solana.UnixTimeSeconds(paid[slot].Unix())
+				return &ts, nil
+			},
+		}
+	}
+
+	wc := &WalletConfig{Wallet: owner, Network: Devnet}
+	rewards, err := wc.StakingRewards(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{599, 598}, epochs, "the current epoch has no rewards yet")
+	if assert.Len(t, rewards, 3) {
+		assert.Equal(t, uint64(599), rewards[0].Epoch)
+		assert.Equal(t, uint64(599), rewards[1].Epoch)
+		assert.Equal(t, StakingReward{StakeAccount: first.Address, Epoch: 598, Slot: 599_000, Timestamp: paid[599_000].Local(), Lamports: 250_598, Commission: &commission}, rewards[2])
+	}
+
+	transactions := rewardTransactions(rewards, GetTransactionHistoryOpts{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)})
+	assert.Len(t, transactions, 2)
+	groups := GroupTransactions(transactions)
+	assert.Len(t, groups, 2, "rewards are not grouped by their empty signature")
+	summary := SummarizeHistory(groups)
+	assert.Equal(t, HistorySummary{Rewards: 2 * 250_599}, summary)
+
+	row := historyRow(transactions[0], nil, DefaultCurrency, Devnet)
+	assert.Equal(t, "reward", row.Direction)
+	assert.Empty(t, row.Signature)
+	assert.Equal(t, uint64(250_599), row.Lamports)
+}
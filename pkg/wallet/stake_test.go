@@ -0,0 +1,107 @@
+package wallet
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStakeInstructions(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	stakeAccount := solana.NewWallet().PublicKey()
+	vote := solana.NewWallet().PublicKey()
+
+	instructions := stakeDelegationInstructions(owner, stakeAccount, vote, 2_000_000_000)
+	assert.Len(t, instructions, 3)
+
+	initialize, err := instructions[1].Data()
+	assert.NoError(t, err)
+	assert.Len(t, initialize, 4+32+32+8+8+32)
+	assert.Equal(t, stakeInitialize, binary.LittleEndian.Uint32(initialize))
+	assert.Equal(t, owner[:], initialize[4:36], "staker")
+	assert.Equal(t, owner[:], initialize[36:68], "withdrawer")
+	assert.Equal(t, make([]byte, 48), initialize[68:], "no lockup")
+
+	delegate, err := instructions[2].Data()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{2, 0, 0, 0}, delegate)
+	accounts := instructions[2].Accounts()
+	assert.Equal(t, []solana.PublicKey{stakeAccount, vote, solana.SysVarClockPubkey, solana.SysVarStakeHistoryPubkey, StakeConfigID, owner},
+		[]solana.PublicKey{accounts[0].PublicKey, accounts[1].PublicKey, accounts[2].PublicKey, accounts[3].PublicKey, accounts[4].PublicKey, accounts[5].PublicKey})
+	assert.True(t, accounts[5].IsSigner)
+}
+
+func TestPrepareAndExecuteStake(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	owner := solana.NewWallet()
+	vote := solana.NewWallet().PublicKey()
+	notVote := solana.NewWallet().PublicKey()
+	fee, reserve := uint64(10_000), uint64(2_282_880)
+
+	var sent []*solana.Transaction
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(context.Context, solana.PublicKey, rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				return &rpc.GetBalanceResult{Value: 1_000_000_000}, nil
+			},
+			GetLatestBlockhashFn: func(context.Context, rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+				return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}}}, nil
+			},
+			GetFeeForMessageFn: func(context.Context, string, rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+				return &rpc.GetFeeForMessageResult{Value: &fee}, nil
+			},
+			GetMinimumBalanceForRentExemptionFn: func(_ context.Context, size uint64, _ rpc.CommitmentType) (uint64, error) {
+				assert.Equal(t, uint64(stakeAccountSize), size)
+				return reserve, nil
+			},
+			GetAccountInfoFn: func(_ context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+				owner := solana.SystemProgramID
+				if account.Equals(vote) {
+					owner = solana.VoteProgramID
+				}
+				return &rpc.GetAccountInfoResult{Value: &rpc.Account{Owner: owner, Data: rpc.DataBytesOrJSONFromBytes(nil)}}, nil
+			},
+			SendTransactionFn: func(_ context.Context, tx *solana.Transaction) (solana.Signature, error) {
+				sent = append(sent, tx)
+				return tx.Signatures[0], nil
+			},
+			GetSignatureStatusesFn: func(context.Context, bool, ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+				return &rpc.GetSignatureStatusesResult{Value: []*rpc.SignatureStatusesResult{{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}}}, nil
+			},
+		}
+	}
+
+	wc := &WalletConfig{Wallet: owner, Network: Devnet}
+	ctx := context.Background()
+
+	_, err := wc.PrepareStake(ctx, "0.5", notVote.String(), UnitSOL)
+	assert.ErrorIs(t, err, ErrNotVoteAccount)
+	_, err = wc.PrepareStake(ctx, "0.002", vote.String(), UnitSOL)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "rent-exempt reserve")
+	}
+	_, err = wc.PrepareStake(ctx, "2", vote.String(), UnitSOL)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	quote, err := wc.PrepareStake(ctx, "max", vote.String(), UnitSOL)
+	assert.NoError(t, err)
+	assert.Equal(t, 1_000_000_000-fee, quote.Lamports)
+	assert.Equal(t, quote.Lamports-reserve, quote.Staked())
+	assert.Equal(t, "max", quote.Expression)
+
+	receipt, err := wc.ExecuteStake(ctx, quote)
+	assert.NoError(t, err)
+	if assert.Len(t, sent, 1) {
+		tx := sent[0]
+		assert.Len(t, tx.Signatures, 2)
+		assert.Equal(t, owner.PublicKey(), tx.Message.AccountKeys[0])
+		assert.Equal(t, receipt.StakeAccount, tx.Message.AccountKeys[1].String())
+		assert.Equal(t, tx.Signatures[0].String(), receipt.Signature)
+		assert.NoError(t, tx.VerifySignatures())
+	}
+}
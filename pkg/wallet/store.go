@@ -0,0 +1,174 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// StoreBackupSuffix is appended to the path of a store file to name the copy of its previous contents,
+// which is written before every change.
+const StoreBackupSuffix = ".bak"
+
+var (
+	ErrStoreCorrupt = errors.New("store file is corrupt")
+	ErrStoreNewer   = errors.New("store file was written by a newer version of sleeng; upgrade to read it")
+)
+
+// StoreKind names what an auxiliary store file holds.
+type StoreKind string
+
+const (
+	StoreAudit    StoreKind = "audit"
+	StorePending  StoreKind = "pending"
+	StoreFailsafe StoreKind = "failsafe"
+	StoreLabels   StoreKind = "labels"
+)
+
+// storeEnvelope is how every auxiliary store file is written: the payload tagged with what it holds and
+// the version of its schema, so a file is checked before it is trusted and upgraded when the schema
+// changes. The key file has its own format and is not a store.
+type storeEnvelope struct {
+	Version int             `json:"version"`
+	Kind    StoreKind       `json:"kind"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// storeMigration upgrades a payload from the version it is registered under to the next one.
+type storeMigration func(payload json.RawMessage) (json.RawMessage, error)
+
+// storeSchema describes one kind of store file.
+type storeSchema struct {
+	Kind StoreKind
+	// Version is the version the store is written with.
+	Version int
+	// Migrations[v] upgrades a payload of version v to version v+1. Version 0 is a file written before
+	// stores had an envelope, which is the bare payload.
+	Migrations map[int]storeMigration
+}
+
+// storeValidator is implemented by payloads that check their contents after they are decoded and
+// before they are written. A payload that fails the check is treated as corrupt.
+type storeValidator interface {
+	validate() error
+}
+
+// storeSchemas is the migration registry: every store kind and how to read its older versions.
+var storeSchemas = map[StoreKind]*storeSchema{}
+
+// registerStoreSchema adds a store kind to the registry and returns its schema.
+func registerStoreSchema(schema storeSchema) *storeSchema {
+	if _, exists := storeSchemas[schema.Kind]; exists {
+		panic("store kind registered twice: " + string(schema.Kind))
+	}
+	storeSchemas[schema.Kind] = &schema
+	return &schema
+}
+
+// bareStorePayload is the migration from version 0: the file was the payload itself.
+func bareStorePayload(payload json.RawMessage) (json.RawMessage, error) {
+	return payload, nil
+}
+
+// readStore decodes the store file at path into v, upgrading older versions in memory; the upgrade is
+// persisted the next time the store is written. Errors reading the file are returned wrapped, so a
+// missing file can be told apart with os.ErrNotExist. A file that cannot be decoded, holds another
+// kind of store or fails validation returns ErrStoreCorrupt.
+func readStore(r FileReader, path string, schema *storeSchema, v interface{}) error {
+	data, err := r.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+
+	corrupt := func(format string, args ...interface{}) error {
+		hint := ""
+		if _, err := r.ReadFile(path + StoreBackupSuffix); err == nil {
+			hint = "; its previous contents are in " + path + StoreBackupSuffix
+		}
+		return fmt.Errorf("%w: %s: %s%s", ErrStoreCorrupt, path, fmt.Sprintf(format, args...), hint)
+	}
+
+	envelope, err := openStoreEnvelope(data)
+	if err != nil {
+		return corrupt("%v", err)
+	}
+	if envelope.Version > 0 && envelope.Kind != schema.Kind {
+		return corrupt("it holds a %s store, not a %s store", envelope.Kind, schema.Kind)
+	}
+	if envelope.Version > schema.Version {
+		return fmt.Errorf("%w: %s has version %d, this version reads up to %d", ErrStoreNewer, path, envelope.Version, schema.Version)
+	}
+
+	payload := envelope.Payload
+	for version := envelope.Version; version < schema.Version; version++ {
+		migrate, ok := schema.Migrations[version]
+		if !ok {
+			return fmt.Errorf("no migration of %s stores from version %d", schema.Kind, version)
+		}
+		if payload, err = migrate(payload); err != nil {
+			return corrupt("migrating from version %d: %v", version, err)
+		}
+	}
+
+	if err := json.Unmarshal(payload, v); err != nil {
+		return corrupt("%v", err)
+	}
+	if validator, ok := v.(storeValidator); ok {
+		if err := validator.validate(); err != nil {
+			return corrupt("%v", err)
+		}
+	}
+	return nil
+}
+
+// openStoreEnvelope splits a store file into its envelope. A file without one was written before
+// stores were versioned and is returned as version 0 of the kind it is read as.
+func openStoreEnvelope(data []byte) (storeEnvelope, error) {
+	if !json.Valid(data) {
+		return storeEnvelope{}, errors.New("not valid JSON")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || len(fields) != 3 || fields["version"] == nil || fields["kind"] == nil || fields["payload"] == nil {
+		return storeEnvelope{Version: 0, Payload: data}, nil
+	}
+
+	var envelope storeEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return storeEnvelope{}, fmt.Errorf("invalid envelope: %w", err)
+	}
+	if envelope.Version < 1 {
+		return storeEnvelope{}, fmt.Errorf("invalid version %d", envelope.Version)
+	}
+	return envelope, nil
+}
+
+// writeStore encodes v as the current version of schema and replaces the store file at path with it,
+// after copying the previous contents next to it. v is validated first, so a store never writes a
+// file it would refuse to read.
+func writeStore(r FileReader, w FileWriter, path string, schema *storeSchema, v interface{}) error {
+	if validator, ok := v.(storeValidator); ok {
+		if err := validator.validate(); err != nil {
+			return fmt.Errorf("refusing to write an invalid %s store: %w", schema.Kind, err)
+		}
+	}
+
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error marshaling JSON: %w", err)
+	}
+	data, err := json.Marshal(storeEnvelope{Version: schema.Version, Kind: schema.Kind, Payload: payload})
+	if err != nil {
+		return fmt.Errorf("error marshaling JSON: %w", err)
+	}
+
+	// A corrupt file is not backed up, so it cannot overwrite the last good backup.
+	if previous, err := r.ReadFile(path); err == nil && json.Valid(previous) && !bytes.Equal(previous, data) {
+		if err := w.WriteFile(path+StoreBackupSuffix, previous); err != nil {
+			return fmt.Errorf("error backing up %s: %w", path, err)
+		}
+	}
+
+	return w.WriteFile(path, data)
+}
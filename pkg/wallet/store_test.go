@@ -0,0 +1,238 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testStorePath = "test.store.json"
+
+// testRecords is a store payload that is valid while no name is empty.
+type testRecords map[string]string
+
+func (r testRecords) validate() error {
+	for id, name := range r {
+		if name == "" {
+			return errors.New("record " + id + " has no name")
+		}
+	}
+	return nil
+}
+
+// testSchema is at version 2: version 1 held the names upper case, version 0 had no envelope.
+var testSchema = &storeSchema{
+	Kind:    "test",
+	Version: 2,
+	Migrations: map[int]storeMigration{
+		0: bareStorePayload,
+		1: func(payload json.RawMessage) (json.RawMessage, error) {
+			var records map[string]string
+			if err := json.Unmarshal(payload, &records); err != nil {
+				return nil, err
+			}
+			for id, name := range records {
+				records[id] = strings.ToLower(name)
+			}
+			return json.Marshal(records)
+		},
+	},
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	files := newMemFiles()
+	assert.NoError(t, writeStore(files, files, testStorePath, testSchema, testRecords{"a": "alice"}))
+	assert.JSONEq(t, `{"version":2,"kind":"test","payload":{"a":"alice"}}`, string(files.files[testStorePath]))
+	// The first write has nothing to back up.
+	assert.NotContains(t, files.files, testStorePath+StoreBackupSuffix)
+
+	assert.NoError(t, writeStore(files, files, testStorePath, testSchema, testRecords{"a": "alice", "b": "bob"}))
+	assert.JSONEq(t, `{"version":2,"kind":"test","payload":{"a":"alice"}}`, string(files.files[testStorePath+StoreBackupSuffix]))
+
+	var records testRecords
+	assert.NoError(t, readStore(files, testStorePath, testSchema, &records))
+	assert.Equal(t, testRecords{"a": "alice", "b": "bob"}, records)
+
+	err := readStore(files, "missing.json", testSchema, &records)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestStoreMigrations(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected testRecords
+	}{
+		{name: "Bare Payload", file: `{"a":"ALICE"}`, expected: testRecords{"a": "alice"}},
+		{name: "Version 1", file: `{"version":1,"kind":"test","payload":{"a":"ALICE"}}`, expected: testRecords{"a": "alice"}},
+		{name: "Current Version", file: `{"version":2,"kind":"test","payload":{"a":"ALICE"}}`, expected: testRecords{"a": "ALICE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			files.files[testStorePath] = []byte(tt.file)
+
+			var records testRecords
+			assert.NoError(t, readStore(files, testStorePath, testSchema, &records))
+			assert.Equal(t, tt.expected, records)
+			// Reading never rewrites the file; the upgrade is persisted by the next write.
+			assert.Equal(t, tt.file, string(files.files[testStorePath]))
+		})
+	}
+}
+
+func TestStoreRefusesBadFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		expectedErr error
+		expectedMsg string
+	}{
+		{name: "Truncated", file: `{"version":2,"kind":"test","payl`, expectedErr: ErrStoreCorrupt, expectedMsg: "not valid JSON"},
+		{name: "Other Kind", file: `{"version":1,"kind":"pending","payload":{}}`, expectedErr: ErrStoreCorrupt, expectedMsg: "holds a pending store"},
+		{name: "Newer Version", file: `{"version":3,"kind":"test","payload":{}}`, expectedErr: ErrStoreNewer},
+		{name: "Invalid Version", file: `{"version":0,"kind":"test","payload":{}}`, expectedErr: ErrStoreCorrupt},
+		{name: "Wrong Payload Type", file: `{"version":2,"kind":"test","payload":[1,2]}`, expectedErr: ErrStoreCorrupt},
+		{name: "Fails Validation", file: `{"version":2,"kind":"test","payload":{"a":""}}`, expectedErr: ErrStoreCorrupt, expectedMsg: "record a has no name"},
+		{name: "Fails Migration", file: `{"version":1,"kind":"test","payload":["ALICE"]}`, expectedErr: ErrStoreCorrupt, expectedMsg: "migrating from version 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			files.files[testStorePath] = []byte(tt.file)
+
+			var records testRecords
+			err := readStore(files, testStorePath, testSchema, &records)
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Contains(t, err.Error(), tt.expectedMsg)
+		})
+	}
+}
+
+func TestStoreCorruptionPointsToBackup(t *testing.T) {
+	files := newMemFiles()
+	assert.NoError(t, writeStore(files, files, testStorePath, testSchema, testRecords{"a": "alice"}))
+	assert.NoError(t, writeStore(files, files, testStorePath, testSchema, testRecords{"a": "alice", "b": "bob"}))
+	good := files.files[testStorePath+StoreBackupSuffix]
+
+	files.files[testStorePath] = []byte(`{"version":2,"kind":"te`)
+	var records testRecords
+	err := readStore(files, testStorePath, testSchema, &records)
+	assert.ErrorIs(t, err, ErrStoreCorrupt)
+	assert.Contains(t, err.Error(), "its previous contents are in "+testStorePath+StoreBackupSuffix)
+
+	// Writing over a corrupt file keeps the last good backup.
+	assert.NoError(t, writeStore(files, files, testStorePath, testSchema, testRecords{"c": "carol"}))
+	assert.Equal(t, good, files.files[testStorePath+StoreBackupSuffix])
+}
+
+func TestStoreRefusesToWriteInvalidPayload(t *testing.T) {
+	files := newMemFiles()
+	err := writeStore(files, files, testStorePath, testSchema, testRecords{"a": ""})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to write an invalid test store")
+	assert.Empty(t, files.files)
+}
+
+func TestStoreMissingMigration(t *testing.T) {
+	schema := &storeSchema{Kind: "test", Version: 2, Migrations: map[int]storeMigration{0: bareStorePayload}}
+	files := newMemFiles()
+	files.files[testStorePath] = []byte(`{"a":"alice"}`)
+
+	var records testRecords
+	err := readStore(files, testStorePath, schema, &records)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no migration of test stores from version 1")
+}
+
+func TestStoreKindsAreRegistered(t *testing.T) {
+	for _, kind := range []StoreKind{StoreAudit, StorePending, StoreFailsafe, StoreLabels} {
+		schema, ok := storeSchemas[kind]
+		if assert.True(t, ok, kind) {
+			for version := 0; version < schema.Version; version++ {
+				assert.Contains(t, schema.Migrations, version, "%s has no migration from version %d", kind, version)
+			}
+		}
+	}
+}
+
+func TestAuditStoreMigration(t *testing.T) {
+	files := newMemFiles()
+	files.files[AuditFilePath] = []byte(`[{"time":"2023-05-01T10:00:00Z","action":"add","changes":null}]`)
+	audit := &AuditLog{FileReader: files, FileWriter: files}
+
+	entries, err := audit.Entries()
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "add", entries[0].Action)
+	}
+
+	assert.NoError(t, audit.Append(AuditEntry{Time: time.Now().UTC(), Action: "send"}))
+	var envelope storeEnvelope
+	assert.NoError(t, json.Unmarshal(files.files[AuditFilePath], &envelope))
+	assert.Equal(t, storeEnvelope{Version: 1, Kind: StoreAudit, Payload: envelope.Payload}, envelope)
+	// The file as older versions wrote it is kept as the backup.
+	assert.JSONEq(t, `[{"time":"2023-05-01T10:00:00Z","action":"add","changes":null}]`, string(files.files[AuditFilePath+StoreBackupSuffix]))
+
+	entries, err = audit.Entries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestPendingStoreMigration(t *testing.T) {
+	files := newMemFiles()
+	files.files[PendingFilePath] = []byte(`{"orig1234":{"id":"orig1234","signature":"sig","from":"A","to":"B","lamports":1000,"network":"devnet","status":"submitted","createdAt":"2023-05-01T10:00:00Z"}}`)
+	store := &PendingStore{FileReader: files, FileWriter: files}
+
+	tx, err := store.Get("orig1234")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000), tx.Lamports)
+
+	assert.NoError(t, store.setStatus("orig1234", PendingConfirmed))
+	var envelope storeEnvelope
+	assert.NoError(t, json.Unmarshal(files.files[PendingFilePath], &envelope))
+	assert.Equal(t, 1, envelope.Version)
+	assert.Equal(t, StorePending, envelope.Kind)
+
+	tx, err = store.Get("orig1234")
+	assert.NoError(t, err)
+	assert.Equal(t, PendingConfirmed, tx.Status)
+
+	// A record stored under another ID is corruption, not a transaction to act on.
+	files.files[PendingFilePath] = []byte(`{"version":1,"kind":"pending","payload":{"orig1234":{"id":"other","status":"submitted"}}}`)
+	_, err = store.List()
+	assert.ErrorIs(t, err, ErrStoreCorrupt)
+}
+
+func TestFailsafeStoreMigration(t *testing.T) {
+	files := newMemFiles()
+	files.files[FailsafeFilePath] = []byte(`{"Addr1":{"alias":"main","from":"Addr1","to":"Addr2","nonceAccount":"Nonce1","nonce":"N","lamports":5,"network":"devnet","status":"armed","signedAt":"2023-05-01T10:00:00Z","transaction":"ct","salt":"s","cipherNonce":"n"}}`)
+	store := &FailsafeStore{FileReader: files, FileWriter: files}
+
+	failsafe, err := store.Get("Addr1")
+	assert.NoError(t, err)
+	assert.Equal(t, "main", failsafe.Alias)
+
+	failsafe.Status = FailsafeInvalidated
+	assert.NoError(t, store.Put(failsafe))
+	var envelope storeEnvelope
+	assert.NoError(t, json.Unmarshal(files.files[FailsafeFilePath], &envelope))
+	assert.Equal(t, 1, envelope.Version)
+	assert.Equal(t, StoreFailsafe, envelope.Kind)
+
+	list, err := store.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []Failsafe{failsafe}, list)
+
+	// A sweep with an unknown status could be broadcast by mistake, so the file is refused.
+	files.files[FailsafeFilePath] = []byte(`{"version":1,"kind":"failsafe","payload":{"Addr1":{"from":"Addr1","status":"ready","transaction":"ct","nonceAccount":"Nonce1"}}}`)
+	_, err = store.List()
+	assert.ErrorIs(t, err, ErrStoreCorrupt)
+}
@@ -0,0 +1,159 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TaxFormat is the CSV layout of a crypto tax tool.
+type TaxFormat string
+
+const (
+	// TaxFormatKoinly is Koinly's universal CSV format.
+	TaxFormatKoinly TaxFormat = "koinly"
+	// TaxFormatCoinTracking is CoinTracking's CSV import format.
+	TaxFormatCoinTracking TaxFormat = "cointracking"
+)
+
+// TaxFormats are the supported tax formats.
+var TaxFormats = []TaxFormat{TaxFormatKoinly, TaxFormatCoinTracking}
+
+// ParseTaxFormat parses the name of a tax format, ignoring case.
+func ParseTaxFormat(name string) (TaxFormat, error) {
+	for _, format := range TaxFormats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	names := make([]string, len(TaxFormats))
+	for i, format := range TaxFormats {
+		names[i] = string(format)
+	}
+	return "", fmt.Errorf("unknown tax format %q, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+// koinlyColumns is the header of Koinly's universal format.
+var koinlyColumns = []string{
+	"Date", "Sent Amount", "Sent Currency", "Received Amount", "Received Currency", "Fee Amount",
+	"Fee Currency", "Net Worth Amount", "Net Worth Currency", "Label", "Description", "TxHash",
+}
+
+// coinTrackingColumns is the header of CoinTracking's CSV import.
+var coinTrackingColumns = []string{
+	"Type", "Buy Amount", "Buy Currency", "Sell Amount", "Sell Currency", "Fee", "Fee Currency",
+	"Exchange", "Trade-Group", "Comment", "Date", "Tx-ID", "Buy Value in Account Currency",
+	"Sell Value in Account Currency",
+}
+
+// PriceHistoryRows values the SOL rows at the rate of their time in rates, for reports that need what
+// a transfer was worth when it was made. Rows whose rate is not in rates are left without a value
+// rather than valued at another time.
+func PriceHistoryRows(rows []HistoryRow, rates HistoricalRates, currency Currency) []HistoryRow {
+	priced := make([]HistoryRow, len(rows))
+	for i, row := range rows {
+		row.Rate, row.Fiat, row.Currency = nil, nil, ""
+		if rate := rates.At(row.Timestamp); rate != nil && row.TokenAmount == nil {
+			fiat := row.SOL.Mul(*rate).Round(2)
+			row.Rate, row.Fiat, row.Currency = rate, &fiat, currency
+		}
+		priced[i] = row
+	}
+	return priced
+}
+
+// WriteTaxReport writes rows as CSV in the layout of format, with a header row. Sent transfers are
+// disposals and received ones acquisitions; staking rewards are marked as income. The fee of a
+// transaction the wallet paid stays on its first row, in SOL, and values are those of the rows.
+func WriteTaxReport(w io.Writer, format TaxFormat, rows []HistoryRow) error {
+	header, record := koinlyColumns, HistoryRow.koinlyRecord
+	switch format {
+	case TaxFormatKoinly:
+	case TaxFormatCoinTracking:
+		header, record = coinTrackingColumns, HistoryRow.coinTrackingRecord
+	default:
+		return fmt.Errorf("unknown tax format %q", format)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV: %w", err)
+	}
+	for _, row := range rows {
+		if err := writer.Write(record(row)); err != nil {
+			return fmt.Errorf("error writing CSV: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing CSV: %w", err)
+	}
+	return nil
+}
+
+// taxAsset returns the amount and asset of a row: SOL, or the token under its symbol, or its mint
+// when it has none.
+func (r HistoryRow) taxAsset() (string, string) {
+	if r.TokenAmount == nil {
+		return r.SOL.String(), "SOL"
+	}
+	if r.Token != "" {
+		return r.TokenAmount.String(), r.Token
+	}
+	return r.TokenAmount.String(), r.Mint
+}
+
+// taxFee returns the fee of a row in SOL, or nothing when the wallet paid none.
+func (r HistoryRow) taxFee() (string, string) {
+	if r.FeeLamports == 0 {
+		return "", ""
+	}
+	return LamportsToSOL(r.FeeLamports).String(), "SOL"
+}
+
+// taxValue returns the fiat value of a row and its currency, or nothing when it has no value.
+func (r HistoryRow) taxValue() (string, string) {
+	if r.Fiat == nil {
+		return "", ""
+	}
+	return r.Fiat.StringFixed(2), string(r.Currency)
+}
+
+func (r HistoryRow) koinlyRecord() []string {
+	amount, asset := r.taxAsset()
+	fee, feeAsset := r.taxFee()
+	value, valueCurrency := r.taxValue()
+
+	record := []string{r.Timestamp.UTC().Format("2006-01-02 15:04:05 UTC"), "", "", "", "", fee, feeAsset, value, valueCurrency, "", r.Label, r.Signature}
+	if r.Direction == "sent" {
+		record[1], record[2] = amount, asset
+	} else {
+		record[3], record[4] = amount, asset
+	}
+	if r.Direction == "reward" {
+		record[9] = "staking"
+	}
+	return record
+}
+
+func (r HistoryRow) coinTrackingRecord() []string {
+	amount, asset := r.taxAsset()
+	fee, feeAsset := r.taxFee()
+	value, _ := r.taxValue()
+
+	kind := "Deposit"
+	switch r.Direction {
+	case "sent":
+		kind = "Withdrawal"
+	case "reward":
+		kind = "Staking"
+	}
+	record := []string{kind, "", "", "", "", fee, feeAsset, "Solana " + string(r.Network), r.Category, r.Label, r.Timestamp.UTC().Format("2006-01-02 15:04:05"), r.Signature, "", ""}
+	if r.Direction == "sent" {
+		record[3], record[4], record[13] = amount, asset, value
+	} else {
+		record[1], record[2], record[12] = amount, asset, value
+	}
+	return record
+}
@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTaxReport(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	other := solana.NewWallet().PublicKey()
+	stake := solana.NewWallet().PublicKey()
+	day := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	transactions := []*Transaction{
+		{Signature: solana.Signature{1}, Amount: 1_500_000_000, From: other, To: owner, Timestamp: day},
+		{Signature: solana.Signature{2}, Amount: 2_000_000_000, From: owner, To: other, Timestamp: day.Add(time.Hour), IsSender: true, Fee: 5_000},
+		{Signature: solana.Signature{3}, Amount: 25_000_000, From: other, To: owner, Timestamp: day.Add(2 * time.Hour), Mint: usdcMint, Symbol: "USDC", Decimals: 6},
+		{Amount: 10_000_000, From: stake, To: stake, Timestamp: day.Add(3 * time.Hour), Reward: true, Epoch: 500},
+	}
+	// The price of the sent transfer's time is unknown.
+	rates := HistoricalRates{day.Unix(): decimal.NewFromInt(20), day.Add(3 * time.Hour).Unix(): decimal.NewFromInt(22)}
+	rows := PriceHistoryRows(HistoryRows(transactions, nil, "EUR", MainnetBeta), rates, "EUR")
+	rows = LabelHistoryRows(rows, map[string]TransactionLabel{solana.Signature{1}.String(): {Text: "Salary", Category: "income"}})
+
+	tests := []struct {
+		format   TaxFormat
+		expected [][]string
+	}{
+		{
+			format: TaxFormatKoinly,
+			expected: [][]string{
+				koinlyColumns,
+				{"2023-06-01 12:00:00 UTC", "", "", "1.5", "SOL", "", "", "30.00", "EUR", "", "Salary", solana.Signature{1}.String()},
+				{"2023-06-01 13:00:00 UTC", "2", "SOL", "", "", "0.000005", "SOL", "", "", "", "", solana.Signature{2}.String()},
+				{"2023-06-01 14:00:00 UTC", "", "", "25", "USDC", "", "", "", "", "", "", solana.Signature{3}.String()},
+				{"2023-06-01 15:00:00 UTC", "", "", "0.01", "SOL", "", "", "0.22", "EUR", "staking", "", ""},
+			},
+		},
+		{
+			format: TaxFormatCoinTracking,
+			expected: [][]string{
+				coinTrackingColumns,
+				{"Deposit", "1.5", "SOL", "", "", "", "", "Solana mainnet-beta", "income", "Salary", "2023-06-01 12:00:00", solana.Signature{1}.String(), "30.00", ""},
+				{"Withdrawal", "", "", "2", "SOL", "0.000005", "SOL", "Solana mainnet-beta", "", "", "2023-06-01 13:00:00", solana.Signature{2}.String(), "", ""},
+				{"Deposit", "25", "USDC", "", "", "", "", "Solana mainnet-beta", "", "", "2023-06-01 14:00:00", solana.Signature{3}.String(), "", ""},
+				{"Staking", "0.01", "SOL", "", "", "", "", "Solana mainnet-beta", "", "", "2023-06-01 15:00:00", "", "0.22", ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, WriteTaxReport(&buf, tt.format, rows))
+
+			records, err := csv.NewReader(&buf).ReadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, records)
+		})
+	}
+}
+
+func TestParseTaxFormat(t *testing.T) {
+	format, err := ParseTaxFormat("Koinly")
+	assert.NoError(t, err)
+	assert.Equal(t, TaxFormatKoinly, format)
+
+	_, err = ParseTaxFormat("turbotax")
+	assert.EqualError(t, err, `unknown tax format "turbotax", expected one of: koinly, cointracking`)
+}
@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phase is a part of a command whose time is measured when timings are on.
+type Phase string
+
+const (
+	PhaseKeystore Phase = "keystore load"
+	PhaseRate     Phase = "rate fetch"
+	PhaseRPC      Phase = "rpc calls"
+	PhaseDecode   Phase = "decode"
+	PhaseRender   Phase = "render"
+)
+
+// Phases lists the phases in the order they are reported.
+var Phases = []Phase{PhaseKeystore, PhaseRate, PhaseRPC, PhaseDecode, PhaseRender}
+
+// timingHint is a rule of the slow-path hints: when Phase took at least Share of the runtime, the
+// report says so and gives Advice.
+type timingHint struct {
+	Phase Phase
+	Share float64
+	// What names the phase in the hint.
+	What   string
+	Advice string
+}
+
+// timingHints are checked in order; every rule that matches adds a hint.
+var timingHints = []timingHint{
+	{Phase: PhaseDecode, Share: 0.4, What: "transaction decoding", Advice: "consider a lower --limit, or --since"},
+	{Phase: PhaseRPC, Share: 0.6, What: "RPC calls", Advice: "consider a faster --rpc-url, or --pin-node to reuse one connection"},
+	{Phase: PhaseRate, Share: 0.4, What: "fetching the exchange rate", Advice: "a rate is reused for a minute, and `send --unit sol` needs none"},
+	{Phase: PhaseKeystore, Share: 0.4, What: "loading the key file", Advice: "the key file is read again for every operation, so keep it on a local disk"},
+}
+
+// phaseTimer is the recorder started with StartTimings, nil while timings are off.
+var phaseTimer atomic.Pointer[PhaseTimer]
+
+// PhaseTimer measures how long each phase of a command took. A phase that runs on several goroutines
+// at once, such as the RPC calls of a history fetch, counts the wall-clock time during which at least
+// one of them was running, so phases never add up to more than the runtime.
+type PhaseTimer struct {
+	now     func() time.Time
+	started time.Time
+
+	mu     sync.Mutex
+	phases map[Phase]*phaseTime
+}
+
+type phaseTime struct {
+	total  time.Duration
+	calls  int
+	active int
+	since  time.Time
+}
+
+func newPhaseTimer(now func() time.Time) *PhaseTimer {
+	return &PhaseTimer{now: now, started: now(), phases: make(map[Phase]*phaseTime)}
+}
+
+// StartTimings switches timings on for the rest of the process and returns the timer the phases are
+// recorded in. Timings are off unless it is called, and TimePhase then costs nothing.
+func StartTimings() *PhaseTimer {
+	timer := newPhaseTimer(time.Now)
+	phaseTimer.Store(timer)
+	return timer
+}
+
+// StopTimings switches timings off again.
+func StopTimings() {
+	phaseTimer.Store(nil)
+}
+
+// TimePhase starts measuring phase and returns the function that stops it. It does nothing while
+// timings are off.
+func TimePhase(phase Phase) func() {
+	timer := phaseTimer.Load()
+	if timer == nil {
+		return func() {}
+	}
+	return timer.start(phase)
+}
+
+func (t *PhaseTimer) start(phase Phase) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.phases[phase]
+	if !ok {
+		p = &phaseTime{}
+		t.phases[phase] = p
+	}
+	p.calls++
+	if p.active == 0 {
+		p.since = t.now()
+	}
+	p.active++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			p.active--
+			if p.active == 0 {
+				p.total += t.now().Sub(p.since)
+			}
+		})
+	}
+}
+
+// PhaseTiming is how long a phase took.
+type PhaseTiming struct {
+	Phase    Phase         `json:"phase"`
+	Duration time.Duration `json:"duration"`
+	// Calls counts how often the phase was entered, e.g. the number of RPC requests.
+	Calls int `json:"calls"`
+	// Share is the part of the runtime the phase took, between 0 and 1.
+	Share float64 `json:"share"`
+}
+
+// TimingReport is what Report returns: the runtime so far, the phases that ran and the hints of the
+// slow paths that dominated.
+type TimingReport struct {
+	Total  time.Duration `json:"total"`
+	Phases []PhaseTiming `json:"phases"`
+	Hints  []string      `json:"hints,omitempty"`
+}
+
+// Report summarises the phases measured since the timer started. Phases still running count up to now.
+func (t *PhaseTimer) Report() TimingReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	report := TimingReport{Total: now.Sub(t.started)}
+	for _, phase := range Phases {
+		p, ok := t.phases[phase]
+		if !ok {
+			continue
+		}
+		duration := p.total
+		if p.active > 0 {
+			duration += now.Sub(p.since)
+		}
+		timing := PhaseTiming{Phase: phase, Duration: duration, Calls: p.calls}
+		if report.Total > 0 {
+			timing.Share = float64(duration) / float64(report.Total)
+		}
+		report.Phases = append(report.Phases, timing)
+	}
+
+	report.Hints = slowPathHints(report.Phases)
+	return report
+}
+
+// slowPathHints applies timingHints to the measured phases, the largest share first.
+func slowPathHints(phases []PhaseTiming) []string {
+	shares := make(map[Phase]float64, len(phases))
+	for _, p := range phases {
+		shares[p.Phase] = p.Share
+	}
+
+	var matched []timingHint
+	for _, rule := range timingHints {
+		if shares[rule.Phase] >= rule.Share {
+			matched = append(matched, rule)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return shares[matched[i].Phase] > shares[matched[j].Phase] })
+
+	hints := make([]string, 0, len(matched))
+	for _, rule := range matched {
+		hints = append(hints, fmt.Sprintf("%s took %.0f%% of runtime; %s", rule.What, shares[rule.Phase]*100, rule.Advice))
+	}
+	return hints
+}
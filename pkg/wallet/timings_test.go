@@ -0,0 +1,188 @@
+package wallet
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestPhaseTimerReport(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	timer := newPhaseTimer(clock.Now)
+
+	stop := timer.start(PhaseKeystore)
+	clock.advance(10 * time.Millisecond)
+	stop()
+
+	// Two overlapping requests count the time during which either was running.
+	first := timer.start(PhaseRPC)
+	clock.advance(5 * time.Millisecond)
+	second := timer.start(PhaseRPC)
+	clock.advance(5 * time.Millisecond)
+	first()
+	clock.advance(5 * time.Millisecond)
+	second()
+	second()
+
+	stop = timer.start(PhaseDecode)
+	clock.advance(75 * time.Millisecond)
+	stop()
+
+	report := timer.Report()
+	assert.Equal(t, 100*time.Millisecond, report.Total)
+	assert.Equal(t, []PhaseTiming{
+		{Phase: PhaseKeystore, Duration: 10 * time.Millisecond, Calls: 1, Share: 0.1},
+		{Phase: PhaseRPC, Duration: 15 * time.Millisecond, Calls: 2, Share: 0.15},
+		{Phase: PhaseDecode, Duration: 75 * time.Millisecond, Calls: 1, Share: 0.75},
+	}, report.Phases)
+	assert.Equal(t, []string{"transaction decoding took 75% of runtime; consider a lower --limit, or --since"}, report.Hints)
+}
+
+func TestSlowPathHints(t *testing.T) {
+	tests := []struct {
+		name     string
+		phases   []PhaseTiming
+		expected []string
+	}{
+		{name: "Nothing Dominates", phases: []PhaseTiming{{Phase: PhaseRPC, Share: 0.3}, {Phase: PhaseDecode, Share: 0.3}}, expected: []string{}},
+		{name: "Render Has No Rule", phases: []PhaseTiming{{Phase: PhaseRender, Share: 0.9}}, expected: []string{}},
+		{
+			name:   "Largest Share First",
+			phases: []PhaseTiming{{Phase: PhaseRate, Share: 0.45}, {Phase: PhaseDecode, Share: 0.5}},
+			expected: []string{
+				"transaction decoding took 50% of runtime; consider a lower --limit, or --since",
+				"fetching the exchange rate took 45% of runtime; a rate is reused for a minute, and `send --unit sol` needs none",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, slowPathHints(tt.phases))
+		})
+	}
+}
+
+// slowRates answers after a delay.
+type slowRates struct{ delay time.Duration }
+
+func (s slowRates) Name() string { return "slow" }
+
+func (s slowRates) SOLRate(context.Context, Currency) (decimal.Decimal, error) {
+	time.Sleep(s.delay)
+	return decimal.NewFromInt(100), nil
+}
+
+// phaseOf returns the timing of phase in report.
+func phaseOf(report TimingReport, phase Phase) (PhaseTiming, bool) {
+	for _, p := range report.Phases {
+		if p.Phase == phase {
+			return p, true
+		}
+	}
+	return PhaseTiming{}, false
+}
+
+func TestTimingsOfCommands(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	const delay = 30 * time.Millisecond
+	owner := solana.NewWallet()
+	other := solana.NewWallet().PublicKey()
+	signatures := []*rpc.TransactionSignature{{Signature: solana.Signature{1}}, {Signature: solana.Signature{2}}, {Signature: solana.Signature{3}}}
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(context.Context, solana.PublicKey, rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				return &rpc.GetBalanceResult{Value: solana.LAMPORTS_PER_SOL}, nil
+			},
+			GetSignaturesForAddressWithOptsFn: func(context.Context, solana.PublicKey, *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+				return signatures, nil
+			},
+			GetTokenAccountsByOwnerFn: func(context.Context, solana.PublicKey, *rpc.GetTokenAccountsConfig, *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+				return &rpc.GetTokenAccountsResult{}, nil
+			},
+			GetTransactionFn: func(context.Context, solana.Signature, *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+				time.Sleep(delay)
+				return transferResultTo(t, owner.PublicKey(), other, owner.PublicKey()), nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		run      func(wc *WalletConfig) error
+		expected []Phase
+		minimum  map[Phase]time.Duration
+		hint     string
+	}{
+		{
+			name: "Transactions",
+			run: func(wc *WalletConfig) error {
+				_, err := wc.GetTransactionHistoryWithOpts(GetTransactionHistoryOpts{Limit: 3})
+				return err
+			},
+			expected: []Phase{PhaseKeystore, PhaseRPC, PhaseDecode},
+			minimum:  map[Phase]time.Duration{PhaseRPC: delay},
+			hint:     "RPC calls took",
+		},
+		{
+			name: "Balance",
+			run: func(wc *WalletConfig) error {
+				_, err := wc.GetWalletBalance("")
+				return err
+			},
+			expected: []Phase{PhaseKeystore, PhaseRate, PhaseRPC},
+			minimum:  map[Phase]time.Duration{PhaseRate: delay},
+			hint:     "fetching the exchange rate took",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			keyOps := &KeyOps{FileReader: files, FileWriter: files}
+			assert.NoError(t, keyOps.WriteKeyToFile("main", []byte(owner.PrivateKey), owner.PublicKey().String()))
+			wc := &WalletConfig{KeyOps: keyOps, Network: Devnet, Rates: slowRates{delay: delay}}
+
+			timer := StartTimings()
+			defer StopTimings()
+			assert.NoError(t, tt.run(wc))
+			report := timer.Report()
+
+			var phases []Phase
+			for _, p := range report.Phases {
+				phases = append(phases, p.Phase)
+				assert.LessOrEqual(t, p.Duration, report.Total)
+			}
+			assert.Equal(t, tt.expected, phases)
+			for phase, minimum := range tt.minimum {
+				timing, _ := phaseOf(report, phase)
+				assert.GreaterOrEqual(t, timing.Duration, minimum, phase)
+			}
+			if assert.NotEmpty(t, report.Hints) {
+				assert.True(t, strings.HasPrefix(report.Hints[0], tt.hint), report.Hints[0])
+			}
+		})
+	}
+}
+
+func TestTimePhaseIsOffByDefault(t *testing.T) {
+	StopTimings()
+	stop := TimePhase(PhaseRPC)
+	stop()
+	assert.Nil(t, phaseTimer.Load())
+}
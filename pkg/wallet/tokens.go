@@ -0,0 +1,489 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// tokenAccountSize is the size of an SPL token account, which determines its rent-exempt minimum.
+const tokenAccountSize = 165
+
+// KnownToken describes a mint from the built-in token list.
+type KnownToken struct {
+	Symbol   string
+	Decimals uint8
+}
+
+// knownTokens maps the mints of major SPL tokens to their symbols.
+var knownTokens = map[string]KnownToken{
+	"EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v": {Symbol: "USDC", Decimals: 6},
+	"Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB": {Symbol: "USDT", Decimals: 6},
+	"So11111111111111111111111111111111111111112":  {Symbol: "wSOL", Decimals: 9},
+	"mSoLzYCxHdYgdzU16g5QSh3i5K3z3KZK7ytfqcJm7So":  {Symbol: "mSOL", Decimals: 9},
+	"DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263": {Symbol: "BONK", Decimals: 5},
+	"JUPyiwrYJFskUPiHa7hkeR8VUtAeFoSYbKedZNsDvCN":  {Symbol: "JUP", Decimals: 6},
+	"4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU": {Symbol: "USDC-Dev", Decimals: 6},
+}
+
+var ErrTokenAccountNotFound = errors.New("no token account for this mint")
+
+// TokenSymbol returns the symbol of a known mint, or a shortened mint address otherwise.
+func TokenSymbol(mint string) string {
+	if known, ok := knownTokens[mint]; ok {
+		return known.Symbol
+	}
+	if len(mint) > 8 {
+		return mint[:4] + "…" + mint[len(mint)-4:]
+	}
+	return mint
+}
+
+// ResolveMint accepts either a mint address or the symbol of a known token.
+func ResolveMint(mintOrSymbol string) (solana.PublicKey, error) {
+	for mint, known := range knownTokens {
+		if strings.EqualFold(known.Symbol, mintOrSymbol) {
+			return solana.MustPublicKeyFromBase58(mint), nil
+		}
+	}
+
+	mint, err := solana.PublicKeyFromBase58(mintOrSymbol)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("%q is neither a known token symbol nor a mint address", mintOrSymbol)
+	}
+
+	return mint, nil
+}
+
+// TokenBalance is the balance of one SPL token account.
+type TokenBalance struct {
+	Mint      string
+	Symbol    string
+	Account   string
+	RawAmount uint64
+	Decimals  uint8
+}
+
+// Amount returns the balance in whole tokens.
+func (b TokenBalance) Amount() decimal.Decimal {
+	return decimal.NewFromInt(int64(b.RawAmount)).Shift(-int32(b.Decimals))
+}
+
+// GetTokenBalances lists the SPL token accounts owned by a wallet. An empty alias means the active wallet.
+func (w *WalletConfig) GetTokenBalances(alias string) ([]TokenBalance, error) {
+	owner, err := w.ownerPublicKey(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenBalances(context.TODO(), endpoints.client(), owner)
+}
+
+// tokenBalances lists the SPL token accounts owned by owner, sorted by symbol and account.
+func tokenBalances(ctx context.Context, client ClientInterface, owner solana.PublicKey) ([]TokenBalance, error) {
+	programID := solana.TokenProgramID
+	accounts, err := client.GetTokenAccountsByOwner(ctx, owner,
+		&rpc.GetTokenAccountsConfig{ProgramId: &programID},
+		&rpc.GetTokenAccountsOpts{Encoding: solana.EncodingBase64},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch token accounts: %w", err)
+	}
+
+	tokenAccounts := make([]token.Account, len(accounts.Value))
+	mints := make([]solana.PublicKey, 0, len(accounts.Value))
+	for i, account := range accounts.Value {
+		if err := tokenAccounts[i].UnmarshalWithDecoder(bin.NewBinDecoder(account.Account.Data.GetBinary())); err != nil {
+			return nil, fmt.Errorf("decode token account %s: %w", account.Pubkey, err)
+		}
+		mints = append(mints, tokenAccounts[i].Mint)
+	}
+
+	decimals, err := mintsDecimals(ctx, client, mints)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]TokenBalance, 0, len(accounts.Value))
+	for i, account := range accounts.Value {
+		balances = append(balances, TokenBalance{
+			Mint:      tokenAccounts[i].Mint.String(),
+			Symbol:    TokenSymbol(tokenAccounts[i].Mint.String()),
+			Account:   account.Pubkey.String(),
+			RawAmount: tokenAccounts[i].Amount,
+			Decimals:  decimals[tokenAccounts[i].Mint],
+		})
+	}
+
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].Symbol != balances[j].Symbol {
+			return balances[i].Symbol < balances[j].Symbol
+		}
+		return balances[i].Account < balances[j].Account
+	})
+
+	return balances, nil
+}
+
+// ownerPublicKey returns the public key of a stored wallet, or of the active wallet when alias is empty.
+func (w *WalletConfig) ownerPublicKey(alias string) (solana.PublicKey, error) {
+	if alias == "" {
+		return w.currentPublicKey()
+	}
+
+	publicKey, err := w.KeyOps.GetPublicKeyByAlias(alias)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+
+	return solana.PublicKeyFromBase58(publicKey)
+}
+
+// mintsDecimals returns the decimals of mints, using the built-in list before asking the cluster. The
+// mints it does not know are read in one round trip.
+func mintsDecimals(ctx context.Context, client ClientInterface, mints []solana.PublicKey) (map[solana.PublicKey]uint8, error) {
+	decimals := make(map[solana.PublicKey]uint8, len(mints))
+	infos := make(map[solana.PublicKey]*rpc.GetAccountInfoResult)
+	var unknown []solana.PublicKey
+	var calls []*batchCall
+	for _, mint := range mints {
+		if known, ok := knownTokens[mint.String()]; ok {
+			decimals[mint] = known.Decimals
+			continue
+		}
+		if _, ok := infos[mint]; ok {
+			continue
+		}
+		infos[mint] = &rpc.GetAccountInfoResult{}
+		unknown = append(unknown, mint)
+		calls = append(calls, getAccountInfoCall(infos[mint], mint))
+	}
+
+	batchRead(ctx, client, calls...)
+	for i, mint := range unknown {
+		var err error
+		if decimals[mint], err = decodeMintDecimals(mint, infos[mint], calls[i].err); err != nil {
+			return nil, err
+		}
+	}
+	return decimals, nil
+}
+
+// decodeMintDecimals returns the decimals of a mint from its account, as read with err.
+func decodeMintDecimals(mint solana.PublicKey, info *rpc.GetAccountInfoResult, err error) (uint8, error) {
+	if errors.Is(err, rpc.ErrNotFound) {
+		return 0, fmt.Errorf("mint %s not found", mint)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get mint %s: %w", mint, err)
+	}
+
+	var mintAccount token.Mint
+	if err := mintAccount.UnmarshalWithDecoder(bin.NewBinDecoder(info.Value.Data.GetBinary())); err != nil {
+		return 0, fmt.Errorf("decode mint %s: %w", mint, err)
+	}
+
+	return mintAccount.Decimals, nil
+}
+
+// decodeTokenAccountBalance returns the amount held by a token account from its account, as read with
+// err, or ErrTokenAccountNotFound if it does not exist.
+func decodeTokenAccountBalance(account solana.PublicKey, info *rpc.GetAccountInfoResult, err error) (uint64, error) {
+	if errors.Is(err, rpc.ErrNotFound) {
+		return 0, ErrTokenAccountNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get token account %s: %w", account, err)
+	}
+
+	var tokenAccount token.Account
+	if err := tokenAccount.UnmarshalWithDecoder(bin.NewBinDecoder(info.Value.Data.GetBinary())); err != nil {
+		return 0, fmt.Errorf("decode token account %s: %w", account, err)
+	}
+
+	return tokenAccount.Amount, nil
+}
+
+// TokenQuote describes a checked SPL token transfer before it is signed.
+type TokenQuote struct {
+	Mint      solana.PublicKey
+	Symbol    string
+	Decimals  uint8
+	RawAmount uint64
+	Owner     solana.PublicKey
+	Recipient solana.PublicKey
+	// Payer pays the fee and any rent. It is Owner, unless the tokens are held by a multisig account.
+	Payer solana.PublicKey
+	// Signers are the multisig signers approving the transfer when Owner is a multisig account.
+	Signers []solana.PublicKey
+	Source  solana.PublicKey
+	// Destination is the recipient's associated token account.
+	Destination solana.PublicKey
+	// CreateAccount is true when the recipient has no associated token account yet. The sender then
+	// pays Rent lamports to create it.
+	CreateAccount bool
+	Rent          uint64
+	Fee           uint64
+}
+
+// Amount returns the amount to send in whole tokens.
+func (q *TokenQuote) Amount() decimal.Decimal {
+	return decimal.NewFromInt(int64(q.RawAmount)).Shift(-int32(q.Decimals))
+}
+
+// SendToken sends an amount of an SPL token to the recipient's associated token account,
+// creating that account first when it does not exist.
+func (w *WalletConfig) SendToken(ctx context.Context, mint, amount, recipient string) (string, error) {
+	quote, err := w.PrepareTokenSend(ctx, mint, amount, recipient)
+	if err != nil {
+		return "", err
+	}
+
+	return w.ExecuteTokenSend(ctx, quote)
+}
+
+// PrepareTokenSend resolves the token accounts involved in a transfer and checks that the sender
+// holds enough tokens and enough SOL for the fee and, if needed, the recipient's account rent.
+func (w *WalletConfig) PrepareTokenSend(ctx context.Context, mint, amount, recipient string) (*TokenQuote, error) {
+	owner, err := w.currentPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkCanSign(); err != nil {
+		return nil, err
+	}
+
+	return w.prepareTokenSend(ctx, mint, amount, recipient, owner, owner)
+}
+
+// prepareTokenSend quotes a transfer of tokens held by owner, whose fee and rent are paid by payer.
+func (w *WalletConfig) prepareTokenSend(ctx context.Context, mint, amount, recipient string, owner, payer solana.PublicKey) (*TokenQuote, error) {
+	mintKey, err := ResolveMint(mint)
+	if err != nil {
+		return nil, err
+	}
+
+	recipientKey, err := solana.PublicKeyFromBase58(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidRecipient, recipient, err)
+	}
+	if err := w.checkRecipientAllowed(recipientKey); err != nil {
+		return nil, err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	source, _, err := solana.FindAssociatedTokenAddress(owner, mintKey)
+	if err != nil {
+		return nil, err
+	}
+
+	destination, _, err := solana.FindAssociatedTokenAddress(recipientKey, mintKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything the quote needs from the cluster is independent, so it is read in one round trip.
+	var (
+		sourceInfo, destinationInfo, mintInfo rpc.GetAccountInfoResult
+		balance                               rpc.GetBalanceResult
+		rent                                  uint64
+		recent                                rpc.GetLatestBlockhashResult
+	)
+	sourceCall := getAccountInfoCall(&sourceInfo, source)
+	destinationCall := getAccountInfoCall(&destinationInfo, destination)
+	balanceCall := getBalanceCall(&balance, payer, rpc.CommitmentConfirmed)
+	rentCall := getRentCall(&rent, tokenAccountSize, rpc.CommitmentConfirmed)
+	rentCall.speculative = true
+	blockhashCall := getLatestBlockhashCall(&recent, rpc.CommitmentFinalized)
+	calls := []*batchCall{sourceCall, destinationCall, balanceCall, rentCall, blockhashCall}
+	known, isKnown := knownTokens[mintKey.String()]
+	mintCall := getAccountInfoCall(&mintInfo, mintKey)
+	if !isKnown {
+		calls = append(calls, mintCall)
+	}
+	batchRead(ctx, client, calls...)
+
+	decimals := known.Decimals
+	if !isKnown {
+		decimals, err = decodeMintDecimals(mintKey, &mintInfo, mintCall.err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rawAmount, err := tokenAmountToRaw(amount, decimals)
+	if err != nil {
+		return nil, err
+	}
+
+	holder, hold, have := "you", "hold", "have"
+	if owner != payer {
+		holder, hold, have = "the multisig "+owner.String(), "holds", "has"
+	}
+	held, err := decodeTokenAccountBalance(source, &sourceInfo, sourceCall.err)
+	if errors.Is(err, ErrTokenAccountNotFound) {
+		return nil, fmt.Errorf("%w: %s %s no %s", ErrTokenAccountNotFound, holder, hold, TokenSymbol(mintKey.String()))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rawAmount > held {
+		return nil, fmt.Errorf("%w: %s %s %s %s but tried to send %s",
+			ErrInsufficientFunds, holder, have,
+			decimal.NewFromInt(int64(held)).Shift(-int32(decimals)),
+			TokenSymbol(mintKey.String()),
+			decimal.NewFromInt(int64(rawAmount)).Shift(-int32(decimals)))
+	}
+
+	quote := &TokenQuote{
+		Mint:        mintKey,
+		Symbol:      TokenSymbol(mintKey.String()),
+		Decimals:    decimals,
+		RawAmount:   rawAmount,
+		Owner:       owner,
+		Payer:       payer,
+		Recipient:   recipientKey,
+		Source:      source,
+		Destination: destination,
+	}
+
+	if _, err := decodeTokenAccountBalance(destination, &destinationInfo, destinationCall.err); errors.Is(err, ErrTokenAccountNotFound) {
+		quote.CreateAccount = true
+		if err := rentCall.ensure(ctx, client); err != nil {
+			return nil, fmt.Errorf("failed to fetch rent for the recipient token account: %w", err)
+		}
+		quote.Rent = rent
+	} else if err != nil {
+		return nil, err
+	}
+
+	if blockhashCall.err == nil {
+		quote.Fee = estimateFeeAt(ctx, client, recent.Value.Blockhash, tokenTransferInstructions(quote), payer, 0)
+	} else {
+		quote.Fee = lamportsPerSignature
+	}
+
+	if balanceCall.err != nil {
+		return nil, fmt.Errorf("failed to fetch balance: %w", balanceCall.err)
+	}
+
+	if need := quote.Fee + quote.Rent; balance.Value < need {
+		return nil, fmt.Errorf("%w: you have %s SOL but need %s SOL for the fee and token account rent",
+			ErrInsufficientFunds, LamportsToSOL(balance.Value), LamportsToSOL(need))
+	}
+
+	return quote, nil
+}
+
+// ExecuteTokenSend signs and submits a prepared token transfer and waits for it to be confirmed.
+func (w *WalletConfig) ExecuteTokenSend(ctx context.Context, quote *TokenQuote) (string, error) {
+	owner, err := w.currentPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	if err := w.checkRecipientAllowed(quote.Recipient); err != nil {
+		return "", err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return "", err
+	}
+	client := endpoints.client()
+
+	recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch blockhash: %w", err)
+	}
+
+	tx, err := solana.NewTransaction(tokenTransferInstructions(quote), recent.Value.Blockhash, solana.TransactionPayer(owner.PublicKey()))
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if owner.PublicKey().Equals(key) {
+			return &owner
+		}
+		return nil
+	}); err != nil {
+		return "", fmt.Errorf("unable to sign transaction: %w", err)
+	}
+	w.recordKeyUsage(owner.PublicKey(), SignedSend, tx.Signatures[0])
+
+	sig, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		return "", fmt.Errorf("failed to submit token transfer: %w", err)
+	}
+
+	if err := awaitSignature(ctx, client, sig); err != nil {
+		return "", err
+	}
+
+	return sig.String(), nil
+}
+
+// tokenTransferInstructions creates the recipient's associated token account when needed and transfers the tokens.
+func tokenTransferInstructions(quote *TokenQuote) []solana.Instruction {
+	var instructions []solana.Instruction
+	if quote.CreateAccount {
+		instructions = append(instructions, associatedtokenaccount.NewCreateInstruction(
+			quote.Payer,
+			quote.Recipient,
+			quote.Mint,
+		).Build())
+	}
+
+	return append(instructions, token.NewTransferCheckedInstruction(
+		quote.RawAmount,
+		quote.Decimals,
+		quote.Source,
+		quote.Mint,
+		quote.Destination,
+		quote.Owner,
+		quote.Signers,
+	).Build())
+}
+
+// tokenAmountToRaw converts a token amount to base units, rejecting amounts finer than the mint allows.
+func tokenAmountToRaw(amount string, decimals uint8) (uint64, error) {
+	value, err := decimal.NewFromString(strings.TrimSpace(amount))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a number", ErrInvalidAmount, amount)
+	}
+
+	if !value.IsPositive() {
+		return 0, fmt.Errorf("%w: amount must be greater than 0", ErrInvalidAmount)
+	}
+
+	if value.Exponent() < -int32(decimals) {
+		return 0, fmt.Errorf("%w: this token has at most %d decimal places", ErrInvalidAmount, decimals)
+	}
+
+	raw := value.Shift(int32(decimals)).BigInt()
+	if !raw.IsUint64() {
+		return 0, fmt.Errorf("%w: amount is too large", ErrInvalidAmount)
+	}
+
+	return raw.Uint64(), nil
+}
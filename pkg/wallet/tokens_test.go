@@ -0,0 +1,144 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+var usdcMint = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+
+// encodeTokenAccount returns the account data of a token account holding amount of mint.
+func encodeTokenAccount(t *testing.T, mint, owner solana.PublicKey, amount uint64) *rpc.DataBytesOrJSON {
+	var buf bytes.Buffer
+	account := token.Account{Mint: mint, Owner: owner, Amount: amount, State: token.Initialized}
+	assert.NoError(t, account.MarshalWithEncoder(bin.NewBinEncoder(&buf)))
+	return rpc.DataBytesOrJSONFromBytes(buf.Bytes())
+}
+
+func TestGetTokenBalances(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	owner := solana.NewWallet()
+	account := solana.NewWallet().PublicKey()
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetTokenAccountsByOwnerFn: func(ctx context.Context, o solana.PublicKey, conf *rpc.GetTokenAccountsConfig, _ *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+				assert.Equal(t, owner.PublicKey(), o)
+				assert.Equal(t, solana.TokenProgramID, *conf.ProgramId)
+				return &rpc.GetTokenAccountsResult{Value: []*rpc.TokenAccount{{
+					Pubkey:  account,
+					Account: rpc.Account{Data: encodeTokenAccount(t, usdcMint, o, 12_345_678)},
+				}}}, nil
+			},
+		}
+	}
+
+	wc := &WalletConfig{Wallet: owner, Network: Devnet}
+	balances, err := wc.GetTokenBalances("")
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, "USDC", balances[0].Symbol)
+	assert.Equal(t, uint8(6), balances[0].Decimals)
+	assert.Equal(t, "12.345678", balances[0].Amount().String())
+	assert.Equal(t, account.String(), balances[0].Account)
+}
+
+func TestPrepareTokenSend(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	sender := solana.NewWallet()
+	recipient := solana.NewWallet().PublicKey()
+	source, _, _ := solana.FindAssociatedTokenAddress(sender.PublicKey(), usdcMint)
+	fee := uint64(5000)
+	rent := uint64(2_039_280)
+
+	tests := []struct {
+		name            string
+		amount          string
+		held            uint64
+		recipientHasATA bool
+		solBalance      uint64
+		expectedRaw     uint64
+		expectCreate    bool
+		expectedErr     error
+		expectAnyErr    bool
+	}{
+		{name: "Recipient Has Account", amount: "1.5", held: 2_000_000, recipientHasATA: true, solBalance: 1_000_000, expectedRaw: 1_500_000},
+		{name: "Recipient Without Account Pays Rent", amount: "1", held: 2_000_000, solBalance: 1_000_000_000, expectedRaw: 1_000_000, expectCreate: true},
+		{name: "Full Balance", amount: "2", held: 2_000_000, recipientHasATA: true, solBalance: 1_000_000, expectedRaw: 2_000_000},
+		{name: "More Than Held", amount: "2.000001", held: 2_000_000, recipientHasATA: true, solBalance: 1_000_000, expectedErr: ErrInsufficientFunds},
+		{name: "Not Enough SOL For Rent", amount: "1", held: 2_000_000, solBalance: 1_000_000, expectedErr: ErrInsufficientFunds},
+		{name: "Too Many Decimals", amount: "0.0000001", held: 2_000_000, recipientHasATA: true, solBalance: 1_000_000, expectedErr: ErrInvalidAmount},
+		{name: "No Source Account", amount: "1", recipientHasATA: true, solBalance: 1_000_000, expectedErr: ErrTokenAccountNotFound},
+		{name: "Invalid Recipient", amount: "1", held: 2_000_000, expectAnyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newRPCClient = func(string) ClientInterface {
+				return &MockClientInterface{
+					GetAccountInfoFn: func(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+						if account.Equals(source) && tt.held > 0 {
+							return &rpc.GetAccountInfoResult{Value: &rpc.Account{Data: encodeTokenAccount(t, usdcMint, sender.PublicKey(), tt.held)}}, nil
+						}
+						if !account.Equals(source) && tt.recipientHasATA {
+							return &rpc.GetAccountInfoResult{Value: &rpc.Account{Data: encodeTokenAccount(t, usdcMint, recipient, 0)}}, nil
+						}
+						return nil, rpc.ErrNotFound
+					},
+					GetMinimumBalanceForRentExemptionFn: func(ctx context.Context, dataSize uint64, _ rpc.CommitmentType) (uint64, error) {
+						assert.Equal(t, uint64(tokenAccountSize), dataSize)
+						return rent, nil
+					},
+					GetBalanceFn: func(ctx context.Context, publicKey solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+						return &rpc.GetBalanceResult{Value: tt.solBalance}, nil
+					},
+					GetLatestBlockhashFn: func(ctx context.Context, _ rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+						return &rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}}}, nil
+					},
+					GetFeeForMessageFn: func(ctx context.Context, message string, _ rpc.CommitmentType) (*rpc.GetFeeForMessageResult, error) {
+						return &rpc.GetFeeForMessageResult{Value: &fee}, nil
+					},
+				}
+			}
+
+			to := recipient.String()
+			if tt.expectAnyErr {
+				to = "not-an-address"
+			}
+
+			wc := &WalletConfig{Wallet: sender, Network: Devnet}
+			quote, err := wc.PrepareTokenSend(context.Background(), "usdc", tt.amount, to)
+
+			if tt.expectAnyErr {
+				assert.Error(t, err)
+				return
+			}
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRaw, quote.RawAmount)
+			assert.Equal(t, tt.expectCreate, quote.CreateAccount)
+			assert.Equal(t, source, quote.Source)
+			if tt.expectCreate {
+				assert.Equal(t, rent, quote.Rent)
+				assert.Len(t, tokenTransferInstructions(quote), 2)
+			} else {
+				assert.Zero(t, quote.Rent)
+				assert.Len(t, tokenTransferInstructions(quote), 1)
+			}
+		})
+	}
+}
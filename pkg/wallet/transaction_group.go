@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// TransactionGroup is one on-chain transaction together with the transfers into or out of the wallet
+// it contains. Transfers share the signature, slot and fee of the group, so the fee is counted once
+// however many transfers there are.
+type TransactionGroup struct {
+	Signature solana.Signature
+	Slot      uint64
+	Timestamp time.Time
+	// Fee is the fee in lamports when the wallet paid it.
+	Fee uint64
+	// Memo is the memo recorded with the transaction, empty without one.
+	Memo      string
+	Transfers []*Transaction
+}
+
+// GroupTransactions groups transfers by signature, newest first. Transfers keep the order they were
+// decoded in within their group. Staking rewards, which have no signature, each form a group of their own.
+func GroupTransactions(transactions []*Transaction) []*TransactionGroup {
+	var groups []*TransactionGroup
+	bySignature := make(map[solana.Signature]*TransactionGroup)
+	for _, tx := range transactions {
+		if tx.Reward {
+			groups = append(groups, &TransactionGroup{Slot: tx.Slot, Timestamp: tx.Timestamp, Transfers: []*Transaction{tx}})
+			continue
+		}
+		group, ok := bySignature[tx.Signature]
+		if !ok {
+			group = &TransactionGroup{Signature: tx.Signature, Slot: tx.Slot, Timestamp: tx.Timestamp, Fee: tx.Fee, Memo: tx.Memo}
+			bySignature[tx.Signature] = group
+			groups = append(groups, group)
+		}
+		group.Transfers = append(group.Transfers, tx)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if !groups[i].Timestamp.Equal(groups[j].Timestamp) {
+			return groups[i].Timestamp.After(groups[j].Timestamp)
+		}
+		if groups[i].Slot != groups[j].Slot {
+			return groups[i].Slot > groups[j].Slot
+		}
+		return groups[i].Signature.String() < groups[j].Signature.String()
+	})
+	return groups
+}
+
+// NetLamports is how much SOL the transaction moved into the wallet, negative when it moved SOL out,
+// including the fee. A transfer from the wallet to itself only costs the fee.
+func (g *TransactionGroup) NetLamports() int64 {
+	return SummarizeHistory([]*TransactionGroup{g}).Net()
+}
+
+// HistorySummary totals the SOL moved by a transaction history. Fees are counted once per transaction.
+type HistorySummary struct {
+	Transactions int
+	Transfers    int
+	// Received and Sent are in lamports and leave out token transfers.
+	Received uint64
+	Sent     uint64
+	Fees     uint64
+	// Rewards are the staking rewards in lamports. They stay in the stake accounts, so they are not
+	// part of Received or of the net change of the wallet's balance.
+	Rewards uint64
+}
+
+// Net is the change of the SOL balance over the history, in lamports.
+func (s HistorySummary) Net() int64 {
+	return int64(s.Received) - int64(s.Sent) - int64(s.Fees)
+}
+
+// SummarizeHistory totals the SOL moved by groups.
+func SummarizeHistory(groups []*TransactionGroup) HistorySummary {
+	var summary HistorySummary
+	for _, group := range groups {
+		if len(group.Transfers) == 1 && group.Transfers[0].Reward {
+			summary.Rewards += group.Transfers[0].Amount
+			continue
+		}
+		summary.Transactions++
+		summary.Transfers += len(group.Transfers)
+		summary.Fees += group.Fee
+		for _, tx := range group.Transfers {
+			if tx.IsToken() {
+				continue
+			}
+			if tx.IsSender {
+				summary.Sent += tx.Amount
+			}
+			if !tx.IsSender || tx.From.Equals(tx.To) {
+				summary.Received += tx.Amount
+			}
+		}
+	}
+	return summary
+}
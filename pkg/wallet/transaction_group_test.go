@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// multiTransferResult returns a getTransaction response for a payout the wallet signs: SOL to two
+// recipients, USDC to a third, and a transfer between two other parties in the same transaction.
+func multiTransferResult(t *testing.T, owner, alice, bob, carol solana.PublicKey) *rpc.GetTransactionResult {
+	ownerATA, _, _ := solana.FindAssociatedTokenAddress(owner, usdcMint)
+	carolATA, _, _ := solana.FindAssociatedTokenAddress(carol, usdcMint)
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(2_000_000_000, owner, alice).Build(),
+		system.NewTransferInstruction(500_000_000, owner, bob).Build(),
+		token.NewTransferInstruction(10_000_000, ownerATA, carolATA, owner, nil).Build(),
+		system.NewTransferInstruction(7, alice, bob).Build(),
+	}, solana.Hash{1}, solana.TransactionPayer(owner))
+	assert.NoError(t, err)
+
+	raw, err := tx.MarshalBinary()
+	assert.NoError(t, err)
+	envelope, err := json.Marshal([]string{base64.StdEncoding.EncodeToString(raw), "base64"})
+	assert.NoError(t, err)
+
+	result := &rpc.GetTransactionResult{Slot: 42, Transaction: &rpc.TransactionResultEnvelope{}}
+	assert.NoError(t, json.Unmarshal(envelope, result.Transaction))
+	blockTime := solana.UnixTimeSeconds(1_700_000_000)
+	result.BlockTime = &blockTime
+	result.Meta = &rpc.TransactionMeta{
+		Fee: 5_000,
+		PreTokenBalances: []rpc.TokenBalance{
+			tokenBalanceFor(t, tx, ownerATA, owner, usdcMint),
+			tokenBalanceFor(t, tx, carolATA, carol, usdcMint),
+		},
+	}
+	return result
+}
+
+func TestGroupMultiTransferTransaction(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	alice := solana.NewWallet().PublicKey()
+	bob := solana.NewWallet().PublicKey()
+	carol := solana.NewWallet().PublicKey()
+
+	client := &MockClientInterface{
+		GetTransactionFn: func(ctx context.Context, sig solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+			if sig == (solana.Signature{1}) {
+				return multiTransferResult(t, owner, alice, bob, carol), nil
+			}
+			result := transferResultTo(t, alice, owner, alice)
+			result.Slot = 41
+			result.Meta = &rpc.TransactionMeta{Fee: 5_000}
+			return result, nil
+		},
+	}
+
+	payout, err := fetchSingleTransaction(context.Background(), client, solana.Signature{1}, owner.String())
+	assert.NoError(t, err)
+	// The transfer between alice and bob does not involve the wallet.
+	assert.Len(t, payout, 3)
+
+	deposit, err := fetchSingleTransaction(context.Background(), client, solana.Signature{2}, owner.String())
+	assert.NoError(t, err)
+
+	groups := GroupTransactions(append(deposit, payout...))
+	if !assert.Len(t, groups, 2) {
+		return
+	}
+
+	group := groups[0]
+	assert.Equal(t, solana.Signature{1}, group.Signature)
+	assert.Equal(t, uint64(42), group.Slot)
+	assert.Equal(t, time.Unix(1_700_000_000, 0), group.Timestamp)
+	assert.Equal(t, uint64(5_000), group.Fee)
+	if assert.Len(t, group.Transfers, 3) {
+		assert.Equal(t, alice, group.Transfers[0].To)
+		assert.Equal(t, bob, group.Transfers[1].To)
+		assert.Equal(t, "USDC", group.Transfers[2].Symbol)
+	}
+	assert.Equal(t, int64(-2_500_005_000), group.NetLamports())
+
+	assert.Equal(t, solana.Signature{2}, groups[1].Signature)
+	assert.Equal(t, uint64(0), groups[1].Fee, "the sender paid the fee of the deposit")
+	assert.Equal(t, int64(1_000), groups[1].NetLamports())
+
+	summary := SummarizeHistory(groups)
+	assert.Equal(t, HistorySummary{Transactions: 2, Transfers: 4, Received: 1_000, Sent: 2_500_000_000, Fees: 5_000}, summary)
+	assert.Equal(t, int64(1_000-2_500_000_000-5_000), summary.Net())
+}
+
+func TestSummarizeSelfTransfer(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	groups := GroupTransactions([]*Transaction{
+		{Signature: solana.Signature{1}, Amount: 1_000, From: owner, To: owner, IsSender: true, Fee: 5_000},
+	})
+
+	assert.Equal(t, int64(-5_000), groups[0].NetLamports())
+}
@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// SigningKind is what a key signed.
+type SigningKind string
+
+const (
+	SignedSend    SigningKind = "send"
+	SignedMessage SigningKind = "message"
+)
+
+// KeyUsage counts how often a stored key has signed and when it last did, for security reviews.
+type KeyUsage struct {
+	SendsSigned    uint64    `json:"sendsSigned"`
+	MessagesSigned uint64    `json:"messagesSigned"`
+	LastSignature  string    `json:"lastSignature,omitempty"`
+	LastUsed       time.Time `json:"lastUsed,omitempty"`
+}
+
+func (u KeyUsage) String() string {
+	if u.LastUsed.IsZero() {
+		return "never used"
+	}
+	return fmt.Sprintf("%d sends, %d messages signed, last %s at %s",
+		u.SendsSigned, u.MessagesSigned, u.LastSignature, u.LastUsed.Format(time.RFC3339))
+}
+
+// RecordKeyUsage counts a signature made by the stored key with the given public key. Keys that are not
+// in the key file, such as one passed with --key, are not tracked. Concurrent calls are serialised so
+// no count is lost.
+func (k *KeyOps) RecordKeyUsage(publicKey string, kind SigningKind, signature string) error {
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return err
+	}
+
+	for alias, wallet := range data.Wallets {
+		if wallet.PublicKey != publicKey {
+			continue
+		}
+
+		usage := *wallet.Usage
+		switch kind {
+		case SignedSend:
+			usage.SendsSigned++
+		case SignedMessage:
+			usage.MessagesSigned++
+		default:
+			return fmt.Errorf("unknown signing kind %q", kind)
+		}
+		usage.LastSignature = signature
+		usage.LastUsed = time.Now().UTC()
+
+		wallet.Usage = &usage
+		data.Wallets[alias] = wallet
+		return k.writeWalletData("sign", data)
+	}
+
+	return nil
+}
+
+// recordKeyUsage counts a signature made by the wallet's key. The signature has already been made,
+// so a failure to record it does not fail the operation.
+func (w *WalletConfig) recordKeyUsage(signer solana.PublicKey, kind SigningKind, signature solana.Signature) {
+	keyOps, ok := w.KeyOps.(*KeyOps)
+	if !ok {
+		return
+	}
+	_ = keyOps.RecordKeyUsage(signer.String(), kind, signature.String())
+}
@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newUsageKeyOps(t *testing.T) *KeyOps {
+	t.Helper()
+
+	dir := t.TempDir()
+	keyOps := &KeyOps{
+		FileReader: &IOUtilFileReader{},
+		FileWriter: &IOUtilFileWriter{},
+		Dir:        dir,
+		Audit:      &AuditLog{FileReader: &IOUtilFileReader{}, FileWriter: &IOUtilFileWriter{}, Dir: dir},
+	}
+	assert.NoError(t, keyOps.FileWriter.WriteFile(keyOps.keyFilePath(), jsonMarshal(t, WalletData{
+		ActiveAlias: "main",
+		Wallets: map[string]Wallet{
+			"main":    {PublicKey: "MainAddr", PrivateKey: "secret-1"},
+			"savings": {PublicKey: "SavingsAddr", PrivateKey: "secret-2"},
+		},
+	})))
+
+	return keyOps
+}
+
+func TestRecordKeyUsage(t *testing.T) {
+	keyOps := newUsageKeyOps(t)
+
+	info, err := keyOps.GetWalletInfo("main")
+	assert.NoError(t, err)
+	assert.Equal(t, "never used", info.Usage.String())
+
+	assert.NoError(t, keyOps.RecordKeyUsage("MainAddr", SignedSend, "sig-1"))
+	assert.NoError(t, keyOps.RecordKeyUsage("MainAddr", SignedMessage, "sig-2"))
+
+	info, err = keyOps.GetWalletInfo("main")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), info.Usage.SendsSigned)
+	assert.Equal(t, uint64(1), info.Usage.MessagesSigned)
+	assert.Equal(t, "sig-2", info.Usage.LastSignature)
+	assert.False(t, info.Usage.LastUsed.IsZero())
+
+	// Other wallets are untouched.
+	info, err = keyOps.GetWalletInfo("savings")
+	assert.NoError(t, err)
+	assert.Equal(t, KeyUsage{}, info.Usage)
+
+	// A key that is not in the key file is not tracked.
+	assert.NoError(t, keyOps.RecordKeyUsage("UnknownAddr", SignedSend, "sig-3"))
+
+	assert.Error(t, keyOps.RecordKeyUsage("MainAddr", SigningKind("vote"), "sig-4"))
+
+	entries, err := keyOps.Audit.Entries()
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "sign", entries[0].Action)
+		assert.Equal(t, "usage", entries[0].Changes[0].Field)
+		assert.Equal(t, "main", entries[0].Changes[0].Alias)
+	}
+}
+
+func TestRecordKeyUsageConcurrent(t *testing.T) {
+	keyOps := newUsageKeyOps(t)
+
+	const signers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < signers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, keyOps.RecordKeyUsage("MainAddr", SignedSend, "sig"))
+		}()
+	}
+	wg.Wait()
+
+	info, err := keyOps.GetWalletInfo("main")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(signers), info.Usage.SendsSigned)
+}
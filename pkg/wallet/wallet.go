@@ -3,19 +3,21 @@ package wallet
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
-	confirm "github.com/gagliardetto/solana-go/rpc/sendAndConfirmTransaction"
-	"github.com/gagliardetto/solana-go/rpc/ws"
 	"github.com/mr-tron/base58"
 	"github.com/shopspring/decimal"
 	"github.com/tyler-smith/go-bip39"
 	"golang.org/x/crypto/ed25519"
 	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 // WalletConfig represents the configuration for a wallet. Use NewWalletConfig to initialize.
@@ -26,19 +28,73 @@ type WalletConfig struct {
 	SeedPhrase   string `json:"seed_phrase,omitempty"`
 	Wallet       *solana.Wallet
 	KeyOps       KeyStore
+	Pending      *PendingStore
+	// FailsafeStore holds the armed failsafe sweeps. Nil disables failsafes.
+	FailsafeStore *FailsafeStore
+	// Labels holds the bookkeeping labels of transactions. Nil leaves transactions unlabeled.
+	Labels  *LabelStore
+	Network Network
+	RPCURL  string
+	// RPCPool are the registered RPC endpoints. Those of Network are used when RPCURL is empty.
+	RPCPool []RPCEndpoint
+	Profile string
+	// Policy limits what PrepareSend accepts, for the CLI and the daemon alike.
+	Policy SpendPolicy
+	// EnforceAllowlist restricts sends to the allow-list of the key file even when the key file does
+	// not enforce it. It can only make the restriction stricter.
+	EnforceAllowlist bool
+	// Currency is the fiat currency amounts are shown and given in. Empty means DefaultCurrency.
+	Currency Currency
+	// Rates fetches exchange rates. Nil means DefaultRates.
+	Rates RateProvider
+	// derivation describes how Wallet was derived when it was imported from a seed phrase.
+	derivation *KeyDerivation
+	// genesis checks that the RPC endpoint serves the selected network; nil skips the check.
+	genesis *genesisState
 }
 
 // Wallet represents our own custom wallet.
 type Wallet struct {
-	PrivateKey string          `json:"key"`
+	PrivateKey string `json:"key"`
+	// Balance is the SOL balance as of LastSynced, cached so listings need no RPC call per wallet.
+	// LastSynced is nil until the balance was first fetched.
 	Balance    decimal.Decimal `json:"balance"`
+	LastSynced *time.Time      `json:"lastSynced,omitempty"`
 	PublicKey  string          `json:"publicKey"`
+	// Encrypted keys hold base64 AES-GCM ciphertext in PrivateKey along with their salt and nonce.
+	Encrypted bool   `json:"encrypted,omitempty"`
+	Salt      string `json:"salt,omitempty"`
+	Nonce     string `json:"nonce,omitempty"`
+	// Derivation records how the key was derived from a seed phrase, so it can be recovered unambiguously.
+	Derivation *KeyDerivation `json:"derivation,omitempty"`
+	// Note and Icon are a free-text reminder and an emoji shown next to the alias in listings.
+	Note string `json:"note,omitempty"`
+	Icon string `json:"icon,omitempty"`
+	// Usage counts the signatures made with the key.
+	Usage *KeyUsage `json:"usage,omitempty"`
+	// WatchOnly entries track an address whose private key is kept elsewhere, such as on a hardware
+	// wallet. PrivateKey is empty.
+	WatchOnly bool `json:"watchOnly,omitempty"`
+	// Keychain entries keep their private key in the OS keychain, under PublicKey. PrivateKey is empty.
+	Keychain bool `json:"keychain,omitempty"`
 }
 
 // WalletData represents the data stored in a wallet file.
 type WalletData struct {
 	ActiveAlias string            `json:"activeAlias"`
 	Wallets     map[string]Wallet `json:"wallets"`
+	Network     string            `json:"network,omitempty"`
+	RPCURL      string            `json:"rpcUrl,omitempty"`
+	// Contacts maps the names of recipients to their addresses. Names never collide with wallet aliases.
+	Contacts map[string]string `json:"contacts,omitempty"`
+	// Multisigs maps names to SPL Token multisig accounts. Names never collide with wallets or contacts.
+	Multisigs map[string]Multisig `json:"multisigs,omitempty"`
+	// Groups maps the names of contact groups to the sorted names of their member contacts.
+	Groups map[string][]string `json:"groups,omitempty"`
+	// Allowlist maps the addresses sends are restricted to, while EnforceAllowlist is set, to the contact
+	// name they were added as, or "" for a plain address.
+	Allowlist        map[string]string `json:"allowlist,omitempty"`
+	EnforceAllowlist bool              `json:"enforceAllowlist,omitempty"`
 }
 
 // KeyStore represents key file operations.
@@ -50,38 +106,248 @@ type KeyStore interface {
 	GetCurrentPublicKey() (string, error)
 	GetPublicKeyByAlias(alias string) (string, error)
 	WriteKeyToFile(alias string, key ed25519.PrivateKey, walletAddress string) error
+	WriteDerivedKeyToFile(alias string, key ed25519.PrivateKey, walletAddress string, derivation KeyDerivation) error
+	GetWalletInfo(alias string) (WalletInfo, error)
 	PrintAllKeys() ([]string, map[string]string, error)
+	GetNetwork() (string, string, error)
+	SetNetwork(network, rpcURL string) error
+	EncryptKeys(passphrase string) ([]string, error)
+	MoveKeysToKeychain() ([]string, error)
+	ListAliases() ([]string, error)
+	SetWalletNote(alias string, note WalletNote) error
+	RenameKey(oldAlias, newAlias string) error
+	DeleteKey(alias string) (string, error)
+	AddContact(name, address string) error
+	RemoveContact(name string) (string, error)
+	Contacts() ([]Contact, error)
+	AddGroupMembers(name string, members ...string) error
+	RemoveGroupMembers(name string, members ...string) ([]string, error)
+	Groups() ([]ContactGroup, error)
+	AddMultisig(m Multisig) error
+	Multisigs() ([]Multisig, error)
+	PlanDeleteKey(alias string) (*KeystorePlan, error)
+	PlanEncryptKeys(passphrase string) (*KeystorePlan, error)
+	ApplyPlan(plan *KeystorePlan) error
+	ExportKey(alias string, format ExportFormat) (string, error)
+	AddWatchOnlyWallet(alias, publicKey string) error
+	UpdateBalances(balances map[string]decimal.Decimal, syncedAt time.Time) error
 }
 
-// NewWalletConfig initializes a new WalletConfig.
+// NewWalletConfig initializes a new WalletConfig using files in the current directory.
 func NewWalletConfig() *WalletConfig {
+	wc := NewWalletConfigInDir("")
+	wc.Profile = DefaultProfile
+	return wc
+}
+
+// NewWalletConfigInDir initializes a new WalletConfig whose files live in dir, such as a profile directory.
+func NewWalletConfigInDir(dir string) *WalletConfig {
 	return &WalletConfig{
 		KeyOps: &KeyOps{
 			FileReader: &IOUtilFileReader{},
-			FileWriter: &IOUtilFileWriter{},
+			FileWriter: newFileWriter(),
+			Dir:        dir,
+			Audit: &AuditLog{
+				FileReader: &IOUtilFileReader{},
+				FileWriter: newFileWriter(),
+				Dir:        dir,
+			},
+		},
+		Pending: &PendingStore{
+			FileReader: &IOUtilFileReader{},
+			FileWriter: newFileWriter(),
+			Dir:        dir,
+		},
+		FailsafeStore: &FailsafeStore{
+			FileReader: &IOUtilFileReader{},
+			FileWriter: newFileWriter(),
+			Dir:        dir,
 		},
+		Labels: &LabelStore{
+			FileReader: &IOUtilFileReader{},
+			FileWriter: newFileWriter(),
+			Dir:        dir,
+		},
+	}
+}
+
+// UsePassphrase sets how passphrases for encrypted keys are obtained.
+func (w *WalletConfig) UsePassphrase(fn PassphraseFunc) {
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok {
+		keyOps.Passphrase = fn
+	}
+}
+
+// RetryPassphrase sets what happens when a passphrase does not decrypt a key: fn returning true asks
+// for it again.
+func (w *WalletConfig) RetryPassphrase(fn func(alias string) bool) {
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok {
+		keyOps.RetryPassphrase = fn
+	}
+}
+
+// StoreKeysInKeychain stores new private keys in keychain instead of the key file. Keys already in
+// the key file stay there until MoveKeysToKeychain.
+func (w *WalletConfig) StoreKeysInKeychain(keychain Keychain) {
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok {
+		keyOps.Keychain = keychain
+		keyOps.NewKeysInKeychain = true
+	}
+}
+
+// UseKeyFile stores the keys in path instead of the profile's key file.
+func (w *WalletConfig) UseKeyFile(path string) {
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok {
+		keyOps.Path = path
+	}
+}
+
+// KeyFile returns the location of the key file, or an empty string for a key store without one.
+func (w *WalletConfig) KeyFile() string {
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok {
+		return keyOps.KeyFile()
+	}
+	return ""
+}
+
+// UseCurrency selects the fiat currency balances, history and amounts to send are given in.
+func (w *WalletConfig) UseCurrency(currency Currency) {
+	w.Currency = currency
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok {
+		keyOps.Currency = currency
+	}
+}
+
+// UseRates selects where exchange rates come from, for example a fixed rate in tests.
+func (w *WalletConfig) UseRates(rates RateProvider) {
+	w.Rates = rates
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok {
+		keyOps.Rates = rates
+	}
+}
+
+// OnKeystoreChange registers fn to be called with what every write to the key file changed.
+func (w *WalletConfig) OnKeystoreChange(fn func(action string, changes []KeystoreChange)) {
+	if keyOps, ok := w.KeyOps.(*KeyOps); ok {
+		keyOps.OnChange = fn
+	}
+}
+
+// UseNetwork selects the cluster used for all RPC and websocket traffic.
+// Empty arguments fall back to the selection persisted in the key file and then to DefaultNetwork.
+// An explicit selection is persisted so later invocations keep using it.
+func (w *WalletConfig) UseNetwork(network, rpcURL string) error {
+	if network == "" && rpcURL == "" {
+		storedNetwork, storedURL, err := w.KeyOps.GetNetwork()
+		if err != nil {
+			return fmt.Errorf("failed to read network selection: %w", err)
+		}
+		return w.setNetwork(storedNetwork, storedURL)
+	}
+
+	if err := w.setNetwork(network, rpcURL); err != nil {
+		return err
+	}
+
+	// Inspecting a wallet on another network must not change the one remembered in the key file.
+	if ReadOnlyMode() {
+		return nil
 	}
+	return w.KeyOps.SetNetwork(string(w.Network), w.RPCURL)
 }
 
-// GenerateNewPaperWallet generates a new paper wallet.
+// setNetwork validates and applies a network selection without persisting it.
+func (w *WalletConfig) setNetwork(network, rpcURL string) error {
+	n := DefaultNetwork
+	if network != "" {
+		var err error
+		n, err = ParseNetwork(network)
+		if err != nil {
+			return err
+		}
+	} else if rpcURL != "" {
+		n = Custom
+	}
+
+	if _, err := resolveEndpoints(n, rpcURL); err != nil {
+		return err
+	}
+
+	w.Network = n
+	w.RPCURL = rpcURL
+	return nil
+}
+
+// Endpoints returns the RPC and websocket URLs for the selected network. Without an RPC URL, the
+// endpoints registered for the network come first, with its public endpoint as the last fallback.
+func (w *WalletConfig) Endpoints() (Endpoints, error) {
+	n := w.Network
+	if n == "" {
+		n = DefaultNetwork
+	}
+	rpcURL := w.RPCURL
+	if rpcURL == "" {
+		rpcURL = w.poolRPCURL(n)
+	}
+	endpoints, err := resolveEndpoints(n, rpcURL)
+	if err != nil || w.genesis == nil {
+		return endpoints, err
+	}
+	return endpoints, w.verifyGenesis(n, endpoints.RPC)
+}
+
+// SelectedNetwork returns the selected network without the RPC URL, which may carry an API key.
+func (w *WalletConfig) SelectedNetwork() Network {
+	if w.Network == "" {
+		return DefaultNetwork
+	}
+	return w.Network
+}
+
+// NetworkName describes the selected network for display, including a custom RPC URL when set.
+func (w *WalletConfig) NetworkName() string {
+	n := w.Network
+	if n == "" {
+		n = DefaultNetwork
+	}
+	if w.RPCURL != "" {
+		return fmt.Sprintf("%s (%s)", n, w.RPCURL)
+	}
+	if pool := w.poolFor(n); len(pool) > 0 {
+		names := make([]string, len(pool))
+		for i, endpoint := range pool {
+			names[i] = endpoint.Name
+		}
+		return fmt.Sprintf("%s (%s)", n, strings.Join(names, ", "))
+	}
+	return string(n)
+}
+
+// GenerateNewPaperWallet generates a new paper wallet whose seed phrase derives the first
+// account on the standard Solana path, so it restores to the same address in other wallets.
 func (w *WalletConfig) GenerateNewPaperWallet() (string, string, error) {
-	seed, privateKey, err := createKeyPairWithMnemonic("")
+	seed, err := newMnemonic()
 	if err != nil {
 		return "", "", err
 	}
-	wallet, err := solana.WalletFromPrivateKeyBase58(base58.Encode(privateKey))
+
+	address, err := w.ImportWalletFromSeed(seed)
 	if err != nil {
 		return "", "", err
 	}
 
-	w.IsPaperBased = true
-	w.Wallet = wallet
-	return seed, wallet.PublicKey().String(), nil
+	return seed, address, nil
 }
 
-// ImportWalletFromSeed imports a wallet from a seed phrase.
+// ImportWalletFromSeed imports the first account derived from a seed phrase.
 func (w *WalletConfig) ImportWalletFromSeed(mnemonic string) (string, error) {
-	_, privateKey, err := createKeyPairWithMnemonic(mnemonic)
+	return w.ImportWalletFromSeedWithOptions(mnemonic, SeedOptions{})
+}
+
+// ImportWalletFromSeedWithOptions imports a wallet from a seed phrase using a specific account,
+// derivation path, BIP-39 passphrase or the legacy derivation.
+func (w *WalletConfig) ImportWalletFromSeedWithOptions(mnemonic string, opts SeedOptions) (string, error) {
+	privateKey, err := deriveKeyFromMnemonic(mnemonic, opts)
 	if err != nil {
 		return "", err
 	}
@@ -90,11 +356,77 @@ func (w *WalletConfig) ImportWalletFromSeed(mnemonic string) (string, error) {
 		return "", err
 	}
 
+	derivation := opts.derivation()
 	w.IsPaperBased = true
 	w.Wallet = wallet
+	w.derivation = &derivation
 	return wallet.PublicKey().String(), nil
 }
 
+// SaveSeedWallet stores the wallet imported from a seed phrase in the key file together with
+// its derivation path, so it no longer needs the seed phrase to be used.
+func (w *WalletConfig) SaveSeedWallet(alias string) error {
+	if w.Wallet == nil || w.derivation == nil {
+		return errors.New("no seed-derived wallet to save")
+	}
+
+	if alias == "" {
+		alias = getRandomAlias() + "-" + "wallet"
+	}
+
+	return w.KeyOps.WriteDerivedKeyToFile(alias, ed25519.PrivateKey(w.Wallet.PrivateKey), w.Wallet.PublicKey().String(), *w.derivation)
+}
+
+// GetWalletInfo describes a stored wallet. An empty alias describes the active wallet.
+func (w *WalletConfig) GetWalletInfo(alias string) (WalletInfo, error) {
+	return w.KeyOps.GetWalletInfo(alias)
+}
+
+// DerivedAccount is one account derived from a seed phrase while scanning.
+type DerivedAccount struct {
+	Index      uint32
+	Path       string
+	PublicKey  string
+	Balance    decimal.Decimal
+	BalanceErr error
+}
+
+// ScanSeedAccounts derives the first count accounts of a seed phrase and looks up their SOL balances
+// so the user can pick the account they actually use. A failed balance lookup is reported per account.
+func (w *WalletConfig) ScanSeedAccounts(ctx context.Context, mnemonic string, opts SeedOptions, count uint32) ([]DerivedAccount, error) {
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	client := endpoints.client()
+
+	accounts := make([]DerivedAccount, 0, count)
+	for i := uint32(0); i < count; i++ {
+		accountOpts := opts
+		accountOpts.AccountIndex = i
+		accountOpts.DerivationPath = ""
+
+		privateKey, err := deriveKeyFromMnemonic(mnemonic, accountOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKey := solana.PrivateKey(privateKey).PublicKey()
+		account := DerivedAccount{Index: i, Path: accountOpts.path(), PublicKey: publicKey.String()}
+
+		balanceResp, err := client.GetBalance(ctx, publicKey, rpc.CommitmentFinalized)
+		if err != nil {
+			account.BalanceErr = err
+		} else {
+			account.Balance = decimal.NewFromInt(int64(balanceResp.Value)).Div(decimal.NewFromInt(LamportsInOneSol))
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
 // CreateNewWallet creates a new wallet.
 func (w *WalletConfig) CreateNewWallet(alias string) (string, error) {
 	account := solana.NewWallet()
@@ -130,21 +462,61 @@ func (w *WalletConfig) CreateNewWalletWithKey(alias, privateKey string) (string,
 	return privkey.PublicKey().String(), nil
 }
 
-// GetCurrentWalletBalanceInEUR returns the balance of a wallet in EUR.
-func (w *WalletConfig) GetCurrentWalletBalanceInEUR(alias string) (string, error) {
+// WalletBalance is a wallet balance in SOL together with its value in the selected currency and the rate used.
+type WalletBalance struct {
+	SOL      decimal.Decimal
+	Fiat     decimal.Decimal
+	Currency Currency
+	Rate     decimal.Decimal
+	// RateErr is why no rate was available. Fiat and Rate are zero then, but SOL is still valid.
+	RateErr error
+}
+
+// GetWalletBalance returns the balance of a stored wallet in SOL and the selected currency.
+// An empty alias means the active wallet. A missing exchange rate is reported in RateErr rather
+// than failing, so the SOL balance can still be shown.
+func (w *WalletConfig) GetWalletBalance(alias string) (*WalletBalance, error) {
 	solBalance, err := w.fetchSolBalance(alias, w.KeyOps)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	w.cacheBalance(alias, solBalance)
 
-	rate, err := fetchSOLEURRate()
+	rate, err := w.FetchSOLRate()
+	if err != nil {
+		return &WalletBalance{SOL: solBalance, Currency: w.FiatCurrency(), RateErr: err}, nil
+	}
+
+	return &WalletBalance{SOL: solBalance, Fiat: solBalance.Mul(rate), Currency: w.FiatCurrency(), Rate: rate}, nil
+}
+
+// GetCurrentWalletBalanceInFiat returns the balance of a wallet in the selected currency, such as €12.50.
+func (w *WalletConfig) GetCurrentWalletBalanceInFiat(alias string) (string, error) {
+	balance, err := w.GetWalletBalance(alias)
 	if err != nil {
 		return "", err
 	}
+	if balance.RateErr != nil {
+		return "", balance.RateErr
+	}
+
+	return balance.Currency.Format(balance.Fiat), nil
+}
 
-	eurBalance := solBalance.Mul(rate)
+// EncryptWallets encrypts every plaintext key in the key file with the passphrase.
+func (w *WalletConfig) EncryptWallets(passphrase string) ([]string, error) {
+	return w.KeyOps.EncryptKeys(passphrase)
+}
+
+// MoveKeysToKeychain moves the private keys in the key file into the keychain set with
+// StoreKeysInKeychain, or the OS keychain, and returns the aliases that were moved.
+func (w *WalletConfig) MoveKeysToKeychain() ([]string, error) {
+	return w.KeyOps.MoveKeysToKeychain()
+}
 
-	return eurBalance.StringFixed(2), nil
+// PlanEncryptWallets previews EncryptWallets without writing the key file.
+func (w *WalletConfig) PlanEncryptWallets(passphrase string) (*KeystorePlan, error) {
+	return w.KeyOps.PlanEncryptKeys(passphrase)
 }
 
 // SwitchWallet switches the current wallet.
@@ -152,11 +524,116 @@ func (w *WalletConfig) SwitchWallet(alias string) error {
 	return w.KeyOps.SetActiveKey(alias)
 }
 
+// RenameWallet gives a stored wallet a new alias.
+func (w *WalletConfig) RenameWallet(oldAlias, newAlias string) error {
+	return w.KeyOps.RenameKey(oldAlias, newAlias)
+}
+
+// RemoveWallet deletes a stored wallet and returns the alias that is active afterwards.
+// Use RemovalRisks first to find out whether the wallet is still in use.
+func (w *WalletConfig) RemoveWallet(alias string) (string, error) {
+	return w.KeyOps.DeleteKey(alias)
+}
+
+// PlanRemoveWallet previews RemoveWallet without writing the key file.
+func (w *WalletConfig) PlanRemoveWallet(alias string) (*KeystorePlan, error) {
+	return w.KeyOps.PlanDeleteKey(alias)
+}
+
+// ApplyKeystorePlan writes a previewed change to the key file.
+func (w *WalletConfig) ApplyKeystorePlan(plan *KeystorePlan) error {
+	return w.KeyOps.ApplyPlan(plan)
+}
+
+// RemovalRisks lists the reasons why removing a stored wallet may lose access to funds:
+// it is the active wallet, or it still holds SOL. An empty result means it is safe to remove.
+// When the balance cannot be checked, the risks found so far are returned with the error.
+func (w *WalletConfig) RemovalRisks(ctx context.Context, alias string) ([]string, error) {
+	info, err := w.KeyOps.GetWalletInfo(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	var risks []string
+	if info.Active {
+		risks = append(risks, "it is the active wallet")
+	}
+
+	publicKey, err := solana.PublicKeyFromBase58(info.PublicKey)
+	if err != nil {
+		return risks, fmt.Errorf("invalid public key for %s: %w", alias, err)
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return risks, err
+	}
+
+	// The balance is looked up by address, so encrypted keys need no passphrase.
+	client := endpoints.client()
+	var balanceResp *rpc.GetBalanceResult
+	err = withRetry(ctx, DefaultRetryPolicy, nil, func(ctx context.Context) error {
+		balanceResp, err = client.GetBalance(ctx, publicKey, rpc.CommitmentFinalized)
+		return err
+	})
+	if err != nil {
+		return risks, fmt.Errorf("failed to check balance: %w", err)
+	}
+	if balanceResp.Value > 0 {
+		risks = append(risks, fmt.Sprintf("it still holds %s SOL", LamportsToSOL(balanceResp.Value)))
+	}
+
+	return risks, nil
+}
+
+// ExportWallet returns the private key of a stored wallet in the given format.
+func (w *WalletConfig) ExportWallet(alias string, format ExportFormat) (string, error) {
+	return w.KeyOps.ExportKey(alias, format)
+}
+
 // RetrieveWallets retrieves all wallets.
 func (w *WalletConfig) RetrieveWallets() ([]string, map[string]string, error) {
 	return w.KeyOps.PrintAllKeys()
 }
 
+// ListWallets describes every stored wallet, sorted by alias.
+func (w *WalletConfig) ListWallets() ([]WalletInfo, error) {
+	aliases, err := w.KeyOps.ListAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]WalletInfo, 0, len(aliases))
+	for _, alias := range aliases {
+		info, err := w.KeyOps.GetWalletInfo(alias)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// StoredAlias returns the alias address is stored under as a wallet, or "" when it is not stored.
+func (w *WalletConfig) StoredAlias(address string) (string, error) {
+	present, err := w.KeyOps.IsKeyFilePresent()
+	if err != nil || !present {
+		return "", err
+	}
+
+	wallets, err := w.ListWallets()
+	if err != nil {
+		return "", err
+	}
+	for _, wallet := range wallets {
+		if wallet.PublicKey == address {
+			return wallet.Alias, nil
+		}
+	}
+	return "", nil
+}
+
 // RetrieveCurrentWalletAddress retrieves the current wallet address.
 func (w *WalletConfig) RetrieveCurrentWalletAddress() (string, error) {
 	if w.Wallet != nil {
@@ -171,120 +648,457 @@ func (w *WalletConfig) RetrieveWalletAddressByAlias(alias string) (string, error
 }
 
 // HasWallets checks if there are any wallets.
+// A key file that only holds settings such as the network does not count.
 func (w *WalletConfig) HasWallets() (bool, error) {
-	return w.KeyOps.IsKeyFilePresent()
+	present, err := w.KeyOps.IsKeyFilePresent()
+	if err != nil || !present {
+		return false, err
+	}
+
+	_, err = w.KeyOps.GetCurrentPublicKey()
+	if errors.Is(err, ErrActiveWalletNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
-// SendFunds sends funds to a recipient.
+// SendOptions holds optional settings for a transfer.
+type SendOptions struct {
+	// NonceAccount makes the transfer use a durable nonce instead of a recent blockhash,
+	// so it can be cancelled later with CancelPending.
+	NonceAccount string
+	// Unit is the denomination of the amount. The zero value means the selected currency.
+	Unit Unit
+	// AutoFund airdrops the shortfall on devnet when the wallet cannot cover the amount plus fee.
+	AutoFund bool
+	// Memo is recorded with the transfer by the memo program, e.g. for exchange deposits.
+	Memo string
+	// PriorityFee adds a compute unit price so the transfer lands sooner during congestion.
+	PriorityFee PriorityFee
+	// AllowBelowRent sends to an address that holds no SOL yet even when the amount is below the
+	// rent-exempt minimum of its new account.
+	AllowBelowRent bool
+}
+
+// SendReceipt describes a completed transfer.
+type SendReceipt struct {
+	Signature string
+	Lamports  uint64
+	// Fiat is the value of the transfer in Currency, or nil when no exchange rate was available.
+	Fiat     *decimal.Decimal
+	Currency Currency
+	Memo     string
+	// PriorityFee is the priority fee paid in lamports.
+	PriorityFee uint64
+	// Origin is where the send was started.
+	Origin Origin
+	// Genesis is the genesis hash of the cluster of a send on a custom network, empty otherwise.
+	Genesis string
+}
+
+// SOL returns the amount sent in SOL.
+func (r *SendReceipt) SOL() decimal.Decimal {
+	return LamportsToSOL(r.Lamports)
+}
+
+// SendFunds sends an amount's worth of SOL, given in the selected currency, to a recipient.
 func (w *WalletConfig) SendFunds(ctx context.Context, amount, recipient string) (string, error) {
-	var privKeyStr string
-	rpcClient := rpc.New(rpc.DevNet_RPC)
-	wsClient, err := ws.Connect(ctx, rpc.DevNet_WS)
+	return w.SendFundsWithUnit(ctx, amount, UnitFiat, recipient)
+}
+
+// SendFundsWithUnit sends an amount given in unit to a recipient.
+// SOL and lamport amounts are sent without looking up the exchange rate.
+func (w *WalletConfig) SendFundsWithUnit(ctx context.Context, amount string, unit Unit, recipient string) (string, error) {
+	receipt, err := w.SendFundsWithOptions(ctx, amount, recipient, SendOptions{Unit: unit})
 	if err != nil {
 		return "", err
 	}
+	return receipt.Signature, nil
+}
 
-	if w.Wallet != nil {
-		privKeyStr = w.Wallet.PrivateKey.String()
-	} else {
-		privKeyStr, err = w.KeyOps.GetCurrentPrivateKey()
-		if err != nil {
-			return "", fmt.Errorf("failed to get current private key: %w", err)
+// quoteAmount resolves an amount expression to lamports against the spendable balance and returns the
+// SOL rate of the currency the amount is valued in when it is available. Rates are only required for
+// fiat numbers in the expression, which are valued in their own currency; SOL and lamport amounts are
+// valued in the selected currency, and the price API only gets valueRateTimeout to value them.
+func (w *WalletConfig) quoteAmount(expr *AmountExpression, unit Unit, spendable uint64) (uint64, *decimal.Decimal, Currency, error) {
+	rates := make(map[Currency]decimal.Decimal)
+	rateOf := func(ctx context.Context, u Unit) (decimal.Decimal, error) {
+		currency := u.Currency(w.FiatCurrency())
+		if rate, ok := rates[currency]; ok {
+			return rate, nil
 		}
+		rate, err := ratesOrDefault(w.Rates).SOLRate(ctx, currency)
+		if err == nil {
+			rates[currency] = rate
+		}
+		return rate, err
 	}
 
-	accountFrom, err := solana.PrivateKeyFromBase58(privKeyStr)
+	currency := unit.Currency(w.FiatCurrency())
+	lamports, err := expr.Resolve(spendable, func(u Unit) (decimal.Decimal, error) {
+		return rateOf(context.TODO(), u)
+	})
 	if err != nil {
-		return "", err
+		return 0, nil, currency, err
 	}
 
-	accountTo := solana.MustPublicKeyFromBase58(recipient)
+	ctx := context.TODO()
+	if !expr.NeedsRate() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, valueRateTimeout)
+		defer cancel()
+	}
+	rate, err := rateOf(ctx, unit)
+	if err != nil {
+		// The fiat value is informational only, so a missing rate does not stop the transfer.
+		return lamports, nil, currency, nil
+	}
+
+	return lamports, &rate, currency, nil
+}
 
-	rate, err := fetchSOLEURRate()
+// SendFundsWithOptions checks a transfer with PrepareSend and then sends it with ExecuteSend.
+func (w *WalletConfig) SendFundsWithOptions(ctx context.Context, amount, recipient string, opts SendOptions) (*SendReceipt, error) {
+	quote, err := w.PrepareSend(ctx, amount, recipient, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.ExecuteSend(ctx, quote, opts)
+}
+
+// ExecuteSend signs and submits a prepared transfer and records it in the pending store and the audit log.
+// The memo and the priority fee resolved by PrepareSend are used, so the transfer is the one quoted.
+func (w *WalletConfig) ExecuteSend(ctx context.Context, quote *SendQuote, opts SendOptions) (*SendReceipt, error) {
+	opts.Memo = quote.Memo
+	opts.PriorityFee = PriorityFee{MicroLamports: quote.ComputeUnitPrice}
+	signature, err := w.sendLamports(ctx, quote.Lamports, quote.To, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transfer has landed, so a failure to log it is not reported as a failed send.
+	_ = w.recordAudit(ctx, "send", fmt.Sprintf("%s SOL to %s (%s)", quote.SOL(), quote.To, signature))
+
+	return &SendReceipt{
+		Signature:   signature,
+		Lamports:    quote.Lamports,
+		Fiat:        quote.Fiat(),
+		Currency:    quote.Currency,
+		Memo:        quote.Memo,
+		PriorityFee: quote.PriorityFee,
+		Origin:      w.operationOrigin(ctx),
+		Genesis:     w.customGenesis(),
+	}, nil
+}
+
+// sendLamports signs and submits a transfer of lamports, recording it in the pending store. When the
+// blockhash expires before the transfer lands, it is signed again with a fresh blockhash, up to
+// maxBlockhashAttempts times; the expired attempt cannot land any more and is marked as such. ctx
+// bounds the whole send, including every attempt and the wait for confirmation.
+func (w *WalletConfig) sendLamports(ctx context.Context, amountToSend uint64, accountTo solana.PublicKey, opts SendOptions) (string, error) {
+	endpoints, err := w.Endpoints()
 	if err != nil {
 		return "", err
 	}
 
-	amountToSend, err := convertEurToLamports(amount, rate)
+	rpcClient := endpoints.client()
+
+	accountFrom, err := w.currentPrivateKey()
 	if err != nil {
 		return "", err
 	}
+	if err := w.checkRecipientAllowed(accountTo); err != nil {
+		return "", err
+	}
 
-	recent, err := rpcClient.GetRecentBlockhash(ctx, rpc.CommitmentFinalized)
+	// A send from a wallet with an armed failsafe advances the failsafe's nonce, so the sweep of the
+	// balance this send spends can no longer land.
+	failsafe, err := w.armedFailsafe(accountFrom.PublicKey())
 	if err != nil {
 		return "", err
 	}
+	if failsafe != nil {
+		if opts.NonceAccount != "" && opts.NonceAccount != failsafe.NonceAccount {
+			return "", fmt.Errorf("%w (%s)", ErrFailsafeNonceAccount, failsafe.NonceAccount)
+		}
+		opts.NonceAccount = failsafe.NonceAccount
+	}
+
+	for attempt := 1; ; attempt++ {
+		pending := PendingTransaction{
+			From:      accountFrom.PublicKey().String(),
+			To:        accountTo.String(),
+			Lamports:  amountToSend,
+			Network:   w.NetworkName(),
+			Status:    PendingSubmitted,
+			CreatedAt: time.Now().UTC(),
+		}
+
+		tx, lastValidBlockHeight, err := w.signTransfer(ctx, rpcClient, accountFrom, accountTo, amountToSend, opts, &pending)
+		if err != nil {
+			return "", err
+		}
+
+		pending.Signature = tx.Signatures[0].String()
+		pending.ID = pendingID(tx.Signatures[0])
+		if err := w.Pending.Put(pending); err != nil {
+			return "", fmt.Errorf("failed to record pending transaction: %w", err)
+		}
+		w.recordKeyUsage(accountFrom.PublicKey(), SignedSend, tx.Signatures[0])
+
+		// Durable nonce transactions never expire, so only blockhash transfers are signed again.
+		retry := lastValidBlockHeight > 0 && attempt < maxBlockhashAttempts
+
+		sig, err := rpcClient.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{PreflightCommitment: rpc.CommitmentFinalized})
+		if err == nil && failsafe != nil {
+			// BroadcastFailsafe checks the nonce on chain as well, so a failure to record this only
+			// leaves the status stale.
+			_ = w.invalidateFailsafe(failsafe)
+		}
+		if err == nil {
+			err = w.awaitSend(ctx, rpcClient, sig, pending, lastValidBlockHeight)
+		} else if isBlockhashExpired(err) {
+			// The node refused the transfer, so it can never land.
+			if err := w.Pending.setStatus(pending.ID, PendingExpired); err != nil {
+				return "", err
+			}
+		}
+
+		if err != nil && isBlockhashExpired(err) && retry {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("transaction %s is still pending: %w", pending.ID, err)
+		}
+		return sig.String(), nil
+	}
+}
+
+// signTransfer builds and signs a transfer with the memo and compute unit price of opts, advancing
+// the durable nonce from opts or using the latest blockhash. It returns the last block height at which the blockhash is valid, 0 for a nonce
+// transfer, and records the nonce in pending.
+func (w *WalletConfig) signTransfer(ctx context.Context, client ClientInterface, from solana.PrivateKey, to solana.PublicKey, lamports uint64, opts SendOptions, pending *PendingTransaction) (*solana.Transaction, uint64, error) {
+	extras := transferExtras{Memo: opts.Memo, ComputeUnitPrice: opts.PriorityFee.MicroLamports}
+	if opts.NonceAccount == "" {
+		recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get latest blockhash: %w", err)
+		}
+
+		tx, err := buildTransfer(from, to, lamports, recent.Value.Blockhash, solana.PublicKey{}, extras)
+		if err != nil {
+			return nil, 0, err
+		}
+		return tx, recent.Value.LastValidBlockHeight, nil
+	}
 
+	nonceAccount, err := solana.PublicKeyFromBase58(opts.NonceAccount)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid nonce account: %w", err)
+	}
+
+	nonce, err := fetchNonce(ctx, client, nonceAccount)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !nonce.AuthorizedPubkey.Equals(from.PublicKey()) {
+		return nil, 0, ErrNonceAuthority
+	}
+
+	tx, err := buildTransfer(from, to, lamports, solana.Hash(nonce.Nonce), nonceAccount, extras)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pending.NonceAccount = nonceAccount.String()
+	pending.Nonce = nonce.Nonce.String()
+	return tx, 0, nil
+}
+
+// awaitSend waits for a submitted transfer to finalize and records how long each commitment level
+// took in its pending record, which is marked confirmed once it is finalized. When waiting times
+// out, the status is checked once more, and a transfer that reached the confirmed level counts as
+// sent. A transfer whose blockhash expired is marked expired.
+func (w *WalletConfig) awaitSend(ctx context.Context, client ClientInterface, sig solana.Signature, pending PendingTransaction, lastValidBlockHeight uint64) error {
+	recorder := newLatencyRecorder(time.Now)
+
+	awaitCtx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+	confirmErr := awaitCommitments(awaitCtx, client, sig, lastValidBlockHeight, recorder.observe)
+
+	if errors.Is(confirmErr, ErrConfirmTimeout) {
+		landed, err := landedAfterTimeout(ctx, client, sig, recorder.observe)
+		switch {
+		case err != nil:
+			confirmErr = fmt.Errorf("%w; checking its status afterwards failed: %v", ErrConfirmTimeout, err)
+		case landed:
+			confirmErr = nil
+		}
+	}
+
+	// Whatever was measured before a timeout is kept, since slow sends are the interesting ones.
+	pending.Latency = &recorder.latency
+	switch {
+	case confirmErr == nil:
+		pending.Status = PendingConfirmed
+	case errors.Is(confirmErr, ErrBlockhashExpired):
+		pending.Status = PendingExpired
+	}
+	if err := w.Pending.Put(pending); err != nil {
+		return err
+	}
+
+	return confirmErr
+}
+
+// currentPrivateKey returns the private key of the in-memory wallet or the active wallet on disk.
+func (w *WalletConfig) currentPrivateKey() (solana.PrivateKey, error) {
+	var privKeyStr string
+	var err error
+
+	if w.Wallet != nil {
+		privKeyStr = w.Wallet.PrivateKey.String()
+	} else {
+		privKeyStr, err = w.KeyOps.GetCurrentPrivateKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current private key: %w", err)
+		}
+	}
+
+	return solana.PrivateKeyFromBase58(privKeyStr)
+}
+
+// buildTransfer builds and signs a system transfer. When nonceAccount is set, the transaction
+// advances that durable nonce first and blockhash must be the nonce value.
+func buildTransfer(from solana.PrivateKey, to solana.PublicKey, lamports uint64, blockhash solana.Hash, nonceAccount solana.PublicKey, extras transferExtras) (*solana.Transaction, error) {
 	tx, err := solana.NewTransaction(
-		[]solana.Instruction{
-			system.NewTransferInstruction(
-				uint64(amountToSend),
-				accountFrom.PublicKey(),
-				accountTo,
-			).Build(),
-		},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(accountFrom.PublicKey()),
+		transferInstructions(from.PublicKey(), to, lamports, nonceAccount, extras),
+		blockhash,
+		solana.TransactionPayer(from.PublicKey()),
 	)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	_, err = tx.Sign(
 		func(key solana.PublicKey) *solana.PrivateKey {
-			if accountFrom.PublicKey().Equals(key) {
-				return &accountFrom
+			if from.PublicKey().Equals(key) {
+				return &from
 			}
 			return nil
 		},
 	)
 	if err != nil {
-		return "", fmt.Errorf("unable to sign transaction: %w", err)
+		return nil, fmt.Errorf("unable to sign transaction: %w", err)
 	}
 
-	sig, err := confirm.SendAndConfirmTransaction(
-		ctx,
-		rpcClient,
-		wsClient,
-		tx,
-	)
-	if err != nil {
-		return "", err
+	return tx, nil
+}
+
+// transferInstructions returns the instructions of a system transfer. Advancing the durable nonce,
+// when one is used, must come first; the compute budget of a priority fee comes before the transfer
+// and the memo after it.
+func transferInstructions(from, to solana.PublicKey, lamports uint64, nonceAccount solana.PublicKey, extras transferExtras) []solana.Instruction {
+	var instructions []solana.Instruction
+	if !nonceAccount.IsZero() {
+		instructions = append(instructions, system.NewAdvanceNonceAccountInstruction(
+			nonceAccount,
+			solana.SysVarRecentBlockHashesPubkey,
+			from,
+		).Build())
 	}
 
-	return sig.String(), nil
+	instructions = append(instructions, extras.computeBudgetInstructions()...)
+	instructions = append(instructions, system.NewTransferInstruction(
+		lamports,
+		from,
+		to,
+	).Build())
+
+	if memo := extras.memoInstruction(from); memo != nil {
+		instructions = append(instructions, memo)
+	}
+	return instructions
 }
 
-// createKeyPairWithMnemonic creates a key pair with an optional mnemonic.
-func createKeyPairWithMnemonic(mnemonic string) (string, ed25519.PrivateKey, error) {
-	if mnemonic == "" {
-		entropy, err := bip39.NewEntropy(128)
-		if err != nil {
-			return "", nil, fmt.Errorf("error generating entropy: %w", err)
-		}
+// newMnemonic generates a new 12 word BIP-39 mnemonic.
+func newMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(128)
+	if err != nil {
+		return "", fmt.Errorf("error generating entropy: %w", err)
+	}
 
-		mnemonic, err = bip39.NewMnemonic(entropy)
-		if err != nil {
-			return "", nil, fmt.Errorf("error generating mnemonic: %w", err)
-		}
+	mnemonic, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		return "", fmt.Errorf("error generating mnemonic: %w", err)
 	}
 
+	return mnemonic, nil
+}
+
+// createLegacyKeyPairWithMnemonic derives a key pair the way sleeng did before BIP-44 support.
+// It is not compatible with other wallets and only exists so old paper wallets can be restored.
+func createLegacyKeyPairWithMnemonic(mnemonic string) (string, ed25519.PrivateKey, error) {
 	entropy, err := bip39.EntropyFromMnemonic(mnemonic)
 	if err != nil {
 		return "", nil, fmt.Errorf("mnemonic not valid: %w", err)
 	}
 
-	privateKey := ed25519.NewKeyFromSeed([]byte(hex.EncodeToString(entropy)))
+	legacySeed := []byte(hex.EncodeToString(entropy))
+	if len(legacySeed) != ed25519.SeedSize {
+		return "", nil, fmt.Errorf("legacy derivation only supports 12 word seed phrases")
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(legacySeed)
 	return mnemonic, privateKey, nil
 }
 
-// FetchSOLEURRate fetches the current SOL to EUR exchange rate.
-func (w *WalletConfig) FetchSOLEURRate() (decimal.Decimal, error) {
-	return fetchSOLEURRate()
+// FetchSOLRate fetches the current rate of SOL in the selected currency.
+func (w *WalletConfig) FetchSOLRate() (decimal.Decimal, error) {
+	return w.fetchRate(w.FiatCurrency())
+}
+
+// RefreshSOLRate fetches the rate in the selected currency past any cached one and, when the
+// provider caches, stores it there for later FetchSOLRate calls.
+func (w *WalletConfig) RefreshSOLRate(ctx context.Context) (decimal.Decimal, error) {
+	provider := ratesOrDefault(w.Rates)
+	if cached, ok := provider.(*CachedRates); ok {
+		return cached.Refresh(ctx, w.FiatCurrency())
+	}
+	return provider.SOLRate(ctx, w.FiatCurrency())
 }
 
-// GetTransactionHistory retrieves the transaction history of the current wallet.
+// fetchRate fetches the current rate of SOL in currency from the configured provider.
+func (w *WalletConfig) fetchRate(currency Currency) (decimal.Decimal, error) {
+	return ratesOrDefault(w.Rates).SOLRate(context.TODO(), currency)
+}
+
+// FiatCurrency returns the selected currency, DefaultCurrency when none is set.
+func (w *WalletConfig) FiatCurrency() Currency {
+	return w.Currency.orDefault()
+}
+
+// DefaultTransactionLimit is how many transactions GetTransactionHistory fetches.
+const DefaultTransactionLimit = 50
+
+// GetTransactionHistory retrieves the DefaultTransactionLimit most recent transactions of the current wallet.
 func (w *WalletConfig) GetTransactionHistory() ([]*Transaction, error) {
+	return w.GetTransactionHistoryWithOpts(GetTransactionHistoryOpts{Limit: DefaultTransactionLimit})
+}
+
+// GetTransactionHistoryWithOpts retrieves the transaction history of the current wallet within the bounds of opts.
+// When some transactions cannot be fetched, the others are returned together with a *HistoryFetchError.
+func (w *WalletConfig) GetTransactionHistoryWithOpts(opts GetTransactionHistoryOpts) ([]*Transaction, error) {
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("invalid limit %d", opts.Limit)
+	}
+
 	var err error
 	var publicKeyStr string
 
@@ -298,12 +1112,47 @@ func (w *WalletConfig) GetTransactionHistory() ([]*Transaction, error) {
 		}
 	}
 
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	network := w.Network
+	if network == "" {
+		network = DefaultNetwork
+	}
+	var history *walletHistory
+	if opts.Cache.cacheable(network) {
+		if opts.Resync {
+			history = newWalletHistory()
+		} else {
+			history = opts.Cache.load(network, publicKeyStr)
+		}
+	}
+
 	// Fetch transactions using the public key
-	transactions, err := fetchTransactions(publicKeyStr)
+	// Transactions fetched before a partial failure are returned alongside the *HistoryFetchError.
+	transactions, err := fetchTransactions(endpoints, publicKeyStr, opts, history)
+	var partial *HistoryFetchError
+	if history != nil && (err == nil || errors.As(err, &partial)) {
+		// Transactions that failed are left out of the history, so they are fetched next time.
+		opts.Cache.store(network, publicKeyStr, history)
+	}
+	if errors.As(err, &partial) {
+		return transactions, err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
 	}
 
+	if opts.RewardEpochs > 0 {
+		rewards, err := stakingRewardsOf(context.Background(), endpoints.client(), solana.MustPublicKeyFromBase58(publicKeyStr), opts.RewardEpochs)
+		if err != nil {
+			return transactions, fmt.Errorf("failed to fetch staking rewards: %w", err)
+		}
+		transactions = append(transactions, rewardTransactions(rewards, opts)...)
+	}
+
 	return transactions, nil
 }
 
@@ -328,14 +1177,55 @@ func (r *IOUtilFileReader) ReadFile(filename string) ([]byte, error) {
 	return data, nil
 }
 
-// IOUtilFileWriter is a file writer using ioutil.
+// IOUtilFileWriter is a file writer that replaces files atomically.
 type IOUtilFileWriter struct{}
 
-// WriteFile writes data to a file.
+// syncFile flushes a file to disk (a package variable so tests can interrupt writes).
+var syncFile = func(f *os.File) error {
+	return f.Sync()
+}
+
+// WriteFile replaces a file with data atomically: the data is written to a temporary file in the same
+// directory, flushed to disk and renamed over the original, so a crash or a concurrent reader sees
+// either the old or the new contents, never a mix. The file is only readable by its owner, and
+// missing directories are created readable only by the owner too.
 func (w *IOUtilFileWriter) WriteFile(filename string, data []byte) error {
-	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error writing to file %s: %w", filename, err)
+	}
+	// Removing fails harmlessly once the file has been renamed.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
 		return fmt.Errorf("error writing to file %s: %w", filename, err)
 	}
+	if err := syncFile(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error flushing file %s: %w", filename, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing to file %s: %w", filename, err)
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("error replacing file %s: %w", filename, err)
+	}
+
+	// Flush the rename itself; not every platform can sync a directory, so this is best effort.
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
 	return nil
 }
 
@@ -359,14 +1249,18 @@ func (w *WalletConfig) IsValidSeed(mnemonic string) error {
 	return nil
 }
 
-// convertEurToLamports converts an amount in EUR to lamports.
-func convertEurToLamports(eurStr string, eurToSolRate decimal.Decimal) (int64, error) {
-	eurAmount, err := decimal.NewFromString(eurStr)
+// convertFiatToLamports converts an amount in a fiat currency to lamports at the SOL rate of that currency.
+func convertFiatToLamports(fiatStr string, solRate decimal.Decimal) (int64, error) {
+	fiatAmount, err := decimal.NewFromString(fiatStr)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse EUR string: %w", err)
+		return 0, fmt.Errorf("failed to parse fiat amount: %w", err)
+	}
+
+	if !solRate.IsPositive() {
+		return 0, fmt.Errorf("invalid SOL rate %s", solRate)
 	}
 
-	solAmount := eurAmount.Div(eurToSolRate)
+	solAmount := fiatAmount.Div(solRate)
 
 	lamports := solAmount.Mul(decimal.NewFromInt(1_000_000_000)).IntPart()
 
@@ -2,6 +2,7 @@
 package wallet
 
 import (
+	"context"
 	"crypto/ed25519"
 	"encoding/json"
 	"errors"
@@ -9,8 +10,12 @@ import (
 	"github.com/mr-tron/base58/base58"
 	"github.com/shopspring/decimal"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // FileReader is an interface that wraps the ReadFile method.
@@ -26,31 +31,250 @@ type FileWriter interface {
 type KeyOps struct {
 	FileReader FileReader
 	FileWriter FileWriter
+	// Passphrase is asked for passphrases when keys are encrypted or decrypted.
+	// When nil, new keys are stored unencrypted and encrypted keys cannot be read.
+	Passphrase PassphraseFunc
+	// RetryPassphrase is called when the passphrase given for alias does not decrypt its key. Returning
+	// true asks Passphrase again, up to maxPassphraseAttempts times in all. When nil, the first wrong
+	// passphrase fails.
+	RetryPassphrase func(alias string) bool
+	// Keychain holds the keys of wallets marked Keychain. Nil means OSKeychain().
+	Keychain Keychain
+	// NewKeysInKeychain stores new keys in Keychain instead of the key file, without a passphrase.
+	NewKeysInKeychain bool
+	// Dir is the profile directory holding the key file. Empty means the current directory.
+	Dir string
+	// Path is the key file. Empty means KeyFileName in Dir.
+	Path string
+	// Audit records every change to the key file when set.
+	Audit *AuditLog
+	// OnChange is called with the changes of every write to the key file when set.
+	OnChange func(action string, changes []KeystoreChange)
+	// Currency is the fiat currency balances are listed in. Empty means DefaultCurrency.
+	Currency Currency
+	// Rates fetches the rate balances are listed with. Nil means DefaultRates.
+	Rates RateProvider
+	// mu serialises read-modify-write updates within this process; see lockKeyFile.
+	mu sync.Mutex
 }
 
-const KeyFilePath = "standard.solana-keygen.json"
+// KeyFileName is the name of the key file in a profile directory.
+const KeyFileName = "keys.json"
 
-var ErrActiveWalletNotFound = errors.New("no active wallet found")
+// LegacyKeyFileName is the key file older versions kept in whatever directory they ran in.
+const LegacyKeyFileName = "standard.solana-keygen.json"
 
-// readWalletData reads and unmarshals wallet data from a given file path.
+// KeyFileEnv overrides the location of the key file, mainly for tests and scripts.
+const KeyFileEnv = "SLEENG_KEYFILE"
+
+// keyFilePath returns the location of the key file for this profile.
+func (k *KeyOps) keyFilePath() string {
+	if k.Path != "" {
+		return k.Path
+	}
+	return filepath.Join(k.Dir, KeyFileName)
+}
+
+// KeyFile returns the location of the key file.
+func (k *KeyOps) KeyFile() string {
+	return k.keyFilePath()
+}
+
+var (
+	ErrActiveWalletNotFound = errors.New("no active wallet found")
+	ErrKeyFileCorrupted     = errors.New("key file is corrupted")
+)
+
+// backupSuffix names the copy of the last good key file kept next to it.
+const backupSuffix = ".bak"
+
+// readWalletData reads and unmarshals wallet data from a given file path. A file that cannot be parsed
+// fails with ErrKeyFileCorrupted, pointing at the backup of the last good version when there is one.
 func (k *KeyOps) readWalletData(filePath string) (WalletData, error) {
-	var data WalletData
+	defer TimePhase(PhaseKeystore)()
 
 	fileData, err := k.FileReader.ReadFile(filePath)
 	if err != nil {
-		return data, fmt.Errorf("error reading file: %w", err)
+		return WalletData{}, fmt.Errorf("error reading file: %w", err)
+	}
+
+	data, err := parseWalletData(fileData)
+	if err == nil {
+		return data, nil
 	}
 
-	if err = json.Unmarshal(fileData, &data); err != nil {
+	backup := filePath + backupSuffix
+	if backupData, readErr := k.FileReader.ReadFile(backup); readErr == nil {
+		if _, parseErr := parseWalletData(backupData); parseErr == nil {
+			return WalletData{}, fmt.Errorf("%w: %s (%v); the last good version is kept in %s, check it and copy it over the key file to restore your wallets",
+				ErrKeyFileCorrupted, filePath, err, backup)
+		}
+	}
+	return WalletData{}, fmt.Errorf("%w: %s (%v)", ErrKeyFileCorrupted, filePath, err)
+}
+
+// lockKeyFile serialises read-modify-write updates of the key file: within this process with mu and,
+// when the FileWriter is a FileLocker, across processes with an advisory lock. It returns the function
+// that releases both.
+func (k *KeyOps) lockKeyFile() (func(), error) {
+	k.mu.Lock()
+
+	locker, ok := k.FileWriter.(FileLocker)
+	if !ok {
+		return k.mu.Unlock, nil
+	}
+
+	unlock, err := locker.LockFile(k.keyFilePath())
+	if err != nil {
+		k.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		k.mu.Unlock()
+	}, nil
+}
+
+// parseWalletData unmarshals and migrates the contents of a key file.
+func parseWalletData(fileData []byte) (WalletData, error) {
+	var data WalletData
+	if err := json.Unmarshal(fileData, &data); err != nil {
 		return data, fmt.Errorf("error unmarshaling JSON: %w", err)
 	}
 
+	migrateWalletData(&data)
 	return data, nil
 }
 
+// writeWalletData writes the key file and reports what the write changed to the audit log and OnChange.
+// The version it replaces is kept as a backup first, unless it is corrupted itself. A write that drops
+// or replaces private keys, as removing a wallet, encrypting the key file or moving keys to the
+// keychain do, backs up the new version instead, so the retired keys are not left behind in the
+// backup. Reporting never fails the write, which has already happened. Keys of removed keychain
+// wallets are then deleted from the keychain; failing to delete one is returned, although the key
+// file was written. Callers hold lockKeyFile.
+func (k *KeyOps) writeWalletData(action string, data WalletData) error {
+	updatedData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error marshaling JSON: %w", err)
+	}
+
+	var before WalletData
+	if previous, err := k.FileReader.ReadFile(k.keyFilePath()); err == nil {
+		if before, err = parseWalletData(previous); err == nil {
+			backup := previous
+			if retiresKeys(before, data) {
+				backup = updatedData
+			}
+			if err := k.FileWriter.WriteFile(k.keyFilePath()+backupSuffix, backup); err != nil {
+				return err
+			}
+		} else {
+			before = WalletData{}
+		}
+	}
+
+	if err := k.FileWriter.WriteFile(k.keyFilePath(), updatedData); err != nil {
+		return err
+	}
+
+	// Compare against what was written, so in-memory migrations are not reported as changes.
+	after := data
+	migrateWalletData(&after)
+	keychainErr := k.removeFromKeychain(before, after)
+	changes := DiffWalletData(before, after)
+	if len(changes) == 0 {
+		return keychainErr
+	}
+
+	if k.Audit != nil {
+		_ = k.Audit.Append(AuditEntry{Time: time.Now().UTC(), Action: action, Changes: changes})
+	}
+	if k.OnChange != nil {
+		k.OnChange(action, changes)
+	}
+
+	return keychainErr
+}
+
+// retiresKeys reports whether after no longer holds a private key that before stored in the key file,
+// because its wallet was removed or the key was encrypted, re-encrypted or moved elsewhere. Renaming a
+// wallet keeps its key.
+func retiresKeys(before, after WalletData) bool {
+	kept := make(map[string]bool, len(after.Wallets))
+	for _, wallet := range after.Wallets {
+		kept[wallet.PrivateKey] = true
+	}
+	for _, wallet := range before.Wallets {
+		if wallet.PrivateKey != "" && !kept[wallet.PrivateKey] {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateWalletData upgrades entries written by older versions. It only changes the data in memory;
+// the upgrade is persisted the next time the key file is written.
+func migrateWalletData(data *WalletData) {
+	for alias, wallet := range data.Wallets {
+		if wallet.Derivation == nil {
+			wallet.Derivation = &KeyDerivation{Path: DerivationUnknown}
+		}
+		if wallet.Usage == nil {
+			wallet.Usage = &KeyUsage{}
+		}
+		data.Wallets[alias] = wallet
+	}
+}
+
+// WalletInfo describes a stored wallet without exposing its private key.
+type WalletInfo struct {
+	Alias      string
+	PublicKey  string
+	Active     bool
+	Encrypted  bool
+	WatchOnly  bool
+	Keychain   bool
+	Derivation KeyDerivation
+	Note       WalletNote
+	Usage      KeyUsage
+}
+
+// GetWalletInfo describes a stored wallet. An empty alias describes the active wallet.
+func (k *KeyOps) GetWalletInfo(alias string) (WalletInfo, error) {
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return WalletInfo{}, err
+	}
+
+	if alias == "" {
+		alias = data.ActiveAlias
+	}
+
+	wallet, exists := data.Wallets[alias]
+	if !exists {
+		if alias == "" {
+			return WalletInfo{}, ErrActiveWalletNotFound
+		}
+		return WalletInfo{}, fmt.Errorf("no wallet found for alias: %s", alias)
+	}
+
+	return WalletInfo{
+		Alias:      alias,
+		PublicKey:  wallet.PublicKey,
+		Active:     alias == data.ActiveAlias,
+		Encrypted:  wallet.Encrypted,
+		WatchOnly:  wallet.WatchOnly,
+		Keychain:   wallet.Keychain,
+		Derivation: *wallet.Derivation,
+		Note:       WalletNote{Text: wallet.Note, Icon: wallet.Icon},
+		Usage:      *wallet.Usage,
+	}, nil
+}
+
 // GetCurrentPrivateKey retrieves the current active wallet's private key.
 func (k *KeyOps) GetCurrentPrivateKey() (string, error) {
-	data, err := k.readWalletData(KeyFilePath)
+	data, err := k.readWalletData(k.keyFilePath())
 	if err != nil {
 		return "", err
 	}
@@ -59,7 +283,7 @@ func (k *KeyOps) GetCurrentPrivateKey() (string, error) {
 	if !exists {
 		return "", ErrActiveWalletNotFound
 	}
-	ret, err := getPrivateKeyFromSolCLICompStr(activeWallet.PrivateKey)
+	ret, err := k.decodePrivateKey(data.ActiveAlias, activeWallet)
 	if err != nil {
 		return "", err
 	}
@@ -69,7 +293,7 @@ func (k *KeyOps) GetCurrentPrivateKey() (string, error) {
 
 // GetPrivateKeyByAlias retrieves a wallet's private key by its alias.
 func (k *KeyOps) GetPrivateKeyByAlias(alias string) (string, error) {
-	data, err := k.readWalletData(KeyFilePath)
+	data, err := k.readWalletData(k.keyFilePath())
 	if err != nil {
 		return "", err
 	}
@@ -79,12 +303,93 @@ func (k *KeyOps) GetPrivateKeyByAlias(alias string) (string, error) {
 		return "", fmt.Errorf("no wallet found for alias: %s", alias)
 	}
 
-	return wallet.PrivateKey, nil
+	if wallet.WatchOnly {
+		return "", fmt.Errorf("%s: %w", alias, ErrWatchOnlyWallet)
+	}
+	if !wallet.Encrypted && !wallet.Keychain {
+		return wallet.PrivateKey, nil
+	}
+
+	key, err := k.decodePrivateKey(alias, wallet)
+	if err != nil {
+		return "", err
+	}
+
+	return getSolCLIComptKey(key), nil
+}
+
+// decodePrivateKey returns the raw private key of a wallet, decrypting it or reading it from the
+// keychain when needed.
+func (k *KeyOps) decodePrivateKey(alias string, wallet Wallet) (ed25519.PrivateKey, error) {
+	if wallet.WatchOnly {
+		return nil, fmt.Errorf("%s: %w", alias, ErrWatchOnlyWallet)
+	}
+	if wallet.Keychain {
+		return k.readFromKeychain(alias, wallet.PublicKey)
+	}
+	if !wallet.Encrypted {
+		return getPrivateKeyFromSolCLICompStr(wallet.PrivateKey)
+	}
+
+	if k.Passphrase == nil {
+		return nil, ErrPassphraseRequired
+	}
+
+	for attempt := 1; ; attempt++ {
+		passphrase, err := k.Passphrase(alias, false)
+		if err != nil {
+			return nil, fmt.Errorf("error reading passphrase: %w", err)
+		}
+
+		key, err := decryptPrivateKey(wallet.PrivateKey, wallet.Salt, wallet.Nonce, passphrase)
+		if err == nil {
+			return key, nil
+		}
+		if !errors.Is(err, ErrWrongPassphrase) || attempt == maxPassphraseAttempts ||
+			k.RetryPassphrase == nil || !k.RetryPassphrase(alias) {
+			return nil, fmt.Errorf("unable to decrypt wallet %s: %w", alias, err)
+		}
+	}
+}
+
+// newWalletEntry builds the stored form of a key, encrypting it when a passphrase is given. A key
+// bound for the keychain is only marked; it is stored there once the key file can take it.
+func (k *KeyOps) newWalletEntry(alias string, key ed25519.PrivateKey, walletAddress string) (Wallet, error) {
+	entry := Wallet{Balance: decimal.Zero, PublicKey: walletAddress}
+	if k.NewKeysInKeychain {
+		entry.Keychain = true
+		return entry, nil
+	}
+
+	var passphrase string
+	if k.Passphrase != nil {
+		var err error
+		passphrase, err = k.Passphrase(alias, true)
+		if err != nil {
+			return Wallet{}, fmt.Errorf("error reading passphrase: %w", err)
+		}
+	}
+
+	if passphrase == "" {
+		entry.PrivateKey = getSolCLIComptKey(key)
+		return entry, nil
+	}
+
+	ciphertext, salt, nonce, err := encryptPrivateKey(key, passphrase)
+	if err != nil {
+		return Wallet{}, err
+	}
+
+	entry.PrivateKey = ciphertext
+	entry.Salt = salt
+	entry.Nonce = nonce
+	entry.Encrypted = true
+	return entry, nil
 }
 
 // IsKeyFilePresent checks if there is a file containing some keys already in place.
 func (k *KeyOps) IsKeyFilePresent() (bool, error) {
-	_, err := k.FileReader.ReadFile(KeyFilePath)
+	_, err := k.FileReader.ReadFile(k.keyFilePath())
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return false, nil
@@ -97,7 +402,13 @@ func (k *KeyOps) IsKeyFilePresent() (bool, error) {
 
 // SetActiveKey sets the active key to the alias specified.
 func (k *KeyOps) SetActiveKey(aliasToActivate string) error {
-	data, err := k.readWalletData(KeyFilePath)
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := k.readWalletData(k.keyFilePath())
 	if err != nil {
 		return err
 	}
@@ -108,17 +419,12 @@ func (k *KeyOps) SetActiveKey(aliasToActivate string) error {
 
 	data.ActiveAlias = aliasToActivate
 
-	updatedData, err := json.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("error marshaling JSON: %w", err)
-	}
-
-	return k.FileWriter.WriteFile(KeyFilePath, updatedData)
+	return k.writeWalletData("switch", data)
 }
 
 // GetCurrentPublicKey retrieves the current active wallet's public key.
 func (k *KeyOps) GetCurrentPublicKey() (string, error) {
-	data, err := k.readWalletData(KeyFilePath)
+	data, err := k.readWalletData(k.keyFilePath())
 	if err != nil {
 		return "", err
 	}
@@ -133,7 +439,7 @@ func (k *KeyOps) GetCurrentPublicKey() (string, error) {
 
 // GetPublicKeyByAlias retrieves a wallet's public key by its alias.
 func (k *KeyOps) GetPublicKeyByAlias(alias string) (string, error) {
-	data, err := k.readWalletData(KeyFilePath)
+	data, err := k.readWalletData(k.keyFilePath())
 	if err != nil {
 		return "", err
 	}
@@ -148,6 +454,24 @@ func (k *KeyOps) GetPublicKeyByAlias(alias string) (string, error) {
 
 // WriteKeyToFile writes a new key to the key file.
 func (k *KeyOps) WriteKeyToFile(alias string, key ed25519.PrivateKey, walletAddress string) error {
+	return k.WriteDerivedKeyToFile(alias, key, walletAddress, KeyDerivation{Path: DerivationNone})
+}
+
+// WriteDerivedKeyToFile writes a new key to the key file, recording how it was derived.
+func (k *KeyOps) WriteDerivedKeyToFile(alias string, key ed25519.PrivateKey, walletAddress string, derivation KeyDerivation) error {
+	// Build the entry first: it may prompt for a passphrase, which must not happen under the lock.
+	entry, err := k.newWalletEntry(alias, key, walletAddress)
+	if err != nil {
+		return err
+	}
+	entry.Derivation = &derivation
+
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	var data WalletData
 	fileExists, err := k.IsKeyFilePresent()
 
@@ -156,7 +480,7 @@ func (k *KeyOps) WriteKeyToFile(alias string, key ed25519.PrivateKey, walletAddr
 	}
 
 	if fileExists {
-		data, err = k.readWalletData(KeyFilePath)
+		data, err = k.readWalletData(k.keyFilePath())
 		if err != nil {
 			return err
 		}
@@ -164,34 +488,280 @@ func (k *KeyOps) WriteKeyToFile(alias string, key ed25519.PrivateKey, walletAddr
 		data.Wallets = make(map[string]Wallet)
 	}
 
-	if _, exists := data.Wallets[alias]; exists {
-		return fmt.Errorf("alias already exists: %s", alias)
+	if err := data.checkNameFree(alias); err != nil {
+		return err
+	}
+	if err := data.checkAddressFree(walletAddress); err != nil {
+		return err
+	}
+
+	if entry.Keychain {
+		if err := k.storeInKeychain(walletAddress, key); err != nil {
+			return err
+		}
 	}
 
-	solanaCliCompatiblekey := getSolCLIComptKey(key)
-	data.Wallets[alias] = Wallet{PrivateKey: solanaCliCompatiblekey, Balance: decimal.Zero, PublicKey: walletAddress}
+	data.Wallets[alias] = entry
 	data.ActiveAlias = alias
 
-	updatedData, err := json.Marshal(data)
+	return k.writeWalletData("add", data)
+}
+
+// GetNetwork returns the network selection persisted in the key file. Both values are empty
+// when no key file exists yet or nothing has been selected.
+func (k *KeyOps) GetNetwork() (string, string, error) {
+	fileExists, err := k.IsKeyFilePresent()
 	if err != nil {
-		return fmt.Errorf("error marshaling JSON: %w", err)
+		return "", "", fmt.Errorf("error checking if keys are already present: %w", err)
+	}
+
+	if !fileExists {
+		return "", "", nil
+	}
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return "", "", err
 	}
 
-	return k.FileWriter.WriteFile(KeyFilePath, updatedData)
+	return data.Network, data.RPCURL, nil
 }
 
-// PrintAllKeys prints all keys in the key file.
+// SetNetwork persists the network selection, creating the key file if needed.
+func (k *KeyOps) SetNetwork(network, rpcURL string) error {
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var data WalletData
+	fileExists, err := k.IsKeyFilePresent()
+	if err != nil {
+		return fmt.Errorf("error checking if keys are already present: %w", err)
+	}
+
+	if fileExists {
+		data, err = k.readWalletData(k.keyFilePath())
+		if err != nil {
+			return err
+		}
+	} else {
+		data.Wallets = make(map[string]Wallet)
+	}
+
+	if data.Network == network && data.RPCURL == rpcURL {
+		return nil
+	}
+
+	data.Network = network
+	data.RPCURL = rpcURL
+
+	return k.writeWalletData("network", data)
+}
+
+// EncryptKeys upgrades every unencrypted key in the key file in place, encrypting it with the passphrase.
+// It returns the aliases that were encrypted.
+func (k *KeyOps) EncryptKeys(passphrase string) ([]string, error) {
+	plan, err := k.PlanEncryptKeys(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := k.ApplyPlan(plan); err != nil {
+		return nil, err
+	}
+
+	return plan.Aliases(), nil
+}
+
+// PlanEncryptKeys computes what EncryptKeys would change without writing anything. The plan holds
+// the ciphertext, so applying it later uses the same passphrase.
+func (k *KeyOps) PlanEncryptKeys(passphrase string) (*KeystorePlan, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	return k.planChange("encrypt", func(data *WalletData) error {
+		for alias, wallet := range data.Wallets {
+			if wallet.Encrypted || wallet.WatchOnly || wallet.Keychain {
+				continue
+			}
+
+			key, err := getPrivateKeyFromSolCLICompStr(wallet.PrivateKey)
+			if err != nil {
+				return fmt.Errorf("error reading key for %s: %w", alias, err)
+			}
+
+			ciphertext, salt, nonce, err := encryptPrivateKey(key, passphrase)
+			if err != nil {
+				return err
+			}
+
+			wallet.PrivateKey = ciphertext
+			wallet.Salt = salt
+			wallet.Nonce = nonce
+			wallet.Encrypted = true
+			data.Wallets[alias] = wallet
+		}
+		return nil
+	})
+}
+
+// ListAliases returns the aliases of all stored wallets in alphabetical order.
+func (k *KeyOps) ListAliases() ([]string, error) {
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := make([]string, 0, len(data.Wallets))
+	for alias := range data.Wallets {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+	return aliases, nil
+}
+
+// RenameKey gives a stored wallet a new alias, keeping it active if it was.
+func (k *KeyOps) RenameKey(oldAlias, newAlias string) error {
+	if newAlias == "" {
+		return errors.New("new alias must not be empty")
+	}
+
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return err
+	}
+
+	wallet, exists := data.Wallets[oldAlias]
+	if !exists {
+		return fmt.Errorf("no wallet found for alias: %s", oldAlias)
+	}
+	if err := data.checkNameFree(newAlias); err != nil {
+		return err
+	}
+
+	delete(data.Wallets, oldAlias)
+	data.Wallets[newAlias] = wallet
+	if data.ActiveAlias == oldAlias {
+		data.ActiveAlias = newAlias
+	}
+
+	return k.writeWalletData("rename", data)
+}
+
+// DeleteKey removes a wallet from the key file. When it was the active wallet, the first remaining
+// alias in alphabetical order becomes active; the new active alias is returned, empty if none is left.
+func (k *KeyOps) DeleteKey(alias string) (string, error) {
+	plan, err := k.PlanDeleteKey(alias)
+	if err != nil {
+		return "", err
+	}
+
+	if err := k.ApplyPlan(plan); err != nil {
+		return "", err
+	}
+
+	return plan.ActiveAlias, nil
+}
+
+// PlanDeleteKey computes what DeleteKey would change without writing anything.
+func (k *KeyOps) PlanDeleteKey(alias string) (*KeystorePlan, error) {
+	return k.planChange("remove", func(data *WalletData) error {
+		if _, exists := data.Wallets[alias]; !exists {
+			return fmt.Errorf("no wallet found for alias: %s", alias)
+		}
+
+		delete(data.Wallets, alias)
+		if data.ActiveAlias == alias {
+			data.ActiveAlias = ""
+			remaining := make([]string, 0, len(data.Wallets))
+			for remainingAlias := range data.Wallets {
+				remaining = append(remaining, remainingAlias)
+			}
+			if len(remaining) > 0 {
+				sort.Strings(remaining)
+				data.ActiveAlias = remaining[0]
+			}
+		}
+		return nil
+	})
+}
+
+// ExportFormat is the encoding of an exported private key.
+type ExportFormat string
+
+const (
+	// ExportBase58 is the encoding used by browser wallets such as Phantom and by --key.
+	ExportBase58 ExportFormat = "base58"
+	// ExportByteArray is the JSON byte array written by solana-keygen.
+	ExportByteArray ExportFormat = "bytes"
+)
+
+// ParseExportFormat validates a user supplied export format.
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch f := ExportFormat(strings.ToLower(s)); f {
+	case ExportBase58, ExportByteArray:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q: use %s or %s", s, ExportBase58, ExportByteArray)
+	}
+}
+
+// ExportKey returns the private key of a stored wallet in the given format, decrypting it when needed.
+func (k *KeyOps) ExportKey(alias string, format ExportFormat) (string, error) {
+	data, err := k.readWalletData(k.keyFilePath())
+	if err != nil {
+		return "", err
+	}
+
+	wallet, exists := data.Wallets[alias]
+	if !exists {
+		return "", fmt.Errorf("no wallet found for alias: %s", alias)
+	}
+
+	key, err := k.decodePrivateKey(alias, wallet)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case ExportBase58:
+		return base58.Encode(key), nil
+	case ExportByteArray:
+		return getSolCLIComptKey(key), nil
+	default:
+		return "", fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// PrintAllKeys prints all keys in the key file with their cached balance and its age. It never asks the
+// cluster for balances; they are refreshed by SyncBalances and balance queries. Balances are shown in
+// SOL when no exchange rate is available.
 func (k *KeyOps) PrintAllKeys() ([]string, map[string]string, error) {
-	data, err := k.readWalletData(KeyFilePath)
+	data, err := k.readWalletData(k.keyFilePath())
 	if err != nil {
 		return nil, nil, err
 	}
 
-	shouldPrintBalance := true
-	rate, err := fetchSOLEURRate()
-	if err != nil {
-		shouldPrintBalance = false
-		return nil, nil, fmt.Errorf("error fetching SOL to EUR rate: %v", err)
+	// The rate is only needed, and fetched, when a balance has been synced.
+	var rate *decimal.Decimal
+	for _, wallet := range data.Wallets {
+		if wallet.LastSynced == nil {
+			continue
+		}
+		if value, err := ratesOrDefault(k.Rates).SOLRate(context.TODO(), k.Currency.orDefault()); err == nil {
+			rate = &value
+		}
+		break
 	}
 
 	aliases := make([]string, 0, len(data.Wallets))
@@ -203,9 +773,22 @@ func (k *KeyOps) PrintAllKeys() ([]string, map[string]string, error) {
 			displayAlias += " (Active)"
 		}
 
-		if shouldPrintBalance {
-			eurBalance := wallet.Balance.Mul(rate)
-			displayAlias += fmt.Sprintf(" // BAL - (€ %s)", eurBalance.StringFixed(2))
+		if wallet.WatchOnly {
+			displayAlias += " (watch-only)"
+		}
+
+		if label := (WalletNote{Text: wallet.Note, Icon: wallet.Icon}).Label(); label != "" {
+			displayAlias += " " + label
+		}
+
+		switch {
+		case wallet.LastSynced == nil:
+			displayAlias += " // BAL - (not synced)"
+		case rate != nil:
+			fiatBalance := wallet.Balance.Mul(*rate)
+			displayAlias += fmt.Sprintf(" // BAL - (%s, %s)", k.Currency.Format(fiatBalance), syncAge(time.Since(*wallet.LastSynced)))
+		default:
+			displayAlias += fmt.Sprintf(" // BAL - (%s SOL, %s)", wallet.Balance, syncAge(time.Since(*wallet.LastSynced)))
 		}
 
 		aliases = append(aliases, displayAlias)
@@ -4,10 +4,13 @@ import (
 	"crypto/ed25519"
 	"encoding/json"
 	"errors"
+	"github.com/mr-tron/base58/base58"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"sort"
 	"testing"
+	"time"
 )
 
 type MockFileReader struct {
@@ -239,6 +242,7 @@ func TestWriteKeyToFile(t *testing.T) {
 	tests := []struct {
 		name        string
 		alias       string
+		address     string
 		mockError   error
 		fileExists  bool
 		expectedErr error
@@ -264,6 +268,14 @@ func TestWriteKeyToFile(t *testing.T) {
 			fileExists:  true,
 			expectedErr: errors.New("alias already exists: existing"),
 		},
+		{
+			name:        "Address Already Stored",
+			alias:       "newkey",
+			address:     "existingAddress",
+			mockError:   nil,
+			fileExists:  true,
+			expectedErr: errors.New("address existingAddress is already stored as existing"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -279,13 +291,17 @@ func TestWriteKeyToFile(t *testing.T) {
 			if tt.fileExists {
 				mockFileReader.mockFileData = jsonMarshal(t, WalletData{
 					Wallets: map[string]Wallet{
-						"existing": {PrivateKey: "existingkey"},
+						"existing": {PrivateKey: "existingkey", PublicKey: "existingAddress"},
 					},
 				})
 			}
+			address := tt.address
+			if address == "" {
+				address = "walletAddress"
+			}
 
 			key := ed25519.PrivateKey("23YcmrXnN9C74zNP6pzkqfCqQKVTNk93rGu8C5fVyw4KPsXeQgqtC7YTPkx1vZJrg6mqYuEUgAFdoxXiU2UrBPZe")
-			err := ops.WriteKeyToFile(tt.alias, key, "walletAddress")
+			err := ops.WriteKeyToFile(tt.alias, key, address)
 
 			if err != nil {
 				assert.Equal(t, tt.expectedErr.Error(), err.Error())
@@ -297,26 +313,39 @@ func TestWriteKeyToFile(t *testing.T) {
 }
 
 func TestPrintAllKeys(t *testing.T) {
+	synced := time.Now().Add(-2*time.Hour - time.Minute)
+	twoWallets := WalletData{
+		ActiveAlias: "active",
+		Wallets: map[string]Wallet{
+			"active":   {PrivateKey: "activekey", Balance: decimal.NewFromInt(10), LastSynced: &synced},
+			"inactive": {PrivateKey: "inactivekey", Balance: decimal.Zero},
+		},
+	}
+
 	tests := []struct {
-		name         string
-		mockFileData WalletData
-		mockError    error
-		expectedErr  error
+		name            string
+		mockFileData    WalletData
+		mockError       error
+		rates           RateProvider
+		expectedAliases []string
+		expectedErr     error
 	}{
 		{
-			name: "Success",
-			mockFileData: WalletData{
-				ActiveAlias: "active",
-				Wallets: map[string]Wallet{
-					"active":   {PrivateKey: "activekey", Balance: decimal.NewFromInt(10)},
-					"inactive": {PrivateKey: "inactivekey", Balance: decimal.NewFromInt(20)},
-				},
-			},
-			expectedErr: nil,
+			name:            "Success",
+			mockFileData:    twoWallets,
+			rates:           StaticRate(decimal.NewFromInt(2)),
+			expectedAliases: []string{"active (Active) // BAL - (€20.00, synced 2h ago)", "inactive // BAL - (not synced)"},
+		},
+		{
+			name:            "Rate Unavailable",
+			mockFileData:    twoWallets,
+			rates:           failingRates{},
+			expectedAliases: []string{"active (Active) // BAL - (10 SOL, synced 2h ago)", "inactive // BAL - (not synced)"},
 		},
 		{
 			name:        "File Read Error",
 			mockError:   errors.New("read error"),
+			rates:       StaticRate(decimal.NewFromInt(2)),
 			expectedErr: errors.New("error reading file: read error"),
 		},
 	}
@@ -330,14 +359,17 @@ func TestPrintAllKeys(t *testing.T) {
 
 			ops := &KeyOps{
 				FileReader: mockFileReader,
+				Rates:      tt.rates,
 			}
 
-			_, _, err := ops.PrintAllKeys()
+			aliases, _, err := ops.PrintAllKeys()
 
 			if err != nil {
 				assert.Equal(t, tt.expectedErr.Error(), err.Error())
 			} else {
 				assert.Equal(t, tt.expectedErr, err)
+				sort.Strings(aliases)
+				assert.Equal(t, tt.expectedAliases, aliases)
 			}
 		})
 	}
@@ -355,3 +387,300 @@ func jsonMarshal(t *testing.T, data WalletData) []byte {
 
 	return b
 }
+
+func TestEncryptedKeys(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	passphraseFor := func(passphrase string) PassphraseFunc {
+		return func(string, bool) (string, error) { return passphrase, nil }
+	}
+
+	tests := []struct {
+		name            string
+		writeWith       PassphraseFunc
+		readWith        PassphraseFunc
+		encryptAfter    string
+		expectEncrypted bool
+		expectedErr     error
+	}{
+		{
+			name:            "Encrypted Round Trip",
+			writeWith:       passphraseFor("correct horse"),
+			readWith:        passphraseFor("correct horse"),
+			expectEncrypted: true,
+		},
+		{
+			name:            "Wrong Passphrase",
+			writeWith:       passphraseFor("correct horse"),
+			readWith:        passphraseFor("battery staple"),
+			expectEncrypted: true,
+			expectedErr:     ErrWrongPassphrase,
+		},
+		{
+			name:            "Missing Passphrase",
+			writeWith:       passphraseFor("correct horse"),
+			expectEncrypted: true,
+			expectedErr:     ErrPassphraseRequired,
+		},
+		{
+			name: "Legacy Plaintext Still Readable",
+		},
+		{
+			name:      "Empty Passphrase Stores Plaintext",
+			writeWith: passphraseFor(""),
+		},
+		{
+			name:            "Migrated In Place",
+			encryptAfter:    "correct horse",
+			readWith:        passphraseFor("correct horse"),
+			expectEncrypted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			ops := &KeyOps{FileReader: files, FileWriter: files, Passphrase: tt.writeWith}
+
+			assert.NoError(t, ops.WriteKeyToFile("main", key, "walletAddress"))
+
+			if tt.encryptAfter != "" {
+				encrypted, err := ops.EncryptKeys(tt.encryptAfter)
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"main"}, encrypted)
+			}
+
+			data, err := ops.readWalletData(KeyFileName)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectEncrypted, data.Wallets["main"].Encrypted)
+
+			ops.Passphrase = tt.readWith
+			got, err := ops.GetCurrentPrivateKey()
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, base58.Encode(key), got)
+
+			byAlias, err := ops.GetPrivateKeyByAlias("main")
+			assert.NoError(t, err)
+			assert.Equal(t, getSolCLIComptKey(key), byAlias)
+		})
+	}
+}
+
+func TestWrongPassphraseIsAskedAgain(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	files := newMemFiles()
+	ops := &KeyOps{FileReader: files, FileWriter: files, Passphrase: func(string, bool) (string, error) { return "correct horse", nil }}
+	assert.NoError(t, ops.WriteKeyToFile("main", key, "walletAddress"))
+
+	typed := []string{"battery staple", "correct horse"}
+	var asked, retried int
+	ops.Passphrase = func(string, bool) (string, error) {
+		asked++
+		return typed[(asked-1)%len(typed)], nil
+	}
+	ops.RetryPassphrase = func(alias string) bool {
+		assert.Equal(t, "main", alias)
+		retried++
+		return true
+	}
+
+	got, err := ops.GetCurrentPrivateKey()
+	assert.NoError(t, err)
+	assert.Equal(t, base58.Encode(key), got)
+	assert.Equal(t, []int{2, 1}, []int{asked, retried})
+
+	// Retrying stops after maxPassphraseAttempts.
+	asked, retried = 0, 0
+	typed = []string{"battery staple"}
+	_, err = ops.GetCurrentPrivateKey()
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+	assert.Equal(t, []int{maxPassphraseAttempts, maxPassphraseAttempts - 1}, []int{asked, retried})
+}
+
+func TestRenameKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		oldAlias       string
+		newAlias       string
+		expectedActive string
+		expectedErr    error
+	}{
+		{name: "Active Wallet Stays Active", oldAlias: "main", newAlias: "daily", expectedActive: "daily"},
+		{name: "Other Wallet", oldAlias: "savings", newAlias: "vault", expectedActive: "main"},
+		{name: "Unknown Alias", oldAlias: "missing", newAlias: "vault", expectedErr: errors.New("no wallet found for alias: missing")},
+		{name: "Alias Taken", oldAlias: "savings", newAlias: "main", expectedErr: errors.New("alias already exists: main")},
+		{name: "Empty Alias", oldAlias: "savings", newAlias: "", expectedErr: errors.New("new alias must not be empty")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			ops := &KeyOps{FileReader: files, FileWriter: files}
+			assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+				ActiveAlias: "main",
+				Wallets: map[string]Wallet{
+					"main":    {PublicKey: "MainAddr", PrivateKey: "secret-1"},
+					"savings": {PublicKey: "SavingsAddr", PrivateKey: "secret-2", Note: "cold"},
+				},
+			})))
+
+			err := ops.RenameKey(tt.oldAlias, tt.newAlias)
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+
+			data, err := ops.readWalletData(KeyFileName)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedActive, data.ActiveAlias)
+			assert.NotContains(t, data.Wallets, tt.oldAlias)
+			assert.Contains(t, data.Wallets, tt.newAlias)
+			assert.Len(t, data.Wallets, 2)
+		})
+	}
+
+	t.Run("Keeps Note", func(t *testing.T) {
+		files := newMemFiles()
+		ops := &KeyOps{FileReader: files, FileWriter: files}
+		assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+			Wallets: map[string]Wallet{"savings": {PublicKey: "SavingsAddr", Note: "cold"}},
+		})))
+
+		assert.NoError(t, ops.RenameKey("savings", "vault"))
+		info, err := ops.GetWalletInfo("vault")
+		assert.NoError(t, err)
+		assert.Equal(t, "SavingsAddr", info.PublicKey)
+		assert.Equal(t, "cold", info.Note.Text)
+	})
+}
+
+func TestDeleteKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		alias          string
+		wallets        []string
+		expectedActive string
+		expectedErr    error
+	}{
+		{name: "Inactive Wallet", alias: "savings", wallets: []string{"main", "savings"}, expectedActive: "main"},
+		{name: "Active Wallet Picks First Remaining", alias: "main", wallets: []string{"main", "zeta", "beta"}, expectedActive: "beta"},
+		{name: "Last Wallet", alias: "main", wallets: []string{"main"}, expectedActive: ""},
+		{name: "Unknown Alias", alias: "missing", wallets: []string{"main"}, expectedErr: errors.New("no wallet found for alias: missing")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			ops := &KeyOps{FileReader: files, FileWriter: files}
+			data := WalletData{ActiveAlias: "main", Wallets: map[string]Wallet{}}
+			for _, alias := range tt.wallets {
+				data.Wallets[alias] = Wallet{PublicKey: alias + "Addr"}
+			}
+			assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, data)))
+
+			active, err := ops.DeleteKey(tt.alias)
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedActive, active)
+
+			stored, err := ops.readWalletData(KeyFileName)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedActive, stored.ActiveAlias)
+			assert.NotContains(t, stored.Wallets, tt.alias)
+			assert.Len(t, stored.Wallets, len(tt.wallets)-1)
+		})
+	}
+
+	t.Run("Write Error", func(t *testing.T) {
+		ops := &KeyOps{
+			FileReader: &MockFileReader{mockFileData: jsonMarshal(t, WalletData{Wallets: map[string]Wallet{"main": {}}})},
+			FileWriter: &MockFileWriter{mockWriteError: errors.New("disk full")},
+		}
+		_, err := ops.DeleteKey("main")
+		assert.EqualError(t, err, "disk full")
+	})
+}
+
+func TestExportKey(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	passphrase := func(string, bool) (string, error) { return "correct horse", nil }
+
+	tests := []struct {
+		name        string
+		encrypted   bool
+		format      ExportFormat
+		readWith    PassphraseFunc
+		expected    string
+		expectedErr error
+	}{
+		{name: "Base58", format: ExportBase58, expected: base58.Encode(key)},
+		{name: "Solana CLI Byte Array", format: ExportByteArray, expected: getSolCLIComptKey(key)},
+		{name: "Encrypted Key Is Decrypted", encrypted: true, format: ExportBase58, readWith: passphrase, expected: base58.Encode(key)},
+		{name: "Encrypted Key Without Passphrase", encrypted: true, format: ExportBase58, expectedErr: ErrPassphraseRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			ops := &KeyOps{FileReader: files, FileWriter: files}
+			if tt.encrypted {
+				ops.Passphrase = passphrase
+			}
+			assert.NoError(t, ops.WriteKeyToFile("main", key, "walletAddress"))
+
+			ops.Passphrase = tt.readWith
+			got, err := ops.ExportKey("main", tt.format)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("Byte Array Round Trips", func(t *testing.T) {
+		files := newMemFiles()
+		ops := &KeyOps{FileReader: files, FileWriter: files}
+		assert.NoError(t, ops.WriteKeyToFile("main", key, "walletAddress"))
+
+		exported, err := ops.ExportKey("main", ExportByteArray)
+		assert.NoError(t, err)
+
+		var bytes []byte
+		var ints []int
+		assert.NoError(t, json.Unmarshal([]byte(exported), &ints))
+		for _, n := range ints {
+			bytes = append(bytes, byte(n))
+		}
+		assert.Equal(t, []byte(key), bytes)
+	})
+
+	t.Run("Unknown Alias", func(t *testing.T) {
+		files := newMemFiles()
+		ops := &KeyOps{FileReader: files, FileWriter: files}
+		assert.NoError(t, ops.WriteKeyToFile("main", key, "walletAddress"))
+
+		_, err := ops.ExportKey("missing", ExportBase58)
+		assert.EqualError(t, err, "no wallet found for alias: missing")
+	})
+}
+
+func TestParseExportFormat(t *testing.T) {
+	for input, expected := range map[string]ExportFormat{"base58": ExportBase58, "BYTES": ExportByteArray} {
+		got, err := ParseExportFormat(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, got)
+	}
+
+	_, err := ParseExportFormat("hex")
+	assert.Error(t, err)
+}
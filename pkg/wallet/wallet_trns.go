@@ -4,34 +4,108 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/semaphore"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
 )
 
 const (
 	transferInstructionType uint32 = 2
 	rpcTimeout                     = 10 * time.Second // 10 seconds
 	maxConcurrentRequests          = 50
+	// maxSignaturesPerPage is the most signatures getSignaturesForAddress returns per call.
+	maxSignaturesPerPage = 1000
+	// maxSupportedTransactionVersion is the newest transaction version decoded. Without it the node
+	// refuses to return versioned transactions at all.
+	maxSupportedTransactionVersion uint64 = 0
 	//systemProgramIDStr represents the system program ID for the solana chain which tells us more about the nature of instruction.
 	systemProgramIDStr = "11111111111111111111111111111111"
+
+	// The SPL Token program identifies its instructions by the first data byte.
+	tokenTransferInstructionType        byte = 3
+	tokenTransferCheckedInstructionType byte = 12
 )
 
-// Transaction represents a single transaction.
+// legacyMemoProgramID is the first version of the memo program, which older wallets and exchanges
+// still record memos with. Transfers made here use solana.MemoProgramID.
+var legacyMemoProgramID = solana.MustPublicKeyFromBase58("Memo1UhkJRfHyvLMcVucJwxXeuD728EqVDDwQDxFMNo")
+
+// GetTransactionHistoryOpts bounds how much history is fetched.
+type GetTransactionHistoryOpts struct {
+	// Limit is the maximum number of transactions to fetch, newest first. Zero means no limit.
+	Limit int
+	// Since stops the history at transactions older than this time. The zero value means no bound.
+	Since time.Time
+	// Until skips transactions newer than this time; they do not count towards Limit. The zero value
+	// means no bound.
+	Until time.Time
+	// Concurrency bounds how many transactions are fetched at once. Zero means maxConcurrentRequests.
+	// It is halved automatically while the node is rate limiting.
+	Concurrency int
+	// MaxAttempts bounds how often a failing request is tried. Zero means DefaultRetryPolicy.
+	MaxAttempts int
+	// RewardEpochs adds the staking rewards of the wallet's stake accounts in this many of the latest
+	// completed epochs, within Since and Until. Rewards do not count towards Limit. Zero leaves them out.
+	RewardEpochs int
+	// Cache keeps the history on disk so only new transactions are fetched. Nil fetches everything.
+	Cache *HistoryCache
+	// Resync rebuilds the cached history from the node instead of reusing it.
+	Resync bool
+}
+
+// retryPolicy returns the retry policy for history requests.
+func (o GetTransactionHistoryOpts) retryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy
+	if o.MaxAttempts > 0 {
+		policy.MaxAttempts = o.MaxAttempts
+	}
+	return policy
+}
+
+// Transaction represents a single transaction. Amount is in lamports for SOL transfers and in the
+// token's base units for SPL token transfers, which have a non-zero Mint. Fee is the fee of the
+// whole transaction in lamports when the wallet paid it, so every transfer of the transaction carries it.
+// A staking reward is paid into the stake account From, which is also To, and has no signature.
 type Transaction struct {
+	Signature solana.Signature
+	Slot      uint64
 	Amount    uint64
+	Fee       uint64
 	From      solana.PublicKey
 	To        solana.PublicKey
 	Timestamp time.Time
 	IsSender  bool
+	Mint      solana.PublicKey
+	Symbol    string
+	Decimals  uint8
+	// Reward is set for the inflation reward of a stake account, earned in Epoch.
+	Reward bool
+	Epoch  uint64
+	// Memo is the text recorded with the memo program in the same transaction, empty without one.
+	Memo string
+}
+
+// IsToken reports whether the transaction is an SPL token transfer rather than a SOL transfer.
+func (t *Transaction) IsToken() bool {
+	return !t.Mint.IsZero()
 }
 
-// decodeSystemTransfer decodes a system transfer instruction from a transaction.
+// TokenAmount returns the amount of an SPL token transfer in whole tokens.
+func (t *Transaction) TokenAmount() decimal.Decimal {
+	return decimal.NewFromInt(int64(t.Amount)).Shift(-int32(t.Decimals))
+}
+
+// decodeSystemTransfer decodes the system transfer instructions of a transaction that move SOL into
+// or out of the wallet, in instruction order.
 func decodeSystemTransfer(tx *solana.Transaction, timestamp time.Time, publicKey string) ([]*Transaction, error) {
 	systemProgramID := solana.MustPublicKeyFromBase58(systemProgramIDStr)
 	var transactions []*Transaction
@@ -46,14 +120,25 @@ func decodeSystemTransfer(tx *solana.Transaction, timestamp time.Time, publicKey
 			continue
 		}
 
+		if len(instruction.Data) < 12 || len(instruction.Accounts) < 2 {
+			continue
+		}
+
 		instructionType := binary.LittleEndian.Uint32(instruction.Data[0:4])
 		if instructionType != transferInstructionType {
 			continue
 		}
 
-		sender := tx.Message.AccountKeys[instruction.Accounts[0]]
-		receiver := tx.Message.AccountKeys[instruction.Accounts[1]]
+		keys := tx.Message.AccountKeys
+		if int(instruction.Accounts[0]) >= len(keys) || int(instruction.Accounts[1]) >= len(keys) {
+			return nil, fmt.Errorf("transfer account index out of range of %d account keys", len(keys))
+		}
+		sender := keys[instruction.Accounts[0]]
+		receiver := keys[instruction.Accounts[1]]
 		amount := binary.LittleEndian.Uint64(instruction.Data[4:12])
+		if sender.String() != publicKey && receiver.String() != publicKey {
+			continue
+		}
 
 		transactions = append(transactions, &Transaction{
 			Amount:    amount,
@@ -67,79 +152,560 @@ func decodeSystemTransfer(tx *solana.Transaction, timestamp time.Time, publicKey
 	return transactions, nil
 }
 
+// decodeMemo returns the memos a transaction records with either version of the memo program, in
+// instruction order and separated by "; ". Memos that are not valid UTF-8 are left out, since the
+// program itself refuses them. Anyone sending to the wallet chooses its memo, so control characters
+// are escaped and cannot move the cursor, recolour the terminal or start a fake history line.
+func decodeMemo(tx *solana.Transaction) (string, error) {
+	var memos []string
+	for _, instruction := range tx.Message.Instructions {
+		progKey, err := tx.ResolveProgramIDIndex(instruction.ProgramIDIndex)
+		if err != nil {
+			return "", fmt.Errorf("resolve program ID index: %w", err)
+		}
+
+		if !progKey.Equals(solana.MemoProgramID) && !progKey.Equals(legacyMemoProgramID) {
+			continue
+		}
+		if len(instruction.Data) == 0 || !utf8.Valid(instruction.Data) {
+			continue
+		}
+		memos = append(memos, escapeControl(string(instruction.Data)))
+	}
+	return strings.Join(memos, "; "), nil
+}
+
+// escapeControl replaces the control characters of s with their Go escapes, such as \n and \x1b.
+func escapeControl(s string) string {
+	if strings.IndexFunc(s, unicode.IsControl) < 0 {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			quoted := strconv.QuoteRune(r)
+			b.WriteString(quoted[1 : len(quoted)-1])
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// tokenAccountInfo is what the pre and post token balances of a transaction tell about a token account.
+type tokenAccountInfo struct {
+	owner    solana.PublicKey
+	mint     solana.PublicKey
+	decimals uint8
+}
+
+// tokenAccountsOf maps account indices to their owner and mint, using the token balances of the transaction meta.
+func tokenAccountsOf(meta *rpc.TransactionMeta) map[uint16]tokenAccountInfo {
+	accounts := make(map[uint16]tokenAccountInfo)
+	if meta == nil {
+		return accounts
+	}
+
+	for _, balances := range [][]rpc.TokenBalance{meta.PreTokenBalances, meta.PostTokenBalances} {
+		for _, balance := range balances {
+			info := tokenAccountInfo{mint: balance.Mint}
+			if balance.Owner != nil {
+				info.owner = *balance.Owner
+			}
+			if balance.UiTokenAmount != nil {
+				info.decimals = balance.UiTokenAmount.Decimals
+			}
+			accounts[balance.AccountIndex] = info
+		}
+	}
+
+	return accounts
+}
+
+// decodeTokenTransfers decodes SPL Token Transfer and TransferChecked instructions, including the ones
+// nested in inner instructions, that move tokens into or out of the wallet. Token accounts are mapped
+// back to their owners so From and To are wallet addresses.
+func decodeTokenTransfers(tx *solana.Transaction, meta *rpc.TransactionMeta, timestamp time.Time, publicKey string) ([]*Transaction, error) {
+	instructions := append([]solana.CompiledInstruction{}, tx.Message.Instructions...)
+	if meta != nil {
+		for _, inner := range meta.InnerInstructions {
+			instructions = append(instructions, inner.Instructions...)
+		}
+	}
+
+	tokenAccounts := tokenAccountsOf(meta)
+	var transactions []*Transaction
+
+	for _, instruction := range instructions {
+		progKey, err := tx.ResolveProgramIDIndex(instruction.ProgramIDIndex)
+		if err != nil {
+			return nil, fmt.Errorf("resolve program ID index: %w", err)
+		}
+
+		if !progKey.Equals(solana.TokenProgramID) {
+			continue
+		}
+
+		transfer, ok := decodeTokenTransfer(tx, instruction, tokenAccounts)
+		if !ok {
+			continue
+		}
+
+		if transfer.From.String() != publicKey && transfer.To.String() != publicKey {
+			continue
+		}
+
+		transfer.Timestamp = timestamp
+		transfer.IsSender = transfer.From.String() == publicKey
+		transactions = append(transactions, transfer)
+	}
+
+	return transactions, nil
+}
+
+// decodeTokenTransfer decodes a single Transfer or TransferChecked instruction. Other instructions are
+// reported as not ok so callers can skip them.
+func decodeTokenTransfer(tx *solana.Transaction, instruction solana.CompiledInstruction, tokenAccounts map[uint16]tokenAccountInfo) (*Transaction, bool) {
+	if len(instruction.Data) < 9 {
+		return nil, false
+	}
+
+	var sourceIndex, destinationIndex, authorityIndex uint16
+	var checkedDecimals *uint8
+
+	switch instruction.Data[0] {
+	case tokenTransferInstructionType:
+		if len(instruction.Accounts) < 3 {
+			return nil, false
+		}
+		sourceIndex, destinationIndex, authorityIndex = instruction.Accounts[0], instruction.Accounts[1], instruction.Accounts[2]
+	case tokenTransferCheckedInstructionType:
+		if len(instruction.Accounts) < 4 || len(instruction.Data) < 10 {
+			return nil, false
+		}
+		sourceIndex, destinationIndex, authorityIndex = instruction.Accounts[0], instruction.Accounts[2], instruction.Accounts[3]
+		decimals := instruction.Data[9]
+		checkedDecimals = &decimals
+	default:
+		return nil, false
+	}
+
+	keys := tx.Message.AccountKeys
+	for _, index := range []uint16{sourceIndex, destinationIndex, authorityIndex} {
+		if int(index) >= len(keys) {
+			return nil, false
+		}
+	}
+
+	source, sourceKnown := tokenAccounts[sourceIndex]
+	destination, destinationKnown := tokenAccounts[destinationIndex]
+
+	transfer := &Transaction{
+		Amount: binary.LittleEndian.Uint64(instruction.Data[1:9]),
+		From:   keys[authorityIndex],
+		To:     keys[destinationIndex],
+	}
+
+	if sourceKnown && !source.owner.IsZero() {
+		transfer.From = source.owner
+	}
+	if destinationKnown && !destination.owner.IsZero() {
+		transfer.To = destination.owner
+	}
+
+	switch {
+	case sourceKnown:
+		transfer.Mint, transfer.Decimals = source.mint, source.decimals
+	case destinationKnown:
+		transfer.Mint, transfer.Decimals = destination.mint, destination.decimals
+	case instruction.Data[0] == tokenTransferCheckedInstructionType && int(instruction.Accounts[1]) < len(keys):
+		transfer.Mint = keys[instruction.Accounts[1]]
+	default:
+		// Without the mint the amount cannot be interpreted.
+		return nil, false
+	}
+
+	if checkedDecimals != nil {
+		transfer.Decimals = *checkedDecimals
+	}
+	transfer.Symbol = TokenSymbol(transfer.Mint.String())
+
+	return transfer, true
+}
+
 // fetchSingleTransaction fetches a single transaction for the given signature.
-func fetchSingleTransaction(client *rpc.Client, signature solana.Signature, publicKey string) ([]*Transaction, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+// Every RPC call gets its own rpcTimeout so a long history cannot run out of time as a whole.
+func fetchSingleTransaction(ctx context.Context, client ClientInterface, signature solana.Signature, publicKey string) ([]*Transaction, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
 	defer cancel()
 
-	txResponse, err := client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{Encoding: solana.EncodingBase64})
+	maxVersion := maxSupportedTransactionVersion
+	txResponse, err := client.GetTransaction(reqCtx, signature, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("get transaction: %w", err)
 	}
 
+	stopDecode := TimePhase(PhaseDecode)
 	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(txResponse.Transaction.GetBinary()))
+	stopDecode()
 	if err != nil {
 		return nil, fmt.Errorf("transaction from decoder: %w", err)
 	}
+	if err := resolveAddressLookups(ctx, client, tx, txResponse.Meta); err != nil {
+		return nil, err
+	}
+
+	blockTime := txResponse.BlockTime
+	if blockTime == nil {
+		blockCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+		defer cancel()
+
+		blockTime, err = client.GetBlockTime(blockCtx, txResponse.Slot)
+		if err != nil {
+			return nil, fmt.Errorf("get block time: %w", err)
+		}
+	}
+
+	stopDecode = TimePhase(PhaseDecode)
+	defer stopDecode()
+	transactions, err := decodeSystemTransfer(tx, blockTime.Time(), publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenTransactions, err := decodeTokenTransfers(tx, txResponse.Meta, blockTime.Time(), publicKey)
+	if err != nil {
+		return nil, err
+	}
 
-	blockTime, err := client.GetBlockTime(ctx, txResponse.Slot)
+	transactions = append(transactions, tokenTransactions...)
+	memo, err := decodeMemo(tx)
 	if err != nil {
-		return nil, fmt.Errorf("get block time: %w", err)
+		return nil, err
 	}
+	var fee uint64
+	if txResponse.Meta != nil && len(tx.Message.AccountKeys) > 0 && tx.Message.AccountKeys[0].String() == publicKey {
+		// The first account pays the fee.
+		fee = txResponse.Meta.Fee
+	}
+	for _, transaction := range transactions {
+		transaction.Signature = signature
+		transaction.Slot = txResponse.Slot
+		transaction.Fee = fee
+		transaction.Memo = memo
+	}
+
+	return transactions, nil
+}
+
+// HistoryFetchError reports transactions that could not be fetched even after retrying. The
+// transactions that were fetched are returned alongside it.
+type HistoryFetchError struct {
+	Failed int
+	Total  int
+	// Err is the first error that was not recovered from.
+	Err error
+}
+
+func (e *HistoryFetchError) Error() string {
+	return fmt.Sprintf("could not fetch %d of %d transactions: %v", e.Failed, e.Total, e.Err)
+}
 
-	return decodeSystemTransfer(tx, blockTime.Time(), publicKey)
+func (e *HistoryFetchError) Unwrap() error {
+	return e.Err
 }
 
-// fetchTransactions fetches all transactions for the given public key.
-// It First fetches all signatures for the given public key
-// and then fetches each transaction for each signature.
-func fetchTransactions(publicKey string) ([]*Transaction, error) {
-	client := rpc.New(rpc.DevNet_RPC)
+// fetchTransactions fetches the transactions for the given public key within the bounds of opts.
+// It First fetches the signatures for the given public key and its token accounts,
+// since incoming token transfers only reference the token account, and then fetches
+// each transaction for each signature. Rate-limited and transient failures are retried,
+// and concurrency is halved whenever the node starts rate limiting. Signatures and transactions found
+// in history are not fetched again, and the ones fetched are added to it.
+func fetchTransactions(endpoints Endpoints, publicKey string, opts GetTransactionHistoryOpts, history *walletHistory) ([]*Transaction, error) {
+	client := endpoints.client()
 	pub, err := solana.PublicKeyFromBase58(publicKey)
 	if err != nil {
 		return nil, fmt.Errorf("invalid public key: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
-	defer cancel()
+	ctx := context.Background()
+	policy := opts.retryPolicy()
 
-	signatures, err := client.GetSignaturesForAddress(ctx, pub)
+	signatures, err := signaturesForWallet(ctx, client, pub, opts, history)
 	if err != nil {
-		return nil, fmt.Errorf("get signatures for address: %w", err)
+		return nil, err
 	}
 
-	var transactions []*Transaction
-	transactionsMutex := &sync.Mutex{}
-	sem := semaphore.NewWeighted(maxConcurrentRequests)
+	return fetchSignedTransactions(ctx, client, publicKey, signatures, opts.limiter(), policy, history)
+}
+
+// limiter returns a request limiter bounded by opts.Concurrency.
+func (o GetTransactionHistoryOpts) limiter() *adaptiveLimiter {
+	concurrency := o.Concurrency
+	if concurrency <= 0 {
+		concurrency = maxConcurrentRequests
+	}
+	return newAdaptiveLimiter(concurrency)
+}
 
-	eg, ctx := errgroup.WithContext(ctx)
+// fetchSignedTransactions fetches the transactions of signatures concurrently within the bounds of
+// limiter, which is throttled whenever the node starts rate limiting. Transactions that still fail
+// after retrying are reported with a *HistoryFetchError alongside the others. Transactions in history
+// are taken from it, and the ones fetched are added.
+func fetchSignedTransactions(ctx context.Context, client ClientInterface, publicKey string, signatures []*rpc.TransactionSignature, limiter *adaptiveLimiter, policy RetryPolicy, history *walletHistory) ([]*Transaction, error) {
+	var (
+		transactions []*Transaction
+		pending      []*rpc.TransactionSignature
+		failed       int
+		firstErr     error
+		mu           sync.Mutex
+		wg           sync.WaitGroup
+	)
 
 	for _, sig := range signatures {
-		if err := sem.Acquire(ctx, 1); err != nil {
-			return nil, fmt.Errorf("failed to acquire semaphore: %w", err)
+		if transfers, ok := history.transfers(sig.Signature); ok {
+			transactions = append(transactions, transfers...)
+			continue
+		}
+		pending = append(pending, sig)
+	}
+
+	for _, sig := range pending {
+		if err := limiter.Acquire(ctx); err != nil {
+			wg.Wait()
+			return transactions, fmt.Errorf("failed to acquire request slot: %w", err)
 		}
 
 		sig := sig // pin
 
-		eg.Go(func() error {
-			defer sem.Release(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer limiter.Release()
+
+			var txList []*Transaction
+			err := withRetry(ctx, policy, func(err error) {
+				if isRateLimited(err) {
+					limiter.Throttle()
+				}
+			}, func(ctx context.Context) error {
+				var err error
+				txList, err = fetchSingleTransaction(ctx, client, sig.Signature, publicKey)
+				return err
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
 
-			txList, err := fetchSingleTransaction(client, sig.Signature, publicKey)
 			if err != nil {
-				return fmt.Errorf("fetching transaction failed for signature %s: %w", sig.Signature, err)
+				failed++
+				if firstErr == nil {
+					firstErr = fmt.Errorf("fetching transaction failed for signature %s: %w", sig.Signature, err)
+				}
+				return
 			}
 
-			transactionsMutex.Lock()
-			defer transactionsMutex.Unlock()
-
 			transactions = append(transactions, txList...)
-			return nil
+			history.setTransfers(sig.Signature, txList)
+		}()
+	}
+
+	wg.Wait()
+
+	if failed > 0 {
+		return transactions, &HistoryFetchError{Failed: failed, Total: len(signatures), Err: firstErr}
+	}
+
+	return transactions, nil
+}
+
+// signaturesForWallet returns the newest signatures of the wallet and of its token accounts within the
+// bounds of opts, newest first and without duplicates. Only the signatures newer than those in
+// history are listed for addresses it holds all signatures of.
+func signaturesForWallet(ctx context.Context, client ClientInterface, pub solana.PublicKey, opts GetTransactionHistoryOpts, history *walletHistory) ([]*rpc.TransactionSignature, error) {
+	addresses := []solana.PublicKey{pub}
+
+	programID := solana.TokenProgramID
+	var tokenAccounts *rpc.GetTokenAccountsResult
+	err := withRetry(ctx, opts.retryPolicy(), nil, func(ctx context.Context) error {
+		reqCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+		defer cancel()
+
+		var err error
+		tokenAccounts, err = client.GetTokenAccountsByOwner(reqCtx, pub,
+			&rpc.GetTokenAccountsConfig{ProgramId: &programID},
+			&rpc.GetTokenAccountsOpts{Encoding: solana.EncodingBase64},
+		)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get token accounts by owner: %w", err)
+	}
+	for _, account := range tokenAccounts.Value {
+		addresses = append(addresses, account.Pubkey)
+	}
+
+	seen := make(map[solana.Signature]bool)
+	var signatures []*rpc.TransactionSignature
+	for _, address := range addresses {
+		addressSignatures, err := paginateSignatures(ctx, client, address, opts, history)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sig := range addressSignatures {
+			if seen[sig.Signature] {
+				continue
+			}
+			seen[sig.Signature] = true
+			signatures = append(signatures, sig)
+		}
+	}
+
+	// Each address is already bounded; merging them needs one more cut to honour the limit overall.
+	sort.SliceStable(signatures, func(i, j int) bool {
+		return signatures[i].Slot > signatures[j].Slot
+	})
+	if opts.Limit > 0 && len(signatures) > opts.Limit {
+		signatures = signatures[:opts.Limit]
+	}
+
+	return signatures, nil
+}
+
+// paginateSignatures returns the signatures of an address within the bounds of opts, newest first.
+// When history holds all signatures of the address, only the newer ones are listed; otherwise the
+// history is paged until opts.Limit signatures are collected or opts.Since is passed, and stored in
+// history when that reaches the first transaction of the address.
+func paginateSignatures(ctx context.Context, client ClientInterface, address solana.PublicKey, opts GetTransactionHistoryOpts, history *walletHistory) ([]*rpc.TransactionSignature, error) {
+	policy := opts.retryPolicy()
+	filter := signatureFilter{opts: opts}
+
+	if synced, ok := history.addressSignatures(address); ok {
+		var until solana.Signature
+		if len(synced) > 0 {
+			until = synced[0].Signature
+		}
+		var newer []*rpc.TransactionSignature
+		_, err := pageSignatures(ctx, client, address, policy, until, func() int { return maxSignaturesPerPage }, func(sig *rpc.TransactionSignature) bool {
+			newer = append(newer, sig)
+			return true
 		})
+		if err != nil {
+			return nil, err
+		}
+
+		all := mergeSignatures(newer, synced)
+		history.setAddressSignatures(address, all)
+		for _, sig := range all {
+			if !filter.add(sig) {
+				break
+			}
+		}
+		return filter.signatures, nil
 	}
 
-	if err := eg.Wait(); err != nil {
+	var listed []*rpc.TransactionSignature
+	pageSize := func() int {
+		if opts.Limit > 0 && opts.Limit-len(filter.signatures) < maxSignaturesPerPage {
+			return opts.Limit - len(filter.signatures)
+		}
+		return maxSignaturesPerPage
+	}
+	ended, err := pageSignatures(ctx, client, address, policy, solana.Signature{}, pageSize, func(sig *rpc.TransactionSignature) bool {
+		listed = append(listed, sig)
+		return filter.add(sig)
+	})
+	if err != nil {
 		return nil, err
 	}
+	if ended {
+		history.setAddressSignatures(address, listed)
+	}
+	return filter.signatures, nil
+}
+
+// pageSignatures pages backwards through the signatures of an address newer than until, or all of
+// them when until is zero, using the Before cursor. Each signature is passed to visit until it returns
+// false. It reports whether the signatures ran out. Some providers return fewer signatures per page
+// than asked for, so they only run out at an empty page, or at a page of signatures already seen from
+// a node that ignores the cursor.
+func pageSignatures(ctx context.Context, client ClientInterface, address solana.PublicKey, policy RetryPolicy, until solana.Signature, pageSize func() int, visit func(*rpc.TransactionSignature) bool) (bool, error) {
+	var before solana.Signature
+	seen := make(map[solana.Signature]bool)
+
+	for {
+		limit := pageSize()
+		var page []*rpc.TransactionSignature
+		err := withRetry(ctx, policy, nil, func(ctx context.Context) error {
+			reqCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+			defer cancel()
+
+			var err error
+			page, err = client.GetSignaturesForAddressWithOpts(reqCtx, address, &rpc.GetSignaturesForAddressOpts{
+				Limit:  &limit,
+				Before: before,
+				Until:  until,
+			})
+			return err
+		})
+		if err != nil {
+			return false, fmt.Errorf("get signatures for address: %w", err)
+		}
+
+		added := 0
+		for _, sig := range page {
+			if seen[sig.Signature] || (!until.IsZero() && sig.Signature == until) {
+				continue
+			}
+			seen[sig.Signature] = true
+			added++
+			if !visit(sig) {
+				return false, nil
+			}
+		}
+		if added == 0 {
+			return true, nil
+		}
+
+		before = page[len(page)-1].Signature
+	}
+}
 
-	return transactions, nil
+// signatureFilter collects the signatures within the bounds of opts from signatures given newest first.
+type signatureFilter struct {
+	opts       GetTransactionHistoryOpts
+	signatures []*rpc.TransactionSignature
+}
+
+// add collects sig when it is within the bounds, and reports whether older signatures can still be:
+// false once opts.Since is passed or opts.Limit signatures are collected. Signatures newer than
+// opts.Until are skipped.
+func (f *signatureFilter) add(sig *rpc.TransactionSignature) bool {
+	if !f.opts.Since.IsZero() && sig.BlockTime != nil && sig.BlockTime.Time().Before(f.opts.Since) {
+		return false
+	}
+	if !f.opts.Until.IsZero() && sig.BlockTime != nil && sig.BlockTime.Time().After(f.opts.Until) {
+		return true
+	}
+	f.signatures = append(f.signatures, sig)
+	return f.opts.Limit == 0 || len(f.signatures) < f.opts.Limit
+}
+
+// mergeSignatures puts the newer signatures of an address in front of the ones already known.
+func mergeSignatures(newer, known []*rpc.TransactionSignature) []*rpc.TransactionSignature {
+	seen := make(map[solana.Signature]bool, len(known))
+	for _, sig := range known {
+		seen[sig.Signature] = true
+	}
+	merged := make([]*rpc.TransactionSignature, 0, len(newer)+len(known))
+	for _, sig := range newer {
+		if !seen[sig.Signature] {
+			merged = append(merged, sig)
+		}
+	}
+	return append(merged, known...)
 }
@@ -0,0 +1,338 @@
+package wallet
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenBalanceFor describes the token account at the given key in tx as owned by owner.
+func tokenBalanceFor(t *testing.T, tx *solana.Transaction, account, owner, mint solana.PublicKey) rpc.TokenBalance {
+	index := -1
+	for i, key := range tx.Message.AccountKeys {
+		if key.Equals(account) {
+			index = i
+		}
+	}
+	assert.NotEqual(t, -1, index)
+	return rpc.TokenBalance{
+		AccountIndex:  uint16(index),
+		Owner:         &owner,
+		Mint:          mint,
+		UiTokenAmount: &rpc.UiTokenAmount{Decimals: 6},
+	}
+}
+
+func TestDecodeTransfers(t *testing.T) {
+	wallet := solana.NewWallet().PublicKey()
+	other := solana.NewWallet().PublicKey()
+	walletATA, _, _ := solana.FindAssociatedTokenAddress(wallet, usdcMint)
+	otherATA, _, _ := solana.FindAssociatedTokenAddress(other, usdcMint)
+	timestamp := time.Unix(1_700_000_000, 0)
+
+	t.Run("Plain SOL Transfer", func(t *testing.T) {
+		tx, err := solana.NewTransaction([]solana.Instruction{
+			system.NewTransferInstruction(1_000, wallet, other).Build(),
+		}, solana.Hash{1}, solana.TransactionPayer(wallet))
+		assert.NoError(t, err)
+
+		transactions, err := decodeSystemTransfer(tx, timestamp, wallet.String())
+		assert.NoError(t, err)
+		assert.Len(t, transactions, 1)
+		assert.Equal(t, uint64(1_000), transactions[0].Amount)
+		assert.True(t, transactions[0].IsSender)
+		assert.False(t, transactions[0].IsToken())
+
+		tokenTransactions, err := decodeTokenTransfers(tx, &rpc.TransactionMeta{}, timestamp, wallet.String())
+		assert.NoError(t, err)
+		assert.Empty(t, tokenTransactions)
+	})
+
+	t.Run("Received TransferChecked", func(t *testing.T) {
+		tx, err := solana.NewTransaction([]solana.Instruction{
+			token.NewTransferCheckedInstruction(25_000_000, 6, otherATA, usdcMint, walletATA, other, nil).Build(),
+		}, solana.Hash{1}, solana.TransactionPayer(other))
+		assert.NoError(t, err)
+
+		meta := &rpc.TransactionMeta{PostTokenBalances: []rpc.TokenBalance{
+			tokenBalanceFor(t, tx, otherATA, other, usdcMint),
+			tokenBalanceFor(t, tx, walletATA, wallet, usdcMint),
+		}}
+
+		transactions, err := decodeTokenTransfers(tx, meta, timestamp, wallet.String())
+		assert.NoError(t, err)
+		assert.Len(t, transactions, 1)
+		assert.Equal(t, other, transactions[0].From)
+		assert.Equal(t, wallet, transactions[0].To)
+		assert.False(t, transactions[0].IsSender)
+		assert.Equal(t, "USDC", transactions[0].Symbol)
+		assert.Equal(t, "25", transactions[0].TokenAmount().String())
+	})
+
+	t.Run("Sent Transfer In Inner Instructions", func(t *testing.T) {
+		// A program moves the tokens on the wallet's behalf; the outer instruction is a system
+		// transfer to another party, so only the inner instruction is a token transfer.
+		tx, err := solana.NewTransaction([]solana.Instruction{
+			system.NewTransferInstruction(1, wallet, other).Build(),
+			token.NewTransferInstruction(5_000_000, walletATA, otherATA, wallet, nil).Build(),
+		}, solana.Hash{1}, solana.TransactionPayer(wallet))
+		assert.NoError(t, err)
+
+		inner := tx.Message.Instructions[1]
+		tx.Message.Instructions = tx.Message.Instructions[:1]
+		meta := &rpc.TransactionMeta{
+			InnerInstructions: []rpc.InnerInstruction{{Index: 0, Instructions: []solana.CompiledInstruction{inner}}},
+			PreTokenBalances: []rpc.TokenBalance{
+				tokenBalanceFor(t, tx, walletATA, wallet, usdcMint),
+				tokenBalanceFor(t, tx, otherATA, other, usdcMint),
+			},
+		}
+
+		transactions, err := decodeTokenTransfers(tx, meta, timestamp, wallet.String())
+		assert.NoError(t, err)
+		assert.Len(t, transactions, 1)
+		assert.True(t, transactions[0].IsSender)
+		assert.Equal(t, other, transactions[0].To)
+		assert.Equal(t, uint8(6), transactions[0].Decimals)
+		assert.Equal(t, "5", transactions[0].TokenAmount().String())
+	})
+
+	t.Run("Unknown Token Instruction Is Skipped", func(t *testing.T) {
+		tx, err := solana.NewTransaction([]solana.Instruction{
+			token.NewCloseAccountInstruction(walletATA, wallet, wallet, nil).Build(),
+		}, solana.Hash{1}, solana.TransactionPayer(wallet))
+		assert.NoError(t, err)
+
+		transactions, err := decodeTokenTransfers(tx, &rpc.TransactionMeta{}, timestamp, wallet.String())
+		assert.NoError(t, err)
+		assert.Empty(t, transactions)
+	})
+}
+
+func TestDecodeMemo(t *testing.T) {
+	wallet := solana.NewWallet().PublicKey()
+	other := solana.NewWallet().PublicKey()
+	memo := func(program solana.PublicKey, data string) solana.Instruction {
+		return solana.NewInstruction(program, solana.AccountMetaSlice{solana.Meta(wallet).SIGNER()}, []byte(data))
+	}
+	transfer := system.NewTransferInstruction(1_000, wallet, other).Build()
+
+	tests := []struct {
+		name         string
+		instructions []solana.Instruction
+		expected     string
+	}{
+		{name: "No Memo", instructions: []solana.Instruction{transfer}},
+		{name: "Memo Program", instructions: []solana.Instruction{transfer, memo(solana.MemoProgramID, "invoice 42")}, expected: "invoice 42"},
+		{name: "Legacy Memo Program", instructions: []solana.Instruction{memo(legacyMemoProgramID, "deposit 7"), transfer}, expected: "deposit 7"},
+		{name: "Several Memos", instructions: []solana.Instruction{memo(solana.MemoProgramID, "a"), transfer, memo(solana.MemoProgramID, "b")}, expected: "a; b"},
+		{name: "Invalid UTF-8", instructions: []solana.Instruction{transfer, memo(solana.MemoProgramID, "\xff\xfe")}},
+		// A sender cannot recolour the terminal or print a fake history line.
+		{
+			name:         "Control Characters Are Escaped",
+			instructions: []solana.Instruction{transfer, memo(solana.MemoProgramID, "paid\x1b[2K\nAction: Received\tx\u0085")},
+			expected:     `paid\x1b[2K\nAction: Received\tx\u0085`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := solana.NewTransaction(tt.instructions, solana.Hash{1}, solana.TransactionPayer(wallet))
+			assert.NoError(t, err)
+
+			memo, err := decodeMemo(tx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, memo)
+		})
+	}
+}
+
+func TestSignaturesForWalletPagination(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	now := time.Unix(1_700_000_000, 0)
+
+	// 2500 signatures, one per minute going back from now, newest first like the RPC returns them.
+	history := make([]*rpc.TransactionSignature, 2500)
+	for i := range history {
+		blockTime := solana.UnixTimeSeconds(now.Add(-time.Duration(i) * time.Minute).Unix())
+		history[i] = &rpc.TransactionSignature{Signature: solana.Signature{byte(i), byte(i >> 8)}, Slot: uint64(10_000 - i), BlockTime: &blockTime}
+	}
+
+	tests := []struct {
+		name          string
+		opts          GetTransactionHistoryOpts
+		expectedCount int
+		expectedPages int
+		// expectedFirst is the index in history of the newest signature returned.
+		expectedFirst int
+		// pageCap is the most signatures the node returns per page, whatever the limit asked for.
+		pageCap int
+	}{
+		{name: "Default Limit Fetches One Small Page", opts: GetTransactionHistoryOpts{Limit: 50}, expectedCount: 50, expectedPages: 1},
+		{name: "Limit Across Pages", opts: GetTransactionHistoryOpts{Limit: 1500}, expectedCount: 1500, expectedPages: 2},
+		{name: "No Limit Pages Until The End", opts: GetTransactionHistoryOpts{}, expectedCount: 2500, expectedPages: 4},
+		{name: "Node Capping Pages", opts: GetTransactionHistoryOpts{}, expectedCount: 2500, expectedPages: 10, pageCap: 300},
+		{name: "Since Stops Paging", opts: GetTransactionHistoryOpts{Since: now.Add(-1200 * time.Minute)}, expectedCount: 1201, expectedPages: 2},
+		{name: "Limit Before Since", opts: GetTransactionHistoryOpts{Limit: 10, Since: now.Add(-1200 * time.Minute)}, expectedCount: 10, expectedPages: 1},
+		{name: "Until Skips Newer", opts: GetTransactionHistoryOpts{Since: now.Add(-1200 * time.Minute), Until: now.Add(-100 * time.Minute)}, expectedCount: 1101, expectedPages: 2, expectedFirst: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := 0
+			client := &MockClientInterface{
+				GetTokenAccountsByOwnerFn: func(ctx context.Context, _ solana.PublicKey, _ *rpc.GetTokenAccountsConfig, _ *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error) {
+					return &rpc.GetTokenAccountsResult{}, nil
+				},
+				GetSignaturesForAddressWithOptsFn: func(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+					pages++
+					assert.LessOrEqual(t, *opts.Limit, maxSignaturesPerPage)
+
+					start := 0
+					if !opts.Before.IsZero() {
+						for i, sig := range history {
+							if sig.Signature == opts.Before {
+								start = i + 1
+							}
+						}
+					}
+					end := start + *opts.Limit
+					if tt.pageCap > 0 && tt.pageCap < *opts.Limit {
+						end = start + tt.pageCap
+					}
+					if end > len(history) {
+						end = len(history)
+					}
+					return history[start:end], nil
+				},
+			}
+
+			signatures, err := signaturesForWallet(context.Background(), client, owner, tt.opts, nil)
+			assert.NoError(t, err)
+			assert.Len(t, signatures, tt.expectedCount)
+			assert.Equal(t, tt.expectedPages, pages)
+			assert.Equal(t, history[tt.expectedFirst].Signature, signatures[0].Signature)
+		})
+	}
+}
+
+func TestFetchSingleTransactionFee(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	other := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name        string
+		from        solana.PublicKey
+		to          solana.PublicKey
+		expectedFee uint64
+	}{
+		{name: "Sent By The Wallet", from: owner, to: other, expectedFee: 5_000},
+		{name: "Received", from: other, to: owner, expectedFee: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &MockClientInterface{
+				GetTransactionFn: func(ctx context.Context, _ solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+					result := transferResultTo(t, tt.from, tt.to, tt.from)
+					result.Meta = &rpc.TransactionMeta{Fee: 5_000}
+					return result, nil
+				},
+			}
+
+			transactions, err := fetchSingleTransaction(context.Background(), client, solana.Signature{1}, owner.String())
+			assert.NoError(t, err)
+			if assert.Len(t, transactions, 1) {
+				assert.Equal(t, tt.expectedFee, transactions[0].Fee)
+				assert.Equal(t, solana.Signature{1}, transactions[0].Signature)
+			}
+		})
+	}
+}
+
+func TestFetchSingleTransactionMemo(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	alice, bob := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+
+	instructions := append(transferInstructions(owner, alice, 1_000, solana.PublicKey{}, transferExtras{Memo: "invoice 42"}),
+		system.NewTransferInstruction(2_000, owner, bob).Build())
+	tx, err := solana.NewTransaction(instructions, solana.Hash{1}, solana.TransactionPayer(owner))
+	assert.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	assert.NoError(t, err)
+	envelope, err := json.Marshal([]string{base64.StdEncoding.EncodeToString(raw), "base64"})
+	assert.NoError(t, err)
+
+	client := &MockClientInterface{
+		GetTransactionFn: func(context.Context, solana.Signature, *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+			result := &rpc.GetTransactionResult{Transaction: &rpc.TransactionResultEnvelope{}}
+			assert.NoError(t, json.Unmarshal(envelope, result.Transaction))
+			blockTime := solana.UnixTimeSeconds(1_700_000_000)
+			result.BlockTime = &blockTime
+			return result, nil
+		},
+	}
+
+	transactions, err := fetchSingleTransaction(context.Background(), client, solana.Signature{1}, owner.String())
+	assert.NoError(t, err)
+	// The memo belongs to the transaction, so every transfer in it carries it.
+	if assert.Len(t, transactions, 2) {
+		assert.Equal(t, "invoice 42", transactions[0].Memo)
+		assert.Equal(t, "invoice 42", transactions[1].Memo)
+	}
+}
+
+func TestFetchSingleTransactionVersioned(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	sender := solana.NewWallet().PublicKey()
+	table := solana.NewWallet().PublicKey()
+
+	// The wallet is only reachable through the address lookup table.
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(2_000, sender, owner).Build(),
+	}, solana.Hash{1}, solana.TransactionPayer(sender), solana.TransactionAddressTables(map[solana.PublicKey]solana.PublicKeySlice{
+		table: {owner},
+	}))
+	assert.NoError(t, err)
+	assert.True(t, tx.Message.IsVersioned())
+	assert.NotContains(t, tx.Message.AccountKeys, owner)
+
+	raw, err := tx.MarshalBinary()
+	assert.NoError(t, err)
+	envelope, err := json.Marshal([]string{base64.StdEncoding.EncodeToString(raw), "base64"})
+	assert.NoError(t, err)
+
+	client := &MockClientInterface{
+		GetTransactionFn: func(ctx context.Context, _ solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+			if opts.MaxSupportedTransactionVersion == nil || *opts.MaxSupportedTransactionVersion != 0 {
+				return nil, errors.New("Transaction version (0) is not supported by the requesting client")
+			}
+			blockTime := solana.UnixTimeSeconds(1_700_000_000)
+			result := &rpc.GetTransactionResult{
+				Transaction: &rpc.TransactionResultEnvelope{},
+				BlockTime:   &blockTime,
+				Meta:        &rpc.TransactionMeta{LoadedAddresses: rpc.LoadedAddresses{Writable: solana.PublicKeySlice{owner}}},
+			}
+			assert.NoError(t, json.Unmarshal(envelope, result.Transaction))
+			return result, nil
+		},
+	}
+
+	transactions, err := fetchSingleTransaction(context.Background(), client, solana.Signature{1}, owner.String())
+	assert.NoError(t, err)
+	if assert.Len(t, transactions, 1) {
+		assert.Equal(t, sender, transactions[0].From)
+		assert.Equal(t, owner, transactions[0].To)
+		assert.Equal(t, uint64(2_000), transactions[0].Amount)
+		assert.False(t, transactions[0].IsSender)
+	}
+}
@@ -0,0 +1,427 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+	"github.com/shopspring/decimal"
+)
+
+// BalanceUpdate is a wallet balance as of a slot.
+type BalanceUpdate struct {
+	Address  solana.PublicKey
+	Lamports uint64
+	Slot     uint64
+}
+
+// accountStream and logStream are websocket subscriptions; *ws.AccountSubscription and
+// *ws.LogSubscription implement them.
+type accountStream interface {
+	Recv() (*ws.AccountResult, error)
+	Unsubscribe()
+}
+
+type logStream interface {
+	Recv() (*ws.LogResult, error)
+	Unsubscribe()
+}
+
+// watchConn is a websocket connection to a cluster, as far as watching an address needs one.
+type watchConn interface {
+	AccountSubscribe(account solana.PublicKey, commitment rpc.CommitmentType) (accountStream, error)
+	LogsSubscribeMentions(mentions solana.PublicKey, commitment rpc.CommitmentType) (logStream, error)
+	Close()
+}
+
+// wsConn adapts *ws.Client to watchConn.
+type wsConn struct {
+	client *ws.Client
+}
+
+func (c wsConn) AccountSubscribe(account solana.PublicKey, commitment rpc.CommitmentType) (accountStream, error) {
+	sub, err := c.client.AccountSubscribe(account, commitment)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (c wsConn) LogsSubscribeMentions(mentions solana.PublicKey, commitment rpc.CommitmentType) (logStream, error) {
+	sub, err := c.client.LogsSubscribeMentions(mentions, commitment)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (c wsConn) Close() {
+	c.client.Close()
+}
+
+// dialWatch opens a websocket connection; tests replace it to feed notifications without a cluster.
+var dialWatch = func(ctx context.Context, endpoint string) (watchConn, error) {
+	client, err := ws.Connect(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return wsConn{client: client}, nil
+}
+
+// watchReconnectPolicy spaces out reconnects after the websocket drops. MaxAttempts is not used:
+// watching reconnects until it is cancelled.
+var watchReconnectPolicy = RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// WatchBalance calls fn with the balance of a stored wallet every time it changes on the cluster,
+// until ctx is cancelled or fn returns an error. An empty alias watches the active wallet.
+func (w *WalletConfig) WatchBalance(ctx context.Context, alias string, fn func(BalanceUpdate) error) error {
+	address, err := w.ownerPublicKey(alias)
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialWatch(ctx, endpoints.WS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", endpoints.WS, err)
+	}
+	defer conn.Close()
+
+	sub, err := conn.AccountSubscribe(address, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", address, err)
+	}
+	defer sub.Unsubscribe()
+
+	results := make(chan notification)
+	go forwardNotifications(ctx, results, func() (notification, error) {
+		result, err := sub.Recv()
+		return notification{account: result}, err
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-results:
+			if n.err != nil {
+				return fmt.Errorf("account subscription ended: %w", n.err)
+			}
+			update := BalanceUpdate{Address: address, Lamports: n.account.Value.Lamports, Slot: n.account.Context.Slot}
+			if err := fn(update); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// TransferEventKind says what a TransferEvent reports.
+type TransferEventKind string
+
+const (
+	// TransferEventBalance reports a change of the SOL balance.
+	TransferEventBalance TransferEventKind = "balance"
+	// TransferEventTransfer reports a transfer to or from the address that was finalized.
+	TransferEventTransfer TransferEventKind = "transfer"
+	// TransferEventReconnecting reports that the websocket dropped and is reconnected after RetryIn.
+	TransferEventReconnecting TransferEventKind = "reconnecting"
+)
+
+// TransferEvent is something WatchAddress saw happen to the watched address.
+type TransferEvent struct {
+	Kind    TransferEventKind
+	Address solana.PublicKey
+	Slot    uint64
+	// Lamports is the new balance and Change its difference to the previous one, for balance events.
+	Lamports uint64
+	Change   int64
+	// Signature is the transaction of a transfer event and Transaction the transfer in it. Transaction
+	// is nil when the transaction could not be fetched; Err says why.
+	Signature   solana.Signature
+	Transaction *Transaction
+	// Value is the balance change or the transferred SOL in Currency, nil when the rate is unavailable
+	// or the transfer is of a token.
+	Value    *decimal.Decimal
+	Currency Currency
+	// Err is why the websocket dropped for reconnecting events, and why the transaction could not be
+	// fetched for transfer events.
+	Err     error
+	RetryIn time.Duration
+}
+
+// WatchAddress calls fn for every balance change of a stored wallet and every SOL or token transfer
+// to or from it that is finalized, until ctx is cancelled. An empty alias watches the active wallet.
+// When the websocket drops it reconnects with backoff, reporting each attempt to fn, and the balance
+// and signatures of the address are checked again so a change or transfer missed while disconnected
+// is still reported.
+func (w *WalletConfig) WatchAddress(ctx context.Context, alias string, fn func(ev TransferEvent)) error {
+	address, err := w.ownerPublicKey(alias)
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := w.Endpoints()
+	if err != nil {
+		return err
+	}
+
+	watcher := &addressWatcher{
+		wallet:  w,
+		client:  endpoints.client(),
+		address: address,
+		fn:      fn,
+		seen:    make(map[solana.Signature]bool),
+	}
+	if watcher.balance, err = watcher.fetchBalance(ctx); err != nil {
+		return err
+	}
+	// Without a newest signature nothing is caught up on until a transfer is notified.
+	if latest, err := watcher.fetchSignatures(ctx, solana.Signature{}, 1); err == nil && len(latest) > 0 {
+		watcher.last = latest[0].Signature
+	}
+
+	attempt := 0
+	for {
+		received, err := watcher.watch(ctx, endpoints.WS)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if received {
+			attempt = 0
+		}
+		attempt++
+		delay := backoff(watchReconnectPolicy, attempt)
+		fn(TransferEvent{Kind: TransferEventReconnecting, Address: address, Err: err, RetryIn: delay})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// addressWatcher is the state WatchAddress keeps across reconnects.
+type addressWatcher struct {
+	wallet  *WalletConfig
+	client  ClientInterface
+	address solana.PublicKey
+	fn      func(TransferEvent)
+	balance uint64
+	// seen holds the signatures already reported, as notifications can repeat after a reconnect.
+	seen map[solana.Signature]bool
+	// last is the newest finalized transaction of the address known, from which a reconnect catches up.
+	last solana.Signature
+}
+
+// notification is a message from one of the subscriptions of a watch.
+type notification struct {
+	account *ws.AccountResult
+	log     *ws.LogResult
+	err     error
+}
+
+// forwardNotifications sends what recv returns to out until it fails or ctx is done. Recv blocks
+// without a context, so it runs in its own goroutine and is abandoned on cancel.
+func forwardNotifications(ctx context.Context, out chan<- notification, recv func() (notification, error)) {
+	for {
+		n, err := recv()
+		n.err = err
+		select {
+		case out <- n:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// watch subscribes to the account and the logs mentioning it over one connection and reports what
+// they notify until either subscription fails or ctx is done. It returns whether anything was
+// received, so a connection that worked for a while reconnects without waiting long.
+func (a *addressWatcher) watch(ctx context.Context, endpoint string) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn, err := dialWatch(ctx, endpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+	}
+	defer conn.Close()
+
+	accountSub, err := conn.AccountSubscribe(a.address, rpc.CommitmentConfirmed)
+	if err != nil {
+		return false, fmt.Errorf("failed to subscribe to %s: %w", a.address, err)
+	}
+	defer accountSub.Unsubscribe()
+
+	// Transactions are only fetched once finalized, because getTransaction does not know them before.
+	logSub, err := conn.LogsSubscribeMentions(a.address, rpc.CommitmentFinalized)
+	if err != nil {
+		return false, fmt.Errorf("failed to subscribe to logs of %s: %w", a.address, err)
+	}
+	defer logSub.Unsubscribe()
+
+	notifications := make(chan notification)
+	go forwardNotifications(ctx, notifications, func() (notification, error) {
+		result, err := accountSub.Recv()
+		return notification{account: result}, err
+	})
+	go forwardNotifications(ctx, notifications, func() (notification, error) {
+		result, err := logSub.Recv()
+		return notification{log: result}, err
+	})
+
+	// Catch up on a change or transfer made while no subscription was open.
+	if balance, err := a.fetchBalance(ctx); err == nil {
+		a.balanceChanged(balance, 0)
+	}
+	a.catchUp(ctx)
+
+	received := false
+	for {
+		select {
+		case <-ctx.Done():
+			return received, ctx.Err()
+		case n := <-notifications:
+			switch {
+			case n.err != nil:
+				return received, fmt.Errorf("subscription ended: %w", n.err)
+			case n.account != nil:
+				a.balanceChanged(n.account.Value.Lamports, n.account.Context.Slot)
+			case n.log != nil:
+				a.logged(ctx, n.log)
+			}
+			received = true
+		}
+	}
+}
+
+// fetchBalance returns the confirmed balance of the address.
+func (a *addressWatcher) fetchBalance(ctx context.Context) (uint64, error) {
+	var balance *rpc.GetBalanceResult
+	err := withRetry(ctx, DefaultRetryPolicy, nil, func(ctx context.Context) error {
+		var err error
+		balance, err = a.client.GetBalance(ctx, a.address, rpc.CommitmentConfirmed)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch balance of %s: %w", a.address, err)
+	}
+	return balance.Value, nil
+}
+
+// balanceChanged reports a new balance unless it is the one reported last.
+func (a *addressWatcher) balanceChanged(lamports, slot uint64) {
+	if lamports == a.balance {
+		return
+	}
+
+	change := int64(lamports) - int64(a.balance)
+	a.balance = lamports
+	a.fn(TransferEvent{
+		Kind:     TransferEventBalance,
+		Address:  a.address,
+		Slot:     slot,
+		Lamports: lamports,
+		Change:   change,
+		Value:    a.value(decimal.NewFromInt(change)),
+		Currency: a.wallet.FiatCurrency(),
+	})
+}
+
+// fetchSignatures returns the finalized signatures of the address newer than until, newest first, at
+// most limit of them. A zero until lists the newest ones.
+func (a *addressWatcher) fetchSignatures(ctx context.Context, until solana.Signature, limit int) ([]*rpc.TransactionSignature, error) {
+	opts := &rpc.GetSignaturesForAddressOpts{Limit: &limit, Until: until, Commitment: rpc.CommitmentFinalized}
+	var signatures []*rpc.TransactionSignature
+	err := withRetry(ctx, DefaultRetryPolicy, nil, func(ctx context.Context) error {
+		var err error
+		signatures, err = a.client.GetSignaturesForAddressWithOpts(ctx, a.address, opts)
+		return err
+	})
+	return signatures, err
+}
+
+// catchUp reports the transactions finalized since the newest one known, oldest first. At most one
+// page is caught up on; a watch that was away for longer than that is better served by the history.
+func (a *addressWatcher) catchUp(ctx context.Context) {
+	if a.last.IsZero() {
+		return
+	}
+	signatures, err := a.fetchSignatures(ctx, a.last, 1000)
+	if err != nil {
+		return
+	}
+	for i := len(signatures) - 1; i >= 0; i-- {
+		a.transaction(ctx, signatures[i].Signature, signatures[i].Slot, signatures[i].Err != nil)
+	}
+}
+
+// logged reports the transfers to or from the address in a transaction that mentioned it.
+func (a *addressWatcher) logged(ctx context.Context, result *ws.LogResult) {
+	a.transaction(ctx, result.Value.Signature, result.Context.Slot, result.Value.Err != nil)
+}
+
+// transaction reports the transfers to or from the address in the transaction sig, unless they were
+// reported before. Failed transactions moved nothing and are skipped.
+func (a *addressWatcher) transaction(ctx context.Context, sig solana.Signature, slot uint64, failed bool) {
+	if a.seen[sig] {
+		return
+	}
+	a.seen[sig] = true
+	a.last = sig
+	if failed {
+		return
+	}
+
+	var transfers []*Transaction
+	err := withRetry(ctx, DefaultRetryPolicy, nil, func(ctx context.Context) error {
+		var err error
+		transfers, err = fetchSingleTransaction(ctx, a.client, sig, a.address.String())
+		return err
+	})
+	if err != nil {
+		a.fn(TransferEvent{Kind: TransferEventTransfer, Address: a.address, Slot: slot, Signature: sig, Err: err})
+		return
+	}
+
+	for _, transfer := range transfers {
+		if !transfer.From.Equals(a.address) && !transfer.To.Equals(a.address) {
+			continue
+		}
+
+		event := TransferEvent{
+			Kind:        TransferEventTransfer,
+			Address:     a.address,
+			Slot:        slot,
+			Signature:   sig,
+			Transaction: transfer,
+			Currency:    a.wallet.FiatCurrency(),
+		}
+		if !transfer.IsToken() {
+			event.Value = a.value(decimal.NewFromInt(int64(transfer.Amount)))
+		}
+		a.fn(event)
+	}
+}
+
+// value converts lamports to the selected currency with the cached rate, nil when it is unavailable.
+func (a *addressWatcher) value(lamports decimal.Decimal) *decimal.Decimal {
+	rate, err := a.wallet.fetchRate(a.wallet.FiatCurrency())
+	if err != nil {
+		return nil
+	}
+	value := lamports.Shift(-9).Mul(rate).Round(2)
+	return &value
+}
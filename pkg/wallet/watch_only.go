@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+)
+
+var ErrWatchOnlyWallet = errors.New("watch-only wallet has no private key on this machine")
+
+// AddWatchOnlyWallet stores an address without a private key so its balance and history can be
+// followed. The new entry only becomes the active wallet when there is none yet, since it cannot send.
+func (k *KeyOps) AddWatchOnlyWallet(alias, publicKey string) error {
+	if _, err := solana.PublicKeyFromBase58(publicKey); err != nil {
+		return fmt.Errorf("invalid address %q: %w", publicKey, err)
+	}
+
+	unlock, err := k.lockKeyFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var data WalletData
+	fileExists, err := k.IsKeyFilePresent()
+	if err != nil {
+		return fmt.Errorf("error checking if keys are already present: %w", err)
+	}
+
+	if fileExists {
+		data, err = k.readWalletData(k.keyFilePath())
+		if err != nil {
+			return err
+		}
+	} else {
+		data.Wallets = make(map[string]Wallet)
+	}
+
+	if err := data.checkNameFree(alias); err != nil {
+		return err
+	}
+	if err := data.checkAddressFree(publicKey); err != nil {
+		return err
+	}
+
+	data.Wallets[alias] = Wallet{
+		Balance:    decimal.Zero,
+		PublicKey:  publicKey,
+		WatchOnly:  true,
+		Derivation: &KeyDerivation{Path: DerivationNone},
+	}
+	if _, exists := data.Wallets[data.ActiveAlias]; !exists {
+		data.ActiveAlias = alias
+	}
+
+	return k.writeWalletData("add", data)
+}
+
+// AddWatchOnlyWallet stores an address to watch under alias, or under a random alias when it is empty.
+// It returns the alias used.
+func (w *WalletConfig) AddWatchOnlyWallet(alias, publicKey string) (string, error) {
+	if alias == "" {
+		alias = getRandomAlias() + "-" + "watch"
+	}
+
+	if err := w.KeyOps.AddWatchOnlyWallet(alias, publicKey); err != nil {
+		return "", err
+	}
+	return alias, nil
+}
+
+// checkCanSign fails with ErrWatchOnlyWallet when the active wallet is watch-only, so a send is
+// refused before it is quoted or signed.
+func (w *WalletConfig) checkCanSign() error {
+	if w.Wallet != nil {
+		return nil
+	}
+
+	info, err := w.KeyOps.GetWalletInfo("")
+	if err != nil {
+		return err
+	}
+	if info.WatchOnly {
+		return fmt.Errorf("cannot send from %s: %w; sign with the device holding its key", info.Alias, ErrWatchOnlyWallet)
+	}
+	return nil
+}
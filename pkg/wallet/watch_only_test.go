@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddWatchOnlyWallet(t *testing.T) {
+	signer := solana.NewWallet()
+	watched := solana.NewWallet().PublicKey().String()
+
+	tests := []struct {
+		name        string
+		existing    map[string]Wallet
+		alias       string
+		publicKey   string
+		expectedErr string
+		expectedAct string
+	}{
+		{name: "First Wallet Becomes Active", alias: "ledger-main", publicKey: watched, expectedAct: "ledger-main"},
+		{
+			name:        "Active Wallet Is Kept",
+			existing:    map[string]Wallet{"main": {PublicKey: signer.PublicKey().String()}},
+			alias:       "ledger-main",
+			publicKey:   watched,
+			expectedAct: "main",
+		},
+		{name: "Invalid Address", alias: "ledger-main", publicKey: "not-an-address", expectedErr: `invalid address "not-an-address"`},
+		{
+			name:        "Duplicate Alias",
+			existing:    map[string]Wallet{"ledger-main": {PublicKey: signer.PublicKey().String()}},
+			alias:       "ledger-main",
+			publicKey:   watched,
+			expectedErr: "alias already exists: ledger-main",
+		},
+		{
+			name:        "Duplicate Address",
+			existing:    map[string]Wallet{"main": {PublicKey: watched}},
+			alias:       "ledger-main",
+			publicKey:   watched,
+			expectedErr: "address " + watched + " is already stored as main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			if tt.existing != nil {
+				assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{ActiveAlias: "main", Wallets: tt.existing})))
+			}
+			keyOps := &KeyOps{FileReader: files, FileWriter: files}
+
+			err := keyOps.AddWatchOnlyWallet(tt.alias, tt.publicKey)
+			if tt.expectedErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+
+			info, err := keyOps.GetWalletInfo(tt.alias)
+			assert.NoError(t, err)
+			assert.True(t, info.WatchOnly)
+			assert.Equal(t, tt.expectedAct == tt.alias, info.Active)
+
+			publicKey, err := keyOps.GetPublicKeyByAlias(tt.alias)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.publicKey, publicKey)
+		})
+	}
+}
+
+func TestWatchOnlyWalletHasNoPrivateKey(t *testing.T) {
+	watched := solana.NewWallet().PublicKey()
+	files := newMemFiles()
+	keyOps := &KeyOps{FileReader: files, FileWriter: files, Rates: StaticRate(decimal.NewFromInt(2))}
+	assert.NoError(t, keyOps.AddWatchOnlyWallet("ledger-main", watched.String()))
+
+	publicKey, err := keyOps.GetCurrentPublicKey()
+	assert.NoError(t, err)
+	assert.Equal(t, watched.String(), publicKey)
+
+	_, err = keyOps.GetCurrentPrivateKey()
+	assert.ErrorIs(t, err, ErrWatchOnlyWallet)
+	_, err = keyOps.GetPrivateKeyByAlias("ledger-main")
+	assert.ErrorIs(t, err, ErrWatchOnlyWallet)
+	_, err = keyOps.ExportKey("ledger-main", ExportBase58)
+	assert.ErrorIs(t, err, ErrWatchOnlyWallet)
+
+	encrypted, err := keyOps.EncryptKeys("correct horse")
+	assert.NoError(t, err)
+	assert.Empty(t, encrypted)
+
+	aliases, _, err := keyOps.PrintAllKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ledger-main (Active) (watch-only) // BAL - (not synced)"}, aliases)
+}
+
+func TestWatchOnlyWalletBalanceAndSend(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	watched := solana.NewWallet().PublicKey()
+	files := newMemFiles()
+	keyOps := &KeyOps{FileReader: files, FileWriter: files}
+	assert.NoError(t, keyOps.AddWatchOnlyWallet("ledger-main", watched.String()))
+
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(_ context.Context, account solana.PublicKey, _ rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				assert.Equal(t, watched, account)
+				return &rpc.GetBalanceResult{Value: 2_000_000_000}, nil
+			},
+		}
+	}
+
+	wc := &WalletConfig{KeyOps: keyOps, Network: Devnet}
+	lamports, err := wc.GetLamportBalance(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2_000_000_000), lamports)
+
+	_, err = wc.PrepareSend(context.Background(), "1", solana.NewWallet().PublicKey().String(), SendOptions{Unit: UnitSOL})
+	assert.ErrorIs(t, err, ErrWatchOnlyWallet)
+}
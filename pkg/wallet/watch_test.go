@@ -0,0 +1,201 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWatchConn is a websocket connection whose notifications are sent by the test. Closing accounts
+// drops the connection.
+type fakeWatchConn struct {
+	accounts chan *ws.AccountResult
+	logs     chan *ws.LogResult
+	closed   chan struct{}
+}
+
+func newFakeWatchConn() *fakeWatchConn {
+	return &fakeWatchConn{
+		accounts: make(chan *ws.AccountResult),
+		logs:     make(chan *ws.LogResult),
+		closed:   make(chan struct{}),
+	}
+}
+
+type fakeStream[T any] struct {
+	results <-chan T
+	closed  <-chan struct{}
+}
+
+func (s fakeStream[T]) Recv() (T, error) {
+	var zero T
+	select {
+	case result, ok := <-s.results:
+		if !ok {
+			return zero, errors.New("connection dropped")
+		}
+		return result, nil
+	case <-s.closed:
+		return zero, errors.New("connection closed")
+	}
+}
+
+func (s fakeStream[T]) Unsubscribe() {}
+
+func (c *fakeWatchConn) AccountSubscribe(solana.PublicKey, rpc.CommitmentType) (accountStream, error) {
+	return fakeStream[*ws.AccountResult]{results: c.accounts, closed: c.closed}, nil
+}
+
+func (c *fakeWatchConn) LogsSubscribeMentions(solana.PublicKey, rpc.CommitmentType) (logStream, error) {
+	return fakeStream[*ws.LogResult]{results: c.logs, closed: c.closed}, nil
+}
+
+func (c *fakeWatchConn) Close() {
+	close(c.closed)
+}
+
+func accountResult(lamports, slot uint64) *ws.AccountResult {
+	result := &ws.AccountResult{}
+	result.Context.Slot = slot
+	result.Value.Lamports = lamports
+	return result
+}
+
+func logResult(sig solana.Signature, failed bool) *ws.LogResult {
+	result := &ws.LogResult{}
+	result.Value.Signature = sig
+	if failed {
+		result.Value.Err = map[string]interface{}{"InstructionError": []interface{}{0, "Custom"}}
+	}
+	return result
+}
+
+func TestWatchAddress(t *testing.T) {
+	defer func(f func(string) ClientInterface) { newRPCClient = f }(newRPCClient)
+
+	defer func(policy RetryPolicy) { DefaultRetryPolicy = policy }(DefaultRetryPolicy)
+	defer func(policy RetryPolicy) { watchReconnectPolicy = policy }(watchReconnectPolicy)
+	defer func(dial func(context.Context, string) (watchConn, error)) { dialWatch = dial }(dialWatch)
+	DefaultRetryPolicy = fastRetries
+	watchReconnectPolicy = RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	owner := solana.NewWallet().PublicKey()
+	files := newMemFiles()
+	assert.NoError(t, files.WriteFile(KeyFileName, jsonMarshal(t, WalletData{
+		ActiveAlias: "main",
+		Wallets:     map[string]Wallet{"main": {PublicKey: owner.String(), WatchOnly: true}},
+	})))
+	wc := &WalletConfig{
+		KeyOps:   &KeyOps{FileReader: files, FileWriter: files},
+		Network:  Devnet,
+		Currency: Currency("EUR"),
+		Rates:    StaticRate(decimal.NewFromInt(100)),
+	}
+
+	var balance atomic.Uint64
+	balance.Store(1_500_000_000)
+	older, sent, failed, missed := solana.Signature{9}, solana.Signature{1}, solana.Signature{2}, solana.Signature{3}
+	sender := solana.NewWallet().PublicKey()
+	newRPCClient = func(string) ClientInterface {
+		return &MockClientInterface{
+			GetBalanceFn: func(context.Context, solana.PublicKey, rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+				return &rpc.GetBalanceResult{Value: balance.Load()}, nil
+			},
+			GetSignaturesForAddressWithOptsFn: func(_ context.Context, _ solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+				assert.Equal(t, rpc.CommitmentFinalized, opts.Commitment)
+				switch opts.Until {
+				case solana.Signature{}:
+					return []*rpc.TransactionSignature{{Signature: older}}, nil
+				case sent, failed:
+					// Finalized while the watch was disconnected, after whichever was notified last.
+					return []*rpc.TransactionSignature{{Signature: missed, Slot: 50}}, nil
+				}
+				return nil, nil
+			},
+			GetTransactionFn: func(_ context.Context, sig solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+				switch sig {
+				case sent:
+					return transferResult(t, owner), nil
+				case missed:
+					return transferResultTo(t, sender, owner, sender), nil
+				}
+				return nil, errors.New("unexpected transaction")
+			},
+		}
+	}
+
+	// The second dial fails, so the watch reconnects twice before the third succeeds.
+	conns := make(chan *fakeWatchConn, 2)
+	var dials atomic.Int32
+	dialWatch = func(context.Context, string) (watchConn, error) {
+		if dials.Add(1) == 2 {
+			return nil, errors.New("connection refused")
+		}
+		conn := newFakeWatchConn()
+		conns <- conn
+		return conn, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan TransferEvent, 10)
+	done := make(chan error)
+	go func() {
+		done <- wc.WatchAddress(ctx, "", func(ev TransferEvent) { events <- ev })
+	}()
+
+	conn := <-conns
+	conn.accounts <- accountResult(2_000_000_000, 42)
+	ev := <-events
+	assert.Equal(t, TransferEventBalance, ev.Kind)
+	assert.Equal(t, uint64(2_000_000_000), ev.Lamports)
+	assert.Equal(t, int64(500_000_000), ev.Change)
+	assert.Equal(t, uint64(42), ev.Slot)
+	assert.Equal(t, "50", ev.Value.String())
+
+	conn.logs <- logResult(sent, false)
+	ev = <-events
+	assert.Equal(t, TransferEventTransfer, ev.Kind)
+	assert.Equal(t, sent, ev.Signature)
+	assert.True(t, ev.Transaction.IsSender)
+	assert.Equal(t, owner, ev.Transaction.From)
+	assert.Equal(t, uint64(1_000), ev.Transaction.Amount)
+	assert.Equal(t, "0", ev.Value.String())
+
+	// A repeated notification and a failed transaction report nothing.
+	conn.logs <- logResult(sent, false)
+	conn.logs <- logResult(failed, true)
+
+	balance.Store(2_100_000_000)
+	close(conn.accounts)
+	ev = <-events
+	assert.Equal(t, TransferEventReconnecting, ev.Kind)
+	assert.Contains(t, ev.Err.Error(), "connection dropped")
+	ev = <-events
+	assert.Equal(t, TransferEventReconnecting, ev.Kind)
+	assert.Contains(t, ev.Err.Error(), "connection refused")
+
+	// The change and transfer made while disconnected are caught up on after reconnecting.
+	<-conns
+	ev = <-events
+	assert.Equal(t, TransferEventBalance, ev.Kind)
+	assert.Equal(t, int64(100_000_000), ev.Change)
+	ev = <-events
+	assert.Equal(t, TransferEventTransfer, ev.Kind)
+	assert.Equal(t, missed, ev.Signature)
+	assert.Equal(t, uint64(50), ev.Slot)
+	assert.False(t, ev.Transaction.IsSender)
+	assert.Equal(t, sender, ev.Transaction.From)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Empty(t, events)
+}